@@ -0,0 +1,51 @@
+package api
+
+import "time"
+
+// Broadcast covers a live stream sent to a client.
+type Broadcast struct {
+	ID            string    `json:"id"`
+	BroadcasterID uint64    `json:"broadcasterID"`
+	Title         string    `json:"title"`
+	Category      string    `json:"category"`
+	Tags          []string  `json:"tags"`
+	Language      string    `json:"language"`
+	Mature        bool      `json:"mature"`
+	IsActive      bool      `json:"isActive"`
+	IsPublished   bool      `json:"isPublished"`
+	ViewerCount   int64     `json:"viewerCount"`
+	PublishedAt   time.Time `json:"publishedAt"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// BroadcastVod covers a video recorded from a broadcast.
+type BroadcastVod struct {
+	ID          string    `json:"id"`
+	PublishedAt time.Time `json:"publishedAt"`
+	Video       Video     `json:"video"`
+}
+
+// BroadcastUpdateRequest covers a request from a broadcaster to edit one of their
+// broadcasts. Fields left unset are not changed.
+type BroadcastUpdateRequest struct {
+	Auth        AuthenticationSet `json:"auth"`
+	Title       *string           `json:"title"`
+	IsPublished *bool             `json:"isPublished"`
+}
+
+// BroadcastResponse covers a response sent to a client fetching or editing a
+// broadcast, with the videos recorded from it.
+type BroadcastResponse struct {
+	Success   bool           `json:"success"`
+	Errors    []string       `json:"errors"`
+	Broadcast Broadcast      `json:"broadcast"`
+	Vods      []BroadcastVod `json:"vods"`
+}
+
+// BroadcastsResponse covers a response sent to a client listing live broadcasts.
+type BroadcastsResponse struct {
+	Success    bool        `json:"success"`
+	Errors     []string    `json:"errors"`
+	Broadcasts []Broadcast `json:"broadcasts"`
+	Page       Page        `json:"page"`
+}
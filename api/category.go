@@ -0,0 +1,37 @@
+package api
+
+// CategoryRequest covers a request from an admin to create or edit a category.
+// The slug of an existing category is taken from its URL and cannot be changed.
+type CategoryRequest struct {
+	Auth        AuthenticationSet `json:"auth"`
+	Slug        string            `json:"slug"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	ImageURL    string            `json:"imageURL"`
+}
+
+// Category covers a category of the directory sent to a client, with the
+// totals of its live broadcasts.
+type Category struct {
+	Slug        string `json:"slug"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	ImageURL    string `json:"imageURL"`
+	Viewers     int64  `json:"viewers"`
+	Broadcasts  int64  `json:"broadcasts"`
+}
+
+// CategoryResponse covers a response sent to a client fetching or managing a category.
+type CategoryResponse struct {
+	Success  bool     `json:"success"`
+	Errors   []string `json:"errors"`
+	Category Category `json:"category"`
+}
+
+// CategoriesResponse covers a response sent to a client browsing the category directory.
+type CategoriesResponse struct {
+	Success    bool       `json:"success"`
+	Errors     []string   `json:"errors"`
+	Categories []Category `json:"categories"`
+	Page       Page       `json:"page"`
+}
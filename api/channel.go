@@ -0,0 +1,41 @@
+package api
+
+import "time"
+
+// ChannelRequest covers a request from a broadcaster to edit their channel page.
+// Every field is replaced, new broadcasts inheriting them as they start.
+type ChannelRequest struct {
+	Auth         AuthenticationSet `json:"auth"`
+	DisplayName  string            `json:"displayName"`
+	Description  string            `json:"description"`
+	AvatarURL    string            `json:"avatarURL"`
+	BannerURL    string            `json:"bannerURL"`
+	DefaultTitle string            `json:"defaultTitle"`
+	Category     string            `json:"category"`
+	Tags         []string          `json:"tags"`
+	Language     string            `json:"language"`
+	Mature       bool              `json:"mature"`
+}
+
+// Channel covers the channel page of a broadcaster sent to a client.
+type Channel struct {
+	BroadcasterID uint64    `json:"broadcasterID"`
+	Username      string    `json:"username"`
+	DisplayName   string    `json:"displayName"`
+	Description   string    `json:"description"`
+	AvatarURL     string    `json:"avatarURL"`
+	BannerURL     string    `json:"bannerURL"`
+	DefaultTitle  string    `json:"defaultTitle"`
+	Category      string    `json:"category"`
+	Tags          []string  `json:"tags"`
+	Language      string    `json:"language"`
+	Mature        bool      `json:"mature"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// ChannelResponse covers a response sent to a client fetching or editing a channel page.
+type ChannelResponse struct {
+	Success bool     `json:"success"`
+	Errors  []string `json:"errors"`
+	Channel Channel  `json:"channel"`
+}
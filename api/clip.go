@@ -0,0 +1,45 @@
+package api
+
+import "time"
+
+// ClipRequest covers a request from a client to cut a clip from a live
+// broadcast or a video. Exactly one of BroadcastID or VideoID should be set.
+// Start and End are offsets into the source, in seconds.
+type ClipRequest struct {
+	Auth        AuthenticationSet `json:"auth"`
+	BroadcastID string            `json:"broadcastID"`
+	VideoID     string            `json:"videoID"`
+	Start       float64           `json:"start"`
+	End         float64           `json:"end"`
+	Title       string            `json:"title"`
+}
+
+// Clip describes a clip, with the range it covers after keyframe alignment in
+// seconds. Path is the prefix the clip's video is played beneath.
+type Clip struct {
+	ID            string    `json:"id"`
+	VideoID       string    `json:"videoID"`
+	CreatorID     uint64    `json:"creatorID"`
+	BroadcasterID uint64    `json:"broadcasterID"`
+	SourceType    string    `json:"sourceType"`
+	SourceID      string    `json:"sourceID"`
+	Start         float64   `json:"start"`
+	End           float64   `json:"end"`
+	Path          string    `json:"path"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// ClipResponse covers a response sent to a client upon cutting a clip.
+type ClipResponse struct {
+	Success bool     `json:"success"`
+	Errors  []string `json:"errors"`
+	Clip    Clip     `json:"clip"`
+}
+
+// ClipsResponse covers a response sent to a client listing a broadcaster's clips.
+type ClipsResponse struct {
+	Success bool     `json:"success"`
+	Errors  []string `json:"errors"`
+	Clips   []Clip   `json:"clips"`
+	Page    Page     `json:"page"`
+}
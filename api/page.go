@@ -0,0 +1,9 @@
+package api
+
+// Page covers the cursors of the pages either side of a listing, sent to a client
+// to fetch them with the cursor query parameter. Cursors are empty where there are
+// no more results.
+type Page struct {
+	Next string `json:"next"`
+	Prev string `json:"prev"`
+}
@@ -0,0 +1,23 @@
+package api
+
+import "time"
+
+// PlaybackTokenRequest covers a request from a client for access to a channel or video.
+// Exactly one of Channel or VideoID should be set.
+type PlaybackTokenRequest struct {
+	Auth    AuthenticationSet `json:"auth"`
+	Channel string            `json:"channel"`
+	VideoID string            `json:"videoID"`
+}
+
+// PlaybackTokenResponse covers a response sent from the User API to a client
+// requesting playback access. Signature holds query parameters to append to
+// media URLs beneath Path.
+type PlaybackTokenResponse struct {
+	Success   bool      `json:"success"`
+	Errors    []string  `json:"errors"`
+	Token     string    `json:"token"`
+	Path      string    `json:"path"`
+	Signature string    `json:"signature"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
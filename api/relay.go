@@ -0,0 +1,30 @@
+package api
+
+import "time"
+
+// RelayTargetRequest covers a request to create or update a restream destination.
+type RelayTargetRequest struct {
+	Auth      AuthenticationSet `json:"auth"`
+	Name      string            `json:"name"`
+	URL       string            `json:"url"`
+	StreamKey string            `json:"streamKey"`
+	Enabled   bool              `json:"enabled"`
+}
+
+// RelayTarget covers a restream destination sent to a client. Stream keys are never returned.
+type RelayTarget struct {
+	ID              string    `json:"id"`
+	Name            string    `json:"name"`
+	URL             string    `json:"url"`
+	Enabled         bool      `json:"enabled"`
+	Status          string    `json:"status"`
+	LastError       string    `json:"lastError"`
+	StatusUpdatedAt time.Time `json:"statusUpdatedAt"`
+}
+
+// RelayTargetsResponse covers a response sent from the User API listing or modifying restream destinations.
+type RelayTargetsResponse struct {
+	Success bool          `json:"success"`
+	Errors  []string      `json:"errors"`
+	Targets []RelayTarget `json:"targets"`
+}
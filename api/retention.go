@@ -0,0 +1,15 @@
+package api
+
+// VideoPinRequest covers a request from a broadcaster to pin one of their
+// videos, keeping it regardless of retention rules, or to unpin it.
+type VideoPinRequest struct {
+	Auth AuthenticationSet `json:"auth"`
+}
+
+// VideoPinResponse covers a response sent to a client upon pinning or unpinning a video.
+type VideoPinResponse struct {
+	Success bool     `json:"success"`
+	Errors  []string `json:"errors"`
+	VideoID string   `json:"videoID"`
+	Pinned  bool     `json:"pinned"`
+}
@@ -0,0 +1,26 @@
+package api
+
+import "time"
+
+// SearchResult covers a channel, broadcast or video matching a search sent to a client.
+// The ID is the broadcaster ID of channels, or the UUID of broadcasts and videos.
+type SearchResult struct {
+	Type          string    `json:"type"`
+	ID            string    `json:"id"`
+	BroadcasterID uint64    `json:"broadcasterID"`
+	Username      string    `json:"username"`
+	Title         string    `json:"title"`
+	Description   string    `json:"description"`
+	Category      string    `json:"category"`
+	Tags          []string  `json:"tags"`
+	IsLive        bool      `json:"isLive"`
+	PublishedAt   time.Time `json:"publishedAt"`
+	Rank          float64   `json:"rank"`
+}
+
+// SearchResponse covers a response sent to a client searching channels, broadcasts and videos.
+type SearchResponse struct {
+	Success bool           `json:"success"`
+	Errors  []string       `json:"errors"`
+	Results []SearchResult `json:"results"`
+}
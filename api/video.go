@@ -0,0 +1,38 @@
+package api
+
+import "time"
+
+// Video covers a video sent to a client. Length is in seconds.
+type Video struct {
+	ID            string    `json:"id"`
+	BroadcasterID uint64    `json:"broadcasterID"`
+	Title         string    `json:"title"`
+	Length        float64   `json:"length"`
+	IsPublished   bool      `json:"isPublished"`
+	PublishedAt   time.Time `json:"publishedAt"`
+	Pinned        bool      `json:"pinned"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// VideoUpdateRequest covers a request from a broadcaster to edit one of their
+// videos. Fields left unset are not changed.
+type VideoUpdateRequest struct {
+	Auth        AuthenticationSet `json:"auth"`
+	Title       *string           `json:"title"`
+	IsPublished *bool             `json:"isPublished"`
+}
+
+// VideoResponse covers a response sent to a client upon editing a video.
+type VideoResponse struct {
+	Success bool     `json:"success"`
+	Errors  []string `json:"errors"`
+	Video   Video    `json:"video"`
+}
+
+// VideosResponse covers a response sent to a client listing a broadcaster's videos.
+type VideosResponse struct {
+	Success bool     `json:"success"`
+	Errors  []string `json:"errors"`
+	Videos  []Video  `json:"videos"`
+	Page    Page     `json:"page"`
+}
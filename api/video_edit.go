@@ -0,0 +1,52 @@
+package api
+
+import "time"
+
+// VideoEditRequest covers a request from a broadcaster to edit their videos.
+// Operation is one of trim, split or merge. Trims cut VideoIDs[0] down to the
+// range from Start to End, splits cut it into parts at each offset in At, and
+// merges join every video in VideoIDs in order. Offsets are in seconds.
+type VideoEditRequest struct {
+	Auth      AuthenticationSet `json:"auth"`
+	Operation string            `json:"operation"`
+	VideoIDs  []string          `json:"videoIDs"`
+	Start     float64           `json:"start"`
+	End       float64           `json:"end"`
+	At        []float64         `json:"at"`
+}
+
+// VideoEditActionRequest covers a request from a broadcaster to confirm or
+// discard a pending edit.
+type VideoEditActionRequest struct {
+	Auth AuthenticationSet `json:"auth"`
+}
+
+// EditedVideo describes a video produced by an edit, with its length in seconds.
+// Path is the prefix the video is played beneath.
+type EditedVideo struct {
+	ID     string  `json:"id"`
+	Title  string  `json:"title"`
+	Length float64 `json:"length"`
+	Path   string  `json:"path"`
+}
+
+// VideoEdit describes an edit of a broadcaster's videos. The sources are kept
+// until the edit is confirmed, and the results deleted if it is discarded.
+type VideoEdit struct {
+	ID            string        `json:"id"`
+	BroadcasterID uint64        `json:"broadcasterID"`
+	Operation     string        `json:"operation"`
+	Status        string        `json:"status"`
+	SourceIDs     []string      `json:"sourceIDs"`
+	Results       []EditedVideo `json:"results"`
+	CreatedAt     time.Time     `json:"createdAt"`
+	UpdatedAt     time.Time     `json:"updatedAt"`
+}
+
+// VideoEditResponse covers a response sent to a client upon creating, looking
+// up, confirming or discarding an edit.
+type VideoEditResponse struct {
+	Success bool      `json:"success"`
+	Errors  []string  `json:"errors"`
+	Edit    VideoEdit `json:"edit"`
+}
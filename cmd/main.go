@@ -2,7 +2,10 @@ package main
 
 import (
 	"fmt"
+	"github.com/M-Ro/go-vodstream/cmd/retention"
 	"github.com/M-Ro/go-vodstream/cmd/streamingester"
+	"github.com/M-Ro/go-vodstream/cmd/users_api"
+	"github.com/M-Ro/go-vodstream/cmd/vodpackager"
 	"github.com/M-Ro/go-vodstream/cmd/web"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -13,7 +16,10 @@ var rootCmd = &cobra.Command{}
 
 // init registers all the available commands to the cli
 func init() {
+	rootCmd.AddCommand(retention.NewCmd())
 	rootCmd.AddCommand(streamingester.NewCmd())
+	rootCmd.AddCommand(users_api.NewCmd())
+	rootCmd.AddCommand(vodpackager.NewCmd())
 	rootCmd.AddCommand(web.NewCmd())
 }
 
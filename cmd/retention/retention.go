@@ -0,0 +1,66 @@
+package retention
+
+import (
+	"context"
+	"fmt"
+	"github.com/M-Ro/go-vodstream/internal/blob"
+	"github.com/M-Ro/go-vodstream/internal/retention"
+	"github.com/M-Ro/go-vodstream/internal/user"
+	"github.com/M-Ro/go-vodstream/storage/sql"
+	"github.com/M-Ro/go-vodstream/storage/sql/broadcast_vod"
+	"github.com/M-Ro/go-vodstream/storage/sql/transaction"
+	sqlUser "github.com/M-Ro/go-vodstream/storage/sql/user"
+	"github.com/M-Ro/go-vodstream/storage/sql/video"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// NewCmd registers the cobra command to be called from the CLI.
+func NewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "retention",
+		Short: "deletes videos beyond the retention rules, reporting the storage reclaimed",
+		Args:  cobra.NoArgs,
+		Run:   Start,
+	}
+
+	cmd.Flags().Bool("dry-run", false, "report what would be deleted without deleting anything")
+
+	return cmd
+}
+
+// Start sweeps videos once, printing what was deleted.
+func Start(cmd *cobra.Command, _ []string) {
+	config := retention.GetConfig()
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		config.DryRun = true
+	}
+
+	media, err := blob.NewStore(blob.GetConfig())
+	if err != nil {
+		log.Fatalf("Couldn't open the media store: %v", err)
+	}
+
+	db := sql.NewDbConn()
+	janitor := retention.NewJanitor(
+		config, media, video.NewVideoStorage(db), broadcast_vod.NewBroadcastVodStorage(db),
+		user.NewRepository(sqlUser.NewUserStorage(db)), transaction.NewTransactor(db),
+	)
+
+	report, err := janitor.Sweep(context.Background())
+	if err != nil {
+		log.Fatalf("Couldn't sweep videos: %v", err)
+	}
+
+	verb := "Deleted"
+	if report.DryRun {
+		verb = "Would delete"
+	}
+
+	for _, deletion := range report.Deletions {
+		fmt.Printf("%s video %s of broadcaster %d (%s): %s, %d bytes in %d objects\n", verb, deletion.Video.Id,
+			deletion.Video.BroadcasterId, deletion.Reason, deletion.Video.Title, deletion.Size, len(deletion.Objects))
+	}
+
+	fmt.Printf("%s %d videos, reclaiming %d bytes\n", verb, len(report.Deletions), report.Reclaimed)
+}
@@ -0,0 +1,227 @@
+package streamingester
+
+import (
+	"context"
+	"github.com/M-Ro/go-vodstream/internal/avtest"
+	"github.com/M-Ro/go-vodstream/internal/cluster"
+	"github.com/M-Ro/go-vodstream/internal/domain/broadcast"
+	"github.com/M-Ro/go-vodstream/internal/live"
+	"github.com/M-Ro/go-vodstream/internal/playback"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/M-Ro/go-vodstream/storage/sql/live_channel"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/nareix/joy4/format/rtmp"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testClusterKey = "clusterKey"
+
+// mockLiveChannelStorage is an in-memory cluster directory shared between test nodes.
+type mockLiveChannelStorage struct {
+	lock     sync.Mutex
+	channels map[string]storage.LiveChannel
+}
+
+func (m *mockLiveChannelStorage) GetByName(_ context.Context, name string, staleBefore time.Time) *storage.LiveChannel {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	liveChannel, ok := m.channels[name]
+	if !ok || liveChannel.HeartbeatAt.Before(staleBefore) {
+		return nil
+	}
+
+	return &liveChannel
+}
+
+func (m *mockLiveChannelStorage) Claim(_ context.Context, liveChannel *storage.LiveChannel, staleBefore time.Time) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if existing, ok := m.channels[liveChannel.Name]; ok && !existing.HeartbeatAt.Before(staleBefore) {
+		return live_channel.ErrNoRowsAffected
+	}
+
+	liveChannel.HeartbeatAt = time.Now()
+	m.channels[liveChannel.Name] = *liveChannel
+
+	return nil
+}
+
+func (m *mockLiveChannelStorage) Release(_ context.Context, name string, nodeAddress string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.channels[name].NodeAddress == nodeAddress {
+		delete(m.channels, name)
+	}
+
+	return nil
+}
+
+func (m *mockLiveChannelStorage) ReleaseNode(_ context.Context, nodeAddress string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for name, liveChannel := range m.channels {
+		if liveChannel.NodeAddress == nodeAddress {
+			delete(m.channels, name)
+		}
+	}
+
+	return nil
+}
+
+func (m *mockLiveChannelStorage) Heartbeat(_ context.Context, _ string) error {
+	return nil
+}
+
+// testNode is an ingester running as a member of a test cluster.
+type testNode struct {
+	addr     string
+	channels *live.Registry
+	ingester *Ingester
+}
+
+// startNode starts an ingester on a free local port, joined to the cluster using directory.
+func startNode(t *testing.T, directory cluster.Directory) testNode {
+	addr := avtest.FreeAddress(t)
+	channels := live.NewRegistry()
+
+	config := cluster.Config{NodeAddress: addr, Key: testClusterKey, HeartbeatInterval: time.Second}
+	node := cluster.NewNode(config, channels, directory, cluster.DialRTMP)
+
+	ingester := NewIngester(
+		channels, testUsers, playback.NewAuthorizer(playback.Config{}, testUsers), WithCluster(node),
+	)
+
+	avtest.StartRTMPServer(t, &rtmp.Server{
+		Addr:          addr,
+		HandlePublish: ingester.HandlePublish,
+		HandlePlay:    ingester.HandlePlay,
+	})
+
+	return testNode{addr: addr, channels: channels, ingester: ingester}
+}
+
+// waitFor polls condition until it is true, failing the test after a timeout.
+func waitFor(t *testing.T, description string, condition func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", description)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// publishTo publishes test packets to the node until stop is closed.
+func publishTo(t *testing.T, addr string, stop chan struct{}) {
+	conn, err := rtmp.Dial("rtmp://" + addr + "/live/publisher?key=publisherKey")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer conn.Close()
+
+	if err := conn.WriteHeader(avtest.Streams()); err != nil {
+		t.Error(err)
+		return
+	}
+
+	for _, packet := range avtest.Packets(100000, 25) {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		if err := conn.WritePacket(packet); err != nil {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestIngester_Cluster_EdgePullsFromOrigin(t *testing.T) {
+	directory := cluster.NewDirectory(
+		&mockLiveChannelStorage{channels: make(map[string]storage.LiveChannel)}, time.Minute,
+	)
+	origin := startNode(t, directory)
+	edge := startNode(t, directory)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go publishTo(t, origin.addr, stop)
+
+	waitFor(t, "origin to register the channel", func() bool {
+		_, err := directory.Lookup(context.Background(), "publisher")
+		return err == nil
+	})
+
+	viewer, err := rtmp.Dial("rtmp://" + edge.addr + "/live/publisher")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	streams, err := viewer.Streams()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(streams) != len(avtest.Streams()) {
+		t.Fatal(cmp.Diff(len(streams), len(avtest.Streams())))
+	}
+
+	for i := 0; i < 50; i++ {
+		if _, err := viewer.ReadPacket(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := edge.channels.Get("publisher"); err != nil {
+		t.Fatalf("expected edge to host the pulled channel: %v", err)
+	}
+
+	viewer.Close()
+
+	waitFor(t, "edge to tear down the pulled channel", func() bool {
+		_, err := edge.channels.Get("publisher")
+		return err == live.ErrChannelNotFound
+	})
+
+	if _, err := origin.channels.Get("publisher"); err != nil {
+		t.Fatalf("expected origin channel to remain live: %v", err)
+	}
+}
+
+func TestIngester_Cluster_RejectsPublishHostedElsewhere(t *testing.T) {
+	storageProvider := &mockLiveChannelStorage{channels: make(map[string]storage.LiveChannel)}
+	directory := cluster.NewDirectory(storageProvider, time.Minute)
+
+	storageProvider.channels["publisher"] = storage.LiveChannel{
+		Name:          "publisher",
+		NodeAddress:   "127.0.0.1:1",
+		BroadcasterId: 1,
+		Visibility:    string(broadcast.VisibilityPublic),
+		HeartbeatAt:   time.Now(),
+	}
+
+	channels := live.NewRegistry()
+	node := cluster.NewNode(cluster.Config{NodeAddress: "127.0.0.1:2"}, channels, directory, cluster.DialRTMP)
+	ingester := NewIngester(channels, testUsers, playback.NewAuthorizer(playback.Config{}, testUsers), WithCluster(node))
+
+	src := avtest.NewDemuxer(avtest.Streams(), avtest.Packets(10, 5))
+
+	err := ingester.publish(context.Background(), testUsers["publisher"], broadcast.VisibilityPublic, src)
+	if !cmp.Equal(err, cluster.ErrHostedElsewhere, cmpopts.EquateErrors()) {
+		t.Fatal(cmp.Diff(err, cluster.ErrHostedElsewhere, cmpopts.EquateErrors()))
+	}
+
+	if _, err := channels.Get("publisher"); err != live.ErrChannelNotFound {
+		t.Fatal("expected rejected channel to be closed locally")
+	}
+}
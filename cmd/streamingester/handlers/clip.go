@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/M-Ro/go-vodstream/api"
+	"github.com/M-Ro/go-vodstream/internal/clip"
+	domainClip "github.com/M-Ro/go-vodstream/internal/domain/clip"
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	"github.com/M-Ro/go-vodstream/internal/playback"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrClipMissingSource  = errors.New("a broadcast or video is required")
+	ErrClipInvalidSource  = errors.New("broadcast or video does not exist")
+	ErrClipMissingChannel = errors.New("a broadcaster is required")
+	ErrClipInvalidChannel = errors.New("broadcaster does not exist")
+	ErrClipInvalidPage    = errors.New("limit and offset must be positive integers")
+)
+
+// ClipHandler cuts clips from the broadcasts and videos this node serves, and
+// lists the clips of a broadcaster.
+type ClipHandler struct {
+	clipper    *clip.Clipper
+	clips      clip.Repository
+	authorizer playback.Authorizer
+	users      playback.UserProvider
+}
+
+func (h *ClipHandler) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc("/v1/clips", h.Create).Methods(http.MethodPost)
+	r.HandleFunc("/v1/clips", h.List).Methods(http.MethodGet)
+}
+
+// clipToAPI converts a clip to its API representation.
+func clipToAPI(c domainClip.Clip) api.Clip {
+	return api.Clip{
+		ID:            c.Id.String(),
+		VideoID:       c.VideoId.String(),
+		CreatorID:     c.CreatorId,
+		BroadcasterID: c.BroadcasterId,
+		SourceType:    string(c.SourceType),
+		SourceID:      c.SourceId.String(),
+		Start:         c.Start.Seconds(),
+		End:           c.End.Seconds(),
+		Path:          playback.VodPathPrefix(c.VideoId),
+		CreatedAt:     c.CreatedAt,
+	}
+}
+
+// writeJSON encodes the response, logging encoding failures against action.
+func writeJSON(w http.ResponseWriter, status int, action string, response interface{}) {
+	encoded, err := json.Marshal(response)
+	if err != nil {
+		log.Errorf("%s failed %v", action, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(encoded)
+}
+
+// writeClipResponse encodes the response, filling the error list when err is set.
+func writeClipResponse(w http.ResponseWriter, status int, response api.ClipResponse, err error) {
+	response.Errors = []string{}
+	if err != nil {
+		response.Errors = append(response.Errors, err.Error())
+	}
+
+	writeJSON(w, status, "Clip", response)
+}
+
+// writeClipsResponse encodes the response, filling the error list when err is set.
+func writeClipsResponse(w http.ResponseWriter, status int, response api.ClipsResponse, err error) {
+	response.Errors = []string{}
+	if err != nil {
+		response.Errors = append(response.Errors, err.Error())
+	}
+
+	if response.Clips == nil {
+		response.Clips = []api.Clip{}
+	}
+
+	writeJSON(w, status, "Listing clips", response)
+}
+
+// seconds converts an offset in seconds from a request to a duration.
+func seconds(value float64) time.Duration {
+	return time.Duration(value * float64(time.Second))
+}
+
+// Create cuts a clip from a live broadcast or a video for an authenticated user
+// permitted to play it.
+func (h *ClipHandler) Create(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Errorf("Clip failed: %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var clipRequest api.ClipRequest
+	err = json.Unmarshal(body, &clipRequest)
+	if err != nil {
+		writeClipResponse(w, http.StatusBadRequest, api.ClipResponse{}, err)
+		return
+	}
+
+	sessionToken := clipRequest.Auth.AccessToken
+	if sessionToken == "" {
+		sessionToken = playback.TokenFromRequest(r)
+	}
+
+	creator, err := h.authorizer.Authenticate(r.Context(), sessionToken)
+	if err != nil {
+		writeClipResponse(w, http.StatusUnauthorized, api.ClipResponse{}, err)
+		return
+	}
+
+	sourceType, sourceId := domainClip.SourceBroadcast, clipRequest.BroadcastID
+	if sourceId == "" {
+		sourceType, sourceId = domainClip.SourceVideo, clipRequest.VideoID
+	}
+
+	if sourceId == "" {
+		writeClipResponse(w, http.StatusBadRequest, api.ClipResponse{}, ErrClipMissingSource)
+		return
+	}
+
+	id, err := uuid.Parse(sourceId)
+	if err != nil {
+		writeClipResponse(w, http.StatusBadRequest, api.ClipResponse{}, ErrClipInvalidSource)
+		return
+	}
+
+	source, err := h.clipper.Source(r.Context(), sourceType, id, creator.Id)
+	if err != nil {
+		writeClipResponse(w, http.StatusNotFound, api.ClipResponse{}, ErrClipInvalidSource)
+		return
+	}
+
+	// Broadcasters may always clip their own media, anyone else must be able to play it.
+	if creator.Id != source.BroadcasterId && !creator.CanStream {
+		writeClipResponse(w, http.StatusForbidden, api.ClipResponse{}, playback.ErrNotPermitted)
+		return
+	}
+
+	created, err := h.clipper.Create(r.Context(), source, clip.Request{
+		CreatorId: creator.Id,
+		Start:     seconds(clipRequest.Start),
+		End:       seconds(clipRequest.End),
+		Title:     clipRequest.Title,
+	})
+	if errors.Is(err, clip.ErrInvalidRange) {
+		writeClipResponse(w, http.StatusBadRequest, api.ClipResponse{}, err)
+		return
+	} else if err != nil {
+		log.Errorf("Clip of %s %s failed: %v", sourceType, id, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writeClipResponse(w, http.StatusOK, api.ClipResponse{Success: true, Clip: clipToAPI(created)}, nil)
+}
+
+// List returns the clips cut from a broadcaster's media, newest first, paged
+// with the limit and offset or cursor query parameters.
+func (h *ClipHandler) List(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	name := query.Get("broadcaster")
+	if name == "" {
+		writeClipsResponse(w, http.StatusBadRequest, api.ClipsResponse{}, ErrClipMissingChannel)
+		return
+	}
+
+	options := []paginate.FuncOption{
+		paginate.WithOrderField("created_at"),
+		paginate.WithOrder(paginate.OrderMethodDesc),
+	}
+
+	for param, option := range map[string]func(uint) paginate.FuncOption{
+		"limit":  paginate.WithLimit,
+		"offset": paginate.WithOffset,
+	} {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+
+		n, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			writeClipsResponse(w, http.StatusBadRequest, api.ClipsResponse{}, ErrClipInvalidPage)
+			return
+		}
+
+		options = append(options, option(uint(n)))
+	}
+
+	if value := query.Get("cursor"); value != "" {
+		cursor, err := paginate.DecodeCursor(value)
+		if err != nil || cursor.Field != "created_at" {
+			writeClipsResponse(w, http.StatusBadRequest, api.ClipsResponse{}, paginate.ErrInvalidCursor)
+			return
+		}
+
+		options = append(options, paginate.WithCursor(cursor))
+	}
+
+	broadcaster, err := h.users.GetByUsername(r.Context(), name)
+	if err != nil {
+		writeClipsResponse(w, http.StatusNotFound, api.ClipsResponse{}, ErrClipInvalidChannel)
+		return
+	}
+
+	clips, page, err := h.clips.ListByBroadcaster(r.Context(), broadcaster.Id, paginate.NewPaginateOptions(options...))
+	if err != nil {
+		log.Errorf("Listing clips of %s failed: %v", name, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	response := api.ClipsResponse{
+		Success: true,
+		Clips:   make([]api.Clip, 0, len(clips)),
+		Page:    api.Page{Next: page.Next, Prev: page.Prev},
+	}
+	for _, c := range clips {
+		response.Clips = append(response.Clips, clipToAPI(c))
+	}
+
+	writeClipsResponse(w, http.StatusOK, response, nil)
+}
+
+// NewClipHandler instantiates a new ClipHandler.
+func NewClipHandler(
+	clipper *clip.Clipper, clips clip.Repository, authorizer playback.Authorizer, users playback.UserProvider,
+) *ClipHandler {
+	return &ClipHandler{
+		clipper:    clipper,
+		clips:      clips,
+		authorizer: authorizer,
+		users:      users,
+	}
+}
@@ -0,0 +1,358 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/M-Ro/go-vodstream/api"
+	"github.com/M-Ro/go-vodstream/internal/blob"
+	"github.com/M-Ro/go-vodstream/internal/clip"
+	"github.com/M-Ro/go-vodstream/internal/domain"
+	"github.com/M-Ro/go-vodstream/internal/domain/user"
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	"github.com/M-Ro/go-vodstream/internal/playback"
+	"github.com/M-Ro/go-vodstream/internal/recording"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/spf13/afero"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var testClipPlaybackConfig = playback.Config{
+	SigningSecret: "testSecret",
+	IssuerIdent:   "vodstream-test",
+	TokenDuration: time.Minute,
+}
+
+// clipVideos stores videos in memory, including those clips are published as.
+type clipVideos map[uuid.UUID]storage.Video
+
+func (v clipVideos) GetByID(_ context.Context, id uuid.UUID) *storage.Video {
+	video, ok := v[id]
+	if !ok {
+		return nil
+	}
+	return &video
+}
+
+func (v clipVideos) Insert(_ context.Context, video *storage.Video) error {
+	video.Id = uuid.New()
+	v[video.Id] = *video
+	return nil
+}
+
+// clipStorage stores clips in memory, listing them in insertion order.
+type clipStorage struct {
+	clips []storage.Clip
+}
+
+func (s *clipStorage) ListByBroadcaster(
+	_ context.Context, broadcasterId uint64, options paginate.QueryOptions,
+) ([]storage.Clip, paginate.Page, error) {
+	clips := make([]storage.Clip, 0)
+	for _, c := range s.clips {
+		if c.BroadcasterId == broadcasterId {
+			clips = append(clips, c)
+		}
+	}
+
+	if int(options.Offset) >= len(clips) {
+		return []storage.Clip{}, paginate.Page{}, nil
+	}
+	clips = clips[options.Offset:]
+
+	if int(options.Limit)+1 < len(clips) {
+		clips = clips[:options.Limit+1]
+	}
+
+	page := paginate.Paginate(&clips, options, func(i int) ([]string, string) {
+		return []string{clips[i].CreatedAt.Format(time.RFC3339Nano)}, clips[i].Id.String()
+	})
+
+	return clips, page, nil
+}
+
+func (s *clipStorage) GetByID(_ context.Context, _ uuid.UUID) *storage.Clip {
+	return nil
+}
+
+func (s *clipStorage) Delete(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+
+func (s *clipStorage) Insert(_ context.Context, c *storage.Clip) error {
+	c.Id = uuid.New()
+	c.CreatedAt = time.Now()
+	s.clips = append(s.clips, *c)
+	return nil
+}
+
+type clipUsers map[string]user.User
+
+func (u clipUsers) GetByUsername(_ context.Context, username string) (user.User, error) {
+	found, ok := u[username]
+	if !ok {
+		return user.User{}, io.EOF
+	}
+	return found, nil
+}
+
+var testClipUsers = clipUsers{
+	"broadcaster": {Id: 1, Username: "broadcaster", CanPublish: true},
+	"viewer":      {Id: 2, Username: "viewer", CanStream: true},
+	"banned":      {Id: 3, Username: "banned"},
+}
+
+func clipSessionToken(t *testing.T, username string) string {
+	claims := domain.AuthClaim{
+		Username: username,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			Issuer:    testClipPlaybackConfig.IssuerIdent,
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testClipPlaybackConfig.SigningSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+// newClipRouter serves clips of a single published video of 100 frames by the
+// broadcaster.
+func newClipRouter(t *testing.T) (*mux.Router, uuid.UUID) {
+	media := blob.NewLocalStore(afero.NewMemMapFs())
+
+	videos := clipVideos{}
+	video := writeRecording(t, media, "test.flv", 100)
+	video.BroadcasterId = 1
+	video.IsPublished = true
+	if err := videos.Insert(context.Background(), &video); err != nil {
+		t.Fatal(err)
+	}
+
+	clips := clip.NewRepository(&clipStorage{})
+	clipper := clip.NewClipper(
+		clip.Config{MaxDuration: 10 * time.Second}, recording.Config{SpoolDirectory: t.TempDir()}, media, videos, nil, clips,
+		storage.Immediate{},
+	)
+	authorizer := playback.NewAuthorizer(testClipPlaybackConfig, testClipUsers)
+
+	r := mux.NewRouter()
+	NewClipHandler(clipper, clips, authorizer, testClipUsers).RegisterRoutes(r)
+
+	return r, video.Id
+}
+
+func TestClipHandler_Create(t *testing.T) {
+	r, videoId := newClipRouter(t)
+
+	tests := []struct {
+		testName string
+		reqBody  api.ClipRequest
+
+		respStatus int
+		respErrors []string
+		respStart  float64
+	}{
+		{
+			testName: "Expect success (200) for viewer clipping a video.",
+			reqBody: api.ClipRequest{
+				Auth:    api.AuthenticationSet{AccessToken: clipSessionToken(t, "viewer")},
+				VideoID: videoId.String(),
+				Start:   1.5,
+				End:     2.5,
+			},
+			respStatus: 200,
+			respErrors: []string{},
+			respStart:  1,
+		},
+		{
+			testName: "Expect success (200) for broadcaster without CanStream clipping their video.",
+			reqBody: api.ClipRequest{
+				Auth:    api.AuthenticationSet{AccessToken: clipSessionToken(t, "broadcaster")},
+				VideoID: videoId.String(),
+				Start:   0,
+				End:     1,
+			},
+			respStatus: 200,
+			respErrors: []string{},
+			respStart:  0,
+		},
+		{
+			testName: "Expect error (401) without a session token.",
+			reqBody: api.ClipRequest{
+				VideoID: videoId.String(),
+				End:     1,
+			},
+			respStatus: 401,
+			respErrors: []string{playback.ErrTokenInvalid.Error()},
+		},
+		{
+			testName: "Expect error (403) for user without CanStream.",
+			reqBody: api.ClipRequest{
+				Auth:    api.AuthenticationSet{AccessToken: clipSessionToken(t, "banned")},
+				VideoID: videoId.String(),
+				End:     1,
+			},
+			respStatus: 403,
+			respErrors: []string{playback.ErrNotPermitted.Error()},
+		},
+		{
+			testName: "Expect error (404) for unknown video.",
+			reqBody: api.ClipRequest{
+				Auth:    api.AuthenticationSet{AccessToken: clipSessionToken(t, "viewer")},
+				VideoID: uuid.New().String(),
+				End:     1,
+			},
+			respStatus: 404,
+			respErrors: []string{ErrClipInvalidSource.Error()},
+		},
+		{
+			testName: "Expect error (404) for broadcast not being recorded.",
+			reqBody: api.ClipRequest{
+				Auth:        api.AuthenticationSet{AccessToken: clipSessionToken(t, "viewer")},
+				BroadcastID: uuid.New().String(),
+				End:         1,
+			},
+			respStatus: 404,
+			respErrors: []string{ErrClipInvalidSource.Error()},
+		},
+		{
+			testName: "Expect error (400) without broadcast or video.",
+			reqBody: api.ClipRequest{
+				Auth: api.AuthenticationSet{AccessToken: clipSessionToken(t, "viewer")},
+				End:  1,
+			},
+			respStatus: 400,
+			respErrors: []string{ErrClipMissingSource.Error()},
+		},
+		{
+			testName: "Expect error (400) for an empty range.",
+			reqBody: api.ClipRequest{
+				Auth:    api.AuthenticationSet{AccessToken: clipSessionToken(t, "viewer")},
+				VideoID: videoId.String(),
+				Start:   2,
+				End:     1,
+			},
+			respStatus: 400,
+			respErrors: []string{clip.ErrInvalidRange.Error()},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			b, err := json.Marshal(test.reqBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/v1/clips", bytes.NewReader(b)))
+			resp := recorder.Result()
+
+			if !cmp.Equal(resp.StatusCode, test.respStatus) {
+				t.Fatal(cmp.Diff(resp.StatusCode, test.respStatus))
+			}
+
+			result := api.ClipResponse{}
+			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(result.Errors, test.respErrors) {
+				t.Fatal(cmp.Diff(result.Errors, test.respErrors))
+			}
+
+			if !result.Success {
+				return
+			}
+
+			if result.Clip.Start != test.respStart || result.Clip.SourceID != videoId.String() {
+				t.Fatalf("unexpected clip: %+v", result.Clip)
+			}
+
+			if result.Clip.Path != "/vod/"+result.Clip.VideoID+"/" {
+				t.Fatal(cmp.Diff(result.Clip.Path, "/vod/"+result.Clip.VideoID+"/"))
+			}
+		})
+	}
+}
+
+func TestClipHandler_List(t *testing.T) {
+	r, videoId := newClipRouter(t)
+
+	for i := 0; i < 3; i++ {
+		b, err := json.Marshal(api.ClipRequest{
+			Auth:    api.AuthenticationSet{AccessToken: clipSessionToken(t, "viewer")},
+			VideoID: videoId.String(),
+			Start:   float64(i),
+			End:     float64(i) + 0.5,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		recorder := httptest.NewRecorder()
+		r.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/v1/clips", bytes.NewReader(b)))
+		if recorder.Code != http.StatusOK {
+			t.Fatal(cmp.Diff(recorder.Code, http.StatusOK))
+		}
+	}
+
+	tests := []struct {
+		testName string
+		query    string
+
+		respStatus int
+		respErrors []string
+		respCount  int
+	}{
+		{testName: "Expect all clips of the broadcaster.", query: "?broadcaster=broadcaster",
+			respStatus: 200, respErrors: []string{}, respCount: 3},
+		{testName: "Expect a page of clips.", query: "?broadcaster=broadcaster&limit=1&offset=1",
+			respStatus: 200, respErrors: []string{}, respCount: 1},
+		{testName: "Expect no clips of a broadcaster with none.", query: "?broadcaster=viewer",
+			respStatus: 200, respErrors: []string{}, respCount: 0},
+		{testName: "Expect error (400) without a broadcaster.", query: "",
+			respStatus: 400, respErrors: []string{ErrClipMissingChannel.Error()}},
+		{testName: "Expect error (400) for an invalid limit.", query: "?broadcaster=broadcaster&limit=-1",
+			respStatus: 400, respErrors: []string{ErrClipInvalidPage.Error()}},
+		{testName: "Expect error (404) for an unknown broadcaster.", query: "?broadcaster=nobody",
+			respStatus: 404, respErrors: []string{ErrClipInvalidChannel.Error()}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/clips"+test.query, nil))
+			resp := recorder.Result()
+
+			if !cmp.Equal(resp.StatusCode, test.respStatus) {
+				t.Fatal(cmp.Diff(resp.StatusCode, test.respStatus))
+			}
+
+			result := api.ClipsResponse{}
+			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(result.Errors, test.respErrors) {
+				t.Fatal(cmp.Diff(result.Errors, test.respErrors))
+			}
+
+			if len(result.Clips) != test.respCount {
+				t.Fatal(cmp.Diff(len(result.Clips), test.respCount))
+			}
+		})
+	}
+}
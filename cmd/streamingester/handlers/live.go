@@ -0,0 +1,405 @@
+package handlers
+
+import (
+	"context"
+	"github.com/M-Ro/go-vodstream/internal/flvstream"
+	"github.com/M-Ro/go-vodstream/internal/hls"
+	"github.com/M-Ro/go-vodstream/internal/live"
+	"github.com/M-Ro/go-vodstream/internal/playback"
+	"github.com/M-Ro/go-vodstream/internal/urlsign"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ChannelProvider looks up live channels for viewers.
+type ChannelProvider interface {
+	// Acquire returns the named channel, and a function to call once the viewer leaves.
+	Acquire(ctx context.Context, name string) (*live.Channel, func(), error)
+}
+
+// LiveHandler serves live channels over HTTP-FLV, WebSocket-FLV, HLS and
+// MPEG-DASH under the same /live/<name>/ prefix playback URLs are signed for.
+type LiveHandler struct {
+	config     flvstream.Config
+	channels   ChannelProvider
+	packagers  *hls.Manager
+	authorizer playback.Authorizer
+	signer     *urlsign.Signer
+	upgrader   websocket.Upgrader
+}
+
+func (h *LiveHandler) RegisterRoutes(r *mux.Router) {
+	s := r.PathPrefix("/live/{name}").Subrouter()
+	if h.signer != nil {
+		s.Use(h.signer.Middleware)
+	}
+
+	s.HandleFunc("/stream.flv", h.FLV).Methods(http.MethodGet)
+	s.HandleFunc("/stream.ws", h.WebSocket).Methods(http.MethodGet)
+	s.HandleFunc("/index.m3u8", h.Playlist).Methods(http.MethodGet)
+	s.HandleFunc("/seg{sequence:[0-9]+}.ts", h.Segment).Methods(http.MethodGet)
+	s.HandleFunc("/part{sequence:[0-9]+}.{part:[0-9]+}.ts", h.Part).Methods(http.MethodGet)
+	s.HandleFunc("/manifest.mpd", h.Manifest).Methods(http.MethodGet)
+	s.HandleFunc("/master.m3u8", h.Multivariant).Methods(http.MethodGet)
+	s.HandleFunc("/track{track:[0-9]+}.m3u8", h.TrackPlaylist).Methods(http.MethodGet)
+	s.HandleFunc("/init{track:[0-9]+}.mp4", h.Init).Methods(http.MethodGet)
+	s.HandleFunc("/seg{sequence:[0-9]+}.{track:[0-9]+}.m4s", h.Fragment).Methods(http.MethodGet)
+}
+
+// authorize checks the viewer may play the channel, writing an error response otherwise.
+func (h *LiveHandler) authorize(w http.ResponseWriter, r *http.Request, channel *live.Channel) bool {
+	resource := playback.Resource{
+		Name:          channel.Name,
+		BroadcasterId: channel.BroadcasterId,
+		Visibility:    channel.Visibility,
+	}
+
+	if _, err := h.authorizer.Authorize(r.Context(), resource, playback.TokenFromRequest(r)); err != nil {
+		http.Error(w, err.Error(), playback.StatusCode(err))
+		return false
+	}
+
+	return true
+}
+
+// acquire returns the requested channel if the viewer is authorized to play it,
+// writing an error response otherwise.
+func (h *LiveHandler) acquire(w http.ResponseWriter, r *http.Request) (*live.Channel, func(), bool) {
+	name := mux.Vars(r)["name"]
+
+	channel, release, err := h.channels.Acquire(r.Context(), name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return nil, nil, false
+	}
+
+	if !h.authorize(w, r, channel) {
+		release()
+		return nil, nil, false
+	}
+
+	return channel, release, true
+}
+
+// FLV streams the channel as a chunked FLV response.
+func (h *LiveHandler) FLV(w http.ResponseWriter, r *http.Request) {
+	dst, ok := flvstream.NewResponseWriter(w, h.config.WriteTimeout)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	channel, release, ok := h.acquire(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+	defer channel.Join()()
+
+	w.Header().Set("Content-Type", "video/x-flv")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(http.StatusOK)
+
+	if err := flvstream.Serve(r.Context(), dst, channel.Queue, h.config); err != nil {
+		log.Debugf("Stopped serving channel %s over HTTP-FLV: %v", channel.Name, err)
+	}
+}
+
+// WebSocket streams the channel as FLV in binary WebSocket messages.
+func (h *LiveHandler) WebSocket(w http.ResponseWriter, r *http.Request) {
+	channel, release, ok := h.acquire(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+	defer channel.Join()()
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Debugf("Couldn't upgrade viewer of channel %s: %v", channel.Name, err)
+		return
+	}
+	defer conn.Close()
+
+	// Viewers don't send anything, but reading is required to notice them leave.
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	dst := flvstream.NewWebSocketWriter(conn, h.config.WriteTimeout)
+	if err := flvstream.Serve(ctx, dst, channel.Queue, h.config); err != nil {
+		log.Debugf("Stopped serving channel %s over WebSocket-FLV: %v", channel.Name, err)
+	}
+
+	conn.WriteControl(
+		websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second),
+	)
+}
+
+// packager returns the HLS packager for the requested channel if the viewer is
+// authorized to play it, writing an error response otherwise.
+func (h *LiveHandler) packager(w http.ResponseWriter, r *http.Request) (*hls.Packager, bool) {
+	packager, err := h.packagers.Get(r.Context(), mux.Vars(r)["name"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return nil, false
+	}
+
+	if !h.authorize(w, r, packager.Channel()) {
+		return nil, false
+	}
+
+	return packager, true
+}
+
+// poll counts the requester as a viewer of the channel, as players poll its
+// playlists and manifest for as long as they are watching.
+func poll(r *http.Request, packager *hls.Packager) {
+	packager.Channel().Poll(urlsign.RemoteIP(r), time.Now())
+}
+
+// hlsStatusCode returns the HTTP status code for an error serving HLS.
+func hlsStatusCode(err error) int {
+	switch err {
+	case hls.ErrInvalidRequest, hls.ErrTooFarAhead:
+		return http.StatusBadRequest
+	case hls.ErrNotReady:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusNotFound
+	}
+}
+
+// Playlist serves the HLS media playlist of the channel. Low-Latency HLS
+// channels support blocking reloads and delta playlists, holding the request
+// until the media asked for is available.
+func (h *LiveHandler) Playlist(w http.ResponseWriter, r *http.Request) {
+	request, err := hls.ParseRequest(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), hlsStatusCode(err))
+		return
+	}
+
+	packager, ok := h.packager(w, r)
+	if !ok {
+		return
+	}
+
+	poll(r, packager)
+
+	playlist, err := packager.Playlist(r.Context(), request)
+	writePlaylist(w, "application/vnd.apple.mpegurl", playlist, err)
+}
+
+// writeMedia writes an MPEG-TS segment or partial segment.
+func writeMedia(w http.ResponseWriter, data []byte, err error) {
+	if err != nil {
+		http.Error(w, err.Error(), hlsStatusCode(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "video/mp2t")
+	w.Header().Set("Cache-Control", "max-age=60")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Write(data)
+}
+
+// Segment serves an HLS segment, waiting for it to complete if it is in progress.
+func (h *LiveHandler) Segment(w http.ResponseWriter, r *http.Request) {
+	sequence, err := strconv.Atoi(mux.Vars(r)["sequence"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	packager, ok := h.packager(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := packager.Segment(r.Context(), sequence)
+	writeMedia(w, data, err)
+}
+
+// Part serves a Low-Latency HLS partial segment, waiting for it if it is the
+// next to be cut.
+func (h *LiveHandler) Part(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	sequence, err := strconv.Atoi(vars["sequence"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	index, err := strconv.Atoi(vars["part"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	packager, ok := h.packager(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := packager.Part(r.Context(), sequence, index)
+	writeMedia(w, data, err)
+}
+
+// writePlaylist writes a playlist or manifest, which change as the channel goes on.
+func writePlaylist(w http.ResponseWriter, contentType string, data []byte, err error) {
+	if err != nil {
+		http.Error(w, err.Error(), hlsStatusCode(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Write(data)
+}
+
+// writeFragment writes a CMAF init segment or fragment.
+func writeFragment(w http.ResponseWriter, data []byte, err error) {
+	if err != nil {
+		http.Error(w, err.Error(), hlsStatusCode(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "video/mp4")
+	w.Header().Set("Cache-Control", "max-age=60")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Write(data)
+}
+
+// Manifest serves the dynamic MPEG-DASH manifest of the channel.
+func (h *LiveHandler) Manifest(w http.ResponseWriter, r *http.Request) {
+	packager, ok := h.packager(w, r)
+	if !ok {
+		return
+	}
+
+	poll(r, packager)
+
+	manifest, err := packager.Manifest(r.Context(), r.URL.Query())
+	writePlaylist(w, "application/dash+xml", manifest, err)
+}
+
+// Multivariant serves the multivariant HLS playlist listing the channel's fMP4 tracks.
+func (h *LiveHandler) Multivariant(w http.ResponseWriter, r *http.Request) {
+	packager, ok := h.packager(w, r)
+	if !ok {
+		return
+	}
+
+	playlist, err := packager.Multivariant(r.Context(), r.URL.Query())
+	writePlaylist(w, "application/vnd.apple.mpegurl", playlist, err)
+}
+
+// TrackPlaylist serves the fMP4 HLS media playlist of one of the channel's tracks.
+func (h *LiveHandler) TrackPlaylist(w http.ResponseWriter, r *http.Request) {
+	track, err := strconv.Atoi(mux.Vars(r)["track"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	request, err := hls.ParseRequest(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), hlsStatusCode(err))
+		return
+	}
+
+	packager, ok := h.packager(w, r)
+	if !ok {
+		return
+	}
+
+	poll(r, packager)
+
+	playlist, err := packager.TrackPlaylist(r.Context(), track, request)
+	writePlaylist(w, "application/vnd.apple.mpegurl", playlist, err)
+}
+
+// Init serves the CMAF init segment of a track, shared by DASH and fMP4 HLS.
+func (h *LiveHandler) Init(w http.ResponseWriter, r *http.Request) {
+	track, err := strconv.Atoi(mux.Vars(r)["track"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	packager, ok := h.packager(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := packager.Init(r.Context(), track)
+	writeFragment(w, data, err)
+}
+
+// Fragment serves the CMAF fragment of a track for a segment, shared by DASH
+// and fMP4 HLS, waiting for the segment to complete if it is in progress.
+func (h *LiveHandler) Fragment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	sequence, err := strconv.Atoi(vars["sequence"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	track, err := strconv.Atoi(vars["track"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	packager, ok := h.packager(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := packager.Fragment(r.Context(), sequence, track)
+	writeFragment(w, data, err)
+}
+
+// NewLiveHandler instantiates a new LiveHandler serving HLS and DASH from
+// packagers. Media URLs must be signed when the playback config has a URL
+// signing secret.
+func NewLiveHandler(
+	config flvstream.Config, packagers *hls.Manager, playbackConfig playback.Config,
+	channels ChannelProvider, authorizer playback.Authorizer,
+) *LiveHandler {
+	handler := &LiveHandler{
+		config:     config,
+		channels:   channels,
+		packagers:  packagers,
+		authorizer: authorizer,
+		upgrader: websocket.Upgrader{
+			// Players are embedded on other origins, access is controlled by the playback token.
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+
+	if playbackConfig.URLSigningSecret != "" {
+		signer := playback.NewSigner(playbackConfig)
+		handler.signer = &signer
+	}
+
+	return handler
+}
@@ -0,0 +1,387 @@
+package handlers
+
+import (
+	"context"
+	"github.com/M-Ro/go-vodstream/internal/avtest"
+	"github.com/M-Ro/go-vodstream/internal/domain/broadcast"
+	"github.com/M-Ro/go-vodstream/internal/domain/user"
+	"github.com/M-Ro/go-vodstream/internal/flvstream"
+	"github.com/M-Ro/go-vodstream/internal/hls"
+	"github.com/M-Ro/go-vodstream/internal/live"
+	"github.com/M-Ro/go-vodstream/internal/playback"
+	"github.com/google/go-cmp/cmp"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/nareix/joy4/format/flv"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testFLVConfig = flvstream.Config{MaxPendingPackets: 1024, WriteTimeout: time.Second}
+
+var testHLSConfig = hls.Config{
+	SegmentDuration:  time.Second,
+	PartDuration:     200 * time.Millisecond,
+	PlaylistSegments: 6,
+	IdleTimeout:      time.Minute,
+}
+
+// registryProvider serves channels from a registry without a cluster.
+type registryProvider struct {
+	channels *live.Registry
+}
+
+func (p registryProvider) Acquire(_ context.Context, name string) (*live.Channel, func(), error) {
+	channel, err := p.channels.Get(name)
+	return channel, func() {}, err
+}
+
+type noUsers struct{}
+
+func (noUsers) GetByUsername(_ context.Context, _ string) (user.User, error) {
+	return user.User{}, io.EOF
+}
+
+// startLiveServer serves a public, a private and a public Low-Latency HLS
+// channel, each fed with packets until the test ends.
+func startLiveServer(t *testing.T, playbackConfig playback.Config) *httptest.Server {
+	channels := live.NewRegistry()
+	stop := make(chan struct{})
+
+	for name, visibility := range map[string]broadcast.Visibility{
+		"public":     broadcast.VisibilityPublic,
+		"private":    broadcast.VisibilityPrivate,
+		"lowlatency": broadcast.VisibilityPublic,
+	} {
+		channel, err := channels.Open(name, 1, visibility, live.WithLowLatency(name == "lowlatency"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := channel.Queue.WriteHeader(avtest.Streams()); err != nil {
+			t.Fatal(err)
+		}
+
+		go func(channel *live.Channel) {
+			// Loop over ten seconds of packets rather than generating them all up front.
+			packets := avtest.Packets(250, 25)
+			loop := 250 * avtest.FrameDuration
+
+			for offset := time.Duration(0); ; offset += loop {
+				for _, packet := range packets {
+					select {
+					case <-stop:
+						channel.Queue.Close()
+						return
+					default:
+					}
+
+					packet.Time += offset
+					channel.Queue.WritePacket(packet)
+					time.Sleep(time.Millisecond)
+				}
+			}
+		}(channel)
+	}
+
+	authorizer := playback.NewAuthorizer(playbackConfig, noUsers{})
+
+	r := mux.NewRouter()
+	NewLiveHandler(
+		testFLVConfig, hls.NewManager(testHLSConfig, nil, registryProvider{channels}), playbackConfig,
+		registryProvider{channels}, authorizer,
+	).RegisterRoutes(r)
+
+	server := httptest.NewServer(r)
+	t.Cleanup(func() {
+		close(stop)
+		server.Close()
+	})
+
+	return server
+}
+
+// readFLV checks the stream starts with a keyframe and yields count packets.
+func readFLV(t *testing.T, r io.Reader, count int) {
+	demuxer := flv.NewDemuxer(r)
+
+	streams, err := demuxer.Streams()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(streams) != len(avtest.Streams()) {
+		t.Fatal(cmp.Diff(len(streams), len(avtest.Streams())))
+	}
+
+	for i := 0; i < count; i++ {
+		packet, err := demuxer.ReadPacket()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if i == 0 && (packet.Idx != avtest.VideoIdx || !packet.IsKeyFrame) {
+			t.Fatal("expected stream to start at a video keyframe")
+		}
+	}
+}
+
+func TestLiveHandler_FLV(t *testing.T) {
+	server := startLiveServer(t, playback.Config{})
+
+	resp, err := http.Get(server.URL + "/live/public/stream.flv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatal(cmp.Diff(resp.StatusCode, http.StatusOK))
+	}
+
+	if contentType := resp.Header.Get("Content-Type"); contentType != "video/x-flv" {
+		t.Fatal(cmp.Diff(contentType, "video/x-flv"))
+	}
+
+	readFLV(t, resp.Body, 50)
+}
+
+func TestLiveHandler_WebSocket(t *testing.T) {
+	server := startLiveServer(t, playback.Config{})
+
+	conn, _, err := websocket.DefaultDialer.Dial(
+		"ws"+strings.TrimPrefix(server.URL, "http")+"/live/public/stream.ws", nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	reader, writer := io.Pipe()
+	go func() {
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				writer.CloseWithError(err)
+				return
+			}
+
+			if messageType != websocket.BinaryMessage {
+				writer.CloseWithError(io.ErrUnexpectedEOF)
+				return
+			}
+
+			if _, err := writer.Write(data); err != nil {
+				return
+			}
+		}
+	}()
+
+	readFLV(t, reader, 50)
+	reader.Close()
+}
+
+func TestLiveHandler_Rejected(t *testing.T) {
+	tests := []struct {
+		testName       string
+		playbackConfig playback.Config
+		path           string
+		expectedStatus int
+	}{
+		{
+			testName:       "expect not found for offline channel",
+			path:           "/live/offline/stream.flv",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			testName:       "expect unauthorized for private channel without token",
+			path:           "/live/private/stream.flv",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			testName:       "expect unauthorized for private channel over websocket without token",
+			path:           "/live/private/stream.ws",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			testName:       "expect unauthorized for private channel playlist without token",
+			path:           "/live/private/index.m3u8",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			testName:       "expect not found for offline channel playlist",
+			path:           "/live/offline/index.m3u8",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			testName:       "expect bad request for part directive without sequence",
+			path:           "/live/lowlatency/index.m3u8?_HLS_part=1",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			testName:       "expect bad request for sequence far beyond the live edge",
+			path:           "/live/lowlatency/index.m3u8?_HLS_msn=100000",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			testName:       "expect forbidden for unsigned url when signing is enabled",
+			playbackConfig: playback.Config{URLSigningSecret: "urlSecret"},
+			path:           "/live/public/stream.flv",
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			server := startLiveServer(t, test.playbackConfig)
+
+			resp, err := http.Get(server.URL + test.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != test.expectedStatus {
+				t.Fatal(cmp.Diff(resp.StatusCode, test.expectedStatus))
+			}
+		})
+	}
+}
+
+// get fetches the path, failing the test unless it responds with 200 OK.
+func get(t *testing.T, url string) []byte {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("%s: %s", resp.Status, body)
+	}
+
+	return body
+}
+
+func TestLiveHandler_HLS(t *testing.T) {
+	server := startLiveServer(t, playback.Config{})
+
+	playlist := string(get(t, server.URL+"/live/public/index.m3u8?session=abc"))
+
+	if !strings.Contains(playlist, "#EXTINF:") {
+		t.Fatalf("expected a complete segment in playlist:\n%s", playlist)
+	}
+
+	if strings.Contains(playlist, "#EXT-X-PART:") {
+		t.Fatalf("expected no partial segments without low latency:\n%s", playlist)
+	}
+
+	if !strings.Contains(playlist, "seg0.ts?session=abc") {
+		t.Fatalf("expected segment URIs to carry the request query:\n%s", playlist)
+	}
+
+	segment := get(t, server.URL+"/live/public/seg0.ts")
+	if len(segment) == 0 || segment[0] != 0x47 {
+		t.Fatal("expected segment to be MPEG-TS")
+	}
+}
+
+func TestLiveHandler_LowLatencyHLS(t *testing.T) {
+	server := startLiveServer(t, playback.Config{})
+
+	playlist := string(get(t, server.URL+"/live/lowlatency/index.m3u8"))
+
+	tags := []string{
+		"#EXT-X-SERVER-CONTROL:CAN-BLOCK-RELOAD=YES", "#EXT-X-PART-INF:", "#EXT-X-PART:", "#EXT-X-PRELOAD-HINT:",
+	}
+
+	for _, tag := range tags {
+		if !strings.Contains(playlist, tag) {
+			t.Fatalf("expected %s in playlist:\n%s", tag, playlist)
+		}
+	}
+
+	// Blocking reloads return once the requested segment has been cut.
+	for sequence := 1; sequence <= 3; sequence++ {
+		playlist = string(get(t, server.URL+"/live/lowlatency/index.m3u8?_HLS_msn="+strconv.Itoa(sequence)))
+		if !strings.Contains(playlist, "seg"+strconv.Itoa(sequence)+".ts") {
+			t.Fatalf("expected segment %d in playlist:\n%s", sequence, playlist)
+		}
+	}
+
+	// The part following the live edge is served once it has been cut.
+	part := get(t, server.URL+"/live/lowlatency/part5.0.ts")
+	if len(part) == 0 || part[0] != 0x47 {
+		t.Fatal("expected part to be MPEG-TS")
+	}
+}
+
+func TestLiveHandler_DASH(t *testing.T) {
+	server := startLiveServer(t, playback.Config{})
+
+	manifest := string(get(t, server.URL+"/live/public/manifest.mpd?session=abc"))
+
+	tags := []string{
+		`type="dynamic"`, `mimeType="video/mp4"`, `mimeType="audio/mp4"`,
+		`initialization="init0.mp4?session=abc"`, `media="seg$Number$.1.m4s?session=abc"`, "<S t=",
+	}
+
+	for _, tag := range tags {
+		if !strings.Contains(manifest, tag) {
+			t.Fatalf("expected %s in manifest:\n%s", tag, manifest)
+		}
+	}
+
+	for track := 0; track < len(avtest.Streams()); track++ {
+		init := get(t, server.URL+"/live/public/init"+strconv.Itoa(track)+".mp4")
+		if len(init) < 8 || string(init[4:8]) != "ftyp" {
+			t.Fatalf("expected track %d init segment to start with ftyp", track)
+		}
+
+		fragment := get(t, server.URL+"/live/public/seg0."+strconv.Itoa(track)+".m4s")
+		if len(fragment) < 8 || string(fragment[4:8]) != "moof" {
+			t.Fatalf("expected track %d fragment to start with moof", track)
+		}
+	}
+}
+
+func TestLiveHandler_FMP4HLS(t *testing.T) {
+	server := startLiveServer(t, playback.Config{})
+
+	multivariant := string(get(t, server.URL+"/live/public/master.m3u8"))
+
+	for _, tag := range []string{`#EXT-X-MEDIA:TYPE=AUDIO`, `URI="track1.m3u8"`, "#EXT-X-STREAM-INF:", "track0.m3u8"} {
+		if !strings.Contains(multivariant, tag) {
+			t.Fatalf("expected %s in multivariant playlist:\n%s", tag, multivariant)
+		}
+	}
+
+	playlist := string(get(t, server.URL+"/live/public/track0.m3u8"))
+
+	for _, tag := range []string{`#EXT-X-MAP:URI="init0.mp4"`, "seg0.0.m4s"} {
+		if !strings.Contains(playlist, tag) {
+			t.Fatalf("expected %s in track playlist:\n%s", tag, playlist)
+		}
+	}
+
+	resp, err := http.Get(server.URL + "/live/public/init2.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatal(cmp.Diff(resp.StatusCode, http.StatusNotFound))
+	}
+}
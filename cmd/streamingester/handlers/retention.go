@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/M-Ro/go-vodstream/api"
+	"github.com/M-Ro/go-vodstream/internal/playback"
+	"github.com/M-Ro/go-vodstream/internal/retention"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"net/http"
+)
+
+var (
+	ErrPinInvalidVideo = errors.New("video does not exist")
+)
+
+// RetentionHandler lets broadcasters pin their videos, keeping them regardless
+// of retention rules.
+type RetentionHandler struct {
+	janitor    *retention.Janitor
+	authorizer playback.Authorizer
+}
+
+func (h *RetentionHandler) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc("/v1/videos/{id}/pin", h.Pin).Methods(http.MethodPost)
+	r.HandleFunc("/v1/videos/{id}/unpin", h.Unpin).Methods(http.MethodPost)
+}
+
+// writeVideoPinResponse encodes the response, filling the error list when err is set.
+func writeVideoPinResponse(w http.ResponseWriter, status int, response api.VideoPinResponse, err error) {
+	response.Errors = []string{}
+	if err != nil {
+		response.Errors = append(response.Errors, err.Error())
+	}
+
+	writeJSON(w, status, "Video pin", response)
+}
+
+// Pin keeps a video of the authenticated broadcaster regardless of retention rules.
+func (h *RetentionHandler) Pin(w http.ResponseWriter, r *http.Request) {
+	h.setPinned(w, r, true)
+}
+
+// Unpin subjects a pinned video of the authenticated broadcaster to retention rules again.
+func (h *RetentionHandler) Unpin(w http.ResponseWriter, r *http.Request) {
+	h.setPinned(w, r, false)
+}
+
+func (h *RetentionHandler) setPinned(w http.ResponseWriter, r *http.Request, pinned bool) {
+	var pinRequest api.VideoPinRequest
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Errorf("Video pin failed: %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &pinRequest); err != nil {
+			writeVideoPinResponse(w, http.StatusBadRequest, api.VideoPinResponse{}, err)
+			return
+		}
+	}
+
+	sessionToken := pinRequest.Auth.AccessToken
+	if sessionToken == "" {
+		sessionToken = playback.TokenFromRequest(r)
+	}
+
+	broadcaster, err := h.authorizer.Authenticate(r.Context(), sessionToken)
+	if err != nil {
+		writeVideoPinResponse(w, http.StatusUnauthorized, api.VideoPinResponse{}, err)
+		return
+	}
+
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeVideoPinResponse(w, http.StatusNotFound, api.VideoPinResponse{}, ErrPinInvalidVideo)
+		return
+	}
+
+	video, err := h.janitor.Video(r.Context(), id)
+	if err != nil {
+		writeVideoPinResponse(w, http.StatusNotFound, api.VideoPinResponse{}, ErrPinInvalidVideo)
+		return
+	}
+
+	// Broadcasters may only pin their own videos.
+	if video.BroadcasterId != broadcaster.Id {
+		writeVideoPinResponse(w, http.StatusForbidden, api.VideoPinResponse{}, playback.ErrNotPermitted)
+		return
+	}
+
+	video, err = h.janitor.Pin(r.Context(), video, pinned)
+	if err != nil {
+		log.Errorf("Video pin failed: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	response := api.VideoPinResponse{Success: true, VideoID: video.Id.String(), Pinned: video.Pinned}
+	writeVideoPinResponse(w, http.StatusOK, response, nil)
+}
+
+// NewRetentionHandler instantiates a new RetentionHandler.
+func NewRetentionHandler(janitor *retention.Janitor, authorizer playback.Authorizer) *RetentionHandler {
+	return &RetentionHandler{
+		janitor:    janitor,
+		authorizer: authorizer,
+	}
+}
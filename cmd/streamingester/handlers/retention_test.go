@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/M-Ro/go-vodstream/api"
+	"github.com/M-Ro/go-vodstream/internal/blob"
+	"github.com/M-Ro/go-vodstream/internal/playback"
+	"github.com/M-Ro/go-vodstream/internal/retention"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/spf13/afero"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// retentionVideos stores videos in memory for the janitor.
+type retentionVideos struct {
+	editVideos
+}
+
+func (v retentionVideos) All(_ context.Context) ([]storage.Video, error) {
+	videos := make([]storage.Video, 0, len(v.clipVideos))
+	for _, video := range v.clipVideos {
+		videos = append(videos, video)
+	}
+
+	return videos, nil
+}
+
+func (v retentionVideos) Update(_ context.Context, id uuid.UUID, video *storage.Video) error {
+	v.clipVideos[id] = *video
+	return nil
+}
+
+func TestRetentionHandler_Pin(t *testing.T) {
+	videos := retentionVideos{editVideos{clipVideos{}}}
+
+	video := storage.Video{Title: "test", BroadcasterId: 1, FilePath: "test.flv"}
+	if err := videos.Insert(context.Background(), &video); err != nil {
+		t.Fatal(err)
+	}
+
+	janitor := retention.NewJanitor(
+		retention.Config{}, blob.NewLocalStore(afero.NewMemMapFs()), videos, nil, nil, storage.Immediate{},
+	)
+	authorizer := playback.NewAuthorizer(testClipPlaybackConfig, testClipUsers)
+
+	r := mux.NewRouter()
+	NewRetentionHandler(janitor, authorizer).RegisterRoutes(r)
+
+	tests := []struct {
+		testName string
+		username string
+		path     string
+
+		respStatus int
+		respErrors []string
+		pinned     bool
+	}{
+		{
+			testName:   "Expect success (200) for broadcaster pinning their video.",
+			username:   "broadcaster",
+			path:       "/v1/videos/" + video.Id.String() + "/pin",
+			respStatus: 200,
+			respErrors: []string{},
+			pinned:     true,
+		},
+		{
+			testName:   "Expect error (403) for viewer unpinning another broadcaster's video.",
+			username:   "viewer",
+			path:       "/v1/videos/" + video.Id.String() + "/unpin",
+			respStatus: 403,
+			respErrors: []string{playback.ErrNotPermitted.Error()},
+			pinned:     true,
+		},
+		{
+			testName:   "Expect error (404) for an unknown video.",
+			username:   "broadcaster",
+			path:       "/v1/videos/" + uuid.New().String() + "/pin",
+			respStatus: 404,
+			respErrors: []string{ErrPinInvalidVideo.Error()},
+			pinned:     true,
+		},
+		{
+			testName:   "Expect success (200) for broadcaster unpinning their video.",
+			username:   "broadcaster",
+			path:       "/v1/videos/" + video.Id.String() + "/unpin",
+			respStatus: 200,
+			respErrors: []string{},
+			pinned:     false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			b, err := json.Marshal(api.VideoPinRequest{
+				Auth: api.AuthenticationSet{AccessToken: clipSessionToken(t, test.username)},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			recorder := httptest.NewRecorder()
+			r.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, test.path, bytes.NewReader(b)))
+			resp := recorder.Result()
+
+			result := api.VideoPinResponse{}
+			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.StatusCode != test.respStatus {
+				t.Fatal(cmp.Diff(resp.StatusCode, test.respStatus))
+			}
+
+			if !cmp.Equal(result.Errors, test.respErrors) {
+				t.Fatal(cmp.Diff(result.Errors, test.respErrors))
+			}
+
+			if stored := videos.clipVideos[video.Id].Pinned; stored != test.pinned {
+				t.Fatal(cmp.Diff(stored, test.pinned))
+			}
+		})
+	}
+
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/v1/videos/"+video.Id.String()+"/pin", nil))
+
+	if recorder.Code != http.StatusUnauthorized {
+		t.Fatal(cmp.Diff(recorder.Code, http.StatusUnauthorized))
+	}
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"github.com/M-Ro/go-vodstream/internal/blob"
+	"github.com/M-Ro/go-vodstream/internal/playback"
+	"github.com/M-Ro/go-vodstream/internal/thumbnail"
+	"github.com/M-Ro/go-vodstream/internal/urlsign"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ThumbnailHandler serves the thumbnails of live channels, and the posters and
+// scrub preview sprites of videos, beside the media they are rendered from.
+type ThumbnailHandler struct {
+	thumbnails  *thumbnail.Generator
+	media       blob.Store
+	urlDuration time.Duration
+	videos      VideoProvider
+	authorizer  playback.Authorizer
+	signer      *urlsign.Signer
+}
+
+func (h *ThumbnailHandler) RegisterRoutes(r *mux.Router) {
+	l := r.PathPrefix("/live/{name}").Subrouter()
+	v := r.PathPrefix("/vod/{id}").Subrouter()
+	if h.signer != nil {
+		l.Use(h.signer.Middleware)
+		v.Use(h.signer.Middleware)
+	}
+
+	l.HandleFunc("/thumbnail.jpg", h.Live).Methods(http.MethodGet)
+	v.HandleFunc("/poster.jpg", h.Poster).Methods(http.MethodGet)
+	v.HandleFunc("/thumbnails.vtt", h.Sprites).Methods(http.MethodGet)
+	v.HandleFunc("/sprite-{sheet:[0-9]+}.jpg", h.Sprite).Methods(http.MethodGet)
+}
+
+// Live serves the latest thumbnail of a live channel to viewers permitted to play it.
+func (h *ThumbnailHandler) Live(w http.ResponseWriter, r *http.Request) {
+	live, err := h.thumbnails.Live(mux.Vars(r)["name"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	resource := playback.Resource{
+		Name:          live.Channel.Name,
+		BroadcasterId: live.Channel.BroadcasterId,
+		Visibility:    live.Channel.Visibility,
+	}
+
+	if _, err := h.authorizer.Authorize(r.Context(), resource, playback.TokenFromRequest(r)); err != nil {
+		http.Error(w, err.Error(), playback.StatusCode(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	http.ServeContent(w, r, "thumbnail.jpg", live.UpdatedAt, bytes.NewReader(live.Image))
+}
+
+// video returns the requested video, writing an error response if it does not
+// exist or is unpublished and the viewer is not its broadcaster.
+func (h *ThumbnailHandler) video(w http.ResponseWriter, r *http.Request) (storage.Video, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return storage.Video{}, false
+	}
+
+	video := h.videos.GetByID(r.Context(), id)
+	if video == nil || video.FilePath == "" || !visible(r, h.authorizer, *video) {
+		http.NotFound(w, r)
+		return storage.Video{}, false
+	}
+
+	return *video, true
+}
+
+// renderError reports a failure to render a video's imagery. Videos without a
+// video stream have no imagery to render.
+func renderError(w http.ResponseWriter, r *http.Request, video storage.Video, err error) {
+	if errors.Is(err, thumbnail.ErrNoVideo) || errors.Is(err, blob.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+
+	log.Errorf("Couldn't render imagery of video %s: %v", video.Id, err)
+	w.WriteHeader(http.StatusInternalServerError)
+}
+
+// Poster serves the poster of a video, rendering it on first request.
+func (h *ThumbnailHandler) Poster(w http.ResponseWriter, r *http.Request) {
+	video, ok := h.video(w, r)
+	if !ok {
+		return
+	}
+
+	key, err := h.thumbnails.Poster(r.Context(), video)
+	if err != nil {
+		renderError(w, r, video, err)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	serveObject(w, r, h.media, key, h.urlDuration)
+}
+
+// Sprites serves the WebVTT track of a video's sprite sheets, rendering them
+// on first request. The request's query is carried to the sheets, so they are
+// covered by the same URL signature.
+func (h *ThumbnailHandler) Sprites(w http.ResponseWriter, r *http.Request) {
+	video, ok := h.video(w, r)
+	if !ok {
+		return
+	}
+
+	key, err := h.thumbnails.Sprites(r.Context(), video)
+	if err != nil {
+		renderError(w, r, video, err)
+		return
+	}
+
+	reader, err := h.media.Get(r.Context(), key)
+	if err != nil {
+		renderError(w, r, video, err)
+		return
+	}
+	defer reader.Close()
+
+	track, err := ioutil.ReadAll(reader)
+	if err != nil {
+		renderError(w, r, video, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/vtt")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Write(thumbnail.WithQuery(track, r.URL.RawQuery))
+}
+
+// Sprite serves one of a video's sprite sheets, once rendered with its track.
+func (h *ThumbnailHandler) Sprite(w http.ResponseWriter, r *http.Request) {
+	video, ok := h.video(w, r)
+	if !ok {
+		return
+	}
+
+	sheet, err := strconv.Atoi(mux.Vars(r)["sheet"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	key, err := h.thumbnails.Sheet(r.Context(), video, sheet)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	serveObject(w, r, h.media, key, h.urlDuration)
+}
+
+// NewThumbnailHandler instantiates a new ThumbnailHandler serving video imagery
+// from media. Video imagery URLs must be signed when the playback config has a
+// URL signing secret.
+func NewThumbnailHandler(
+	thumbnails *thumbnail.Generator, media blob.Store, playbackConfig playback.Config, videos VideoProvider,
+	authorizer playback.Authorizer,
+) *ThumbnailHandler {
+	handler := &ThumbnailHandler{
+		thumbnails:  thumbnails,
+		media:       media,
+		urlDuration: playbackConfig.URLDuration,
+		videos:      videos,
+		authorizer:  authorizer,
+	}
+
+	if playbackConfig.URLSigningSecret != "" {
+		signer := playback.NewSigner(playbackConfig)
+		handler.signer = &signer
+	}
+
+	return handler
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"github.com/M-Ro/go-vodstream/internal/avtest"
+	"github.com/M-Ro/go-vodstream/internal/blob"
+	"github.com/M-Ro/go-vodstream/internal/domain/broadcast"
+	"github.com/M-Ro/go-vodstream/internal/live"
+	"github.com/M-Ro/go-vodstream/internal/playback"
+	"github.com/M-Ro/go-vodstream/internal/thumbnail"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/spf13/afero"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testThumbnailConfig = thumbnail.Config{
+	Width:          64,
+	Quality:        80,
+	LiveInterval:   time.Second,
+	PosterOffset:   time.Second,
+	SpriteInterval: time.Second,
+	SpriteWidth:    16,
+	SpriteColumns:  2,
+	SpriteRows:     2,
+	Timeout:        time.Second,
+}
+
+// startThumbnailServer serves the imagery of a single published video of 100
+// frames beside the video itself, and the thumbnails of live channels.
+func startThumbnailServer(t *testing.T) (*httptest.Server, *thumbnail.Generator, uuid.UUID) {
+	return startVideoServer(t, true)
+}
+
+// startVideoServer serves a single recorded video of 100 frames by the
+// broadcaster, published or not, with its imagery.
+func startVideoServer(t *testing.T, published bool) (*httptest.Server, *thumbnail.Generator, uuid.UUID) {
+	media := blob.NewLocalStore(afero.NewMemMapFs())
+	video := writeRecording(t, media, "test.flv", 100)
+	video.BroadcasterId, video.IsPublished = 1, published
+
+	videos := videoMap{video.Id: video}
+	generator := thumbnail.NewGenerator(testThumbnailConfig, media, thumbnail.NoopExtractor{})
+	authorizer := playback.NewAuthorizer(testClipPlaybackConfig, testClipUsers)
+
+	// Imagery is served beside the video, under the same prefix.
+	r := mux.NewRouter()
+	NewVodHandler(media, playback.Config{}, videos, authorizer).RegisterRoutes(r)
+	NewThumbnailHandler(generator, media, playback.Config{}, videos, authorizer).RegisterRoutes(r)
+
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+
+	return server, generator, video.Id
+}
+
+func TestThumbnailHandler_Video(t *testing.T) {
+	server, _, id := startThumbnailServer(t)
+	prefix := "/vod/" + id.String()
+
+	tests := []struct {
+		testName    string
+		path        string
+		respStatus  int
+		contentType string
+	}{
+		{testName: "poster", path: prefix + "/poster.jpg", respStatus: 200, contentType: "image/jpeg"},
+		{testName: "sprite sheet before its track", path: prefix + "/sprite-0.jpg", respStatus: 404},
+		{testName: "sprite track", path: prefix + "/thumbnails.vtt?exp=1", respStatus: 200, contentType: "text/vtt"},
+		{testName: "sprite sheet", path: prefix + "/sprite-0.jpg", respStatus: 200, contentType: "image/jpeg"},
+		{testName: "missing sprite sheet", path: prefix + "/sprite-1.jpg", respStatus: 404},
+		{testName: "unknown video", path: "/vod/" + uuid.New().String() + "/poster.jpg", respStatus: 404},
+		{testName: "video beside imagery", path: prefix + "/video.flv", respStatus: 200, contentType: "video/x-flv"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			resp, err := http.Get(server.URL + test.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if !cmp.Equal(resp.StatusCode, test.respStatus) {
+				t.Fatal(cmp.Diff(resp.StatusCode, test.respStatus))
+			}
+
+			if test.contentType != "" && resp.Header.Get("Content-Type") != test.contentType {
+				t.Fatal(cmp.Diff(resp.Header.Get("Content-Type"), test.contentType))
+			}
+		})
+	}
+
+	// 3.96 seconds of video make 4 one second sprites, all on the first sheet.
+	body := get(t, server.URL+prefix+"/thumbnails.vtt?exp=1")
+	if count := strings.Count(string(body), "sprite-0.jpg?exp=1#xywh="); count != 4 {
+		t.Fatalf("expected 4 cues carrying the query:\n%s", body)
+	}
+}
+
+func TestThumbnailHandler_Unpublished(t *testing.T) {
+	server, _, id := startVideoServer(t, false)
+	prefix := "/vod/" + id.String()
+
+	tests := []struct {
+		testName   string
+		path       string
+		username   string
+		respStatus int
+	}{
+		{testName: "poster without a token", path: prefix + "/poster.jpg", respStatus: 404},
+		{testName: "poster for a viewer", path: prefix + "/poster.jpg", username: "viewer", respStatus: 404},
+		{testName: "poster for the broadcaster", path: prefix + "/poster.jpg", username: "broadcaster", respStatus: 200},
+		{testName: "sprite track for a viewer", path: prefix + "/thumbnails.vtt", username: "viewer", respStatus: 404},
+		{
+			testName: "sprite track for the broadcaster", path: prefix + "/thumbnails.vtt", username: "broadcaster",
+			respStatus: 200,
+		},
+		{testName: "sprite sheet for a viewer", path: prefix + "/sprite-0.jpg", username: "viewer", respStatus: 404},
+		{testName: "video without a token", path: prefix + "/video.flv", respStatus: 404},
+		{testName: "video for a viewer", path: prefix + "/video.flv?start=1", username: "viewer", respStatus: 404},
+		{testName: "video for the broadcaster", path: prefix + "/video.flv", username: "broadcaster", respStatus: 200},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, server.URL+test.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if test.username != "" {
+				req.Header.Set("Authorization", "Bearer "+clipSessionToken(t, test.username))
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if !cmp.Equal(resp.StatusCode, test.respStatus) {
+				t.Fatal(cmp.Diff(resp.StatusCode, test.respStatus))
+			}
+		})
+	}
+}
+
+func TestThumbnailHandler_Live(t *testing.T) {
+	server, generator, _ := startThumbnailServer(t)
+	channels := live.NewRegistry()
+
+	for _, channel := range []struct {
+		name       string
+		visibility broadcast.Visibility
+	}{
+		{name: "public", visibility: broadcast.VisibilityPublic},
+		{name: "private", visibility: broadcast.VisibilityPrivate},
+	} {
+		opened, err := channels.Open(channel.name, 1, channel.visibility)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer channels.Close(opened)
+
+		if err := opened.Queue.WriteHeader(avtest.Streams()); err != nil {
+			t.Fatal(err)
+		}
+
+		generator.Start(opened)
+		time.Sleep(20 * time.Millisecond)
+
+		for _, packet := range avtest.Packets(25, 25) {
+			opened.Queue.WritePacket(packet)
+		}
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	tests := []struct {
+		testName   string
+		path       string
+		respStatus int
+	}{
+		{testName: "public channel", path: "/live/public/thumbnail.jpg", respStatus: 200},
+		{testName: "private channel without a token", path: "/live/private/thumbnail.jpg", respStatus: 401},
+		{testName: "offline channel", path: "/live/offline/thumbnail.jpg", respStatus: 404},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			resp, err := http.Get(server.URL + test.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if !cmp.Equal(resp.StatusCode, test.respStatus) {
+				t.Fatal(cmp.Diff(resp.StatusCode, test.respStatus))
+			}
+		})
+	}
+}
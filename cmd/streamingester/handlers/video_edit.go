@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/M-Ro/go-vodstream/api"
+	"github.com/M-Ro/go-vodstream/internal/domain/edit"
+	videoEdit "github.com/M-Ro/go-vodstream/internal/edit"
+	"github.com/M-Ro/go-vodstream/internal/playback"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrEditMissingVideo     = errors.New("a video is required")
+	ErrEditInvalidVideo     = errors.New("video does not exist")
+	ErrEditInvalidOperation = errors.New("operation must be one of trim, split or merge")
+	ErrEditInvalidEdit      = errors.New("edit does not exist")
+)
+
+// VideoEditHandler lets broadcasters trim, split and merge their videos, then
+// confirm or discard the results.
+type VideoEditHandler struct {
+	editor     *videoEdit.Editor
+	edits      videoEdit.Repository
+	authorizer playback.Authorizer
+}
+
+func (h *VideoEditHandler) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc("/v1/edits", h.Create).Methods(http.MethodPost)
+	r.HandleFunc("/v1/edits/{id}", h.Get).Methods(http.MethodGet)
+	r.HandleFunc("/v1/edits/{id}/confirm", h.Confirm).Methods(http.MethodPost)
+	r.HandleFunc("/v1/edits/{id}/discard", h.Discard).Methods(http.MethodPost)
+}
+
+// editToAPI converts an edit to its API representation, describing whichever
+// of its results still exist.
+func editToAPI(e edit.Edit, results []storage.Video) api.VideoEdit {
+	converted := api.VideoEdit{
+		ID:            e.Id.String(),
+		BroadcasterID: e.BroadcasterId,
+		Operation:     string(e.Operation),
+		Status:        string(e.Status),
+		SourceIDs:     make([]string, 0, len(e.Sources)),
+		Results:       make([]api.EditedVideo, 0, len(results)),
+		CreatedAt:     e.CreatedAt,
+		UpdatedAt:     e.UpdatedAt,
+	}
+
+	for _, id := range e.Sources {
+		converted.SourceIDs = append(converted.SourceIDs, id.String())
+	}
+
+	for _, video := range results {
+		converted.Results = append(converted.Results, api.EditedVideo{
+			ID:     video.Id.String(),
+			Title:  video.Title,
+			Length: (time.Duration(video.Length) * time.Millisecond).Seconds(),
+			Path:   playback.VodPathPrefix(video.Id),
+		})
+	}
+
+	return converted
+}
+
+// writeVideoEditResponse encodes the response, filling the error list when err is set.
+func writeVideoEditResponse(w http.ResponseWriter, status int, response api.VideoEditResponse, err error) {
+	response.Errors = []string{}
+	if err != nil {
+		response.Errors = append(response.Errors, err.Error())
+	}
+
+	if response.Edit.SourceIDs == nil {
+		response.Edit.SourceIDs = []string{}
+	}
+
+	if response.Edit.Results == nil {
+		response.Edit.Results = []api.EditedVideo{}
+	}
+
+	writeJSON(w, status, "Video edit", response)
+}
+
+// writeError reports an editor error to the client, logging failures that are
+// not the client's against action.
+func (h *VideoEditHandler) writeError(w http.ResponseWriter, err error, action string) {
+	switch {
+	case errors.Is(err, videoEdit.ErrInvalidRange), errors.Is(err, videoEdit.ErrTooFewVideos),
+		errors.Is(err, videoEdit.ErrMixedBroadcasters), errors.Is(err, videoEdit.ErrIncompatible):
+		writeVideoEditResponse(w, http.StatusBadRequest, api.VideoEditResponse{}, err)
+	case errors.Is(err, videoEdit.ErrNotPending):
+		writeVideoEditResponse(w, http.StatusConflict, api.VideoEditResponse{}, err)
+	default:
+		log.Errorf("%s failed: %v", action, err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// Create trims, splits or merges videos of the authenticated broadcaster,
+// publishing the results as new videos pending confirmation.
+func (h *VideoEditHandler) Create(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Errorf("Video edit failed: %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var editRequest api.VideoEditRequest
+	err = json.Unmarshal(body, &editRequest)
+	if err != nil {
+		writeVideoEditResponse(w, http.StatusBadRequest, api.VideoEditResponse{}, err)
+		return
+	}
+
+	sessionToken := editRequest.Auth.AccessToken
+	if sessionToken == "" {
+		sessionToken = playback.TokenFromRequest(r)
+	}
+
+	broadcaster, err := h.authorizer.Authenticate(r.Context(), sessionToken)
+	if err != nil {
+		writeVideoEditResponse(w, http.StatusUnauthorized, api.VideoEditResponse{}, err)
+		return
+	}
+
+	if len(editRequest.VideoIDs) == 0 {
+		writeVideoEditResponse(w, http.StatusBadRequest, api.VideoEditResponse{}, ErrEditMissingVideo)
+		return
+	}
+
+	videos := make([]storage.Video, 0, len(editRequest.VideoIDs))
+	for _, videoId := range editRequest.VideoIDs {
+		id, err := uuid.Parse(videoId)
+		if err != nil {
+			writeVideoEditResponse(w, http.StatusBadRequest, api.VideoEditResponse{}, ErrEditInvalidVideo)
+			return
+		}
+
+		video, err := h.editor.Video(r.Context(), id)
+		if err != nil {
+			writeVideoEditResponse(w, http.StatusNotFound, api.VideoEditResponse{}, ErrEditInvalidVideo)
+			return
+		}
+
+		// Broadcasters may only edit their own videos.
+		if video.BroadcasterId != broadcaster.Id {
+			writeVideoEditResponse(w, http.StatusForbidden, api.VideoEditResponse{}, playback.ErrNotPermitted)
+			return
+		}
+
+		videos = append(videos, video)
+	}
+
+	var created edit.Edit
+	switch edit.Operation(editRequest.Operation) {
+	case edit.OperationTrim:
+		created, err = h.editor.Trim(r.Context(), videos[0], seconds(editRequest.Start), seconds(editRequest.End))
+	case edit.OperationSplit:
+		at := make([]time.Duration, 0, len(editRequest.At))
+		for _, offset := range editRequest.At {
+			at = append(at, seconds(offset))
+		}
+
+		created, err = h.editor.Split(r.Context(), videos[0], at)
+	case edit.OperationMerge:
+		created, err = h.editor.Merge(r.Context(), videos)
+	default:
+		writeVideoEditResponse(w, http.StatusBadRequest, api.VideoEditResponse{}, ErrEditInvalidOperation)
+		return
+	}
+
+	if err != nil {
+		h.writeError(w, err, "Video edit "+editRequest.Operation)
+		return
+	}
+
+	response := api.VideoEditResponse{
+		Success: true,
+		Edit:    editToAPI(created, h.editor.Videos(r.Context(), created.Results)),
+	}
+
+	writeVideoEditResponse(w, http.StatusOK, response, nil)
+}
+
+// owned authenticates the request and returns the edit named in its path, if
+// it belongs to the authenticated broadcaster. Failures are written to w.
+func (h *VideoEditHandler) owned(w http.ResponseWriter, r *http.Request, sessionToken string) (edit.Edit, bool) {
+	if sessionToken == "" {
+		sessionToken = playback.TokenFromRequest(r)
+	}
+
+	broadcaster, err := h.authorizer.Authenticate(r.Context(), sessionToken)
+	if err != nil {
+		writeVideoEditResponse(w, http.StatusUnauthorized, api.VideoEditResponse{}, err)
+		return edit.Edit{}, false
+	}
+
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeVideoEditResponse(w, http.StatusNotFound, api.VideoEditResponse{}, ErrEditInvalidEdit)
+		return edit.Edit{}, false
+	}
+
+	found, err := h.edits.GetByID(r.Context(), id)
+	if err != nil {
+		writeVideoEditResponse(w, http.StatusNotFound, api.VideoEditResponse{}, ErrEditInvalidEdit)
+		return edit.Edit{}, false
+	}
+
+	if found.BroadcasterId != broadcaster.Id {
+		writeVideoEditResponse(w, http.StatusForbidden, api.VideoEditResponse{}, playback.ErrNotPermitted)
+		return edit.Edit{}, false
+	}
+
+	return found, true
+}
+
+// Get describes an edit of the authenticated broadcaster's videos.
+func (h *VideoEditHandler) Get(w http.ResponseWriter, r *http.Request) {
+	found, ok := h.owned(w, r, "")
+	if !ok {
+		return
+	}
+
+	response := api.VideoEditResponse{
+		Success: true,
+		Edit:    editToAPI(found, h.editor.Videos(r.Context(), found.Results)),
+	}
+
+	writeVideoEditResponse(w, http.StatusOK, response, nil)
+}
+
+// resolve confirms or discards a pending edit with the given editor method.
+func (h *VideoEditHandler) resolve(
+	w http.ResponseWriter, r *http.Request, action string, apply func(context.Context, edit.Edit) (edit.Edit, error),
+) {
+	var actionRequest api.VideoEditActionRequest
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Errorf("Video edit %s failed: %v\n", action, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &actionRequest); err != nil {
+			writeVideoEditResponse(w, http.StatusBadRequest, api.VideoEditResponse{}, err)
+			return
+		}
+	}
+
+	found, ok := h.owned(w, r, actionRequest.Auth.AccessToken)
+	if !ok {
+		return
+	}
+
+	resolved, err := apply(r.Context(), found)
+	if err != nil {
+		h.writeError(w, err, "Video edit "+action+" of "+found.Id.String())
+		return
+	}
+
+	response := api.VideoEditResponse{
+		Success: true,
+		Edit:    editToAPI(resolved, h.editor.Videos(r.Context(), resolved.Results)),
+	}
+
+	writeVideoEditResponse(w, http.StatusOK, response, nil)
+}
+
+// Confirm replaces the sources of a pending edit with its results.
+func (h *VideoEditHandler) Confirm(w http.ResponseWriter, r *http.Request) {
+	h.resolve(w, r, "confirm", h.editor.Confirm)
+}
+
+// Discard abandons a pending edit, deleting its results.
+func (h *VideoEditHandler) Discard(w http.ResponseWriter, r *http.Request) {
+	h.resolve(w, r, "discard", h.editor.Discard)
+}
+
+// NewVideoEditHandler instantiates a new VideoEditHandler.
+func NewVideoEditHandler(
+	editor *videoEdit.Editor, edits videoEdit.Repository, authorizer playback.Authorizer,
+) *VideoEditHandler {
+	return &VideoEditHandler{
+		editor:     editor,
+		edits:      edits,
+		authorizer: authorizer,
+	}
+}
@@ -0,0 +1,339 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/M-Ro/go-vodstream/api"
+	"github.com/M-Ro/go-vodstream/internal/blob"
+	videoEdit "github.com/M-Ro/go-vodstream/internal/edit"
+	"github.com/M-Ro/go-vodstream/internal/playback"
+	"github.com/M-Ro/go-vodstream/internal/recording"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/spf13/afero"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// editVideos stores videos in memory, including those edits are published as.
+type editVideos struct {
+	clipVideos
+}
+
+func (v editVideos) Delete(_ context.Context, id uuid.UUID) error {
+	delete(v.clipVideos, id)
+	return nil
+}
+
+// editVods links none of the edited videos to a broadcast.
+type editVods struct{}
+
+func (editVods) GetByVideoID(_ context.Context, _ uuid.UUID) ([]storage.BroadcastVod, error) {
+	return nil, nil
+}
+
+func (editVods) Insert(_ context.Context, vod *storage.BroadcastVod) error {
+	vod.Id = uuid.New()
+	return nil
+}
+
+func (editVods) DeleteByVideoID(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+
+// editStorage stores video edits in memory.
+type editStorage map[uuid.UUID]storage.VideoEdit
+
+func (s editStorage) GetByID(_ context.Context, id uuid.UUID) *storage.VideoEdit {
+	e, ok := s[id]
+	if !ok {
+		return nil
+	}
+	return &e
+}
+
+func (s editStorage) Insert(_ context.Context, e *storage.VideoEdit) error {
+	e.Id = uuid.New()
+	e.CreatedAt = time.Now()
+	e.UpdatedAt = e.CreatedAt
+	s[e.Id] = *e
+	return nil
+}
+
+func (s editStorage) Update(_ context.Context, id uuid.UUID, e *storage.VideoEdit) error {
+	e.UpdatedAt = time.Now()
+	s[id] = *e
+	return nil
+}
+
+// newVideoEditRouter serves edits of two recorded videos of 100 frames, one by
+// the broadcaster and one by the viewer.
+func newVideoEditRouter(t *testing.T) (*mux.Router, editVideos, []uuid.UUID) {
+	media := blob.NewLocalStore(afero.NewMemMapFs())
+	videos := editVideos{clipVideos{}}
+
+	ids := make([]uuid.UUID, 0, 2)
+	for _, broadcasterId := range []uint64{1, 2} {
+		video := writeRecording(t, media, uuid.New().String()+".flv", 100)
+		video.Title, video.BroadcasterId = "test", broadcasterId
+
+		if err := videos.Insert(context.Background(), &video); err != nil {
+			t.Fatal(err)
+		}
+
+		ids = append(ids, video.Id)
+	}
+
+	edits := videoEdit.NewRepository(editStorage{})
+	editor := videoEdit.NewEditor(
+		recording.Config{SpoolDirectory: t.TempDir()}, media, videos, editVods{}, edits, storage.Immediate{},
+	)
+	authorizer := playback.NewAuthorizer(testClipPlaybackConfig, testClipUsers)
+
+	r := mux.NewRouter()
+	NewVideoEditHandler(editor, edits, authorizer).RegisterRoutes(r)
+
+	return r, videos, ids
+}
+
+// serveVideoEdit sends the request body to path, decoding the response.
+func serveVideoEdit(t *testing.T, r *mux.Router, path string, reqBody interface{}) (int, api.VideoEditResponse) {
+	b, err := json.Marshal(reqBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(b)))
+	resp := recorder.Result()
+
+	result := api.VideoEditResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+
+	return resp.StatusCode, result
+}
+
+func TestVideoEditHandler_Create(t *testing.T) {
+	r, _, ids := newVideoEditRouter(t)
+	own, other := ids[0].String(), ids[1].String()
+
+	tests := []struct {
+		testName string
+		reqBody  api.VideoEditRequest
+
+		respStatus  int
+		respErrors  []string
+		respResults int
+	}{
+		{
+			testName: "Expect success (200) for broadcaster trimming their video.",
+			reqBody: api.VideoEditRequest{
+				Auth:      api.AuthenticationSet{AccessToken: clipSessionToken(t, "broadcaster")},
+				Operation: "trim",
+				VideoIDs:  []string{own},
+				Start:     1,
+				End:       3,
+			},
+			respStatus:  200,
+			respErrors:  []string{},
+			respResults: 1,
+		},
+		{
+			testName: "Expect success (200) for broadcaster splitting their video.",
+			reqBody: api.VideoEditRequest{
+				Auth:      api.AuthenticationSet{AccessToken: clipSessionToken(t, "broadcaster")},
+				Operation: "split",
+				VideoIDs:  []string{own},
+				At:        []float64{1.5, 3},
+			},
+			respStatus:  200,
+			respErrors:  []string{},
+			respResults: 3,
+		},
+		{
+			testName: "Expect error (401) without a session token.",
+			reqBody: api.VideoEditRequest{
+				Operation: "trim",
+				VideoIDs:  []string{own},
+				End:       1,
+			},
+			respStatus: 401,
+			respErrors: []string{playback.ErrTokenInvalid.Error()},
+		},
+		{
+			testName: "Expect error (403) for broadcaster merging another user's video.",
+			reqBody: api.VideoEditRequest{
+				Auth:      api.AuthenticationSet{AccessToken: clipSessionToken(t, "broadcaster")},
+				Operation: "merge",
+				VideoIDs:  []string{own, other},
+			},
+			respStatus: 403,
+			respErrors: []string{playback.ErrNotPermitted.Error()},
+		},
+		{
+			testName: "Expect error (404) for unknown video.",
+			reqBody: api.VideoEditRequest{
+				Auth:      api.AuthenticationSet{AccessToken: clipSessionToken(t, "broadcaster")},
+				Operation: "trim",
+				VideoIDs:  []string{uuid.New().String()},
+				End:       1,
+			},
+			respStatus: 404,
+			respErrors: []string{ErrEditInvalidVideo.Error()},
+		},
+		{
+			testName: "Expect error (400) without a video.",
+			reqBody: api.VideoEditRequest{
+				Auth:      api.AuthenticationSet{AccessToken: clipSessionToken(t, "broadcaster")},
+				Operation: "trim",
+				End:       1,
+			},
+			respStatus: 400,
+			respErrors: []string{ErrEditMissingVideo.Error()},
+		},
+		{
+			testName: "Expect error (400) for an unknown operation.",
+			reqBody: api.VideoEditRequest{
+				Auth:      api.AuthenticationSet{AccessToken: clipSessionToken(t, "broadcaster")},
+				Operation: "reverse",
+				VideoIDs:  []string{own},
+			},
+			respStatus: 400,
+			respErrors: []string{ErrEditInvalidOperation.Error()},
+		},
+		{
+			testName: "Expect error (400) for merging a single video.",
+			reqBody: api.VideoEditRequest{
+				Auth:      api.AuthenticationSet{AccessToken: clipSessionToken(t, "broadcaster")},
+				Operation: "merge",
+				VideoIDs:  []string{own},
+			},
+			respStatus: 400,
+			respErrors: []string{videoEdit.ErrTooFewVideos.Error()},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			status, result := serveVideoEdit(t, r, "/v1/edits", test.reqBody)
+
+			if !cmp.Equal(status, test.respStatus) {
+				t.Fatal(cmp.Diff(status, test.respStatus))
+			}
+
+			if !cmp.Equal(result.Errors, test.respErrors) {
+				t.Fatal(cmp.Diff(result.Errors, test.respErrors))
+			}
+
+			if !result.Success {
+				return
+			}
+
+			if result.Edit.Status != "pending" || len(result.Edit.Results) != test.respResults {
+				t.Fatalf("unexpected edit: %+v", result.Edit)
+			}
+
+			for _, video := range result.Edit.Results {
+				if video.Path != "/vod/"+video.ID+"/" {
+					t.Fatal(cmp.Diff(video.Path, "/vod/"+video.ID+"/"))
+				}
+			}
+		})
+	}
+}
+
+func TestVideoEditHandler_Confirm(t *testing.T) {
+	r, videos, ids := newVideoEditRouter(t)
+
+	_, created := serveVideoEdit(t, r, "/v1/edits", api.VideoEditRequest{
+		Auth:      api.AuthenticationSet{AccessToken: clipSessionToken(t, "broadcaster")},
+		Operation: "trim",
+		VideoIDs:  []string{ids[0].String()},
+		Start:     1,
+		End:       2,
+	})
+	if !created.Success {
+		t.Fatal(created.Errors)
+	}
+
+	path := "/v1/edits/" + created.Edit.ID
+
+	tests := []struct {
+		testName string
+		path     string
+		username string
+
+		respStatus int
+		respErrors []string
+	}{
+		{
+			testName:   "Expect error (403) for another user confirming the edit.",
+			path:       path + "/confirm",
+			username:   "viewer",
+			respStatus: 403,
+			respErrors: []string{playback.ErrNotPermitted.Error()},
+		},
+		{
+			testName:   "Expect error (404) for an unknown edit.",
+			path:       "/v1/edits/" + uuid.New().String() + "/confirm",
+			username:   "broadcaster",
+			respStatus: 404,
+			respErrors: []string{ErrEditInvalidEdit.Error()},
+		},
+		{
+			testName:   "Expect success (200) for broadcaster confirming their edit.",
+			path:       path + "/confirm",
+			username:   "broadcaster",
+			respStatus: 200,
+			respErrors: []string{},
+		},
+		{
+			testName:   "Expect error (409) for discarding a confirmed edit.",
+			path:       path + "/discard",
+			username:   "broadcaster",
+			respStatus: 409,
+			respErrors: []string{videoEdit.ErrNotPending.Error()},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			status, result := serveVideoEdit(t, r, test.path, api.VideoEditActionRequest{
+				Auth: api.AuthenticationSet{AccessToken: clipSessionToken(t, test.username)},
+			})
+
+			if !cmp.Equal(status, test.respStatus) {
+				t.Fatal(cmp.Diff(status, test.respStatus))
+			}
+
+			if !cmp.Equal(result.Errors, test.respErrors) {
+				t.Fatal(cmp.Diff(result.Errors, test.respErrors))
+			}
+		})
+	}
+
+	if _, ok := videos.clipVideos[ids[0]]; ok {
+		t.Fatal("expected the trimmed source to be deleted once confirmed")
+	}
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, path, nil)
+	request.Header.Set("Authorization", "Bearer "+clipSessionToken(t, "broadcaster"))
+	r.ServeHTTP(recorder, request)
+
+	result := api.VideoEditResponse{}
+	if err := json.NewDecoder(recorder.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Edit.Status != "confirmed" || len(result.Edit.Results) != 1 {
+		t.Fatalf("unexpected edit: %+v", result.Edit)
+	}
+}
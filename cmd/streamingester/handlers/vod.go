@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"context"
+	"github.com/M-Ro/go-vodstream/internal/blob"
+	"github.com/M-Ro/go-vodstream/internal/playback"
+	"github.com/M-Ro/go-vodstream/internal/recording"
+	"github.com/M-Ro/go-vodstream/internal/urlsign"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// StartQueryParam is the URL query parameter viewers seek with, in seconds.
+const StartQueryParam = "start"
+
+// VideoProvider looks up recorded videos.
+type VideoProvider interface {
+	GetByID(ctx context.Context, id uuid.UUID) *storage.Video
+}
+
+// VodHandler serves recorded videos under the /vod/<id>/ prefix playback URLs
+// are signed for.
+type VodHandler struct {
+	media       blob.Store
+	urlDuration time.Duration
+	videos      VideoProvider
+	authorizer  playback.Authorizer
+	signer      *urlsign.Signer
+}
+
+func (h *VodHandler) RegisterRoutes(r *mux.Router) {
+	s := r.PathPrefix("/vod/{id}").Subrouter()
+	if h.signer != nil {
+		s.Use(h.signer.Middleware)
+	}
+
+	s.HandleFunc("/video.flv", h.FLV).Methods(http.MethodGet)
+}
+
+// serveObject serves an object of the media store. Viewers are redirected to
+// stores able to presign URLs, valid for expiry, to fetch it from directly.
+func serveObject(w http.ResponseWriter, r *http.Request, media blob.Store, key string, expiry time.Duration) {
+	url, err := media.Presign(r.Context(), key, expiry)
+	if err == nil {
+		http.Redirect(w, r, url, http.StatusFound)
+		return
+	} else if err != blob.ErrPresignUnsupported {
+		log.Errorf("Couldn't presign %s: %v", key, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	file, err := blob.Open(r.Context(), media, key)
+	if err == blob.ErrNotFound {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		log.Errorf("Couldn't open %s: %v", key, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", blob.ContentType(key))
+	http.ServeContent(w, r, "", file.Object().ModTime, file)
+}
+
+// visible reports whether the video may be served to the viewer of the request.
+// Unpublished videos are hidden from everyone but their broadcaster, who must
+// present their session token.
+func visible(r *http.Request, authorizer playback.Authorizer, video storage.Video) bool {
+	if video.IsPublished {
+		return true
+	}
+
+	viewer, err := authorizer.Authenticate(r.Context(), playback.TokenFromRequest(r))
+	return err == nil && viewer.Id == video.BroadcasterId
+}
+
+// FLV serves the recording of a video. Seeks start from the keyframe at or
+// before the requested time, found through the recording's index so only the
+// header and the media from that keyframe on are read. Videos played from the
+// start are served as any other object of the media store.
+func (h *VodHandler) FLV(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	start := time.Duration(0)
+	if value := r.URL.Query().Get(StartQueryParam); value != "" {
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err != nil || seconds < 0 {
+			http.Error(w, "invalid start time", http.StatusBadRequest)
+			return
+		}
+		start = time.Duration(seconds * float64(time.Second))
+	}
+
+	video := h.videos.GetByID(r.Context(), id)
+	if video == nil || video.FilePath == "" || !visible(r, h.authorizer, *video) {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if start == 0 {
+		serveObject(w, r, h.media, video.FilePath, h.urlDuration)
+		return
+	}
+
+	file, index, err := recording.Open(r.Context(), h.media, *video)
+	if err != nil {
+		log.Errorf("Couldn't open recording of video %s: %v", id, err)
+		http.NotFound(w, r)
+		return
+	}
+	defer file.Close()
+
+	keyFrame, err := index.Seek(start)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "video/x-flv")
+	w.Header().Set("Content-Length", strconv.FormatInt(index.HeaderSize+index.Size-keyFrame.Offset, 10))
+	w.WriteHeader(http.StatusOK)
+
+	header := io.NewSectionReader(file, 0, index.HeaderSize)
+	media := io.NewSectionReader(file, keyFrame.Offset, index.Size-keyFrame.Offset)
+
+	if _, err := io.Copy(w, io.MultiReader(header, media)); err != nil {
+		log.Debugf("Stopped serving video %s: %v", id, err)
+	}
+}
+
+// NewVodHandler instantiates a new VodHandler serving recordings from media.
+// Media URLs must be signed when the playback config has a URL signing secret.
+func NewVodHandler(
+	media blob.Store, playbackConfig playback.Config, videos VideoProvider, authorizer playback.Authorizer,
+) *VodHandler {
+	handler := &VodHandler{
+		media:       media,
+		urlDuration: playbackConfig.URLDuration,
+		videos:      videos,
+		authorizer:  authorizer,
+	}
+
+	if playbackConfig.URLSigningSecret != "" {
+		signer := playback.NewSigner(playbackConfig)
+		handler.signer = &signer
+	}
+
+	return handler
+}
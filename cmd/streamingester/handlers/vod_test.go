@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"context"
+	"github.com/M-Ro/go-vodstream/internal/avtest"
+	"github.com/M-Ro/go-vodstream/internal/blob"
+	"github.com/M-Ro/go-vodstream/internal/blob/s3test"
+	"github.com/M-Ro/go-vodstream/internal/playback"
+	"github.com/M-Ro/go-vodstream/internal/recording"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/nareix/joy4/format/flv"
+	"github.com/spf13/afero"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type videoMap map[uuid.UUID]storage.Video
+
+func (v videoMap) GetByID(_ context.Context, id uuid.UUID) *storage.Video {
+	video, ok := v[id]
+	if !ok {
+		return nil
+	}
+	return &video
+}
+
+// writeRecording stores a recording called name of count frames, with a
+// keyframe every second, returning the video it would be published as.
+func writeRecording(t *testing.T, media blob.Store, name string, count int) storage.Video {
+	config := recording.Config{SpoolDirectory: t.TempDir()}
+
+	_, err := recording.Create(context.Background(), config, media, name, avtest.Streams(),
+		func(writer *recording.Writer) error {
+			for _, packet := range avtest.Packets(count, 25) {
+				if err := writer.WritePacket(packet); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return storage.Video{Id: uuid.New(), FilePath: name, IndexPath: recording.IndexFile(name)}
+}
+
+// startVodServer serves a single published video of 100 frames from media.
+func startVodServer(t *testing.T, media blob.Store) (*httptest.Server, uuid.UUID) {
+	video := writeRecording(t, media, "test.flv", 100)
+	video.IsPublished = true
+
+	authorizer := playback.NewAuthorizer(testClipPlaybackConfig, testClipUsers)
+
+	r := mux.NewRouter()
+	NewVodHandler(media, playback.Config{URLDuration: time.Minute}, videoMap{video.Id: video}, authorizer).
+		RegisterRoutes(r)
+
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+
+	return server, video.Id
+}
+
+// newS3Store returns a store over an S3 stand-in, closed once the test is done.
+func newS3Store(t *testing.T) (*blob.S3Store, *s3test.Server) {
+	server := s3test.NewServer()
+	t.Cleanup(server.Close)
+
+	media, err := blob.NewS3Store(blob.S3Config{
+		Endpoint:  server.URL,
+		Region:    s3test.Region,
+		Bucket:    s3test.Bucket,
+		AccessKey: s3test.AccessKey,
+		SecretKey: s3test.SecretKey,
+		PathStyle: true,
+	}, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return media, server
+}
+
+func TestVodHandler_FLV(t *testing.T) {
+	server, id := startVodServer(t, blob.NewLocalStore(afero.NewMemMapFs()))
+
+	tests := []struct {
+		testName string
+		query    string
+		expected time.Duration
+	}{
+		{
+			testName: "expect the whole recording without a start time",
+			expected: 0,
+		},
+		{
+			testName: "expect playback to start at the keyframe before the start time",
+			query:    "?start=2.5",
+			expected: 2 * time.Second,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			resp, err := http.Get(server.URL + "/vod/" + id.String() + "/video.flv" + test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				t.Fatal(cmp.Diff(resp.StatusCode, http.StatusOK))
+			}
+
+			demuxer := flv.NewDemuxer(resp.Body)
+			first, err := demuxer.ReadPacket()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !first.IsKeyFrame || first.Time != test.expected {
+				t.Fatalf("expected playback to start at the keyframe at %s, got %s", test.expected, first.Time)
+			}
+		})
+	}
+}
+
+func TestVodHandler_Presigned(t *testing.T) {
+	media, store := newS3Store(t)
+	server, id := startVodServer(t, media)
+
+	tests := []struct {
+		testName   string
+		query      string
+		redirected bool
+	}{
+		{testName: "expect the whole recording to be fetched from the store", redirected: true},
+		{testName: "expect seeks to be served through the index", query: "?start=2.5"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			resp, err := http.Get(server.URL + "/vod/" + id.String() + "/video.flv" + test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				t.Fatal(cmp.Diff(resp.StatusCode, http.StatusOK))
+			}
+
+			if redirected := strings.HasPrefix(resp.Request.URL.String(), store.URL); redirected != test.redirected {
+				t.Fatalf("expected redirected to be %t, got %s", test.redirected, resp.Request.URL)
+			}
+
+			if contentType := resp.Header.Get("Content-Type"); contentType != "video/x-flv" {
+				t.Fatal(cmp.Diff(contentType, "video/x-flv"))
+			}
+
+			if _, err := flv.NewDemuxer(resp.Body).ReadPacket(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestVodHandler_Rejected(t *testing.T) {
+	server, id := startVodServer(t, blob.NewLocalStore(afero.NewMemMapFs()))
+
+	tests := []struct {
+		testName     string
+		path         string
+		expectedCode int
+	}{
+		{
+			testName:     "expect 404 for an unknown video",
+			path:         "/vod/" + uuid.New().String() + "/video.flv",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			testName:     "expect 404 for an invalid id",
+			path:         "/vod/abc/video.flv",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			testName:     "expect 400 for an invalid start time",
+			path:         "/vod/" + id.String() + "/video.flv?start=-1",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			resp, err := http.Get(server.URL + test.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != test.expectedCode {
+				t.Fatal(cmp.Diff(resp.StatusCode, test.expectedCode))
+			}
+		})
+	}
+}
@@ -0,0 +1,462 @@
+package streamingester
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"github.com/M-Ro/go-vodstream/internal/blob"
+	broadcastTracker "github.com/M-Ro/go-vodstream/internal/broadcast"
+	"github.com/M-Ro/go-vodstream/internal/cluster"
+	"github.com/M-Ro/go-vodstream/internal/domain/broadcast"
+	domainRelay "github.com/M-Ro/go-vodstream/internal/domain/relay"
+	"github.com/M-Ro/go-vodstream/internal/domain/user"
+	"github.com/M-Ro/go-vodstream/internal/hls"
+	"github.com/M-Ro/go-vodstream/internal/live"
+	"github.com/M-Ro/go-vodstream/internal/playback"
+	"github.com/M-Ro/go-vodstream/internal/pull"
+	"github.com/M-Ro/go-vodstream/internal/recording"
+	"github.com/M-Ro/go-vodstream/internal/relay"
+	"github.com/M-Ro/go-vodstream/internal/thumbnail"
+	"github.com/nareix/joy4/av"
+	"github.com/nareix/joy4/av/avutil"
+	"github.com/nareix/joy4/format/rtmp"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrPublishKeyInvalid   = errors.New("publish key is not valid")
+	ErrPublishNotPermitted = errors.New("user is not permitted to publish")
+)
+
+const (
+	// PublishKeyQueryParam is the URL query parameter publishers present their publish key in.
+	PublishKeyQueryParam = "key"
+
+	// VisibilityQueryParam is the URL query parameter publishers may set the broadcast visibility with.
+	VisibilityQueryParam = "visibility"
+
+	// LowLatencyQueryParam is the URL query parameter publishers may enable Low-Latency HLS with.
+	LowLatencyQueryParam = "low_latency"
+
+	// DVRQueryParam is the URL query parameter publishers may set the DVR window
+	// of their channel with, as a duration, "event" to keep the whole broadcast
+	// or "off" to disable it.
+	DVRQueryParam = "dvr"
+
+	// viewerReportInterval is how often the viewer count of each broadcast is recorded.
+	viewerReportInterval = 15 * time.Second
+)
+
+// UserProvider looks up the publisher of a stream.
+type UserProvider interface {
+	GetByUsername(ctx context.Context, username string) (user.User, error)
+}
+
+// RelayTargetProvider looks up the targets a publisher restreams to.
+type RelayTargetProvider interface {
+	GetByUserID(ctx context.Context, userId uint64) ([]domainRelay.Target, error)
+}
+
+// Ingester accepts streams from publishers and serves them to authorized viewers.
+// Streams are published to rtmp://host/live/<username>?key=<publish key>, and
+// played from rtmp://host/live/<username>?token=<token>.
+type Ingester struct {
+	channels   *live.Registry
+	users      UserProvider
+	authorizer playback.Authorizer
+
+	relays       *relay.Manager
+	relayTargets RelayTargetProvider
+
+	cluster *cluster.Node
+
+	broadcasts *broadcastTracker.Tracker
+
+	recorder *recording.Recorder
+
+	thumbnails *thumbnail.Generator
+
+	hlsConfig hls.Config
+	packagers *hls.Manager
+}
+
+type IngesterOption func(i *Ingester)
+
+// WithRelays enables restreaming of published channels to each publisher's relay targets.
+func WithRelays(relays *relay.Manager, targets RelayTargetProvider) IngesterOption {
+	return func(i *Ingester) {
+		i.relays = relays
+		i.relayTargets = targets
+	}
+}
+
+// WithCluster registers published channels with the cluster, and serves
+// channels published to other nodes by pulling them from their origin.
+func WithCluster(node *cluster.Node) IngesterOption {
+	return func(i *Ingester) {
+		i.cluster = node
+	}
+}
+
+// WithBroadcasts records a broadcast for each publish, inheriting the metadata
+// of the publisher's channel.
+func WithBroadcasts(tracker *broadcastTracker.Tracker) IngesterOption {
+	return func(i *Ingester) {
+		i.broadcasts = tracker
+	}
+}
+
+// WithRecorder records every published channel, publishing each recording as a video.
+func WithRecorder(recorder *recording.Recorder) IngesterOption {
+	return func(i *Ingester) {
+		i.recorder = recorder
+	}
+}
+
+// WithThumbnails renders periodic thumbnails of every published channel, and
+// the poster and sprite sheets of each recording once it is published.
+func WithThumbnails(thumbnails *thumbnail.Generator) IngesterOption {
+	return func(i *Ingester) {
+		i.thumbnails = thumbnails
+	}
+}
+
+// WithHLS packages channels as HLS and DASH. Channels with DVR are packaged
+// from the moment they are published, others once first requested, and their
+// segments are stored in media.
+func WithHLS(config hls.Config, media blob.Store) IngesterOption {
+	return func(i *Ingester) {
+		i.hlsConfig = config
+		i.packagers = hls.NewManager(config, media, i)
+	}
+}
+
+// Packagers returns the HLS packagers of channels served by the ingester, or
+// nil without WithHLS.
+func (i *Ingester) Packagers() *hls.Manager {
+	return i.packagers
+}
+
+// dvr returns the DVR a publisher requested for their channel, falling back to
+// the configured default. Windows are capped at the configured maximum.
+func (i *Ingester) dvr(value string) live.DVR {
+	if i.packagers == nil {
+		return live.DVR{}
+	}
+
+	switch value {
+	case "":
+		return i.hlsConfig.DVR
+	case "event":
+		return live.DVR{Event: true}
+	case "off":
+		return live.DVR{}
+	}
+
+	window, err := time.ParseDuration(value)
+	if err != nil || window < 0 {
+		log.Warnf("Ignoring invalid DVR window %q", value)
+		return i.hlsConfig.DVR
+	}
+
+	if window > i.hlsConfig.DVRMaxWindow {
+		window = i.hlsConfig.DVRMaxWindow
+	}
+
+	return live.DVR{Window: window}
+}
+
+// channelName returns the name of the channel addressed by an RTMP URL.
+// rtmp.SplitPath includes the query string in the stream name, so it is split
+// on the path alone.
+func channelName(u *url.URL) string {
+	_, name := rtmp.SplitPath(&url.URL{Path: u.Path})
+
+	return name
+}
+
+// authenticatePublisher returns the user publishing to the named channel, or
+// an error if the publish key does not match or the user cannot publish.
+func (i *Ingester) authenticatePublisher(ctx context.Context, name string, publishKey string) (user.User, error) {
+	publisher, err := i.users.GetByUsername(ctx, name)
+	if err != nil {
+		return user.User{}, ErrPublishKeyInvalid
+	}
+
+	if publisher.PublishKey == "" ||
+		subtle.ConstantTimeCompare([]byte(publisher.PublishKey), []byte(publishKey)) != 1 {
+		return user.User{}, ErrPublishKeyInvalid
+	}
+
+	if !publisher.CanPublish {
+		return user.User{}, ErrPublishNotPermitted
+	}
+
+	return publisher, nil
+}
+
+// HandlePublish authenticates a publisher and relays its packets to the channel
+// queue until the publisher disconnects.
+func (i *Ingester) HandlePublish(conn *rtmp.Conn) {
+	defer conn.Close()
+
+	ctx := context.Background()
+	name := channelName(conn.URL)
+	query := conn.URL.Query()
+
+	publisher, err := i.authenticatePublisher(ctx, name, query.Get(PublishKeyQueryParam))
+	if err != nil {
+		log.Warnf("Rejected publish to channel %s: %v", name, err)
+		return
+	}
+
+	visibility := broadcast.ParseVisibility(query.Get(VisibilityQueryParam))
+	lowLatency, _ := strconv.ParseBool(query.Get(LowLatencyQueryParam))
+
+	err = i.publish(ctx, publisher, visibility, conn,
+		live.WithLowLatency(lowLatency), live.WithDVR(i.dvr(query.Get(DVRQueryParam))))
+	if err != nil {
+		log.Warnf("Rejected publish to channel %s: %v", name, err)
+	}
+}
+
+// Pull republishes a remote source into the channel of the user it is
+// configured for, returning once the source ends or ctx is cancelled.
+func (i *Ingester) Pull(ctx context.Context, source pull.Source, src av.Demuxer) error {
+	publisher, err := i.users.GetByUsername(ctx, source.Username)
+	if err != nil {
+		return err
+	}
+
+	if !publisher.CanPublish {
+		return ErrPublishNotPermitted
+	}
+
+	return i.publish(ctx, publisher, source.Visibility, src,
+		live.WithLowLatency(source.LowLatency), live.WithDVR(i.dvr("")))
+}
+
+// publish opens the publisher's channel and relays packets from src to the
+// channel queue until src ends. Pushed and pulled streams share this path so
+// that both are registered and restreamed alike. An error is returned only if
+// the channel could not be opened.
+func (i *Ingester) publish(
+	ctx context.Context, publisher user.User, visibility broadcast.Visibility, src av.Demuxer,
+	opts ...live.ChannelOption,
+) error {
+	channel, err := i.channels.Open(publisher.Username, publisher.Id, visibility, opts...)
+	if err != nil {
+		return err
+	}
+	defer i.channels.Close(channel)
+
+	if i.cluster != nil {
+		if err := i.cluster.Register(ctx, channel); err != nil {
+			return err
+		}
+		defer i.cluster.Unregister(channel)
+	}
+
+	streams, err := src.Streams()
+	if err != nil {
+		log.Errorf("Couldn't read streams for channel %s: %v", channel.Name, err)
+		return nil
+	}
+
+	if err = channel.Queue.WriteHeader(streams); err != nil {
+		log.Errorf("Couldn't write header for channel %s: %v", channel.Name, err)
+		return nil
+	}
+
+	log.Infof("Channel %s has started streaming (%s).", channel.Name, channel.Visibility)
+
+	if i.broadcasts != nil {
+		if _, err := i.broadcasts.Start(ctx, channel); err != nil {
+			log.Errorf("Couldn't record the broadcast of channel %s: %v", channel.Name, err)
+		} else {
+			defer i.endBroadcast(channel)
+			defer i.reportViewers(channel)()
+		}
+	}
+
+	if i.relays != nil {
+		targets, err := i.relayTargets.GetByUserID(ctx, publisher.Id)
+		if err != nil {
+			log.Errorf("Couldn't load relay targets for channel %s: %v", channel.Name, err)
+		} else {
+			session := i.relays.Start(channel, targets)
+			defer session.Stop()
+		}
+	}
+
+	if i.recorder != nil {
+		// The recording completes once the channel is closed.
+		session := i.recorder.Start(channel)
+
+		if i.thumbnails != nil {
+			go i.renderRecording(session)
+		}
+	}
+
+	if i.thumbnails != nil {
+		i.thumbnails.Start(channel)
+	}
+
+	if i.packagers != nil && channel.DVR.Enabled() {
+		// Viewers may rewind to before anyone requested the channel.
+		if _, err := i.packagers.Get(ctx, channel.Name); err != nil {
+			log.Errorf("Couldn't start DVR for channel %s: %v", channel.Name, err)
+		}
+	}
+
+	if err := avutil.CopyPackets(channel.Queue, src); err == io.EOF {
+		log.Infof("Channel %s has stopped streaming.", channel.Name)
+	} else if err != nil {
+		log.Errorf("Channel %s stopped streaming: %v", channel.Name, err)
+	}
+
+	return nil
+}
+
+// endBroadcast marks the broadcast of a channel as no longer live.
+func (i *Ingester) endBroadcast(channel *live.Channel) {
+	if err := i.broadcasts.End(context.Background(), channel.BroadcastId); err != nil {
+		log.Errorf("Couldn't end the broadcast of channel %s: %v", channel.Name, err)
+	}
+}
+
+// reportViewers records the viewer count of a channel's broadcast whenever it
+// changes, until the returned function is called.
+func (i *Ingester) reportViewers(channel *live.Channel) func() {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(viewerReportInterval)
+		defer ticker.Stop()
+
+		var reported int64
+		for {
+			select {
+			case <-stop:
+				return
+			case now := <-ticker.C:
+				viewers := channel.Viewers(now)
+				if viewers == reported {
+					continue
+				}
+
+				if err := i.broadcasts.ReportViewers(context.Background(), channel.BroadcastId, viewers); err != nil {
+					log.Warnf("Couldn't record the viewers of channel %s: %v", channel.Name, err)
+					continue
+				}
+
+				reported = viewers
+			}
+		}
+	}()
+
+	// Waiting for the last report keeps it from landing after the broadcast ends.
+	return func() {
+		close(stop)
+		<-done
+	}
+}
+
+// renderRecording renders the poster and sprite sheets of a recording once it
+// has been published as a video.
+func (i *Ingester) renderRecording(session *recording.Session) {
+	video, err := session.Wait()
+	if err != nil {
+		return
+	}
+
+	if err := i.thumbnails.Video(context.Background(), video); err != nil {
+		log.Warnf("Couldn't render imagery of video %s: %v", video.Id, err)
+	}
+}
+
+// acquire returns the named channel for a viewer, along with a function to
+// call once the viewer leaves. In a cluster, channels published elsewhere are
+// pulled from their origin, except for other nodes which are only served
+// channels published here.
+func (i *Ingester) acquire(ctx context.Context, name string, peer bool) (*live.Channel, func(), error) {
+	if i.cluster == nil || peer {
+		channel, err := i.channels.Get(name)
+		return channel, func() {}, err
+	}
+
+	return i.cluster.Acquire(ctx, name)
+}
+
+// Acquire returns the named channel for a viewer playing over a protocol other
+// than RTMP, along with a function to call once the viewer leaves.
+func (i *Ingester) Acquire(ctx context.Context, name string) (*live.Channel, func(), error) {
+	return i.acquire(ctx, name, false)
+}
+
+// HandlePlay authorizes a viewer against the requested channel and serves the
+// stream from the latest keyframe. Other nodes in the cluster presenting the
+// cluster key are not authorized, as they authorize their own viewers.
+func (i *Ingester) HandlePlay(conn *rtmp.Conn) {
+	defer conn.Close()
+
+	ctx := context.Background()
+	name := channelName(conn.URL)
+	query := conn.URL.Query()
+	peer := i.cluster != nil && i.cluster.IsPeer(query.Get(cluster.KeyQueryParam))
+
+	channel, release, err := i.acquire(ctx, name, peer)
+	if err != nil {
+		log.Debugf("Viewer requested channel %s: %v", name, err)
+		return
+	}
+	defer release()
+
+	if peer {
+		if err := avutil.CopyFile(conn, channel.Queue.Latest()); err != nil && err != io.EOF {
+			log.Infof("Couldn't serve channel %s to a node: %v", channel.Name, err)
+		}
+		return
+	}
+
+	resource := playback.Resource{
+		Name:          channel.Name,
+		BroadcasterId: channel.BroadcasterId,
+		Visibility:    channel.Visibility,
+	}
+
+	_, err = i.authorizer.Authorize(ctx, resource, query.Get(playback.TokenQueryParam))
+	if err != nil {
+		log.Infof("Rejected viewer for channel %s: %v", channel.Name, err)
+		return
+	}
+	defer channel.Join()()
+
+	if err := avutil.CopyFile(conn, channel.Queue.Latest()); err != nil && err != io.EOF {
+		log.Infof("Couldn't serve channel %s to a viewer: %v", channel.Name, err)
+	}
+}
+
+// NewIngester instantiates a new Ingester.
+func NewIngester(
+	channels *live.Registry, users UserProvider, authorizer playback.Authorizer, opts ...IngesterOption,
+) *Ingester {
+	ingester := &Ingester{
+		channels:   channels,
+		users:      users,
+		authorizer: authorizer,
+	}
+
+	for _, opt := range opts {
+		opt(ingester)
+	}
+
+	return ingester
+}
@@ -0,0 +1,142 @@
+package streamingester
+
+import (
+	"context"
+	"github.com/M-Ro/go-vodstream/internal/domain/user"
+	"github.com/M-Ro/go-vodstream/internal/hls"
+	"github.com/M-Ro/go-vodstream/internal/live"
+	"github.com/M-Ro/go-vodstream/internal/playback"
+	userRepository "github.com/M-Ro/go-vodstream/internal/user"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type mockUserProvider map[string]user.User
+
+func (m mockUserProvider) GetByUsername(_ context.Context, username string) (user.User, error) {
+	u, ok := m[strings.ToLower(username)]
+	if !ok {
+		return user.User{}, userRepository.ErrUserNotFound
+	}
+
+	return u, nil
+}
+
+var testUsers = mockUserProvider{
+	"publisher": {Id: 1, Username: "publisher", PublishKey: "publisherKey", CanPublish: true},
+	"nokey":     {Id: 2, Username: "nokey", PublishKey: "", CanPublish: true},
+	"banned":    {Id: 3, Username: "banned", PublishKey: "bannedKey", CanPublish: false},
+}
+
+func TestIngester_authenticatePublisher(t *testing.T) {
+	tests := []struct {
+		testName      string
+		url           string
+		expectedError error
+		expectedUser  user.User
+	}{
+		{
+			testName:      "expect success with matching publish key",
+			url:           "rtmp://localhost:1935/live/Publisher?key=publisherKey",
+			expectedError: nil,
+			expectedUser:  testUsers["publisher"],
+		},
+		{
+			testName:      "expect success with other query parameters",
+			url:           "rtmp://localhost:1935/live/publisher?visibility=unlisted&key=publisherKey",
+			expectedError: nil,
+			expectedUser:  testUsers["publisher"],
+		},
+		{
+			testName:      "expect error with wrong publish key",
+			url:           "rtmp://localhost:1935/live/publisher?key=wrongKey",
+			expectedError: ErrPublishKeyInvalid,
+		},
+		{
+			testName:      "expect error for unknown user",
+			url:           "rtmp://localhost:1935/live/nobody?key=publisherKey",
+			expectedError: ErrPublishKeyInvalid,
+		},
+		{
+			testName:      "expect error for user without a publish key",
+			url:           "rtmp://localhost:1935/live/nokey",
+			expectedError: ErrPublishKeyInvalid,
+		},
+		{
+			testName:      "expect error for user without CanPublish",
+			url:           "rtmp://localhost:1935/live/banned?key=bannedKey",
+			expectedError: ErrPublishNotPermitted,
+		},
+	}
+
+	ingester := NewIngester(live.NewRegistry(), testUsers, playback.NewAuthorizer(playback.Config{}, testUsers))
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			u, err := url.Parse(test.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			publisher, err := ingester.authenticatePublisher(
+				context.Background(), channelName(u), u.Query().Get(PublishKeyQueryParam),
+			)
+
+			if !cmp.Equal(err, test.expectedError, cmpopts.EquateErrors()) {
+				t.Fatal(cmp.Diff(err, test.expectedError, cmpopts.EquateErrors()))
+			}
+
+			if !cmp.Equal(publisher, test.expectedUser) {
+				t.Fatal(cmp.Diff(publisher, test.expectedUser))
+			}
+		})
+	}
+}
+
+func TestChannelName(t *testing.T) {
+	u, err := url.Parse("rtmp://localhost:1935/live/Publisher?key=publisherKey")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if name := channelName(u); name != "Publisher" {
+		t.Fatal(cmp.Diff(name, "Publisher"))
+	}
+}
+
+func TestIngester_dvr(t *testing.T) {
+	config := hls.Config{DVR: live.DVR{Window: time.Minute}, DVRMaxWindow: time.Hour}
+	ingester := NewIngester(live.NewRegistry(), testUsers, playback.NewAuthorizer(playback.Config{}, testUsers),
+		WithHLS(config, nil))
+
+	tests := []struct {
+		testName string
+		value    string
+		expected live.DVR
+	}{
+		{testName: "expect the default without a request", value: "", expected: config.DVR},
+		{testName: "expect the requested window", value: "30m", expected: live.DVR{Window: 30 * time.Minute}},
+		{testName: "expect windows capped at the maximum", value: "3h", expected: live.DVR{Window: time.Hour}},
+		{testName: "expect event playlists", value: "event", expected: live.DVR{Event: true}},
+		{testName: "expect DVR disabled", value: "off", expected: live.DVR{}},
+		{testName: "expect the default for an invalid window", value: "forever", expected: config.DVR},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			if dvr := ingester.dvr(test.value); dvr != test.expected {
+				t.Fatal(cmp.Diff(dvr, test.expected))
+			}
+		})
+	}
+
+	// Without HLS there is nothing to rewind.
+	ingester = NewIngester(live.NewRegistry(), testUsers, playback.NewAuthorizer(playback.Config{}, testUsers))
+	if dvr := ingester.dvr("event"); dvr.Enabled() {
+		t.Fatal(cmp.Diff(dvr, live.DVR{}))
+	}
+}
@@ -1,16 +1,43 @@
 package streamingester
 
 import (
-	"fmt"
-	"github.com/nareix/joy4/av/avutil"
-	"github.com/nareix/joy4/av/pubsub"
+	"context"
+	"github.com/M-Ro/go-vodstream/cmd/streamingester/handlers"
+	"github.com/M-Ro/go-vodstream/internal/blob"
+	"github.com/M-Ro/go-vodstream/internal/broadcast"
+	"github.com/M-Ro/go-vodstream/internal/channel"
+	"github.com/M-Ro/go-vodstream/internal/clip"
+	"github.com/M-Ro/go-vodstream/internal/cluster"
+	"github.com/M-Ro/go-vodstream/internal/edit"
+	"github.com/M-Ro/go-vodstream/internal/encryption"
+	"github.com/M-Ro/go-vodstream/internal/flvstream"
+	"github.com/M-Ro/go-vodstream/internal/hls"
+	"github.com/M-Ro/go-vodstream/internal/live"
+	"github.com/M-Ro/go-vodstream/internal/playback"
+	"github.com/M-Ro/go-vodstream/internal/pull"
+	"github.com/M-Ro/go-vodstream/internal/recording"
+	"github.com/M-Ro/go-vodstream/internal/relay"
+	"github.com/M-Ro/go-vodstream/internal/retention"
+	"github.com/M-Ro/go-vodstream/internal/thumbnail"
+	"github.com/M-Ro/go-vodstream/internal/user"
+	"github.com/M-Ro/go-vodstream/storage/sql"
+	sqlBroadcast "github.com/M-Ro/go-vodstream/storage/sql/broadcast"
+	"github.com/M-Ro/go-vodstream/storage/sql/broadcast_vod"
+	sqlChannel "github.com/M-Ro/go-vodstream/storage/sql/channel"
+	sqlClip "github.com/M-Ro/go-vodstream/storage/sql/clip"
+	"github.com/M-Ro/go-vodstream/storage/sql/live_channel"
+	"github.com/M-Ro/go-vodstream/storage/sql/relay_target"
+	"github.com/M-Ro/go-vodstream/storage/sql/transaction"
+	sqlUser "github.com/M-Ro/go-vodstream/storage/sql/user"
+	"github.com/M-Ro/go-vodstream/storage/sql/video"
+	"github.com/M-Ro/go-vodstream/storage/sql/video_edit"
+	"github.com/gorilla/mux"
 	"github.com/nareix/joy4/format"
 	"github.com/nareix/joy4/format/rtmp"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-	"io"
-	"os"
+	"net/http"
 )
 
 // NewCmd registers the cobra command.
@@ -22,59 +49,130 @@ func NewCmd() *cobra.Command {
 	}
 }
 
-var que *pubsub.Queue
-
 func Start(_ *cobra.Command, _ []string) {
 	log.Info("Starting livestream ingester")
 
 	bindAddress := viper.GetString("stream_ingester.bind_address")
+	httpBindAddress := viper.GetString("stream_ingester.http_bind_address")
 
 	format.RegisterAll()
 
-	server := &rtmp.Server{
-		Addr:          bindAddress,
-		HandlePublish: HandlePublish,
-		HandlePlay:    handlePlay,
-	}
+	db := sql.NewDbConn()
+	users := user.NewRepository(sqlUser.NewUserStorage(db))
 
-	fmt.Println("Info: Starting the stream server at", bindAddress)
-	if err := server.ListenAndServe(); err != nil {
-		fmt.Fprintln(os.Stderr, "Fatal: Couldn't run the stream server:", err)
+	// Workflows spanning several storages run within a single transaction.
+	transactions := transaction.NewTransactor(db)
+
+	channels := live.NewRegistry()
+	opts := make([]IngesterOption, 0)
+
+	cipher, err := encryption.NewCipher(viper.GetString("relay.encryption_key"))
+	if err != nil {
+		log.Warnf("Relaying disabled: %v", err)
+	} else {
+		relayTargets := relay.NewRepository(relay_target.NewRelayTargetStorage(db), cipher)
+		relays := relay.NewManager(relay.GetConfig(), relay.DialRTMP, relayTargets)
+		opts = append(opts, WithRelays(relays, relayTargets))
 	}
-}
 
-func HandlePublish(conn *rtmp.Conn) {
-	defer conn.Close()
+	if viper.GetBool("cluster.enabled") {
+		config := cluster.GetConfig()
+		directory := cluster.NewDirectory(live_channel.NewLiveChannelStorage(db), cluster.GetTTL())
+		node := cluster.NewNode(config, channels, directory, cluster.DialRTMP)
+		go node.Run(context.Background())
 
-	if que != nil {
-		que.Close()
+		log.Infof("Joined ingest cluster as %s", config.NodeAddress)
+		opts = append(opts, WithCluster(node))
 	}
-	que = pubsub.NewQueue()
 
-	streams, err := conn.Streams()
+	// Broadcasts inherit the metadata of the publisher's channel page.
+	broadcasts := broadcast.NewRepository(sqlBroadcast.NewBroadcastStorage(db))
+	channelPages := channel.NewRepository(sqlChannel.NewChannelStorage(db))
+	opts = append(opts, WithBroadcasts(broadcast.NewTracker(broadcasts, channelPages, transactions)))
+
+	mediaConfig := blob.GetConfig()
+	media, err := blob.NewStore(mediaConfig)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "Error: Couldn't stream:", err)
-		return
+		log.Fatalf("Couldn't open the media store: %v", err)
 	}
-	que.WriteHeader(streams)
 
-	fmt.Println("Info: The server has started streaming.")
+	recordingConfig := recording.GetConfig()
+	videos := video.NewVideoStorage(db)
+	vods := broadcast_vod.NewBroadcastVodStorage(db)
+
+	// Live broadcasts can only be clipped while they are being recorded.
+	var recordings clip.LiveRecordings
 
-	if err := avutil.CopyPackets(que, conn); err == io.EOF {
-		fmt.Println("Info: The server has stopped streaming.")
-	} else if err != nil {
-		fmt.Fprintln(os.Stderr, "Error: Couldn't stream:", err)
+	if viper.GetBool("recording.enabled") {
+		log.Infof("Recording published channels to the %s media store", mediaConfig.Backend)
+		recorder := recording.NewRecorder(recordingConfig, media, videos, vods, transactions)
+		recordings = recorder
+		opts = append(opts, WithRecorder(recorder))
 	}
-}
 
-func handlePlay(conn *rtmp.Conn) {
-	defer conn.Close()
-	if que == nil {
-		return
+	thumbnailConfig := thumbnail.GetConfig()
+	thumbnails := thumbnail.NewGenerator(thumbnailConfig, media, thumbnail.NewExtractor(thumbnailConfig))
+
+	if viper.GetBool("thumbnail.enabled") {
+		log.Infof("Rendering thumbnails of published channels every %s", thumbnailConfig.LiveInterval)
+		opts = append(opts, WithThumbnails(thumbnails))
 	}
 
-	if err := avutil.CopyFile(conn, que.Latest()); err != nil && err != io.EOF {
-		fmt.Printf("%+v\n", err)
-		fmt.Println("Info: Couldn't serve the stream to a viewer:", err)
+	clips := clip.NewRepository(sqlClip.NewClipStorage(db))
+	clipper := clip.NewClipper(clip.GetConfig(), recordingConfig, media, videos, recordings, clips, transactions)
+
+	edits := edit.NewRepository(video_edit.NewVideoEditStorage(db))
+	editor := edit.NewEditor(recordingConfig, media, videos, vods, edits, transactions)
+
+	retentionConfig := retention.GetConfig()
+	janitor := retention.NewJanitor(retentionConfig, media, videos, vods, users, transactions)
+
+	if viper.GetBool("retention.enabled") {
+		log.Infof("Sweeping videos beyond retention rules every %s", retentionConfig.Interval)
+		go janitor.Run(context.Background())
 	}
-}
\ No newline at end of file
+
+	playbackConfig := playback.GetConfig()
+	authorizer := playback.NewAuthorizer(playbackConfig, users)
+
+	opts = append(opts, WithHLS(hls.GetConfig(), media))
+	ingester := NewIngester(channels, users, authorizer, opts...)
+
+	sources, err := pull.GetSources()
+	if err != nil {
+		log.Fatalf("Couldn't read pull sources: %v", err)
+	}
+
+	if len(sources) > 0 {
+		puller := pull.NewPuller(pull.GetConfig(), sources, pull.OpenURL, ingester.Pull)
+		go puller.Run(context.Background())
+	}
+
+	r := mux.NewRouter()
+	handlers.NewLiveHandler(
+		flvstream.GetConfig(), ingester.Packagers(), playbackConfig, ingester, authorizer,
+	).RegisterRoutes(r)
+	handlers.NewVodHandler(media, playbackConfig, videos, authorizer).RegisterRoutes(r)
+	handlers.NewThumbnailHandler(thumbnails, media, playbackConfig, videos, authorizer).RegisterRoutes(r)
+	handlers.NewClipHandler(clipper, clips, authorizer, users).RegisterRoutes(r)
+	handlers.NewVideoEditHandler(editor, edits, authorizer).RegisterRoutes(r)
+	handlers.NewRetentionHandler(janitor, authorizer).RegisterRoutes(r)
+
+	go func() {
+		log.Info("Starting the playback server at ", httpBindAddress)
+		if err := http.ListenAndServe(httpBindAddress, r); err != nil {
+			log.Fatalf("Couldn't run the playback server: %v", err)
+		}
+	}()
+
+	server := &rtmp.Server{
+		Addr:          bindAddress,
+		HandlePublish: ingester.HandlePublish,
+		HandlePlay:    ingester.HandlePlay,
+	}
+
+	log.Info("Starting the stream server at ", bindAddress)
+	if err := server.ListenAndServe(); err != nil {
+		log.Fatalf("Couldn't run the stream server: %v", err)
+	}
+}
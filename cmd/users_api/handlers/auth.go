@@ -5,7 +5,7 @@ import (
 	"errors"
 	"github.com/M-Ro/go-vodstream/api"
 	"github.com/M-Ro/go-vodstream/internal/domain"
-	"github.com/M-Ro/go-vodstream/storage/sql"
+	"github.com/M-Ro/go-vodstream/internal/user"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/gorilla/mux"
 	log "github.com/sirupsen/logrus"
@@ -35,7 +35,7 @@ type AuthHandlerConfig struct {
 
 type AuthHandler struct {
 	config      AuthHandlerConfig
-	userStorage *sql.UserStorage
+	userStorage user.StorageProvider
 }
 
 func (h *AuthHandler) RegisterRoutes(r *mux.Router) {
@@ -119,7 +119,7 @@ func getConfig() AuthHandlerConfig {
 	}
 }
 
-func NewAuthHandler(userStorage *sql.UserStorage) AuthHandler {
+func NewAuthHandler(userStorage user.StorageProvider) AuthHandler {
 	return AuthHandler{
 		config:      getConfig(),
 		userStorage: userStorage,
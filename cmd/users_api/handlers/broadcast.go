@@ -0,0 +1,384 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/M-Ro/go-vodstream/api"
+	"github.com/M-Ro/go-vodstream/internal/domain/broadcast"
+	"github.com/M-Ro/go-vodstream/internal/domain/user"
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	"github.com/M-Ro/go-vodstream/internal/playback"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrBroadcastNotFound = errors.New("broadcast does not exist")
+	ErrInvalidPage       = errors.New("limit and offset must be positive integers")
+	ErrMissingTitle      = errors.New("title must not be empty")
+)
+
+// BroadcastProvider stores the broadcasts of each broadcaster.
+type BroadcastProvider interface {
+	ListLive(ctx context.Context, options paginate.QueryOptions) ([]broadcast.Broadcast, paginate.Page, error)
+	GetByID(ctx context.Context, id uuid.UUID) (broadcast.Broadcast, error)
+	Update(ctx context.Context, id uuid.UUID, broadcast broadcast.Broadcast) (broadcast.Broadcast, error)
+}
+
+// BroadcastVodProvider links broadcasts to the videos recorded from them.
+type BroadcastVodProvider interface {
+	GetByBroadcastID(ctx context.Context, broadcastId uuid.UUID) ([]broadcast.Vod, error)
+}
+
+// BroadcastHandler lists live broadcasts, and lets broadcasters edit theirs.
+type BroadcastHandler struct {
+	authorizer playback.Authorizer
+	broadcasts BroadcastProvider
+	vods       BroadcastVodProvider
+	videos     VideoProvider
+}
+
+func (h *BroadcastHandler) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc("/v1/broadcasts", h.List).Methods(http.MethodGet)
+	r.HandleFunc("/v1/broadcasts/{id}", h.Get).Methods(http.MethodGet)
+	r.HandleFunc("/v1/broadcasts/{id}", h.Update).Methods(http.MethodPatch)
+}
+
+// writeJSON encodes the response, logging encoding failures against action.
+func writeJSON(w http.ResponseWriter, status int, action string, response interface{}) {
+	encoded, err := json.Marshal(response)
+	if err != nil {
+		log.Errorf("%s failed %v", action, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(encoded)
+}
+
+// pageOptions reads the limit and offset query parameters, ordering by the
+// given field, newest first. A cursor query parameter handed out with an
+// earlier page of the same listing pages from it instead of the offset.
+// Given the schema of the listed rows, the order may be replaced by the sort
+// query parameter, and rows filtered by query parameters named by their fields.
+func pageOptions(query url.Values, orderField string, schema paginate.Schema) ([]paginate.FuncOption, error) {
+	orders := []paginate.Order{{Field: orderField, Method: paginate.OrderMethodDesc}}
+	options := make([]paginate.FuncOption, 0)
+
+	for param, option := range map[string]func(uint) paginate.FuncOption{
+		"limit":  paginate.WithLimit,
+		"offset": paginate.WithOffset,
+	} {
+		value := query.Get(param)
+		if value == "" {
+			continue
+		}
+
+		n, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return nil, ErrInvalidPage
+		}
+
+		options = append(options, option(uint(n)))
+	}
+
+	if schema != nil {
+		if value := query.Get("sort"); value != "" {
+			sorted, err := paginate.ParseSort(value, schema)
+			if err != nil {
+				return nil, err
+			}
+
+			orders = sorted
+		}
+
+		filters, err := paginate.ParseFilters(query, schema)
+		if err != nil {
+			return nil, err
+		}
+
+		options = append(options, paginate.WithFilters(filters...))
+	}
+	options = append(options, paginate.WithSort(orders...))
+
+	if value := query.Get("cursor"); value != "" {
+		cursor, err := paginate.DecodeCursor(value)
+		if err != nil || cursor.Field != paginate.OrderKey(orders) {
+			return nil, paginate.ErrInvalidCursor
+		}
+
+		options = append(options, paginate.WithCursor(cursor))
+	}
+
+	return options, nil
+}
+
+// pageToAPI converts the cursors of the pages either side of a listing to their API representation.
+func pageToAPI(page paginate.Page) api.Page {
+	return api.Page{
+		Next: page.Next,
+		Prev: page.Prev,
+	}
+}
+
+// viewer returns the user making the request if a valid session token was sent.
+// Anonymous requests are permitted, only seeing what is published.
+func viewer(r *http.Request, authorizer playback.Authorizer) (user.User, bool) {
+	token := playback.TokenFromRequest(r)
+	if token == "" {
+		return user.User{}, false
+	}
+
+	u, err := authorizer.Authenticate(r.Context(), token)
+	return u, err == nil
+}
+
+// readUpdateRequest decodes a request editing the title or publication of a
+// broadcast or video.
+func readUpdateRequest(r *http.Request, request interface{}) error {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(body, request)
+}
+
+// editTitle returns the title a broadcast or video is renamed to, or the
+// current title if the request does not rename it. Blank titles are rejected.
+func editTitle(current string, title *string) (string, error) {
+	if title == nil {
+		return current, nil
+	}
+
+	if strings.TrimSpace(*title) == "" {
+		return current, ErrMissingTitle
+	}
+
+	return strings.TrimSpace(*title), nil
+}
+
+// broadcastToAPI converts a broadcast to its API representation.
+func broadcastToAPI(b broadcast.Broadcast) api.Broadcast {
+	tags := b.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	return api.Broadcast{
+		ID:            b.Id.String(),
+		BroadcasterID: b.BroadcasterId,
+		Title:         b.Title,
+		Category:      b.Category,
+		Tags:          tags,
+		Language:      b.Language,
+		Mature:        b.Mature,
+		IsActive:      b.IsActive,
+		IsPublished:   b.IsPublished,
+		ViewerCount:   b.ViewerCount,
+		PublishedAt:   b.PublishedAt,
+		CreatedAt:     b.CreatedAt,
+	}
+}
+
+// writeBroadcastResponse encodes the response, filling the error list when err is set.
+func writeBroadcastResponse(w http.ResponseWriter, status int, response api.BroadcastResponse, err error) {
+	response.Success = err == nil
+	response.Errors = []string{}
+	if err != nil {
+		response.Errors = append(response.Errors, err.Error())
+	}
+
+	if response.Vods == nil {
+		response.Vods = []api.BroadcastVod{}
+	}
+
+	writeJSON(w, status, "Broadcast", response)
+}
+
+// writeBroadcastsResponse encodes the response, filling the error list when err is set.
+func writeBroadcastsResponse(w http.ResponseWriter, status int, response api.BroadcastsResponse, err error) {
+	response.Success = err == nil
+	response.Errors = []string{}
+	if err != nil {
+		response.Errors = append(response.Errors, err.Error())
+	}
+
+	if response.Broadcasts == nil {
+		response.Broadcasts = []api.Broadcast{}
+	}
+
+	writeJSON(w, status, "Listing broadcasts", response)
+}
+
+// broadcast returns the broadcast addressed by the request. Unpublished
+// broadcasts are only returned to their broadcaster.
+func (h *BroadcastHandler) broadcast(
+	r *http.Request, requester user.User, authenticated bool,
+) (broadcast.Broadcast, error) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		return broadcast.Broadcast{}, ErrBroadcastNotFound
+	}
+
+	b, err := h.broadcasts.GetByID(r.Context(), id)
+	if err != nil {
+		return broadcast.Broadcast{}, ErrBroadcastNotFound
+	}
+
+	if !b.IsPublished && (!authenticated || requester.Id != b.BroadcasterId) {
+		return broadcast.Broadcast{}, ErrBroadcastNotFound
+	}
+
+	return b, nil
+}
+
+// broadcastVods returns the videos recorded from a broadcast. Unpublished
+// videos are only returned to their broadcaster.
+func (h *BroadcastHandler) broadcastVods(
+	ctx context.Context, b broadcast.Broadcast, owner bool,
+) ([]api.BroadcastVod, error) {
+	vods, err := h.vods.GetByBroadcastID(ctx, b.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	converted := make([]api.BroadcastVod, 0, len(vods))
+	for _, vod := range vods {
+		v, err := h.videos.GetByID(ctx, vod.VideoId)
+		if err != nil || (!v.IsPublished && !owner) {
+			continue
+		}
+
+		converted = append(converted, api.BroadcastVod{
+			ID:          vod.Id.String(),
+			PublishedAt: vod.PublishedAt,
+			Video:       videoToAPI(v),
+		})
+	}
+
+	return converted, nil
+}
+
+// List returns the live, published broadcasts, most recently started first,
+// paged with the limit and offset or cursor query parameters. They may be
+// ordered otherwise by the sort query parameter, and filtered by query
+// parameters named by the fields of broadcast.Fields.
+func (h *BroadcastHandler) List(w http.ResponseWriter, r *http.Request) {
+	options, err := pageOptions(r.URL.Query(), "published_at", broadcast.Fields)
+	if err != nil {
+		writeBroadcastsResponse(w, http.StatusBadRequest, api.BroadcastsResponse{}, err)
+		return
+	}
+
+	broadcasts, page, err := h.broadcasts.ListLive(r.Context(), paginate.NewPaginateOptions(options...))
+	if err != nil {
+		log.Errorf("Listing live broadcasts failed: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	response := api.BroadcastsResponse{Broadcasts: make([]api.Broadcast, 0, len(broadcasts)), Page: pageToAPI(page)}
+	for _, b := range broadcasts {
+		response.Broadcasts = append(response.Broadcasts, broadcastToAPI(b))
+	}
+
+	writeBroadcastsResponse(w, http.StatusOK, response, nil)
+}
+
+// Get returns a broadcast with the videos recorded from it.
+func (h *BroadcastHandler) Get(w http.ResponseWriter, r *http.Request) {
+	u, authenticated := viewer(r, h.authorizer)
+
+	b, err := h.broadcast(r, u, authenticated)
+	if err != nil {
+		writeBroadcastResponse(w, http.StatusNotFound, api.BroadcastResponse{}, err)
+		return
+	}
+
+	vods, err := h.broadcastVods(r.Context(), b, authenticated && u.Id == b.BroadcasterId)
+	if err != nil {
+		log.Errorf("Listing vods of broadcast %s failed: %v", b.Id, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writeBroadcastResponse(w, http.StatusOK, api.BroadcastResponse{Broadcast: broadcastToAPI(b), Vods: vods}, nil)
+}
+
+// Update edits the title of a broadcast owned by the authenticated user, or publishes or unpublishes it.
+func (h *BroadcastHandler) Update(w http.ResponseWriter, r *http.Request) {
+	owner, err := h.authorizer.Authenticate(r.Context(), playback.TokenFromRequest(r))
+	if err != nil {
+		writeBroadcastResponse(w, http.StatusUnauthorized, api.BroadcastResponse{}, err)
+		return
+	}
+
+	b, err := h.broadcast(r, owner, true)
+	if err != nil {
+		writeBroadcastResponse(w, http.StatusNotFound, api.BroadcastResponse{}, err)
+		return
+	}
+
+	if b.BroadcasterId != owner.Id {
+		writeBroadcastResponse(w, http.StatusForbidden, api.BroadcastResponse{}, playback.ErrNotPermitted)
+		return
+	}
+
+	var request api.BroadcastUpdateRequest
+	if err = readUpdateRequest(r, &request); err != nil {
+		writeBroadcastResponse(w, http.StatusBadRequest, api.BroadcastResponse{}, err)
+		return
+	}
+
+	if b.Title, err = editTitle(b.Title, request.Title); err != nil {
+		writeBroadcastResponse(w, http.StatusBadRequest, api.BroadcastResponse{}, err)
+		return
+	}
+
+	if request.IsPublished != nil {
+		if *request.IsPublished {
+			b.Publish(time.Now())
+		} else {
+			b.Unpublish()
+		}
+	}
+
+	b, err = h.broadcasts.Update(r.Context(), b.Id, b)
+	if err != nil {
+		log.Errorf("Broadcast update failed: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	vods, err := h.broadcastVods(r.Context(), b, true)
+	if err != nil {
+		log.Errorf("Listing vods of broadcast %s failed: %v", b.Id, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writeBroadcastResponse(w, http.StatusOK, api.BroadcastResponse{Broadcast: broadcastToAPI(b), Vods: vods}, nil)
+}
+
+// NewBroadcastHandler instantiates a new BroadcastHandler.
+func NewBroadcastHandler(
+	authorizer playback.Authorizer, broadcasts BroadcastProvider, vods BroadcastVodProvider, videos VideoProvider,
+) BroadcastHandler {
+	return BroadcastHandler{
+		authorizer: authorizer,
+		broadcasts: broadcasts,
+		vods:       vods,
+		videos:     videos,
+	}
+}
@@ -0,0 +1,572 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/M-Ro/go-vodstream/api"
+	broadcastRepository "github.com/M-Ro/go-vodstream/internal/broadcast"
+	"github.com/M-Ro/go-vodstream/internal/domain/broadcast"
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	"github.com/M-Ro/go-vodstream/internal/playback"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// mockBroadcastProvider stores broadcasts in memory.
+type mockBroadcastProvider map[uuid.UUID]broadcast.Broadcast
+
+func (m mockBroadcastProvider) ListLive(
+	_ context.Context, options paginate.QueryOptions,
+) ([]broadcast.Broadcast, paginate.Page, error) {
+	broadcasts := make([]broadcast.Broadcast, 0)
+	for _, b := range m {
+		if b.IsActive && b.IsPublished && matchesBroadcast(b, options.Filters) {
+			broadcasts = append(broadcasts, b)
+		}
+	}
+
+	sort.Slice(broadcasts, func(a, b int) bool {
+		first, second := broadcasts[a], broadcasts[b]
+		if options.Order.Method == paginate.OrderMethodAsc {
+			first, second = second, first
+		}
+
+		if options.Order.Field == "viewer_count" {
+			return first.ViewerCount > second.ViewerCount
+		}
+
+		return first.PublishedAt.After(second.PublishedAt)
+	})
+
+	broadcasts, page := pageBroadcasts(broadcasts, options)
+	return broadcasts, page, nil
+}
+
+func (m mockBroadcastProvider) ListLiveByCategory(
+	_ context.Context, category string, options paginate.QueryOptions,
+) ([]broadcast.Broadcast, paginate.Page, error) {
+	broadcasts := make([]broadcast.Broadcast, 0)
+	for _, b := range m {
+		if b.IsActive && b.IsPublished && b.Category == category {
+			broadcasts = append(broadcasts, b)
+		}
+	}
+
+	sort.Slice(broadcasts, func(a, b int) bool {
+		if options.Order.Field == "viewer_count" {
+			return broadcasts[a].ViewerCount > broadcasts[b].ViewerCount
+		}
+
+		return broadcasts[a].PublishedAt.After(broadcasts[b].PublishedAt)
+	})
+
+	broadcasts, page := pageBroadcasts(broadcasts, options)
+	return broadcasts, page, nil
+}
+
+// matchesBroadcast returns true if the broadcast matches the filters on its broadcaster and
+// viewer count, the only filters tested.
+func matchesBroadcast(b broadcast.Broadcast, filters []paginate.Filter) bool {
+	for _, filter := range filters {
+		switch filter.Field {
+		case "broadcaster_id":
+			if int64(b.BroadcasterId) != filter.Values[0].(int64) {
+				return false
+			}
+		case "viewer_count":
+			from, to := filter.Values[0], filter.Values[1]
+			if (from != nil && b.ViewerCount < from.(int64)) || (to != nil && b.ViewerCount > to.(int64)) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// pageBroadcasts selects the page of the sorted broadcasts specified by the options, as
+// the SQL storage would, finding the row a cursor points at by its ID.
+func pageBroadcasts(
+	broadcasts []broadcast.Broadcast, options paginate.QueryOptions,
+) ([]broadcast.Broadcast, paginate.Page) {
+	if options.Cursor != nil {
+		at := len(broadcasts)
+		for i, b := range broadcasts {
+			if b.Id.String() == options.Cursor.Id {
+				at = i
+			}
+		}
+
+		if options.Cursor.Backward {
+			before := make([]broadcast.Broadcast, 0, at)
+			for i := at - 1; i >= 0; i-- {
+				before = append(before, broadcasts[i])
+			}
+			broadcasts = before
+		} else if at < len(broadcasts) {
+			broadcasts = broadcasts[at+1:]
+		} else {
+			broadcasts = []broadcast.Broadcast{}
+		}
+	} else if int(options.Offset) < len(broadcasts) {
+		broadcasts = broadcasts[options.Offset:]
+	} else {
+		broadcasts = []broadcast.Broadcast{}
+	}
+
+	if int(options.Limit)+1 < len(broadcasts) {
+		broadcasts = broadcasts[:options.Limit+1]
+	}
+
+	page := paginate.Paginate(&broadcasts, options, func(i int) ([]string, string) {
+		return []string{broadcastKey(broadcasts[i], options.Order.Field)}, broadcasts[i].Id.String()
+	})
+
+	return broadcasts, page
+}
+
+// broadcastKey formats the value of the field a broadcast is ordered by for a cursor.
+func broadcastKey(b broadcast.Broadcast, field string) string {
+	if field == "viewer_count" {
+		return strconv.FormatInt(b.ViewerCount, 10)
+	}
+
+	return b.PublishedAt.Format(time.RFC3339Nano)
+}
+
+func (m mockBroadcastProvider) GetByID(_ context.Context, id uuid.UUID) (broadcast.Broadcast, error) {
+	b, ok := m[id]
+	if !ok {
+		return broadcast.Broadcast{}, broadcastRepository.ErrBroadcastNotFound
+	}
+
+	return b, nil
+}
+
+func (m mockBroadcastProvider) Update(_ context.Context, id uuid.UUID, b broadcast.Broadcast) (broadcast.Broadcast, error) {
+	m[id] = b
+	return b, nil
+}
+
+// mockBroadcastVodProvider links broadcasts to videos in memory.
+type mockBroadcastVodProvider []broadcast.Vod
+
+func (m mockBroadcastVodProvider) GetByBroadcastID(_ context.Context, broadcastId uuid.UUID) ([]broadcast.Vod, error) {
+	vods := make([]broadcast.Vod, 0)
+	for _, vod := range m {
+		if vod.BroadcastId == broadcastId {
+			vods = append(vods, vod)
+		}
+	}
+
+	return vods, nil
+}
+
+var (
+	liveBroadcastId    = uuid.MustParse("6e0b9c8a-4d3f-4a1e-8b7c-5d4e3f2a1b01")
+	endedBroadcastId   = uuid.MustParse("6e0b9c8a-4d3f-4a1e-8b7c-5d4e3f2a1b02")
+	hiddenBroadcastId  = uuid.MustParse("6e0b9c8a-4d3f-4a1e-8b7c-5d4e3f2a1b03")
+	viewerBroadcastId  = uuid.MustParse("6e0b9c8a-4d3f-4a1e-8b7c-5d4e3f2a1b04")
+	testBroadcastStart = time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)
+)
+
+func newMockBroadcasts() mockBroadcastProvider {
+	return mockBroadcastProvider{
+		liveBroadcastId: {
+			Id: liveBroadcastId, BroadcasterId: 1, Title: "live", Category: "speedruns", IsActive: true,
+			IsPublished: true, ViewerCount: 50, PublishedAt: testBroadcastStart,
+		},
+		endedBroadcastId: {
+			Id: endedBroadcastId, BroadcasterId: 1, Title: "ended", IsPublished: true,
+			PublishedAt: testBroadcastStart.Add(-24 * time.Hour),
+		},
+		hiddenBroadcastId: {
+			Id: hiddenBroadcastId, BroadcasterId: 1, Title: "hidden", Category: "speedruns", IsActive: true,
+			PublishedAt: testBroadcastStart.Add(time.Hour),
+		},
+		viewerBroadcastId: {
+			Id: viewerBroadcastId, BroadcasterId: 2, Title: "viewer live", Category: "speedruns", IsActive: true,
+			IsPublished: true, ViewerCount: 10, PublishedAt: testBroadcastStart.Add(2 * time.Hour),
+		},
+	}
+}
+
+func newMockBroadcastVods() mockBroadcastVodProvider {
+	return mockBroadcastVodProvider{
+		{Id: uuid.New(), BroadcastId: endedBroadcastId, VideoId: publishedVideoId},
+		{Id: uuid.New(), BroadcastId: endedBroadcastId, VideoId: unpublishedVideoId},
+		{Id: uuid.New(), BroadcastId: endedBroadcastId, VideoId: uuid.New()},
+	}
+}
+
+func newTestBroadcastRouter(broadcasts mockBroadcastProvider) *mux.Router {
+	r := mux.NewRouter()
+
+	authorizer := playback.NewAuthorizer(testPlaybackConfig, testPlaybackUsers)
+	handler := NewBroadcastHandler(authorizer, broadcasts, newMockBroadcastVods(), newMockVideos())
+	handler.RegisterRoutes(r)
+
+	return r
+}
+
+func TestBroadcastHandler_List(t *testing.T) {
+	viewerLiveCursor := paginate.Cursor{
+		Field: "published_at",
+		Value: testBroadcastStart.Add(2 * time.Hour).Format(time.RFC3339Nano),
+		Id:    viewerBroadcastId.String(),
+	}
+	liveCursorBack := paginate.Cursor{
+		Field:    "published_at",
+		Value:    testBroadcastStart.Format(time.RFC3339Nano),
+		Id:       liveBroadcastId.String(),
+		Backward: true,
+	}
+
+	tests := []struct {
+		testName string
+		endpoint string
+
+		respStatus int
+		respErrors []string
+		respTitles []string
+		respPage   api.Page
+	}{
+		{
+			testName:   "Expect success (200) listing live, published broadcasts, newest first.",
+			endpoint:   "/v1/broadcasts",
+			respStatus: 200,
+			respErrors: []string{},
+			respTitles: []string{"viewer live", "live"},
+		},
+		{
+			testName:   "Expect success (200) limiting the broadcasts listed, with a cursor to the next page.",
+			endpoint:   "/v1/broadcasts?limit=1",
+			respStatus: 200,
+			respErrors: []string{},
+			respTitles: []string{"viewer live"},
+			respPage:   api.Page{Next: viewerLiveCursor.Encode()},
+		},
+		{
+			testName:   "Expect success (200) listing the page after a cursor, with a cursor back.",
+			endpoint:   "/v1/broadcasts?limit=1&cursor=" + viewerLiveCursor.Encode(),
+			respStatus: 200,
+			respErrors: []string{},
+			respTitles: []string{"live"},
+			respPage:   api.Page{Prev: liveCursorBack.Encode()},
+		},
+		{
+			testName:   "Expect success (200) listing the page before a cursor.",
+			endpoint:   "/v1/broadcasts?limit=1&cursor=" + liveCursorBack.Encode(),
+			respStatus: 200,
+			respErrors: []string{},
+			respTitles: []string{"viewer live"},
+			respPage:   api.Page{Next: viewerLiveCursor.Encode()},
+		},
+		{
+			testName:   "Expect success (200) listing broadcasts sorted by the sort query parameter.",
+			endpoint:   "/v1/broadcasts?sort=-viewer_count",
+			respStatus: 200,
+			respErrors: []string{},
+			respTitles: []string{"live", "viewer live"},
+		},
+		{
+			testName:   "Expect success (200) listing broadcasts filtered by their fields.",
+			endpoint:   "/v1/broadcasts?broadcaster_id=2",
+			respStatus: 200,
+			respErrors: []string{},
+			respTitles: []string{"viewer live"},
+		},
+		{
+			testName:   "Expect success (200) listing broadcasts filtered by a range.",
+			endpoint:   "/v1/broadcasts?viewer_count[gte]=20&viewer_count[lte]=100",
+			respStatus: 200,
+			respErrors: []string{},
+			respTitles: []string{"live"},
+		},
+		{
+			testName:   "Expect error (400) listing sorted by a field that may not be sorted by.",
+			endpoint:   "/v1/broadcasts?sort=-broadcaster_id",
+			respStatus: 400,
+			respErrors: []string{paginate.ErrInvalidSort.Error() + `: "broadcaster_id"`},
+			respTitles: []string{},
+		},
+		{
+			testName:   "Expect error (400) listing filtered by a value of another kind.",
+			endpoint:   "/v1/broadcasts?viewer_count[gte]=many",
+			respStatus: 400,
+			respErrors: []string{paginate.ErrInvalidFilter.Error() + `: "viewer_count[gte]"`},
+			respTitles: []string{},
+		},
+		{
+			testName:   "Expect error (400) listing with an invalid page.",
+			endpoint:   "/v1/broadcasts?offset=first",
+			respStatus: 400,
+			respErrors: []string{ErrInvalidPage.Error()},
+			respTitles: []string{},
+		},
+		{
+			testName:   "Expect error (400) listing with a malformed cursor.",
+			endpoint:   "/v1/broadcasts?cursor=first",
+			respStatus: 400,
+			respErrors: []string{paginate.ErrInvalidCursor.Error()},
+			respTitles: []string{},
+		},
+		{
+			testName:   "Expect error (400) listing with a cursor taken from another order.",
+			endpoint:   "/v1/broadcasts?cursor=" + paginate.Cursor{Field: "viewer_count", Value: "10"}.Encode(),
+			respStatus: 400,
+			respErrors: []string{paginate.ErrInvalidCursor.Error()},
+			respTitles: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, test.endpoint, nil)
+			newTestBroadcastRouter(newMockBroadcasts()).ServeHTTP(recorder, req)
+			resp := recorder.Result()
+
+			if !cmp.Equal(resp.StatusCode, test.respStatus) {
+				t.Fatal(cmp.Diff(resp.StatusCode, test.respStatus))
+			}
+
+			result := api.BroadcastsResponse{}
+			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(result.Errors, test.respErrors) {
+				t.Fatal(cmp.Diff(result.Errors, test.respErrors))
+			}
+
+			titles := make([]string, 0, len(result.Broadcasts))
+			for _, b := range result.Broadcasts {
+				titles = append(titles, b.Title)
+			}
+
+			if !cmp.Equal(titles, test.respTitles) {
+				t.Fatal(cmp.Diff(titles, test.respTitles))
+			}
+
+			if !cmp.Equal(result.Page, test.respPage) {
+				t.Fatal(cmp.Diff(result.Page, test.respPage))
+			}
+		})
+	}
+}
+
+func TestBroadcastHandler_Get(t *testing.T) {
+	tests := []struct {
+		testName string
+		endpoint string
+		username string
+
+		respStatus int
+		respErrors []string
+		respTitle  string
+		respVods   []string
+	}{
+		{
+			testName:   "Expect success (200) getting a broadcast with its published vods.",
+			endpoint:   "/v1/broadcasts/" + endedBroadcastId.String(),
+			respStatus: 200,
+			respErrors: []string{},
+			respTitle:  "ended",
+			respVods:   []string{"published"},
+		},
+		{
+			testName:   "Expect success (200) getting unpublished vods of own broadcast.",
+			endpoint:   "/v1/broadcasts/" + endedBroadcastId.String(),
+			username:   "broadcaster",
+			respStatus: 200,
+			respErrors: []string{},
+			respTitle:  "ended",
+			respVods:   []string{"published", "unpublished"},
+		},
+		{
+			testName:   "Expect success (200) getting own unpublished broadcast.",
+			endpoint:   "/v1/broadcasts/" + hiddenBroadcastId.String(),
+			username:   "broadcaster",
+			respStatus: 200,
+			respErrors: []string{},
+			respTitle:  "hidden",
+			respVods:   []string{},
+		},
+		{
+			testName:   "Expect error (404) getting another broadcaster's unpublished broadcast.",
+			endpoint:   "/v1/broadcasts/" + hiddenBroadcastId.String(),
+			username:   "viewer",
+			respStatus: 404,
+			respErrors: []string{ErrBroadcastNotFound.Error()},
+			respVods:   []string{},
+		},
+		{
+			testName:   "Expect error (404) getting an unknown broadcast.",
+			endpoint:   "/v1/broadcasts/" + uuid.New().String(),
+			respStatus: 404,
+			respErrors: []string{ErrBroadcastNotFound.Error()},
+			respVods:   []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, test.endpoint, nil)
+			if test.username != "" {
+				req.Header.Set("Authorization", "Bearer "+sessionToken(t, test.username))
+			}
+
+			newTestBroadcastRouter(newMockBroadcasts()).ServeHTTP(recorder, req)
+			resp := recorder.Result()
+
+			if !cmp.Equal(resp.StatusCode, test.respStatus) {
+				t.Fatal(cmp.Diff(resp.StatusCode, test.respStatus))
+			}
+
+			result := api.BroadcastResponse{}
+			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(result.Errors, test.respErrors) {
+				t.Fatal(cmp.Diff(result.Errors, test.respErrors))
+			}
+
+			if result.Broadcast.Title != test.respTitle {
+				t.Fatal(cmp.Diff(result.Broadcast.Title, test.respTitle))
+			}
+
+			vods := make([]string, 0, len(result.Vods))
+			for _, vod := range result.Vods {
+				vods = append(vods, vod.Video.Title)
+			}
+
+			if !cmp.Equal(vods, test.respVods) {
+				t.Fatal(cmp.Diff(vods, test.respVods))
+			}
+		})
+	}
+}
+
+func TestBroadcastHandler_Update(t *testing.T) {
+	title := func(title string) *string { return &title }
+	published := func(published bool) *bool { return &published }
+
+	tests := []struct {
+		testName string
+		endpoint string
+		username string
+		reqBody  api.BroadcastUpdateRequest
+
+		respStatus    int
+		respErrors    []string
+		respTitle     string
+		respPublished bool
+	}{
+		{
+			testName:      "Expect success (200) renaming own broadcast.",
+			endpoint:      "/v1/broadcasts/" + liveBroadcastId.String(),
+			username:      "broadcaster",
+			reqBody:       api.BroadcastUpdateRequest{Title: title("renamed")},
+			respStatus:    200,
+			respErrors:    []string{},
+			respTitle:     "renamed",
+			respPublished: true,
+		},
+		{
+			testName:      "Expect success (200) publishing own unpublished broadcast.",
+			endpoint:      "/v1/broadcasts/" + hiddenBroadcastId.String(),
+			username:      "broadcaster",
+			reqBody:       api.BroadcastUpdateRequest{IsPublished: published(true)},
+			respStatus:    200,
+			respErrors:    []string{},
+			respTitle:     "hidden",
+			respPublished: true,
+		},
+		{
+			testName:   "Expect success (200) unpublishing own broadcast.",
+			endpoint:   "/v1/broadcasts/" + liveBroadcastId.String(),
+			username:   "broadcaster",
+			reqBody:    api.BroadcastUpdateRequest{IsPublished: published(false)},
+			respStatus: 200,
+			respErrors: []string{},
+			respTitle:  "live",
+		},
+		{
+			testName:   "Expect error (400) renaming own broadcast to a blank title.",
+			endpoint:   "/v1/broadcasts/" + liveBroadcastId.String(),
+			username:   "broadcaster",
+			reqBody:    api.BroadcastUpdateRequest{Title: title("")},
+			respStatus: 400,
+			respErrors: []string{ErrMissingTitle.Error()},
+		},
+		{
+			testName:   "Expect error (401) updating without a session.",
+			endpoint:   "/v1/broadcasts/" + liveBroadcastId.String(),
+			reqBody:    api.BroadcastUpdateRequest{Title: title("renamed")},
+			respStatus: 401,
+			respErrors: []string{playback.ErrTokenInvalid.Error()},
+		},
+		{
+			testName:   "Expect error (403) updating another broadcaster's broadcast.",
+			endpoint:   "/v1/broadcasts/" + viewerBroadcastId.String(),
+			username:   "broadcaster",
+			reqBody:    api.BroadcastUpdateRequest{Title: title("renamed")},
+			respStatus: 403,
+			respErrors: []string{playback.ErrNotPermitted.Error()},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			b, err := json.Marshal(test.reqBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPatch, test.endpoint, bytes.NewReader(b))
+			if test.username != "" {
+				req.Header.Set("Authorization", "Bearer "+sessionToken(t, test.username))
+			}
+
+			broadcasts := newMockBroadcasts()
+			newTestBroadcastRouter(broadcasts).ServeHTTP(recorder, req)
+			resp := recorder.Result()
+
+			if !cmp.Equal(resp.StatusCode, test.respStatus) {
+				t.Fatal(cmp.Diff(resp.StatusCode, test.respStatus))
+			}
+
+			result := api.BroadcastResponse{}
+			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(result.Errors, test.respErrors) {
+				t.Fatal(cmp.Diff(result.Errors, test.respErrors))
+			}
+
+			if result.Broadcast.Title != test.respTitle || result.Broadcast.IsPublished != test.respPublished {
+				t.Fatalf("expected %q published %t, got %+v", test.respTitle, test.respPublished, result.Broadcast)
+			}
+
+			if test.respStatus == http.StatusOK {
+				stored := broadcastToAPI(broadcasts[uuid.MustParse(result.Broadcast.ID)])
+				if !cmp.Equal(stored, result.Broadcast) {
+					t.Fatal(cmp.Diff(stored, result.Broadcast))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,349 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/M-Ro/go-vodstream/api"
+	"github.com/M-Ro/go-vodstream/internal/domain/broadcast"
+	"github.com/M-Ro/go-vodstream/internal/domain/category"
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	"github.com/M-Ro/go-vodstream/internal/playback"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxCategorySlugLength        = 64
+	maxCategoryNameLength        = 64
+	maxCategoryDescriptionLength = 2000
+)
+
+var (
+	ErrCategoryNotFound    = errors.New("category does not exist")
+	ErrCategoryExists      = errors.New("category already exists")
+	ErrCategorySlug        = errors.New("slug must be at most 64 lowercase letters, digits and single hyphens")
+	ErrCategoryName        = errors.New("name must be between 1 and 64 characters")
+	ErrCategoryDescription = errors.New("description must be at most 2000 characters")
+	ErrCategoryImageURL    = errors.New("image must be an http:// or https:// url")
+	ErrCategoryInvalidSort = errors.New("sort must be one of viewers or started")
+	ErrNotAdmin            = errors.New("user is not permitted to manage categories")
+)
+
+// categorySlug matches the slugs categories are addressed by, such as "just-chatting".
+var categorySlug = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// categoryBroadcastSorts maps the sort query parameter of broadcasts within a
+// category to the field they are ordered by, most first.
+var categoryBroadcastSorts = map[string]string{
+	"":        "viewer_count",
+	"viewers": "viewer_count",
+	"started": "published_at",
+}
+
+// CategoryProvider stores the categories of the directory.
+type CategoryProvider interface {
+	List(ctx context.Context, options paginate.QueryOptions) ([]category.Category, paginate.Page, error)
+	GetBySlug(ctx context.Context, slug string) (category.Category, error)
+	Insert(ctx context.Context, category *category.Category) error
+	Update(ctx context.Context, slug string, category category.Category) (category.Category, error)
+	Delete(ctx context.Context, slug string) error
+}
+
+// CategoryBroadcastProvider lists the live broadcasts within a category.
+type CategoryBroadcastProvider interface {
+	ListLiveByCategory(
+		ctx context.Context, category string, options paginate.QueryOptions,
+	) ([]broadcast.Broadcast, paginate.Page, error)
+}
+
+// CategoryHandler serves the category directory for browsing live broadcasts,
+// and lets admins manage its categories.
+type CategoryHandler struct {
+	authorizer playback.Authorizer
+	categories CategoryProvider
+	broadcasts CategoryBroadcastProvider
+}
+
+func (h *CategoryHandler) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc("/v1/categories", h.List).Methods(http.MethodGet)
+	r.HandleFunc("/v1/categories", h.Create).Methods(http.MethodPost)
+	r.HandleFunc("/v1/categories/{slug}", h.Get).Methods(http.MethodGet)
+	r.HandleFunc("/v1/categories/{slug}", h.Update).Methods(http.MethodPut)
+	r.HandleFunc("/v1/categories/{slug}", h.Delete).Methods(http.MethodDelete)
+	r.HandleFunc("/v1/categories/{slug}/broadcasts", h.Broadcasts).Methods(http.MethodGet)
+}
+
+// categoryToAPI converts a category to its API representation.
+func categoryToAPI(c category.Category) api.Category {
+	return api.Category{
+		Slug:        c.Slug,
+		Name:        c.Name,
+		Description: c.Description,
+		ImageURL:    c.ImageURL,
+		Viewers:     c.Viewers,
+		Broadcasts:  c.Broadcasts,
+	}
+}
+
+// writeCategoryResponse encodes the response, filling the error list when err is set.
+func writeCategoryResponse(w http.ResponseWriter, status int, response api.CategoryResponse, err error) {
+	response.Success = err == nil
+	response.Errors = []string{}
+	if err != nil {
+		response.Errors = append(response.Errors, err.Error())
+	}
+
+	writeJSON(w, status, "Category", response)
+}
+
+// writeCategoriesResponse encodes the response, filling the error list when err is set.
+func writeCategoriesResponse(w http.ResponseWriter, status int, response api.CategoriesResponse, err error) {
+	response.Success = err == nil
+	response.Errors = []string{}
+	if err != nil {
+		response.Errors = append(response.Errors, err.Error())
+	}
+
+	if response.Categories == nil {
+		response.Categories = []api.Category{}
+	}
+
+	writeJSON(w, status, "Listing categories", response)
+}
+
+// readCategoryRequest decodes and validates a request creating or editing a
+// category, returning the category it describes.
+func readCategoryRequest(r *http.Request) (category.Category, error) {
+	var categoryRequest api.CategoryRequest
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return category.Category{}, err
+	}
+
+	if err = json.Unmarshal(body, &categoryRequest); err != nil {
+		return category.Category{}, err
+	}
+
+	edited := category.Category{
+		Slug:        strings.TrimSpace(categoryRequest.Slug),
+		Name:        strings.TrimSpace(categoryRequest.Name),
+		Description: strings.TrimSpace(categoryRequest.Description),
+		ImageURL:    strings.TrimSpace(categoryRequest.ImageURL),
+	}
+
+	if edited.Name == "" || utf8.RuneCountInString(edited.Name) > maxCategoryNameLength {
+		return category.Category{}, ErrCategoryName
+	}
+
+	if utf8.RuneCountInString(edited.Description) > maxCategoryDescriptionLength {
+		return category.Category{}, ErrCategoryDescription
+	}
+
+	if !imageURL(edited.ImageURL) {
+		return category.Category{}, ErrCategoryImageURL
+	}
+
+	return edited, nil
+}
+
+// validSlug returns true if slug may address a category.
+func validSlug(slug string) bool {
+	return len(slug) <= maxCategorySlugLength && categorySlug.MatchString(slug)
+}
+
+// authorizeAdmin returns the status and error to respond with unless the
+// request was made by an admin.
+func (h *CategoryHandler) authorizeAdmin(r *http.Request) (int, error) {
+	admin, err := h.authorizer.Authenticate(r.Context(), playback.TokenFromRequest(r))
+	if err != nil {
+		return http.StatusUnauthorized, err
+	}
+
+	if !admin.IsAdmin {
+		return http.StatusForbidden, ErrNotAdmin
+	}
+
+	return http.StatusOK, nil
+}
+
+// List returns the categories of the directory, most watched first, paged with
+// the limit and offset or cursor query parameters. They may be ordered otherwise
+// by the sort query parameter, and filtered by query parameters named by the
+// fields of category.Fields.
+func (h *CategoryHandler) List(w http.ResponseWriter, r *http.Request) {
+	options, err := pageOptions(r.URL.Query(), "viewers", category.Fields)
+	if err != nil {
+		writeCategoriesResponse(w, http.StatusBadRequest, api.CategoriesResponse{}, err)
+		return
+	}
+
+	categories, page, err := h.categories.List(r.Context(), paginate.NewPaginateOptions(options...))
+	if err != nil {
+		log.Errorf("Listing categories failed: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	response := api.CategoriesResponse{Categories: make([]api.Category, 0, len(categories)), Page: pageToAPI(page)}
+	for _, c := range categories {
+		response.Categories = append(response.Categories, categoryToAPI(c))
+	}
+
+	writeCategoriesResponse(w, http.StatusOK, response, nil)
+}
+
+// Get returns a category with the totals of its live broadcasts.
+func (h *CategoryHandler) Get(w http.ResponseWriter, r *http.Request) {
+	c, err := h.categories.GetBySlug(r.Context(), mux.Vars(r)["slug"])
+	if err != nil {
+		writeCategoryResponse(w, http.StatusNotFound, api.CategoryResponse{}, ErrCategoryNotFound)
+		return
+	}
+
+	writeCategoryResponse(w, http.StatusOK, api.CategoryResponse{Category: categoryToAPI(c)}, nil)
+}
+
+// Broadcasts returns the live, published broadcasts within a category, sorted
+// by the sort query parameter as the most watched or most recently started
+// first, and paged with the limit and offset or cursor query parameters.
+func (h *CategoryHandler) Broadcasts(w http.ResponseWriter, r *http.Request) {
+	c, err := h.categories.GetBySlug(r.Context(), mux.Vars(r)["slug"])
+	if err != nil {
+		writeBroadcastsResponse(w, http.StatusNotFound, api.BroadcastsResponse{}, ErrCategoryNotFound)
+		return
+	}
+
+	orderField, ok := categoryBroadcastSorts[r.URL.Query().Get("sort")]
+	if !ok {
+		writeBroadcastsResponse(w, http.StatusBadRequest, api.BroadcastsResponse{}, ErrCategoryInvalidSort)
+		return
+	}
+
+	options, err := pageOptions(r.URL.Query(), orderField, nil)
+	if err != nil {
+		writeBroadcastsResponse(w, http.StatusBadRequest, api.BroadcastsResponse{}, err)
+		return
+	}
+
+	broadcasts, page, err := h.broadcasts.ListLiveByCategory(r.Context(), c.Slug, paginate.NewPaginateOptions(options...))
+	if err != nil {
+		log.Errorf("Listing live broadcasts of category %s failed: %v", c.Slug, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	response := api.BroadcastsResponse{Broadcasts: make([]api.Broadcast, 0, len(broadcasts)), Page: pageToAPI(page)}
+	for _, b := range broadcasts {
+		response.Broadcasts = append(response.Broadcasts, broadcastToAPI(b))
+	}
+
+	writeBroadcastsResponse(w, http.StatusOK, response, nil)
+}
+
+// Create adds a category to the directory. Only admins may create categories.
+func (h *CategoryHandler) Create(w http.ResponseWriter, r *http.Request) {
+	if status, err := h.authorizeAdmin(r); err != nil {
+		writeCategoryResponse(w, status, api.CategoryResponse{}, err)
+		return
+	}
+
+	created, err := readCategoryRequest(r)
+	if err != nil {
+		writeCategoryResponse(w, http.StatusBadRequest, api.CategoryResponse{}, err)
+		return
+	}
+
+	if !validSlug(created.Slug) {
+		writeCategoryResponse(w, http.StatusBadRequest, api.CategoryResponse{}, ErrCategorySlug)
+		return
+	}
+
+	if _, err := h.categories.GetBySlug(r.Context(), created.Slug); err == nil {
+		writeCategoryResponse(w, http.StatusConflict, api.CategoryResponse{}, ErrCategoryExists)
+		return
+	}
+
+	if err := h.categories.Insert(r.Context(), &created); err != nil {
+		log.Errorf("Category creation failed: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writeCategoryResponse(w, http.StatusCreated, api.CategoryResponse{Category: categoryToAPI(created)}, nil)
+}
+
+// Update edits a category of the directory. Only admins may edit categories.
+func (h *CategoryHandler) Update(w http.ResponseWriter, r *http.Request) {
+	if status, err := h.authorizeAdmin(r); err != nil {
+		writeCategoryResponse(w, status, api.CategoryResponse{}, err)
+		return
+	}
+
+	current, err := h.categories.GetBySlug(r.Context(), mux.Vars(r)["slug"])
+	if err != nil {
+		writeCategoryResponse(w, http.StatusNotFound, api.CategoryResponse{}, ErrCategoryNotFound)
+		return
+	}
+
+	edited, err := readCategoryRequest(r)
+	if err != nil {
+		writeCategoryResponse(w, http.StatusBadRequest, api.CategoryResponse{}, err)
+		return
+	}
+
+	edited.Slug = current.Slug
+	edited.CreatedAt = current.CreatedAt
+
+	updated, err := h.categories.Update(r.Context(), current.Slug, edited)
+	if err != nil {
+		log.Errorf("Category update failed: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	updated.Viewers, updated.Broadcasts = current.Viewers, current.Broadcasts
+
+	writeCategoryResponse(w, http.StatusOK, api.CategoryResponse{Category: categoryToAPI(updated)}, nil)
+}
+
+// Delete removes a category from the directory. Channels and broadcasts in the
+// category keep it until edited, but are no longer browsable through it. Only
+// admins may delete categories.
+func (h *CategoryHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if status, err := h.authorizeAdmin(r); err != nil {
+		writeCategoryResponse(w, status, api.CategoryResponse{}, err)
+		return
+	}
+
+	deleted, err := h.categories.GetBySlug(r.Context(), mux.Vars(r)["slug"])
+	if err != nil {
+		writeCategoryResponse(w, http.StatusNotFound, api.CategoryResponse{}, ErrCategoryNotFound)
+		return
+	}
+
+	if err := h.categories.Delete(r.Context(), deleted.Slug); err != nil {
+		log.Errorf("Category deletion failed: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writeCategoryResponse(w, http.StatusOK, api.CategoryResponse{Category: categoryToAPI(deleted)}, nil)
+}
+
+// NewCategoryHandler instantiates a new CategoryHandler.
+func NewCategoryHandler(
+	authorizer playback.Authorizer, categories CategoryProvider, broadcasts CategoryBroadcastProvider,
+) CategoryHandler {
+	return CategoryHandler{
+		authorizer: authorizer,
+		categories: categories,
+		broadcasts: broadcasts,
+	}
+}
@@ -0,0 +1,405 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/M-Ro/go-vodstream/api"
+	categoryRepository "github.com/M-Ro/go-vodstream/internal/category"
+	"github.com/M-Ro/go-vodstream/internal/domain/category"
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	"github.com/M-Ro/go-vodstream/internal/playback"
+	"github.com/google/go-cmp/cmp"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mockCategoryProvider stores categories in memory, keyed by slug.
+type mockCategoryProvider map[string]category.Category
+
+func (m mockCategoryProvider) List(
+	_ context.Context, options paginate.QueryOptions,
+) ([]category.Category, paginate.Page, error) {
+	categories := make([]category.Category, 0, len(m))
+	for _, c := range m {
+		categories = append(categories, c)
+	}
+
+	sort.Slice(categories, func(a, b int) bool {
+		if categories[a].Viewers != categories[b].Viewers {
+			return categories[a].Viewers > categories[b].Viewers
+		}
+
+		return categories[a].Slug < categories[b].Slug
+	})
+
+	if int(options.Offset) >= len(categories) {
+		return []category.Category{}, paginate.Page{}, nil
+	}
+	categories = categories[options.Offset:]
+
+	if int(options.Limit)+1 < len(categories) {
+		categories = categories[:options.Limit+1]
+	}
+
+	page := paginate.Paginate(&categories, options, func(i int) ([]string, string) {
+		return []string{strconv.FormatInt(categories[i].Viewers, 10)}, categories[i].Slug
+	})
+
+	return categories, page, nil
+}
+
+func (m mockCategoryProvider) GetBySlug(_ context.Context, slug string) (category.Category, error) {
+	c, ok := m[slug]
+	if !ok {
+		return category.Category{}, categoryRepository.ErrCategoryNotFound
+	}
+
+	return c, nil
+}
+
+func (m mockCategoryProvider) Insert(_ context.Context, c *category.Category) error {
+	c.CreatedAt = time.Now()
+	c.UpdatedAt = c.CreatedAt
+	m[c.Slug] = *c
+
+	return nil
+}
+
+func (m mockCategoryProvider) Update(_ context.Context, slug string, c category.Category) (category.Category, error) {
+	c.UpdatedAt = time.Now()
+	m[slug] = c
+
+	return c, nil
+}
+
+func (m mockCategoryProvider) Delete(_ context.Context, slug string) error {
+	delete(m, slug)
+	return nil
+}
+
+func newMockCategories() mockCategoryProvider {
+	return mockCategoryProvider{
+		"speedruns":     {Slug: "speedruns", Name: "Speedruns", Viewers: 60, Broadcasts: 2},
+		"just-chatting": {Slug: "just-chatting", Name: "Just Chatting", Viewers: 120, Broadcasts: 3},
+		"music":         {Slug: "music", Name: "Music"},
+	}
+}
+
+func newTestCategoryRouter(categories mockCategoryProvider) *mux.Router {
+	r := mux.NewRouter()
+
+	authorizer := playback.NewAuthorizer(testPlaybackConfig, testPlaybackUsers)
+	handler := NewCategoryHandler(authorizer, categories, newMockBroadcasts())
+	handler.RegisterRoutes(r)
+
+	return r
+}
+
+func TestCategoryHandler_List(t *testing.T) {
+	tests := []struct {
+		testName string
+		endpoint string
+
+		respStatus int
+		respErrors []string
+		respSlugs  []string
+	}{
+		{
+			testName:   "Expect success (200) listing categories, most watched first.",
+			endpoint:   "/v1/categories",
+			respStatus: 200,
+			respErrors: []string{},
+			respSlugs:  []string{"just-chatting", "speedruns", "music"},
+		},
+		{
+			testName:   "Expect success (200) paging categories.",
+			endpoint:   "/v1/categories?limit=1&offset=1",
+			respStatus: 200,
+			respErrors: []string{},
+			respSlugs:  []string{"speedruns"},
+		},
+		{
+			testName:   "Expect error (400) listing with an invalid page.",
+			endpoint:   "/v1/categories?offset=-1",
+			respStatus: 400,
+			respErrors: []string{ErrInvalidPage.Error()},
+			respSlugs:  []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, test.endpoint, nil)
+
+			newTestCategoryRouter(newMockCategories()).ServeHTTP(recorder, req)
+			resp := recorder.Result()
+
+			if !cmp.Equal(resp.StatusCode, test.respStatus) {
+				t.Fatal(cmp.Diff(resp.StatusCode, test.respStatus))
+			}
+
+			result := api.CategoriesResponse{}
+			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(result.Errors, test.respErrors) {
+				t.Fatal(cmp.Diff(result.Errors, test.respErrors))
+			}
+
+			slugs := make([]string, 0, len(result.Categories))
+			for _, c := range result.Categories {
+				slugs = append(slugs, c.Slug)
+			}
+
+			if !cmp.Equal(slugs, test.respSlugs) {
+				t.Fatal(cmp.Diff(slugs, test.respSlugs))
+			}
+		})
+	}
+}
+
+func TestCategoryHandler_Broadcasts(t *testing.T) {
+	tests := []struct {
+		testName string
+		endpoint string
+
+		respStatus int
+		respErrors []string
+		respTitles []string
+	}{
+		{
+			testName:   "Expect success (200) listing the live broadcasts of a category, most watched first.",
+			endpoint:   "/v1/categories/speedruns/broadcasts",
+			respStatus: 200,
+			respErrors: []string{},
+			respTitles: []string{"live", "viewer live"},
+		},
+		{
+			testName:   "Expect success (200) listing the live broadcasts of a category, most recently started first.",
+			endpoint:   "/v1/categories/speedruns/broadcasts?sort=started",
+			respStatus: 200,
+			respErrors: []string{},
+			respTitles: []string{"viewer live", "live"},
+		},
+		{
+			testName:   "Expect success (200) listing a category without live broadcasts.",
+			endpoint:   "/v1/categories/music/broadcasts",
+			respStatus: 200,
+			respErrors: []string{},
+			respTitles: []string{},
+		},
+		{
+			testName:   "Expect error (400) sorting by an unknown field.",
+			endpoint:   "/v1/categories/speedruns/broadcasts?sort=title",
+			respStatus: 400,
+			respErrors: []string{ErrCategoryInvalidSort.Error()},
+			respTitles: []string{},
+		},
+		{
+			testName:   "Expect error (404) listing an unknown category.",
+			endpoint:   "/v1/categories/unknown/broadcasts",
+			respStatus: 404,
+			respErrors: []string{ErrCategoryNotFound.Error()},
+			respTitles: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, test.endpoint, nil)
+
+			newTestCategoryRouter(newMockCategories()).ServeHTTP(recorder, req)
+			resp := recorder.Result()
+
+			if !cmp.Equal(resp.StatusCode, test.respStatus) {
+				t.Fatal(cmp.Diff(resp.StatusCode, test.respStatus))
+			}
+
+			result := api.BroadcastsResponse{}
+			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(result.Errors, test.respErrors) {
+				t.Fatal(cmp.Diff(result.Errors, test.respErrors))
+			}
+
+			titles := make([]string, 0, len(result.Broadcasts))
+			for _, b := range result.Broadcasts {
+				titles = append(titles, b.Title)
+			}
+
+			if !cmp.Equal(titles, test.respTitles) {
+				t.Fatal(cmp.Diff(titles, test.respTitles))
+			}
+		})
+	}
+}
+
+func TestCategoryHandler_Manage(t *testing.T) {
+	tests := []struct {
+		testName string
+		method   string
+		endpoint string
+		username string
+		reqBody  api.CategoryRequest
+
+		respStatus   int
+		respErrors   []string
+		respCategory api.Category
+		respStored   *category.Category
+	}{
+		{
+			testName:     "Expect success (201) creating a category as an admin.",
+			method:       http.MethodPost,
+			endpoint:     "/v1/categories",
+			username:     "admin",
+			reqBody:      api.CategoryRequest{Slug: "retro-games", Name: " Retro Games ", ImageURL: "https://example.com/retro.png"},
+			respStatus:   201,
+			respErrors:   []string{},
+			respCategory: api.Category{Slug: "retro-games", Name: "Retro Games", ImageURL: "https://example.com/retro.png"},
+			respStored:   &category.Category{Slug: "retro-games", Name: "Retro Games", ImageURL: "https://example.com/retro.png"},
+		},
+		{
+			testName:   "Expect error (400) creating a category with an invalid slug.",
+			method:     http.MethodPost,
+			endpoint:   "/v1/categories",
+			username:   "admin",
+			reqBody:    api.CategoryRequest{Slug: "Retro Games", Name: "Retro Games"},
+			respStatus: 400,
+			respErrors: []string{ErrCategorySlug.Error()},
+		},
+		{
+			testName:   "Expect error (400) creating a category without a name.",
+			method:     http.MethodPost,
+			endpoint:   "/v1/categories",
+			username:   "admin",
+			reqBody:    api.CategoryRequest{Slug: "retro-games"},
+			respStatus: 400,
+			respErrors: []string{ErrCategoryName.Error()},
+		},
+		{
+			testName:   "Expect error (409) creating a category that already exists.",
+			method:     http.MethodPost,
+			endpoint:   "/v1/categories",
+			username:   "admin",
+			reqBody:    api.CategoryRequest{Slug: "music", Name: "Music"},
+			respStatus: 409,
+			respErrors: []string{ErrCategoryExists.Error()},
+		},
+		{
+			testName:   "Expect error (401) creating a category without a session.",
+			method:     http.MethodPost,
+			endpoint:   "/v1/categories",
+			reqBody:    api.CategoryRequest{Slug: "retro-games", Name: "Retro Games"},
+			respStatus: 401,
+			respErrors: []string{playback.ErrTokenInvalid.Error()},
+		},
+		{
+			testName:   "Expect error (403) creating a category as a broadcaster.",
+			method:     http.MethodPost,
+			endpoint:   "/v1/categories",
+			username:   "broadcaster",
+			reqBody:    api.CategoryRequest{Slug: "retro-games", Name: "Retro Games"},
+			respStatus: 403,
+			respErrors: []string{ErrNotAdmin.Error()},
+		},
+		{
+			testName:     "Expect success (200) renaming a category as an admin, keeping its slug and live totals.",
+			method:       http.MethodPut,
+			endpoint:     "/v1/categories/speedruns",
+			username:     "admin",
+			reqBody:      api.CategoryRequest{Slug: "ignored", Name: "Speedrunning", Description: strings.Repeat("a", 10)},
+			respStatus:   200,
+			respErrors:   []string{},
+			respCategory: api.Category{Slug: "speedruns", Name: "Speedrunning", Description: "aaaaaaaaaa", Viewers: 60, Broadcasts: 2},
+			respStored:   &category.Category{Slug: "speedruns", Name: "Speedrunning", Description: "aaaaaaaaaa"},
+		},
+		{
+			testName:   "Expect error (404) editing an unknown category.",
+			method:     http.MethodPut,
+			endpoint:   "/v1/categories/unknown",
+			username:   "admin",
+			reqBody:    api.CategoryRequest{Name: "Unknown"},
+			respStatus: 404,
+			respErrors: []string{ErrCategoryNotFound.Error()},
+		},
+		{
+			testName:     "Expect success (200) deleting a category as an admin.",
+			method:       http.MethodDelete,
+			endpoint:     "/v1/categories/music",
+			username:     "admin",
+			respStatus:   200,
+			respErrors:   []string{},
+			respCategory: api.Category{Slug: "music", Name: "Music"},
+		},
+		{
+			testName:   "Expect error (403) deleting a category as a viewer.",
+			method:     http.MethodDelete,
+			endpoint:   "/v1/categories/music",
+			username:   "viewer",
+			respStatus: 403,
+			respErrors: []string{ErrNotAdmin.Error()},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			b, err := json.Marshal(test.reqBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest(test.method, test.endpoint, bytes.NewReader(b))
+			if test.username != "" {
+				req.Header.Set("Authorization", "Bearer "+sessionToken(t, test.username))
+			}
+
+			categories := newMockCategories()
+			newTestCategoryRouter(categories).ServeHTTP(recorder, req)
+			resp := recorder.Result()
+
+			if !cmp.Equal(resp.StatusCode, test.respStatus) {
+				t.Fatal(cmp.Diff(resp.StatusCode, test.respStatus))
+			}
+
+			result := api.CategoryResponse{}
+			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(result.Errors, test.respErrors) {
+				t.Fatal(cmp.Diff(result.Errors, test.respErrors))
+			}
+
+			if !cmp.Equal(result.Category, test.respCategory) {
+				t.Fatal(cmp.Diff(result.Category, test.respCategory))
+			}
+
+			if test.method == http.MethodDelete && test.respStatus == http.StatusOK {
+				if _, ok := categories[test.respCategory.Slug]; ok {
+					t.Fatalf("expected category %s deleted", test.respCategory.Slug)
+				}
+			}
+
+			if test.respStored != nil {
+				stored := categories[test.respStored.Slug]
+				stored.CreatedAt, stored.UpdatedAt = time.Time{}, time.Time{}
+				if !cmp.Equal(stored, *test.respStored) {
+					t.Fatal(cmp.Diff(stored, *test.respStored))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/M-Ro/go-vodstream/api"
+	"github.com/M-Ro/go-vodstream/internal/domain/channel"
+	"github.com/M-Ro/go-vodstream/internal/domain/user"
+	"github.com/M-Ro/go-vodstream/internal/playback"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxDisplayNameLength  = 64
+	maxDescriptionLength  = 2000
+	maxDefaultTitleLength = 140
+	maxTags               = 10
+	maxTagLength          = 25
+)
+
+var (
+	ErrChannelNotFound     = errors.New("channel does not exist")
+	ErrChannelDisplayName  = errors.New("display name must be at most 64 characters")
+	ErrChannelDescription  = errors.New("description must be at most 2000 characters")
+	ErrChannelDefaultTitle = errors.New("default title must be at most 140 characters")
+	ErrChannelCategory     = errors.New("category must be the slug of a category in the directory")
+	ErrChannelImageURL     = errors.New("avatar and banner must be http:// or https:// urls")
+	ErrChannelTags         = errors.New("at most 10 tags of at most 25 characters are permitted")
+	ErrChannelLanguage     = errors.New("language must be a two letter ISO 639-1 code")
+)
+
+// ChannelProvider stores the channel page of each broadcaster.
+type ChannelProvider interface {
+	GetByBroadcasterID(ctx context.Context, broadcasterId uint64) (channel.Channel, error)
+	Save(ctx context.Context, channel channel.Channel) (channel.Channel, error)
+}
+
+// ChannelHandler serves the channel pages of broadcasters, and lets broadcasters edit theirs.
+type ChannelHandler struct {
+	authorizer playback.Authorizer
+	users      playback.UserProvider
+	channels   ChannelProvider
+	categories CategoryProvider
+}
+
+func (h *ChannelHandler) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc("/v1/channels/{name}", h.Get).Methods(http.MethodGet)
+	r.HandleFunc("/v1/channels/{name}", h.Update).Methods(http.MethodPut)
+}
+
+// channelToAPI converts the channel of a broadcaster to its API representation.
+func channelToAPI(broadcaster user.User, c channel.Channel) api.Channel {
+	tags := c.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	return api.Channel{
+		BroadcasterID: broadcaster.Id,
+		Username:      broadcaster.Username,
+		DisplayName:   c.DisplayName,
+		Description:   c.Description,
+		AvatarURL:     c.AvatarURL,
+		BannerURL:     c.BannerURL,
+		DefaultTitle:  c.DefaultTitle,
+		Category:      c.Category,
+		Tags:          tags,
+		Language:      c.Language,
+		Mature:        c.Mature,
+		UpdatedAt:     c.UpdatedAt,
+	}
+}
+
+// writeChannelResponse encodes the response, filling the error list when err is set.
+func writeChannelResponse(w http.ResponseWriter, status int, response api.ChannelResponse, err error) {
+	response.Success = err == nil
+	response.Errors = []string{}
+	if err != nil {
+		response.Errors = append(response.Errors, err.Error())
+	}
+
+	if response.Channel.Tags == nil {
+		response.Channel.Tags = []string{}
+	}
+
+	writeJSON(w, status, "Channel", response)
+}
+
+// imageURL returns true if value is empty, or an http(s) URL an image may be loaded from.
+func imageURL(value string) bool {
+	if value == "" {
+		return true
+	}
+
+	parsed, err := url.Parse(value)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
+// normaliseTags lowercases and trims tags, dropping blank and repeated tags.
+func normaliseTags(tags []string) ([]string, error) {
+	normalised := make([]string, 0, len(tags))
+	seen := make(map[string]bool)
+
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+
+		if utf8.RuneCountInString(tag) > maxTagLength {
+			return nil, ErrChannelTags
+		}
+
+		seen[tag] = true
+		normalised = append(normalised, tag)
+	}
+
+	if len(normalised) > maxTags {
+		return nil, ErrChannelTags
+	}
+
+	return normalised, nil
+}
+
+// readChannelRequest decodes and validates a request editing a channel page,
+// returning the channel it describes.
+func readChannelRequest(r *http.Request, broadcaster user.User) (channel.Channel, error) {
+	var channelRequest api.ChannelRequest
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return channel.Channel{}, err
+	}
+
+	if err = json.Unmarshal(body, &channelRequest); err != nil {
+		return channel.Channel{}, err
+	}
+
+	edited := channel.Default(broadcaster.Id, broadcaster.Username)
+	if name := strings.TrimSpace(channelRequest.DisplayName); name != "" {
+		edited.DisplayName = name
+	}
+
+	edited.Description = strings.TrimSpace(channelRequest.Description)
+	edited.AvatarURL = strings.TrimSpace(channelRequest.AvatarURL)
+	edited.BannerURL = strings.TrimSpace(channelRequest.BannerURL)
+	edited.DefaultTitle = strings.TrimSpace(channelRequest.DefaultTitle)
+	edited.Category = strings.ToLower(strings.TrimSpace(channelRequest.Category))
+	edited.Language = strings.ToLower(strings.TrimSpace(channelRequest.Language))
+	edited.Mature = channelRequest.Mature
+
+	for _, check := range []struct {
+		value string
+		max   int
+		err   error
+	}{
+		{edited.DisplayName, maxDisplayNameLength, ErrChannelDisplayName},
+		{edited.Description, maxDescriptionLength, ErrChannelDescription},
+		{edited.DefaultTitle, maxDefaultTitleLength, ErrChannelDefaultTitle},
+	} {
+		if utf8.RuneCountInString(check.value) > check.max {
+			return channel.Channel{}, check.err
+		}
+	}
+
+	if !imageURL(edited.AvatarURL) || !imageURL(edited.BannerURL) {
+		return channel.Channel{}, ErrChannelImageURL
+	}
+
+	if edited.Language != "" && (len(edited.Language) != 2 || strings.Trim(edited.Language, "abcdefghijklmnopqrstuvwxyz") != "") {
+		return channel.Channel{}, ErrChannelLanguage
+	}
+
+	if edited.Tags, err = normaliseTags(channelRequest.Tags); err != nil {
+		return channel.Channel{}, err
+	}
+
+	return edited, nil
+}
+
+// broadcaster returns the user whose channel is addressed by the request.
+func (h *ChannelHandler) broadcaster(r *http.Request) (user.User, error) {
+	broadcaster, err := h.users.GetByUsername(r.Context(), mux.Vars(r)["name"])
+	if err != nil {
+		return user.User{}, ErrChannelNotFound
+	}
+
+	return broadcaster, nil
+}
+
+// Get returns the channel page of a broadcaster. Broadcasters who have not
+// set up their channel page are returned the default.
+func (h *ChannelHandler) Get(w http.ResponseWriter, r *http.Request) {
+	broadcaster, err := h.broadcaster(r)
+	if err != nil {
+		writeChannelResponse(w, http.StatusNotFound, api.ChannelResponse{}, err)
+		return
+	}
+
+	c, err := h.channels.GetByBroadcasterID(r.Context(), broadcaster.Id)
+	if err != nil {
+		c = channel.Default(broadcaster.Id, broadcaster.Username)
+	}
+
+	writeChannelResponse(w, http.StatusOK, api.ChannelResponse{Channel: channelToAPI(broadcaster, c)}, nil)
+}
+
+// Update replaces the channel page of the authenticated broadcaster.
+func (h *ChannelHandler) Update(w http.ResponseWriter, r *http.Request) {
+	owner, err := h.authorizer.Authenticate(r.Context(), playback.TokenFromRequest(r))
+	if err != nil {
+		writeChannelResponse(w, http.StatusUnauthorized, api.ChannelResponse{}, err)
+		return
+	}
+
+	broadcaster, err := h.broadcaster(r)
+	if err != nil {
+		writeChannelResponse(w, http.StatusNotFound, api.ChannelResponse{}, err)
+		return
+	}
+
+	if broadcaster.Id != owner.Id || !owner.CanPublish {
+		writeChannelResponse(w, http.StatusForbidden, api.ChannelResponse{}, playback.ErrNotPermitted)
+		return
+	}
+
+	edited, err := readChannelRequest(r, owner)
+	if err != nil {
+		writeChannelResponse(w, http.StatusBadRequest, api.ChannelResponse{}, err)
+		return
+	}
+
+	if edited.Category != "" {
+		if _, err := h.categories.GetBySlug(r.Context(), edited.Category); err != nil {
+			writeChannelResponse(w, http.StatusBadRequest, api.ChannelResponse{}, ErrChannelCategory)
+			return
+		}
+	}
+
+	if current, err := h.channels.GetByBroadcasterID(r.Context(), owner.Id); err == nil {
+		edited.CreatedAt = current.CreatedAt
+	}
+
+	saved, err := h.channels.Save(r.Context(), edited)
+	if err != nil {
+		log.Errorf("Channel update failed: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writeChannelResponse(w, http.StatusOK, api.ChannelResponse{Channel: channelToAPI(owner, saved)}, nil)
+}
+
+// NewChannelHandler instantiates a new ChannelHandler.
+func NewChannelHandler(
+	authorizer playback.Authorizer, users playback.UserProvider, channels ChannelProvider,
+	categories CategoryProvider,
+) ChannelHandler {
+	return ChannelHandler{
+		authorizer: authorizer,
+		users:      users,
+		channels:   channels,
+		categories: categories,
+	}
+}
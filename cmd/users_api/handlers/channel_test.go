@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/M-Ro/go-vodstream/api"
+	channelRepository "github.com/M-Ro/go-vodstream/internal/channel"
+	"github.com/M-Ro/go-vodstream/internal/domain/channel"
+	"github.com/M-Ro/go-vodstream/internal/playback"
+	"github.com/google/go-cmp/cmp"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mockChannelProvider stores channels in memory, keyed by broadcaster.
+type mockChannelProvider map[uint64]channel.Channel
+
+func (m mockChannelProvider) GetByBroadcasterID(_ context.Context, broadcasterId uint64) (channel.Channel, error) {
+	c, ok := m[broadcasterId]
+	if !ok {
+		return channel.Channel{}, channelRepository.ErrChannelNotFound
+	}
+
+	return c, nil
+}
+
+func (m mockChannelProvider) Save(_ context.Context, c channel.Channel) (channel.Channel, error) {
+	c.UpdatedAt = time.Now()
+	if c.CreatedAt.IsZero() {
+		c.CreatedAt = c.UpdatedAt
+	}
+
+	m[c.BroadcasterId] = c
+	return c, nil
+}
+
+func newTestChannelRouter(channels mockChannelProvider) *mux.Router {
+	r := mux.NewRouter()
+
+	authorizer := playback.NewAuthorizer(testPlaybackConfig, testPlaybackUsers)
+	handler := NewChannelHandler(authorizer, testPlaybackUsers, channels, newMockCategories())
+	handler.RegisterRoutes(r)
+
+	return r
+}
+
+func TestChannelHandler_Get(t *testing.T) {
+	channels := mockChannelProvider{
+		1: {
+			BroadcasterId: 1,
+			DisplayName:   "The Broadcaster",
+			Category:      "speedruns",
+			Tags:          []string{"any%"},
+			Language:      "en",
+		},
+	}
+
+	tests := []struct {
+		testName string
+		endpoint string
+
+		respStatus  int
+		respErrors  []string
+		respChannel api.Channel
+	}{
+		{
+			testName:   "Expect success (200) fetching a channel page.",
+			endpoint:   "/v1/channels/broadcaster",
+			respStatus: 200,
+			respErrors: []string{},
+			respChannel: api.Channel{
+				BroadcasterID: 1,
+				Username:      "broadcaster",
+				DisplayName:   "The Broadcaster",
+				Category:      "speedruns",
+				Tags:          []string{"any%"},
+				Language:      "en",
+			},
+		},
+		{
+			testName:   "Expect success (200) fetching the default page of a channel not set up.",
+			endpoint:   "/v1/channels/viewer",
+			respStatus: 200,
+			respErrors: []string{},
+			respChannel: api.Channel{
+				BroadcasterID: 2,
+				Username:      "viewer",
+				DisplayName:   "viewer",
+				Tags:          []string{},
+			},
+		},
+		{
+			testName:    "Expect error (404) fetching the channel of an unknown user.",
+			endpoint:    "/v1/channels/unknown",
+			respStatus:  404,
+			respErrors:  []string{ErrChannelNotFound.Error()},
+			respChannel: api.Channel{Tags: []string{}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, test.endpoint, nil)
+
+			newTestChannelRouter(channels).ServeHTTP(recorder, req)
+			resp := recorder.Result()
+
+			if !cmp.Equal(resp.StatusCode, test.respStatus) {
+				t.Fatal(cmp.Diff(resp.StatusCode, test.respStatus))
+			}
+
+			result := api.ChannelResponse{}
+			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(result.Errors, test.respErrors) {
+				t.Fatal(cmp.Diff(result.Errors, test.respErrors))
+			}
+
+			if !cmp.Equal(result.Channel, test.respChannel) {
+				t.Fatal(cmp.Diff(result.Channel, test.respChannel))
+			}
+		})
+	}
+}
+
+func TestChannelHandler_Update(t *testing.T) {
+	tests := []struct {
+		testName string
+		endpoint string
+		username string
+		reqBody  api.ChannelRequest
+
+		respStatus int
+		respErrors []string
+		respStored *channel.Channel
+	}{
+		{
+			testName: "Expect success (200) editing own channel page, normalising tags and language.",
+			endpoint: "/v1/channels/broadcaster",
+			username: "broadcaster",
+			reqBody: api.ChannelRequest{
+				DisplayName:  " The Broadcaster ",
+				Description:  "Speedruns most evenings.",
+				AvatarURL:    "https://example.com/avatar.png",
+				DefaultTitle: "Any% attempts",
+				Category:     " Speedruns ",
+				Tags:         []string{"Any%", " any% ", "", "PB"},
+				Language:     "EN",
+				Mature:       true,
+			},
+			respStatus: 200,
+			respErrors: []string{},
+			respStored: &channel.Channel{
+				BroadcasterId: 1,
+				DisplayName:   "The Broadcaster",
+				Description:   "Speedruns most evenings.",
+				AvatarURL:     "https://example.com/avatar.png",
+				DefaultTitle:  "Any% attempts",
+				Category:      "speedruns",
+				Tags:          []string{"any%", "pb"},
+				Language:      "en",
+				Mature:        true,
+			},
+		},
+		{
+			testName:   "Expect success (200) falling back to the username for a blank display name.",
+			endpoint:   "/v1/channels/broadcaster",
+			username:   "broadcaster",
+			reqBody:    api.ChannelRequest{},
+			respStatus: 200,
+			respErrors: []string{},
+			respStored: &channel.Channel{BroadcasterId: 1, DisplayName: "broadcaster", Tags: []string{}},
+		},
+		{
+			testName:   "Expect error (400) setting a display name that is too long.",
+			endpoint:   "/v1/channels/broadcaster",
+			username:   "broadcaster",
+			reqBody:    api.ChannelRequest{DisplayName: strings.Repeat("a", maxDisplayNameLength+1)},
+			respStatus: 400,
+			respErrors: []string{ErrChannelDisplayName.Error()},
+		},
+		{
+			testName:   "Expect error (400) setting an avatar that is not an http(s) url.",
+			endpoint:   "/v1/channels/broadcaster",
+			username:   "broadcaster",
+			reqBody:    api.ChannelRequest{AvatarURL: "javascript:alert(1)"},
+			respStatus: 400,
+			respErrors: []string{ErrChannelImageURL.Error()},
+		},
+		{
+			testName:   "Expect error (400) setting too many tags.",
+			endpoint:   "/v1/channels/broadcaster",
+			username:   "broadcaster",
+			reqBody:    api.ChannelRequest{Tags: strings.Split("a b c d e f g h i j k", " ")},
+			respStatus: 400,
+			respErrors: []string{ErrChannelTags.Error()},
+		},
+		{
+			testName:   "Expect error (400) setting a category missing from the directory.",
+			endpoint:   "/v1/channels/broadcaster",
+			username:   "broadcaster",
+			reqBody:    api.ChannelRequest{Category: "unknown"},
+			respStatus: 400,
+			respErrors: []string{ErrChannelCategory.Error()},
+		},
+		{
+			testName:   "Expect error (400) setting an invalid language.",
+			endpoint:   "/v1/channels/broadcaster",
+			username:   "broadcaster",
+			reqBody:    api.ChannelRequest{Language: "english"},
+			respStatus: 400,
+			respErrors: []string{ErrChannelLanguage.Error()},
+		},
+		{
+			testName:   "Expect error (401) editing without a session.",
+			endpoint:   "/v1/channels/broadcaster",
+			respStatus: 401,
+			respErrors: []string{playback.ErrTokenInvalid.Error()},
+		},
+		{
+			testName:   "Expect error (403) editing another broadcaster's channel.",
+			endpoint:   "/v1/channels/broadcaster",
+			username:   "viewer",
+			respStatus: 403,
+			respErrors: []string{playback.ErrNotPermitted.Error()},
+		},
+		{
+			testName:   "Expect error (403) editing own channel without permission to publish.",
+			endpoint:   "/v1/channels/viewer",
+			username:   "viewer",
+			respStatus: 403,
+			respErrors: []string{playback.ErrNotPermitted.Error()},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			b, err := json.Marshal(test.reqBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPut, test.endpoint, bytes.NewReader(b))
+			if test.username != "" {
+				req.Header.Set("Authorization", "Bearer "+sessionToken(t, test.username))
+			}
+
+			channels := mockChannelProvider{}
+			newTestChannelRouter(channels).ServeHTTP(recorder, req)
+			resp := recorder.Result()
+
+			if !cmp.Equal(resp.StatusCode, test.respStatus) {
+				t.Fatal(cmp.Diff(resp.StatusCode, test.respStatus))
+			}
+
+			result := api.ChannelResponse{}
+			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(result.Errors, test.respErrors) {
+				t.Fatal(cmp.Diff(result.Errors, test.respErrors))
+			}
+
+			if test.respStored == nil {
+				if len(channels) != 0 {
+					t.Fatalf("expected no channel stored, got %+v", channels)
+				}
+				return
+			}
+
+			stored := channels[test.respStored.BroadcasterId]
+			stored.CreatedAt, stored.UpdatedAt = time.Time{}, time.Time{}
+			if !cmp.Equal(stored, *test.respStored) {
+				t.Fatal(cmp.Diff(stored, *test.respStored))
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/M-Ro/go-vodstream/api"
+	"github.com/M-Ro/go-vodstream/internal/domain/broadcast"
+	"github.com/M-Ro/go-vodstream/internal/domain/cluster"
+	"github.com/M-Ro/go-vodstream/internal/domain/video"
+	"github.com/M-Ro/go-vodstream/internal/playback"
+	"github.com/M-Ro/go-vodstream/internal/urlsign"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrPlaybackMissingTarget = errors.New("a channel or video is required")
+	ErrPlaybackInvalidTarget = errors.New("channel or video does not exist")
+)
+
+// PlaybackOriginProvider looks up the channels live in the cluster directory,
+// carrying the visibility their broadcasters published them with.
+type PlaybackOriginProvider interface {
+	Lookup(ctx context.Context, name string) (cluster.Origin, error)
+}
+
+// PlaybackVideoProvider looks up the videos viewers request to play.
+type PlaybackVideoProvider interface {
+	GetByID(ctx context.Context, id uuid.UUID) (video.Video, error)
+}
+
+type PlaybackHandler struct {
+	config     playback.Config
+	authorizer playback.Authorizer
+	users      playback.UserProvider
+	origins    PlaybackOriginProvider
+	videos     PlaybackVideoProvider
+	signer     urlsign.Signer
+}
+
+func (h *PlaybackHandler) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc("/v1/playback/token", h.Token).Methods(http.MethodPost)
+}
+
+// writePlaybackResponse encodes the response, filling the error list when err is set.
+func writePlaybackResponse(w http.ResponseWriter, status int, response api.PlaybackTokenResponse, err error) {
+	response.Errors = []string{}
+	if err != nil {
+		response.Errors = append(response.Errors, err.Error())
+	}
+
+	encoded, encodeErr := json.Marshal(response)
+	if encodeErr != nil {
+		log.Errorf("Playback token failed %v", encodeErr)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(encoded)
+}
+
+// Token mints a playback token and signed media URL parameters for an
+// authenticated viewer permitted to play the requested channel or video.
+func (h *PlaybackHandler) Token(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Errorf("Playback token failed: %v\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var tokenRequest api.PlaybackTokenRequest
+	err = json.Unmarshal(body, &tokenRequest)
+	if err != nil {
+		writePlaybackResponse(w, http.StatusBadRequest, api.PlaybackTokenResponse{}, err)
+		return
+	}
+
+	sessionToken := tokenRequest.Auth.AccessToken
+	if sessionToken == "" {
+		sessionToken = playback.TokenFromRequest(r)
+	}
+
+	viewer, err := h.authorizer.Authenticate(r.Context(), sessionToken)
+	if err != nil {
+		writePlaybackResponse(w, http.StatusUnauthorized, api.PlaybackTokenResponse{}, err)
+		return
+	}
+
+	response := api.PlaybackTokenResponse{}
+
+	switch {
+	case tokenRequest.Channel != "":
+		broadcaster, err := h.users.GetByUsername(r.Context(), tokenRequest.Channel)
+		if err != nil {
+			writePlaybackResponse(w, http.StatusNotFound, api.PlaybackTokenResponse{}, ErrPlaybackInvalidTarget)
+			return
+		}
+
+		resource := playback.Resource{
+			Name:          broadcaster.Username,
+			BroadcasterId: broadcaster.Id,
+			Visibility:    h.visibility(r.Context(), broadcaster.Username),
+		}
+
+		if _, err := h.authorizer.Authorize(r.Context(), resource, sessionToken); err != nil {
+			writePlaybackResponse(w, playback.StatusCode(err), api.PlaybackTokenResponse{}, err)
+			return
+		}
+
+		response.Token, err = h.authorizer.IssueToken(viewer.Username, broadcaster.Username)
+		if err != nil {
+			log.Errorf("Playback token failed, token signing error: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		response.Path = playback.LivePathPrefix(broadcaster.Username)
+	case tokenRequest.VideoID != "":
+		id, err := uuid.Parse(tokenRequest.VideoID)
+		if err != nil {
+			writePlaybackResponse(w, http.StatusBadRequest, api.PlaybackTokenResponse{}, ErrPlaybackInvalidTarget)
+			return
+		}
+
+		// Unpublished videos are hidden from everyone but their broadcaster.
+		v, err := h.videos.GetByID(r.Context(), id)
+		if err != nil || (!v.IsPublished && v.BroadcasterId != viewer.Id) {
+			writePlaybackResponse(w, http.StatusNotFound, api.PlaybackTokenResponse{}, ErrPlaybackInvalidTarget)
+			return
+		}
+
+		response.Path = playback.VodPathPrefix(v.Id)
+	default:
+		writePlaybackResponse(w, http.StatusBadRequest, api.PlaybackTokenResponse{}, ErrPlaybackMissingTarget)
+		return
+	}
+
+	options := urlsign.Options{
+		Expires:    time.Now().Add(h.config.URLDuration),
+		PathPrefix: response.Path,
+	}
+
+	if h.config.BindURLToAddress {
+		options.IP = urlsign.RemoteIP(r)
+	}
+
+	response.Success = true
+	response.Signature = h.signer.Query(response.Path, options).Encode()
+	response.ExpiresAt = options.Expires
+
+	writePlaybackResponse(w, http.StatusOK, response, nil)
+}
+
+// visibility returns the visibility the named channel is live with. Channels missing
+// from the cluster directory, or without one, are treated as public; the ingesters
+// authorize playback against the live channel itself either way.
+func (h *PlaybackHandler) visibility(ctx context.Context, name string) broadcast.Visibility {
+	if h.origins == nil {
+		return broadcast.VisibilityPublic
+	}
+
+	origin, err := h.origins.Lookup(ctx, name)
+	if err != nil {
+		return broadcast.VisibilityPublic
+	}
+
+	return origin.Visibility
+}
+
+// NewPlaybackHandler instantiates a PlaybackHandler. origins may be nil when no
+// cluster directory is available.
+func NewPlaybackHandler(
+	config playback.Config, users playback.UserProvider, origins PlaybackOriginProvider, videos PlaybackVideoProvider,
+) PlaybackHandler {
+	return PlaybackHandler{
+		config:     config,
+		authorizer: playback.NewAuthorizer(config, users),
+		users:      users,
+		origins:    origins,
+		videos:     videos,
+		signer:     playback.NewSigner(config),
+	}
+}
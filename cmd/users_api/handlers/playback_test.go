@@ -0,0 +1,297 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/M-Ro/go-vodstream/api"
+	clusterDirectory "github.com/M-Ro/go-vodstream/internal/cluster"
+	"github.com/M-Ro/go-vodstream/internal/domain"
+	"github.com/M-Ro/go-vodstream/internal/domain/broadcast"
+	"github.com/M-Ro/go-vodstream/internal/domain/cluster"
+	"github.com/M-Ro/go-vodstream/internal/domain/user"
+	"github.com/M-Ro/go-vodstream/internal/playback"
+	userRepository "github.com/M-Ro/go-vodstream/internal/user"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testPlaybackConfig = playback.Config{
+	SigningSecret:    "testSecret",
+	IssuerIdent:      "vodstream-test",
+	TokenDuration:    time.Minute,
+	URLSigningSecret: "testUrlSecret",
+	URLDuration:      time.Hour,
+}
+
+type mockUserProvider map[string]user.User
+
+func (m mockUserProvider) GetByUsername(_ context.Context, username string) (user.User, error) {
+	u, ok := m[strings.ToLower(username)]
+	if !ok {
+		return user.User{}, userRepository.ErrUserNotFound
+	}
+
+	return u, nil
+}
+
+var testPlaybackUsers = mockUserProvider{
+	"broadcaster": {Id: 1, Username: "broadcaster", CanPublish: true},
+	"viewer":      {Id: 2, Username: "viewer", CanStream: true},
+	"banned":      {Id: 3, Username: "banned", CanStream: false},
+	"admin":       {Id: 4, Username: "admin", IsAdmin: true},
+}
+
+// mockOriginProvider holds the channels live in the cluster directory.
+type mockOriginProvider map[string]cluster.Origin
+
+func (m mockOriginProvider) Lookup(_ context.Context, name string) (cluster.Origin, error) {
+	origin, ok := m[strings.ToLower(name)]
+	if !ok {
+		return cluster.Origin{}, clusterDirectory.ErrOriginNotFound
+	}
+
+	return origin, nil
+}
+
+// testPlaybackOrigins has the admin live privately, the broadcaster is offline.
+var testPlaybackOrigins = mockOriginProvider{
+	"admin": {Name: "admin", BroadcasterId: 4, Visibility: broadcast.VisibilityPrivate},
+}
+
+var (
+	testPublishedVideo   = uuid.MustParse("7c9e6679-7425-40de-944b-e07fc1f90ae7")
+	testUnpublishedVideo = uuid.MustParse("a1b2c3d4-7425-40de-944b-e07fc1f90ae7")
+)
+
+var testPlaybackVideos = mockVideoProvider{
+	testPublishedVideo:   {Id: testPublishedVideo, BroadcasterId: 1, IsPublished: true},
+	testUnpublishedVideo: {Id: testUnpublishedVideo, BroadcasterId: 1, IsPublished: false},
+}
+
+func sessionToken(t *testing.T, username string) string {
+	claims := domain.AuthClaim{
+		Username: username,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			Issuer:    testPlaybackConfig.IssuerIdent,
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testPlaybackConfig.SigningSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+func TestPlaybackHandler_Token(t *testing.T) {
+	tests := []struct {
+		testName string
+		reqBody  api.PlaybackTokenRequest
+
+		respStatus int
+		respPath   string
+		respErrors []string
+		respToken  bool
+	}{
+		{
+			testName: "Expect success (200) for viewer requesting a channel.",
+			reqBody: api.PlaybackTokenRequest{
+				Auth:    api.AuthenticationSet{AccessToken: sessionToken(t, "viewer")},
+				Channel: "Broadcaster",
+			},
+			respStatus: 200,
+			respPath:   "/live/broadcaster/",
+			respErrors: []string{},
+			respToken:  true,
+		},
+		{
+			testName: "Expect success (200) for viewer requesting a video.",
+			reqBody: api.PlaybackTokenRequest{
+				Auth:    api.AuthenticationSet{AccessToken: sessionToken(t, "viewer")},
+				VideoID: "7c9e6679-7425-40de-944b-e07fc1f90ae7",
+			},
+			respStatus: 200,
+			respPath:   "/vod/7c9e6679-7425-40de-944b-e07fc1f90ae7/",
+			respErrors: []string{},
+			respToken:  false,
+		},
+		{
+			testName: "Expect success (200) for broadcaster requesting own unpublished video.",
+			reqBody: api.PlaybackTokenRequest{
+				Auth:    api.AuthenticationSet{AccessToken: sessionToken(t, "broadcaster")},
+				VideoID: testUnpublishedVideo.String(),
+			},
+			respStatus: 200,
+			respPath:   "/vod/a1b2c3d4-7425-40de-944b-e07fc1f90ae7/",
+			respErrors: []string{},
+			respToken:  false,
+		},
+		{
+			testName: "Expect error (404) for viewer requesting an unpublished video.",
+			reqBody: api.PlaybackTokenRequest{
+				Auth:    api.AuthenticationSet{AccessToken: sessionToken(t, "viewer")},
+				VideoID: testUnpublishedVideo.String(),
+			},
+			respStatus: 404,
+			respErrors: []string{ErrPlaybackInvalidTarget.Error()},
+		},
+		{
+			testName: "Expect error (404) for unknown video.",
+			reqBody: api.PlaybackTokenRequest{
+				Auth:    api.AuthenticationSet{AccessToken: sessionToken(t, "viewer")},
+				VideoID: "00000000-7425-40de-944b-e07fc1f90ae7",
+			},
+			respStatus: 404,
+			respErrors: []string{ErrPlaybackInvalidTarget.Error()},
+		},
+		{
+			testName: "Expect success (200) for viewer without CanStream requesting a published video.",
+			reqBody: api.PlaybackTokenRequest{
+				Auth:    api.AuthenticationSet{AccessToken: sessionToken(t, "banned")},
+				VideoID: testPublishedVideo.String(),
+			},
+			respStatus: 200,
+			respPath:   "/vod/7c9e6679-7425-40de-944b-e07fc1f90ae7/",
+			respErrors: []string{},
+			respToken:  false,
+		},
+		{
+			testName: "Expect success (200) for broadcaster without CanStream requesting own channel.",
+			reqBody: api.PlaybackTokenRequest{
+				Auth:    api.AuthenticationSet{AccessToken: sessionToken(t, "broadcaster")},
+				Channel: "broadcaster",
+			},
+			respStatus: 200,
+			respPath:   "/live/broadcaster/",
+			respErrors: []string{},
+			respToken:  true,
+		},
+		{
+			testName: "Expect error (401) without a session token.",
+			reqBody: api.PlaybackTokenRequest{
+				Channel: "broadcaster",
+			},
+			respStatus: 401,
+			respErrors: []string{playback.ErrTokenInvalid.Error()},
+		},
+		{
+			testName: "Expect success (200) for viewer without CanStream requesting a public channel.",
+			reqBody: api.PlaybackTokenRequest{
+				Auth:    api.AuthenticationSet{AccessToken: sessionToken(t, "banned")},
+				Channel: "broadcaster",
+			},
+			respStatus: 200,
+			respPath:   "/live/broadcaster/",
+			respErrors: []string{},
+			respToken:  true,
+		},
+		{
+			testName: "Expect success (200) for viewer requesting a private channel.",
+			reqBody: api.PlaybackTokenRequest{
+				Auth:    api.AuthenticationSet{AccessToken: sessionToken(t, "viewer")},
+				Channel: "admin",
+			},
+			respStatus: 200,
+			respPath:   "/live/admin/",
+			respErrors: []string{},
+			respToken:  true,
+		},
+		{
+			testName: "Expect error (403) for viewer without CanStream requesting a private channel.",
+			reqBody: api.PlaybackTokenRequest{
+				Auth:    api.AuthenticationSet{AccessToken: sessionToken(t, "banned")},
+				Channel: "admin",
+			},
+			respStatus: 403,
+			respErrors: []string{playback.ErrNotPermitted.Error()},
+		},
+		{
+			testName: "Expect error (404) for unknown channel.",
+			reqBody: api.PlaybackTokenRequest{
+				Auth:    api.AuthenticationSet{AccessToken: sessionToken(t, "viewer")},
+				Channel: "nobody",
+			},
+			respStatus: 404,
+			respErrors: []string{ErrPlaybackInvalidTarget.Error()},
+		},
+		{
+			testName: "Expect error (400) without channel or video.",
+			reqBody: api.PlaybackTokenRequest{
+				Auth: api.AuthenticationSet{AccessToken: sessionToken(t, "viewer")},
+			},
+			respStatus: 400,
+			respErrors: []string{ErrPlaybackMissingTarget.Error()},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			// Encode request body as JSON
+			b, err := json.Marshal(test.reqBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Perform HTTP test, fetch result.
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/v1/playback/token", bytes.NewReader(b))
+
+			r := mux.NewRouter()
+
+			handler := NewPlaybackHandler(testPlaybackConfig, testPlaybackUsers, testPlaybackOrigins, testPlaybackVideos)
+			handler.RegisterRoutes(r)
+
+			r.ServeHTTP(recorder, req)
+			resp := recorder.Result()
+
+			// Compare response output to expected test output.
+			if !cmp.Equal(resp.StatusCode, test.respStatus) {
+				t.Fatal(cmp.Diff(resp.StatusCode, test.respStatus))
+			}
+
+			result := api.PlaybackTokenResponse{}
+			err = json.NewDecoder(resp.Body).Decode(&result)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(result.Errors, test.respErrors) {
+				t.Fatal(cmp.Diff(result.Errors, test.respErrors))
+			}
+
+			if !result.Success {
+				return
+			}
+
+			if !cmp.Equal(result.Path, test.respPath) {
+				t.Fatal(cmp.Diff(result.Path, test.respPath))
+			}
+
+			if (result.Token != "") != test.respToken {
+				t.Fatalf("unexpected token presence: %q", result.Token)
+			}
+
+			// The signature must verify for media beneath the returned path.
+			signed, err := url.Parse(result.Path + "index.m3u8?" + result.Signature)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			signer := playback.NewSigner(testPlaybackConfig)
+			if err := signer.Verify(signed, "192.0.2.1"); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/M-Ro/go-vodstream/api"
+	"github.com/M-Ro/go-vodstream/internal/domain/relay"
+	"github.com/M-Ro/go-vodstream/internal/domain/user"
+	"github.com/M-Ro/go-vodstream/internal/playback"
+	relayRepository "github.com/M-Ro/go-vodstream/internal/relay"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+var (
+	ErrRelayMissingDetails = errors.New("missing details for relay target")
+	ErrRelayInvalidURL     = errors.New("relay target url must be an rtmp:// url")
+)
+
+// RelayTargetProvider stores the relay targets of each user.
+type RelayTargetProvider interface {
+	GetByID(ctx context.Context, id uuid.UUID) (relay.Target, error)
+	GetByUserID(ctx context.Context, userId uint64) ([]relay.Target, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+	Insert(ctx context.Context, target *relay.Target) error
+	Update(ctx context.Context, id uuid.UUID, target relay.Target) (relay.Target, error)
+}
+
+type RelayHandler struct {
+	authorizer playback.Authorizer
+	targets    RelayTargetProvider
+}
+
+func (h *RelayHandler) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc("/v1/relay/targets", h.List).Methods(http.MethodGet)
+	r.HandleFunc("/v1/relay/targets", h.Create).Methods(http.MethodPost)
+	r.HandleFunc("/v1/relay/targets/{id}", h.Update).Methods(http.MethodPut)
+	r.HandleFunc("/v1/relay/targets/{id}", h.Delete).Methods(http.MethodDelete)
+}
+
+// relayTargetToAPI converts a relay target to its API representation.
+func relayTargetToAPI(target relay.Target) api.RelayTarget {
+	return api.RelayTarget{
+		ID:              target.Id.String(),
+		Name:            target.Name,
+		URL:             target.URL,
+		Enabled:         target.Enabled,
+		Status:          string(target.Status),
+		LastError:       target.LastError,
+		StatusUpdatedAt: target.StatusUpdatedAt,
+	}
+}
+
+// writeRelayResponse encodes the given targets, or the error if set.
+func writeRelayResponse(w http.ResponseWriter, status int, targets []relay.Target, err error) {
+	response := api.RelayTargetsResponse{
+		Success: err == nil,
+		Errors:  []string{},
+		Targets: make([]api.RelayTarget, len(targets)),
+	}
+
+	if err != nil {
+		response.Errors = append(response.Errors, err.Error())
+	}
+
+	for i, target := range targets {
+		response.Targets[i] = relayTargetToAPI(target)
+	}
+
+	encoded, encodeErr := json.Marshal(response)
+	if encodeErr != nil {
+		log.Errorf("Relay targets failed %v", encodeErr)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(encoded)
+}
+
+// readRelayRequest decodes and validates a create or update request.
+func readRelayRequest(r *http.Request) (api.RelayTargetRequest, error) {
+	var relayRequest api.RelayTargetRequest
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return relayRequest, err
+	}
+
+	if err = json.Unmarshal(body, &relayRequest); err != nil {
+		return relayRequest, err
+	}
+
+	if relayRequest.Name == "" || relayRequest.URL == "" || relayRequest.StreamKey == "" {
+		return relayRequest, ErrRelayMissingDetails
+	}
+
+	parsed, err := url.Parse(relayRequest.URL)
+	if err != nil || parsed.Scheme != "rtmp" || parsed.Host == "" {
+		return relayRequest, ErrRelayInvalidURL
+	}
+
+	return relayRequest, nil
+}
+
+// authenticate returns the user making the request from the bearer session token.
+func (h *RelayHandler) authenticate(r *http.Request) (user.User, error) {
+	return h.authorizer.Authenticate(r.Context(), playback.TokenFromRequest(r))
+}
+
+// ownedTarget returns the target addressed by the request if it belongs to owner.
+func (h *RelayHandler) ownedTarget(r *http.Request, owner user.User) (relay.Target, error) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		return relay.Target{}, relayRepository.ErrTargetNotFound
+	}
+
+	target, err := h.targets.GetByID(r.Context(), id)
+	if err != nil || target.UserId != owner.Id {
+		return relay.Target{}, relayRepository.ErrTargetNotFound
+	}
+
+	return target, nil
+}
+
+// List returns the relay targets of the authenticated user.
+func (h *RelayHandler) List(w http.ResponseWriter, r *http.Request) {
+	owner, err := h.authenticate(r)
+	if err != nil {
+		writeRelayResponse(w, http.StatusUnauthorized, nil, err)
+		return
+	}
+
+	targets, err := h.targets.GetByUserID(r.Context(), owner.Id)
+	if err != nil {
+		log.Errorf("Relay targets failed: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writeRelayResponse(w, http.StatusOK, targets, nil)
+}
+
+// Create adds a relay target for the authenticated user.
+func (h *RelayHandler) Create(w http.ResponseWriter, r *http.Request) {
+	owner, err := h.authenticate(r)
+	if err != nil {
+		writeRelayResponse(w, http.StatusUnauthorized, nil, err)
+		return
+	}
+
+	if !owner.CanPublish {
+		writeRelayResponse(w, http.StatusForbidden, nil, playback.ErrNotPermitted)
+		return
+	}
+
+	relayRequest, err := readRelayRequest(r)
+	if err != nil {
+		writeRelayResponse(w, http.StatusBadRequest, nil, err)
+		return
+	}
+
+	target := relay.Target{
+		UserId:    owner.Id,
+		Name:      relayRequest.Name,
+		URL:       relayRequest.URL,
+		StreamKey: relayRequest.StreamKey,
+		Enabled:   relayRequest.Enabled,
+		Status:    relay.StatusIdle,
+	}
+
+	if err = h.targets.Insert(r.Context(), &target); err != nil {
+		log.Errorf("Relay target create failed: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writeRelayResponse(w, http.StatusCreated, []relay.Target{target}, nil)
+}
+
+// Update replaces the configuration of a relay target owned by the authenticated user.
+func (h *RelayHandler) Update(w http.ResponseWriter, r *http.Request) {
+	owner, err := h.authenticate(r)
+	if err != nil {
+		writeRelayResponse(w, http.StatusUnauthorized, nil, err)
+		return
+	}
+
+	target, err := h.ownedTarget(r, owner)
+	if err != nil {
+		writeRelayResponse(w, http.StatusNotFound, nil, err)
+		return
+	}
+
+	relayRequest, err := readRelayRequest(r)
+	if err != nil {
+		writeRelayResponse(w, http.StatusBadRequest, nil, err)
+		return
+	}
+
+	target.Name = relayRequest.Name
+	target.URL = relayRequest.URL
+	target.StreamKey = relayRequest.StreamKey
+	target.Enabled = relayRequest.Enabled
+
+	target, err = h.targets.Update(r.Context(), target.Id, target)
+	if err != nil {
+		log.Errorf("Relay target update failed: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writeRelayResponse(w, http.StatusOK, []relay.Target{target}, nil)
+}
+
+// Delete removes a relay target owned by the authenticated user.
+func (h *RelayHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	owner, err := h.authenticate(r)
+	if err != nil {
+		writeRelayResponse(w, http.StatusUnauthorized, nil, err)
+		return
+	}
+
+	target, err := h.ownedTarget(r, owner)
+	if err != nil {
+		writeRelayResponse(w, http.StatusNotFound, nil, err)
+		return
+	}
+
+	if err = h.targets.Delete(r.Context(), target.Id); err != nil {
+		log.Errorf("Relay target delete failed: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writeRelayResponse(w, http.StatusOK, nil, nil)
+}
+
+func NewRelayHandler(authorizer playback.Authorizer, targets RelayTargetProvider) RelayHandler {
+	return RelayHandler{
+		authorizer: authorizer,
+		targets:    targets,
+	}
+}
@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/M-Ro/go-vodstream/api"
+	"github.com/M-Ro/go-vodstream/internal/domain/relay"
+	"github.com/M-Ro/go-vodstream/internal/playback"
+	relayRepository "github.com/M-Ro/go-vodstream/internal/relay"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// mockRelayTargetProvider stores relay targets in memory.
+type mockRelayTargetProvider map[uuid.UUID]relay.Target
+
+func (m mockRelayTargetProvider) GetByID(_ context.Context, id uuid.UUID) (relay.Target, error) {
+	target, ok := m[id]
+	if !ok {
+		return relay.Target{}, relayRepository.ErrTargetNotFound
+	}
+
+	return target, nil
+}
+
+func (m mockRelayTargetProvider) GetByUserID(_ context.Context, userId uint64) ([]relay.Target, error) {
+	targets := make([]relay.Target, 0)
+	for _, target := range m {
+		if target.UserId == userId {
+			targets = append(targets, target)
+		}
+	}
+
+	return targets, nil
+}
+
+func (m mockRelayTargetProvider) Delete(_ context.Context, id uuid.UUID) error {
+	delete(m, id)
+	return nil
+}
+
+func (m mockRelayTargetProvider) Insert(_ context.Context, target *relay.Target) error {
+	target.Id = uuid.New()
+	m[target.Id] = *target
+
+	return nil
+}
+
+func (m mockRelayTargetProvider) Update(_ context.Context, id uuid.UUID, target relay.Target) (relay.Target, error) {
+	m[id] = target
+	return target, nil
+}
+
+var (
+	broadcasterTargetId = uuid.MustParse("8f14e45f-ceea-467a-9f5e-2a1b4c6d7e80")
+	viewerTargetId      = uuid.MustParse("c9f0f895-fb98-4b91-8e5f-1d2c3b4a5968")
+)
+
+func newMockRelayTargets() mockRelayTargetProvider {
+	return mockRelayTargetProvider{
+		broadcasterTargetId: {
+			Id:        broadcasterTargetId,
+			UserId:    1,
+			Name:      "other platform",
+			URL:       "rtmp://live.example.com/app",
+			StreamKey: "secretKey",
+			Enabled:   true,
+			Status:    relay.StatusLive,
+		},
+		viewerTargetId: {
+			Id:        viewerTargetId,
+			UserId:    2,
+			Name:      "viewer platform",
+			URL:       "rtmp://live.example.org/app",
+			StreamKey: "viewerKey",
+		},
+	}
+}
+
+func TestRelayHandler(t *testing.T) {
+	tests := []struct {
+		testName string
+		method   string
+		endpoint string
+		username string
+		reqBody  *api.RelayTargetRequest
+
+		respStatus  int
+		respErrors  []string
+		respTargets int
+	}{
+		{
+			testName:    "Expect success (200) listing own targets.",
+			method:      http.MethodGet,
+			endpoint:    "/v1/relay/targets",
+			username:    "broadcaster",
+			respStatus:  200,
+			respErrors:  []string{},
+			respTargets: 1,
+		},
+		{
+			testName:   "Expect error (401) listing without a session.",
+			method:     http.MethodGet,
+			endpoint:   "/v1/relay/targets",
+			respStatus: 401,
+			respErrors: []string{playback.ErrTokenInvalid.Error()},
+		},
+		{
+			testName: "Expect success (201) creating a target.",
+			method:   http.MethodPost,
+			endpoint: "/v1/relay/targets",
+			username: "broadcaster",
+			reqBody: &api.RelayTargetRequest{
+				Name:      "new platform",
+				URL:       "rtmp://ingest.example.net/live",
+				StreamKey: "newKey",
+				Enabled:   true,
+			},
+			respStatus:  201,
+			respErrors:  []string{},
+			respTargets: 1,
+		},
+		{
+			testName: "Expect error (403) creating a target without CanPublish.",
+			method:   http.MethodPost,
+			endpoint: "/v1/relay/targets",
+			username: "viewer",
+			reqBody: &api.RelayTargetRequest{
+				Name:      "new platform",
+				URL:       "rtmp://ingest.example.net/live",
+				StreamKey: "newKey",
+			},
+			respStatus: 403,
+			respErrors: []string{playback.ErrNotPermitted.Error()},
+		},
+		{
+			testName: "Expect error (400) creating a target with missing details.",
+			method:   http.MethodPost,
+			endpoint: "/v1/relay/targets",
+			username: "broadcaster",
+			reqBody: &api.RelayTargetRequest{
+				Name: "new platform",
+				URL:  "rtmp://ingest.example.net/live",
+			},
+			respStatus: 400,
+			respErrors: []string{ErrRelayMissingDetails.Error()},
+		},
+		{
+			testName: "Expect error (400) creating a target with a non rtmp url.",
+			method:   http.MethodPost,
+			endpoint: "/v1/relay/targets",
+			username: "broadcaster",
+			reqBody: &api.RelayTargetRequest{
+				Name:      "new platform",
+				URL:       "https://ingest.example.net/live",
+				StreamKey: "newKey",
+			},
+			respStatus: 400,
+			respErrors: []string{ErrRelayInvalidURL.Error()},
+		},
+		{
+			testName: "Expect success (200) updating own target.",
+			method:   http.MethodPut,
+			endpoint: "/v1/relay/targets/" + broadcasterTargetId.String(),
+			username: "broadcaster",
+			reqBody: &api.RelayTargetRequest{
+				Name:      "renamed platform",
+				URL:       "rtmp://live.example.com/app",
+				StreamKey: "rotatedKey",
+			},
+			respStatus:  200,
+			respErrors:  []string{},
+			respTargets: 1,
+		},
+		{
+			testName: "Expect error (404) updating another users target.",
+			method:   http.MethodPut,
+			endpoint: "/v1/relay/targets/" + viewerTargetId.String(),
+			username: "broadcaster",
+			reqBody: &api.RelayTargetRequest{
+				Name:      "renamed platform",
+				URL:       "rtmp://live.example.com/app",
+				StreamKey: "rotatedKey",
+			},
+			respStatus: 404,
+			respErrors: []string{relayRepository.ErrTargetNotFound.Error()},
+		},
+		{
+			testName:   "Expect success (200) deleting own target.",
+			method:     http.MethodDelete,
+			endpoint:   "/v1/relay/targets/" + broadcasterTargetId.String(),
+			username:   "broadcaster",
+			respStatus: 200,
+			respErrors: []string{},
+		},
+		{
+			testName:   "Expect error (404) deleting an unknown target.",
+			method:     http.MethodDelete,
+			endpoint:   "/v1/relay/targets/not-a-uuid",
+			username:   "broadcaster",
+			respStatus: 404,
+			respErrors: []string{relayRepository.ErrTargetNotFound.Error()},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			var body []byte
+			if test.reqBody != nil {
+				b, err := json.Marshal(test.reqBody)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body = b
+			}
+
+			// Perform HTTP test, fetch result.
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest(test.method, test.endpoint, bytes.NewReader(body))
+			if test.username != "" {
+				req.Header.Set("Authorization", "Bearer "+sessionToken(t, test.username))
+			}
+
+			r := mux.NewRouter()
+
+			authorizer := playback.NewAuthorizer(testPlaybackConfig, testPlaybackUsers)
+			handler := NewRelayHandler(authorizer, newMockRelayTargets())
+			handler.RegisterRoutes(r)
+
+			r.ServeHTTP(recorder, req)
+			resp := recorder.Result()
+
+			// Compare response output to expected test output.
+			if !cmp.Equal(resp.StatusCode, test.respStatus) {
+				t.Fatal(cmp.Diff(resp.StatusCode, test.respStatus))
+			}
+
+			result := api.RelayTargetsResponse{}
+			err := json.NewDecoder(resp.Body).Decode(&result)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(result.Errors, test.respErrors) {
+				t.Fatal(cmp.Diff(result.Errors, test.respErrors))
+			}
+
+			if !cmp.Equal(len(result.Targets), test.respTargets) {
+				t.Fatal(cmp.Diff(len(result.Targets), test.respTargets))
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"github.com/M-Ro/go-vodstream/api"
+	"github.com/M-Ro/go-vodstream/internal/domain/search"
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	searchRepository "github.com/M-Ro/go-vodstream/internal/search"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrSearchInvalidType = errors.New("type must be a comma separated list of channel, broadcast or video")
+)
+
+// SearchProvider searches published channels, broadcasts and videos.
+type SearchProvider interface {
+	Search(ctx context.Context, input string, types []search.Type, options paginate.QueryOptions) ([]search.Result, error)
+}
+
+// SearchHandler serves full-text search across channels, broadcasts and videos.
+type SearchHandler struct {
+	searcher SearchProvider
+}
+
+func (h *SearchHandler) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc("/v1/search", h.Search).Methods(http.MethodGet)
+}
+
+// searchResultToAPI converts a search result to its API representation.
+func searchResultToAPI(result search.Result) api.SearchResult {
+	tags := result.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	return api.SearchResult{
+		Type:          string(result.Type),
+		ID:            result.Id,
+		BroadcasterID: result.BroadcasterId,
+		Username:      result.Username,
+		Title:         result.Title,
+		Description:   result.Description,
+		Category:      result.Category,
+		Tags:          tags,
+		IsLive:        result.IsLive,
+		PublishedAt:   result.PublishedAt,
+		Rank:          result.Rank,
+	}
+}
+
+// writeSearchResponse encodes the response, filling the error list when err is set.
+func writeSearchResponse(w http.ResponseWriter, status int, response api.SearchResponse, err error) {
+	response.Success = err == nil
+	response.Errors = []string{}
+	if err != nil {
+		response.Errors = append(response.Errors, err.Error())
+	}
+
+	if response.Results == nil {
+		response.Results = []api.SearchResult{}
+	}
+
+	writeJSON(w, status, "Search", response)
+}
+
+// searchTypes reads the comma separated types of results to search for, all
+// types if none are given.
+func searchTypes(value string) ([]search.Type, error) {
+	if value == "" {
+		return search.Types, nil
+	}
+
+	types := make([]search.Type, 0, len(search.Types))
+	for _, name := range strings.Split(value, ",") {
+		t, ok := searchTypeByName(strings.TrimSpace(name))
+		if !ok {
+			return nil, ErrSearchInvalidType
+		}
+
+		if !containsSearchType(types, t) {
+			types = append(types, t)
+		}
+	}
+
+	return types, nil
+}
+
+// searchTypeByName returns the type of result with the given name.
+func searchTypeByName(name string) (search.Type, bool) {
+	for _, t := range search.Types {
+		if string(t) == name {
+			return t, true
+		}
+	}
+
+	return "", false
+}
+
+// containsSearchType returns true if t is within types.
+func containsSearchType(types []search.Type, t search.Type) bool {
+	for _, other := range types {
+		if other == t {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Search returns the published channels, broadcasts and videos matching the q
+// query parameter, best matches first. Every word of the query must match,
+// partially typed words as a prefix. Results may be restricted with the type
+// query parameter, and are paged with the limit and offset query parameters.
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	types, err := searchTypes(query.Get("type"))
+	if err != nil {
+		writeSearchResponse(w, http.StatusBadRequest, api.SearchResponse{}, err)
+		return
+	}
+
+	options, err := pageOptions(query, "rank", nil)
+	if err != nil {
+		writeSearchResponse(w, http.StatusBadRequest, api.SearchResponse{}, err)
+		return
+	}
+
+	results, err := h.searcher.Search(r.Context(), query.Get("q"), types, paginate.NewPaginateOptions(options...))
+	if errors.Is(err, searchRepository.ErrEmptyQuery) || errors.Is(err, searchRepository.ErrQueryTooLong) {
+		writeSearchResponse(w, http.StatusBadRequest, api.SearchResponse{}, err)
+		return
+	}
+
+	if err != nil {
+		log.Errorf("Search failed: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	response := api.SearchResponse{Results: make([]api.SearchResult, 0, len(results))}
+	for _, result := range results {
+		response.Results = append(response.Results, searchResultToAPI(result))
+	}
+
+	writeSearchResponse(w, http.StatusOK, response, nil)
+}
+
+// NewSearchHandler instantiates a new SearchHandler.
+func NewSearchHandler(searcher SearchProvider) SearchHandler {
+	return SearchHandler{
+		searcher: searcher,
+	}
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/M-Ro/go-vodstream/api"
+	"github.com/M-Ro/go-vodstream/internal/domain/search"
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	searchRepository "github.com/M-Ro/go-vodstream/internal/search"
+	"github.com/google/go-cmp/cmp"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// mockSearchProvider returns its results of the searched types, best ranked first,
+// as if every one of them matched the query.
+type mockSearchProvider []search.Result
+
+func (m mockSearchProvider) Search(
+	_ context.Context, input string, types []search.Type, options paginate.QueryOptions,
+) ([]search.Result, error) {
+	if _, err := searchRepository.ParseQuery(input); err != nil {
+		return []search.Result{}, err
+	}
+
+	results := make([]search.Result, 0, len(m))
+	for _, result := range m {
+		if containsSearchType(types, result.Type) {
+			results = append(results, result)
+		}
+	}
+
+	if int(options.Offset) >= len(results) {
+		return []search.Result{}, nil
+	}
+	results = results[options.Offset:]
+
+	if int(options.Limit) < len(results) {
+		results = results[:options.Limit]
+	}
+
+	return results, nil
+}
+
+func newTestSearchRouter() *mux.Router {
+	r := mux.NewRouter()
+
+	publishedAt := time.Date(2026, 10, 18, 20, 0, 0, 0, time.UTC)
+	handler := NewSearchHandler(mockSearchProvider{
+		{
+			Type:          search.TypeChannel,
+			Id:            "1",
+			BroadcasterId: 1,
+			Username:      "broadcaster",
+			Title:         "The Broadcaster",
+			Category:      "speedruns",
+			Tags:          []string{"any%"},
+			IsLive:        true,
+			PublishedAt:   publishedAt,
+			Rank:          0.9,
+		},
+		{
+			Type:          search.TypeBroadcast,
+			Id:            "6f1c9a52-8d3e-4b7a-a1c2-5e4f3d2c1b01",
+			BroadcasterId: 1,
+			Username:      "broadcaster",
+			Title:         "Any% attempts",
+			Category:      "speedruns",
+			IsLive:        true,
+			PublishedAt:   publishedAt,
+			Rank:          0.6,
+		},
+		{
+			Type:          search.TypeVideo,
+			Id:            "6f1c9a52-8d3e-4b7a-a1c2-5e4f3d2c1b02",
+			BroadcasterId: 1,
+			Username:      "broadcaster",
+			Title:         "Any% world record",
+			PublishedAt:   publishedAt,
+			Rank:          0.3,
+		},
+	})
+	handler.RegisterRoutes(r)
+
+	return r
+}
+
+func TestSearchHandler_Search(t *testing.T) {
+	publishedAt := time.Date(2026, 10, 18, 20, 0, 0, 0, time.UTC)
+
+	channelResult := api.SearchResult{
+		Type:          "channel",
+		ID:            "1",
+		BroadcasterID: 1,
+		Username:      "broadcaster",
+		Title:         "The Broadcaster",
+		Category:      "speedruns",
+		Tags:          []string{"any%"},
+		IsLive:        true,
+		PublishedAt:   publishedAt,
+		Rank:          0.9,
+	}
+	broadcastResult := api.SearchResult{
+		Type:          "broadcast",
+		ID:            "6f1c9a52-8d3e-4b7a-a1c2-5e4f3d2c1b01",
+		BroadcasterID: 1,
+		Username:      "broadcaster",
+		Title:         "Any% attempts",
+		Category:      "speedruns",
+		Tags:          []string{},
+		IsLive:        true,
+		PublishedAt:   publishedAt,
+		Rank:          0.6,
+	}
+	videoResult := api.SearchResult{
+		Type:          "video",
+		ID:            "6f1c9a52-8d3e-4b7a-a1c2-5e4f3d2c1b02",
+		BroadcasterID: 1,
+		Username:      "broadcaster",
+		Title:         "Any% world record",
+		Tags:          []string{},
+		PublishedAt:   publishedAt,
+		Rank:          0.3,
+	}
+
+	tests := []struct {
+		testName string
+		endpoint string
+
+		respStatus  int
+		respErrors  []string
+		respResults []api.SearchResult
+	}{
+		{
+			testName:    "Expect success (200) searching every type.",
+			endpoint:    "/v1/search?q=any",
+			respStatus:  200,
+			respErrors:  []string{},
+			respResults: []api.SearchResult{channelResult, broadcastResult, videoResult},
+		},
+		{
+			testName:    "Expect success (200) searching the given types.",
+			endpoint:    "/v1/search?q=any&type=video,channel,video",
+			respStatus:  200,
+			respErrors:  []string{},
+			respResults: []api.SearchResult{channelResult, videoResult},
+		},
+		{
+			testName:    "Expect success (200) paging through results.",
+			endpoint:    "/v1/search?q=any&limit=1&offset=1",
+			respStatus:  200,
+			respErrors:  []string{},
+			respResults: []api.SearchResult{broadcastResult},
+		},
+		{
+			testName:    "Expect error (400) searching an unknown type.",
+			endpoint:    "/v1/search?q=any&type=clip",
+			respStatus:  400,
+			respErrors:  []string{ErrSearchInvalidType.Error()},
+			respResults: []api.SearchResult{},
+		},
+		{
+			testName:    "Expect error (400) searching without words.",
+			endpoint:    "/v1/search?q=%25",
+			respStatus:  400,
+			respErrors:  []string{searchRepository.ErrEmptyQuery.Error()},
+			respResults: []api.SearchResult{},
+		},
+		{
+			testName:    "Expect error (400) searching with an invalid page.",
+			endpoint:    "/v1/search?q=any&limit=x",
+			respStatus:  400,
+			respErrors:  []string{ErrInvalidPage.Error()},
+			respResults: []api.SearchResult{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, test.endpoint, nil)
+
+			newTestSearchRouter().ServeHTTP(recorder, req)
+			resp := recorder.Result()
+
+			if !cmp.Equal(resp.StatusCode, test.respStatus) {
+				t.Fatal(cmp.Diff(resp.StatusCode, test.respStatus))
+			}
+
+			result := api.SearchResponse{}
+			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(result.Errors, test.respErrors) {
+				t.Fatal(cmp.Diff(result.Errors, test.respErrors))
+			}
+
+			if !cmp.Equal(result.Results, test.respResults) {
+				t.Fatal(cmp.Diff(result.Results, test.respResults))
+			}
+		})
+	}
+}
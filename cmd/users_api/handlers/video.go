@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"github.com/M-Ro/go-vodstream/api"
+	"github.com/M-Ro/go-vodstream/internal/domain/video"
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	"github.com/M-Ro/go-vodstream/internal/playback"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrVideoNotFound           = errors.New("video does not exist")
+	ErrVideoMissingBroadcaster = errors.New("a broadcaster is required")
+	ErrVideoInvalidBroadcaster = errors.New("broadcaster does not exist")
+)
+
+// VideoProvider stores the videos of each broadcaster.
+type VideoProvider interface {
+	ListByBroadcaster(
+		ctx context.Context, broadcasterId uint64, options paginate.QueryOptions,
+	) ([]video.Video, paginate.Page, error)
+	ListPublishedByBroadcaster(
+		ctx context.Context, broadcasterId uint64, options paginate.QueryOptions,
+	) ([]video.Video, paginate.Page, error)
+	GetByID(ctx context.Context, id uuid.UUID) (video.Video, error)
+	Update(ctx context.Context, id uuid.UUID, video video.Video) (video.Video, error)
+}
+
+// VideoHandler lists the videos of broadcasters, and lets broadcasters edit theirs.
+type VideoHandler struct {
+	authorizer playback.Authorizer
+	users      playback.UserProvider
+	videos     VideoProvider
+}
+
+func (h *VideoHandler) RegisterRoutes(r *mux.Router) {
+	r.HandleFunc("/v1/videos", h.List).Methods(http.MethodGet)
+	r.HandleFunc("/v1/videos/{id}", h.Update).Methods(http.MethodPatch)
+}
+
+// writeVideoResponse encodes the response, filling the error list when err is set.
+func writeVideoResponse(w http.ResponseWriter, status int, response api.VideoResponse, err error) {
+	response.Success = err == nil
+	response.Errors = []string{}
+	if err != nil {
+		response.Errors = append(response.Errors, err.Error())
+	}
+
+	writeJSON(w, status, "Video", response)
+}
+
+// writeVideosResponse encodes the response, filling the error list when err is set.
+func writeVideosResponse(w http.ResponseWriter, status int, response api.VideosResponse, err error) {
+	response.Success = err == nil
+	response.Errors = []string{}
+	if err != nil {
+		response.Errors = append(response.Errors, err.Error())
+	}
+
+	if response.Videos == nil {
+		response.Videos = []api.Video{}
+	}
+
+	writeJSON(w, status, "Listing videos", response)
+}
+
+// videoToAPI converts a video to its API representation.
+func videoToAPI(v video.Video) api.Video {
+	return api.Video{
+		ID:            v.Id.String(),
+		BroadcasterID: v.BroadcasterId,
+		Title:         v.Title,
+		Length:        v.Length.Seconds(),
+		IsPublished:   v.IsPublished,
+		PublishedAt:   v.PublishedAt,
+		Pinned:        v.Pinned,
+		CreatedAt:     v.CreatedAt,
+	}
+}
+
+// List returns the videos of the broadcaster named by the broadcaster query
+// parameter, newest first, paged with the limit and offset or cursor query parameters.
+// They may be ordered otherwise by the sort query parameter, and filtered by query
+// parameters named by the fields of video.Fields. Unpublished videos are only listed
+// to their broadcaster.
+func (h *VideoHandler) List(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	name := query.Get("broadcaster")
+	if name == "" {
+		writeVideosResponse(w, http.StatusBadRequest, api.VideosResponse{}, ErrVideoMissingBroadcaster)
+		return
+	}
+
+	options, err := pageOptions(query, "published_at", video.Fields)
+	if err != nil {
+		writeVideosResponse(w, http.StatusBadRequest, api.VideosResponse{}, err)
+		return
+	}
+
+	broadcaster, err := h.users.GetByUsername(r.Context(), name)
+	if err != nil {
+		writeVideosResponse(w, http.StatusNotFound, api.VideosResponse{}, ErrVideoInvalidBroadcaster)
+		return
+	}
+
+	list := h.videos.ListPublishedByBroadcaster
+	if u, authenticated := viewer(r, h.authorizer); authenticated && u.Id == broadcaster.Id {
+		list = h.videos.ListByBroadcaster
+	}
+
+	videos, page, err := list(r.Context(), broadcaster.Id, paginate.NewPaginateOptions(options...))
+	if err != nil {
+		log.Errorf("Listing videos of %s failed: %v", name, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	response := api.VideosResponse{Videos: make([]api.Video, 0, len(videos)), Page: pageToAPI(page)}
+	for _, v := range videos {
+		response.Videos = append(response.Videos, videoToAPI(v))
+	}
+
+	writeVideosResponse(w, http.StatusOK, response, nil)
+}
+
+// Update edits the title of a video owned by the authenticated user, or publishes or unpublishes it.
+func (h *VideoHandler) Update(w http.ResponseWriter, r *http.Request) {
+	owner, err := h.authorizer.Authenticate(r.Context(), playback.TokenFromRequest(r))
+	if err != nil {
+		writeVideoResponse(w, http.StatusUnauthorized, api.VideoResponse{}, err)
+		return
+	}
+
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeVideoResponse(w, http.StatusNotFound, api.VideoResponse{}, ErrVideoNotFound)
+		return
+	}
+
+	v, err := h.videos.GetByID(r.Context(), id)
+	if err != nil || (!v.IsPublished && v.BroadcasterId != owner.Id) {
+		writeVideoResponse(w, http.StatusNotFound, api.VideoResponse{}, ErrVideoNotFound)
+		return
+	}
+
+	if v.BroadcasterId != owner.Id {
+		writeVideoResponse(w, http.StatusForbidden, api.VideoResponse{}, playback.ErrNotPermitted)
+		return
+	}
+
+	var request api.VideoUpdateRequest
+	if err = readUpdateRequest(r, &request); err != nil {
+		writeVideoResponse(w, http.StatusBadRequest, api.VideoResponse{}, err)
+		return
+	}
+
+	if v.Title, err = editTitle(v.Title, request.Title); err != nil {
+		writeVideoResponse(w, http.StatusBadRequest, api.VideoResponse{}, err)
+		return
+	}
+
+	if request.IsPublished != nil {
+		if *request.IsPublished {
+			v.Publish(time.Now())
+		} else {
+			v.Unpublish()
+		}
+	}
+
+	v, err = h.videos.Update(r.Context(), v.Id, v)
+	if err != nil {
+		log.Errorf("Video update failed: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writeVideoResponse(w, http.StatusOK, api.VideoResponse{Video: videoToAPI(v)}, nil)
+}
+
+// NewVideoHandler instantiates a new VideoHandler.
+func NewVideoHandler(authorizer playback.Authorizer, users playback.UserProvider, videos VideoProvider) VideoHandler {
+	return VideoHandler{
+		authorizer: authorizer,
+		users:      users,
+		videos:     videos,
+	}
+}
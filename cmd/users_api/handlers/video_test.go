@@ -0,0 +1,367 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/M-Ro/go-vodstream/api"
+	"github.com/M-Ro/go-vodstream/internal/domain/video"
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	"github.com/M-Ro/go-vodstream/internal/playback"
+	videoRepository "github.com/M-Ro/go-vodstream/internal/video"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+)
+
+// mockVideoProvider stores videos in memory.
+type mockVideoProvider map[uuid.UUID]video.Video
+
+func (m mockVideoProvider) list(
+	broadcasterId uint64, publishedOnly bool, options paginate.QueryOptions,
+) ([]video.Video, paginate.Page, error) {
+	videos := make([]video.Video, 0)
+	for _, v := range m {
+		if v.BroadcasterId == broadcasterId && (v.IsPublished || !publishedOnly) {
+			videos = append(videos, v)
+		}
+	}
+
+	sort.Slice(videos, func(a, b int) bool {
+		return videos[a].PublishedAt.After(videos[b].PublishedAt)
+	})
+
+	if int(options.Offset) >= len(videos) {
+		return []video.Video{}, paginate.Page{}, nil
+	}
+	videos = videos[options.Offset:]
+
+	if int(options.Limit)+1 < len(videos) {
+		videos = videos[:options.Limit+1]
+	}
+
+	page := paginate.Paginate(&videos, options, func(i int) ([]string, string) {
+		return []string{videos[i].PublishedAt.Format(time.RFC3339Nano)}, videos[i].Id.String()
+	})
+
+	return videos, page, nil
+}
+
+func (m mockVideoProvider) ListByBroadcaster(
+	_ context.Context, broadcasterId uint64, options paginate.QueryOptions,
+) ([]video.Video, paginate.Page, error) {
+	return m.list(broadcasterId, false, options)
+}
+
+func (m mockVideoProvider) ListPublishedByBroadcaster(
+	_ context.Context, broadcasterId uint64, options paginate.QueryOptions,
+) ([]video.Video, paginate.Page, error) {
+	return m.list(broadcasterId, true, options)
+}
+
+func (m mockVideoProvider) GetByID(_ context.Context, id uuid.UUID) (video.Video, error) {
+	v, ok := m[id]
+	if !ok {
+		return video.Video{}, videoRepository.ErrVideoNotFound
+	}
+
+	return v, nil
+}
+
+func (m mockVideoProvider) Update(_ context.Context, id uuid.UUID, v video.Video) (video.Video, error) {
+	m[id] = v
+	return v, nil
+}
+
+var (
+	publishedVideoId   = uuid.MustParse("3b5d5c37-1b2a-4c4e-9d8f-0a1b2c3d4e01")
+	unpublishedVideoId = uuid.MustParse("3b5d5c37-1b2a-4c4e-9d8f-0a1b2c3d4e02")
+	olderVideoId       = uuid.MustParse("3b5d5c37-1b2a-4c4e-9d8f-0a1b2c3d4e03")
+)
+
+var testVideosPublishedAt = time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+func newMockVideos() mockVideoProvider {
+	return mockVideoProvider{
+		publishedVideoId: {
+			Id:            publishedVideoId,
+			Title:         "published",
+			BroadcasterId: 1,
+			Length:        90 * time.Second,
+			IsPublished:   true,
+			PublishedAt:   testVideosPublishedAt,
+		},
+		unpublishedVideoId: {
+			Id:            unpublishedVideoId,
+			Title:         "unpublished",
+			BroadcasterId: 1,
+			PublishedAt:   testVideosPublishedAt.Add(time.Hour),
+		},
+		olderVideoId: {
+			Id:            olderVideoId,
+			Title:         "older",
+			BroadcasterId: 1,
+			IsPublished:   true,
+			PublishedAt:   testVideosPublishedAt.Add(-time.Hour),
+		},
+	}
+}
+
+func TestVideoHandler_List(t *testing.T) {
+	tests := []struct {
+		testName string
+		endpoint string
+		username string
+
+		respStatus int
+		respErrors []string
+		respTitles []string
+	}{
+		{
+			testName:   "Expect success (200) listing the published videos of a broadcaster, newest first.",
+			endpoint:   "/v1/videos?broadcaster=broadcaster",
+			respStatus: 200,
+			respErrors: []string{},
+			respTitles: []string{"published", "older"},
+		},
+		{
+			testName:   "Expect success (200) listing unpublished videos to their broadcaster.",
+			endpoint:   "/v1/videos?broadcaster=broadcaster",
+			username:   "broadcaster",
+			respStatus: 200,
+			respErrors: []string{},
+			respTitles: []string{"unpublished", "published", "older"},
+		},
+		{
+			testName:   "Expect success (200) hiding unpublished videos from other users.",
+			endpoint:   "/v1/videos?broadcaster=broadcaster",
+			username:   "viewer",
+			respStatus: 200,
+			respErrors: []string{},
+			respTitles: []string{"published", "older"},
+		},
+		{
+			testName:   "Expect success (200) paging videos.",
+			endpoint:   "/v1/videos?broadcaster=broadcaster&limit=1&offset=1",
+			respStatus: 200,
+			respErrors: []string{},
+			respTitles: []string{"older"},
+		},
+		{
+			testName:   "Expect error (400) listing without a broadcaster.",
+			endpoint:   "/v1/videos",
+			respStatus: 400,
+			respErrors: []string{ErrVideoMissingBroadcaster.Error()},
+			respTitles: []string{},
+		},
+		{
+			testName:   "Expect error (400) listing with an invalid page.",
+			endpoint:   "/v1/videos?broadcaster=broadcaster&limit=-1",
+			respStatus: 400,
+			respErrors: []string{ErrInvalidPage.Error()},
+			respTitles: []string{},
+		},
+		{
+			testName:   "Expect error (404) listing an unknown broadcaster.",
+			endpoint:   "/v1/videos?broadcaster=unknown",
+			respStatus: 404,
+			respErrors: []string{ErrVideoInvalidBroadcaster.Error()},
+			respTitles: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, test.endpoint, nil)
+			if test.username != "" {
+				req.Header.Set("Authorization", "Bearer "+sessionToken(t, test.username))
+			}
+
+			r := mux.NewRouter()
+
+			authorizer := playback.NewAuthorizer(testPlaybackConfig, testPlaybackUsers)
+			handler := NewVideoHandler(authorizer, testPlaybackUsers, newMockVideos())
+			handler.RegisterRoutes(r)
+
+			r.ServeHTTP(recorder, req)
+			resp := recorder.Result()
+
+			if !cmp.Equal(resp.StatusCode, test.respStatus) {
+				t.Fatal(cmp.Diff(resp.StatusCode, test.respStatus))
+			}
+
+			result := api.VideosResponse{}
+			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(result.Errors, test.respErrors) {
+				t.Fatal(cmp.Diff(result.Errors, test.respErrors))
+			}
+
+			titles := make([]string, 0, len(result.Videos))
+			for _, v := range result.Videos {
+				titles = append(titles, v.Title)
+			}
+
+			if !cmp.Equal(titles, test.respTitles) {
+				t.Fatal(cmp.Diff(titles, test.respTitles))
+			}
+		})
+	}
+}
+
+func TestVideoHandler_Update(t *testing.T) {
+	title := func(title string) *string { return &title }
+	published := func(published bool) *bool { return &published }
+
+	tests := []struct {
+		testName string
+		endpoint string
+		username string
+		reqBody  api.VideoUpdateRequest
+
+		respStatus int
+		respErrors []string
+		respVideo  api.Video
+	}{
+		{
+			testName:   "Expect success (200) renaming own video.",
+			endpoint:   "/v1/videos/" + publishedVideoId.String(),
+			username:   "broadcaster",
+			reqBody:    api.VideoUpdateRequest{Title: title(" renamed ")},
+			respStatus: 200,
+			respErrors: []string{},
+			respVideo: api.Video{
+				ID: publishedVideoId.String(), BroadcasterID: 1, Title: "renamed", Length: 90,
+				IsPublished: true, PublishedAt: testVideosPublishedAt,
+			},
+		},
+		{
+			testName:   "Expect success (200) unpublishing own video, keeping when it was published.",
+			endpoint:   "/v1/videos/" + publishedVideoId.String(),
+			username:   "broadcaster",
+			reqBody:    api.VideoUpdateRequest{IsPublished: published(false)},
+			respStatus: 200,
+			respErrors: []string{},
+			respVideo: api.Video{
+				ID: publishedVideoId.String(), BroadcasterID: 1, Title: "published", Length: 90,
+				PublishedAt: testVideosPublishedAt,
+			},
+		},
+		{
+			testName:   "Expect error (400) renaming own video to a blank title.",
+			endpoint:   "/v1/videos/" + publishedVideoId.String(),
+			username:   "broadcaster",
+			reqBody:    api.VideoUpdateRequest{Title: title("  ")},
+			respStatus: 400,
+			respErrors: []string{ErrMissingTitle.Error()},
+		},
+		{
+			testName:   "Expect error (401) updating without a session.",
+			endpoint:   "/v1/videos/" + publishedVideoId.String(),
+			reqBody:    api.VideoUpdateRequest{Title: title("renamed")},
+			respStatus: 401,
+			respErrors: []string{playback.ErrTokenInvalid.Error()},
+		},
+		{
+			testName:   "Expect error (403) updating another broadcaster's video.",
+			endpoint:   "/v1/videos/" + publishedVideoId.String(),
+			username:   "viewer",
+			reqBody:    api.VideoUpdateRequest{Title: title("renamed")},
+			respStatus: 403,
+			respErrors: []string{playback.ErrNotPermitted.Error()},
+		},
+		{
+			testName:   "Expect error (404) updating another broadcaster's unpublished video.",
+			endpoint:   "/v1/videos/" + unpublishedVideoId.String(),
+			username:   "viewer",
+			reqBody:    api.VideoUpdateRequest{IsPublished: published(true)},
+			respStatus: 404,
+			respErrors: []string{ErrVideoNotFound.Error()},
+		},
+		{
+			testName:   "Expect error (404) updating an unknown video.",
+			endpoint:   "/v1/videos/not-a-uuid",
+			username:   "broadcaster",
+			reqBody:    api.VideoUpdateRequest{Title: title("renamed")},
+			respStatus: 404,
+			respErrors: []string{ErrVideoNotFound.Error()},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			b, err := json.Marshal(test.reqBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPatch, test.endpoint, bytes.NewReader(b))
+			if test.username != "" {
+				req.Header.Set("Authorization", "Bearer "+sessionToken(t, test.username))
+			}
+
+			r := mux.NewRouter()
+
+			authorizer := playback.NewAuthorizer(testPlaybackConfig, testPlaybackUsers)
+			handler := NewVideoHandler(authorizer, testPlaybackUsers, newMockVideos())
+			handler.RegisterRoutes(r)
+
+			r.ServeHTTP(recorder, req)
+			resp := recorder.Result()
+
+			if !cmp.Equal(resp.StatusCode, test.respStatus) {
+				t.Fatal(cmp.Diff(resp.StatusCode, test.respStatus))
+			}
+
+			result := api.VideoResponse{}
+			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(result.Errors, test.respErrors) {
+				t.Fatal(cmp.Diff(result.Errors, test.respErrors))
+			}
+
+			if !cmp.Equal(result.Video, test.respVideo) {
+				t.Fatal(cmp.Diff(result.Video, test.respVideo))
+			}
+		})
+	}
+}
+
+func TestVideoHandler_Republish(t *testing.T) {
+	videos := newMockVideos()
+
+	r := mux.NewRouter()
+	authorizer := playback.NewAuthorizer(testPlaybackConfig, testPlaybackUsers)
+	handler := NewVideoHandler(authorizer, testPlaybackUsers, videos)
+	handler.RegisterRoutes(r)
+
+	b, err := json.Marshal(api.VideoUpdateRequest{IsPublished: func(b bool) *bool { return &b }(true)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPatch, "/v1/videos/"+unpublishedVideoId.String(), bytes.NewReader(b))
+	req.Header.Set("Authorization", "Bearer "+sessionToken(t, "broadcaster"))
+	r.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatal(cmp.Diff(recorder.Code, http.StatusOK))
+	}
+
+	// Publishing stamps when the video was published.
+	if v := videos[unpublishedVideoId]; !v.IsPublished || time.Since(v.PublishedAt) > time.Minute {
+		t.Fatalf("expected the video published now, got %+v", v)
+	}
+}
@@ -2,9 +2,32 @@ package users_api
 
 import (
 	"github.com/M-Ro/go-vodstream/cmd/users_api/handlers"
+	broadcastRepository "github.com/M-Ro/go-vodstream/internal/broadcast"
+	categoryRepository "github.com/M-Ro/go-vodstream/internal/category"
+	channelRepository "github.com/M-Ro/go-vodstream/internal/channel"
+	"github.com/M-Ro/go-vodstream/internal/cluster"
+	"github.com/M-Ro/go-vodstream/internal/encryption"
+	"github.com/M-Ro/go-vodstream/internal/playback"
+	"github.com/M-Ro/go-vodstream/internal/relay"
+	searchRepository "github.com/M-Ro/go-vodstream/internal/search"
+	userRepository "github.com/M-Ro/go-vodstream/internal/user"
+	videoRepository "github.com/M-Ro/go-vodstream/internal/video"
+	memoryBroadcast "github.com/M-Ro/go-vodstream/storage/memory/broadcast"
+	memoryBroadcastVod "github.com/M-Ro/go-vodstream/storage/memory/broadcast_vod"
+	memoryUser "github.com/M-Ro/go-vodstream/storage/memory/user"
+	memoryVideo "github.com/M-Ro/go-vodstream/storage/memory/video"
 	"github.com/M-Ro/go-vodstream/storage/sql"
+	"github.com/M-Ro/go-vodstream/storage/sql/broadcast"
+	"github.com/M-Ro/go-vodstream/storage/sql/broadcast_vod"
+	"github.com/M-Ro/go-vodstream/storage/sql/category"
+	"github.com/M-Ro/go-vodstream/storage/sql/channel"
+	"github.com/M-Ro/go-vodstream/storage/sql/live_channel"
+	"github.com/M-Ro/go-vodstream/storage/sql/relay_target"
+	"github.com/M-Ro/go-vodstream/storage/sql/search"
 	"github.com/M-Ro/go-vodstream/storage/sql/user"
+	"github.com/M-Ro/go-vodstream/storage/sql/video"
 	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -13,26 +36,73 @@ import (
 
 // NewCmd registers the cobra command to be called from the CLI.
 func NewCmd() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "users_api",
 		Short: "launches an auth/users API provider.",
 		Run:   Start,
 	}
+
+	cmd.Flags().String("storage", "sql", "where to store users, broadcasts and videos: sql, or memory for development")
+
+	return cmd
 }
 
 // Start the http(s) listen server.
-func Start(_ *cobra.Command, _ []string) {
+func Start(cmd *cobra.Command, _ []string) {
 	log.Info("Starting Users API")
 
 	bindAddress := viper.GetString("api.users.bind_address")
 
-	db := sql.NewDbConn()
-	storage := user.NewUserStorage(db)
+	var (
+		db               *sqlx.DB
+		userStorage      userRepository.StorageProvider
+		broadcastStorage broadcastRepository.StorageProvider
+		vodStorage       broadcastRepository.VodStorageProvider
+		videoStorage     videoRepository.StorageProvider
+		origins          handlers.PlaybackOriginProvider
+	)
+
+	switch backend, _ := cmd.Flags().GetString("storage"); backend {
+	case "sql":
+		db = sql.NewDbConn()
+		userStorage = user.NewUserStorage(db)
+		broadcastStorage = broadcast.NewBroadcastStorage(db)
+		vodStorage = broadcast_vod.NewBroadcastVodStorage(db)
+		videoStorage = video.NewVideoStorage(db)
+		origins = cluster.NewDirectory(live_channel.NewLiveChannelStorage(db), cluster.GetTTL())
+	case "memory":
+		log.Warn("Storing users, broadcasts and videos in memory, lost on exit. " +
+			"Channel, category, search and relay target routes need SQL storage and are disabled.")
+		userStorage = memoryUser.NewUserStorage()
+		broadcastStorage = memoryBroadcast.NewBroadcastStorage()
+		vodStorage = memoryBroadcastVod.NewBroadcastVodStorage()
+		videoStorage = memoryVideo.NewVideoStorage()
+	default:
+		log.Fatalf("Unknown storage %q, expected sql or memory", backend)
+	}
+
+	users := userRepository.NewRepository(userStorage)
+	broadcasts := broadcastRepository.NewRepository(broadcastStorage)
+	vods := broadcastRepository.NewVodRepository(vodStorage)
+	videos := videoRepository.NewRepository(videoStorage)
+
+	playbackConfig := playback.GetConfig()
+	authorizer := playback.NewAuthorizer(playbackConfig, users)
 
-	handler := handlers.NewAuthHandler(storage)
+	handler := handlers.NewAuthHandler(userStorage)
+	playbackHandler := handlers.NewPlaybackHandler(playbackConfig, users, origins, videos)
+	broadcastHandler := handlers.NewBroadcastHandler(authorizer, broadcasts, vods, videos)
+	videoHandler := handlers.NewVideoHandler(authorizer, users, videos)
 
 	r := mux.NewRouter()
 	handler.RegisterRoutes(r)
+	playbackHandler.RegisterRoutes(r)
+	broadcastHandler.RegisterRoutes(r)
+	videoHandler.RegisterRoutes(r)
+
+	if db != nil {
+		registerSqlRoutes(r, db, authorizer, users, broadcasts)
+	}
 
 	log.Println("Listening on" + bindAddress + "..")
 	err := http.ListenAndServe(bindAddress, r)
@@ -40,3 +110,33 @@ func Start(_ *cobra.Command, _ []string) {
 		log.Fatal(err)
 	}
 }
+
+// registerSqlRoutes registers the routes of the handlers only SQL storage backs.
+func registerSqlRoutes(
+	r *mux.Router,
+	db *sqlx.DB,
+	authorizer playback.Authorizer,
+	users userRepository.Repository,
+	broadcasts broadcastRepository.Repository,
+) {
+	channels := channelRepository.NewRepository(channel.NewChannelStorage(db))
+	categories := categoryRepository.NewRepository(category.NewCategoryStorage(db))
+	searcher := searchRepository.NewRepository(search.NewSearchStorage(db))
+
+	channelHandler := handlers.NewChannelHandler(authorizer, users, channels, categories)
+	categoryHandler := handlers.NewCategoryHandler(authorizer, categories, broadcasts)
+	searchHandler := handlers.NewSearchHandler(searcher)
+
+	channelHandler.RegisterRoutes(r)
+	categoryHandler.RegisterRoutes(r)
+	searchHandler.RegisterRoutes(r)
+
+	cipher, err := encryption.NewCipher(viper.GetString("relay.encryption_key"))
+	if err != nil {
+		log.Warnf("Relay target routes disabled: %v", err)
+	} else {
+		relayTargets := relay.NewRepository(relay_target.NewRelayTargetStorage(db), cipher)
+		relayHandler := handlers.NewRelayHandler(authorizer, relayTargets)
+		relayHandler.RegisterRoutes(r)
+	}
+}
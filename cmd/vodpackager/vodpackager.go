@@ -0,0 +1,47 @@
+package vodpackager
+
+import (
+	"context"
+	"github.com/M-Ro/go-vodstream/internal/blob"
+	"github.com/M-Ro/go-vodstream/internal/vod"
+	"github.com/nareix/joy4/av/avutil"
+	"github.com/nareix/joy4/format"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// NewCmd registers the cobra command to be called from the CLI.
+func NewCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "vodpackager <input> <output prefix>",
+		Short: "packages a recording as MPEG-DASH and fMP4 HLS for on demand playback",
+		Args:  cobra.ExactArgs(2),
+		Run:   Start,
+	}
+}
+
+// Start packages the input file beneath the output prefix of the media store.
+func Start(_ *cobra.Command, args []string) {
+	input, output := args[0], args[1]
+
+	format.RegisterAll()
+
+	media, err := blob.NewStore(blob.GetConfig())
+	if err != nil {
+		log.Fatalf("Couldn't open the media store: %v", err)
+	}
+
+	demuxer, err := avutil.Open(input)
+	if err != nil {
+		log.Fatalf("Couldn't open %s: %v", input, err)
+	}
+	defer demuxer.Close()
+
+	log.Infof("Packaging %s into %s", input, output)
+
+	if err := vod.Package(context.Background(), demuxer, media, output, vod.GetConfig()); err != nil {
+		log.Fatalf("Couldn't package %s: %v", input, err)
+	}
+
+	log.Infof("Packaged %s", input)
+}
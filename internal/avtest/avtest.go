@@ -0,0 +1,132 @@
+// Package avtest provides synthetic audio/video streams for testing media pipelines.
+package avtest
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"github.com/nareix/joy4/av"
+	"github.com/nareix/joy4/codec/aacparser"
+	"github.com/nareix/joy4/codec/h264parser"
+	"io"
+	"time"
+)
+
+// FrameDuration is the time between consecutive frames produced by Packets.
+const FrameDuration = 40 * time.Millisecond
+
+// VideoIdx and AudioIdx are the stream indexes of the streams returned by Streams.
+const (
+	VideoIdx = 0
+	AudioIdx = 1
+)
+
+// 1280x720 H.264 high profile parameter sets.
+const (
+	testSPS = "6764001facd9405005bb011000000300100000030320f1831960"
+	testPPS = "68ebecb22c"
+)
+
+// Streams returns codec data for a H.264 video stream and an AAC audio stream.
+func Streams() []av.CodecData {
+	sps, _ := hex.DecodeString(testSPS)
+	pps, _ := hex.DecodeString(testPPS)
+
+	video, err := h264parser.NewCodecDataFromSPSAndPPS(sps, pps)
+	if err != nil {
+		panic(err)
+	}
+
+	audio, err := aacparser.NewCodecDataFromMPEG4AudioConfig(aacparser.MPEG4AudioConfig{
+		SampleRate:    44100,
+		ChannelLayout: av.CH_STEREO,
+		ObjectType:    aacparser.AOT_AAC_LC,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return []av.CodecData{video, audio}
+}
+
+// Payload sizes, large enough for buffered muxers to flush regularly.
+const (
+	keyFrameSize   = 8192
+	deltaFrameSize = 2048
+	audioFrameSize = 256
+)
+
+// nalu returns a length prefixed NAL unit of the given type and size, tagged
+// with n so packets can be told apart after a round trip through a muxer.
+func nalu(naluType byte, size int, n int) []byte {
+	payload := make([]byte, 4+size)
+	binary.BigEndian.PutUint32(payload[0:4], uint32(len(payload)-4))
+	payload[4] = 0x60 | naluType
+	binary.BigEndian.PutUint32(payload[5:9], uint32(n))
+
+	return payload
+}
+
+// Packets returns count frames of interleaved video and audio packets, with a
+// keyframe every gopSize frames starting from the first.
+func Packets(count int, gopSize int) []av.Packet {
+	packets := make([]av.Packet, 0, count*2)
+
+	for i := 0; i < count; i++ {
+		keyFrame := i%gopSize == 0
+
+		naluType, size := byte(1), deltaFrameSize
+		if keyFrame {
+			naluType, size = 5, keyFrameSize
+		}
+
+		audio := make([]byte, audioFrameSize)
+		audio[0] = byte(i)
+
+		frameTime := time.Duration(i) * FrameDuration
+
+		packets = append(packets,
+			av.Packet{
+				Idx:        VideoIdx,
+				IsKeyFrame: keyFrame,
+				Time:       frameTime,
+				Data:       nalu(naluType, size, i),
+			},
+			av.Packet{
+				Idx:  AudioIdx,
+				Time: frameTime,
+				Data: audio,
+			},
+		)
+	}
+
+	return packets
+}
+
+// Demuxer replays a fixed set of packets, returning io.EOF once exhausted.
+type Demuxer struct {
+	streams []av.CodecData
+	packets []av.Packet
+}
+
+func (d *Demuxer) Streams() ([]av.CodecData, error) {
+	return d.streams, nil
+}
+
+func (d *Demuxer) ReadPacket() (av.Packet, error) {
+	if len(d.packets) == 0 {
+		return av.Packet{}, io.EOF
+	}
+
+	packet := d.packets[0]
+	d.packets = d.packets[1:]
+
+	return packet, nil
+}
+
+// NewDemuxer instantiates a Demuxer over the given streams and packets.
+func NewDemuxer(streams []av.CodecData, packets []av.Packet) *Demuxer {
+	return &Demuxer{
+		streams: streams,
+		packets: packets,
+	}
+}
@@ -0,0 +1,45 @@
+package avtest
+
+import (
+	"github.com/nareix/joy4/format/rtmp"
+	"net"
+	"testing"
+	"time"
+)
+
+// FreeAddress returns a local address with a port that is free to listen on.
+func FreeAddress(t testing.TB) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	return listener.Addr().String()
+}
+
+// StartRTMPServer starts the given server on its address, or a free local port
+// if none is set, returning the address once it is accepting connections. The
+// server runs until the test binary exits, as rtmp.Server cannot be shut down.
+func StartRTMPServer(t testing.TB, server *rtmp.Server) string {
+	if server.Addr == "" {
+		server.Addr = FreeAddress(t)
+	}
+
+	go server.ListenAndServe()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		conn, err := net.Dial("tcp", server.Addr)
+		if err == nil {
+			conn.Close()
+			return server.Addr
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("rtmp server did not start: %v", err)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
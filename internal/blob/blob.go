@@ -0,0 +1,150 @@
+// Package blob stores media as objects keyed by slash separated paths, either
+// on a local filesystem or in an S3-compatible object store, so recordings,
+// segments and imagery are written and served the same way from either.
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/spf13/afero"
+	"github.com/spf13/viper"
+	"io"
+	"mime"
+	"path"
+	"time"
+)
+
+var (
+	ErrNotFound           = errors.New("object not found")
+	ErrPresignUnsupported = errors.New("store cannot presign object URLs")
+	ErrUnknownBackend     = errors.New("unknown storage backend")
+)
+
+// Backend names accepted by NewStore.
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+// Object describes a stored object.
+type Object struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+// Store reads and writes objects.
+type Store interface {
+	// Put writes the object, replacing any object already at key. Readers never
+	// see a partially written object.
+	Put(ctx context.Context, key string, r io.Reader) error
+
+	// Get returns the contents of the object, or ErrNotFound.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// GetRange returns length bytes of the object from offset, or the rest of
+	// the object if length is negative.
+	GetRange(ctx context.Context, key string, offset int64, length int64) (io.ReadCloser, error)
+
+	// Stat describes the object, or returns ErrNotFound.
+	Stat(ctx context.Context, key string) (Object, error)
+
+	// Delete removes the object. Removing an object that does not exist is not an error.
+	Delete(ctx context.Context, key string) error
+
+	// List returns every object whose key starts with prefix, sorted by key.
+	List(ctx context.Context, prefix string) ([]Object, error)
+
+	// Presign returns a URL the object may be fetched from directly until the
+	// expiry passes, or ErrPresignUnsupported.
+	Presign(ctx context.Context, key string, expiry time.Duration) (string, error)
+}
+
+type Config struct {
+	// Backend selects the store, BackendLocal or BackendS3.
+	Backend string
+
+	// Directory is where the local store keeps objects.
+	Directory string
+
+	S3 S3Config
+}
+
+// contentTypes covers the media types the mime package does not know of.
+var contentTypes = map[string]string{
+	".flv":  "video/x-flv",
+	".m3u8": "application/vnd.apple.mpegurl",
+	".mpd":  "application/dash+xml",
+	".m4s":  "video/iso.segment",
+	".mp4":  "video/mp4",
+	".ts":   "video/mp2t",
+	".vtt":  "text/vtt",
+}
+
+// ContentType returns the media type of an object, from the extension of its key.
+func ContentType(key string) string {
+	ext := path.Ext(key)
+	if contentType, ok := contentTypes[ext]; ok {
+		return contentType
+	}
+
+	if contentType := mime.TypeByExtension(ext); contentType != "" {
+		return contentType
+	}
+
+	return "application/octet-stream"
+}
+
+// RemovePrefix deletes every object whose key starts with prefix.
+func RemovePrefix(ctx context.Context, store Store, prefix string) error {
+	objects, err := store.List(ctx, prefix)
+	if err != nil {
+		return err
+	}
+
+	for _, object := range objects {
+		if err := store.Delete(ctx, object.Key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func GetConfig() Config {
+	viper.SetDefault("media.backend", BackendLocal)
+	viper.SetDefault("media.directory", "recordings")
+	viper.SetDefault("media.s3.region", "us-east-1")
+
+	return Config{
+		Backend:   viper.GetString("media.backend"),
+		Directory: viper.GetString("media.directory"),
+		S3: S3Config{
+			Endpoint:  viper.GetString("media.s3.endpoint"),
+			Region:    viper.GetString("media.s3.region"),
+			Bucket:    viper.GetString("media.s3.bucket"),
+			AccessKey: viper.GetString("media.s3.access_key"),
+			SecretKey: viper.GetString("media.s3.secret_key"),
+			PathStyle: viper.GetBool("media.s3.path_style"),
+		},
+	}
+}
+
+// NewStore returns the Store selected by the config.
+func NewStore(config Config) (Store, error) {
+	switch config.Backend {
+	case BackendLocal:
+		return NewDirectoryStore(config.Directory), nil
+	case BackendS3:
+		return NewS3Store(config.S3, nil)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownBackend, config.Backend)
+	}
+}
+
+// NewDirectoryStore instantiates a LocalStore keeping objects beneath a
+// directory of the OS filesystem.
+func NewDirectoryStore(directory string) *LocalStore {
+	return NewLocalStore(afero.NewBasePathFs(afero.NewOsFs(), directory))
+}
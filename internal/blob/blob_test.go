@@ -0,0 +1,292 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"github.com/M-Ro/go-vodstream/internal/blob/s3test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/spf13/afero"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// testStores returns every Store implementation, each empty.
+func testStores(t *testing.T) map[string]Store {
+	server := s3test.NewServer()
+	t.Cleanup(server.Close)
+
+	// Lists are paged a few objects at a time to cover continuation.
+	server.MaxKeys = 2
+
+	s3, err := NewS3Store(S3Config{
+		Endpoint:  server.URL,
+		Region:    s3test.Region,
+		Bucket:    s3test.Bucket,
+		AccessKey: s3test.AccessKey,
+		SecretKey: s3test.SecretKey,
+		PathStyle: true,
+	}, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return map[string]Store{
+		"memory":    NewLocalStore(afero.NewMemMapFs()),
+		"directory": NewDirectoryStore(t.TempDir()),
+		"s3":        s3,
+	}
+}
+
+// read reads an object, or a range of it.
+func read(store Store, key string, offset int64, length int64) ([]byte, error) {
+	reader, err := store.GetRange(context.Background(), key, offset, length)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return ioutil.ReadAll(reader)
+}
+
+func TestStore_Get(t *testing.T) {
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			if err := store.Put(ctx, "a.flv", bytes.NewReader([]byte("replaced"))); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := store.Put(ctx, "a.flv", bytes.NewBufferString("0123456789")); err != nil {
+				t.Fatal(err)
+			}
+
+			tests := []struct {
+				testName string
+				key      string
+				offset   int64
+				length   int64
+				expected string
+				err      error
+			}{
+				{testName: "expect the whole object", key: "a.flv", length: -1, expected: "0123456789"},
+				{testName: "expect a range", key: "a.flv", offset: 2, length: 3, expected: "234"},
+				{testName: "expect the rest from an offset", key: "a.flv", offset: 7, length: -1, expected: "789"},
+				{testName: "expect an empty range", key: "a.flv", offset: 4, length: 0, expected: ""},
+				{testName: "expect missing objects not found", key: "b.flv", length: -1, err: ErrNotFound},
+			}
+
+			for _, test := range tests {
+				t.Run(test.testName, func(t *testing.T) {
+					data, err := read(store, test.key, test.offset, test.length)
+					if !cmp.Equal(err, test.err, cmpopts.EquateErrors()) {
+						t.Fatal(cmp.Diff(err, test.err, cmpopts.EquateErrors()))
+					}
+
+					if err == nil && string(data) != test.expected {
+						t.Fatal(cmp.Diff(string(data), test.expected))
+					}
+				})
+			}
+
+			object, err := store.Stat(ctx, "a.flv")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if object.Key != "a.flv" || object.Size != 10 || object.ModTime.IsZero() {
+				t.Fatalf("unexpected object %+v", object)
+			}
+
+			if _, err := store.Stat(ctx, "b.flv"); err != ErrNotFound {
+				t.Fatal(cmp.Diff(err, ErrNotFound, cmpopts.EquateErrors()))
+			}
+		})
+	}
+}
+
+func TestStore_List(t *testing.T) {
+	keys := []string{"a.flv", "a.index.json", "ab.flv", "dvr/x/seg0.ts", "dvr/x/seg1.ts", "dvr/y/seg0.ts"}
+
+	tests := []struct {
+		testName string
+		prefix   string
+		expected []string
+	}{
+		{testName: "expect every object without a prefix", prefix: "", expected: keys},
+		{testName: "expect the sidecars of a recording", prefix: "a.", expected: []string{"a.flv", "a.index.json"}},
+		{testName: "expect the objects beneath a directory", prefix: "dvr/x/", expected: keys[3:5]},
+		{testName: "expect nothing for an unknown prefix", prefix: "clips/", expected: []string{}},
+	}
+
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, key := range keys {
+				if err := store.Put(context.Background(), key, bytes.NewReader([]byte(key))); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			for _, test := range tests {
+				t.Run(test.testName, func(t *testing.T) {
+					objects, err := store.List(context.Background(), test.prefix)
+					if err != nil {
+						t.Fatal(err)
+					}
+
+					listed := make([]string, 0)
+					for _, object := range objects {
+						listed = append(listed, object.Key)
+
+						if object.Size != int64(len(object.Key)) {
+							t.Fatalf("expected %s to be %d bytes, got %d", object.Key, len(object.Key), object.Size)
+						}
+					}
+
+					if !cmp.Equal(listed, test.expected) {
+						t.Fatal(cmp.Diff(listed, test.expected))
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestStore_Delete(t *testing.T) {
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			for _, key := range []string{"dvr/x/seg0.ts", "dvr/x/seg1.ts", "a.flv"} {
+				if err := store.Put(ctx, key, bytes.NewReader([]byte(key))); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := RemovePrefix(ctx, store, "dvr/"); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := store.Delete(ctx, "missing.flv"); err != nil {
+				t.Fatal(err)
+			}
+
+			objects, err := store.List(ctx, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected := []Object{{Key: "a.flv", Size: 5}}
+			if !cmp.Equal(objects, expected, cmpopts.IgnoreFields(Object{}, "ModTime")) {
+				t.Fatal(cmp.Diff(objects, expected, cmpopts.IgnoreFields(Object{}, "ModTime")))
+			}
+		})
+	}
+}
+
+func TestStore_Presign(t *testing.T) {
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := store.Put(context.Background(), "sprites.vtt", bytes.NewBufferString("WEBVTT")); err != nil {
+				t.Fatal(err)
+			}
+
+			url, err := store.Presign(context.Background(), "sprites.vtt", time.Minute)
+			if _, local := store.(*LocalStore); local {
+				if err != ErrPresignUnsupported {
+					t.Fatal(cmp.Diff(err, ErrPresignUnsupported, cmpopts.EquateErrors()))
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := http.Get(url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			body, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.StatusCode != http.StatusOK || string(body) != "WEBVTT" {
+				t.Fatalf("expected the object from its presigned URL, got %s: %s", resp.Status, body)
+			}
+
+			if contentType := resp.Header.Get("Content-Type"); contentType != "text/vtt" {
+				t.Fatal(cmp.Diff(contentType, "text/vtt"))
+			}
+		})
+	}
+}
+
+func TestFile(t *testing.T) {
+	data := make([]byte, 2*chunkSize+100)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := store.Put(context.Background(), "a.flv", bytes.NewReader(data)); err != nil {
+				t.Fatal(err)
+			}
+
+			file, err := Open(context.Background(), store, "a.flv")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+
+			// Reads straddle chunks.
+			p := make([]byte, 200)
+			if n, err := file.ReadAt(p, chunkSize-100); n != len(p) || err != nil {
+				t.Fatalf("expected a full read across chunks, got %d: %v", n, err)
+			}
+
+			if !bytes.Equal(p, data[chunkSize-100:chunkSize+100]) {
+				t.Fatal("read the wrong bytes across chunks")
+			}
+
+			if n, err := file.ReadAt(p, int64(len(data))-50); n != 50 || err != io.EOF {
+				t.Fatalf("expected a short read at the end, got %d: %v", n, err)
+			}
+
+			if _, err := file.Seek(10, io.SeekStart); err != nil {
+				t.Fatal(err)
+			}
+
+			rest, err := ioutil.ReadAll(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(rest, data[10:]) {
+				t.Fatal("read the wrong bytes after seeking")
+			}
+		})
+	}
+}
+
+func TestContentType(t *testing.T) {
+	tests := map[string]string{
+		"a.flv":               "video/x-flv",
+		"a.poster.jpg":        "image/jpeg",
+		"dvr/x/seg0.ts":       "video/mp2t",
+		"vod/master.m3u8":     "application/vnd.apple.mpegurl",
+		"a.unknown-extension": "application/octet-stream",
+	}
+
+	for key, expected := range tests {
+		if contentType := ContentType(key); contentType != expected {
+			t.Fatal(cmp.Diff(contentType, expected))
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+var (
+	ErrInvalidSeek = errors.New("seek to a negative offset")
+)
+
+// chunkSize is the least read from a store at once, so the small reads
+// demuxers make near each other are served by a single request.
+const chunkSize = 1 << 20
+
+// File reads an object through ranged reads, for callers expecting random
+// access to a file.
+type File struct {
+	ctx    context.Context
+	store  Store
+	object Object
+
+	lock sync.Mutex
+
+	// chunk holds the object from chunkOffset, as last read.
+	chunk       []byte
+	chunkOffset int64
+
+	// offset is where Read continues from.
+	offset int64
+}
+
+// Open opens an object for reading. Reads are made with ctx.
+func Open(ctx context.Context, store Store, key string) (*File, error) {
+	object, err := store.Stat(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{ctx: ctx, store: store, object: object}, nil
+}
+
+// Object describes the object being read.
+func (f *File) Object() Object {
+	return f.object
+}
+
+// fill reads at least length bytes from offset, or up to the end of the
+// object, into the chunk. The lock must be held.
+func (f *File) fill(offset int64, length int64) error {
+	if length < chunkSize {
+		length = chunkSize
+	}
+
+	if offset+length > f.object.Size {
+		length = f.object.Size - offset
+	}
+
+	reader, err := f.store.GetRange(f.ctx, f.object.Key, offset, length)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	chunk := make([]byte, length)
+	if _, err := io.ReadFull(reader, chunk); err != nil {
+		return err
+	}
+
+	f.chunk, f.chunkOffset = chunk, offset
+	return nil
+}
+
+func (f *File) ReadAt(p []byte, off int64) (int, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	n := 0
+	for n < len(p) {
+		at := off + int64(n)
+		if at >= f.object.Size {
+			return n, io.EOF
+		}
+
+		if at < f.chunkOffset || at >= f.chunkOffset+int64(len(f.chunk)) {
+			if err := f.fill(at, int64(len(p)-n)); err != nil {
+				return n, err
+			}
+		}
+
+		n += copy(p[n:], f.chunk[at-f.chunkOffset:])
+	}
+
+	return n, nil
+}
+
+func (f *File) Read(p []byte) (int, error) {
+	n, err := f.ReadAt(p, f.offset)
+	f.offset += int64(n)
+
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+
+	return n, err
+}
+
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.object.Size
+	}
+
+	if offset < 0 {
+		return 0, ErrInvalidSeek
+	}
+
+	f.offset = offset
+	return offset, nil
+}
+
+// Close releases the chunk read. Nothing is held open between reads.
+func (f *File) Close() error {
+	f.lock.Lock()
+	f.chunk = nil
+	f.lock.Unlock()
+
+	return nil
+}
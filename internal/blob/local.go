@@ -0,0 +1,176 @@
+package blob
+
+import (
+	"context"
+	"github.com/spf13/afero"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// tempPrefix starts the names of objects still being written, which are
+// skipped when listing.
+const tempPrefix = ".tmp-"
+
+// LocalStore keeps objects as files on a filesystem, at the path of their key.
+type LocalStore struct {
+	fs afero.Fs
+}
+
+// readCloser closes a file read through a limited reader.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func (s *LocalStore) path(key string) string {
+	return filepath.FromSlash(key)
+}
+
+// Put writes the object to a temporary file beside its path, moving it into
+// place once complete.
+func (s *LocalStore) Put(_ context.Context, key string, r io.Reader) error {
+	dir := filepath.Dir(s.path(key))
+	if err := s.fs.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	file, err := afero.TempFile(s.fs, dir, tempPrefix+"*")
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(file, r)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = s.fs.Rename(file.Name(), s.path(key))
+	}
+
+	if err != nil {
+		s.fs.Remove(file.Name())
+		return err
+	}
+
+	return nil
+}
+
+// open opens the file of an object.
+func (s *LocalStore) open(key string) (afero.File, error) {
+	file, err := s.fs.Open(s.path(key))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err == nil && info.IsDir() {
+		err = ErrNotFound
+	}
+
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return file, nil
+}
+
+func (s *LocalStore) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	return s.open(key)
+}
+
+func (s *LocalStore) GetRange(_ context.Context, key string, offset int64, length int64) (io.ReadCloser, error) {
+	file, err := s.open(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if length < 0 {
+		return file, nil
+	}
+
+	return readCloser{Reader: io.LimitReader(file, length), Closer: file}, nil
+}
+
+func (s *LocalStore) Stat(_ context.Context, key string) (Object, error) {
+	info, err := s.fs.Stat(s.path(key))
+	if os.IsNotExist(err) || (err == nil && info.IsDir()) {
+		return Object{}, ErrNotFound
+	} else if err != nil {
+		return Object{}, err
+	}
+
+	return Object{Key: key, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+// Delete removes the object's file, and any directories left empty by it.
+func (s *LocalStore) Delete(_ context.Context, key string) error {
+	path := s.path(key)
+
+	if err := s.fs.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	for dir := filepath.Dir(path); dir != "." && dir != string(filepath.Separator); dir = filepath.Dir(dir) {
+		if empty, err := afero.IsEmpty(s.fs, dir); err != nil || !empty || s.fs.Remove(dir) != nil {
+			break
+		}
+	}
+
+	return nil
+}
+
+// List walks the directory holding prefix, so listing a prefix ending in a
+// slash reads only the objects beneath it.
+func (s *LocalStore) List(_ context.Context, prefix string) ([]Object, error) {
+	root := filepath.Dir(s.path(prefix + "_"))
+
+	objects := make([]Object, 0)
+	err := afero.Walk(s.fs, root, func(path string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		key := filepath.ToSlash(filepath.Clean(path))
+		if info.IsDir() || strings.HasPrefix(info.Name(), tempPrefix) || !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		objects = append(objects, Object{Key: key, Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Key < objects[j].Key
+	})
+
+	return objects, nil
+}
+
+// Presign is unsupported, local objects are served through the application.
+func (s *LocalStore) Presign(_ context.Context, _ string, _ time.Duration) (string, error) {
+	return "", ErrPresignUnsupported
+}
+
+// NewLocalStore instantiates a LocalStore keeping objects on fs.
+func NewLocalStore(fs afero.Fs) *LocalStore {
+	return &LocalStore{fs: fs}
+}
@@ -0,0 +1,295 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// maxPresignExpiry is the longest S3 accepts presigned URLs for.
+const maxPresignExpiry = 7 * 24 * time.Hour
+
+type S3Config struct {
+	// Endpoint is the base URL of the service, such as https://s3.eu-west-1.amazonaws.com.
+	Endpoint string
+	Region   string
+	Bucket   string
+
+	AccessKey string
+	SecretKey string
+
+	// PathStyle addresses the bucket in the URL path rather than the host
+	// name, as most self-hosted S3-compatible services expect.
+	PathStyle bool
+}
+
+// S3Store keeps objects in a bucket of an S3-compatible service. Objects are
+// uploaded in a single request, so are limited to the 5GB S3 allows.
+type S3Store struct {
+	config   S3Config
+	endpoint *url.URL
+	signer   signer
+	client   *http.Client
+}
+
+// s3Error is the body of an error response.
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// listResult is the body of a ListObjectsV2 response.
+type listResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// url returns the URL of an object, or of the bucket if key is empty.
+func (s *S3Store) url(key string) *url.URL {
+	u := *s.endpoint
+	path := strings.TrimSuffix(u.Path, "/")
+
+	if s.config.PathStyle {
+		path += "/" + s.config.Bucket
+	} else {
+		u.Host = s.config.Bucket + "." + u.Host
+	}
+
+	u.Path = path + "/" + key
+	u.RawPath = escape(u.Path, true)
+
+	return &u
+}
+
+// do signs and sends a request, turning error responses into errors.
+func (s *S3Store) do(req *http.Request, payload string) (*http.Response, error) {
+	s.signer.sign(req, payload, time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+
+	var body s3Error
+	if err := xml.NewDecoder(resp.Body).Decode(&body); err != nil || body.Code == "" {
+		return nil, fmt.Errorf("s3 %s %s: %s", req.Method, req.URL.Path, resp.Status)
+	}
+
+	return nil, fmt.Errorf("s3 %s %s: %s: %s", req.Method, req.URL.Path, body.Code, body.Message)
+}
+
+// length returns the length of r, buffering it if it cannot be found out
+// otherwise, as uploads must state their length.
+func length(r io.Reader) (io.Reader, int64, error) {
+	switch r := r.(type) {
+	case *bytes.Reader:
+		return r, int64(r.Len()), nil
+	case *bytes.Buffer:
+		return r, int64(r.Len()), nil
+	case *os.File:
+		info, err := r.Stat()
+		if err != nil {
+			return nil, 0, err
+		}
+
+		offset, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		return r, info.Size() - offset, nil
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return bytes.NewReader(data), int64(len(data)), nil
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, r io.Reader) error {
+	body, size, err := length(r)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.url(key).String(), ioutil.NopCloser(body))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", ContentType(key))
+
+	resp, err := s.do(req, unsignedPayload)
+	if err != nil {
+		return err
+	}
+
+	return resp.Body.Close()
+}
+
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return s.GetRange(ctx, key, 0, -1)
+}
+
+func (s *S3Store) GetRange(ctx context.Context, key string, offset int64, length int64) (io.ReadCloser, error) {
+	// Ranges cannot be empty, so nothing is requested.
+	if length == 0 {
+		return ioutil.NopCloser(bytes.NewReader(nil)), nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url(key).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if length >= 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	} else if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := s.do(req, emptyPayload)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+func (s *S3Store) Stat(ctx context.Context, key string) (Object, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, s.url(key).String(), nil)
+	if err != nil {
+		return Object{}, err
+	}
+
+	resp, err := s.do(req, emptyPayload)
+	if err != nil {
+		return Object{}, err
+	}
+	resp.Body.Close()
+
+	modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+
+	return Object{Key: key, Size: resp.ContentLength, ModTime: modTime}, nil
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.url(key).String(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do(req, emptyPayload)
+	if err == ErrNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	return resp.Body.Close()
+}
+
+// List pages through ListObjectsV2 until every matching object is listed.
+func (s *S3Store) List(ctx context.Context, prefix string) ([]Object, error) {
+	objects := make([]Object, 0)
+	token := ""
+
+	for {
+		u := s.url("")
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		u.RawQuery = canonicalQuery(query)
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := s.do(req, emptyPayload)
+		if err != nil {
+			return nil, err
+		}
+
+		var result listResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, content := range result.Contents {
+			objects = append(objects, Object{Key: content.Key, Size: content.Size, ModTime: content.LastModified})
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+
+		token = result.NextContinuationToken
+	}
+}
+
+// Presign returns a URL to GET the object from the service directly. Expiries
+// are capped at the seven days S3 accepts.
+func (s *S3Store) Presign(_ context.Context, key string, expiry time.Duration) (string, error) {
+	if expiry > maxPresignExpiry {
+		expiry = maxPresignExpiry
+	} else if expiry < time.Second {
+		expiry = time.Second
+	}
+
+	u := s.url(key)
+	s.signer.presign(u, time.Now(), expiry)
+
+	return u.String(), nil
+}
+
+// NewS3Store instantiates an S3Store. The default HTTP client is used if client is nil.
+func NewS3Store(config S3Config, client *http.Client) (*S3Store, error) {
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	if endpoint.Scheme == "" || endpoint.Host == "" || config.Bucket == "" {
+		return nil, fmt.Errorf("s3 endpoint and bucket are required, got %q and %q", config.Endpoint, config.Bucket)
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &S3Store{
+		config:   config,
+		endpoint: endpoint,
+		signer:   signer{accessKey: config.AccessKey, secretKey: config.SecretKey, region: config.Region},
+		client:   client,
+	}, nil
+}
@@ -0,0 +1,211 @@
+// Package s3test provides an in-process stand-in for an S3-compatible service,
+// holding a single bucket in memory and addressed path style.
+package s3test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Credentials requests to the server must be signed with.
+const (
+	Region    = "us-east-1"
+	Bucket    = "media"
+	AccessKey = "s3test"
+	SecretKey = "s3test-secret"
+)
+
+type object struct {
+	data        []byte
+	contentType string
+	modTime     time.Time
+}
+
+// Server serves the bucket. Requests must carry a signature, or be presigned,
+// for AccessKey. Signatures themselves are not verified.
+type Server struct {
+	*httptest.Server
+
+	// MaxKeys is the most objects listed per page.
+	MaxKeys int
+
+	lock    sync.Mutex
+	objects map[string]object
+}
+
+type listContent struct {
+	Key          string `xml:"Key"`
+	Size         int    `xml:"Size"`
+	LastModified string `xml:"LastModified"`
+}
+
+type listBucketResult struct {
+	XMLName               xml.Name      `xml:"ListBucketResult"`
+	Name                  string        `xml:"Name"`
+	Prefix                string        `xml:"Prefix"`
+	KeyCount              int           `xml:"KeyCount"`
+	IsTruncated           bool          `xml:"IsTruncated"`
+	NextContinuationToken string        `xml:"NextContinuationToken,omitempty"`
+	Contents              []listContent `xml:"Contents"`
+}
+
+// writeError writes an S3 error response.
+func writeError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message></Error>", code, http.StatusText(status))
+}
+
+// authorized returns true if the request is signed, or presigned and not
+// expired, for AccessKey.
+func authorized(r *http.Request) bool {
+	credential := "Credential=" + AccessKey + "/"
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		return strings.HasPrefix(auth, "AWS4-HMAC-SHA256 ") && strings.Contains(auth, credential) &&
+			r.Header.Get("X-Amz-Date") != "" && r.Header.Get("X-Amz-Content-Sha256") != ""
+	}
+
+	query := r.URL.Query()
+	if !strings.HasPrefix(query.Get("X-Amz-Credential"), AccessKey+"/") || query.Get("X-Amz-Signature") == "" {
+		return false
+	}
+
+	date, err := time.Parse("20060102T150405Z", query.Get("X-Amz-Date"))
+	expires, expiresErr := strconv.Atoi(query.Get("X-Amz-Expires"))
+
+	return err == nil && expiresErr == nil && time.Now().Before(date.Add(time.Duration(expires)*time.Second))
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !authorized(r) {
+		writeError(w, http.StatusForbidden, "AccessDenied")
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	if path != Bucket && !strings.HasPrefix(path, Bucket+"/") {
+		writeError(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	key := strings.TrimPrefix(strings.TrimPrefix(path, Bucket), "/")
+	if key == "" {
+		if r.Method != http.MethodGet || r.URL.Query().Get("list-type") != "2" {
+			writeError(w, http.StatusNotImplemented, "NotImplemented")
+			return
+		}
+
+		s.list(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		s.put(w, r, key)
+	case http.MethodGet, http.MethodHead:
+		s.get(w, r, key)
+	case http.MethodDelete:
+		s.lock.Lock()
+		delete(s.objects, key)
+		s.lock.Unlock()
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+func (s *Server) put(w http.ResponseWriter, r *http.Request, key string) {
+	if r.ContentLength < 0 {
+		writeError(w, http.StatusLengthRequired, "MissingContentLength")
+		return
+	}
+
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil || int64(len(data)) != r.ContentLength {
+		writeError(w, http.StatusBadRequest, "IncompleteBody")
+		return
+	}
+
+	s.lock.Lock()
+	s.objects[key] = object{data: data, contentType: r.Header.Get("Content-Type"), modTime: time.Now()}
+	s.lock.Unlock()
+}
+
+// get serves an object, and ranges of it.
+func (s *Server) get(w http.ResponseWriter, r *http.Request, key string) {
+	s.lock.Lock()
+	obj, ok := s.objects[key]
+	s.lock.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "NoSuchKey")
+		return
+	}
+
+	w.Header().Set("Content-Type", obj.contentType)
+	http.ServeContent(w, r, "", obj.modTime, bytes.NewReader(obj.data))
+}
+
+// list serves a page of ListObjectsV2. Continuation tokens are the last key listed.
+func (s *Server) list(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	prefix, after := query.Get("prefix"), query.Get("continuation-token")
+
+	s.lock.Lock()
+	keys := make([]string, 0)
+	for key := range s.objects {
+		if strings.HasPrefix(key, prefix) && key > after {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	result := listBucketResult{Name: Bucket, Prefix: prefix}
+	for _, key := range keys {
+		if len(result.Contents) == s.MaxKeys {
+			result.IsTruncated = true
+			result.NextContinuationToken = result.Contents[len(result.Contents)-1].Key
+			break
+		}
+
+		obj := s.objects[key]
+		result.Contents = append(result.Contents, listContent{
+			Key:          key,
+			Size:         len(obj.data),
+			LastModified: obj.modTime.UTC().Format(time.RFC3339Nano),
+		})
+	}
+	s.lock.Unlock()
+
+	result.KeyCount = len(result.Contents)
+
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(result)
+}
+
+// Object returns the contents and content type of a stored object.
+func (s *Server) Object(key string) ([]byte, string, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	obj, ok := s.objects[key]
+	return obj.data, obj.contentType, ok
+}
+
+// NewServer starts a Server with an empty bucket. It must be closed once done.
+func NewServer() *Server {
+	s := &Server{MaxKeys: 1000, objects: make(map[string]object)}
+	s.Server = httptest.NewServer(s)
+
+	return s
+}
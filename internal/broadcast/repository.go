@@ -0,0 +1,138 @@
+package broadcast
+
+import (
+	"context"
+	"errors"
+	"github.com/M-Ro/go-vodstream/internal/domain/broadcast"
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrBroadcastNotFound = errors.New("no broadcast found")
+)
+
+type StorageProvider interface {
+	All(ctx context.Context) ([]storage.Broadcast, error)
+	List(ctx context.Context, options paginate.QueryOptions) ([]storage.Broadcast, paginate.Page, error)
+	ListLive(ctx context.Context, options paginate.QueryOptions) ([]storage.Broadcast, paginate.Page, error)
+	ListLiveByCategory(
+		ctx context.Context, category string, options paginate.QueryOptions,
+	) ([]storage.Broadcast, paginate.Page, error)
+	GetByID(ctx context.Context, id uuid.UUID) *storage.Broadcast
+	Delete(ctx context.Context, id uuid.UUID) error
+	Insert(ctx context.Context, broadcast *storage.Broadcast) error
+	Update(ctx context.Context, id uuid.UUID, broadcast *storage.Broadcast) error
+	UpdateViewerCount(ctx context.Context, id uuid.UUID, viewers int64) error
+}
+
+type Repository struct {
+	StorageProvider StorageProvider
+}
+
+// All returns all broadcasts in the repository.
+func (r Repository) All(ctx context.Context) ([]broadcast.Broadcast, error) {
+	broadcasts, err := r.StorageProvider.All(ctx)
+	if err != nil {
+		return []broadcast.Broadcast{}, err
+	}
+
+	return storage.BroadcastsToDomain(broadcasts), nil
+}
+
+// List returns a set of broadcasts specified by the provided QueryOptions,
+// with the cursors of the pages either side.
+func (r Repository) List(
+	ctx context.Context, options paginate.QueryOptions,
+) ([]broadcast.Broadcast, paginate.Page, error) {
+	broadcasts, page, err := r.StorageProvider.List(ctx, options)
+	if err != nil {
+		return []broadcast.Broadcast{}, paginate.Page{}, err
+	}
+
+	return storage.BroadcastsToDomain(broadcasts), page, nil
+}
+
+// ListLive returns a set of the live, published broadcasts specified by the provided QueryOptions,
+// with the cursors of the pages either side.
+func (r Repository) ListLive(
+	ctx context.Context, options paginate.QueryOptions,
+) ([]broadcast.Broadcast, paginate.Page, error) {
+	broadcasts, page, err := r.StorageProvider.ListLive(ctx, options)
+	if err != nil {
+		return []broadcast.Broadcast{}, paginate.Page{}, err
+	}
+
+	return storage.BroadcastsToDomain(broadcasts), page, nil
+}
+
+// ListLiveByCategory returns a set of the live, published broadcasts in the given category,
+// specified by the provided QueryOptions, with the cursors of the pages either side.
+func (r Repository) ListLiveByCategory(
+	ctx context.Context, category string, options paginate.QueryOptions,
+) ([]broadcast.Broadcast, paginate.Page, error) {
+	broadcasts, page, err := r.StorageProvider.ListLiveByCategory(ctx, category, options)
+	if err != nil {
+		return []broadcast.Broadcast{}, paginate.Page{}, err
+	}
+
+	return storage.BroadcastsToDomain(broadcasts), page, nil
+}
+
+// GetByID returns the broadcast with the given ID, or returns an error.
+func (r Repository) GetByID(ctx context.Context, id uuid.UUID) (broadcast.Broadcast, error) {
+	getBroadcast := r.StorageProvider.GetByID(ctx, id)
+	if getBroadcast == nil {
+		return broadcast.Broadcast{}, ErrBroadcastNotFound
+	}
+
+	return storage.BroadcastToDomain(*getBroadcast), nil
+}
+
+// Delete removes a broadcast with the given ID. Returns an error on failure.
+func (r Repository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.StorageProvider.Delete(ctx, id)
+}
+
+// Insert takes a domain model and inserts it to the storage provider.
+// After successful insertion the broadcast ID and timestamps are filled.
+func (r Repository) Insert(ctx context.Context, newBroadcast *broadcast.Broadcast) error {
+	storageBroadcast := storage.BroadcastToStorage(*newBroadcast)
+
+	err := r.StorageProvider.Insert(ctx, &storageBroadcast)
+	if err != nil {
+		return err
+	}
+
+	newBroadcast.Id = storageBroadcast.Id
+	newBroadcast.CreatedAt = storageBroadcast.CreatedAt
+	newBroadcast.UpdatedAt = storageBroadcast.UpdatedAt
+
+	return nil
+}
+
+// Update takes a broadcast and updates the record within the StorageProvider.
+func (r Repository) Update(
+	ctx context.Context, id uuid.UUID, updateBroadcast broadcast.Broadcast,
+) (broadcast.Broadcast, error) {
+	storageBroadcast := storage.BroadcastToStorage(updateBroadcast)
+
+	err := r.StorageProvider.Update(ctx, id, &storageBroadcast)
+	if err != nil {
+		return broadcast.Broadcast{}, err
+	}
+
+	return storage.BroadcastToDomain(storageBroadcast), nil
+}
+
+// UpdateViewerCount sets how many are watching the broadcast with the given ID.
+func (r Repository) UpdateViewerCount(ctx context.Context, id uuid.UUID, viewers int64) error {
+	return r.StorageProvider.UpdateViewerCount(ctx, id, viewers)
+}
+
+func NewRepository(s StorageProvider) Repository {
+	return Repository{
+		StorageProvider: s,
+	}
+}
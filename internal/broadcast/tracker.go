@@ -0,0 +1,95 @@
+package broadcast
+
+import (
+	"context"
+	"github.com/M-Ro/go-vodstream/internal/domain/broadcast"
+	"github.com/M-Ro/go-vodstream/internal/domain/channel"
+	"github.com/M-Ro/go-vodstream/internal/live"
+	"github.com/google/uuid"
+)
+
+// BroadcastStorage stores the broadcasts tracked as channels are published.
+type BroadcastStorage interface {
+	GetByID(ctx context.Context, id uuid.UUID) (broadcast.Broadcast, error)
+	Insert(ctx context.Context, broadcast *broadcast.Broadcast) error
+	Update(ctx context.Context, id uuid.UUID, broadcast broadcast.Broadcast) (broadcast.Broadcast, error)
+	UpdateViewerCount(ctx context.Context, id uuid.UUID, viewers int64) error
+}
+
+// ChannelProvider looks up the channel metadata new broadcasts inherit.
+type ChannelProvider interface {
+	GetByBroadcasterID(ctx context.Context, broadcasterId uint64) (channel.Channel, error)
+}
+
+// Transactor runs units of work spanning several storages atomically.
+type Transactor interface {
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// Tracker records a broadcast for each publish of a live channel, active
+// until the publisher disconnects.
+type Tracker struct {
+	broadcasts   BroadcastStorage
+	channels     ChannelProvider
+	transactions Transactor
+}
+
+// Start records the broadcast of a channel that has just been published,
+// inheriting the current metadata of the broadcaster's channel. Only public
+// broadcasts are published, so unlisted and private broadcasts are never listed.
+func (t *Tracker) Start(ctx context.Context, live *live.Channel) (broadcast.Broadcast, error) {
+	metadata, err := t.channels.GetByBroadcasterID(ctx, live.BroadcasterId)
+	if err != nil {
+		metadata = channel.Default(live.BroadcasterId, live.Name)
+	}
+
+	started := broadcast.Broadcast{
+		Id:            live.BroadcastId,
+		BroadcasterId: live.BroadcasterId,
+		Title:         metadata.BroadcastTitle(),
+		Category:      metadata.Category,
+		Tags:          metadata.Tags,
+		Language:      metadata.Language,
+		Mature:        metadata.Mature,
+		IsActive:      true,
+		IsPublished:   live.Visibility == broadcast.VisibilityPublic,
+		PublishedAt:   live.StartedAt,
+	}
+
+	if err := t.broadcasts.Insert(ctx, &started); err != nil {
+		return broadcast.Broadcast{}, err
+	}
+
+	return started, nil
+}
+
+// ReportViewers records how many are watching the broadcast with the given ID.
+func (t *Tracker) ReportViewers(ctx context.Context, id uuid.UUID, viewers int64) error {
+	return t.broadcasts.UpdateViewerCount(ctx, id, viewers)
+}
+
+// End marks the broadcast with the given ID as no longer live, keeping any
+// edits made to it while it was.
+func (t *Tracker) End(ctx context.Context, id uuid.UUID) error {
+	return t.transactions.WithTx(ctx, func(ctx context.Context) error {
+		ended, err := t.broadcasts.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		ended.IsActive = false
+		ended.ViewerCount = 0
+
+		_, err = t.broadcasts.Update(ctx, id, ended)
+		return err
+	})
+}
+
+// NewTracker instantiates a new Tracker.
+func NewTracker(broadcasts BroadcastStorage, channels ChannelProvider, transactions Transactor) *Tracker {
+	return &Tracker{
+		broadcasts:   broadcasts,
+		channels:     channels,
+		transactions: transactions,
+	}
+}
@@ -0,0 +1,161 @@
+package broadcast
+
+import (
+	"context"
+	"errors"
+	"github.com/M-Ro/go-vodstream/internal/domain/broadcast"
+	"github.com/M-Ro/go-vodstream/internal/domain/channel"
+	"github.com/M-Ro/go-vodstream/internal/live"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"testing"
+	"time"
+)
+
+// mockTrackedBroadcasts stores tracked broadcasts in memory.
+type mockTrackedBroadcasts map[uuid.UUID]broadcast.Broadcast
+
+func (m mockTrackedBroadcasts) GetByID(_ context.Context, id uuid.UUID) (broadcast.Broadcast, error) {
+	b, ok := m[id]
+	if !ok {
+		return broadcast.Broadcast{}, ErrBroadcastNotFound
+	}
+
+	return b, nil
+}
+
+func (m mockTrackedBroadcasts) Insert(_ context.Context, b *broadcast.Broadcast) error {
+	m[b.Id] = *b
+	return nil
+}
+
+func (m mockTrackedBroadcasts) Update(_ context.Context, id uuid.UUID, b broadcast.Broadcast) (broadcast.Broadcast, error) {
+	m[id] = b
+	return b, nil
+}
+
+func (m mockTrackedBroadcasts) UpdateViewerCount(_ context.Context, id uuid.UUID, viewers int64) error {
+	b, ok := m[id]
+	if !ok {
+		return ErrBroadcastNotFound
+	}
+
+	b.ViewerCount = viewers
+	m[id] = b
+
+	return nil
+}
+
+// mockChannels looks up channel metadata in memory, keyed by broadcaster.
+type mockChannels map[uint64]channel.Channel
+
+func (m mockChannels) GetByBroadcasterID(_ context.Context, broadcasterId uint64) (channel.Channel, error) {
+	c, ok := m[broadcasterId]
+	if !ok {
+		return channel.Channel{}, errors.New("channel does not exist")
+	}
+
+	return c, nil
+}
+
+func TestTracker_Start(t *testing.T) {
+	startedAt := time.Date(2026, 10, 18, 20, 0, 0, 0, time.UTC)
+	channels := mockChannels{
+		1: {
+			BroadcasterId: 1,
+			DisplayName:   "The Broadcaster",
+			DefaultTitle:  "Any% attempts",
+			Category:      "speedruns",
+			Tags:          []string{"any%"},
+			Language:      "en",
+			Mature:        true,
+		},
+	}
+
+	tests := []struct {
+		testName string
+		live     *live.Channel
+		expected broadcast.Broadcast
+	}{
+		{
+			testName: "Expect the broadcast to inherit the channel's metadata.",
+			live: &live.Channel{
+				Name:          "broadcaster",
+				BroadcastId:   uuid.MustParse("6f1c9a52-8d3e-4b7a-a1c2-5e4f3d2c1b01"),
+				BroadcasterId: 1,
+				Visibility:    broadcast.VisibilityPublic,
+				StartedAt:     startedAt,
+			},
+			expected: broadcast.Broadcast{
+				Id:            uuid.MustParse("6f1c9a52-8d3e-4b7a-a1c2-5e4f3d2c1b01"),
+				BroadcasterId: 1,
+				Title:         "Any% attempts",
+				Category:      "speedruns",
+				Tags:          []string{"any%"},
+				Language:      "en",
+				Mature:        true,
+				IsActive:      true,
+				IsPublished:   true,
+				PublishedAt:   startedAt,
+			},
+		},
+		{
+			testName: "Expect an unlisted broadcast without a channel page to be titled after the broadcaster and unpublished.",
+			live: &live.Channel{
+				Name:          "viewer",
+				BroadcastId:   uuid.MustParse("6f1c9a52-8d3e-4b7a-a1c2-5e4f3d2c1b02"),
+				BroadcasterId: 2,
+				Visibility:    broadcast.VisibilityUnlisted,
+				StartedAt:     startedAt,
+			},
+			expected: broadcast.Broadcast{
+				Id:            uuid.MustParse("6f1c9a52-8d3e-4b7a-a1c2-5e4f3d2c1b02"),
+				BroadcasterId: 2,
+				Title:         "viewer",
+				Tags:          []string{},
+				IsActive:      true,
+				PublishedAt:   startedAt,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			ctx := context.Background()
+			broadcasts := mockTrackedBroadcasts{}
+			tracker := NewTracker(broadcasts, channels, storage.Immediate{})
+
+			started, err := tracker.Start(ctx, test.live)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(started, test.expected) {
+				t.Fatal(cmp.Diff(started, test.expected))
+			}
+
+			if err := tracker.ReportViewers(ctx, started.Id, 42); err != nil {
+				t.Fatal(err)
+			}
+
+			if viewers := broadcasts[started.Id].ViewerCount; viewers != 42 {
+				t.Fatalf("expected the reported viewers stored, got %d", viewers)
+			}
+
+			// Edits made while live are kept when the broadcast ends.
+			edited := broadcasts[started.Id]
+			edited.Title = "edited"
+			broadcasts[started.Id] = edited
+
+			if err := tracker.End(ctx, started.Id); err != nil {
+				t.Fatal(err)
+			}
+
+			ended := broadcasts[started.Id]
+			if ended.IsActive || ended.ViewerCount != 0 || ended.Title != "edited" {
+				t.Fatalf("expected the edited broadcast to have ended without viewers, got %+v", ended)
+			}
+		})
+	}
+}
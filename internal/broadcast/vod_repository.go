@@ -0,0 +1,119 @@
+package broadcast
+
+import (
+	"context"
+	"errors"
+	"github.com/M-Ro/go-vodstream/internal/domain/broadcast"
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrVodNotFound = errors.New("no broadcast vod found")
+)
+
+type VodStorageProvider interface {
+	All(ctx context.Context) ([]storage.BroadcastVod, error)
+	List(ctx context.Context, options paginate.QueryOptions) ([]storage.BroadcastVod, paginate.Page, error)
+	GetByID(ctx context.Context, id uuid.UUID) *storage.BroadcastVod
+	GetByStreamID(ctx context.Context, streamId uuid.UUID) ([]storage.BroadcastVod, error)
+	GetByVideoID(ctx context.Context, videoId uuid.UUID) ([]storage.BroadcastVod, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+	DeleteByVideoID(ctx context.Context, videoId uuid.UUID) error
+	Insert(ctx context.Context, broadcastVod *storage.BroadcastVod) error
+	Update(ctx context.Context, id uuid.UUID, broadcastVod *storage.BroadcastVod) error
+}
+
+// VodRepository stores the links between broadcasts and the videos recorded from them.
+type VodRepository struct {
+	StorageProvider VodStorageProvider
+}
+
+// All returns all broadcast VODs in the repository.
+func (r VodRepository) All(ctx context.Context) ([]broadcast.Vod, error) {
+	vods, err := r.StorageProvider.All(ctx)
+	if err != nil {
+		return []broadcast.Vod{}, err
+	}
+
+	return storage.BroadcastVodsToDomain(vods), nil
+}
+
+// List returns a set of broadcast VODs specified by the provided QueryOptions,
+// with the cursors of the pages either side.
+func (r VodRepository) List(
+	ctx context.Context, options paginate.QueryOptions,
+) ([]broadcast.Vod, paginate.Page, error) {
+	vods, page, err := r.StorageProvider.List(ctx, options)
+	if err != nil {
+		return []broadcast.Vod{}, paginate.Page{}, err
+	}
+
+	return storage.BroadcastVodsToDomain(vods), page, nil
+}
+
+// GetByID returns the broadcast VOD with the given ID, or returns an error.
+func (r VodRepository) GetByID(ctx context.Context, id uuid.UUID) (broadcast.Vod, error) {
+	getVod := r.StorageProvider.GetByID(ctx, id)
+	if getVod == nil {
+		return broadcast.Vod{}, ErrVodNotFound
+	}
+
+	return storage.BroadcastVodToDomain(*getVod), nil
+}
+
+// GetByBroadcastID returns the VODs of the broadcast with the given ID, in order of publication.
+func (r VodRepository) GetByBroadcastID(ctx context.Context, broadcastId uuid.UUID) ([]broadcast.Vod, error) {
+	vods, err := r.StorageProvider.GetByStreamID(ctx, broadcastId)
+	if err != nil {
+		return []broadcast.Vod{}, err
+	}
+
+	return storage.BroadcastVodsToDomain(vods), nil
+}
+
+// Delete removes a broadcast VOD with the given ID. Returns an error on failure.
+func (r VodRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.StorageProvider.Delete(ctx, id)
+}
+
+// DeleteByVideoID removes every broadcast VOD of the video with the given ID. Returns an error on failure.
+func (r VodRepository) DeleteByVideoID(ctx context.Context, videoId uuid.UUID) error {
+	return r.StorageProvider.DeleteByVideoID(ctx, videoId)
+}
+
+// Insert takes a domain model and inserts it to the storage provider.
+// After successful insertion the broadcast VOD ID and timestamps are filled.
+func (r VodRepository) Insert(ctx context.Context, newVod *broadcast.Vod) error {
+	storageVod := storage.BroadcastVodToStorage(*newVod)
+
+	err := r.StorageProvider.Insert(ctx, &storageVod)
+	if err != nil {
+		return err
+	}
+
+	newVod.Id = storageVod.Id
+	newVod.CreatedAt = storageVod.CreatedAt
+	newVod.UpdatedAt = storageVod.UpdatedAt
+
+	return nil
+}
+
+// Update takes a broadcast VOD and updates the record within the StorageProvider.
+func (r VodRepository) Update(ctx context.Context, id uuid.UUID, updateVod broadcast.Vod) (broadcast.Vod, error) {
+	storageVod := storage.BroadcastVodToStorage(updateVod)
+
+	err := r.StorageProvider.Update(ctx, id, &storageVod)
+	if err != nil {
+		return broadcast.Vod{}, err
+	}
+
+	return storage.BroadcastVodToDomain(storageVod), nil
+}
+
+func NewVodRepository(s VodStorageProvider) VodRepository {
+	return VodRepository{
+		StorageProvider: s,
+	}
+}
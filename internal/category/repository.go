@@ -0,0 +1,100 @@
+package category
+
+import (
+	"context"
+	"errors"
+	"github.com/M-Ro/go-vodstream/internal/domain/category"
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	"github.com/M-Ro/go-vodstream/storage"
+)
+
+var (
+	ErrCategoryNotFound = errors.New("no category found")
+)
+
+type StorageProvider interface {
+	All(ctx context.Context) ([]storage.Category, error)
+	List(ctx context.Context, options paginate.QueryOptions) ([]storage.Category, paginate.Page, error)
+	GetBySlug(ctx context.Context, slug string) *storage.Category
+	Delete(ctx context.Context, slug string) error
+	Insert(ctx context.Context, category *storage.Category) error
+	Update(ctx context.Context, slug string, category *storage.Category) error
+}
+
+type Repository struct {
+	StorageProvider StorageProvider
+}
+
+// All returns all categories in the repository.
+func (r Repository) All(ctx context.Context) ([]category.Category, error) {
+	categories, err := r.StorageProvider.All(ctx)
+	if err != nil {
+		return []category.Category{}, err
+	}
+
+	return storage.CategoriesToDomain(categories), nil
+}
+
+// List returns a set of categories with the totals of their live broadcasts,
+// specified by the provided QueryOptions, with the cursors of the pages either side.
+func (r Repository) List(
+	ctx context.Context, options paginate.QueryOptions,
+) ([]category.Category, paginate.Page, error) {
+	categories, page, err := r.StorageProvider.List(ctx, options)
+	if err != nil {
+		return []category.Category{}, paginate.Page{}, err
+	}
+
+	return storage.CategoriesToDomain(categories), page, nil
+}
+
+// GetBySlug returns the category with the given slug, or returns an error.
+func (r Repository) GetBySlug(ctx context.Context, slug string) (category.Category, error) {
+	getCategory := r.StorageProvider.GetBySlug(ctx, slug)
+	if getCategory == nil {
+		return category.Category{}, ErrCategoryNotFound
+	}
+
+	return storage.CategoryToDomain(*getCategory), nil
+}
+
+// Delete removes the category with the given slug. Returns an error on failure.
+func (r Repository) Delete(ctx context.Context, slug string) error {
+	return r.StorageProvider.Delete(ctx, slug)
+}
+
+// Insert takes a domain model and inserts it to the storage provider.
+// After successful insertion the category timestamps are filled.
+func (r Repository) Insert(ctx context.Context, newCategory *category.Category) error {
+	storageCategory := storage.CategoryToStorage(*newCategory)
+
+	err := r.StorageProvider.Insert(ctx, &storageCategory)
+	if err != nil {
+		return err
+	}
+
+	newCategory.CreatedAt = storageCategory.CreatedAt
+	newCategory.UpdatedAt = storageCategory.UpdatedAt
+
+	return nil
+}
+
+// Update takes a category and updates the record with the given slug within the StorageProvider.
+func (r Repository) Update(
+	ctx context.Context, slug string, updateCategory category.Category,
+) (category.Category, error) {
+	storageCategory := storage.CategoryToStorage(updateCategory)
+
+	err := r.StorageProvider.Update(ctx, slug, &storageCategory)
+	if err != nil {
+		return category.Category{}, err
+	}
+
+	return storage.CategoryToDomain(storageCategory), nil
+}
+
+func NewRepository(s StorageProvider) Repository {
+	return Repository{
+		StorageProvider: s,
+	}
+}
@@ -0,0 +1,87 @@
+package channel
+
+import (
+	"context"
+	"errors"
+	"github.com/M-Ro/go-vodstream/internal/domain/channel"
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	"github.com/M-Ro/go-vodstream/storage"
+)
+
+var (
+	ErrChannelNotFound = errors.New("no channel found")
+)
+
+type StorageProvider interface {
+	All(ctx context.Context) ([]storage.Channel, error)
+	List(ctx context.Context, options paginate.QueryOptions) ([]storage.Channel, paginate.Page, error)
+	GetByBroadcasterID(ctx context.Context, broadcasterId uint64) *storage.Channel
+	Delete(ctx context.Context, broadcasterId uint64) error
+	Insert(ctx context.Context, channel *storage.Channel) error
+	Update(ctx context.Context, broadcasterId uint64, channel *storage.Channel) error
+}
+
+type Repository struct {
+	StorageProvider StorageProvider
+}
+
+// All returns all channels in the repository.
+func (r Repository) All(ctx context.Context) ([]channel.Channel, error) {
+	channels, err := r.StorageProvider.All(ctx)
+	if err != nil {
+		return []channel.Channel{}, err
+	}
+
+	return storage.ChannelsToDomain(channels), nil
+}
+
+// List returns a set of channels specified by the provided QueryOptions,
+// with the cursors of the pages either side.
+func (r Repository) List(ctx context.Context, options paginate.QueryOptions) ([]channel.Channel, paginate.Page, error) {
+	channels, page, err := r.StorageProvider.List(ctx, options)
+	if err != nil {
+		return []channel.Channel{}, paginate.Page{}, err
+	}
+
+	return storage.ChannelsToDomain(channels), page, nil
+}
+
+// GetByBroadcasterID returns the channel of the given broadcaster, or returns an error
+// if they have not set one up.
+func (r Repository) GetByBroadcasterID(ctx context.Context, broadcasterId uint64) (channel.Channel, error) {
+	getChannel := r.StorageProvider.GetByBroadcasterID(ctx, broadcasterId)
+	if getChannel == nil {
+		return channel.Channel{}, ErrChannelNotFound
+	}
+
+	return storage.ChannelToDomain(*getChannel), nil
+}
+
+// Delete removes the channel of the given broadcaster. Returns an error on failure.
+func (r Repository) Delete(ctx context.Context, broadcasterId uint64) error {
+	return r.StorageProvider.Delete(ctx, broadcasterId)
+}
+
+// Save stores a broadcaster's channel, creating it the first time it is saved.
+func (r Repository) Save(ctx context.Context, saveChannel channel.Channel) (channel.Channel, error) {
+	storageChannel := storage.ChannelToStorage(saveChannel)
+
+	var err error
+	if r.StorageProvider.GetByBroadcasterID(ctx, saveChannel.BroadcasterId) == nil {
+		err = r.StorageProvider.Insert(ctx, &storageChannel)
+	} else {
+		err = r.StorageProvider.Update(ctx, saveChannel.BroadcasterId, &storageChannel)
+	}
+
+	if err != nil {
+		return channel.Channel{}, err
+	}
+
+	return storage.ChannelToDomain(storageChannel), nil
+}
+
+func NewRepository(s StorageProvider) Repository {
+	return Repository{
+		StorageProvider: s,
+	}
+}
@@ -0,0 +1,287 @@
+// Package clip cuts keyframe-aligned ranges from recordings without
+// re-encoding, publishing each as a video of its own.
+package clip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/M-Ro/go-vodstream/internal/blob"
+	"github.com/M-Ro/go-vodstream/internal/domain/broadcast"
+	"github.com/M-Ro/go-vodstream/internal/domain/clip"
+	"github.com/M-Ro/go-vodstream/internal/live"
+	"github.com/M-Ro/go-vodstream/internal/recording"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var (
+	ErrSourceNotFound = errors.New("clip source does not exist")
+	ErrInvalidSource  = errors.New("clip source type is not supported")
+	ErrInvalidRange   = errors.New("clip range is invalid")
+)
+
+// VideoStorage looks up the videos clips are cut from, and persists the videos
+// clips are published as.
+type VideoStorage interface {
+	GetByID(ctx context.Context, id uuid.UUID) *storage.Video
+	Insert(ctx context.Context, video *storage.Video) error
+}
+
+// Transactor runs units of work spanning several storages atomically.
+type Transactor interface {
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// LiveRecordings looks up the recordings of broadcasts still live.
+type LiveRecordings interface {
+	Live(broadcastId uuid.UUID) (*recording.Session, error)
+}
+
+type Config struct {
+	// MaxDuration is the longest range a single clip may cover.
+	MaxDuration time.Duration
+}
+
+// Source is the broadcast or video a clip is cut from.
+type Source struct {
+	Type clip.SourceType
+	Id   uuid.UUID
+
+	// BroadcasterId is the user whose media the clip is cut from.
+	BroadcasterId uint64
+
+	// Title names the source, clips are titled after it unless given a title.
+	Title string
+
+	// IsPublished is whether the source is listed to anyone but its broadcaster,
+	// as are the clips cut from it.
+	IsPublished bool
+
+	// Channel is the live channel of a broadcast source.
+	Channel *live.Channel
+
+	video   *storage.Video
+	session *recording.Session
+}
+
+// Request describes the range of a source to cut, as offsets from its start.
+type Request struct {
+	CreatorId uint64
+	Start     time.Duration
+	End       time.Duration
+	Title     string
+}
+
+// file is a recording opened for reading ranges of.
+type file interface {
+	io.ReaderAt
+	io.Closer
+}
+
+// Clipper cuts clips from recorded videos and the recordings of live broadcasts.
+type Clipper struct {
+	config          Config
+	recordingConfig recording.Config
+	media           blob.Store
+
+	videos       VideoStorage
+	recordings   LiveRecordings
+	clips        Repository
+	transactions Transactor
+}
+
+// Source resolves the broadcast or video with the given id for the creator of a clip.
+// Unpublished videos, and the broadcasts of which recordings are kept unpublished, are
+// hidden from everyone but their broadcaster.
+func (c *Clipper) Source(
+	ctx context.Context, sourceType clip.SourceType, id uuid.UUID, creatorId uint64,
+) (Source, error) {
+	switch sourceType {
+	case clip.SourceVideo:
+		video := c.videos.GetByID(ctx, id)
+		if video == nil || video.FilePath == "" || (!video.IsPublished && video.BroadcasterId != creatorId) {
+			return Source{}, ErrSourceNotFound
+		}
+
+		return Source{
+			Type:          sourceType,
+			Id:            id,
+			BroadcasterId: video.BroadcasterId,
+			Title:         video.Title,
+			IsPublished:   video.IsPublished,
+			video:         video,
+		}, nil
+	case clip.SourceBroadcast:
+		if c.recordings == nil {
+			return Source{}, ErrSourceNotFound
+		}
+
+		session, err := c.recordings.Live(id)
+		if err != nil {
+			return Source{}, ErrSourceNotFound
+		}
+
+		// Only the recordings of public broadcasts are published.
+		published := session.Channel.Visibility == broadcast.VisibilityPublic
+		if !published && session.Channel.BroadcasterId != creatorId {
+			return Source{}, ErrSourceNotFound
+		}
+
+		return Source{
+			Type:          sourceType,
+			Id:            id,
+			BroadcasterId: session.Channel.BroadcasterId,
+			Title:         session.Channel.Name,
+			IsPublished:   published,
+			Channel:       session.Channel,
+			session:       session,
+		}, nil
+	default:
+		return Source{}, ErrInvalidSource
+	}
+}
+
+// open opens the recording of the source and reads its index. The recordings
+// of live broadcasts are flushed first, so everything indexed can be read from
+// the spool.
+func (c *Clipper) open(ctx context.Context, source Source) (file, recording.Index, error) {
+	if source.video != nil {
+		return recording.Open(ctx, c.media, *source.video)
+	}
+
+	name, index, err := source.session.Snapshot()
+	if err != nil {
+		return nil, recording.Index{}, err
+	}
+
+	spooled, err := os.Open(filepath.Join(c.recordingConfig.SpoolDirectory, name))
+	if err != nil {
+		return nil, recording.Index{}, err
+	}
+
+	return spooled, index, nil
+}
+
+// Create cuts the requested range from the source and inserts it as a video, published
+// only if the source is.
+// The range is widened to start on the keyframe at or before its start, and to
+// end on the first keyframe at or after its end.
+func (c *Clipper) Create(ctx context.Context, source Source, request Request) (clip.Clip, error) {
+	if request.Start < 0 || request.End <= request.Start || request.End-request.Start > c.config.MaxDuration {
+		return clip.Clip{}, ErrInvalidRange
+	}
+
+	file, index, err := c.open(ctx, source)
+	if err != nil {
+		return clip.Clip{}, err
+	}
+	defer file.Close()
+
+	if len(index.KeyFrames) == 0 || request.Start >= index.Duration {
+		return clip.Clip{}, ErrInvalidRange
+	}
+
+	// Offsets are relative to the first keyframe, where playback of the source starts.
+	base := index.KeyFrames[0].Time
+
+	from, until, err := index.Range(base+request.Start, base+request.End)
+	if err != nil {
+		return clip.Clip{}, err
+	}
+
+	name := fmt.Sprintf("clip-%s.flv", uuid.New())
+
+	cutIndex, err := c.cut(ctx, file, index, from, until, name)
+	if err != nil {
+		return clip.Clip{}, err
+	}
+
+	title := request.Title
+	if title == "" {
+		title = source.Title
+	}
+
+	video := storage.Video{
+		Title:         title,
+		BroadcasterId: source.BroadcasterId,
+		Length:        uint64(cutIndex.Duration.Milliseconds()),
+		IsPublished:   source.IsPublished,
+		PublishedAt:   time.Now(),
+		FilePath:      name,
+		IndexPath:     recording.IndexFile(name),
+	}
+
+	var newClip clip.Clip
+	err = c.transactions.WithTx(ctx, func(ctx context.Context) error {
+		if err := c.videos.Insert(ctx, &video); err != nil {
+			return err
+		}
+
+		newClip = clip.Clip{
+			VideoId:       video.Id,
+			CreatorId:     request.CreatorId,
+			BroadcasterId: source.BroadcasterId,
+			SourceType:    source.Type,
+			SourceId:      source.Id,
+			Start:         from.Time - base,
+			End:           from.Time - base + cutIndex.Duration,
+		}
+
+		return c.clips.Insert(ctx, &newClip)
+	})
+	if err != nil {
+		recording.Remove(ctx, c.media, name)
+		return clip.Clip{}, err
+	}
+
+	return newClip, nil
+}
+
+// cut remuxes the recording from the keyframe up to the byte offset until into
+// a new recording with its own index, rebasing timestamps to start at zero.
+func (c *Clipper) cut(
+	ctx context.Context, file io.ReaderAt, index recording.Index, from recording.KeyFrame, until int64, name string,
+) (recording.Index, error) {
+	demuxer := recording.Section(file, index, from, until)
+
+	streams, err := demuxer.Streams()
+	if err != nil {
+		return recording.Index{}, err
+	}
+
+	return recording.Create(ctx, c.recordingConfig, c.media, name, streams, func(writer *recording.Writer) error {
+		_, err := recording.Copy(writer, demuxer, -from.Time)
+		return err
+	})
+}
+
+func GetConfig() Config {
+	viper.SetDefault("clip.max_duration", "60s")
+
+	return Config{
+		MaxDuration: viper.GetDuration("clip.max_duration"),
+	}
+}
+
+// NewClipper instantiates a Clipper writing clips alongside recordings in media.
+// Live broadcasts cannot be clipped when recordings is nil.
+func NewClipper(
+	config Config, recordingConfig recording.Config, media blob.Store, videos VideoStorage, recordings LiveRecordings,
+	clips Repository, transactions Transactor,
+) *Clipper {
+	return &Clipper{
+		config:          config,
+		recordingConfig: recordingConfig,
+		media:           media,
+		videos:          videos,
+		recordings:      recordings,
+		clips:           clips,
+		transactions:    transactions,
+	}
+}
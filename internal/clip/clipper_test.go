@@ -0,0 +1,382 @@
+package clip
+
+import (
+	"context"
+	"github.com/M-Ro/go-vodstream/internal/avtest"
+	"github.com/M-Ro/go-vodstream/internal/blob"
+	"github.com/M-Ro/go-vodstream/internal/domain/broadcast"
+	"github.com/M-Ro/go-vodstream/internal/domain/clip"
+	"github.com/M-Ro/go-vodstream/internal/live"
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	"github.com/M-Ro/go-vodstream/internal/recording"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/google/uuid"
+	"github.com/spf13/afero"
+	"testing"
+	"time"
+)
+
+type fakeVideos struct {
+	videos map[uuid.UUID]storage.Video
+}
+
+func (f *fakeVideos) GetByID(_ context.Context, id uuid.UUID) *storage.Video {
+	video, ok := f.videos[id]
+	if !ok {
+		return nil
+	}
+
+	return &video
+}
+
+func (f *fakeVideos) Insert(_ context.Context, video *storage.Video) error {
+	video.Id = uuid.New()
+	f.videos[video.Id] = *video
+	return nil
+}
+
+type fakeClips struct {
+	clips []storage.Clip
+}
+
+func (f *fakeClips) ListByBroadcaster(
+	_ context.Context, broadcasterId uint64, _ paginate.QueryOptions,
+) ([]storage.Clip, paginate.Page, error) {
+	clips := make([]storage.Clip, 0)
+	for _, c := range f.clips {
+		if c.BroadcasterId == broadcasterId {
+			clips = append(clips, c)
+		}
+	}
+
+	return clips, paginate.Page{}, nil
+}
+
+func (f *fakeClips) GetByID(_ context.Context, id uuid.UUID) *storage.Clip {
+	for _, c := range f.clips {
+		if c.Id == id {
+			return &c
+		}
+	}
+
+	return nil
+}
+
+func (f *fakeClips) Delete(_ context.Context, _ uuid.UUID) error {
+	return nil
+}
+
+func (f *fakeClips) Insert(_ context.Context, c *storage.Clip) error {
+	c.Id = uuid.New()
+	c.CreatedAt = time.Now()
+	f.clips = append(f.clips, *c)
+	return nil
+}
+
+// writeVideo records count frames, with a keyframe every second, as a video.
+func writeVideo(t *testing.T, clipper *Clipper, videos *fakeVideos, count int) storage.Video {
+	_, err := recording.Create(context.Background(), clipper.recordingConfig, clipper.media, "source.flv",
+		avtest.Streams(), func(writer *recording.Writer) error {
+			for _, packet := range avtest.Packets(count, 25) {
+				if err := writer.WritePacket(packet); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	video := storage.Video{
+		Title:         "source",
+		BroadcasterId: 2,
+		IsPublished:   true,
+		FilePath:      "source.flv",
+		IndexPath:     recording.IndexFile("source.flv"),
+	}
+	if err := videos.Insert(context.Background(), &video); err != nil {
+		t.Fatal(err)
+	}
+
+	return video
+}
+
+// newClipper instantiates a Clipper over fake storages, spooling to a temporary
+// directory and storing media in memory.
+func newClipper(t *testing.T, recordings LiveRecordings) (*Clipper, *fakeVideos, *fakeClips) {
+	videos := &fakeVideos{videos: make(map[uuid.UUID]storage.Video)}
+	clips := &fakeClips{}
+
+	clipper := NewClipper(
+		Config{MaxDuration: 10 * time.Second},
+		recording.Config{SpoolDirectory: t.TempDir()},
+		blob.NewLocalStore(afero.NewMemMapFs()),
+		videos,
+		recordings,
+		NewRepository(clips),
+		storage.Immediate{},
+	)
+
+	return clipper, videos, clips
+}
+
+func TestClipper_Create(t *testing.T) {
+	clipper, videos, clips := newClipper(t, nil)
+	source := writeVideo(t, clipper, videos, 200)
+
+	ctx := context.Background()
+
+	clipSource, err := clipper.Source(ctx, clip.SourceVideo, source.Id, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created, err := clipper.Create(ctx, clipSource, Request{
+		CreatorId: 3,
+		Start:     1500 * time.Millisecond,
+		End:       3200 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The clip starts on the keyframe at 1s, and runs up to the keyframe at 4s.
+	duration := 74 * avtest.FrameDuration
+	expected := clip.Clip{
+		Id:            created.Id,
+		VideoId:       created.VideoId,
+		CreatorId:     3,
+		BroadcasterId: 2,
+		SourceType:    clip.SourceVideo,
+		SourceId:      source.Id,
+		Start:         time.Second,
+		End:           time.Second + duration,
+		CreatedAt:     created.CreatedAt,
+	}
+
+	if diff := cmp.Diff(created, expected); diff != "" {
+		t.Fatal(diff)
+	}
+
+	if len(clips.clips) != 1 {
+		t.Fatal(cmp.Diff(len(clips.clips), 1))
+	}
+
+	video := videos.GetByID(ctx, created.VideoId)
+	if video == nil {
+		t.Fatal("expected the clip to be published as a video")
+	}
+
+	if video.Title != "source" || video.BroadcasterId != 2 || !video.IsPublished ||
+		video.Length != uint64(duration.Milliseconds()) {
+		t.Fatalf("unexpected clip video: %+v", video)
+	}
+
+	file, index, err := recording.Open(ctx, clipper.media, *video)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	keyFrames := make([]time.Duration, 0, len(index.KeyFrames))
+	for _, keyFrame := range index.KeyFrames {
+		keyFrames = append(keyFrames, keyFrame.Time)
+	}
+
+	if diff := cmp.Diff(keyFrames, []time.Duration{0, time.Second, 2 * time.Second}); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestClipper_Create_InvalidRange(t *testing.T) {
+	clipper, videos, _ := newClipper(t, nil)
+	source := writeVideo(t, clipper, videos, 100)
+
+	clipSource, err := clipper.Source(context.Background(), clip.SourceVideo, source.Id, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		testName string
+		start    time.Duration
+		end      time.Duration
+	}{
+		{testName: "negative start", start: -time.Second, end: time.Second},
+		{testName: "end before start", start: 2 * time.Second, end: time.Second},
+		{testName: "empty range", start: time.Second, end: time.Second},
+		{testName: "longer than max duration", start: 0, end: 11 * time.Second},
+		{testName: "start past the end", start: 5 * time.Second, end: 6 * time.Second},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			_, err := clipper.Create(context.Background(), clipSource, Request{Start: test.start, End: test.end})
+			if !cmp.Equal(err, ErrInvalidRange, cmpopts.EquateErrors()) {
+				t.Fatal(cmp.Diff(err, ErrInvalidRange, cmpopts.EquateErrors()))
+			}
+		})
+	}
+}
+
+func TestClipper_Source(t *testing.T) {
+	clipper, _, _ := newClipper(t, nil)
+
+	tests := []struct {
+		testName   string
+		sourceType clip.SourceType
+		err        error
+	}{
+		{testName: "missing video", sourceType: clip.SourceVideo, err: ErrSourceNotFound},
+		{testName: "broadcast without recorder", sourceType: clip.SourceBroadcast, err: ErrSourceNotFound},
+		{testName: "unknown source type", sourceType: "stream", err: ErrInvalidSource},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			_, err := clipper.Source(context.Background(), test.sourceType, uuid.New(), 3)
+			if !cmp.Equal(err, test.err, cmpopts.EquateErrors()) {
+				t.Fatal(cmp.Diff(err, test.err, cmpopts.EquateErrors()))
+			}
+		})
+	}
+}
+
+func TestClipper_Source_Unpublished(t *testing.T) {
+	clipper, videos, _ := newClipper(t, nil)
+	source := writeVideo(t, clipper, videos, 100)
+
+	source.IsPublished = false
+	videos.videos[source.Id] = source
+
+	ctx := context.Background()
+
+	_, err := clipper.Source(ctx, clip.SourceVideo, source.Id, 3)
+	if !cmp.Equal(err, ErrSourceNotFound, cmpopts.EquateErrors()) {
+		t.Fatal(cmp.Diff(err, ErrSourceNotFound, cmpopts.EquateErrors()))
+	}
+
+	clipSource, err := clipper.Source(ctx, clip.SourceVideo, source.Id, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created, err := clipper.Create(ctx, clipSource, Request{CreatorId: 2, Start: 0, End: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if video := videos.GetByID(ctx, created.VideoId); video == nil || video.IsPublished {
+		t.Fatalf("expected the clip of an unpublished video to be unpublished: %+v", video)
+	}
+}
+
+type discardVideos struct{}
+
+func (discardVideos) Insert(_ context.Context, video *storage.Video) error {
+	video.Id = uuid.New()
+	return nil
+}
+
+type discardVods struct{}
+
+func (discardVods) Insert(_ context.Context, vod *storage.BroadcastVod) error {
+	vod.Id = uuid.New()
+	return nil
+}
+
+// recordLive records 100 frames of a live broadcast by broadcaster 2 with the given
+// visibility, returning the channel and a func ending the broadcast.
+func recordLive(t *testing.T, clipper *Clipper, visibility broadcast.Visibility) (*live.Channel, func()) {
+	recorder := recording.NewRecorder(
+		clipper.recordingConfig, clipper.media, discardVideos{}, discardVods{}, storage.Immediate{},
+	)
+	clipper.recordings = recorder
+
+	channels := live.NewRegistry()
+
+	channel, err := channels.Open("testUser1", 2, visibility)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := channel.Queue.WriteHeader(avtest.Streams()); err != nil {
+		t.Fatal(err)
+	}
+
+	session := recorder.Start(channel)
+
+	for _, packet := range avtest.Packets(100, 25) {
+		channel.Queue.WritePacket(packet)
+		time.Sleep(100 * time.Microsecond)
+	}
+
+	// Wait for the recorder to catch up with the queue.
+	time.Sleep(50 * time.Millisecond)
+
+	return channel, func() {
+		channels.Close(channel)
+		session.Wait()
+	}
+}
+
+func TestClipper_Create_Live(t *testing.T) {
+	clipper, videos, _ := newClipper(t, nil)
+	channel, end := recordLive(t, clipper, broadcast.VisibilityPublic)
+	defer end()
+
+	ctx := context.Background()
+
+	source, err := clipper.Source(ctx, clip.SourceBroadcast, channel.BroadcastId, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if source.Channel != channel || source.BroadcasterId != 2 {
+		t.Fatalf("unexpected source: %+v", source)
+	}
+
+	created, err := clipper.Create(ctx, source, Request{CreatorId: 3, Start: 0, End: 2 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if created.Start != 0 || created.End != 49*avtest.FrameDuration {
+		t.Fatalf("unexpected clip range: %s-%s", created.Start, created.End)
+	}
+
+	if video := videos.GetByID(ctx, created.VideoId); video == nil || !video.IsPublished {
+		t.Fatalf("expected the clip of a public broadcast to be published: %+v", video)
+	}
+}
+
+func TestClipper_Create_LivePrivate(t *testing.T) {
+	clipper, videos, _ := newClipper(t, nil)
+	channel, end := recordLive(t, clipper, broadcast.VisibilityPrivate)
+	defer end()
+
+	ctx := context.Background()
+
+	_, err := clipper.Source(ctx, clip.SourceBroadcast, channel.BroadcastId, 3)
+	if !cmp.Equal(err, ErrSourceNotFound, cmpopts.EquateErrors()) {
+		t.Fatal(cmp.Diff(err, ErrSourceNotFound, cmpopts.EquateErrors()))
+	}
+
+	source, err := clipper.Source(ctx, clip.SourceBroadcast, channel.BroadcastId, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created, err := clipper.Create(ctx, source, Request{CreatorId: 2, Start: 0, End: 2 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if video := videos.GetByID(ctx, created.VideoId); video == nil || video.IsPublished {
+		t.Fatalf("expected the clip of a private broadcast to be unpublished: %+v", video)
+	}
+}
@@ -0,0 +1,77 @@
+package clip
+
+import (
+	"context"
+	"errors"
+	"github.com/M-Ro/go-vodstream/internal/domain/clip"
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrClipNotFound = errors.New("no clip found")
+)
+
+type StorageProvider interface {
+	ListByBroadcaster(
+		ctx context.Context, broadcasterId uint64, options paginate.QueryOptions,
+	) ([]storage.Clip, paginate.Page, error)
+	GetByID(ctx context.Context, id uuid.UUID) *storage.Clip
+	Delete(ctx context.Context, id uuid.UUID) error
+	Insert(ctx context.Context, clip *storage.Clip) error
+}
+
+type Repository struct {
+	StorageProvider StorageProvider
+}
+
+// ListByBroadcaster returns a set of clips cut from the broadcaster's media,
+// specified by the provided QueryOptions, with the cursors of the pages either side.
+func (r Repository) ListByBroadcaster(
+	ctx context.Context, broadcasterId uint64, options paginate.QueryOptions,
+) ([]clip.Clip, paginate.Page, error) {
+	clips, page, err := r.StorageProvider.ListByBroadcaster(ctx, broadcasterId, options)
+	if err != nil {
+		return []clip.Clip{}, paginate.Page{}, err
+	}
+
+	return storage.ClipsToDomain(clips), page, nil
+}
+
+// GetByID returns the clip with the given ID, or returns an error.
+func (r Repository) GetByID(ctx context.Context, id uuid.UUID) (clip.Clip, error) {
+	getClip := r.StorageProvider.GetByID(ctx, id)
+	if getClip == nil {
+		return clip.Clip{}, ErrClipNotFound
+	}
+
+	return storage.ClipToDomain(*getClip), nil
+}
+
+// Delete removes a clip with the given ID. Returns an error on failure.
+func (r Repository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.StorageProvider.Delete(ctx, id)
+}
+
+// Insert takes a domain model and inserts it to the storage provider.
+// After successful insertion the clip ID and creation time are filled.
+func (r Repository) Insert(ctx context.Context, newClip *clip.Clip) error {
+	storageClip := storage.ClipToStorage(*newClip)
+
+	err := r.StorageProvider.Insert(ctx, &storageClip)
+	if err != nil {
+		return err
+	}
+
+	newClip.Id = storageClip.Id
+	newClip.CreatedAt = storageClip.CreatedAt
+
+	return nil
+}
+
+func NewRepository(s StorageProvider) Repository {
+	return Repository{
+		StorageProvider: s,
+	}
+}
@@ -0,0 +1,90 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"github.com/M-Ro/go-vodstream/internal/domain/cluster"
+	"github.com/M-Ro/go-vodstream/internal/live"
+	"github.com/M-Ro/go-vodstream/storage"
+	"time"
+)
+
+var (
+	ErrHostedElsewhere = errors.New("channel is live on another node")
+	ErrOriginNotFound  = errors.New("no origin node found for channel")
+)
+
+type StorageProvider interface {
+	GetByName(ctx context.Context, name string, staleBefore time.Time) *storage.LiveChannel
+	Claim(ctx context.Context, liveChannel *storage.LiveChannel, staleBefore time.Time) error
+	Release(ctx context.Context, name string, nodeAddress string) error
+	ReleaseNode(ctx context.Context, nodeAddress string) error
+	Heartbeat(ctx context.Context, nodeAddress string) error
+}
+
+// Directory records which node each live channel is published to. Entries
+// whose heartbeat is older than the TTL are treated as abandoned.
+type Directory struct {
+	StorageProvider StorageProvider
+	TTL             time.Duration
+}
+
+// staleBefore returns the heartbeat time below which entries are abandoned.
+func (d Directory) staleBefore() time.Time {
+	return time.Now().Add(-d.TTL)
+}
+
+// Register records the channel as published to the given node. Returns
+// ErrHostedElsewhere if another live node already holds the channel.
+func (d Directory) Register(ctx context.Context, channel *live.Channel, nodeAddress string) error {
+	liveChannel := storage.LiveChannelToStorage(cluster.Origin{
+		Name:          channel.Name,
+		NodeAddress:   nodeAddress,
+		BroadcasterId: channel.BroadcasterId,
+		Visibility:    channel.Visibility,
+		LowLatency:    channel.LowLatency,
+		StartedAt:     channel.StartedAt,
+	})
+
+	if err := d.StorageProvider.Claim(ctx, &liveChannel, d.staleBefore()); err != nil {
+		if d.StorageProvider.GetByName(ctx, channel.Name, d.staleBefore()) != nil {
+			return ErrHostedElsewhere
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Unregister removes the channel if it is held by the given node.
+func (d Directory) Unregister(ctx context.Context, name string, nodeAddress string) error {
+	return d.StorageProvider.Release(ctx, name, nodeAddress)
+}
+
+// Lookup returns the origin of the named channel, or ErrOriginNotFound.
+func (d Directory) Lookup(ctx context.Context, name string) (cluster.Origin, error) {
+	liveChannel := d.StorageProvider.GetByName(ctx, name, d.staleBefore())
+	if liveChannel == nil {
+		return cluster.Origin{}, ErrOriginNotFound
+	}
+
+	return storage.LiveChannelToDomain(*liveChannel), nil
+}
+
+// Heartbeat keeps every channel held by the given node alive.
+func (d Directory) Heartbeat(ctx context.Context, nodeAddress string) error {
+	return d.StorageProvider.Heartbeat(ctx, nodeAddress)
+}
+
+// Reset removes every channel held by the given node, left behind if it exited uncleanly.
+func (d Directory) Reset(ctx context.Context, nodeAddress string) error {
+	return d.StorageProvider.ReleaseNode(ctx, nodeAddress)
+}
+
+// NewDirectory instantiates a new Directory.
+func NewDirectory(s StorageProvider, ttl time.Duration) Directory {
+	return Directory{
+		StorageProvider: s,
+		TTL:             ttl,
+	}
+}
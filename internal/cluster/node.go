@@ -0,0 +1,262 @@
+package cluster
+
+import (
+	"context"
+	"crypto/subtle"
+	"github.com/M-Ro/go-vodstream/internal/live"
+	"github.com/nareix/joy4/av"
+	"github.com/nareix/joy4/av/avutil"
+	"github.com/nareix/joy4/format/rtmp"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dialTimeout bounds how long connecting to an origin may take.
+const dialTimeout = 10 * time.Second
+
+// KeyQueryParam is the URL query parameter nodes present the cluster key in
+// when pulling a channel from its origin.
+const KeyQueryParam = "cluster_key"
+
+// Dialer opens a connection to play from the given URL.
+type Dialer func(url string) (av.DemuxCloser, error)
+
+type Config struct {
+	// NodeAddress is the RTMP host:port other nodes reach this node on.
+	NodeAddress string
+
+	// Key authenticates nodes pulling channels from each other.
+	Key string
+
+	HeartbeatInterval time.Duration
+}
+
+// Node is an ingester participating in a cluster. Channels published to it are
+// registered in the directory as originating here, and play requests for
+// channels hosted elsewhere are served by pulling from the origin. A pulled
+// channel is shared between all local viewers and torn down when the last leaves.
+type Node struct {
+	config    Config
+	channels  *live.Registry
+	directory Directory
+	dial      Dialer
+
+	lock  sync.Mutex
+	pulls map[string]*edgePull
+}
+
+// edgePull is a channel pulled from its origin for local viewers.
+type edgePull struct {
+	channel *live.Channel
+	conn    av.DemuxCloser
+	viewers int
+
+	// ready is closed once the pull has connected, or failed with err.
+	ready chan struct{}
+	err   error
+}
+
+// DialRTMP connects to an RTMP server for playing.
+func DialRTMP(url string) (av.DemuxCloser, error) {
+	return rtmp.DialTimeout(url, dialTimeout)
+}
+
+// IsPeer returns true if key matches the cluster key.
+func (n *Node) IsPeer(key string) bool {
+	return n.config.Key != "" && subtle.ConstantTimeCompare([]byte(n.config.Key), []byte(key)) == 1
+}
+
+// Register records a channel published to this node in the directory.
+func (n *Node) Register(ctx context.Context, channel *live.Channel) error {
+	return n.directory.Register(ctx, channel, n.config.NodeAddress)
+}
+
+// Unregister removes a channel published to this node from the directory.
+func (n *Node) Unregister(channel *live.Channel) {
+	if err := n.directory.Unregister(context.Background(), channel.Name, n.config.NodeAddress); err != nil {
+		log.Errorf("Couldn't unregister channel %s: %v", channel.Name, err)
+	}
+}
+
+// Acquire returns the named channel for a viewer, pulling it from its origin
+// if it is not published locally. The returned release function must be
+// called once the viewer leaves.
+func (n *Node) Acquire(ctx context.Context, name string) (*live.Channel, func(), error) {
+	name = strings.ToLower(name)
+
+	n.lock.Lock()
+
+	if pull, ok := n.pulls[name]; ok {
+		pull.viewers++
+		n.lock.Unlock()
+
+		<-pull.ready
+		if pull.err != nil {
+			n.release(name, pull)
+			return nil, func() {}, pull.err
+		}
+
+		return pull.channel, func() { n.release(name, pull) }, nil
+	}
+
+	if channel, err := n.channels.Get(name); err == nil {
+		n.lock.Unlock()
+		return channel, func() {}, nil
+	}
+
+	pull := &edgePull{viewers: 1, ready: make(chan struct{})}
+	n.pulls[name] = pull
+	n.lock.Unlock()
+
+	pull.err = n.connect(ctx, name, pull)
+	close(pull.ready)
+
+	if pull.err != nil {
+		n.release(name, pull)
+		return nil, func() {}, pull.err
+	}
+
+	return pull.channel, func() { n.release(name, pull) }, nil
+}
+
+// connect pulls the named channel from its origin into a local channel.
+func (n *Node) connect(ctx context.Context, name string, pull *edgePull) error {
+	origin, err := n.directory.Lookup(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if origin.NodeAddress == n.config.NodeAddress {
+		return live.ErrChannelNotFound
+	}
+
+	query := url.Values{}
+	query.Set(KeyQueryParam, n.config.Key)
+
+	originURL := url.URL{Scheme: "rtmp", Host: origin.NodeAddress, Path: "/live/" + origin.Name, RawQuery: query.Encode()}
+
+	conn, err := n.dial(originURL.String())
+	if err != nil {
+		return err
+	}
+
+	streams, err := conn.Streams()
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	channel, err := n.channels.Open(
+		origin.Name, origin.BroadcasterId, origin.Visibility, live.WithLowLatency(origin.LowLatency),
+	)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	if err := channel.Queue.WriteHeader(streams); err != nil {
+		n.channels.Close(channel)
+		conn.Close()
+		return err
+	}
+
+	pull.channel = channel
+	pull.conn = conn
+
+	log.Infof("Pulling channel %s from origin %s", channel.Name, origin.NodeAddress)
+
+	go n.copy(name, pull)
+
+	return nil
+}
+
+// copy relays packets from the origin until it ends or the pull is torn down.
+func (n *Node) copy(name string, pull *edgePull) {
+	if err := avutil.CopyPackets(pull.channel.Queue, pull.conn); err != nil && err != io.EOF {
+		log.Debugf("Stopped pulling channel %s: %v", name, err)
+	}
+
+	n.lock.Lock()
+	if n.pulls[name] == pull {
+		delete(n.pulls, name)
+	}
+	n.lock.Unlock()
+
+	pull.conn.Close()
+	n.channels.Close(pull.channel)
+}
+
+// release removes a viewer from the pull, disconnecting from the origin after the last.
+func (n *Node) release(name string, pull *edgePull) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	pull.viewers--
+	if pull.viewers > 0 {
+		return
+	}
+
+	if n.pulls[name] == pull {
+		delete(n.pulls, name)
+	}
+
+	// Closing the origin connection ends the copy, which closes the local channel.
+	if pull.conn != nil {
+		pull.conn.Close()
+	}
+}
+
+// Run keeps the channels published to this node alive in the directory until
+// ctx is cancelled. Entries left by a previous run of this node are removed first.
+func (n *Node) Run(ctx context.Context) {
+	if err := n.directory.Reset(ctx, n.config.NodeAddress); err != nil {
+		log.Errorf("Couldn't reset cluster directory for %s: %v", n.config.NodeAddress, err)
+	}
+
+	ticker := time.NewTicker(n.config.HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := n.directory.Heartbeat(ctx, n.config.NodeAddress); err != nil {
+				log.Errorf("Couldn't send cluster heartbeat: %v", err)
+			}
+		}
+	}
+}
+
+func GetConfig() Config {
+	viper.SetDefault("cluster.heartbeat_interval", "10s")
+
+	return Config{
+		NodeAddress:       viper.GetString("cluster.node_address"),
+		Key:               viper.GetString("cluster.key"),
+		HeartbeatInterval: viper.GetDuration("cluster.heartbeat_interval"),
+	}
+}
+
+// GetTTL returns how long a channel stays in the directory without a heartbeat.
+func GetTTL() time.Duration {
+	viper.SetDefault("cluster.heartbeat_ttl", "30s")
+
+	return viper.GetDuration("cluster.heartbeat_ttl")
+}
+
+// NewNode instantiates a new Node.
+func NewNode(config Config, channels *live.Registry, directory Directory, dial Dialer) *Node {
+	return &Node{
+		config:    config,
+		channels:  channels,
+		directory: directory,
+		dial:      dial,
+		pulls:     make(map[string]*edgePull),
+	}
+}
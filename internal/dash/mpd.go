@@ -0,0 +1,224 @@
+// Package dash renders MPEG-DASH manifests over CMAF tracks.
+package dash
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/M-Ro/go-vodstream/internal/fmp4"
+	"github.com/nareix/joy4/codec/aacparser"
+	"github.com/nareix/joy4/codec/h264parser"
+	"time"
+)
+
+// timescale is the number of ticks per second of segment timelines.
+const timescale = 1000
+
+// Representation is a CMAF track listed in the manifest.
+type Representation struct {
+	// Index numbers the track in its init segment and fragment URIs.
+	Index int
+	Track fmp4.Track
+
+	// Bandwidth is the peak bitrate of the track, in bits per second.
+	Bandwidth int
+}
+
+// Segment is a run of media shared by every track, starting at a keyframe.
+type Segment struct {
+	Sequence int
+	Start    time.Duration
+	Duration time.Duration
+}
+
+// Manifest describes a presentation. Live presentations are dynamic, and are
+// reloaded by viewers every MinimumUpdatePeriod. VOD presentations are static.
+type Manifest struct {
+	Dynamic bool
+
+	// AvailabilityStartTime is the wall clock time of media time zero, for
+	// dynamic manifests.
+	AvailabilityStartTime time.Time
+	PublishTime           time.Time
+	MinimumUpdatePeriod   time.Duration
+	TimeShiftBufferDepth  time.Duration
+
+	// MinBufferTime is how much media viewers should buffer before playing.
+	MinBufferTime time.Duration
+
+	Representations []Representation
+	Segments        []Segment
+
+	// Query is appended to every URI in the manifest.
+	Query string
+}
+
+type mpd struct {
+	XMLName                   xml.Name `xml:"urn:mpeg:dash:schema:mpd:2011 MPD"`
+	Profiles                  string   `xml:"profiles,attr"`
+	Type                      string   `xml:"type,attr"`
+	AvailabilityStartTime     string   `xml:"availabilityStartTime,attr,omitempty"`
+	PublishTime               string   `xml:"publishTime,attr,omitempty"`
+	MinimumUpdatePeriod       string   `xml:"minimumUpdatePeriod,attr,omitempty"`
+	TimeShiftBufferDepth      string   `xml:"timeShiftBufferDepth,attr,omitempty"`
+	MediaPresentationDuration string   `xml:"mediaPresentationDuration,attr,omitempty"`
+	MaxSegmentDuration        string   `xml:"maxSegmentDuration,attr"`
+	MinBufferTime             string   `xml:"minBufferTime,attr"`
+	Period                    period   `xml:"Period"`
+}
+
+type period struct {
+	Id             string          `xml:"id,attr"`
+	Start          string          `xml:"start,attr"`
+	AdaptationSets []adaptationSet `xml:"AdaptationSet"`
+}
+
+type adaptationSet struct {
+	ContentType      string          `xml:"contentType,attr"`
+	MimeType         string          `xml:"mimeType,attr"`
+	SegmentAlignment bool            `xml:"segmentAlignment,attr"`
+	StartWithSAP     int             `xml:"startWithSAP,attr"`
+	SegmentTemplate  segmentTemplate `xml:"SegmentTemplate"`
+	Representation   representation  `xml:"Representation"`
+}
+
+type segmentTemplate struct {
+	Timescale       int             `xml:"timescale,attr"`
+	Initialization  string          `xml:"initialization,attr"`
+	Media           string          `xml:"media,attr"`
+	StartNumber     int             `xml:"startNumber,attr"`
+	SegmentTimeline segmentTimeline `xml:"SegmentTimeline"`
+}
+
+type segmentTimeline struct {
+	S []timelineEntry `xml:"S"`
+}
+
+type timelineEntry struct {
+	T int64 `xml:"t,attr"`
+	D int64 `xml:"d,attr"`
+}
+
+type representation struct {
+	Id                        string                     `xml:"id,attr"`
+	Codecs                    string                     `xml:"codecs,attr"`
+	Bandwidth                 int                        `xml:"bandwidth,attr"`
+	Width                     int                        `xml:"width,attr,omitempty"`
+	Height                    int                        `xml:"height,attr,omitempty"`
+	AudioSamplingRate         int                        `xml:"audioSamplingRate,attr,omitempty"`
+	AudioChannelConfiguration *audioChannelConfiguration `xml:"AudioChannelConfiguration,omitempty"`
+}
+
+type audioChannelConfiguration struct {
+	SchemeIdUri string `xml:"schemeIdUri,attr"`
+	Value       int    `xml:"value,attr"`
+}
+
+// duration formats d as an xs:duration.
+func duration(d time.Duration) string {
+	return fmt.Sprintf("PT%.3fS", d.Seconds())
+}
+
+// dateTime formats t as an xs:dateTime.
+func dateTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+// withQuery appends the query to a URI.
+func withQuery(uri string, query string) string {
+	if query == "" {
+		return uri
+	}
+
+	return uri + "?" + query
+}
+
+// adaptationSet returns the adaptation set holding a single representation,
+// as CMAF tracks are not multiplexed.
+func (m Manifest) adaptationSet(r Representation, timeline segmentTimeline, startNumber int) adaptationSet {
+	set := adaptationSet{
+		SegmentAlignment: true,
+		StartWithSAP:     1,
+		SegmentTemplate: segmentTemplate{
+			Timescale:       timescale,
+			Initialization:  withQuery(fmp4.InitURI(r.Index), m.Query),
+			Media:           withQuery(fmp4.SegmentTemplate(r.Index), m.Query),
+			StartNumber:     startNumber,
+			SegmentTimeline: timeline,
+		},
+		Representation: representation{
+			Id:        fmt.Sprint(r.Index),
+			Codecs:    r.Track.Codecs(),
+			Bandwidth: r.Bandwidth,
+		},
+	}
+
+	switch codec := r.Track.Codec.(type) {
+	case h264parser.CodecData:
+		set.ContentType, set.MimeType = "video", "video/mp4"
+		set.Representation.Width = codec.Width()
+		set.Representation.Height = codec.Height()
+	case aacparser.CodecData:
+		set.ContentType, set.MimeType = "audio", "audio/mp4"
+		set.Representation.AudioSamplingRate = codec.SampleRate()
+		set.Representation.AudioChannelConfiguration = &audioChannelConfiguration{
+			SchemeIdUri: "urn:mpeg:dash:23003:3:audio_channel_configuration:2011",
+			Value:       codec.ChannelLayout().Count(),
+		}
+	}
+
+	return set
+}
+
+// Marshal renders the manifest as an MPD document.
+func (m Manifest) Marshal() ([]byte, error) {
+	doc := mpd{
+		Profiles:      "urn:mpeg:dash:profile:isoff-live:2011",
+		Type:          "static",
+		MinBufferTime: duration(m.MinBufferTime),
+		Period:        period{Id: "0", Start: duration(0)},
+	}
+
+	timeline := segmentTimeline{}
+	total, longest := time.Duration(0), time.Duration(0)
+
+	for _, segment := range m.Segments {
+		// Durations are taken between rounded times so the timeline has no gaps.
+		start := segment.Start.Milliseconds()
+		timeline.S = append(timeline.S, timelineEntry{
+			T: start,
+			D: (segment.Start + segment.Duration).Milliseconds() - start,
+		})
+
+		total += segment.Duration
+		if segment.Duration > longest {
+			longest = segment.Duration
+		}
+	}
+	doc.MaxSegmentDuration = duration(longest)
+
+	startNumber := 0
+	if len(m.Segments) > 0 {
+		startNumber = m.Segments[0].Sequence
+	}
+
+	if m.Dynamic {
+		doc.Type = "dynamic"
+		doc.AvailabilityStartTime = dateTime(m.AvailabilityStartTime)
+		doc.PublishTime = dateTime(m.PublishTime)
+		doc.MinimumUpdatePeriod = duration(m.MinimumUpdatePeriod)
+		doc.TimeShiftBufferDepth = duration(m.TimeShiftBufferDepth)
+	} else {
+		doc.MediaPresentationDuration = duration(total)
+	}
+
+	for _, r := range m.Representations {
+		doc.Period.AdaptationSets = append(doc.Period.AdaptationSets, m.adaptationSet(r, timeline, startNumber))
+	}
+
+	body, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), append(body, '\n')...), nil
+}
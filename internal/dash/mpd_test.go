@@ -0,0 +1,78 @@
+package dash
+
+import (
+	"github.com/M-Ro/go-vodstream/internal/avtest"
+	"github.com/M-Ro/go-vodstream/internal/fmp4"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestManifest_Marshal(t *testing.T) {
+	var representations []Representation
+	for idx, stream := range avtest.Streams() {
+		track, err := fmp4.NewTrack(stream)
+		if err != nil {
+			t.Fatal(err)
+		}
+		representations = append(representations, Representation{Index: idx, Track: track, Bandwidth: 1000})
+	}
+
+	segments := []Segment{
+		{Sequence: 4, Start: 8000 * time.Millisecond, Duration: 2040 * time.Millisecond},
+		{Sequence: 5, Start: 10040 * time.Millisecond, Duration: 1960 * time.Millisecond},
+	}
+
+	epoch := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		testName string
+		manifest Manifest
+		want     []string
+	}{
+		{
+			testName: "static",
+			manifest: Manifest{Representations: representations, Segments: segments, MinBufferTime: 2 * time.Second},
+			want: []string{
+				`type="static"`, `mediaPresentationDuration="PT4.000S"`, `maxSegmentDuration="PT2.040S"`,
+				`minBufferTime="PT2.000S"`, `startNumber="4"`, `<S t="8000" d="2040"></S>`,
+				`<S t="10040" d="1960"></S>`, `initialization="init1.mp4"`, `media="seg$Number$.0.m4s"`,
+				`contentType="video"`, `contentType="audio"`, `codecs="mp4a.40.2"`, `audioSamplingRate="44100"`,
+			},
+		},
+		{
+			testName: "dynamic",
+			manifest: Manifest{
+				Dynamic:               true,
+				AvailabilityStartTime: epoch,
+				PublishTime:           epoch.Add(time.Minute),
+				MinimumUpdatePeriod:   2 * time.Second,
+				TimeShiftBufferDepth:  4 * time.Second,
+				Representations:       representations,
+				Segments:              segments,
+				Query:                 "token=abc",
+			},
+			want: []string{
+				`type="dynamic"`, `availabilityStartTime="2026-10-18T12:00:00.000Z"`,
+				`publishTime="2026-10-18T12:01:00.000Z"`, `minimumUpdatePeriod="PT2.000S"`,
+				`timeShiftBufferDepth="PT4.000S"`, `initialization="init0.mp4?token=abc"`,
+				`media="seg$Number$.1.m4s?token=abc"`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			body, err := test.manifest.Marshal()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, want := range test.want {
+				if !strings.Contains(string(body), want) {
+					t.Fatalf("expected %s in manifest:\n%s", want, body)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package broadcast
+
+import (
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	"github.com/google/uuid"
+	"time"
+)
+
+// Broadcast is a single live stream of a broadcaster, from publish to unpublish.
+type Broadcast struct {
+	Id uuid.UUID
+
+	// The user responsible for this broadcast
+	BroadcasterId uint64
+
+	Title string
+
+	// Metadata inherited from the broadcaster's channel as the broadcast started
+	Category string
+	Tags     []string
+	Language string
+	Mature   bool
+
+	// IsActive broadcasts are live. Only published broadcasts are listed.
+	IsActive    bool
+	IsPublished bool
+
+	// ViewerCount is how many are watching while the broadcast is live, as last
+	// reported by the ingester.
+	ViewerCount int64
+
+	PublishedAt time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Publish lists the broadcast, stamping when it was published unless it already was.
+func (b *Broadcast) Publish(now time.Time) {
+	if !b.IsPublished {
+		b.IsPublished = true
+		b.PublishedAt = now
+	}
+}
+
+// Unpublish hides the broadcast from everyone but its broadcaster.
+func (b *Broadcast) Unpublish() {
+	b.IsPublished = false
+}
+
+// Fields are the fields listings of broadcasts may be ordered or filtered by.
+var Fields = paginate.Schema{
+	"id":             {Kind: paginate.KindUUID, Sortable: true, Filterable: true},
+	"broadcaster_id": {Kind: paginate.KindInt, Sortable: false, Filterable: true},
+	"title":          {Kind: paginate.KindString, Sortable: true, Filterable: true},
+	"category":       {Kind: paginate.KindString, Sortable: true, Filterable: true},
+	"language":       {Kind: paginate.KindString, Sortable: false, Filterable: true},
+	"mature":         {Kind: paginate.KindBool, Sortable: false, Filterable: true},
+	"is_active":      {Kind: paginate.KindBool, Sortable: false, Filterable: true},
+	"is_published":   {Kind: paginate.KindBool, Sortable: false, Filterable: true},
+	"viewer_count":   {Kind: paginate.KindInt, Sortable: true, Filterable: true},
+	"published_at":   {Kind: paginate.KindTime, Sortable: true, Filterable: true},
+	"created_at":     {Kind: paginate.KindTime, Sortable: true, Filterable: true},
+}
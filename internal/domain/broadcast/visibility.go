@@ -0,0 +1,27 @@
+package broadcast
+
+type Visibility string
+
+const (
+	// VisibilityPublic broadcasts are listed and playable by anyone.
+	VisibilityPublic Visibility = "public"
+
+	// VisibilityUnlisted broadcasts are playable by anyone holding the URL, but are not listed.
+	VisibilityUnlisted Visibility = "unlisted"
+
+	// VisibilityPrivate broadcasts are only playable by authenticated viewers permitted to stream.
+	VisibilityPrivate Visibility = "private"
+)
+
+// ParseVisibility converts the given string to a Visibility, falling back to
+// VisibilityPublic when the value is not recognised.
+func ParseVisibility(value string) Visibility {
+	switch Visibility(value) {
+	case VisibilityUnlisted:
+		return VisibilityUnlisted
+	case VisibilityPrivate:
+		return VisibilityPrivate
+	default:
+		return VisibilityPublic
+	}
+}
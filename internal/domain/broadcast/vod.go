@@ -0,0 +1,29 @@
+package broadcast
+
+import (
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	"github.com/google/uuid"
+	"time"
+)
+
+// Vod links a broadcast to a video recorded from it.
+type Vod struct {
+	Id uuid.UUID
+
+	BroadcastId uuid.UUID
+	VideoId     uuid.UUID
+
+	PublishedAt time.Time
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// VodFields are the fields listings of the videos of broadcasts may be ordered or filtered by.
+var VodFields = paginate.Schema{
+	"id":           {Kind: paginate.KindUUID, Sortable: true, Filterable: true},
+	"stream_id":    {Kind: paginate.KindUUID, Sortable: false, Filterable: true},
+	"video_id":     {Kind: paginate.KindUUID, Sortable: false, Filterable: true},
+	"published_at": {Kind: paginate.KindTime, Sortable: true, Filterable: true},
+	"created_at":   {Kind: paginate.KindTime, Sortable: true, Filterable: true},
+}
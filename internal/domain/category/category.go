@@ -0,0 +1,33 @@
+package category
+
+import (
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	"time"
+)
+
+// Category groups live broadcasts by what is being streamed, such as a game.
+// Categories are managed by admins, and referenced by channels and broadcasts
+// through their slug.
+type Category struct {
+	Slug        string
+	Name        string
+	Description string
+	ImageURL    string
+
+	// Live totals across the category's live, published broadcasts, only
+	// filled when browsing the directory.
+	Viewers    int64
+	Broadcasts int64
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Fields are the fields listings of categories may be ordered or filtered by.
+var Fields = paginate.Schema{
+	"slug":       {Kind: paginate.KindString, Sortable: true, Filterable: true},
+	"name":       {Kind: paginate.KindString, Sortable: true, Filterable: true},
+	"viewers":    {Kind: paginate.KindInt, Sortable: true, Filterable: true},
+	"broadcasts": {Kind: paginate.KindInt, Sortable: true, Filterable: true},
+	"created_at": {Kind: paginate.KindTime, Sortable: true, Filterable: true},
+}
@@ -0,0 +1,63 @@
+package channel
+
+import (
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	"time"
+)
+
+// Channel is the public page of a broadcaster, describing what they stream.
+// New broadcasts inherit the channel's metadata as it is when they start.
+type Channel struct {
+	// The user this channel belongs to
+	BroadcasterId uint64
+
+	DisplayName string
+	Description string
+
+	AvatarURL string
+	BannerURL string
+
+	// DefaultTitle is the title of new broadcasts, falling back to the display name.
+	DefaultTitle string
+
+	Category string
+	Tags     []string
+
+	// Language is an ISO 639-1 code, or empty if unspecified.
+	Language string
+
+	// Mature channels stream content unsuitable for younger viewers.
+	Mature bool
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// BroadcastTitle returns the title new broadcasts of the channel start with.
+func (c Channel) BroadcastTitle() string {
+	if c.DefaultTitle != "" {
+		return c.DefaultTitle
+	}
+
+	return c.DisplayName
+}
+
+// Default returns the channel of a broadcaster who has not set one up yet.
+func Default(broadcasterId uint64, username string) Channel {
+	return Channel{
+		BroadcasterId: broadcasterId,
+		DisplayName:   username,
+		Tags:          []string{},
+	}
+}
+
+// Fields are the fields listings of channels may be ordered or filtered by.
+var Fields = paginate.Schema{
+	"broadcaster_id": {Kind: paginate.KindInt, Sortable: true, Filterable: true},
+	"display_name":   {Kind: paginate.KindString, Sortable: true, Filterable: true},
+	"category":       {Kind: paginate.KindString, Sortable: false, Filterable: true},
+	"language":       {Kind: paginate.KindString, Sortable: false, Filterable: true},
+	"mature":         {Kind: paginate.KindBool, Sortable: false, Filterable: true},
+	"created_at":     {Kind: paginate.KindTime, Sortable: true, Filterable: true},
+	"updated_at":     {Kind: paginate.KindTime, Sortable: true, Filterable: true},
+}
@@ -0,0 +1,47 @@
+package clip
+
+import (
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	"github.com/google/uuid"
+	"time"
+)
+
+// SourceType is the kind of media a clip is cut from.
+type SourceType string
+
+const (
+	SourceBroadcast SourceType = "broadcast"
+	SourceVideo     SourceType = "video"
+)
+
+// Clip is a keyframe-aligned range cut from a broadcast or video, published as
+// a video of its own.
+type Clip struct {
+	Id uuid.UUID
+
+	// VideoId is the video the clip was published as.
+	VideoId uuid.UUID
+
+	// The user who cut the clip, and the user whose broadcast it was cut from.
+	CreatorId     uint64
+	BroadcasterId uint64
+
+	SourceType SourceType
+	SourceId   uuid.UUID
+
+	// Start and End are the offsets into the source the clip covers, after
+	// aligning them to keyframes.
+	Start time.Duration
+	End   time.Duration
+
+	CreatedAt time.Time
+}
+
+// Fields are the fields listings of clips may be ordered or filtered by.
+var Fields = paginate.Schema{
+	"id":          {Kind: paginate.KindUUID, Sortable: true, Filterable: true},
+	"video_id":    {Kind: paginate.KindUUID, Sortable: false, Filterable: true},
+	"creator_id":  {Kind: paginate.KindInt, Sortable: false, Filterable: true},
+	"source_type": {Kind: paginate.KindString, Sortable: false, Filterable: true},
+	"created_at":  {Kind: paginate.KindTime, Sortable: true, Filterable: true},
+}
@@ -0,0 +1,21 @@
+package cluster
+
+import (
+	"github.com/M-Ro/go-vodstream/internal/domain/broadcast"
+	"time"
+)
+
+// Origin records which ingester node a live channel is being published to.
+type Origin struct {
+	Name string
+
+	// NodeAddress is the RTMP address other nodes pull the channel from.
+	NodeAddress string
+
+	BroadcasterId uint64
+	Visibility    broadcast.Visibility
+	LowLatency    bool
+
+	StartedAt   time.Time
+	HeartbeatAt time.Time
+}
@@ -0,0 +1,43 @@
+package edit
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Operation is the kind of change an edit makes to a broadcaster's videos.
+type Operation string
+
+const (
+	OperationTrim  Operation = "trim"
+	OperationSplit Operation = "split"
+	OperationMerge Operation = "merge"
+)
+
+// Status tracks whether an edit's results have replaced its sources.
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusConfirmed Status = "confirmed"
+	StatusDiscarded Status = "discarded"
+)
+
+// Edit is a trim, split or merge of a broadcaster's videos. Its results are
+// published as new videos, the sources are kept until the edit is confirmed.
+type Edit struct {
+	Id uuid.UUID
+
+	// BroadcasterId is the user whose videos were edited.
+	BroadcasterId uint64
+
+	Operation Operation
+	Status    Status
+
+	// Sources are the videos edited, Results the videos the edit produced, in order.
+	Sources []uuid.UUID
+	Results []uuid.UUID
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
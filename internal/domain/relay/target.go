@@ -0,0 +1,43 @@
+package relay
+
+import (
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	"github.com/google/uuid"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusIdle       Status = "idle"
+	StatusConnecting Status = "connecting"
+	StatusLive       Status = "live"
+	StatusRetrying   Status = "retrying"
+)
+
+// Target is an external RTMP destination a broadcaster restreams to.
+type Target struct {
+	Id     uuid.UUID
+	UserId uint64
+
+	Name      string
+	URL       string
+	StreamKey string
+	Enabled   bool
+
+	Status          Status
+	LastError       string
+	StatusUpdatedAt time.Time
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// TargetFields are the fields listings of relay targets may be ordered or filtered by.
+var TargetFields = paginate.Schema{
+	"id":         {Kind: paginate.KindUUID, Sortable: true, Filterable: true},
+	"name":       {Kind: paginate.KindString, Sortable: true, Filterable: true},
+	"enabled":    {Kind: paginate.KindBool, Sortable: false, Filterable: true},
+	"status":     {Kind: paginate.KindString, Sortable: false, Filterable: true},
+	"created_at": {Kind: paginate.KindTime, Sortable: true, Filterable: true},
+}
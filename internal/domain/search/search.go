@@ -0,0 +1,41 @@
+package search
+
+import "time"
+
+// Type is the kind of document a search result was found in.
+type Type string
+
+const (
+	TypeChannel   Type = "channel"
+	TypeBroadcast Type = "broadcast"
+	TypeVideo     Type = "video"
+)
+
+// Types lists every kind of document that can be searched, in the order
+// results of equal rank are listed.
+var Types = []Type{TypeBroadcast, TypeChannel, TypeVideo}
+
+// Result is a channel, broadcast or video matching a search query.
+type Result struct {
+	Type Type
+
+	// The broadcaster ID of channels, or the UUID of broadcasts and videos
+	Id string
+
+	BroadcasterId uint64
+	Username      string
+
+	// The display name of channels, or the title of broadcasts and videos
+	Title       string
+	Description string
+
+	Category string
+	Tags     []string
+
+	// IsLive is set for live broadcasts, and channels that are live
+	IsLive      bool
+	PublishedAt time.Time
+
+	// Rank is how closely the result matched the query, higher first
+	Rank float64
+}
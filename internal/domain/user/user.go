@@ -1,6 +1,9 @@
 package user
 
-import "time"
+import (
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	"time"
+)
 
 type User struct {
 	Id         uint64
@@ -13,6 +16,23 @@ type User struct {
 	CanPublish bool
 	CanStream  bool
 
+	// IsAdmin users manage site-wide content, such as the category directory.
+	IsAdmin bool
+
+	// StorageQuota caps the bytes of video kept for the user, or is zero for
+	// the configured default.
+	StorageQuota int64
+
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
+
+// Fields are the fields listings of users may be ordered or filtered by.
+var Fields = paginate.Schema{
+	"id":          {Kind: paginate.KindInt, Sortable: true, Filterable: true},
+	"username":    {Kind: paginate.KindString, Sortable: true, Filterable: true},
+	"can_publish": {Kind: paginate.KindBool, Sortable: false, Filterable: true},
+	"can_stream":  {Kind: paginate.KindBool, Sortable: false, Filterable: true},
+	"is_admin":    {Kind: paginate.KindBool, Sortable: false, Filterable: true},
+	"created_at":  {Kind: paginate.KindTime, Sortable: true, Filterable: true},
+}
@@ -0,0 +1,56 @@
+package video
+
+import (
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	"github.com/google/uuid"
+	"time"
+)
+
+// Video is a recording, clip or edit of a broadcast, stored in the media store.
+type Video struct {
+	Id    uuid.UUID
+	Title string
+
+	// The user whose broadcast this video was recorded from
+	BroadcasterId uint64
+
+	Length time.Duration
+
+	// Only published videos are listed to anyone but their broadcaster.
+	IsPublished bool
+	PublishedAt time.Time
+
+	FilePath  string
+	IndexPath string
+
+	// Pinned videos are kept forever, regardless of retention rules
+	Pinned bool
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Publish lists the video, stamping when it was published unless it already was.
+func (v *Video) Publish(now time.Time) {
+	if !v.IsPublished {
+		v.IsPublished = true
+		v.PublishedAt = now
+	}
+}
+
+// Unpublish hides the video from everyone but its broadcaster.
+func (v *Video) Unpublish() {
+	v.IsPublished = false
+}
+
+// Fields are the fields listings of videos may be ordered or filtered by.
+var Fields = paginate.Schema{
+	"id":             {Kind: paginate.KindUUID, Sortable: true, Filterable: true},
+	"title":          {Kind: paginate.KindString, Sortable: true, Filterable: true},
+	"broadcaster_id": {Kind: paginate.KindInt, Sortable: false, Filterable: true},
+	"length":         {Kind: paginate.KindInt, Sortable: true, Filterable: true},
+	"is_published":   {Kind: paginate.KindBool, Sortable: false, Filterable: true},
+	"published_at":   {Kind: paginate.KindTime, Sortable: true, Filterable: true},
+	"pinned":         {Kind: paginate.KindBool, Sortable: false, Filterable: true},
+	"created_at":     {Kind: paginate.KindTime, Sortable: true, Filterable: true},
+}
@@ -0,0 +1,463 @@
+// Package edit trims, splits and merges a broadcaster's videos on keyframes
+// without re-encoding. The results of an edit are published as new videos, and
+// only replace the videos they were cut from once the edit is confirmed.
+package edit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/M-Ro/go-vodstream/internal/blob"
+	"github.com/M-Ro/go-vodstream/internal/domain/edit"
+	"github.com/M-Ro/go-vodstream/internal/recording"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/google/uuid"
+	"github.com/nareix/joy4/av"
+	"io"
+	"time"
+)
+
+var (
+	ErrVideoNotFound     = errors.New("video does not exist")
+	ErrInvalidRange      = errors.New("edit range is invalid")
+	ErrTooFewVideos      = errors.New("at least two videos are required to merge")
+	ErrMixedBroadcasters = errors.New("videos belong to different broadcasters")
+	ErrIncompatible      = errors.New("videos cannot be joined without re-encoding")
+	ErrNotPending        = errors.New("edit has already been confirmed or discarded")
+)
+
+// VideoStorage looks up the videos being edited, publishes the videos edits
+// produce, and deletes whichever of the two an edit leaves behind.
+type VideoStorage interface {
+	GetByID(ctx context.Context, id uuid.UUID) *storage.Video
+	Insert(ctx context.Context, video *storage.Video) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+// VodStorage links videos to the broadcasts they were recorded from, carrying
+// the links of the videos an edit replaces over to its results.
+type VodStorage interface {
+	GetByVideoID(ctx context.Context, videoId uuid.UUID) ([]storage.BroadcastVod, error)
+	Insert(ctx context.Context, vod *storage.BroadcastVod) error
+	DeleteByVideoID(ctx context.Context, videoId uuid.UUID) error
+}
+
+// Transactor runs units of work spanning several storages atomically.
+type Transactor interface {
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// piece is a recording written by an edit, not yet published as a video.
+type piece struct {
+	name  string
+	index recording.Index
+}
+
+// Editor edits recorded videos, writing its results alongside recordings.
+type Editor struct {
+	recordingConfig recording.Config
+	media           blob.Store
+
+	videos       VideoStorage
+	vods         VodStorage
+	edits        Repository
+	transactions Transactor
+}
+
+// Video returns the recorded video with the given id.
+func (e *Editor) Video(ctx context.Context, id uuid.UUID) (storage.Video, error) {
+	video := e.videos.GetByID(ctx, id)
+	if video == nil || video.FilePath == "" {
+		return storage.Video{}, ErrVideoNotFound
+	}
+
+	return *video, nil
+}
+
+// Videos returns the videos with the given ids, skipping any that no longer exist.
+func (e *Editor) Videos(ctx context.Context, ids []uuid.UUID) []storage.Video {
+	videos := make([]storage.Video, 0, len(ids))
+	for _, id := range ids {
+		if video := e.videos.GetByID(ctx, id); video != nil {
+			videos = append(videos, *video)
+		}
+	}
+
+	return videos
+}
+
+// Trim cuts the video down to the range from start to end, as offsets from its
+// start. The range is widened to start on the keyframe at or before its start,
+// and to end on the first keyframe at or after its end.
+func (e *Editor) Trim(ctx context.Context, video storage.Video, start time.Duration, end time.Duration) (edit.Edit, error) {
+	if start < 0 || end <= start {
+		return edit.Edit{}, ErrInvalidRange
+	}
+
+	file, index, err := recording.Open(ctx, e.media, video)
+	if err != nil {
+		return edit.Edit{}, err
+	}
+	defer file.Close()
+
+	if len(index.KeyFrames) == 0 || start >= index.Duration {
+		return edit.Edit{}, ErrInvalidRange
+	}
+
+	// Offsets are relative to the first keyframe, where playback of the video starts.
+	base := index.KeyFrames[0].Time
+
+	from, until, err := index.Range(base+start, base+end)
+	if err != nil {
+		return edit.Edit{}, err
+	}
+
+	trimmed, err := e.cut(ctx, file, index, from, until)
+	if err != nil {
+		return edit.Edit{}, err
+	}
+
+	return e.publish(ctx, edit.OperationTrim, []storage.Video{video}, []piece{trimmed})
+}
+
+// Split cuts the video into consecutive parts at the given offsets from its
+// start, in increasing order. Each part starts on the keyframe at or before its
+// offset, so no two offsets may fall within the same keyframe interval.
+func (e *Editor) Split(ctx context.Context, video storage.Video, at []time.Duration) (edit.Edit, error) {
+	if len(at) == 0 {
+		return edit.Edit{}, ErrInvalidRange
+	}
+
+	file, index, err := recording.Open(ctx, e.media, video)
+	if err != nil {
+		return edit.Edit{}, err
+	}
+	defer file.Close()
+
+	if len(index.KeyFrames) == 0 {
+		return edit.Edit{}, ErrInvalidRange
+	}
+
+	base := index.KeyFrames[0].Time
+
+	bounds := []recording.KeyFrame{index.KeyFrames[0]}
+	for _, t := range at {
+		if t <= 0 || t >= index.Duration {
+			return edit.Edit{}, ErrInvalidRange
+		}
+
+		keyFrame, err := index.Seek(base + t)
+		if err != nil {
+			return edit.Edit{}, err
+		}
+
+		if keyFrame.Time <= bounds[len(bounds)-1].Time {
+			return edit.Edit{}, ErrInvalidRange
+		}
+
+		bounds = append(bounds, keyFrame)
+	}
+
+	pieces := make([]piece, 0, len(bounds))
+	for i, from := range bounds {
+		until := index.Size
+		if i+1 < len(bounds) {
+			until = bounds[i+1].Offset
+		}
+
+		part, err := e.cut(ctx, file, index, from, until)
+		if err != nil {
+			e.discard(ctx, pieces)
+			return edit.Edit{}, err
+		}
+
+		pieces = append(pieces, part)
+	}
+
+	return e.publish(ctx, edit.OperationSplit, []storage.Video{video}, pieces)
+}
+
+// Merge joins the videos end to end, in the order given. The videos must belong
+// to the same broadcaster, and carry the same codecs with the same parameters.
+func (e *Editor) Merge(ctx context.Context, videos []storage.Video) (edit.Edit, error) {
+	if len(videos) < 2 {
+		return edit.Edit{}, ErrTooFewVideos
+	}
+
+	files := make([]*blob.File, 0, len(videos))
+	defer func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}()
+
+	indexes := make([]recording.Index, 0, len(videos))
+	for _, video := range videos {
+		if video.BroadcasterId != videos[0].BroadcasterId {
+			return edit.Edit{}, ErrMixedBroadcasters
+		}
+
+		file, index, err := recording.Open(ctx, e.media, video)
+		if err != nil {
+			return edit.Edit{}, err
+		}
+		files = append(files, file)
+
+		if len(index.KeyFrames) == 0 {
+			return edit.Edit{}, ErrInvalidRange
+		}
+
+		if len(indexes) > 0 && !recording.Compatible(indexes[0].Streams, index.Streams) {
+			return edit.Edit{}, ErrIncompatible
+		}
+
+		indexes = append(indexes, index)
+	}
+
+	sections := make([]av.Demuxer, len(files))
+	for i, file := range files {
+		sections[i] = recording.Section(file, indexes[i], indexes[i].KeyFrames[0], indexes[i].Size)
+	}
+
+	streams, err := sections[0].Streams()
+	if err != nil {
+		return edit.Edit{}, err
+	}
+
+	name := fmt.Sprintf("edit-%s.flv", uuid.New())
+
+	// Each video starts where the last ended, from its first keyframe.
+	index, err := recording.Create(ctx, e.recordingConfig, e.media, name, streams, func(writer *recording.Writer) error {
+		var end time.Duration
+		for i, section := range sections {
+			var err error
+			if end, err = recording.Copy(writer, section, end-indexes[i].KeyFrames[0].Time); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return edit.Edit{}, err
+	}
+
+	return e.publish(ctx, edit.OperationMerge, videos, []piece{{name: name, index: index}})
+}
+
+// cut remuxes the recording from the keyframe up to the byte offset until into
+// a new recording with its own index, rebasing timestamps to start at zero.
+func (e *Editor) cut(
+	ctx context.Context, file io.ReaderAt, index recording.Index, from recording.KeyFrame, until int64,
+) (piece, error) {
+	demuxer := recording.Section(file, index, from, until)
+
+	streams, err := demuxer.Streams()
+	if err != nil {
+		return piece{}, err
+	}
+
+	name := fmt.Sprintf("edit-%s.flv", uuid.New())
+
+	cutIndex, err := recording.Create(ctx, e.recordingConfig, e.media, name, streams, func(writer *recording.Writer) error {
+		_, err := recording.Copy(writer, demuxer, -from.Time)
+		return err
+	})
+	if err != nil {
+		return piece{}, err
+	}
+
+	return piece{name: name, index: cutIndex}, nil
+}
+
+// discard removes recordings written for an edit that failed.
+func (e *Editor) discard(ctx context.Context, pieces []piece) {
+	for _, p := range pieces {
+		recording.Remove(ctx, e.media, p.name)
+	}
+}
+
+// publish inserts the pieces as videos of the sources' broadcaster, recording
+// the edit as pending. Parts of a split are numbered after the source's title.
+func (e *Editor) publish(ctx context.Context, operation edit.Operation, sources []storage.Video, pieces []piece) (edit.Edit, error) {
+	newEdit := edit.Edit{
+		BroadcasterId: sources[0].BroadcasterId,
+		Operation:     operation,
+		Status:        edit.StatusPending,
+		Sources:       make([]uuid.UUID, 0, len(sources)),
+		Results:       make([]uuid.UUID, 0, len(pieces)),
+	}
+
+	for _, source := range sources {
+		newEdit.Sources = append(newEdit.Sources, source.Id)
+	}
+
+	err := e.transactions.WithTx(ctx, func(ctx context.Context) error {
+		newEdit.Results = newEdit.Results[:0]
+
+		for i, p := range pieces {
+			title := sources[0].Title
+			if len(pieces) > 1 {
+				title = fmt.Sprintf("%s (part %d)", title, i+1)
+			}
+
+			video := storage.Video{
+				Title:         title,
+				BroadcasterId: sources[0].BroadcasterId,
+				Length:        uint64(p.index.Duration.Milliseconds()),
+				IsPublished:   sources[0].IsPublished,
+				PublishedAt:   sources[0].PublishedAt,
+				FilePath:      p.name,
+				IndexPath:     recording.IndexFile(p.name),
+			}
+
+			if err := e.videos.Insert(ctx, &video); err != nil {
+				return err
+			}
+
+			newEdit.Results = append(newEdit.Results, video.Id)
+		}
+
+		return e.edits.Insert(ctx, &newEdit)
+	})
+	if err != nil {
+		e.discard(ctx, pieces)
+		return edit.Edit{}, err
+	}
+
+	return newEdit, nil
+}
+
+// delete removes the videos with the given ids, skipping any already gone.
+// Returns the recordings of the deleted videos, to be removed once the
+// deletion can no longer be rolled back.
+func (e *Editor) delete(ctx context.Context, ids []uuid.UUID) ([]string, error) {
+	recordings := make([]string, 0, len(ids))
+
+	for _, id := range ids {
+		video := e.videos.GetByID(ctx, id)
+		if video == nil {
+			continue
+		}
+
+		if err := e.vods.DeleteByVideoID(ctx, id); err != nil {
+			return nil, err
+		}
+
+		if err := e.videos.Delete(ctx, id); err != nil {
+			return nil, err
+		}
+
+		recordings = append(recordings, video.FilePath)
+	}
+
+	return recordings, nil
+}
+
+// link links the results of an edit to every broadcast its sources were recorded
+// from, as published when the earliest source of each broadcast was. Results follow
+// each other a millisecond apart, keeping the parts of a split in order.
+func (e *Editor) link(ctx context.Context, sources []uuid.UUID, results []uuid.UUID) error {
+	streams := make([]uuid.UUID, 0)
+	published := make(map[uuid.UUID]time.Time)
+
+	for _, id := range sources {
+		vods, err := e.vods.GetByVideoID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		for _, vod := range vods {
+			at, ok := published[vod.StreamId]
+			if !ok {
+				streams = append(streams, vod.StreamId)
+			}
+
+			if !ok || vod.PublishedAt.Before(at) {
+				published[vod.StreamId] = vod.PublishedAt
+			}
+		}
+	}
+
+	for _, streamId := range streams {
+		for i, id := range results {
+			vod := storage.BroadcastVod{
+				StreamId:    streamId,
+				VideoId:     id,
+				PublishedAt: published[streamId].Add(time.Duration(i) * time.Millisecond),
+			}
+
+			if err := e.vods.Insert(ctx, &vod); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Confirm replaces the sources of a pending edit with its results, deleting
+// the source videos and their recordings. The results take the place of the
+// sources among the VODs of the broadcasts they were recorded from.
+func (e *Editor) Confirm(ctx context.Context, pending edit.Edit) (edit.Edit, error) {
+	return e.resolve(ctx, pending, edit.StatusConfirmed, pending.Sources)
+}
+
+// Discard abandons a pending edit, deleting the videos it produced.
+func (e *Editor) Discard(ctx context.Context, pending edit.Edit) (edit.Edit, error) {
+	return e.resolve(ctx, pending, edit.StatusDiscarded, pending.Results)
+}
+
+// resolve deletes the given videos of a pending edit and marks it with status,
+// then removes the recordings of the deleted videos. Confirmed results are linked
+// to the broadcasts of the sources before the sources are deleted.
+func (e *Editor) resolve(ctx context.Context, pending edit.Edit, status edit.Status, remove []uuid.UUID) (edit.Edit, error) {
+	if pending.Status != edit.StatusPending {
+		return edit.Edit{}, ErrNotPending
+	}
+
+	var recordings []string
+	err := e.transactions.WithTx(ctx, func(ctx context.Context) error {
+		if status == edit.StatusConfirmed {
+			if err := e.link(ctx, pending.Sources, pending.Results); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		if recordings, err = e.delete(ctx, remove); err != nil {
+			return err
+		}
+
+		pending.Status = status
+		return e.edits.Update(ctx, &pending)
+	})
+	if err != nil {
+		return edit.Edit{}, err
+	}
+
+	for _, name := range recordings {
+		recording.Remove(ctx, e.media, name)
+	}
+
+	return pending, nil
+}
+
+// NewEditor instantiates an Editor writing edits alongside recordings in media.
+func NewEditor(
+	recordingConfig recording.Config,
+	media blob.Store,
+	videos VideoStorage,
+	vods VodStorage,
+	edits Repository,
+	transactions Transactor,
+) *Editor {
+	return &Editor{
+		recordingConfig: recordingConfig,
+		media:           media,
+		videos:          videos,
+		vods:            vods,
+		edits:           edits,
+		transactions:    transactions,
+	}
+}
@@ -0,0 +1,487 @@
+package edit
+
+import (
+	"context"
+	"fmt"
+	"github.com/M-Ro/go-vodstream/internal/avtest"
+	"github.com/M-Ro/go-vodstream/internal/blob"
+	"github.com/M-Ro/go-vodstream/internal/domain/edit"
+	"github.com/M-Ro/go-vodstream/internal/recording"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/google/uuid"
+	"github.com/spf13/afero"
+	"sort"
+	"testing"
+	"time"
+)
+
+type fakeVideos struct {
+	videos map[uuid.UUID]storage.Video
+}
+
+func (f *fakeVideos) GetByID(_ context.Context, id uuid.UUID) *storage.Video {
+	video, ok := f.videos[id]
+	if !ok {
+		return nil
+	}
+
+	return &video
+}
+
+func (f *fakeVideos) Insert(_ context.Context, video *storage.Video) error {
+	video.Id = uuid.New()
+	f.videos[video.Id] = *video
+	return nil
+}
+
+func (f *fakeVideos) Delete(_ context.Context, id uuid.UUID) error {
+	delete(f.videos, id)
+	return nil
+}
+
+// fakeVods links videos to the broadcasts they were recorded from.
+type fakeVods struct {
+	vods map[uuid.UUID]storage.BroadcastVod
+}
+
+func (f *fakeVods) GetByVideoID(_ context.Context, videoId uuid.UUID) ([]storage.BroadcastVod, error) {
+	vods := make([]storage.BroadcastVod, 0)
+	for _, vod := range f.vods {
+		if vod.VideoId == videoId {
+			vods = append(vods, vod)
+		}
+	}
+
+	return vods, nil
+}
+
+// GetByStreamID returns the videos linked to the broadcast, in order of publication.
+func (f *fakeVods) GetByStreamID(streamId uuid.UUID) []uuid.UUID {
+	vods := make([]storage.BroadcastVod, 0)
+	for _, vod := range f.vods {
+		if vod.StreamId == streamId {
+			vods = append(vods, vod)
+		}
+	}
+
+	sort.Slice(vods, func(i, j int) bool {
+		return vods[i].PublishedAt.Before(vods[j].PublishedAt)
+	})
+
+	videoIds := make([]uuid.UUID, 0, len(vods))
+	for _, vod := range vods {
+		videoIds = append(videoIds, vod.VideoId)
+	}
+
+	return videoIds
+}
+
+func (f *fakeVods) Insert(_ context.Context, vod *storage.BroadcastVod) error {
+	vod.Id = uuid.New()
+	f.vods[vod.Id] = *vod
+	return nil
+}
+
+func (f *fakeVods) DeleteByVideoID(_ context.Context, videoId uuid.UUID) error {
+	for id, vod := range f.vods {
+		if vod.VideoId == videoId {
+			delete(f.vods, id)
+		}
+	}
+
+	return nil
+}
+
+type fakeEdits struct {
+	edits map[uuid.UUID]storage.VideoEdit
+}
+
+func (f *fakeEdits) GetByID(_ context.Context, id uuid.UUID) *storage.VideoEdit {
+	e, ok := f.edits[id]
+	if !ok {
+		return nil
+	}
+
+	return &e
+}
+
+func (f *fakeEdits) Insert(_ context.Context, e *storage.VideoEdit) error {
+	e.Id = uuid.New()
+	e.CreatedAt = time.Now()
+	e.UpdatedAt = e.CreatedAt
+	f.edits[e.Id] = *e
+	return nil
+}
+
+func (f *fakeEdits) Update(_ context.Context, id uuid.UUID, e *storage.VideoEdit) error {
+	e.UpdatedAt = time.Now()
+	f.edits[id] = *e
+	return nil
+}
+
+// writeVideo records count frames, with a keyframe every second, as a video of
+// the given broadcaster.
+func writeVideo(t *testing.T, editor *Editor, videos *fakeVideos, broadcasterId uint64, count int) storage.Video {
+	name := fmt.Sprintf("source-%s.flv", uuid.New())
+
+	_, err := recording.Create(context.Background(), editor.recordingConfig, editor.media, name, avtest.Streams(),
+		func(writer *recording.Writer) error {
+			for _, packet := range avtest.Packets(count, 25) {
+				if err := writer.WritePacket(packet); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	indexPath := recording.IndexFile(name)
+	video := storage.Video{Title: "source", BroadcasterId: broadcasterId, FilePath: name, IndexPath: indexPath}
+	if err := videos.Insert(context.Background(), &video); err != nil {
+		t.Fatal(err)
+	}
+
+	return video
+}
+
+// newEditor instantiates an Editor over fake storages, spooling to a temporary
+// directory and storing media in memory.
+func newEditor(t *testing.T) (*Editor, *fakeVideos, *fakeEdits) {
+	editor, videos, _, edits := newEditorWithVods(t)
+	return editor, videos, edits
+}
+
+// newEditorWithVods instantiates an Editor as newEditor does, also returning the
+// links of videos to broadcasts.
+func newEditorWithVods(t *testing.T) (*Editor, *fakeVideos, *fakeVods, *fakeEdits) {
+	videos := &fakeVideos{videos: make(map[uuid.UUID]storage.Video)}
+	vods := &fakeVods{vods: make(map[uuid.UUID]storage.BroadcastVod)}
+	edits := &fakeEdits{edits: make(map[uuid.UUID]storage.VideoEdit)}
+
+	editor := NewEditor(
+		recording.Config{SpoolDirectory: t.TempDir()}, blob.NewLocalStore(afero.NewMemMapFs()), videos, vods,
+		NewRepository(edits), storage.Immediate{},
+	)
+
+	return editor, videos, vods, edits
+}
+
+// keyFrames opens a video's recording and returns the times of its keyframes.
+func keyFrames(t *testing.T, editor *Editor, video storage.Video) []time.Duration {
+	file, index, err := recording.Open(context.Background(), editor.media, video)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	times := make([]time.Duration, 0, len(index.KeyFrames))
+	for _, keyFrame := range index.KeyFrames {
+		times = append(times, keyFrame.Time)
+	}
+
+	return times
+}
+
+func TestEditor_Trim(t *testing.T) {
+	editor, videos, edits := newEditor(t)
+	source := writeVideo(t, editor, videos, 2, 200)
+
+	ctx := context.Background()
+
+	trimmed, err := editor.Trim(ctx, source, 1500*time.Millisecond, 3200*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := edit.Edit{
+		Id:            trimmed.Id,
+		BroadcasterId: 2,
+		Operation:     edit.OperationTrim,
+		Status:        edit.StatusPending,
+		Sources:       []uuid.UUID{source.Id},
+		Results:       trimmed.Results,
+		CreatedAt:     trimmed.CreatedAt,
+		UpdatedAt:     trimmed.UpdatedAt,
+	}
+
+	if diff := cmp.Diff(trimmed, expected); diff != "" {
+		t.Fatal(diff)
+	}
+
+	if len(trimmed.Results) != 1 || len(edits.edits) != 1 {
+		t.Fatalf("expected one result in one edit, found %d in %d", len(trimmed.Results), len(edits.edits))
+	}
+
+	// The trimmed video starts on the keyframe at 1s, and runs up to the keyframe at 4s.
+	result := videos.GetByID(ctx, trimmed.Results[0])
+	if result == nil {
+		t.Fatal("expected the trimmed video to be published")
+	}
+
+	if result.Title != "source" || result.BroadcasterId != 2 || result.Length != uint64((74*avtest.FrameDuration).Milliseconds()) {
+		t.Fatalf("unexpected trimmed video: %+v", result)
+	}
+
+	if diff := cmp.Diff(keyFrames(t, editor, *result), []time.Duration{0, time.Second, 2 * time.Second}); diff != "" {
+		t.Fatal(diff)
+	}
+
+	// The source is kept until the edit is confirmed.
+	if videos.GetByID(ctx, source.Id) == nil {
+		t.Fatal("expected the source to be kept while the edit is pending")
+	}
+}
+
+func TestEditor_Split(t *testing.T) {
+	editor, videos, _ := newEditor(t)
+	source := writeVideo(t, editor, videos, 2, 200)
+
+	ctx := context.Background()
+
+	split, err := editor.Split(ctx, source, []time.Duration{2500 * time.Millisecond, 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(split.Results) != 3 {
+		t.Fatal(cmp.Diff(len(split.Results), 3))
+	}
+
+	// Parts start on the keyframes at 0s, 2s and 5s.
+	lengths := []time.Duration{49 * avtest.FrameDuration, 74 * avtest.FrameDuration, 74 * avtest.FrameDuration}
+	for i, id := range split.Results {
+		part := videos.GetByID(ctx, id)
+		if part == nil {
+			t.Fatalf("expected part %d to be published", i+1)
+		}
+
+		if title := fmt.Sprintf("source (part %d)", i+1); part.Title != title {
+			t.Fatal(cmp.Diff(part.Title, title))
+		}
+
+		if part.Length != uint64(lengths[i].Milliseconds()) {
+			t.Fatal(cmp.Diff(part.Length, uint64(lengths[i].Milliseconds())))
+		}
+
+		if first := keyFrames(t, editor, *part)[0]; first != 0 {
+			t.Fatalf("expected part %d to start at zero, found %s", i+1, first)
+		}
+	}
+}
+
+func TestEditor_Split_InvalidRange(t *testing.T) {
+	editor, videos, _ := newEditor(t)
+	source := writeVideo(t, editor, videos, 2, 100)
+
+	tests := []struct {
+		testName string
+		at       []time.Duration
+	}{
+		{testName: "no offsets", at: nil},
+		{testName: "at the start", at: []time.Duration{0}},
+		{testName: "past the end", at: []time.Duration{5 * time.Second}},
+		{testName: "same keyframe interval", at: []time.Duration{time.Second, 1500 * time.Millisecond}},
+		{testName: "out of order", at: []time.Duration{3 * time.Second, time.Second}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			_, err := editor.Split(context.Background(), source, test.at)
+			if !cmp.Equal(err, ErrInvalidRange, cmpopts.EquateErrors()) {
+				t.Fatal(cmp.Diff(err, ErrInvalidRange, cmpopts.EquateErrors()))
+			}
+		})
+	}
+}
+
+func TestEditor_Merge(t *testing.T) {
+	editor, videos, _ := newEditor(t)
+	first := writeVideo(t, editor, videos, 2, 100)
+	second := writeVideo(t, editor, videos, 2, 50)
+
+	ctx := context.Background()
+
+	merged, err := editor.Merge(ctx, []storage.Video{first, second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(merged.Sources, []uuid.UUID{first.Id, second.Id}); diff != "" {
+		t.Fatal(diff)
+	}
+
+	result := videos.GetByID(ctx, merged.Results[0])
+	if result == nil {
+		t.Fatal("expected the merged video to be published")
+	}
+
+	// The second video follows one frame after the last frame of the first.
+	if result.Length != uint64((149 * avtest.FrameDuration).Milliseconds()) {
+		t.Fatal(cmp.Diff(result.Length, uint64((149 * avtest.FrameDuration).Milliseconds())))
+	}
+
+	expected := []time.Duration{0, time.Second, 2 * time.Second, 3 * time.Second, 4 * time.Second, 5 * time.Second}
+	if diff := cmp.Diff(keyFrames(t, editor, *result), expected); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestEditor_Merge_Invalid(t *testing.T) {
+	editor, videos, _ := newEditor(t)
+	first := writeVideo(t, editor, videos, 2, 50)
+	other := writeVideo(t, editor, videos, 3, 50)
+
+	tests := []struct {
+		testName string
+		videos   []storage.Video
+		err      error
+	}{
+		{testName: "single video", videos: []storage.Video{first}, err: ErrTooFewVideos},
+		{testName: "different broadcasters", videos: []storage.Video{first, other}, err: ErrMixedBroadcasters},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			_, err := editor.Merge(context.Background(), test.videos)
+			if !cmp.Equal(err, test.err, cmpopts.EquateErrors()) {
+				t.Fatal(cmp.Diff(err, test.err, cmpopts.EquateErrors()))
+			}
+		})
+	}
+}
+
+func TestEditor_Confirm(t *testing.T) {
+	editor, videos, edits := newEditor(t)
+	source := writeVideo(t, editor, videos, 2, 100)
+
+	ctx := context.Background()
+
+	trimmed, err := editor.Trim(ctx, source, time.Second, 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	confirmed, err := editor.Confirm(ctx, trimmed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if confirmed.Status != edit.StatusConfirmed || edits.edits[trimmed.Id].Status != string(edit.StatusConfirmed) {
+		t.Fatal("expected the edit to be confirmed")
+	}
+
+	if videos.GetByID(ctx, source.Id) != nil {
+		t.Fatal("expected the source video to be deleted")
+	}
+
+	if _, err := editor.media.Stat(ctx, source.FilePath); err != blob.ErrNotFound {
+		t.Fatalf("expected the source recording to be removed: %v", err)
+	}
+
+	if videos.GetByID(ctx, trimmed.Results[0]) == nil {
+		t.Fatal("expected the trimmed video to be kept")
+	}
+
+	if _, err := editor.Discard(ctx, confirmed); err != ErrNotPending {
+		t.Fatal(cmp.Diff(err, ErrNotPending, cmpopts.EquateErrors()))
+	}
+}
+
+func TestEditor_Discard(t *testing.T) {
+	editor, videos, _ := newEditor(t)
+	source := writeVideo(t, editor, videos, 2, 100)
+
+	ctx := context.Background()
+
+	split, err := editor.Split(ctx, source, []time.Duration{2 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	discarded, err := editor.Discard(ctx, split)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if discarded.Status != edit.StatusDiscarded {
+		t.Fatal(cmp.Diff(discarded.Status, edit.StatusDiscarded))
+	}
+
+	if len(videos.videos) != 1 || videos.GetByID(ctx, source.Id) == nil {
+		t.Fatal("expected only the source video to remain")
+	}
+
+	objects, err := editor.media.List(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(objects) != 2 {
+		t.Fatalf("expected only the source recording and its index to remain, found %d objects", len(objects))
+	}
+}
+
+func TestEditor_Confirm_BroadcastVods(t *testing.T) {
+	editor, videos, vods, _ := newEditorWithVods(t)
+
+	ctx := context.Background()
+
+	// Four recordings of a broadcast, published a minute apart, are then trimmed,
+	// split, and the last two merged.
+	broadcastId := uuid.New()
+	start := time.Now()
+	recorded := make([]storage.Video, 4)
+	for i := range recorded {
+		recorded[i] = writeVideo(t, editor, videos, 2, 100)
+
+		vod := storage.BroadcastVod{
+			StreamId:    broadcastId,
+			VideoId:     recorded[i].Id,
+			PublishedAt: start.Add(time.Duration(i) * time.Minute),
+		}
+		if err := vods.Insert(ctx, &vod); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	trimmed, err := editor.Trim(ctx, recorded[0], time.Second, 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	split, err := editor.Split(ctx, recorded[1], []time.Duration{2 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	merged, err := editor.Merge(ctx, []storage.Video{recorded[2], recorded[3]})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Pending results are not yet VODs of the broadcast.
+	expected := []uuid.UUID{recorded[0].Id, recorded[1].Id, recorded[2].Id, recorded[3].Id}
+	if diff := cmp.Diff(vods.GetByStreamID(broadcastId), expected); diff != "" {
+		t.Fatal(diff)
+	}
+
+	for _, pending := range []edit.Edit{trimmed, split, merged} {
+		if _, err := editor.Confirm(ctx, pending); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected = []uuid.UUID{trimmed.Results[0], split.Results[0], split.Results[1], merged.Results[0]}
+	if diff := cmp.Diff(vods.GetByStreamID(broadcastId), expected); diff != "" {
+		t.Fatal(diff)
+	}
+
+	if len(vods.vods) != len(expected) {
+		t.Fatalf("expected the links of the sources to be removed, found %d links", len(vods.vods))
+	}
+}
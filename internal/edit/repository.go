@@ -0,0 +1,71 @@
+package edit
+
+import (
+	"context"
+	"errors"
+	"github.com/M-Ro/go-vodstream/internal/domain/edit"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrEditNotFound = errors.New("no video edit found")
+)
+
+type StorageProvider interface {
+	GetByID(ctx context.Context, id uuid.UUID) *storage.VideoEdit
+	Insert(ctx context.Context, edit *storage.VideoEdit) error
+	Update(ctx context.Context, id uuid.UUID, edit *storage.VideoEdit) error
+}
+
+type Repository struct {
+	StorageProvider StorageProvider
+}
+
+// GetByID returns the video edit with the given ID, or returns an error.
+func (r Repository) GetByID(ctx context.Context, id uuid.UUID) (edit.Edit, error) {
+	getEdit := r.StorageProvider.GetByID(ctx, id)
+	if getEdit == nil {
+		return edit.Edit{}, ErrEditNotFound
+	}
+
+	return storage.VideoEditToDomain(*getEdit), nil
+}
+
+// Insert takes a domain model and inserts it to the storage provider.
+// After successful insertion the edit ID and timestamps are filled.
+func (r Repository) Insert(ctx context.Context, newEdit *edit.Edit) error {
+	storageEdit := storage.VideoEditToStorage(*newEdit)
+
+	err := r.StorageProvider.Insert(ctx, &storageEdit)
+	if err != nil {
+		return err
+	}
+
+	newEdit.Id = storageEdit.Id
+	newEdit.CreatedAt = storageEdit.CreatedAt
+	newEdit.UpdatedAt = storageEdit.UpdatedAt
+
+	return nil
+}
+
+// Update takes a domain model and updates the stored edit with its ID.
+// After a successful update the edit's update time is filled.
+func (r Repository) Update(ctx context.Context, updated *edit.Edit) error {
+	storageEdit := storage.VideoEditToStorage(*updated)
+
+	err := r.StorageProvider.Update(ctx, updated.Id, &storageEdit)
+	if err != nil {
+		return err
+	}
+
+	updated.UpdatedAt = storageEdit.UpdatedAt
+
+	return nil
+}
+
+func NewRepository(s StorageProvider) Repository {
+	return Repository{
+		StorageProvider: s,
+	}
+}
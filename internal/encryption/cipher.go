@@ -0,0 +1,76 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+)
+
+var (
+	ErrEmptyKey         = errors.New("encryption key must not be empty")
+	ErrCiphertextLength = errors.New("ciphertext is too short")
+)
+
+// Cipher encrypts short secrets, such as stream keys, for storage at rest using AES-256-GCM.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// Encrypt returns the base64 encoded nonce and ciphertext of plaintext.
+func (c Cipher) Encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt reverses Encrypt. Returns an error if the ciphertext was modified or
+// encrypted with a different key.
+func (c Cipher) Decrypt(ciphertext string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", err
+	}
+
+	if len(sealed) < c.aead.NonceSize() {
+		return "", ErrCiphertextLength
+	}
+
+	nonce, sealed := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+
+	plaintext, err := c.aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
+
+// NewCipher instantiates a Cipher, deriving the AES key from the given configured key.
+func NewCipher(key string) (Cipher, error) {
+	if key == "" {
+		return Cipher{}, ErrEmptyKey
+	}
+
+	derived := sha256.Sum256([]byte(key))
+
+	block, err := aes.NewCipher(derived[:])
+	if err != nil {
+		return Cipher{}, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return Cipher{}, err
+	}
+
+	return Cipher{aead: aead}, nil
+}
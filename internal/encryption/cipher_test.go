@@ -0,0 +1,88 @@
+package encryption
+
+import (
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"testing"
+)
+
+func TestNewCipher(t *testing.T) {
+	_, err := NewCipher("")
+	if !cmp.Equal(err, ErrEmptyKey, cmpopts.EquateErrors()) {
+		t.Fatal(cmp.Diff(err, ErrEmptyKey, cmpopts.EquateErrors()))
+	}
+}
+
+func TestCipher_RoundTrip(t *testing.T) {
+	c, err := NewCipher("testKey")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, plaintext := range []string{"", "live_123456_abcdef"} {
+		ciphertext, err := c.Encrypt(plaintext)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if plaintext != "" && ciphertext == plaintext {
+			t.Fatal("ciphertext should not equal plaintext")
+		}
+
+		decrypted, err := c.Decrypt(ciphertext)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !cmp.Equal(decrypted, plaintext) {
+			t.Fatal(cmp.Diff(decrypted, plaintext))
+		}
+	}
+}
+
+func TestCipher_Decrypt_Fail(t *testing.T) {
+	c, err := NewCipher("testKey")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := NewCipher("otherKey")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ciphertext, err := c.Encrypt("live_123456_abcdef")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		testName   string
+		cipher     Cipher
+		ciphertext string
+	}{
+		{
+			testName:   "expect error with another key",
+			cipher:     other,
+			ciphertext: ciphertext,
+		},
+		{
+			testName:   "expect error with truncated ciphertext",
+			cipher:     c,
+			ciphertext: "AAAA",
+		},
+		{
+			testName:   "expect error with invalid encoding",
+			cipher:     c,
+			ciphertext: "not base64!",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			if _, err := test.cipher.Decrypt(test.ciphertext); err == nil {
+				t.Fatal("expected decryption to fail")
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package flvstream
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/gorilla/websocket"
+	"github.com/nareix/joy4/av"
+	"github.com/nareix/joy4/av/pubsub"
+	"github.com/nareix/joy4/format/flv"
+	"github.com/spf13/viper"
+	"io"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrViewerTooSlow = errors.New("viewer fell too far behind the live stream")
+)
+
+type Config struct {
+	// MaxPendingPackets is how many packets may be waiting to be written to a
+	// viewer before it is dropped.
+	MaxPendingPackets int
+
+	// WriteTimeout bounds how long a single write to a viewer may take.
+	WriteTimeout time.Duration
+}
+
+// WriteFlusher is a destination for FLV data. Flush is called whenever the
+// viewer has caught up with the stream, so buffered data should be sent.
+type WriteFlusher interface {
+	io.Writer
+	Flush() error
+}
+
+// deadlineSetter is implemented by the response writers of net/http servers
+// which can bound how long writing the response may take.
+type deadlineSetter interface {
+	SetWriteDeadline(deadline time.Time) error
+}
+
+// responseWriter streams FLV as the chunked body of an HTTP response. Each flush,
+// and the writes leading up to it, must complete within timeout, so that a viewer
+// which stops reading is dropped rather than holding its connection open.
+type responseWriter struct {
+	w        http.ResponseWriter
+	flusher  http.Flusher
+	deadline deadlineSetter // nil where the response has no write deadline
+	timeout  time.Duration
+
+	// Whether the deadline of the writes since the last flush has been set
+	armed bool
+}
+
+func (r *responseWriter) Write(p []byte) (int, error) {
+	if !r.armed {
+		if err := r.setDeadline(); err != nil {
+			return 0, err
+		}
+		r.armed = true
+	}
+
+	return r.w.Write(p)
+}
+
+func (r *responseWriter) Flush() error {
+	if err := r.setDeadline(); err != nil {
+		return err
+	}
+	r.armed = false
+
+	r.flusher.Flush()
+	return nil
+}
+
+// setDeadline bounds the writes to the response from now, where supported.
+func (r *responseWriter) setDeadline() error {
+	if r.timeout <= 0 || r.deadline == nil {
+		return nil
+	}
+
+	return r.deadline.SetWriteDeadline(time.Now().Add(r.timeout))
+}
+
+// NewResponseWriter returns a WriteFlusher writing to an HTTP response, where each
+// flush must complete within timeout, or false if the response cannot be flushed.
+func NewResponseWriter(w http.ResponseWriter, timeout time.Duration) (WriteFlusher, bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, false
+	}
+
+	deadline, _ := w.(deadlineSetter)
+
+	return &responseWriter{w: w, flusher: flusher, deadline: deadline, timeout: timeout}, true
+}
+
+// WebSocketWriter sends buffered FLV data as a binary message on each flush.
+type WebSocketWriter struct {
+	conn    *websocket.Conn
+	buf     bytes.Buffer
+	timeout time.Duration
+}
+
+func (ws *WebSocketWriter) Write(p []byte) (int, error) {
+	return ws.buf.Write(p)
+}
+
+func (ws *WebSocketWriter) Flush() error {
+	if ws.buf.Len() == 0 {
+		return nil
+	}
+
+	if err := ws.conn.SetWriteDeadline(time.Now().Add(ws.timeout)); err != nil {
+		return err
+	}
+
+	err := ws.conn.WriteMessage(websocket.BinaryMessage, ws.buf.Bytes())
+	ws.buf.Reset()
+
+	return err
+}
+
+// NewWebSocketWriter returns a WriteFlusher sending to conn, where each write
+// must complete within timeout.
+func NewWebSocketWriter(conn *websocket.Conn, timeout time.Duration) *WebSocketWriter {
+	return &WebSocketWriter{conn: conn, timeout: timeout}
+}
+
+// read forwards packets from the cursor until it ends, ctx is cancelled, or
+// packets is full, reporting why it stopped on errc.
+func read(ctx context.Context, cursor *pubsub.QueueCursor, packets chan<- av.Packet, errc chan<- error) {
+	defer close(packets)
+
+	for {
+		packet, err := cursor.ReadPacket()
+		if err != nil {
+			errc <- err
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			errc <- ctx.Err()
+			return
+		case packets <- packet:
+		default:
+			errc <- ErrViewerTooSlow
+			return
+		}
+	}
+}
+
+// Serve writes the queue to dst as FLV, starting at the latest video keyframe
+// with timestamps from zero. It returns nil once the channel ends, or an error if
+// ctx is cancelled, writing fails or the viewer falls more than
+// MaxPendingPackets behind. A slow viewer never holds up the channel.
+func Serve(ctx context.Context, dst WriteFlusher, queue *pubsub.Queue, config Config) error {
+	// DelayedGopCount positions the cursor just before the most recent
+	// keyframe, packets up to the keyframe are skipped below.
+	cursor := queue.DelayedGopCount(1)
+
+	streams, err := cursor.Streams()
+	if err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+
+	muxer := flv.NewMuxerWriteFlusher(dst)
+	if err := muxer.WriteHeader(streams); err != nil {
+		return err
+	}
+
+	if err := dst.Flush(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	packets := make(chan av.Packet, config.MaxPendingPackets)
+	errc := make(chan error, 1)
+	go read(ctx, cursor, packets, errc)
+
+	videoIdx := -1
+	for idx, stream := range streams {
+		if stream.Type().IsVideo() {
+			videoIdx = idx
+			break
+		}
+	}
+
+	started, base := videoIdx == -1, time.Duration(0)
+
+	for {
+		var packet av.Packet
+		var ok bool
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case packet, ok = <-packets:
+		}
+
+		if !ok {
+			break
+		}
+
+		if !started {
+			if int(packet.Idx) != videoIdx || !packet.IsKeyFrame {
+				continue
+			}
+			started, base = true, packet.Time
+		}
+
+		packet.Time -= base
+		if packet.Time < 0 {
+			packet.Time = 0
+		}
+
+		if err := muxer.WritePacket(packet); err != nil {
+			return err
+		}
+
+		if len(packets) == 0 {
+			if err := dst.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := <-errc; err != io.EOF {
+		return err
+	}
+
+	return muxer.WriteTrailer()
+}
+
+func GetConfig() Config {
+	viper.SetDefault("stream_ingester.flv.max_pending_packets", 1024)
+	viper.SetDefault("stream_ingester.flv.write_timeout", "10s")
+
+	return Config{
+		MaxPendingPackets: viper.GetInt("stream_ingester.flv.max_pending_packets"),
+		WriteTimeout:      viper.GetDuration("stream_ingester.flv.write_timeout"),
+	}
+}
@@ -0,0 +1,253 @@
+package flvstream
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/M-Ro/go-vodstream/internal/avtest"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/nareix/joy4/av/pubsub"
+	"github.com/nareix/joy4/format/flv"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testConfig = Config{MaxPendingPackets: 16, WriteTimeout: time.Second}
+
+// bufferWriter collects everything written to it.
+type bufferWriter struct {
+	lock sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (b *bufferWriter) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *bufferWriter) Flush() error {
+	return nil
+}
+
+// stalledWriter blocks every write after the header has been flushed until released.
+type stalledWriter struct {
+	lock    sync.Mutex
+	flushed bool
+	release chan struct{}
+}
+
+func (s *stalledWriter) Write(p []byte) (int, error) {
+	s.lock.Lock()
+	flushed := s.flushed
+	s.lock.Unlock()
+
+	if flushed {
+		<-s.release
+	}
+
+	return len(p), nil
+}
+
+func (s *stalledWriter) Flush() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.flushed = true
+	return nil
+}
+
+func TestServe_WritesFromLatestKeyframe(t *testing.T) {
+	queue := pubsub.NewQueue()
+	if err := queue.WriteHeader(avtest.Streams()); err != nil {
+		t.Fatal(err)
+	}
+
+	packets := avtest.Packets(100, 25)
+	for _, packet := range packets[:30] {
+		queue.WritePacket(packet)
+	}
+
+	dst := &bufferWriter{}
+	done := make(chan error, 1)
+	go func() {
+		done <- Serve(context.Background(), dst, queue, Config{MaxPendingPackets: 1024})
+	}()
+
+	// Wait for the viewer to take its position before more packets arrive.
+	time.Sleep(50 * time.Millisecond)
+	for _, packet := range packets[30:] {
+		queue.WritePacket(packet)
+		time.Sleep(100 * time.Microsecond)
+	}
+	queue.Close()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for serve to finish")
+	}
+
+	demuxer := flv.NewDemuxer(bytes.NewReader(dst.buf.Bytes()))
+
+	streams, err := demuxer.Streams()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(streams) != len(avtest.Streams()) {
+		t.Fatal(cmp.Diff(len(streams), len(avtest.Streams())))
+	}
+
+	first, err := demuxer.ReadPacket()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !first.IsKeyFrame || first.Idx != avtest.VideoIdx {
+		t.Fatal("expected the first packet to be a video keyframe")
+	}
+
+	if first.Time != 0 {
+		t.Fatal(cmp.Diff(first.Time, time.Duration(0)))
+	}
+
+	count := 1
+	for {
+		if _, err := demuxer.ReadPacket(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		count++
+	}
+
+	// The viewer starts at the keyframe opening the GOP in progress.
+	if count != len(packets) {
+		t.Fatal(cmp.Diff(count, len(packets)))
+	}
+}
+
+func TestServe_DropsSlowViewer(t *testing.T) {
+	queue := pubsub.NewQueue()
+	if err := queue.WriteHeader(avtest.Streams()); err != nil {
+		t.Fatal(err)
+	}
+	defer queue.Close()
+
+	dst := &stalledWriter{release: make(chan struct{})}
+	done := make(chan error, 1)
+	go func() {
+		done <- Serve(context.Background(), dst, queue, testConfig)
+	}()
+
+	// Writing to the channel must never block on the stalled viewer.
+	written := make(chan struct{})
+	go func() {
+		for _, packet := range avtest.Packets(200, 25) {
+			queue.WritePacket(packet)
+			time.Sleep(time.Millisecond)
+		}
+		close(written)
+	}()
+
+	select {
+	case <-written:
+	case <-time.After(5 * time.Second):
+		t.Fatal("channel was held up by a slow viewer")
+	}
+
+	close(dst.release)
+
+	select {
+	case err := <-done:
+		if !cmp.Equal(err, ErrViewerTooSlow, cmpopts.EquateErrors()) {
+			t.Fatal(cmp.Diff(err, ErrViewerTooSlow, cmpopts.EquateErrors()))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the slow viewer to be dropped")
+	}
+}
+
+func TestServe_StopsOnCancel(t *testing.T) {
+	queue := pubsub.NewQueue()
+	if err := queue.WriteHeader(avtest.Streams()); err != nil {
+		t.Fatal(err)
+	}
+	defer queue.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- Serve(ctx, &bufferWriter{}, queue, testConfig)
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		if !cmp.Equal(err, context.Canceled, cmpopts.EquateErrors()) {
+			t.Fatal(cmp.Diff(err, context.Canceled, cmpopts.EquateErrors()))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for serve to stop")
+	}
+}
+
+func TestResponseWriter_DropsStalledViewer(t *testing.T) {
+	done := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dst, ok := NewResponseWriter(w, 100*time.Millisecond)
+		if !ok {
+			done <- errors.New("response cannot be flushed")
+			return
+		}
+
+		// Write until the connection's buffers fill and a write times out.
+		chunk := make([]byte, 64*1024)
+		for {
+			if _, err := dst.Write(chunk); err != nil {
+				done <- err
+				return
+			}
+
+			if err := dst.Flush(); err != nil {
+				done <- err
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	// The viewer requests the stream, then never reads the response.
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("GET / HTTP/1.1\r\nHost: viewer\r\n\r\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-done:
+		if !cmp.Equal(err, os.ErrDeadlineExceeded, cmpopts.EquateErrors()) {
+			t.Fatal(cmp.Diff(err, os.ErrDeadlineExceeded, cmpopts.EquateErrors()))
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the stalled viewer to be dropped")
+	}
+}
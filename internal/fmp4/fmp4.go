@@ -0,0 +1,380 @@
+// Package fmp4 writes single track fragmented MP4 (CMAF) init segments and
+// fragments, shared by DASH manifests and fMP4 HLS playlists.
+package fmp4
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/nareix/joy4/av"
+	"github.com/nareix/joy4/codec/aacparser"
+	"github.com/nareix/joy4/codec/h264parser"
+	"github.com/nareix/joy4/format/mp4/mp4io"
+	"time"
+)
+
+var (
+	ErrUnsupportedCodec = errors.New("codec is not supported in fragmented mp4")
+)
+
+// videoTimescale is the timescale of video tracks, matching MPEG-TS.
+const videoTimescale = 90000
+
+// trackId is the id of the single track in every init segment and fragment.
+const trackId = 1
+
+// Sample flags marking a sample as a sync sample, or as depending on others.
+const (
+	syncSampleFlags    = 0x02000000
+	nonSyncSampleFlags = 0x01010000
+)
+
+// trun flags for the fields written per sample.
+const (
+	trunDataOffset        = 0x000001
+	trunSampleDuration    = 0x000100
+	trunSampleSize        = 0x000200
+	trunSampleFlags       = 0x000400
+	trunCompositionOffset = 0x000800
+)
+
+// tfhdDefaultBaseIsMoof makes sample data offsets relative to the moof.
+const tfhdDefaultBaseIsMoof = 0x020000
+
+// InitURI returns the URI of the init segment of a track, relative to the manifest.
+func InitURI(track int) string {
+	return fmt.Sprintf("init%d.mp4", track)
+}
+
+// SegmentURI returns the URI of a track's fragment of a segment, relative to the manifest.
+func SegmentURI(sequence int, track int) string {
+	return fmt.Sprintf("seg%d.%d.m4s", sequence, track)
+}
+
+// SegmentTemplate returns the DASH segment template matching SegmentURI for a track.
+func SegmentTemplate(track int) string {
+	return fmt.Sprintf("seg$Number$.%d.m4s", track)
+}
+
+// Track is a single audio or video stream written as CMAF.
+type Track struct {
+	Codec av.CodecData
+
+	// Timescale is the number of ticks per second sample times are written in.
+	Timescale uint32
+}
+
+// NewTrack returns the track for a stream, or ErrUnsupportedCodec.
+func NewTrack(codec av.CodecData) (Track, error) {
+	switch codec := codec.(type) {
+	case h264parser.CodecData:
+		return Track{Codec: codec, Timescale: videoTimescale}, nil
+	case aacparser.CodecData:
+		return Track{Codec: codec, Timescale: uint32(codec.SampleRate())}, nil
+	default:
+		return Track{}, ErrUnsupportedCodec
+	}
+}
+
+// IsVideo returns true for video tracks.
+func (t Track) IsVideo() bool {
+	return t.Codec.Type().IsVideo()
+}
+
+// Codecs returns the RFC 6381 codecs parameter of the track.
+func (t Track) Codecs() string {
+	switch codec := t.Codec.(type) {
+	case h264parser.CodecData:
+		sps := codec.SPS()
+		if len(sps) < 4 {
+			return "avc1"
+		}
+		return fmt.Sprintf("avc1.%02x%02x%02x", sps[1], sps[2], sps[3])
+	case aacparser.CodecData:
+		return fmt.Sprintf("mp4a.40.%d", codec.Config.ObjectType)
+	default:
+		return ""
+	}
+}
+
+// ticks converts a duration to the track timescale.
+func (t Track) ticks(d time.Duration) uint64 {
+	if d < 0 {
+		return 0
+	}
+
+	return uint64(d) * uint64(t.Timescale) / uint64(time.Second)
+}
+
+// box returns an ISO BMFF box of the given type wrapping the payloads.
+func box(boxType string, payloads ...[]byte) []byte {
+	size := 8
+	for _, payload := range payloads {
+		size += len(payload)
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, size))
+	binary.Write(buf, binary.BigEndian, uint32(size))
+	buf.WriteString(boxType)
+	for _, payload := range payloads {
+		buf.Write(payload)
+	}
+
+	return buf.Bytes()
+}
+
+// fullBox returns an ISO BMFF full box, with a version and flags before the payloads.
+func fullBox(boxType string, version uint8, flags uint32, payloads ...[]byte) []byte {
+	header := make([]byte, 4)
+	binary.BigEndian.PutUint32(header, uint32(version)<<24|flags&0xffffff)
+
+	return box(boxType, append([][]byte{header}, payloads...)...)
+}
+
+// fileType returns the ftyp box marking the file as CMAF.
+func fileType() []byte {
+	payload := &bytes.Buffer{}
+	payload.WriteString("iso6")
+	binary.Write(payload, binary.BigEndian, uint32(0))
+	for _, brand := range []string{"iso6", "cmfc", "dash", "mp41"} {
+		payload.WriteString(brand)
+	}
+
+	return box("ftyp", payload.Bytes())
+}
+
+// trackAtom returns the trak of the track, with an empty sample table as
+// samples are carried in fragments.
+func (t Track) trackAtom() *mp4io.Track {
+	sample := &mp4io.SampleTable{
+		SampleDesc:    &mp4io.SampleDesc{},
+		TimeToSample:  &mp4io.TimeToSample{},
+		SampleToChunk: &mp4io.SampleToChunk{},
+		SampleSize:    &mp4io.SampleSize{},
+		ChunkOffset:   &mp4io.ChunkOffset{},
+	}
+
+	track := &mp4io.Track{
+		Header: &mp4io.TrackHeader{
+			TrackId: trackId,
+			Flags:   0x0003, // Track enabled | Track in movie
+			Matrix:  [9]int32{0x10000, 0, 0, 0, 0x10000, 0, 0, 0, 0x40000000},
+		},
+		Media: &mp4io.Media{
+			Header: &mp4io.MediaHeader{
+				TimeScale: int32(t.Timescale),
+				Language:  21956,
+			},
+			Info: &mp4io.MediaInfo{
+				Sample: sample,
+				Data: &mp4io.DataInfo{
+					Refer: &mp4io.DataRefer{
+						Url: &mp4io.DataReferUrl{
+							Flags: 0x000001, // Self reference
+						},
+					},
+				},
+			},
+		},
+	}
+
+	switch codec := t.Codec.(type) {
+	case h264parser.CodecData:
+		width, height := codec.Width(), codec.Height()
+		sample.SampleDesc.AVC1Desc = &mp4io.AVC1Desc{
+			DataRefIdx:           1,
+			HorizontalResolution: 72,
+			VorizontalResolution: 72,
+			Width:                int16(width),
+			Height:               int16(height),
+			FrameCount:           1,
+			Depth:                24,
+			ColorTableId:         -1,
+			Conf:                 &mp4io.AVC1Conf{Data: codec.AVCDecoderConfRecordBytes()},
+		}
+		track.Media.Handler = &mp4io.HandlerRefer{
+			SubType: [4]byte{'v', 'i', 'd', 'e'},
+			Name:    []byte("Video Media Handler"),
+		}
+		track.Media.Info.Video = &mp4io.VideoMediaInfo{Flags: 0x000001}
+		track.Header.TrackWidth = float64(width)
+		track.Header.TrackHeight = float64(height)
+
+	case aacparser.CodecData:
+		sample.SampleDesc.MP4ADesc = &mp4io.MP4ADesc{
+			DataRefIdx:       1,
+			NumberOfChannels: int16(codec.ChannelLayout().Count()),
+			SampleSize:       int16(codec.SampleFormat().BytesPerSample()),
+			SampleRate:       float64(codec.SampleRate()),
+			Conf: &mp4io.ElemStreamDesc{
+				DecConfig: codec.MPEG4AudioConfigBytes(),
+			},
+		}
+		track.Header.Volume = 1
+		track.Header.AlternateGroup = 1
+		track.Media.Handler = &mp4io.HandlerRefer{
+			SubType: [4]byte{'s', 'o', 'u', 'n'},
+			Name:    []byte("Sound Handler"),
+		}
+		track.Media.Info.Sound = &mp4io.SoundMediaInfo{}
+	}
+
+	return track
+}
+
+// InitSegment returns the ftyp and moov boxes describing the track.
+func (t Track) InitSegment() []byte {
+	movie := &mp4io.Movie{
+		Header: &mp4io.MovieHeader{
+			TimeScale:       1000,
+			PreferredRate:   1,
+			PreferredVolume: 1,
+			Matrix:          [9]int32{0x10000, 0, 0, 0, 0x10000, 0, 0, 0, 0x40000000},
+			NextTrackId:     trackId + 1,
+		},
+		MovieExtend: &mp4io.MovieExtend{
+			Tracks: []*mp4io.TrackExtend{
+				{TrackId: trackId, DefaultSampleDescIdx: 1},
+			},
+		},
+		Tracks: []*mp4io.Track{t.trackAtom()},
+	}
+
+	moov := make([]byte, movie.Len())
+	movie.Marshal(moov)
+
+	return append(fileType(), moov...)
+}
+
+// Sample is a single frame of a track.
+type Sample struct {
+	Time            time.Duration
+	Duration        time.Duration
+	CompositionTime time.Duration
+	KeyFrame        bool
+	Data            []byte
+}
+
+// Fragment returns a moof and mdat box carrying the samples, numbered by sequence.
+func (t Track) Fragment(sequence uint32, samples []Sample) []byte {
+	if len(samples) == 0 {
+		return nil
+	}
+
+	mdatSize := 0
+	for _, sample := range samples {
+		mdatSize += len(sample.Data)
+	}
+
+	run := func(dataOffset uint32) []byte {
+		buf := &bytes.Buffer{}
+		binary.Write(buf, binary.BigEndian, uint32(len(samples)))
+		binary.Write(buf, binary.BigEndian, dataOffset)
+
+		for _, sample := range samples {
+			// Durations are taken between converted times so rounding never drifts.
+			duration := t.ticks(sample.Time+sample.Duration) - t.ticks(sample.Time)
+
+			flags := uint32(syncSampleFlags)
+			if t.IsVideo() && !sample.KeyFrame {
+				flags = nonSyncSampleFlags
+			}
+
+			binary.Write(buf, binary.BigEndian, uint32(duration))
+			binary.Write(buf, binary.BigEndian, uint32(len(sample.Data)))
+			binary.Write(buf, binary.BigEndian, flags)
+			binary.Write(buf, binary.BigEndian, int32(t.ticks(sample.CompositionTime)))
+		}
+
+		return fullBox("trun", 1,
+			trunDataOffset|trunSampleDuration|trunSampleSize|trunSampleFlags|trunCompositionOffset, buf.Bytes())
+	}
+
+	header := make([]byte, 4)
+	binary.BigEndian.PutUint32(header, trackId)
+
+	decodeTime := make([]byte, 8)
+	binary.BigEndian.PutUint64(decodeTime, t.ticks(samples[0].Time))
+
+	seqnum := make([]byte, 4)
+	binary.BigEndian.PutUint32(seqnum, sequence)
+
+	moof := func(dataOffset uint32) []byte {
+		return box("moof",
+			fullBox("mfhd", 0, 0, seqnum),
+			box("traf",
+				fullBox("tfhd", 0, tfhdDefaultBaseIsMoof, header),
+				fullBox("tfdt", 1, 0, decodeTime),
+				run(dataOffset),
+			),
+		)
+	}
+
+	// Sample data starts after the moof and the mdat header, the moof is the
+	// same size whatever the offset.
+	fragment := moof(uint32(len(moof(0)) + 8))
+
+	mdat := make([][]byte, 0, len(samples))
+	for _, sample := range samples {
+		mdat = append(mdat, sample.Data)
+	}
+
+	return append(fragment, box("mdat", mdat...)...)
+}
+
+// Fragmenter collects the packets of a track into samples between fragments.
+type Fragmenter struct {
+	track   Track
+	samples []Sample
+
+	// duration is the last known sample duration, given to the final sample of
+	// a fragment as its successor is not yet known.
+	duration time.Duration
+}
+
+// WritePacket adds a packet of the track to the fragment being collected.
+func (f *Fragmenter) WritePacket(packet av.Packet) {
+	if n := len(f.samples); n > 0 {
+		if duration := packet.Time - f.samples[n-1].Time; duration > 0 {
+			f.samples[n-1].Duration = duration
+			f.duration = duration
+		}
+	}
+
+	f.samples = append(f.samples, Sample{
+		Time:            packet.Time,
+		CompositionTime: packet.CompositionTime,
+		KeyFrame:        packet.IsKeyFrame,
+		Data:            packet.Data,
+	})
+}
+
+// Flush returns the collected samples as a fragment numbered by sequence, or
+// nil if there are none.
+func (f *Fragmenter) Flush(sequence uint32) []byte {
+	if len(f.samples) == 0 {
+		return nil
+	}
+
+	f.samples[len(f.samples)-1].Duration = f.duration
+
+	fragment := f.track.Fragment(sequence, f.samples)
+	f.samples = nil
+
+	return fragment
+}
+
+// NewFragmenter instantiates a Fragmenter for the track.
+func NewFragmenter(track Track) *Fragmenter {
+	// Until two samples have been seen, assume 30fps video or 1024 sample AAC frames.
+	duration := time.Second / 30
+	if codec, ok := track.Codec.(aacparser.CodecData); ok {
+		duration = time.Duration(1024) * time.Second / time.Duration(codec.SampleRate())
+	}
+
+	return &Fragmenter{
+		track:    track,
+		duration: duration,
+	}
+}
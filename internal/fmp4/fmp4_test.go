@@ -0,0 +1,145 @@
+package fmp4
+
+import (
+	"encoding/binary"
+	"github.com/M-Ro/go-vodstream/internal/avtest"
+	"github.com/google/go-cmp/cmp"
+	"testing"
+	"time"
+)
+
+// boxes splits data into its top level boxes, keyed by type in order.
+func boxes(t *testing.T, data []byte) ([]string, map[string][]byte) {
+	var types []string
+	payloads := make(map[string][]byte)
+
+	for len(data) > 0 {
+		if len(data) < 8 {
+			t.Fatalf("truncated box header: %d bytes", len(data))
+		}
+
+		size := int(binary.BigEndian.Uint32(data))
+		if size < 8 || size > len(data) {
+			t.Fatalf("box size %d out of range of %d bytes", size, len(data))
+		}
+
+		boxType := string(data[4:8])
+		types = append(types, boxType)
+		payloads[boxType] = data[8:size]
+		data = data[size:]
+	}
+
+	return types, payloads
+}
+
+func TestNewTrack(t *testing.T) {
+	streams := avtest.Streams()
+
+	video, err := NewTrack(streams[avtest.VideoIdx])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if video.Timescale != 90000 || !video.IsVideo() {
+		t.Fatalf("unexpected video track: %+v", video)
+	}
+
+	audio, err := NewTrack(streams[avtest.AudioIdx])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if audio.IsVideo() || audio.Codecs() != "mp4a.40.2" {
+		t.Fatalf("unexpected audio track: %+v, %s", audio, audio.Codecs())
+	}
+}
+
+func TestTrack_InitSegment(t *testing.T) {
+	for _, stream := range avtest.Streams() {
+		track, err := NewTrack(stream)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		types, _ := boxes(t, track.InitSegment())
+		if diff := cmp.Diff(types, []string{"ftyp", "moov"}); diff != "" {
+			t.Fatal(diff)
+		}
+	}
+}
+
+func TestFragmenter(t *testing.T) {
+	track, err := NewTrack(avtest.Streams()[avtest.VideoIdx])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fragmenter := NewFragmenter(track)
+
+	if fragment := fragmenter.Flush(1); fragment != nil {
+		t.Fatal("expected no fragment without samples")
+	}
+
+	var samples []byte
+	count := 0
+
+	for _, packet := range avtest.Packets(50, 25) {
+		if int(packet.Idx) != avtest.VideoIdx {
+			continue
+		}
+
+		fragmenter.WritePacket(packet)
+		samples = append(samples, packet.Data...)
+		count++
+
+		if count == 10 {
+			break
+		}
+	}
+
+	fragment := fragmenter.Flush(3)
+
+	types, payloads := boxes(t, fragment)
+	if diff := cmp.Diff(types, []string{"moof", "mdat"}); diff != "" {
+		t.Fatal(diff)
+	}
+
+	if diff := cmp.Diff(payloads["mdat"], samples); diff != "" {
+		t.Fatal(diff)
+	}
+
+	_, moof := boxes(t, payloads["moof"])
+
+	if sequence := binary.BigEndian.Uint32(moof["mfhd"][4:]); sequence != 3 {
+		t.Fatal(cmp.Diff(sequence, uint32(3)))
+	}
+
+	_, traf := boxes(t, moof["traf"])
+
+	trun := traf["trun"]
+	if entries := binary.BigEndian.Uint32(trun[4:]); entries != uint32(count) {
+		t.Fatal(cmp.Diff(entries, uint32(count)))
+	}
+
+	// The data offset points from the start of the moof to the sample data.
+	offset := int(binary.BigEndian.Uint32(trun[8:]))
+	if diff := cmp.Diff(fragment[offset:], samples); diff != "" {
+		t.Fatal(diff)
+	}
+
+	// Every sample, including the last, lasts one frame.
+	frame := uint32(track.ticks(avtest.FrameDuration))
+	for i := 0; i < count; i++ {
+		if duration := binary.BigEndian.Uint32(trun[12+16*i:]); duration != frame {
+			t.Fatalf("sample %d: %s", i, cmp.Diff(duration, frame))
+		}
+	}
+
+	if decodeTime := binary.BigEndian.Uint64(traf["tfdt"][4:]); decodeTime != 0 {
+		t.Fatal(cmp.Diff(decodeTime, uint64(0)))
+	}
+
+	if ticks := track.ticks(-time.Second); ticks != 0 {
+		t.Fatal(cmp.Diff(ticks, uint64(0)))
+	}
+}
@@ -0,0 +1,362 @@
+package hls
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/M-Ro/go-vodstream/internal/dash"
+	"github.com/M-Ro/go-vodstream/internal/fmp4"
+	"github.com/nareix/joy4/codec/h264parser"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TrackURI returns the URI of the fMP4 media playlist of a track, relative to
+// the multivariant playlist.
+func TrackURI(track int) string {
+	return fmt.Sprintf("track%d.m3u8", track)
+}
+
+// Rendition is a CMAF track listed in a multivariant playlist.
+type Rendition struct {
+	// Index numbers the track in its playlist, init segment and fragment URIs.
+	Index int
+	Track fmp4.Track
+
+	// Bandwidth is the peak bitrate of the track, in bits per second.
+	Bandwidth int
+}
+
+// audioGroup is the group id audio renditions are listed under.
+const audioGroup = "audio"
+
+// Multivariant renders the multivariant playlist over CMAF tracks. Video
+// tracks are listed as variant streams carrying the audio tracks as
+// alternative renditions. Audio only channels list their audio as the variant.
+func Multivariant(renditions []Rendition, query string) []byte {
+	var video, audio []Rendition
+	for _, rendition := range renditions {
+		if rendition.Track.IsVideo() {
+			video = append(video, rendition)
+		} else {
+			audio = append(audio, rendition)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteString("#EXTM3U\n")
+	buf.WriteString("#EXT-X-VERSION:7\n")
+	buf.WriteString("#EXT-X-INDEPENDENT-SEGMENTS\n")
+
+	if len(video) == 0 {
+		for _, rendition := range audio {
+			fmt.Fprintf(buf, "#EXT-X-STREAM-INF:BANDWIDTH=%d,CODECS=\"%s\"\n", rendition.Bandwidth,
+				rendition.Track.Codecs())
+			fmt.Fprintf(buf, "%s\n", withQuery(TrackURI(rendition.Index), query))
+		}
+
+		return buf.Bytes()
+	}
+
+	audioBandwidth := 0
+	var audioCodecs []string
+
+	for i, rendition := range audio {
+		isDefault := "NO"
+		if i == 0 {
+			isDefault = "YES"
+		}
+
+		fmt.Fprintf(buf, "#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"%s\",NAME=\"audio%d\",DEFAULT=%s,AUTOSELECT=YES,URI=\"%s\"\n",
+			audioGroup, rendition.Index, isDefault, withQuery(TrackURI(rendition.Index), query))
+
+		if rendition.Bandwidth > audioBandwidth {
+			audioBandwidth = rendition.Bandwidth
+		}
+		audioCodecs = append(audioCodecs, rendition.Track.Codecs())
+	}
+
+	for _, rendition := range video {
+		codecs := append([]string{rendition.Track.Codecs()}, audioCodecs...)
+		fmt.Fprintf(buf, "#EXT-X-STREAM-INF:BANDWIDTH=%d,CODECS=\"%s\"", rendition.Bandwidth+audioBandwidth,
+			strings.Join(codecs, ","))
+
+		if codec, ok := rendition.Track.Codec.(h264parser.CodecData); ok {
+			fmt.Fprintf(buf, ",RESOLUTION=%dx%d", codec.Width(), codec.Height())
+		}
+
+		if len(audio) > 0 {
+			fmt.Fprintf(buf, ",AUDIO=\"%s\"", audioGroup)
+		}
+
+		fmt.Fprintf(buf, "\n%s\n", withQuery(TrackURI(rendition.Index), query))
+	}
+
+	return buf.Bytes()
+}
+
+// TrackSegment is a segment listed in a track playlist.
+type TrackSegment struct {
+	Sequence int
+	Duration time.Duration
+}
+
+// TrackPlaylist is the fMP4 media playlist of a single CMAF track.
+type TrackPlaylist struct {
+	Track    int
+	Segments []TrackSegment
+
+	// TargetDuration is advertised as the target duration if set, otherwise
+	// the longest segment is.
+	TargetDuration time.Duration
+
+	// VOD marks the playlist as complete and unchanging, Ended marks a live
+	// playlist that will not grow further. Event marks a live playlist that
+	// only ever grows, as segments are never removed from it.
+	VOD   bool
+	Ended bool
+	Event bool
+
+	// Offset starts playback this far behind the end of the playlist, if set.
+	Offset time.Duration
+
+	// Query is appended to every URI in the playlist.
+	Query string
+}
+
+// Marshal renders the track playlist.
+func (t TrackPlaylist) Marshal() []byte {
+	target := t.TargetDuration
+	sequence := 0
+
+	for _, segment := range t.Segments {
+		if segment.Duration > target {
+			target = segment.Duration
+		}
+	}
+
+	if len(t.Segments) > 0 {
+		sequence = t.Segments[0].Sequence
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteString("#EXTM3U\n")
+	buf.WriteString("#EXT-X-VERSION:7\n")
+	fmt.Fprintf(buf, "#EXT-X-TARGETDURATION:%d\n", int(ceilSecond(target).Seconds()))
+	fmt.Fprintf(buf, "#EXT-X-MEDIA-SEQUENCE:%d\n", sequence)
+
+	if t.VOD {
+		buf.WriteString("#EXT-X-PLAYLIST-TYPE:VOD\n")
+	} else if t.Event {
+		buf.WriteString("#EXT-X-PLAYLIST-TYPE:EVENT\n")
+	}
+
+	buf.WriteString("#EXT-X-INDEPENDENT-SEGMENTS\n")
+	writeStart(buf, t.Offset)
+	fmt.Fprintf(buf, "#EXT-X-MAP:URI=\"%s\"\n", withQuery(fmp4.InitURI(t.Track), t.Query))
+
+	for _, segment := range t.Segments {
+		fmt.Fprintf(buf, "#EXTINF:%s,\n", seconds(segment.Duration))
+		fmt.Fprintf(buf, "%s\n", withQuery(fmp4.SegmentURI(segment.Sequence, t.Track), t.Query))
+	}
+
+	if t.VOD || t.Ended {
+		buf.WriteString("#EXT-X-ENDLIST\n")
+	}
+
+	return buf.Bytes()
+}
+
+// cmafReady returns true once the first segment is complete, and so its CMAF
+// fragments can be listed. The lock must be held.
+func (p *Packager) cmafReady() bool {
+	return len(p.segments) > 0 && p.segments[0].complete()
+}
+
+// waitCMAF waits for the first complete segment, returning
+// fmp4.ErrUnsupportedCodec if the channel has streams CMAF cannot carry.
+func (p *Packager) waitCMAF(ctx context.Context) error {
+	p.touch()
+
+	ctx, cancel := context.WithTimeout(ctx, p.blockTimeout())
+	defer cancel()
+
+	if err := p.wait(ctx, p.cmafReady); err != nil {
+		return err
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.tracks == nil {
+		return fmp4.ErrUnsupportedCodec
+	}
+
+	return nil
+}
+
+// listed returns the complete segments within the playlist window. The lock
+// must be held.
+func (p *Packager) listed() []*segment {
+	complete := p.segments
+	if last := p.last(); last != nil && !last.complete() {
+		complete = complete[:len(complete)-1]
+	}
+
+	return p.window(complete)
+}
+
+// bandwidth estimates the peak bitrate of a track over the retained segments.
+// The lock must be held.
+func (p *Packager) bandwidth(track int) int {
+	peak := 0
+
+	for _, segment := range p.segments {
+		if !segment.complete() || segment.duration <= 0 || track >= len(segment.fragments) {
+			continue
+		}
+
+		bits := float64(8 * len(segment.fragments[track]))
+		if rate := int(bits / segment.duration.Seconds()); rate > peak {
+			peak = rate
+		}
+	}
+
+	return peak
+}
+
+// Init returns the CMAF init segment of a track.
+func (p *Packager) Init(ctx context.Context, track int) ([]byte, error) {
+	if err := p.waitCMAF(ctx); err != nil {
+		return nil, err
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if track < 0 || track >= len(p.tracks) {
+		return nil, ErrNotAvailable
+	}
+
+	return p.tracks[track].InitSegment(), nil
+}
+
+// Fragment returns the CMAF fragment of a track for the segment with the given
+// sequence number, waiting for the segment to complete if it is still being
+// written.
+func (p *Packager) Fragment(ctx context.Context, sequence int, track int) ([]byte, error) {
+	p.touch()
+
+	if err := p.await(ctx, sequence, -1); err != nil {
+		return nil, err
+	}
+
+	p.lock.Lock()
+
+	segment, err := p.find(sequence)
+	if err != nil || !segment.complete() || track < 0 {
+		p.lock.Unlock()
+		return nil, ErrNotAvailable
+	}
+
+	// Stored segments may have had their fragments dropped from memory.
+	if segment.data == nil {
+		p.lock.Unlock()
+		return p.load(ctx, fmp4.SegmentURI(sequence, track))
+	}
+
+	defer p.lock.Unlock()
+
+	if track >= len(segment.fragments) || segment.fragments[track] == nil {
+		return nil, ErrNotAvailable
+	}
+
+	return segment.fragments[track], nil
+}
+
+// Manifest renders the dynamic DASH manifest over the segments in the
+// playlist window.
+func (p *Packager) Manifest(ctx context.Context, query url.Values) ([]byte, error) {
+	if err := p.waitCMAF(ctx); err != nil {
+		return nil, err
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	manifest := dash.Manifest{
+		Dynamic:               true,
+		AvailabilityStartTime: p.epoch,
+		PublishTime:           time.Now(),
+		MinimumUpdatePeriod:   p.targetDuration,
+		MinBufferTime:         p.targetDuration,
+		Query:                 query.Encode(),
+	}
+
+	for idx, track := range p.tracks {
+		manifest.Representations = append(manifest.Representations, dash.Representation{
+			Index:     idx,
+			Track:     track,
+			Bandwidth: p.bandwidth(idx),
+		})
+	}
+
+	for _, segment := range p.listed() {
+		manifest.Segments = append(manifest.Segments, dash.Segment{
+			Sequence: segment.sequence,
+			Start:    segment.start,
+			Duration: segment.duration,
+		})
+		manifest.TimeShiftBufferDepth += segment.duration
+	}
+
+	return manifest.Marshal()
+}
+
+// Multivariant renders the multivariant playlist over the channel's CMAF tracks.
+func (p *Packager) Multivariant(ctx context.Context, query url.Values) ([]byte, error) {
+	if err := p.waitCMAF(ctx); err != nil {
+		return nil, err
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	renditions := make([]Rendition, 0, len(p.tracks))
+	for idx, track := range p.tracks {
+		renditions = append(renditions, Rendition{Index: idx, Track: track, Bandwidth: p.bandwidth(idx)})
+	}
+
+	return Multivariant(renditions, query.Encode()), nil
+}
+
+// TrackPlaylist renders the fMP4 media playlist of a track, listing the same
+// segments as the MPEG-TS playlist. Delivery directives other than the start
+// offset are ignored.
+func (p *Packager) TrackPlaylist(ctx context.Context, track int, request PlaylistRequest) ([]byte, error) {
+	if err := p.waitCMAF(ctx); err != nil {
+		return nil, err
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if track < 0 || track >= len(p.tracks) {
+		return nil, ErrNotAvailable
+	}
+
+	playlist := TrackPlaylist{
+		Track:          track,
+		TargetDuration: p.targetDuration,
+		Ended:          p.ended,
+		Event:          p.event(),
+		Offset:         request.Offset,
+		Query:          request.Query.Encode(),
+	}
+	for _, segment := range p.listed() {
+		playlist.Segments = append(playlist.Segments, TrackSegment{Sequence: segment.sequence, Duration: segment.duration})
+	}
+
+	return playlist.Marshal(), nil
+}
@@ -0,0 +1,83 @@
+package hls
+
+import (
+	"github.com/M-Ro/go-vodstream/internal/avtest"
+	"github.com/M-Ro/go-vodstream/internal/fmp4"
+	"github.com/google/go-cmp/cmp"
+	"testing"
+	"time"
+)
+
+func TestMultivariant(t *testing.T) {
+	streams := avtest.Streams()
+
+	video, err := fmp4.NewTrack(streams[avtest.VideoIdx])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	audio, err := fmp4.NewTrack(streams[avtest.AudioIdx])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		testName   string
+		renditions []Rendition
+		expected   string
+	}{
+		{
+			testName: "expect audio as an alternative rendition of video",
+			renditions: []Rendition{
+				{Index: 0, Track: video, Bandwidth: 2000000},
+				{Index: 1, Track: audio, Bandwidth: 128000},
+			},
+			expected: "#EXTM3U\n" +
+				"#EXT-X-VERSION:7\n" +
+				"#EXT-X-INDEPENDENT-SEGMENTS\n" +
+				"#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"audio\",NAME=\"audio1\",DEFAULT=YES,AUTOSELECT=YES,URI=\"track1.m3u8?token=abc\"\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=2128000,CODECS=\"avc1.64001f,mp4a.40.2\",RESOLUTION=1280x720,AUDIO=\"audio\"\n" +
+				"track0.m3u8?token=abc\n",
+		},
+		{
+			testName:   "expect audio only channels to list audio as the variant",
+			renditions: []Rendition{{Index: 0, Track: audio, Bandwidth: 128000}},
+			expected: "#EXTM3U\n" +
+				"#EXT-X-VERSION:7\n" +
+				"#EXT-X-INDEPENDENT-SEGMENTS\n" +
+				"#EXT-X-STREAM-INF:BANDWIDTH=128000,CODECS=\"mp4a.40.2\"\n" +
+				"track0.m3u8?token=abc\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			if diff := cmp.Diff(string(Multivariant(test.renditions, "token=abc")), test.expected); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestTrackPlaylist_Marshal(t *testing.T) {
+	playlist := TrackPlaylist{
+		Track:          1,
+		TargetDuration: 2 * time.Second,
+		Segments:       []TrackSegment{{Sequence: 3, Duration: 2 * time.Second}, {Sequence: 4, Duration: 1500 * time.Millisecond}},
+		Ended:          true,
+	}
+
+	expected := "#EXTM3U\n" +
+		"#EXT-X-VERSION:7\n" +
+		"#EXT-X-TARGETDURATION:2\n" +
+		"#EXT-X-MEDIA-SEQUENCE:3\n" +
+		"#EXT-X-INDEPENDENT-SEGMENTS\n" +
+		"#EXT-X-MAP:URI=\"init1.mp4\"\n" +
+		"#EXTINF:2.000,\nseg3.1.m4s\n" +
+		"#EXTINF:1.500,\nseg4.1.m4s\n" +
+		"#EXT-X-ENDLIST\n"
+
+	if diff := cmp.Diff(string(playlist.Marshal()), expected); diff != "" {
+		t.Fatal(diff)
+	}
+}
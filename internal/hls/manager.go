@@ -0,0 +1,91 @@
+package hls
+
+import (
+	"context"
+	"github.com/M-Ro/go-vodstream/internal/blob"
+	"github.com/M-Ro/go-vodstream/internal/live"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ChannelProvider looks up live channels to package.
+type ChannelProvider interface {
+	// Acquire returns the named channel, and a function to call once packaging stops.
+	Acquire(ctx context.Context, name string) (*live.Channel, func(), error)
+}
+
+// Manager packages live channels on demand. A channel is packaged from its
+// first HLS request until it ends or goes unrequested for the idle timeout, so
+// channels nobody watches over HLS are never packaged.
+type Manager struct {
+	config   Config
+	media    blob.Store
+	channels ChannelProvider
+
+	lock      sync.Mutex
+	packagers map[string]*Packager
+}
+
+// Get returns the packager for the named channel, starting one if the channel
+// is not already being packaged.
+func (m *Manager) Get(ctx context.Context, name string) (*Packager, error) {
+	channel, release, err := m.channels.Acquire(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	name = strings.ToLower(name)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if packager, ok := m.packagers[name]; ok {
+		if packager.channel == channel {
+			release()
+			return packager, nil
+		}
+
+		// The channel was republished, the packager is left with the old one.
+		packager.stop()
+	}
+
+	packager := newPackager(m.config, m.media, channel, release)
+	m.packagers[name] = packager
+
+	go packager.run()
+	go m.expire(name, packager)
+
+	return packager, nil
+}
+
+// expire stops the packager once it goes idle.
+func (m *Manager) expire(name string, packager *Packager) {
+	ticker := time.NewTicker(m.config.IdleTimeout / 2)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if packager.isStopped() || packager.idle() {
+			break
+		}
+	}
+
+	m.lock.Lock()
+	if m.packagers[name] == packager {
+		delete(m.packagers, name)
+	}
+	m.lock.Unlock()
+
+	packager.stop()
+}
+
+// NewManager instantiates a new Manager. The segments of DVR channels are
+// stored in media.
+func NewManager(config Config, media blob.Store, channels ChannelProvider) *Manager {
+	return &Manager{
+		config:    config,
+		media:     media,
+		channels:  channels,
+		packagers: make(map[string]*Packager),
+	}
+}
@@ -0,0 +1,702 @@
+package hls
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/M-Ro/go-vodstream/internal/blob"
+	"github.com/M-Ro/go-vodstream/internal/fmp4"
+	"github.com/M-Ro/go-vodstream/internal/live"
+	"github.com/nareix/joy4/av"
+	"github.com/nareix/joy4/format/ts"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"io/ioutil"
+	"path"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNotAvailable = errors.New("segment is no longer available")
+	ErrNotReady     = errors.New("segment was not ready in time")
+	ErrTooFarAhead  = errors.New("segment is too far beyond the live edge")
+)
+
+type Config struct {
+	// SegmentDuration is the minimum length of a segment. Segments are cut on
+	// the first video keyframe after it, so may run longer.
+	SegmentDuration time.Duration
+
+	// PartDuration is the target length of the partial segments advertised
+	// to Low-Latency HLS viewers.
+	PartDuration time.Duration
+
+	// PlaylistSegments is how many complete segments are listed in the playlist.
+	PlaylistSegments int
+
+	// IdleTimeout is how long a channel is packaged after its last request.
+	IdleTimeout time.Duration
+
+	// DVR is applied to channels whose publisher does not choose their own,
+	// and DVRMaxWindow caps the windows publishers may choose.
+	DVR          live.DVR
+	DVRMaxWindow time.Duration
+
+	// DVRPrefix is where the segments of DVR channels are kept in the media
+	// store once dropped from memory, beneath a prefix per broadcast.
+	DVRPrefix string
+}
+
+// ceilSecond rounds d up to a whole number of seconds, as target durations are
+// advertised in seconds.
+func ceilSecond(d time.Duration) time.Duration {
+	if d <= 0 {
+		return time.Second
+	}
+
+	return (d + time.Second - 1).Truncate(time.Second)
+}
+
+// part is a partial segment, a run of packets within a segment.
+type part struct {
+	data     []byte
+	duration time.Duration
+
+	// independent is set when the part starts with a keyframe.
+	independent bool
+}
+
+type segment struct {
+	sequence int
+	start    time.Duration
+	parts    []*part
+	duration time.Duration
+
+	// data is set once the segment is complete, the parts then refer to slices of it.
+	data []byte
+
+	// fragments holds the segment as a CMAF fragment per track, once complete.
+	fragments [][]byte
+
+	// files lists the segment and its fragments once written to the media
+	// store. Stored segments may have their media dropped from memory.
+	files []string
+}
+
+func (s *segment) complete() bool {
+	return s.data != nil || s.stored()
+}
+
+func (s *segment) stored() bool {
+	return s.files != nil
+}
+
+// Packager cuts a live channel into MPEG-TS segments and partial segments, and
+// CMAF fragments of each track sharing the same boundaries, and renders HLS
+// playlists and DASH manifests over them. Segments are kept for twice the
+// length of the playlist so viewers can finish fetching those that fall out of it.
+// Segments of DVR channels are also written to the media store, and served
+// from there until they fall out of the channel's DVR window.
+type Packager struct {
+	config  Config
+	media   blob.Store
+	channel *live.Channel
+	release func()
+
+	lock     sync.Mutex
+	tracks   []fmp4.Track
+	segments []*segment
+	ended    bool
+	stopped  bool
+	accessed time.Time
+
+	// targetDuration is the longest segment seen so far, rounded up to the second.
+	targetDuration time.Duration
+
+	// epoch is the earliest wall clock time media time zero could have been
+	// received at. Packets read from the queue backlog arrive late, so the
+	// earliest estimate is the closest to when the publisher sent it.
+	epoch time.Time
+
+	// changed is closed and replaced whenever a part is added or the channel ends.
+	changed chan struct{}
+
+	// prefix holds the stored segments of a DVR channel, and is empty
+	// for other channels.
+	prefix string
+}
+
+// Channel returns the channel being packaged.
+func (p *Packager) Channel() *live.Channel {
+	return p.channel
+}
+
+// notify wakes requests waiting on the packager. The lock must be held.
+func (p *Packager) notify() {
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+// touch records a request, keeping the packager running.
+func (p *Packager) touch() {
+	p.lock.Lock()
+	p.accessed = time.Now()
+	p.lock.Unlock()
+}
+
+// idle returns true if the packager has not been requested within the idle
+// timeout. DVR channels never go idle while live, as viewers may rewind to
+// media cut while nobody was watching.
+func (p *Packager) idle() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.prefix != "" && !p.ended {
+		return false
+	}
+
+	return time.Since(p.accessed) > p.config.IdleTimeout
+}
+
+// stop ends packaging and releases the channel. Segments already cut remain
+// available, until the channel has also ended and stored segments are removed.
+func (p *Packager) stop() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.stopped {
+		return
+	}
+
+	p.stopped = true
+	p.release()
+	p.removeStored()
+}
+
+// removeStored removes the stored segments of a DVR channel once packaging
+// has both ended and stopped. The lock must be held.
+func (p *Packager) removeStored() {
+	if p.prefix == "" || !p.ended || !p.stopped {
+		return
+	}
+
+	if err := blob.RemovePrefix(context.Background(), p.media, p.prefix); err != nil {
+		log.Warnf("Couldn't remove DVR segments of channel %s: %v", p.channel.Name, err)
+	}
+}
+
+func (p *Packager) isStopped() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.stopped
+}
+
+// last returns the segment being written, or the final segment once the channel
+// has ended. The lock must be held.
+func (p *Packager) last() *segment {
+	if len(p.segments) == 0 {
+		return nil
+	}
+
+	return p.segments[len(p.segments)-1]
+}
+
+// addPart appends a part to the segment being written. The lock must be held.
+func (p *Packager) addPart(part *part) {
+	last := p.last()
+	last.parts = append(last.parts, part)
+	last.duration += part.duration
+	p.notify()
+}
+
+// completeSegment joins the parts of the segment being written. The lock must be held.
+func (p *Packager) completeSegment(fragments [][]byte) {
+	last := p.last()
+	last.fragments = fragments
+
+	data := make([]byte, 0)
+	for _, part := range last.parts {
+		data = append(data, part.data...)
+	}
+
+	offset := 0
+	for _, part := range last.parts {
+		part.data = data[offset : offset+len(part.data)]
+		offset += len(part.data)
+	}
+	last.data = data
+
+	if target := ceilSecond(last.duration); target > p.targetDuration {
+		p.targetDuration = target
+	}
+
+	p.notify()
+}
+
+// expired returns true if the segment ends further behind the live edge than
+// the channel's DVR window. The lock must be held.
+func (p *Packager) expired(s *segment) bool {
+	dvr := p.channel.DVR
+	if dvr.Event {
+		return false
+	}
+
+	last := p.last()
+	return last.start+last.duration-(s.start+s.duration) > dvr.Window
+}
+
+// trim drops segments beyond the retention window. Stored segments have their
+// media dropped from memory instead, and are only dropped once expired.
+// Returns the stored segments dropped, whose files must be removed. The lock
+// must be held.
+func (p *Packager) trim() []*segment {
+	retain := 2 * p.config.PlaylistSegments
+	if len(p.segments) <= retain {
+		return nil
+	}
+
+	var dropped []*segment
+	kept := make([]*segment, 0, len(p.segments))
+
+	for i, s := range p.segments {
+		if i < len(p.segments)-retain {
+			if !s.stored() {
+				continue
+			}
+
+			if p.expired(s) {
+				dropped = append(dropped, s)
+				continue
+			}
+
+			s.data, s.parts, s.fragments = nil, nil, nil
+		}
+
+		kept = append(kept, s)
+	}
+
+	p.segments = kept
+
+	return dropped
+}
+
+// store writes a complete segment and its fragments to the media store.
+// Segments that fail to store are dropped as they would be without DVR.
+func (p *Packager) store(s *segment) {
+	files := []string{SegmentURI(s.sequence)}
+	contents := [][]byte{s.data}
+
+	for track, fragment := range s.fragments {
+		if fragment != nil {
+			files = append(files, fmp4.SegmentURI(s.sequence, track))
+			contents = append(contents, fragment)
+		}
+	}
+
+	for idx, name := range files {
+		if err := p.media.Put(context.Background(), p.prefix+name, bytes.NewReader(contents[idx])); err != nil {
+			log.Errorf("Couldn't store DVR segment of channel %s: %v", p.channel.Name, err)
+			p.discard([]*segment{{files: files[:idx+1]}})
+			return
+		}
+	}
+
+	p.lock.Lock()
+	s.files = files
+	p.lock.Unlock()
+}
+
+// discard removes the objects of stored segments.
+func (p *Packager) discard(segments []*segment) {
+	for _, s := range segments {
+		for _, name := range s.files {
+			if err := p.media.Delete(context.Background(), p.prefix+name); err != nil {
+				log.Warnf("Couldn't remove DVR segment of channel %s: %v", p.channel.Name, err)
+			}
+		}
+	}
+}
+
+// load reads a stored segment or fragment from the media store.
+func (p *Packager) load(ctx context.Context, name string) ([]byte, error) {
+	reader, err := p.media.Get(ctx, p.prefix+name)
+	if err != nil {
+		return nil, ErrNotAvailable
+	}
+	defer reader.Close()
+
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, ErrNotAvailable
+	}
+
+	return data, nil
+}
+
+// cutter splits packets into parts and segments as they are muxed.
+type cutter struct {
+	p           *Packager
+	muxer       *ts.Muxer
+	fragmenters []*fmp4.Fragmenter
+	video       int
+
+	// opened is set once the first segment has started at a keyframe.
+	opened   bool
+	sequence int
+
+	buf          *bytes.Buffer
+	independent  bool
+	partStart    time.Duration
+	segmentStart time.Duration
+	lastTime     time.Duration
+	epoch        time.Time
+}
+
+// keyFrame returns true if a segment may start at the packet.
+func (c *cutter) keyFrame(packet av.Packet) bool {
+	return c.video == -1 || (int(packet.Idx) == c.video && packet.IsKeyFrame)
+}
+
+// openPart starts buffering a part at the packet.
+func (c *cutter) openPart(packet av.Packet) {
+	c.buf = &bytes.Buffer{}
+	c.muxer.SetWriter(c.buf)
+	c.partStart = packet.Time
+	c.independent = c.keyFrame(packet)
+}
+
+// closePart hands the buffered part, ending at end, to the packager.
+func (c *cutter) closePart(end time.Duration) {
+	if c.buf.Len() == 0 {
+		return
+	}
+
+	duration := end - c.partStart
+	if duration < 0 {
+		duration = 0
+	}
+
+	c.p.lock.Lock()
+	c.p.addPart(&part{data: c.buf.Bytes(), duration: duration, independent: c.independent})
+	c.p.epoch = c.epoch
+	c.p.lock.Unlock()
+}
+
+// openSegment starts a new segment at the packet.
+func (c *cutter) openSegment(packet av.Packet) error {
+	c.p.lock.Lock()
+	c.p.segments = append(c.p.segments, &segment{sequence: c.sequence, start: packet.Time})
+	c.p.lock.Unlock()
+
+	c.sequence++
+	c.segmentStart = packet.Time
+	c.openPart(packet)
+
+	// Each segment repeats the program tables so it can be decoded on its own.
+	return c.muxer.WritePATPMT()
+}
+
+// closeSegment closes the part being written and completes the segment,
+// storing it if the channel has DVR.
+func (c *cutter) closeSegment(end time.Duration) {
+	c.closePart(end)
+
+	fragments := make([][]byte, len(c.fragmenters))
+	for idx, fragmenter := range c.fragmenters {
+		// Fragment sequence numbers start from one.
+		fragments[idx] = fragmenter.Flush(uint32(c.sequence))
+	}
+
+	c.p.lock.Lock()
+	c.p.completeSegment(fragments)
+	last := c.p.last()
+	c.p.lock.Unlock()
+
+	// The prefix is only set before packaging starts.
+	if c.p.prefix != "" {
+		c.p.store(last)
+	}
+
+	c.p.lock.Lock()
+	dropped := c.p.trim()
+	c.p.lock.Unlock()
+
+	c.p.discard(dropped)
+}
+
+// writePacket muxes the packet, first cutting a new segment if it is a keyframe
+// and the segment is long enough, or a new part if the part is long enough.
+func (c *cutter) writePacket(packet av.Packet) error {
+	if !c.opened {
+		if !c.keyFrame(packet) {
+			return nil
+		}
+
+		c.opened = true
+		if err := c.openSegment(packet); err != nil {
+			return err
+		}
+	} else if c.keyFrame(packet) && packet.Time-c.segmentStart >= c.p.config.SegmentDuration {
+		c.closeSegment(packet.Time)
+		if err := c.openSegment(packet); err != nil {
+			return err
+		}
+	} else if packet.Time-c.partStart >= c.p.config.PartDuration {
+		c.closePart(packet.Time)
+		c.openPart(packet)
+	}
+
+	c.lastTime = packet.Time
+	if epoch := time.Now().Add(-packet.Time); c.epoch.IsZero() || epoch.Before(c.epoch) {
+		c.epoch = epoch
+	}
+
+	if c.fragmenters != nil {
+		c.fragmenters[packet.Idx].WritePacket(packet)
+	}
+
+	return c.muxer.WritePacket(packet)
+}
+
+// close completes the segment being written once the channel has ended.
+func (c *cutter) close() {
+	if c.opened {
+		c.closeSegment(c.lastTime)
+	}
+}
+
+// run packages the channel from the start of its oldest buffered GOP until the
+// channel ends or the packager is stopped.
+func (p *Packager) run() {
+	defer func() {
+		p.lock.Lock()
+		p.ended = true
+		p.notify()
+		p.removeStored()
+		p.lock.Unlock()
+	}()
+
+	if p.channel.DVR.Enabled() && p.media != nil {
+		p.lock.Lock()
+		p.prefix = path.Join(p.config.DVRPrefix, p.channel.BroadcastId.String()) + "/"
+		p.lock.Unlock()
+	}
+
+	cursor := p.channel.Queue.Oldest()
+
+	streams, err := cursor.Streams()
+	if err != nil {
+		return
+	}
+
+	c := &cutter{p: p, muxer: ts.NewMuxer(ioutil.Discard), video: -1}
+	for idx, stream := range streams {
+		if stream.Type().IsVideo() {
+			c.video = idx
+			break
+		}
+	}
+
+	if err := c.muxer.WriteHeader(streams); err != nil {
+		return
+	}
+
+	// CMAF fragments are only cut if every stream can be carried in fragmented MP4.
+	tracks := make([]fmp4.Track, 0, len(streams))
+	for _, stream := range streams {
+		track, err := fmp4.NewTrack(stream)
+		if err != nil {
+			tracks = nil
+			break
+		}
+		tracks = append(tracks, track)
+	}
+
+	for _, track := range tracks {
+		c.fragmenters = append(c.fragmenters, fmp4.NewFragmenter(track))
+	}
+
+	p.lock.Lock()
+	p.tracks = tracks
+	p.lock.Unlock()
+
+	for {
+		packet, err := cursor.ReadPacket()
+		if err != nil || p.isStopped() {
+			break
+		}
+
+		if err := c.writePacket(packet); err != nil {
+			break
+		}
+	}
+
+	c.close()
+}
+
+// wait blocks until ready returns true or the channel ends, returning an error
+// if ctx is done first. ready is called with the lock held.
+func (p *Packager) wait(ctx context.Context, ready func() bool) error {
+	for {
+		p.lock.Lock()
+		if p.ended || ready() {
+			p.lock.Unlock()
+			return nil
+		}
+		changed := p.changed
+		p.lock.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ErrNotReady
+		case <-changed:
+		}
+	}
+}
+
+// blockTimeout is how long a request may wait for media, three target
+// durations as recommended for blocking playlist reloads.
+func (p *Packager) blockTimeout() time.Duration {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return 3 * p.targetDuration
+}
+
+// available returns true once the part of the segment with the given sequence
+// number has been cut, or the whole segment if part is negative. The lock must
+// be held.
+func (p *Packager) available(sequence int, part int) bool {
+	last := p.last()
+	if last == nil || sequence > last.sequence {
+		return false
+	}
+
+	if sequence < last.sequence || last.complete() {
+		return true
+	}
+
+	return part >= 0 && part < len(last.parts)
+}
+
+// find returns the segment with the given sequence number, or ErrNotAvailable.
+// The lock must be held.
+func (p *Packager) find(sequence int) (*segment, error) {
+	for _, segment := range p.segments {
+		if segment.sequence == sequence {
+			return segment, nil
+		}
+	}
+
+	return nil, ErrNotAvailable
+}
+
+// await waits for the part of the segment to be cut, or the whole segment if
+// part is negative. Requests more than two segments beyond the live edge are
+// rejected with ErrTooFarAhead.
+func (p *Packager) await(ctx context.Context, sequence int, part int) error {
+	p.lock.Lock()
+	next := 0
+	if last := p.last(); last != nil {
+		next = last.sequence + 1
+	}
+	p.lock.Unlock()
+
+	if sequence > next+1 {
+		return ErrTooFarAhead
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.blockTimeout())
+	defer cancel()
+
+	return p.wait(ctx, func() bool {
+		return p.available(sequence, part)
+	})
+}
+
+// Segment returns the segment with the given sequence number, waiting for it
+// to complete if it is still being written.
+func (p *Packager) Segment(ctx context.Context, sequence int) ([]byte, error) {
+	p.touch()
+
+	if err := p.await(ctx, sequence, -1); err != nil {
+		return nil, err
+	}
+
+	p.lock.Lock()
+
+	segment, err := p.find(sequence)
+	if err != nil || !segment.complete() {
+		p.lock.Unlock()
+		return nil, ErrNotAvailable
+	}
+
+	data := segment.data
+	p.lock.Unlock()
+
+	if data == nil {
+		return p.load(ctx, SegmentURI(sequence))
+	}
+
+	return data, nil
+}
+
+// Part returns a partial segment, waiting for it to be cut if it is the next
+// expected. Parts of complete segments are returned as they were advertised.
+func (p *Packager) Part(ctx context.Context, sequence int, index int) ([]byte, error) {
+	p.touch()
+
+	if err := p.await(ctx, sequence, index); err != nil {
+		return nil, err
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	segment, err := p.find(sequence)
+	if err != nil || index >= len(segment.parts) {
+		return nil, ErrNotAvailable
+	}
+
+	return segment.parts[index].data, nil
+}
+
+// newPackager instantiates a Packager for the channel. release is called once
+// packaging stops. Segments are only stored if media is not nil.
+func newPackager(config Config, media blob.Store, channel *live.Channel, release func()) *Packager {
+	return &Packager{
+		config:         config,
+		media:          media,
+		channel:        channel,
+		release:        release,
+		accessed:       time.Now(),
+		targetDuration: ceilSecond(config.SegmentDuration),
+		changed:        make(chan struct{}),
+	}
+}
+
+func GetConfig() Config {
+	viper.SetDefault("stream_ingester.hls.segment_duration", "2s")
+	viper.SetDefault("stream_ingester.hls.part_duration", "500ms")
+	viper.SetDefault("stream_ingester.hls.playlist_segments", 12)
+	viper.SetDefault("stream_ingester.hls.idle_timeout", "30s")
+	viper.SetDefault("stream_ingester.hls.dvr.window", "0s")
+	viper.SetDefault("stream_ingester.hls.dvr.event", false)
+	viper.SetDefault("stream_ingester.hls.dvr.max_window", "2h")
+	viper.SetDefault("stream_ingester.hls.dvr.prefix", "dvr")
+
+	return Config{
+		SegmentDuration:  viper.GetDuration("stream_ingester.hls.segment_duration"),
+		PartDuration:     viper.GetDuration("stream_ingester.hls.part_duration"),
+		PlaylistSegments: viper.GetInt("stream_ingester.hls.playlist_segments"),
+		IdleTimeout:      viper.GetDuration("stream_ingester.hls.idle_timeout"),
+		DVR: live.DVR{
+			Window: viper.GetDuration("stream_ingester.hls.dvr.window"),
+			Event:  viper.GetBool("stream_ingester.hls.dvr.event"),
+		},
+		DVRMaxWindow: viper.GetDuration("stream_ingester.hls.dvr.max_window"),
+		DVRPrefix:    viper.GetString("stream_ingester.hls.dvr.prefix"),
+	}
+}
@@ -0,0 +1,343 @@
+package hls
+
+import (
+	"bytes"
+	"context"
+	"github.com/M-Ro/go-vodstream/internal/avtest"
+	"github.com/M-Ro/go-vodstream/internal/blob"
+	"github.com/M-Ro/go-vodstream/internal/domain/broadcast"
+	"github.com/M-Ro/go-vodstream/internal/live"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/nareix/joy4/format/ts"
+	"github.com/spf13/afero"
+	"io"
+	"testing"
+	"time"
+)
+
+var testConfig = Config{
+	SegmentDuration:  time.Second,
+	PartDuration:     200 * time.Millisecond,
+	PlaylistSegments: 3,
+	IdleTimeout:      time.Minute,
+}
+
+// packageAll runs a packager over a channel opened with opts, holding count
+// frames with a keyframe every gopSize frames, returning once the channel has ended.
+func packageAll(t *testing.T, config Config, count int, gopSize int, opts ...live.ChannelOption) *Packager {
+	channels := live.NewRegistry()
+
+	channel, err := channels.Open("testUser1", 1, broadcast.VisibilityPublic, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := channel.Queue.WriteHeader(avtest.Streams()); err != nil {
+		t.Fatal(err)
+	}
+
+	packager := newPackager(config, blob.NewLocalStore(afero.NewMemMapFs()), channel, func() {})
+
+	done := make(chan struct{})
+	go func() {
+		packager.run()
+		close(done)
+	}()
+
+	// Wait for the packager to take its position before packets arrive.
+	time.Sleep(20 * time.Millisecond)
+	for _, packet := range avtest.Packets(count, gopSize) {
+		channel.Queue.WritePacket(packet)
+		time.Sleep(100 * time.Microsecond)
+	}
+	channels.Close(channel)
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the packager to finish")
+	}
+
+	return packager
+}
+
+func TestPackager_CutsSegmentsOnKeyframes(t *testing.T) {
+	// 10 seconds of frames with a keyframe every 1.2 seconds.
+	packager := packageAll(t, Config{
+		SegmentDuration:  time.Second,
+		PartDuration:     200 * time.Millisecond,
+		PlaylistSegments: 100,
+		IdleTimeout:      time.Minute,
+	}, 250, 30, live.WithLowLatency(true))
+
+	if len(packager.segments) != 9 {
+		t.Fatal(cmp.Diff(len(packager.segments), 9))
+	}
+
+	for i, segment := range packager.segments {
+		if !segment.complete() {
+			t.Fatalf("expected segment %d to be complete", i)
+		}
+
+		if segment.sequence != i {
+			t.Fatal(cmp.Diff(segment.sequence, i))
+		}
+
+		if !segment.parts[0].independent {
+			t.Fatalf("expected segment %d to start with an independent part", i)
+		}
+
+		for _, part := range segment.parts[:len(segment.parts)-1] {
+			if part.duration < testConfig.PartDuration {
+				t.Fatal("expected every part but the last to reach the part target")
+			}
+		}
+
+		// Every segment but the last is a whole GOP.
+		if i < len(packager.segments)-1 && segment.duration != 30*avtest.FrameDuration {
+			t.Fatal(cmp.Diff(segment.duration, 30*avtest.FrameDuration))
+		}
+
+		joined := make([]byte, 0)
+		for _, part := range segment.parts {
+			joined = append(joined, part.data...)
+		}
+
+		if !bytes.Equal(joined, segment.data) {
+			t.Fatalf("expected segment %d to be the concatenation of its parts", i)
+		}
+	}
+
+	if packager.targetDuration != 2*time.Second {
+		t.Fatal(cmp.Diff(packager.targetDuration, 2*time.Second))
+	}
+}
+
+func TestPackager_SegmentsDecodeAlone(t *testing.T) {
+	packager := packageAll(t, testConfig, 100, 25)
+
+	for _, segment := range packager.segments {
+		demuxer := ts.NewDemuxer(bytes.NewReader(segment.data))
+
+		streams, err := demuxer.Streams()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(streams) != len(avtest.Streams()) {
+			t.Fatal(cmp.Diff(len(streams), len(avtest.Streams())))
+		}
+
+		first, err := demuxer.ReadPacket()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if first.Idx != avtest.VideoIdx || !first.IsKeyFrame {
+			t.Fatal("expected segment to start at a video keyframe")
+		}
+
+		for {
+			if _, err := demuxer.ReadPacket(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestPackager_FragmentsShareSegments(t *testing.T) {
+	packager := packageAll(t, testConfig, 100, 25)
+
+	if len(packager.tracks) != len(avtest.Streams()) {
+		t.Fatal(cmp.Diff(len(packager.tracks), len(avtest.Streams())))
+	}
+
+	for i, segment := range packager.segments {
+		if segment.start != time.Duration(i)*25*avtest.FrameDuration {
+			t.Fatal(cmp.Diff(segment.start, time.Duration(i)*25*avtest.FrameDuration))
+		}
+
+		for track := range segment.fragments {
+			fragment, err := packager.Fragment(context.Background(), segment.sequence, track)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(fragment) < 8 || string(fragment[4:8]) != "moof" {
+				t.Fatalf("expected fragment %d of segment %d to start with moof", track, i)
+			}
+		}
+	}
+}
+
+func TestPackager_RetainsTwicePlaylist(t *testing.T) {
+	packager := packageAll(t, testConfig, 250, 25)
+
+	if len(packager.segments) != 2*testConfig.PlaylistSegments {
+		t.Fatal(cmp.Diff(len(packager.segments), 2*testConfig.PlaylistSegments))
+	}
+
+	if _, err := packager.Segment(context.Background(), 0); err != ErrNotAvailable {
+		t.Fatal(cmp.Diff(err, ErrNotAvailable, cmpopts.EquateErrors()))
+	}
+
+	if _, err := packager.Segment(context.Background(), 9); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := packager.Part(context.Background(), 9, 0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPackager_Await(t *testing.T) {
+	channels := live.NewRegistry()
+
+	channel, err := channels.Open("testUser1", 1, broadcast.VisibilityPublic, live.WithLowLatency(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer channels.Close(channel)
+
+	if err := channel.Queue.WriteHeader(avtest.Streams()); err != nil {
+		t.Fatal(err)
+	}
+
+	packager := newPackager(testConfig, nil, channel, func() {})
+	go packager.run()
+
+	if err := packager.await(context.Background(), 5, -1); err != ErrTooFarAhead {
+		t.Fatal(cmp.Diff(err, ErrTooFarAhead, cmpopts.EquateErrors()))
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- packager.await(context.Background(), 1, 2)
+	}()
+
+	// Segment 1 part 2 starts 1.4 seconds in, and is cut at 1.6 seconds.
+	packets := avtest.Packets(100, 25)
+	for _, packet := range packets[:2*40] {
+		channel.Queue.WritePacket(packet)
+		time.Sleep(100 * time.Microsecond)
+	}
+
+	select {
+	case <-done:
+		t.Fatal("expected await to block until the part is cut")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	for _, packet := range packets[2*40 : 2*41+1] {
+		channel.Queue.WritePacket(packet)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the part")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := packager.await(ctx, 2, -1); err != ErrNotReady {
+		t.Fatal(cmp.Diff(err, ErrNotReady, cmpopts.EquateErrors()))
+	}
+}
+
+func TestPackager_DVR(t *testing.T) {
+	config := testConfig
+	config.DVRPrefix = "dvr"
+
+	// 10 one second segments, keeping those ending within 7 seconds of the last.
+	packager := packageAll(t, config, 250, 25, live.WithDVR(live.DVR{Window: 7 * time.Second}))
+
+	if len(packager.segments) != 8 || packager.segments[0].sequence != 2 {
+		t.Fatalf("expected segments 2 to 9 to be kept, found %d from %d", len(packager.segments),
+			packager.segments[0].sequence)
+	}
+
+	if _, err := packager.Segment(context.Background(), 1); err != ErrNotAvailable {
+		t.Fatal(cmp.Diff(err, ErrNotAvailable, cmpopts.EquateErrors()))
+	}
+
+	// Segment 2 is beyond the retention window, so is served from the media store.
+	if packager.segments[0].data != nil {
+		t.Fatal("expected segment 2 to be dropped from memory")
+	}
+
+	data, err := packager.Segment(context.Background(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := ts.NewDemuxer(bytes.NewReader(data)).ReadPacket()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.Idx != avtest.VideoIdx || !first.IsKeyFrame {
+		t.Fatal("expected stored segment to start at a video keyframe")
+	}
+
+	fragment, err := packager.Fragment(context.Background(), 2, avtest.AudioIdx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fragment) < 8 || string(fragment[4:8]) != "moof" {
+		t.Fatal("expected stored fragment to start with moof")
+	}
+
+	playlist, err := packager.Playlist(context.Background(), PlaylistRequest{Sequence: -1, Part: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Contains(playlist, []byte("#EXT-X-MEDIA-SEQUENCE:2\n")) || bytes.Contains(playlist, []byte("EVENT")) {
+		t.Fatalf("expected a sliding playlist over the DVR window:\n%s", playlist)
+	}
+
+	if count := bytes.Count(playlist, []byte("#EXTINF")); count != 8 {
+		t.Fatal(cmp.Diff(count, 8))
+	}
+
+	// Stored segments are removed once the ended channel stops being packaged.
+	packager.stop()
+
+	objects, err := packager.media.List(context.Background(), "dvr/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(objects) != 0 {
+		t.Fatalf("expected the DVR segments to be removed, found %d", len(objects))
+	}
+}
+
+func TestPackager_DVR_Event(t *testing.T) {
+	packager := packageAll(t, testConfig, 250, 25, live.WithDVR(live.DVR{Event: true}))
+	defer packager.stop()
+
+	if len(packager.segments) != 10 {
+		t.Fatal(cmp.Diff(len(packager.segments), 10))
+	}
+
+	playlist, err := packager.TrackPlaylist(context.Background(), avtest.VideoIdx, PlaylistRequest{Offset: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tag := range []string{"#EXT-X-PLAYLIST-TYPE:EVENT\n", "#EXT-X-START:TIME-OFFSET=-60.000\n", "seg0.0.m4s\n"} {
+		if !bytes.Contains(playlist, []byte(tag)) {
+			t.Fatalf("expected %q in the event playlist:\n%s", tag, playlist)
+		}
+	}
+}
@@ -0,0 +1,293 @@
+package hls
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrInvalidRequest = errors.New("invalid playlist delivery directives")
+)
+
+// Delivery directives viewers append to playlist requests.
+const (
+	SequenceQueryParam = "_HLS_msn"
+	PartQueryParam     = "_HLS_part"
+	SkipQueryParam     = "_HLS_skip"
+
+	// OffsetQueryParam starts playback this many seconds behind the live edge.
+	OffsetQueryParam = "offset"
+)
+
+// PlaylistRequest holds the delivery directives of a playlist request.
+type PlaylistRequest struct {
+	// Sequence blocks the request until the segment with this media sequence
+	// number is available, if not negative.
+	Sequence int
+
+	// Part blocks the request until this part of the segment is available, if
+	// not negative.
+	Part int
+
+	// Skip requests a delta playlist, omitting segments the viewer already has.
+	Skip bool
+
+	// Offset starts playback this far behind the live edge, if set. Players
+	// start from the beginning of the playlist if it is shorter.
+	Offset time.Duration
+
+	// Query is appended to every URI in the playlist, carrying the token and
+	// signature the playlist was requested with through to its media.
+	Query url.Values
+}
+
+// ParseRequest reads the delivery directives from a playlist request query.
+// Other parameters are kept to be passed on to media requests.
+func ParseRequest(query url.Values) (PlaylistRequest, error) {
+	request := PlaylistRequest{Sequence: -1, Part: -1, Query: url.Values{}}
+
+	for key, values := range query {
+		switch key {
+		case SequenceQueryParam, PartQueryParam, SkipQueryParam, OffsetQueryParam:
+		default:
+			request.Query[key] = values
+		}
+	}
+
+	var err error
+
+	if value := query.Get(SequenceQueryParam); value != "" {
+		if request.Sequence, err = strconv.Atoi(value); err != nil || request.Sequence < 0 {
+			return PlaylistRequest{}, ErrInvalidRequest
+		}
+	}
+
+	if value := query.Get(PartQueryParam); value != "" {
+		// A part is only meaningful within a segment.
+		if request.Part, err = strconv.Atoi(value); err != nil || request.Part < 0 || request.Sequence < 0 {
+			return PlaylistRequest{}, ErrInvalidRequest
+		}
+	}
+
+	request.Skip = query.Get(SkipQueryParam) == "YES"
+
+	if value := query.Get(OffsetQueryParam); value != "" {
+		offset, err := strconv.ParseFloat(value, 64)
+		if err != nil || offset < 0 {
+			return PlaylistRequest{}, ErrInvalidRequest
+		}
+		request.Offset = time.Duration(offset * float64(time.Second))
+	}
+
+	return request, nil
+}
+
+// SegmentURI returns the URI of the segment with the given sequence number,
+// relative to the playlist.
+func SegmentURI(sequence int) string {
+	return fmt.Sprintf("seg%d.ts", sequence)
+}
+
+// PartURI returns the URI of a partial segment, relative to the playlist.
+func PartURI(sequence int, index int) string {
+	return fmt.Sprintf("part%d.%d.ts", sequence, index)
+}
+
+// seconds formats a duration as decimal seconds.
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
+
+// writeStart writes the tag starting playback offset behind the end of the
+// playlist, if set.
+func writeStart(buf *bytes.Buffer, offset time.Duration) {
+	if offset > 0 {
+		fmt.Fprintf(buf, "#EXT-X-START:TIME-OFFSET=-%s\n", seconds(offset))
+	}
+}
+
+// withQuery appends the encoded query to a URI.
+func withQuery(uri string, query string) string {
+	if query == "" {
+		return uri
+	}
+
+	return uri + "?" + query
+}
+
+// ready returns true once there is enough media for a viewer to start playing,
+// a part for Low-Latency HLS viewers or a complete segment otherwise. The lock
+// must be held.
+func (p *Packager) ready() bool {
+	if len(p.segments) == 0 {
+		return false
+	}
+
+	if p.channel.LowLatency {
+		return len(p.segments[0].parts) > 0
+	}
+
+	return p.segments[0].complete()
+}
+
+// Playlist renders the media playlist. Low-Latency HLS channels honour the
+// delivery directives, blocking until the requested segment or part is
+// available and omitting old segments from delta playlists. Other channels
+// ignore them, and only list complete segments.
+func (p *Packager) Playlist(ctx context.Context, request PlaylistRequest) ([]byte, error) {
+	p.touch()
+
+	lowLatency := p.channel.LowLatency
+
+	if lowLatency && request.Sequence >= 0 {
+		if err := p.await(ctx, request.Sequence, request.Part); err != nil {
+			return nil, err
+		}
+	} else {
+		waitCtx, cancel := context.WithTimeout(ctx, p.blockTimeout())
+		err := p.wait(waitCtx, p.ready)
+		cancel()
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.render(lowLatency, lowLatency && request.Skip, request.Offset, request.Query.Encode()), nil
+}
+
+// window returns the complete segments listed in playlists: the most recent
+// PlaylistSegments, or every segment within the DVR window if that is longer.
+// The lock must be held.
+func (p *Packager) window(complete []*segment) []*segment {
+	start := 0
+	if len(complete) > p.config.PlaylistSegments {
+		start = len(complete) - p.config.PlaylistSegments
+	}
+
+	if p.prefix != "" {
+		for start > 0 && !p.expired(complete[start-1]) {
+			start--
+		}
+	}
+
+	return complete[start:]
+}
+
+// event returns true if the playlist only ever grows, for DVR channels
+// keeping the whole broadcast. The lock must be held.
+func (p *Packager) event() bool {
+	return p.prefix != "" && p.channel.DVR.Event
+}
+
+// render writes the media playlist. The lock must be held.
+func (p *Packager) render(lowLatency bool, skip bool, offset time.Duration, query string) []byte {
+	complete := p.segments
+	var open *segment
+
+	if last := p.last(); last != nil && !last.complete() {
+		complete, open = p.segments[:len(p.segments)-1], last
+	}
+
+	complete = p.window(complete)
+
+	listed := complete
+	if lowLatency && open != nil {
+		listed = append(append([]*segment(nil), complete...), open)
+	}
+
+	// after[i] is the duration of the playlist following the ith listed segment.
+	after := make([]time.Duration, len(listed))
+	for i := len(listed) - 2; i >= 0; i-- {
+		after[i] = after[i+1] + listed[i+1].duration
+	}
+
+	partHoldBack := 3 * p.config.PartDuration
+	skipUntil := 6 * p.targetDuration
+	partWindow := 3 * p.targetDuration
+
+	buf := &bytes.Buffer{}
+	buf.WriteString("#EXTM3U\n")
+
+	if lowLatency {
+		buf.WriteString("#EXT-X-VERSION:9\n")
+	} else {
+		buf.WriteString("#EXT-X-VERSION:3\n")
+	}
+
+	fmt.Fprintf(buf, "#EXT-X-TARGETDURATION:%d\n", int(p.targetDuration.Seconds()))
+
+	sequence := 0
+	if len(listed) > 0 {
+		sequence = listed[0].sequence
+	} else if open != nil {
+		sequence = open.sequence
+	}
+	fmt.Fprintf(buf, "#EXT-X-MEDIA-SEQUENCE:%d\n", sequence)
+
+	if p.event() {
+		buf.WriteString("#EXT-X-PLAYLIST-TYPE:EVENT\n")
+	}
+
+	writeStart(buf, offset)
+
+	if lowLatency {
+		fmt.Fprintf(buf, "#EXT-X-SERVER-CONTROL:CAN-BLOCK-RELOAD=YES,PART-HOLD-BACK=%s,CAN-SKIP-UNTIL=%s\n",
+			seconds(partHoldBack), seconds(skipUntil))
+		fmt.Fprintf(buf, "#EXT-X-PART-INF:PART-TARGET=%s\n", seconds(p.config.PartDuration))
+	}
+
+	skipped := 0
+	if skip {
+		for skipped < len(complete) && after[skipped] >= skipUntil {
+			skipped++
+		}
+
+		if skipped > 0 {
+			fmt.Fprintf(buf, "#EXT-X-SKIP:SKIPPED-SEGMENTS=%d\n", skipped)
+		}
+	}
+
+	for i, segment := range listed[skipped:] {
+		if lowLatency && after[skipped+i] < partWindow {
+			for idx, part := range segment.parts {
+				fmt.Fprintf(buf, "#EXT-X-PART:DURATION=%s,URI=\"%s\"", seconds(part.duration),
+					withQuery(PartURI(segment.sequence, idx), query))
+				if part.independent {
+					buf.WriteString(",INDEPENDENT=YES")
+				}
+				buf.WriteString("\n")
+			}
+		}
+
+		if segment == open {
+			break
+		}
+
+		fmt.Fprintf(buf, "#EXTINF:%s,\n", seconds(segment.duration))
+		fmt.Fprintf(buf, "%s\n", withQuery(SegmentURI(segment.sequence), query))
+	}
+
+	if p.ended {
+		buf.WriteString("#EXT-X-ENDLIST\n")
+	} else if lowLatency {
+		hint := PartURI(sequence, 0)
+		if open != nil {
+			hint = PartURI(open.sequence, len(open.parts))
+		} else if last := p.last(); last != nil {
+			hint = PartURI(last.sequence+1, 0)
+		}
+
+		fmt.Fprintf(buf, "#EXT-X-PRELOAD-HINT:TYPE=PART,URI=\"%s\"\n", withQuery(hint, query))
+	}
+
+	return buf.Bytes()
+}
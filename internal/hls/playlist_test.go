@@ -0,0 +1,220 @@
+package hls
+
+import (
+	"context"
+	"github.com/M-Ro/go-vodstream/internal/live"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestParseRequest(t *testing.T) {
+	tests := []struct {
+		testName        string
+		query           string
+		expectedError   error
+		expectedRequest PlaylistRequest
+	}{
+		{
+			testName:        "expect no directives",
+			query:           "token=abc",
+			expectedRequest: PlaylistRequest{Sequence: -1, Part: -1, Query: url.Values{"token": {"abc"}}},
+		},
+		{
+			testName:        "expect sequence and part",
+			query:           "_HLS_msn=4&_HLS_part=2&_HLS_skip=YES&token=abc",
+			expectedRequest: PlaylistRequest{Sequence: 4, Part: 2, Skip: true, Query: url.Values{"token": {"abc"}}},
+		},
+		{
+			testName:        "expect start offset",
+			query:           "offset=90.5&token=abc",
+			expectedRequest: PlaylistRequest{Sequence: -1, Part: -1, Offset: 90500 * time.Millisecond, Query: url.Values{"token": {"abc"}}},
+		},
+		{
+			testName:      "expect error for negative offset",
+			query:         "offset=-1",
+			expectedError: ErrInvalidRequest,
+		},
+		{
+			testName:      "expect error for part without sequence",
+			query:         "_HLS_part=2",
+			expectedError: ErrInvalidRequest,
+		},
+		{
+			testName:      "expect error for malformed sequence",
+			query:         "_HLS_msn=abc",
+			expectedError: ErrInvalidRequest,
+		},
+		{
+			testName:      "expect error for negative part",
+			query:         "_HLS_msn=1&_HLS_part=-1",
+			expectedError: ErrInvalidRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			query, err := url.ParseQuery(test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			request, err := ParseRequest(query)
+
+			if !cmp.Equal(err, test.expectedError, cmpopts.EquateErrors()) {
+				t.Fatal(cmp.Diff(err, test.expectedError, cmpopts.EquateErrors()))
+			}
+
+			if err != nil {
+				return
+			}
+
+			if !cmp.Equal(request, test.expectedRequest) {
+				t.Fatal(cmp.Diff(request, test.expectedRequest))
+			}
+		})
+	}
+}
+
+// testPackager returns a packager holding count complete two second segments of
+// four parts each, followed by a segment in progress with a single part.
+func testPackager(lowLatency bool, count int) *Packager {
+	packager := newPackager(Config{
+		SegmentDuration:  2 * time.Second,
+		PartDuration:     500 * time.Millisecond,
+		PlaylistSegments: 10,
+	}, nil, &live.Channel{LowLatency: lowLatency}, func() {})
+
+	for sequence := 0; sequence <= count; sequence++ {
+		packager.segments = append(packager.segments, &segment{sequence: sequence})
+
+		parts := 4
+		if sequence == count {
+			parts = 1
+		}
+
+		for idx := 0; idx < parts; idx++ {
+			packager.addPart(&part{data: []byte{0x47}, duration: 500 * time.Millisecond, independent: idx == 0})
+		}
+
+		if sequence < count {
+			packager.completeSegment(nil)
+		}
+	}
+
+	return packager
+}
+
+func TestPackager_Playlist(t *testing.T) {
+	tests := []struct {
+		testName   string
+		lowLatency bool
+		count      int
+		request    PlaylistRequest
+		expected   string
+	}{
+		{
+			testName: "expect complete segments only without low latency",
+			count:    2,
+			request:  PlaylistRequest{Sequence: -1, Part: -1, Query: url.Values{"token": {"abc"}}},
+			expected: "#EXTM3U\n" +
+				"#EXT-X-VERSION:3\n" +
+				"#EXT-X-TARGETDURATION:2\n" +
+				"#EXT-X-MEDIA-SEQUENCE:0\n" +
+				"#EXTINF:2.000,\nseg0.ts?token=abc\n" +
+				"#EXTINF:2.000,\nseg1.ts?token=abc\n",
+		},
+		{
+			testName: "expect start offset behind the live edge",
+			count:    2,
+			request:  PlaylistRequest{Sequence: -1, Part: -1, Offset: 3 * time.Second},
+			expected: "#EXTM3U\n" +
+				"#EXT-X-VERSION:3\n" +
+				"#EXT-X-TARGETDURATION:2\n" +
+				"#EXT-X-MEDIA-SEQUENCE:0\n" +
+				"#EXT-X-START:TIME-OFFSET=-3.000\n" +
+				"#EXTINF:2.000,\nseg0.ts\n" +
+				"#EXTINF:2.000,\nseg1.ts\n",
+		},
+		{
+			testName:   "expect parts near the live edge and a preload hint",
+			lowLatency: true,
+			count:      4,
+			request:    PlaylistRequest{Sequence: 4, Part: 0},
+			expected: "#EXTM3U\n" +
+				"#EXT-X-VERSION:9\n" +
+				"#EXT-X-TARGETDURATION:2\n" +
+				"#EXT-X-MEDIA-SEQUENCE:0\n" +
+				"#EXT-X-SERVER-CONTROL:CAN-BLOCK-RELOAD=YES,PART-HOLD-BACK=1.500,CAN-SKIP-UNTIL=12.000\n" +
+				"#EXT-X-PART-INF:PART-TARGET=0.500\n" +
+				"#EXTINF:2.000,\nseg0.ts\n" +
+				"#EXT-X-PART:DURATION=0.500,URI=\"part1.0.ts\",INDEPENDENT=YES\n" +
+				"#EXT-X-PART:DURATION=0.500,URI=\"part1.1.ts\"\n" +
+				"#EXT-X-PART:DURATION=0.500,URI=\"part1.2.ts\"\n" +
+				"#EXT-X-PART:DURATION=0.500,URI=\"part1.3.ts\"\n" +
+				"#EXTINF:2.000,\nseg1.ts\n" +
+				"#EXT-X-PART:DURATION=0.500,URI=\"part2.0.ts\",INDEPENDENT=YES\n" +
+				"#EXT-X-PART:DURATION=0.500,URI=\"part2.1.ts\"\n" +
+				"#EXT-X-PART:DURATION=0.500,URI=\"part2.2.ts\"\n" +
+				"#EXT-X-PART:DURATION=0.500,URI=\"part2.3.ts\"\n" +
+				"#EXTINF:2.000,\nseg2.ts\n" +
+				"#EXT-X-PART:DURATION=0.500,URI=\"part3.0.ts\",INDEPENDENT=YES\n" +
+				"#EXT-X-PART:DURATION=0.500,URI=\"part3.1.ts\"\n" +
+				"#EXT-X-PART:DURATION=0.500,URI=\"part3.2.ts\"\n" +
+				"#EXT-X-PART:DURATION=0.500,URI=\"part3.3.ts\"\n" +
+				"#EXTINF:2.000,\nseg3.ts\n" +
+				"#EXT-X-PART:DURATION=0.500,URI=\"part4.0.ts\",INDEPENDENT=YES\n" +
+				"#EXT-X-PRELOAD-HINT:TYPE=PART,URI=\"part4.1.ts\"\n",
+		},
+		{
+			testName:   "expect delta playlist to skip segments beyond the skip boundary",
+			lowLatency: true,
+			count:      8,
+			request:    PlaylistRequest{Sequence: -1, Part: -1, Skip: true},
+			expected: "#EXTM3U\n" +
+				"#EXT-X-VERSION:9\n" +
+				"#EXT-X-TARGETDURATION:2\n" +
+				"#EXT-X-MEDIA-SEQUENCE:0\n" +
+				"#EXT-X-SERVER-CONTROL:CAN-BLOCK-RELOAD=YES,PART-HOLD-BACK=1.500,CAN-SKIP-UNTIL=12.000\n" +
+				"#EXT-X-PART-INF:PART-TARGET=0.500\n" +
+				"#EXT-X-SKIP:SKIPPED-SEGMENTS=2\n" +
+				"#EXTINF:2.000,\nseg2.ts\n" +
+				"#EXTINF:2.000,\nseg3.ts\n" +
+				"#EXTINF:2.000,\nseg4.ts\n" +
+				"#EXT-X-PART:DURATION=0.500,URI=\"part5.0.ts\",INDEPENDENT=YES\n" +
+				"#EXT-X-PART:DURATION=0.500,URI=\"part5.1.ts\"\n" +
+				"#EXT-X-PART:DURATION=0.500,URI=\"part5.2.ts\"\n" +
+				"#EXT-X-PART:DURATION=0.500,URI=\"part5.3.ts\"\n" +
+				"#EXTINF:2.000,\nseg5.ts\n" +
+				"#EXT-X-PART:DURATION=0.500,URI=\"part6.0.ts\",INDEPENDENT=YES\n" +
+				"#EXT-X-PART:DURATION=0.500,URI=\"part6.1.ts\"\n" +
+				"#EXT-X-PART:DURATION=0.500,URI=\"part6.2.ts\"\n" +
+				"#EXT-X-PART:DURATION=0.500,URI=\"part6.3.ts\"\n" +
+				"#EXTINF:2.000,\nseg6.ts\n" +
+				"#EXT-X-PART:DURATION=0.500,URI=\"part7.0.ts\",INDEPENDENT=YES\n" +
+				"#EXT-X-PART:DURATION=0.500,URI=\"part7.1.ts\"\n" +
+				"#EXT-X-PART:DURATION=0.500,URI=\"part7.2.ts\"\n" +
+				"#EXT-X-PART:DURATION=0.500,URI=\"part7.3.ts\"\n" +
+				"#EXTINF:2.000,\nseg7.ts\n" +
+				"#EXT-X-PART:DURATION=0.500,URI=\"part8.0.ts\",INDEPENDENT=YES\n" +
+				"#EXT-X-PRELOAD-HINT:TYPE=PART,URI=\"part8.1.ts\"\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			packager := testPackager(test.lowLatency, test.count)
+
+			playlist, err := packager.Playlist(context.Background(), test.request)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(playlist) != test.expected {
+				t.Fatal(cmp.Diff(string(playlist), test.expected))
+			}
+		})
+	}
+}
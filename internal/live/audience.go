@@ -0,0 +1,68 @@
+package live
+
+import (
+	"sync"
+	"time"
+)
+
+// PollWindow is how long a viewer polling the channel's playlists is counted
+// after their last request. Players reload live playlists every few seconds, so
+// viewers polling less often than this have left.
+const PollWindow = 30 * time.Second
+
+// audience counts the viewers of a channel. Viewers streaming over a
+// connection are counted while connected, while HLS and DASH viewers, which
+// only poll, are counted for PollWindow after each poll.
+type audience struct {
+	lock      sync.Mutex
+	connected int64
+	polls     map[string]time.Time
+}
+
+// Join counts a viewer connected to the channel, returning a function to call
+// once they leave.
+func (c *Channel) Join() func() {
+	c.audience.lock.Lock()
+	c.audience.connected++
+	c.audience.lock.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.audience.lock.Lock()
+			c.audience.connected--
+			c.audience.lock.Unlock()
+		})
+	}
+}
+
+// Poll counts the viewer identified by key as watching the channel as of now.
+func (c *Channel) Poll(key string, now time.Time) {
+	c.audience.lock.Lock()
+	defer c.audience.lock.Unlock()
+
+	if c.audience.polls == nil {
+		c.audience.polls = make(map[string]time.Time)
+	}
+
+	c.audience.polls[key] = now
+}
+
+// Viewers returns how many are watching the channel as of now, forgetting
+// polling viewers who have left.
+func (c *Channel) Viewers(now time.Time) int64 {
+	c.audience.lock.Lock()
+	defer c.audience.lock.Unlock()
+
+	viewers := c.audience.connected
+	for key, polled := range c.audience.polls {
+		if now.Sub(polled) > PollWindow {
+			delete(c.audience.polls, key)
+			continue
+		}
+
+		viewers++
+	}
+
+	return viewers
+}
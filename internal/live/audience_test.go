@@ -0,0 +1,36 @@
+package live
+
+import (
+	"github.com/M-Ro/go-vodstream/internal/domain/broadcast"
+	"testing"
+	"time"
+)
+
+func TestChannel_Viewers(t *testing.T) {
+	r := NewRegistry()
+	channel, err := r.Open("testUser1", 1, broadcast.VisibilityPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2026, 10, 18, 20, 0, 0, 0, time.UTC)
+
+	leave := channel.Join()
+	channel.Join()
+	channel.Poll("203.0.113.1", now)
+	channel.Poll("203.0.113.2", now.Add(-PollWindow/2))
+	channel.Poll("203.0.113.2", now)
+	channel.Poll("203.0.113.3", now.Add(-2*PollWindow))
+
+	if viewers := channel.Viewers(now); viewers != 4 {
+		t.Fatalf("expected 2 connected and 2 polling viewers, got %d", viewers)
+	}
+
+	// Leaving more than once is only counted once.
+	leave()
+	leave()
+
+	if viewers := channel.Viewers(now.Add(PollWindow + time.Second)); viewers != 1 {
+		t.Fatalf("expected only the remaining connected viewer, got %d", viewers)
+	}
+}
@@ -0,0 +1,68 @@
+package live
+
+import (
+	"github.com/M-Ro/go-vodstream/internal/domain/broadcast"
+	"github.com/google/uuid"
+	"github.com/nareix/joy4/av/pubsub"
+	"time"
+)
+
+// Channel is a stream currently being published to the ingester.
+type Channel struct {
+	// Name the channel is published and played under, the broadcasters username.
+	Name string
+
+	// BroadcastId identifies this publish of the channel, and changes each time it is opened.
+	BroadcastId uuid.UUID
+
+	// The user responsible for this channel
+	BroadcasterId uint64
+
+	Visibility broadcast.Visibility
+
+	// LowLatency enables Low-Latency HLS for viewers of the channel.
+	LowLatency bool
+
+	// DVR lets viewers rewind the channel.
+	DVR DVR
+
+	// Queue holds the most recent packets received from the publisher.
+	Queue *pubsub.Queue
+
+	StartedAt time.Time
+
+	audience audience
+}
+
+// DVR configures how far behind the live edge viewers may rewind a channel.
+type DVR struct {
+	// Window is how much of the channel is kept for viewers to rewind through,
+	// zero disables DVR.
+	Window time.Duration
+
+	// Event keeps the whole broadcast, listed as an event playlist so players
+	// present it as one growing timeline. Window is ignored.
+	Event bool
+}
+
+// Enabled returns true if any of the channel is kept behind the live edge.
+func (d DVR) Enabled() bool {
+	return d.Event || d.Window > 0
+}
+
+// ChannelOption configures a channel as it is opened.
+type ChannelOption func(c *Channel)
+
+// WithLowLatency sets whether the channel is packaged as Low-Latency HLS.
+func WithLowLatency(enabled bool) ChannelOption {
+	return func(c *Channel) {
+		c.LowLatency = enabled
+	}
+}
+
+// WithDVR sets how far behind the live edge viewers may rewind the channel.
+func WithDVR(dvr DVR) ChannelOption {
+	return func(c *Channel) {
+		c.DVR = dvr
+	}
+}
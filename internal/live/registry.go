@@ -0,0 +1,110 @@
+package live
+
+import (
+	"errors"
+	"github.com/M-Ro/go-vodstream/internal/domain/broadcast"
+	"github.com/google/uuid"
+	"github.com/nareix/joy4/av/pubsub"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrChannelExists   = errors.New("channel is already live")
+	ErrChannelNotFound = errors.New("no live channel found")
+)
+
+// Registry holds the set of channels currently live on this ingester.
+type Registry struct {
+	lock     sync.RWMutex
+	channels map[string]*Channel
+}
+
+// normaliseName returns the key a channel name is stored under. Channel names
+// follow usernames, which are case-insensitive.
+func normaliseName(name string) string {
+	return strings.ToLower(name)
+}
+
+// Open creates a new live channel with the given name. Returns ErrChannelExists
+// if the channel is already being published.
+func (r *Registry) Open(
+	name string, broadcasterId uint64, visibility broadcast.Visibility, opts ...ChannelOption,
+) (*Channel, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	key := normaliseName(name)
+	if _, exists := r.channels[key]; exists {
+		return nil, ErrChannelExists
+	}
+
+	channel := &Channel{
+		Name:          key,
+		BroadcastId:   uuid.New(),
+		BroadcasterId: broadcasterId,
+		Visibility:    visibility,
+		Queue:         pubsub.NewQueue(),
+		StartedAt:     time.Now(),
+	}
+
+	for _, opt := range opts {
+		opt(channel)
+	}
+
+	r.channels[key] = channel
+
+	return channel, nil
+}
+
+// Get returns the live channel with the given name, or ErrChannelNotFound.
+func (r *Registry) Get(name string) (*Channel, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	channel, exists := r.channels[normaliseName(name)]
+	if !exists {
+		return nil, ErrChannelNotFound
+	}
+
+	return channel, nil
+}
+
+// Close ends the given channel, disconnecting any viewers reading from its queue.
+// Close is a no-op if the channel has already been replaced or removed.
+func (r *Registry) Close(channel *Channel) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	channel.Queue.Close()
+
+	if r.channels[channel.Name] == channel {
+		delete(r.channels, channel.Name)
+	}
+}
+
+// List returns all live channels, ordered by name.
+func (r *Registry) List() []*Channel {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	channels := make([]*Channel, 0, len(r.channels))
+	for _, channel := range r.channels {
+		channels = append(channels, channel)
+	}
+
+	sort.Slice(channels, func(i, j int) bool {
+		return channels[i].Name < channels[j].Name
+	})
+
+	return channels
+}
+
+// NewRegistry instantiates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		channels: make(map[string]*Channel),
+	}
+}
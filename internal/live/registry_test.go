@@ -0,0 +1,147 @@
+package live
+
+import (
+	"github.com/M-Ro/go-vodstream/internal/domain/broadcast"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"testing"
+)
+
+func TestRegistry_Open(t *testing.T) {
+	tests := []struct {
+		testName      string
+		existing      []string
+		channelName   string
+		expectedError error
+		expectedName  string
+	}{
+		{
+			testName:      "expect success with empty registry",
+			existing:      []string{},
+			channelName:   "testUser1",
+			expectedError: nil,
+			expectedName:  "testuser1",
+		},
+		{
+			testName:      "expect success alongside other channels",
+			existing:      []string{"testUser2"},
+			channelName:   "testUser1",
+			expectedError: nil,
+			expectedName:  "testuser1",
+		},
+		{
+			testName:      "expect error when channel is already live",
+			existing:      []string{"testUser1"},
+			channelName:   "TESTUSER1",
+			expectedError: ErrChannelExists,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			r := NewRegistry()
+			for _, name := range test.existing {
+				if _, err := r.Open(name, 1, broadcast.VisibilityPublic); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			channel, err := r.Open(test.channelName, 2, broadcast.VisibilityPrivate)
+
+			if !cmp.Equal(err, test.expectedError, cmpopts.EquateErrors()) {
+				t.Fatal(cmp.Diff(err, test.expectedError, cmpopts.EquateErrors()))
+			}
+
+			if err != nil {
+				return
+			}
+
+			if channel.Name != test.expectedName {
+				t.Fatal(cmp.Diff(channel.Name, test.expectedName))
+			}
+
+			if channel.Queue == nil {
+				t.Fatal("channel queue should not be nil")
+			}
+		})
+	}
+}
+
+func TestRegistry_Get(t *testing.T) {
+	r := NewRegistry()
+
+	opened, err := r.Open("testUser1", 1, broadcast.VisibilityUnlisted)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	channel, err := r.Get("TestUser1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if channel != opened {
+		t.Fatal("expected the opened channel to be returned")
+	}
+
+	_, err = r.Get("testUser2")
+	if !cmp.Equal(err, ErrChannelNotFound, cmpopts.EquateErrors()) {
+		t.Fatal(cmp.Diff(err, ErrChannelNotFound, cmpopts.EquateErrors()))
+	}
+}
+
+func TestRegistry_Close(t *testing.T) {
+	r := NewRegistry()
+
+	first, err := r.Open("testUser1", 1, broadcast.VisibilityPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.Close(first)
+
+	if _, err = r.Get("testUser1"); err != ErrChannelNotFound {
+		t.Fatal("expected channel to be removed after close")
+	}
+
+	if _, err = first.Queue.Latest().ReadPacket(); err == nil {
+		t.Fatal("expected closed queue to return an error")
+	}
+
+	// Closing a stale channel must not remove its replacement.
+	second, err := r.Open("testUser1", 1, broadcast.VisibilityPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.Close(first)
+
+	channel, err := r.Get("testUser1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if channel != second {
+		t.Fatal("expected replacement channel to remain registered")
+	}
+}
+
+func TestRegistry_List(t *testing.T) {
+	r := NewRegistry()
+
+	for _, name := range []string{"testUser3", "testUser1", "testUser2"} {
+		if _, err := r.Open(name, 1, broadcast.VisibilityPublic); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names := make([]string, 0)
+	for _, channel := range r.List() {
+		names = append(names, channel.Name)
+	}
+
+	expected := []string{"testuser1", "testuser2", "testuser3"}
+	if !cmp.Equal(names, expected) {
+		t.Fatal(cmp.Diff(names, expected))
+	}
+}
@@ -0,0 +1,130 @@
+package paginate
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+)
+
+var (
+	ErrInvalidCursor = errors.New("cursor is invalid")
+)
+
+// Cursor points between pages at a row, by the value of the field rows are
+// ordered by and the ID breaking ties between rows of equal value. Values are
+// kept as text, and compared by the storage as the type of the field.
+type Cursor struct {
+	Field string `json:"f"` // the order key of the rows, see QueryOptions.OrderKey
+	Value string `json:"v"`
+	Id    string `json:"i"`
+
+	// The values of the row for each of the orders of QueryOptions.ThenBy
+	Then []string `json:"t,omitempty"`
+
+	// Backward cursors select the page before the row rather than after it
+	Backward bool `json:"b,omitempty"`
+}
+
+// Page holds the encoded cursors of the pages either side of a page of rows,
+// empty where there are no more rows.
+type Page struct {
+	Next string
+	Prev string
+}
+
+// Encode returns the cursor as an opaque string to be handed to clients.
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor returns the cursor encoded by Encode, or ErrInvalidCursor.
+func DecodeCursor(encoded string) (Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(b, &cursor); err != nil || cursor.Field == "" {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	return cursor, nil
+}
+
+// Paginate completes a page of rows fetched for the given options, with up to one row more than
+// the limit to tell whether there are more. rows points to the slice of rows, which is trimmed to
+// the limit and put back in order if the rows were fetched backwards, ready to be returned. key
+// returns the values of the row at the given index of the completed page for each of the orders
+// of the options, and its ID.
+func Paginate(rows interface{}, options QueryOptions, key func(i int) (values []string, id string)) Page {
+	slice := reflect.ValueOf(rows).Elem()
+
+	more := slice.Len() > int(options.Limit)
+	if more {
+		slice.Set(slice.Slice(0, int(options.Limit)))
+	}
+
+	backward := options.Cursor != nil && options.Cursor.Backward
+	if backward {
+		swap := reflect.Swapper(slice.Interface())
+		for i, j := 0, slice.Len()-1; i < j; i, j = i+1, j-1 {
+			swap(i, j)
+		}
+	}
+
+	// Paging from a cursor, the rows on the side of it we came from remain.
+	hasNext, hasPrev := more, options.Offset > 0
+	if options.Cursor != nil {
+		if backward {
+			hasNext, hasPrev = true, more
+		} else {
+			hasNext, hasPrev = more, true
+		}
+	}
+
+	page := Page{}
+	if slice.Len() == 0 {
+		// Past the last row, the way back is the cursor turned around.
+		if options.Cursor != nil {
+			turned := *options.Cursor
+			turned.Backward = !turned.Backward
+
+			if backward {
+				page.Next = turned.Encode()
+			} else {
+				page.Prev = turned.Encode()
+			}
+		}
+
+		return page
+	}
+
+	if hasNext {
+		page.Next = cursorAt(options, key, slice.Len()-1, false).Encode()
+	}
+
+	if hasPrev {
+		page.Prev = cursorAt(options, key, 0, true).Encode()
+	}
+
+	return page
+}
+
+// cursorAt returns the cursor pointing at the row at the given index.
+func cursorAt(options QueryOptions, key func(i int) ([]string, string), i int, backward bool) Cursor {
+	values, id := key(i)
+
+	cursor := Cursor{Field: options.OrderKey(), Id: id, Backward: backward}
+	if len(values) > 0 {
+		cursor.Value, cursor.Then = values[0], values[1:]
+	}
+
+	if len(cursor.Then) == 0 {
+		cursor.Then = nil
+	}
+
+	return cursor
+}
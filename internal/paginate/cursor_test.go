@@ -0,0 +1,123 @@
+package paginate
+
+import (
+	"github.com/google/go-cmp/cmp"
+	"strconv"
+	"testing"
+)
+
+func TestDecodeCursor(t *testing.T) {
+	t.Parallel()
+
+	cursor := Cursor{Field: "published_at", Value: "2026-10-18 20:00:00", Id: "42", Backward: true}
+
+	decoded, err := DecodeCursor(cursor.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !cmp.Equal(decoded, cursor) {
+		t.Fatal(cmp.Diff(decoded, cursor))
+	}
+
+	for _, encoded := range []string{"", "not a cursor", Cursor{Value: "1"}.Encode()} {
+		if _, err := DecodeCursor(encoded); err != ErrInvalidCursor {
+			t.Fatalf("expected %v decoding %q, got %v", ErrInvalidCursor, encoded, err)
+		}
+	}
+}
+
+func TestNewPaginateOptions_Cursor(t *testing.T) {
+	t.Parallel()
+
+	cursor := Cursor{Field: "username", Value: "a", Id: "1"}
+
+	options := NewPaginateOptions(WithCursor(cursor), WithOrderField("username"))
+	if !cmp.Equal(options.Cursor, &cursor) {
+		t.Fatal(cmp.Diff(options.Cursor, &cursor))
+	}
+
+	// Cursors taken from rows in another order are discarded.
+	options = NewPaginateOptions(WithCursor(cursor))
+	if options.Cursor != nil {
+		t.Fatalf("expected the cursor discarded, got %+v", options.Cursor)
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	cursorAt := func(id int, backward bool) string {
+		return Cursor{Field: "id", Value: strconv.Itoa(id), Id: strconv.Itoa(id), Backward: backward}.Encode()
+	}
+
+	tests := []struct {
+		testName string
+		options  QueryOptions
+		fetched  []int
+
+		expectedRows []int
+		expectedPage Page
+	}{
+		{
+			testName:     "Expect no cursors for a single page.",
+			options:      NewPaginateOptions(WithLimit(3)),
+			fetched:      []int{1, 2},
+			expectedRows: []int{1, 2},
+			expectedPage: Page{},
+		},
+		{
+			testName:     "Expect a cursor to the next page when a row more was fetched.",
+			options:      NewPaginateOptions(WithLimit(2)),
+			fetched:      []int{1, 2, 3},
+			expectedRows: []int{1, 2},
+			expectedPage: Page{Next: cursorAt(2, false)},
+		},
+		{
+			testName:     "Expect a cursor to the previous page when offset.",
+			options:      NewPaginateOptions(WithLimit(2), WithOffset(2)),
+			fetched:      []int{3, 4},
+			expectedRows: []int{3, 4},
+			expectedPage: Page{Prev: cursorAt(3, true)},
+		},
+		{
+			testName:     "Expect both cursors paging forward from a cursor with more rows.",
+			options:      NewPaginateOptions(WithLimit(2), WithCursor(Cursor{Field: "id", Value: "2", Id: "2"})),
+			fetched:      []int{3, 4, 5},
+			expectedRows: []int{3, 4},
+			expectedPage: Page{Next: cursorAt(4, false), Prev: cursorAt(3, true)},
+		},
+		{
+			testName: "Expect rows fetched backward from a cursor put back in order.",
+			options: NewPaginateOptions(
+				WithLimit(2), WithCursor(Cursor{Field: "id", Value: "3", Id: "3", Backward: true}),
+			),
+			fetched:      []int{2, 1},
+			expectedRows: []int{1, 2},
+			expectedPage: Page{Next: cursorAt(2, false)},
+		},
+		{
+			testName:     "Expect the way back past the last row to be the cursor turned around.",
+			options:      NewPaginateOptions(WithLimit(2), WithCursor(Cursor{Field: "id", Value: "5", Id: "5"})),
+			fetched:      []int{},
+			expectedRows: []int{},
+			expectedPage: Page{Prev: cursorAt(5, true)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			rows := test.fetched
+
+			page := Paginate(&rows, test.options, func(i int) ([]string, string) {
+				return []string{strconv.Itoa(rows[i])}, strconv.Itoa(rows[i])
+			})
+
+			if !cmp.Equal(rows, test.expectedRows) {
+				t.Fatal(cmp.Diff(rows, test.expectedRows))
+			}
+
+			if !cmp.Equal(page, test.expectedPage) {
+				t.Fatal(cmp.Diff(page, test.expectedPage))
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package paginate
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"time"
+)
+
+var (
+	ErrInvalidSort   = errors.New("sort must be a comma separated list of sortable fields, descending if prefixed with -")
+	ErrInvalidFilter = errors.New("filter is invalid")
+)
+
+type Operator string
+
+const (
+	OperatorEquals Operator = "eq"
+	OperatorIn     Operator = "in"
+	OperatorRange  Operator = "range"
+	OperatorILike  Operator = "ilike"
+)
+
+// Kind is the type of the values of a field.
+type Kind int
+
+const (
+	KindString Kind = iota
+	KindInt
+	KindBool
+	KindTime
+	KindUUID
+)
+
+// operators are the filters each kind of field may be filtered with.
+var operators = map[Kind][]Operator{
+	KindString: {OperatorEquals, OperatorIn, OperatorILike},
+	KindInt:    {OperatorEquals, OperatorIn, OperatorRange},
+	KindBool:   {OperatorEquals},
+	KindTime:   {OperatorEquals, OperatorRange},
+	KindUUID:   {OperatorEquals, OperatorIn},
+}
+
+// Filter restricts listed rows to those whose field compares with its values.
+type Filter struct {
+	Field    string
+	Operator Operator
+
+	// One value for equals and ILIKE, one or more for in, and the lower and upper
+	// bounds for range, inclusive. Either bound may be nil for an open range.
+	// Values are of the Go type of the kind of the field: string, int64, bool,
+	// time.Time or uuid.UUID.
+	Values []interface{}
+}
+
+// Equals filters rows to those whose field is value.
+func Equals(field string, value interface{}) Filter {
+	return Filter{Field: field, Operator: OperatorEquals, Values: []interface{}{value}}
+}
+
+// In filters rows to those whose field is any of values.
+func In(field string, values ...interface{}) Filter {
+	return Filter{Field: field, Operator: OperatorIn, Values: values}
+}
+
+// Range filters rows to those whose field is between from and to inclusive,
+// either of which may be nil to leave the range open.
+func Range(field string, from interface{}, to interface{}) Filter {
+	return Filter{Field: field, Operator: OperatorRange, Values: []interface{}{from, to}}
+}
+
+// ILike filters rows to those whose field matches the case insensitive LIKE pattern.
+func ILike(field string, pattern string) Filter {
+	return Filter{Field: field, Operator: OperatorILike, Values: []interface{}{pattern}}
+}
+
+// Field describes a field of listed rows callers may order or filter by.
+type Field struct {
+	Kind       Kind
+	Sortable   bool
+	Filterable bool
+}
+
+// Schema whitelists the fields of an entity callers may order or filter listings
+// of it by, keyed by column name. As names are written into queries, storages
+// check options against the schema of what they list before querying.
+type Schema map[string]Field
+
+// Validate returns ErrInvalidSort if the options order by a field that is not
+// sortable or in an unknown direction, or ErrInvalidFilter if they filter by a
+// field that is not filterable, with an operator the kind of field does not
+// support or with values of another kind.
+func (s Schema) Validate(options QueryOptions) error {
+	for _, order := range options.Orders() {
+		field, ok := s[order.Field]
+		if !ok || !field.Sortable || (order.Method != OrderMethodAsc && order.Method != OrderMethodDesc) {
+			return fmt.Errorf("%w: %q", ErrInvalidSort, order.Field)
+		}
+	}
+
+	for _, filter := range options.Filters {
+		if err := s.validateFilter(filter); err != nil {
+			return fmt.Errorf("%w: %q", err, filter.Field)
+		}
+	}
+
+	return nil
+}
+
+// validateFilter returns ErrInvalidFilter if the filter may not be applied.
+func (s Schema) validateFilter(filter Filter) error {
+	field, ok := s[filter.Field]
+	if !ok || !field.Filterable || !supports(field.Kind, filter.Operator) {
+		return ErrInvalidFilter
+	}
+
+	switch filter.Operator {
+	case OperatorRange:
+		if len(filter.Values) != 2 || (filter.Values[0] == nil && filter.Values[1] == nil) {
+			return ErrInvalidFilter
+		}
+	case OperatorIn:
+		if len(filter.Values) == 0 {
+			return ErrInvalidFilter
+		}
+	default:
+		if len(filter.Values) != 1 {
+			return ErrInvalidFilter
+		}
+	}
+
+	for _, value := range filter.Values {
+		if value == nil && filter.Operator == OperatorRange {
+			continue
+		}
+
+		if !isKind(field.Kind, value) {
+			return ErrInvalidFilter
+		}
+	}
+
+	return nil
+}
+
+// supports returns true if fields of the kind may be filtered with the operator.
+func supports(kind Kind, operator Operator) bool {
+	for _, other := range operators[kind] {
+		if other == operator {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isKind returns true if value is of the Go type of the kind.
+func isKind(kind Kind, value interface{}) bool {
+	switch value.(type) {
+	case string:
+		return kind == KindString
+	case int64:
+		return kind == KindInt
+	case bool:
+		return kind == KindBool
+	case time.Time:
+		return kind == KindTime
+	case uuid.UUID:
+		return kind == KindUUID
+	default:
+		return false
+	}
+}
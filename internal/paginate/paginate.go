@@ -1,6 +1,9 @@
 package paginate
 
-import log "github.com/sirupsen/logrus"
+import (
+	log "github.com/sirupsen/logrus"
+	"strings"
+)
 
 type OrderMethod string
 
@@ -18,6 +21,17 @@ type QueryOptions struct {
 	Limit  uint
 	Offset uint
 	Order  Order
+
+	// ThenBy orders rows of equal Order, each order breaking ties of those before.
+	ThenBy []Order
+
+	// Filters restrict the rows listed to those matching every filter.
+	Filters []Filter
+
+	// Cursor pages from a row of a previous page rather than by offset, which is
+	// then ignored. Rows are found by their order key, so pages stay consistent
+	// as rows are inserted while scrolling.
+	Cursor *Cursor
 }
 
 type FuncOption func(options *QueryOptions)
@@ -38,9 +52,36 @@ func NewPaginateOptions(opts ...FuncOption) QueryOptions {
 		opt(&paginateOptions)
 	}
 
+	cursor := paginateOptions.Cursor
+	if cursor != nil && (cursor.Field != paginateOptions.OrderKey() || len(cursor.Then) != len(paginateOptions.ThenBy)) {
+		log.Warnf("cursor taken from rows ordered by %s, discarding", paginateOptions.Cursor.Field)
+		paginateOptions.Cursor = nil
+	}
+
 	return paginateOptions
 }
 
+// Orders returns every order rows are listed in, Order first.
+func (o QueryOptions) Orders() []Order {
+	return append([]Order{o.Order}, o.ThenBy...)
+}
+
+// OrderKey returns the comma separated fields rows are ordered by, which cursors
+// taken from the rows are marked with.
+func (o QueryOptions) OrderKey() string {
+	return OrderKey(o.Orders())
+}
+
+// OrderKey returns the comma separated fields of the orders.
+func OrderKey(orders []Order) string {
+	fields := make([]string, 0, len(orders))
+	for _, order := range orders {
+		fields = append(fields, order.Field)
+	}
+
+	return strings.Join(fields, ",")
+}
+
 func WithLimit(limit uint) FuncOption {
 	return func(p *QueryOptions) {
 		if limit <= 0 || limit > 1000 {
@@ -74,3 +115,27 @@ func WithOrder(method OrderMethod) FuncOption {
 		p.Order.Method = method
 	}
 }
+
+func WithCursor(cursor Cursor) FuncOption {
+	return func(p *QueryOptions) {
+		p.Cursor = &cursor
+	}
+}
+
+// WithSort orders rows by the first of the orders, then by each of the rest in turn.
+func WithSort(orders ...Order) FuncOption {
+	return func(p *QueryOptions) {
+		if len(orders) == 0 {
+			return
+		}
+
+		p.Order = orders[0]
+		p.ThenBy = append([]Order{}, orders[1:]...)
+	}
+}
+
+func WithFilters(filters ...Filter) FuncOption {
+	return func(p *QueryOptions) {
+		p.Filters = append(p.Filters, filters...)
+	}
+}
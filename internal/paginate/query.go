@@ -0,0 +1,186 @@
+package paginate
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rangeBounds maps the bracketed operators of range filters to the bound they set.
+var rangeBounds = map[string]int{
+	"gte": 0,
+	"lte": 1,
+}
+
+// ParseSort reads the orders given by the value of a sort query parameter, a comma
+// separated list of sortable fields of the schema, each descending if prefixed with
+// a - and ascending otherwise. ErrInvalidSort is returned for any other field.
+func ParseSort(value string, schema Schema) ([]Order, error) {
+	names := strings.Split(value, ",")
+	orders := make([]Order, 0, len(names))
+
+	for _, name := range names {
+		order := Order{Field: strings.TrimSpace(name), Method: OrderMethodAsc}
+		if strings.HasPrefix(order.Field, "-") {
+			order.Field, order.Method = order.Field[1:], OrderMethodDesc
+		}
+
+		if field, ok := schema[order.Field]; !ok || !field.Sortable {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidSort, order.Field)
+		}
+
+		for _, other := range orders {
+			if other.Field == order.Field {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidSort, order.Field)
+			}
+		}
+
+		orders = append(orders, order)
+	}
+
+	return orders, nil
+}
+
+// ParseFilters reads the filters on fields of the schema given by query parameters,
+// named by the field with the operator in brackets:
+//
+//	category=speedruns             equals
+//	category[in]=speedruns,retro   in
+//	viewer_count[gte]=10           range, with either or both bounds
+//	title[ilike]=%any%             ILIKE
+//
+// Values are parsed as the kind of the field, times as RFC 3339. Parameters without
+// brackets naming no field of the schema are left to the caller, while ErrInvalidFilter
+// is returned for any other that cannot be applied.
+func ParseFilters(query url.Values, schema Schema) ([]Filter, error) {
+	params := make([]string, 0, len(query))
+	for param := range query {
+		params = append(params, param)
+	}
+	sort.Strings(params)
+
+	filters := make([]Filter, 0)
+	ranges := make(map[string]int)
+
+	for _, param := range params {
+		name, operator := param, string(OperatorEquals)
+		if i := strings.IndexByte(param, '['); i >= 0 && strings.HasSuffix(param, "]") {
+			name, operator = param[:i], param[i+1:len(param)-1]
+		} else if _, ok := schema[name]; !ok {
+			continue
+		}
+
+		field, ok := schema[name]
+		if !ok || !field.Filterable {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidFilter, param)
+		}
+
+		filter, err := parseFilter(name, operator, field.Kind, query.Get(param))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", err, param)
+		}
+
+		// Both bounds of a range are kept in one filter.
+		if i, ok := ranges[name]; ok && filter.Operator == OperatorRange {
+			bound := rangeBounds[operator]
+			if filters[i].Values[bound] != nil {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidFilter, param)
+			}
+
+			filters[i].Values[bound] = filter.Values[bound]
+			continue
+		}
+
+		if filter.Operator == OperatorRange {
+			ranges[name] = len(filters)
+		}
+
+		if !supports(field.Kind, filter.Operator) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidFilter, param)
+		}
+
+		filters = append(filters, filter)
+	}
+
+	return filters, nil
+}
+
+// parseFilter returns the filter on the named field given by a bracketed operator
+// and the value of its parameter.
+func parseFilter(name string, operator string, kind Kind, value string) (Filter, error) {
+	if bound, ok := rangeBounds[operator]; ok {
+		parsed, err := parseValue(kind, value)
+		if err != nil {
+			return Filter{}, err
+		}
+
+		filter := Range(name, nil, nil)
+		filter.Values[bound] = parsed
+
+		return filter, nil
+	}
+
+	switch Operator(operator) {
+	case OperatorEquals:
+		parsed, err := parseValue(kind, value)
+		if err != nil {
+			return Filter{}, err
+		}
+
+		return Equals(name, parsed), nil
+	case OperatorIn:
+		values := strings.Split(value, ",")
+
+		filter := In(name)
+		for _, v := range values {
+			parsed, err := parseValue(kind, strings.TrimSpace(v))
+			if err != nil {
+				return Filter{}, err
+			}
+
+			filter.Values = append(filter.Values, parsed)
+		}
+
+		return filter, nil
+	case OperatorILike:
+		if kind != KindString || value == "" {
+			return Filter{}, ErrInvalidFilter
+		}
+
+		return ILike(name, value), nil
+	default:
+		return Filter{}, ErrInvalidFilter
+	}
+}
+
+// parseValue parses a value of the kind from text, returning ErrInvalidFilter if it
+// is not one.
+func parseValue(kind Kind, value string) (interface{}, error) {
+	var parsed interface{}
+	var err error
+
+	switch kind {
+	case KindString:
+		parsed = value
+	case KindInt:
+		parsed, err = strconv.ParseInt(value, 10, 64)
+	case KindBool:
+		parsed, err = strconv.ParseBool(value)
+	case KindTime:
+		parsed, err = time.Parse(time.RFC3339, value)
+	case KindUUID:
+		parsed, err = uuid.Parse(value)
+	default:
+		err = ErrInvalidFilter
+	}
+
+	if err != nil {
+		return nil, ErrInvalidFilter
+	}
+
+	return parsed, nil
+}
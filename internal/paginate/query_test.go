@@ -0,0 +1,143 @@
+package paginate
+
+import (
+	"errors"
+	"github.com/google/go-cmp/cmp"
+	"net/url"
+	"testing"
+	"time"
+)
+
+var testSchema = Schema{
+	"id":           {Kind: KindInt, Sortable: true, Filterable: true},
+	"title":        {Kind: KindString, Sortable: true, Filterable: true},
+	"category":     {Kind: KindString, Filterable: true},
+	"mature":       {Kind: KindBool, Filterable: true},
+	"viewer_count": {Kind: KindInt, Sortable: true, Filterable: true},
+	"published_at": {Kind: KindTime, Sortable: true, Filterable: true},
+	"email":        {Kind: KindString},
+}
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		testName string
+		value    string
+
+		expectedOrders []Order
+		expectedErr    error
+	}{
+		{
+			testName: "Expect fields ordered ascending unless prefixed with -.",
+			value:    "-viewer_count, title",
+			expectedOrders: []Order{
+				{Field: "viewer_count", Method: OrderMethodDesc},
+				{Field: "title", Method: OrderMethodAsc},
+			},
+		},
+		{
+			testName:    "Expect error ordering by a field missing from the schema.",
+			value:       "title,password",
+			expectedErr: ErrInvalidSort,
+		},
+		{
+			testName:    "Expect error ordering by a field that is not sortable.",
+			value:       "-category",
+			expectedErr: ErrInvalidSort,
+		},
+		{
+			testName:    "Expect error ordering by a field twice.",
+			value:       "title,-title",
+			expectedErr: ErrInvalidSort,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			orders, err := ParseSort(test.value, testSchema)
+			if !errors.Is(err, test.expectedErr) {
+				t.Fatalf("expected %v, got %v", test.expectedErr, err)
+			}
+
+			if !cmp.Equal(orders, test.expectedOrders) {
+				t.Fatal(cmp.Diff(orders, test.expectedOrders))
+			}
+		})
+	}
+}
+
+func TestParseFilters(t *testing.T) {
+	tests := []struct {
+		testName string
+		query    string
+
+		expectedFilters []Filter
+		expectedErr     error
+	}{
+		{
+			testName: "Expect filters of each operator, parsed as the kind of their field.",
+			query: "category[in]=speedruns,retro&mature=false&published_at[gte]=2026-10-18T20:00:00Z" +
+				"&title[ilike]=%25any%25&viewer_count[gte]=10&viewer_count[lte]=100&limit=5&q=any",
+			expectedFilters: []Filter{
+				In("category", "speedruns", "retro"),
+				Equals("mature", false),
+				Range("published_at", time.Date(2026, 10, 18, 20, 0, 0, 0, time.UTC), nil),
+				ILike("title", "%any%"),
+				Range("viewer_count", int64(10), int64(100)),
+			},
+		},
+		{
+			testName:        "Expect no filters from parameters naming no field.",
+			query:           "limit=5&cursor=abc",
+			expectedFilters: []Filter{},
+		},
+		{
+			testName:    "Expect error filtering by a bracketed field missing from the schema.",
+			query:       "password[eq]=secret",
+			expectedErr: ErrInvalidFilter,
+		},
+		{
+			testName:    "Expect error filtering by a field that is not filterable.",
+			query:       "email=a@example.com",
+			expectedErr: ErrInvalidFilter,
+		},
+		{
+			testName:    "Expect error filtering with an unknown operator.",
+			query:       "title[like]=any",
+			expectedErr: ErrInvalidFilter,
+		},
+		{
+			testName:    "Expect error filtering with an operator the kind of field does not support.",
+			query:       "mature[gte]=true",
+			expectedErr: ErrInvalidFilter,
+		},
+		{
+			testName:    "Expect error filtering with a value not of the kind of the field.",
+			query:       "viewer_count=many",
+			expectedErr: ErrInvalidFilter,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			query, err := url.ParseQuery(test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			filters, err := ParseFilters(query, testSchema)
+			if !errors.Is(err, test.expectedErr) {
+				t.Fatalf("expected %v, got %v", test.expectedErr, err)
+			}
+
+			if !cmp.Equal(filters, test.expectedFilters) {
+				t.Fatal(cmp.Diff(filters, test.expectedFilters))
+			}
+
+			if err == nil {
+				if err := testSchema.Validate(NewPaginateOptions(WithFilters(filters...))); err != nil {
+					t.Fatalf("expected parsed filters to be valid, got %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package playback
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"github.com/M-Ro/go-vodstream/internal/domain"
+	"github.com/M-Ro/go-vodstream/internal/domain/broadcast"
+	"github.com/M-Ro/go-vodstream/internal/domain/user"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/spf13/viper"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	ErrTokenRequired = errors.New("a token is required to play this broadcast")
+	ErrTokenInvalid  = errors.New("token is not valid")
+	ErrNotPermitted  = errors.New("viewer is not permitted to play this broadcast")
+)
+
+// TokenQueryParam is the URL query parameter viewers present their token in.
+const TokenQueryParam = "token"
+
+// audiencePrefix prefixes the channel name in the audience claim of playback tokens.
+const audiencePrefix = "playback:"
+
+// UserProvider looks up the viewer a token was issued to.
+type UserProvider interface {
+	GetByUsername(ctx context.Context, username string) (user.User, error)
+}
+
+type Config struct {
+	SigningSecret string
+	IssuerIdent   string
+	TokenDuration time.Duration
+
+	// URLSigningSecret signs media URLs handed to viewers.
+	URLSigningSecret string
+	URLDuration      time.Duration
+
+	// BindURLToAddress restricts signed URLs to the address they were requested from.
+	BindURLToAddress bool
+}
+
+// Resource describes the broadcast a viewer is requesting to play.
+type Resource struct {
+	Name          string
+	BroadcasterId uint64
+	Visibility    broadcast.Visibility
+}
+
+// Viewer is the result of a successful authorization. Anonymous viewers have no user.
+type Viewer struct {
+	Anonymous bool
+	User      user.User
+}
+
+type Authorizer struct {
+	config Config
+	users  UserProvider
+}
+
+// Audience returns the audience claim used by playback tokens for the named channel.
+func Audience(name string) string {
+	return audiencePrefix + strings.ToLower(name)
+}
+
+// Authorize decides if the holder of token may play the given resource.
+// Public and unlisted broadcasts may be played anonymously, private broadcasts
+// require a token for a user with the CanStream permission. A token, when
+// provided, is always verified, but never loses a viewer access anonymous
+// viewers have.
+func (a Authorizer) Authorize(ctx context.Context, resource Resource, token string) (Viewer, error) {
+	if token == "" {
+		if resource.Visibility == broadcast.VisibilityPrivate {
+			return Viewer{}, ErrTokenRequired
+		}
+
+		return Viewer{Anonymous: true}, nil
+	}
+
+	claim, err := a.parseToken(token)
+	if err != nil {
+		return Viewer{}, err
+	}
+
+	// Session tokens carry no audience and are accepted for any channel, playback
+	// tokens are only accepted for the channel they were issued for.
+	if len(claim.Audience) > 0 && !claim.VerifyAudience(Audience(resource.Name), true) {
+		return Viewer{}, ErrTokenInvalid
+	}
+
+	viewer, err := a.users.GetByUsername(ctx, claim.Username)
+	if err != nil {
+		return Viewer{}, ErrTokenInvalid
+	}
+
+	// Broadcasters may always watch their own broadcasts.
+	if viewer.Id == resource.BroadcasterId {
+		return Viewer{User: viewer}, nil
+	}
+
+	if resource.Visibility == broadcast.VisibilityPrivate && !viewer.CanStream {
+		return Viewer{}, ErrNotPermitted
+	}
+
+	return Viewer{User: viewer}, nil
+}
+
+// Authenticate verifies a session token and returns the user it was issued to.
+// Playback tokens are rejected so they cannot be exchanged for fresh tokens.
+func (a Authorizer) Authenticate(ctx context.Context, token string) (user.User, error) {
+	claim, err := a.parseToken(token)
+	if err != nil {
+		return user.User{}, err
+	}
+
+	if len(claim.Audience) > 0 {
+		return user.User{}, ErrTokenInvalid
+	}
+
+	viewer, err := a.users.GetByUsername(ctx, claim.Username)
+	if err != nil {
+		return user.User{}, ErrTokenInvalid
+	}
+
+	return viewer, nil
+}
+
+// IssueToken returns a short-lived signed token permitting the given user to play
+// the named channel.
+func (a Authorizer) IssueToken(username string, name string) (string, error) {
+	claims := domain.AuthClaim{
+		Username: username,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{Audience(name)},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(a.config.TokenDuration)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    a.config.IssuerIdent,
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	return token.SignedString([]byte(a.config.SigningSecret))
+}
+
+// parseToken verifies the signature, expiry and issuer of the given token.
+func (a Authorizer) parseToken(token string) (domain.AuthClaim, error) {
+	var claim domain.AuthClaim
+
+	parsed, err := jwt.ParseWithClaims(token, &claim, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+
+		return []byte(a.config.SigningSecret), nil
+	})
+	if err != nil || !parsed.Valid {
+		return domain.AuthClaim{}, ErrTokenInvalid
+	}
+
+	if subtle.ConstantTimeCompare([]byte(claim.Issuer), []byte(a.config.IssuerIdent)) != 1 {
+		return domain.AuthClaim{}, ErrTokenInvalid
+	}
+
+	return claim, nil
+}
+
+// TokenFromRequest extracts a viewer token from an HTTP request, either from the
+// token query parameter or a bearer Authorization header.
+func TokenFromRequest(r *http.Request) string {
+	if token := r.URL.Query().Get(TokenQueryParam); token != "" {
+		return token
+	}
+
+	header := r.Header.Get("Authorization")
+	if strings.HasPrefix(header, "Bearer ") {
+		return strings.TrimPrefix(header, "Bearer ")
+	}
+
+	return ""
+}
+
+// StatusCode maps an authorization error to the HTTP status returned to the viewer.
+func StatusCode(err error) int {
+	switch err {
+	case nil:
+		return http.StatusOK
+	case ErrTokenRequired, ErrTokenInvalid:
+		return http.StatusUnauthorized
+	default:
+		return http.StatusForbidden
+	}
+}
+
+func GetConfig() Config {
+	viper.SetDefault("api.auth.signing_secret", "change")
+	viper.SetDefault("api.auth.ident", "vodstream")
+	viper.SetDefault("api.auth.playback_token_duration", "5m")
+	viper.SetDefault("api.playback.url_signing_secret", "change")
+	viper.SetDefault("api.playback.url_duration", "6h")
+	viper.SetDefault("api.playback.bind_url_to_address", false)
+
+	return Config{
+		SigningSecret:    viper.GetString("api.auth.signing_secret"),
+		IssuerIdent:      viper.GetString("api.auth.ident"),
+		TokenDuration:    viper.GetDuration("api.auth.playback_token_duration"),
+		URLSigningSecret: viper.GetString("api.playback.url_signing_secret"),
+		URLDuration:      viper.GetDuration("api.playback.url_duration"),
+		BindURLToAddress: viper.GetBool("api.playback.bind_url_to_address"),
+	}
+}
+
+func NewAuthorizer(config Config, users UserProvider) Authorizer {
+	return Authorizer{
+		config: config,
+		users:  users,
+	}
+}
@@ -0,0 +1,279 @@
+package playback
+
+import (
+	"context"
+	"github.com/M-Ro/go-vodstream/internal/domain"
+	"github.com/M-Ro/go-vodstream/internal/domain/broadcast"
+	"github.com/M-Ro/go-vodstream/internal/domain/user"
+	userRepository "github.com/M-Ro/go-vodstream/internal/user"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testConfig = Config{
+	SigningSecret: "testSecret",
+	IssuerIdent:   "vodstream-test",
+	TokenDuration: time.Minute,
+}
+
+type mockUserProvider map[string]user.User
+
+func (m mockUserProvider) GetByUsername(_ context.Context, username string) (user.User, error) {
+	u, ok := m[strings.ToLower(username)]
+	if !ok {
+		return user.User{}, userRepository.ErrUserNotFound
+	}
+
+	return u, nil
+}
+
+var testUsers = mockUserProvider{
+	"broadcaster": {Id: 1, Username: "broadcaster", CanPublish: true},
+	"viewer":      {Id: 2, Username: "viewer", CanStream: true},
+	"banned":      {Id: 3, Username: "banned", CanStream: false},
+}
+
+// signClaim signs a claim with the given secret, for producing tokens the
+// Authorizer did not issue itself.
+func signClaim(t *testing.T, claim domain.AuthClaim, secret string) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claim).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+func sessionClaim(username string, expiresAt time.Time) domain.AuthClaim {
+	return domain.AuthClaim{
+		Username: username,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			Issuer:    testConfig.IssuerIdent,
+		},
+	}
+}
+
+func TestAuthorizer_Authorize(t *testing.T) {
+	a := NewAuthorizer(testConfig, testUsers)
+
+	viewerPlaybackToken, err := a.IssueToken("viewer", "broadcaster")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherChannelToken, err := a.IssueToken("viewer", "someoneElse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	public := Resource{Name: "broadcaster", BroadcasterId: 1, Visibility: broadcast.VisibilityPublic}
+	unlisted := Resource{Name: "broadcaster", BroadcasterId: 1, Visibility: broadcast.VisibilityUnlisted}
+	private := Resource{Name: "broadcaster", BroadcasterId: 1, Visibility: broadcast.VisibilityPrivate}
+
+	tests := []struct {
+		testName       string
+		resource       Resource
+		token          string
+		expectedError  error
+		expectedViewer Viewer
+	}{
+		{
+			testName:       "expect anonymous viewer on public broadcast without token",
+			resource:       public,
+			token:          "",
+			expectedError:  nil,
+			expectedViewer: Viewer{Anonymous: true},
+		},
+		{
+			testName:       "expect anonymous viewer on unlisted broadcast without token",
+			resource:       unlisted,
+			token:          "",
+			expectedError:  nil,
+			expectedViewer: Viewer{Anonymous: true},
+		},
+		{
+			testName:      "expect error on private broadcast without token",
+			resource:      private,
+			token:         "",
+			expectedError: ErrTokenRequired,
+		},
+		{
+			testName:       "expect success on private broadcast with session token",
+			resource:       private,
+			token:          signClaim(t, sessionClaim("viewer", time.Now().Add(time.Hour)), testConfig.SigningSecret),
+			expectedError:  nil,
+			expectedViewer: Viewer{User: testUsers["viewer"]},
+		},
+		{
+			testName:       "expect success on private broadcast with playback token",
+			resource:       private,
+			token:          viewerPlaybackToken,
+			expectedError:  nil,
+			expectedViewer: Viewer{User: testUsers["viewer"]},
+		},
+		{
+			testName:      "expect error with playback token for another channel",
+			resource:      private,
+			token:         otherChannelToken,
+			expectedError: ErrTokenInvalid,
+		},
+		{
+			testName:      "expect error with expired token",
+			resource:      private,
+			token:         signClaim(t, sessionClaim("viewer", time.Now().Add(-time.Hour)), testConfig.SigningSecret),
+			expectedError: ErrTokenInvalid,
+		},
+		{
+			testName:      "expect error with token signed by another secret",
+			resource:      public,
+			token:         signClaim(t, sessionClaim("viewer", time.Now().Add(time.Hour)), "otherSecret"),
+			expectedError: ErrTokenInvalid,
+		},
+		{
+			testName:      "expect error with token from another issuer",
+			resource:      public,
+			token:         signClaim(t, domain.AuthClaim{Username: "viewer"}, testConfig.SigningSecret),
+			expectedError: ErrTokenInvalid,
+		},
+		{
+			testName:      "expect error with token for unknown user",
+			resource:      private,
+			token:         signClaim(t, sessionClaim("nobody", time.Now().Add(time.Hour)), testConfig.SigningSecret),
+			expectedError: ErrTokenInvalid,
+		},
+		{
+			testName:      "expect error for user without CanStream on private broadcast",
+			resource:      private,
+			token:         signClaim(t, sessionClaim("banned", time.Now().Add(time.Hour)), testConfig.SigningSecret),
+			expectedError: ErrNotPermitted,
+		},
+		{
+			testName:       "expect user without CanStream to play public broadcast",
+			resource:       public,
+			token:          signClaim(t, sessionClaim("banned", time.Now().Add(time.Hour)), testConfig.SigningSecret),
+			expectedError:  nil,
+			expectedViewer: Viewer{User: testUsers["banned"]},
+		},
+		{
+			testName:       "expect user without CanStream to play unlisted broadcast",
+			resource:       unlisted,
+			token:          signClaim(t, sessionClaim("banned", time.Now().Add(time.Hour)), testConfig.SigningSecret),
+			expectedError:  nil,
+			expectedViewer: Viewer{User: testUsers["banned"]},
+		},
+		{
+			testName:       "expect broadcaster to play own private broadcast",
+			resource:       private,
+			token:          signClaim(t, sessionClaim("broadcaster", time.Now().Add(time.Hour)), testConfig.SigningSecret),
+			expectedError:  nil,
+			expectedViewer: Viewer{User: testUsers["broadcaster"]},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			viewer, err := a.Authorize(context.Background(), test.resource, test.token)
+
+			if !cmp.Equal(err, test.expectedError, cmpopts.EquateErrors()) {
+				t.Fatal(cmp.Diff(err, test.expectedError, cmpopts.EquateErrors()))
+			}
+
+			if !cmp.Equal(viewer, test.expectedViewer) {
+				t.Fatal(cmp.Diff(viewer, test.expectedViewer))
+			}
+		})
+	}
+}
+
+func TestTokenFromRequest(t *testing.T) {
+	tests := []struct {
+		testName      string
+		target        string
+		authorization string
+		expectedToken string
+	}{
+		{
+			testName:      "expect empty token without query or header",
+			target:        "/live/broadcaster.flv",
+			expectedToken: "",
+		},
+		{
+			testName:      "expect token from query parameter",
+			target:        "/live/broadcaster.flv?token=abc",
+			expectedToken: "abc",
+		},
+		{
+			testName:      "expect token from bearer header",
+			target:        "/live/broadcaster.flv",
+			authorization: "Bearer def",
+			expectedToken: "def",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			req := httptest.NewRequest("GET", test.target, nil)
+			if test.authorization != "" {
+				req.Header.Set("Authorization", test.authorization)
+			}
+
+			token := TokenFromRequest(req)
+			if token != test.expectedToken {
+				t.Fatal(cmp.Diff(token, test.expectedToken))
+			}
+		})
+	}
+}
+
+func TestAuthorizer_Authenticate(t *testing.T) {
+	a := NewAuthorizer(testConfig, testUsers)
+
+	playbackToken, err := a.IssueToken("viewer", "broadcaster")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		testName      string
+		token         string
+		expectedError error
+		expectedUser  user.User
+	}{
+		{
+			testName:      "expect user with valid session token",
+			token:         signClaim(t, sessionClaim("viewer", time.Now().Add(time.Hour)), testConfig.SigningSecret),
+			expectedError: nil,
+			expectedUser:  testUsers["viewer"],
+		},
+		{
+			testName:      "expect error with playback token",
+			token:         playbackToken,
+			expectedError: ErrTokenInvalid,
+		},
+		{
+			testName:      "expect error with empty token",
+			token:         "",
+			expectedError: ErrTokenInvalid,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			u, err := a.Authenticate(context.Background(), test.token)
+
+			if !cmp.Equal(err, test.expectedError, cmpopts.EquateErrors()) {
+				t.Fatal(cmp.Diff(err, test.expectedError, cmpopts.EquateErrors()))
+			}
+
+			if !cmp.Equal(u, test.expectedUser) {
+				t.Fatal(cmp.Diff(u, test.expectedUser))
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package playback
+
+import (
+	"github.com/M-Ro/go-vodstream/internal/urlsign"
+	"github.com/google/uuid"
+	"strings"
+)
+
+// Path prefixes media is served beneath, each followed by the name of a channel or the ID of a video.
+const (
+	livePrefix = "/live/"
+	vodPrefix  = "/vod/"
+)
+
+// LivePathPrefix returns the HTTP path prefix live media for the named channel is served beneath.
+func LivePathPrefix(name string) string {
+	return livePrefix + strings.ToLower(name) + "/"
+}
+
+// VodPathPrefix returns the HTTP path prefix media for the given video is served beneath.
+func VodPathPrefix(id uuid.UUID) string {
+	return vodPrefix + id.String() + "/"
+}
+
+// CanonicalPath returns the cleaned media path with the channel name or video ID it is
+// served beneath in lower case, as the prefixes are signed, since both are matched
+// regardless of case.
+func CanonicalPath(path string) string {
+	for _, prefix := range []string{livePrefix, vodPrefix} {
+		if !strings.HasPrefix(path, prefix) {
+			continue
+		}
+
+		rest := path[len(prefix):]
+		end := strings.IndexByte(rest, '/')
+		if end < 0 {
+			end = len(rest)
+		}
+
+		return prefix + strings.ToLower(rest[:end]) + rest[end:]
+	}
+
+	return path
+}
+
+// NewSigner returns the signer of media URLs, comparing paths in their canonical form.
+func NewSigner(config Config) urlsign.Signer {
+	return urlsign.NewSigner(config.URLSigningSecret).WithCanonicalPath(CanonicalPath)
+}
@@ -0,0 +1,59 @@
+package playback
+
+import (
+	"github.com/M-Ro/go-vodstream/internal/urlsign"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestCanonicalPath(t *testing.T) {
+	tests := []struct {
+		testName string
+		path     string
+		expected string
+	}{
+		{"expect channel name lowercased", "/live/Alice/index.m3u8", "/live/alice/index.m3u8"},
+		{"expect video ID lowercased", "/vod/7C9E6679-7425-40DE-944B-E07FC1F90AE7/poster.jpg", "/vod/7c9e6679-7425-40de-944b-e07fc1f90ae7/poster.jpg"},
+		{"expect media name untouched", "/live/alice/Seg3.ts", "/live/alice/Seg3.ts"},
+		{"expect other paths untouched", "/Other/Path", "/Other/Path"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			if got := CanonicalPath(test.path); got != test.expected {
+				t.Fatal(cmp.Diff(got, test.expected))
+			}
+		})
+	}
+}
+
+func TestNewSigner(t *testing.T) {
+	signer := NewSigner(Config{URLSigningSecret: "testUrlSecret"})
+	prefix := LivePathPrefix("Alice")
+	signature := signer.Query(prefix, urlsign.Options{Expires: time.Now().Add(time.Minute), PathPrefix: prefix})
+
+	tests := []struct {
+		testName      string
+		path          string
+		expectedError error
+	}{
+		{"expect success beneath the prefix", "/live/alice/index.m3u8", nil},
+		{"expect success with mixed case channel name", "/live/Alice/index.m3u8", nil},
+		{"expect error climbing out of the prefix", "/live/alice/../bob/index.m3u8", urlsign.ErrOutOfScope},
+		{"expect error climbing out of the prefix in mixed case", "/live/ALICE/../Bob/index.m3u8", urlsign.ErrOutOfScope},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			u := &url.URL{Path: test.path, RawQuery: signature.Encode()}
+
+			err := signer.Verify(u, "192.0.2.1")
+			if !cmp.Equal(err, test.expectedError, cmpopts.EquateErrors()) {
+				t.Fatal(cmp.Diff(err, test.expectedError, cmpopts.EquateErrors()))
+			}
+		})
+	}
+}
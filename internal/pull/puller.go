@@ -0,0 +1,160 @@
+package pull
+
+import (
+	"context"
+	"github.com/nareix/joy4/av"
+	"github.com/nareix/joy4/av/avutil"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"sync"
+	"time"
+)
+
+// Opener connects to a remote source for reading. HLS sources are not
+// supported by joy4, so in practice sources are RTMP, RTSP or local files.
+type Opener func(url string) (av.DemuxCloser, error)
+
+// PublishFunc republishes packets read from a source into its local channel,
+// returning once the source ends or ctx is cancelled.
+type PublishFunc func(ctx context.Context, source Source, src av.Demuxer) error
+
+// Puller keeps each configured source republished while its schedule is active.
+type Puller struct {
+	config  Config
+	sources []Source
+	open    Opener
+	publish PublishFunc
+	now     func() time.Time
+}
+
+// OpenURL opens a source with any format registered with avutil.
+func OpenURL(url string) (av.DemuxCloser, error) {
+	return avutil.Open(url)
+}
+
+// Run pulls every source until ctx is cancelled.
+func (p *Puller) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	for _, source := range p.sources {
+		wg.Add(1)
+		go func(source Source) {
+			defer wg.Done()
+			p.run(ctx, source)
+		}(source)
+	}
+
+	wg.Wait()
+}
+
+// sleep waits for d, returning false if ctx was cancelled first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// run pulls a single source, waiting for its schedule and reconnecting on failure.
+func (p *Puller) run(ctx context.Context, source Source) {
+	retry := backoff{initial: source.Retry.InitialBackoff, max: source.Retry.MaxBackoff}
+	attempts := 0
+
+	for ctx.Err() == nil {
+		active, end := source.Schedule.Active(p.now())
+		if !active {
+			attempts = 0
+			retry.Reset()
+
+			if !sleep(ctx, p.config.CheckInterval) {
+				return
+			}
+			continue
+		}
+
+		if source.Retry.MaxAttempts > 0 && attempts >= source.Retry.MaxAttempts {
+			log.Warnf("Giving up on pull source %s for %s until its next window", source.URL, source.Username)
+			p.waitForWindowEnd(ctx, source, end)
+			attempts = 0
+			retry.Reset()
+			continue
+		}
+
+		attempts++
+		received, err := p.pull(ctx, source, end)
+		if received {
+			attempts = 0
+			retry.Reset()
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err != nil && err != io.EOF {
+			log.Warnf("Pull source %s for %s failed: %v", source.URL, source.Username, err)
+		}
+
+		if !sleep(ctx, retry.Next()) {
+			return
+		}
+	}
+}
+
+// waitForWindowEnd blocks until the current schedule window ends. Sources
+// without a schedule are never active again, so this blocks until ctx is cancelled.
+func (p *Puller) waitForWindowEnd(ctx context.Context, source Source, end time.Time) {
+	if end.IsZero() {
+		<-ctx.Done()
+		return
+	}
+
+	sleep(ctx, end.Sub(p.now()))
+}
+
+// pull connects to the source and publishes it until it ends, ctx is
+// cancelled, or the schedule window closes. It reports whether the source
+// produced a stream header, which counts as a successful connection.
+func (p *Puller) pull(ctx context.Context, source Source, end time.Time) (bool, error) {
+	src, err := p.open(source.URL)
+	if err != nil {
+		return false, err
+	}
+	defer src.Close()
+
+	pullCtx, cancel := context.WithCancel(ctx)
+	if !end.IsZero() {
+		pullCtx, cancel = context.WithTimeout(ctx, end.Sub(p.now()))
+	}
+	defer cancel()
+
+	// Closing the source unblocks any pending read once the window closes.
+	go func() {
+		<-pullCtx.Done()
+		src.Close()
+	}()
+
+	if _, err := src.Streams(); err != nil {
+		return false, err
+	}
+
+	log.Infof("Pulling %s into channel %s", source.URL, source.Username)
+
+	return true, p.publish(pullCtx, source, src)
+}
+
+// NewPuller instantiates a new Puller.
+func NewPuller(config Config, sources []Source, open Opener, publish PublishFunc) *Puller {
+	return &Puller{
+		config:  config,
+		sources: sources,
+		open:    open,
+		publish: publish,
+		now:     time.Now,
+	}
+}
@@ -0,0 +1,157 @@
+package pull
+
+import (
+	"context"
+	"errors"
+	"github.com/M-Ro/go-vodstream/internal/avtest"
+	"github.com/google/go-cmp/cmp"
+	"github.com/nareix/joy4/av"
+	"github.com/nareix/joy4/av/avutil"
+	"github.com/nareix/joy4/format/rtmp"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errUnreachable = errors.New("source unreachable")
+
+func init() {
+	avutil.DefaultHandlers.Add(rtmp.Handler)
+}
+
+// blockingDemuxer returns a header immediately, then blocks reads until closed.
+type blockingDemuxer struct {
+	closed chan struct{}
+	once   sync.Once
+}
+
+func (d *blockingDemuxer) Streams() ([]av.CodecData, error) {
+	return avtest.Streams(), nil
+}
+
+func (d *blockingDemuxer) ReadPacket() (av.Packet, error) {
+	<-d.closed
+	return av.Packet{}, io.EOF
+}
+
+func (d *blockingDemuxer) Close() error {
+	d.once.Do(func() { close(d.closed) })
+	return nil
+}
+
+func testRetryPolicy() RetryPolicy {
+	return RetryPolicy{InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+}
+
+func TestPuller_Run_RepublishesRemoteRTMP(t *testing.T) {
+	packets := avtest.Packets(100, 25)
+
+	server := &rtmp.Server{
+		HandlePlay: func(conn *rtmp.Conn) {
+			defer conn.Close()
+			avutil.CopyFile(conn, avtest.NewDemuxer(avtest.Streams(), packets))
+		},
+	}
+	addr := avtest.StartRTMPServer(t, server)
+
+	received := make(chan int, 1)
+	publish := func(ctx context.Context, source Source, src av.Demuxer) error {
+		count := 0
+		for {
+			if _, err := src.ReadPacket(); err != nil {
+				received <- count
+				return err
+			}
+			count++
+		}
+	}
+
+	source := Source{Username: "someuser", URL: "rtmp://" + addr + "/live/remote", Retry: testRetryPolicy()}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go NewPuller(Config{CheckInterval: time.Millisecond}, []Source{source}, OpenURL, publish).Run(ctx)
+
+	select {
+	case count := <-received:
+		if count != len(packets) {
+			t.Fatal(cmp.Diff(count, len(packets)))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for pulled packets")
+	}
+}
+
+func TestPuller_Run_StopsAfterMaxAttempts(t *testing.T) {
+	var opens int32
+	open := func(url string) (av.DemuxCloser, error) {
+		atomic.AddInt32(&opens, 1)
+		return nil, errUnreachable
+	}
+	publish := func(ctx context.Context, source Source, src av.Demuxer) error {
+		t.Fatal("expected unreachable source not to be published")
+		return nil
+	}
+
+	retry := testRetryPolicy()
+	retry.MaxAttempts = 3
+	source := Source{Username: "someuser", URL: "rtmp://remote/live/stream", Retry: retry}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	NewPuller(Config{CheckInterval: time.Millisecond}, []Source{source}, open, publish).Run(ctx)
+
+	if got := atomic.LoadInt32(&opens); got != 3 {
+		t.Fatal(cmp.Diff(got, int32(3)))
+	}
+}
+
+func TestPuller_Run_StopsAtWindowEnd(t *testing.T) {
+	// Pretend it is shortly before the window closes at 22:00 UTC.
+	windowEnd := time.Date(2026, 10, 19, 22, 0, 0, 0, time.UTC)
+	offset := windowEnd.Add(-100 * time.Millisecond).Sub(time.Now())
+
+	var opens int32
+	open := func(url string) (av.DemuxCloser, error) {
+		atomic.AddInt32(&opens, 1)
+		return &blockingDemuxer{closed: make(chan struct{})}, nil
+	}
+
+	stopped := make(chan struct{}, 1)
+	publish := func(ctx context.Context, source Source, src av.Demuxer) error {
+		_, err := src.ReadPacket()
+		stopped <- struct{}{}
+		return err
+	}
+
+	source := Source{
+		Username: "someuser",
+		URL:      "rtmp://remote/live/stream",
+		Retry:    testRetryPolicy(),
+		Schedule: Schedule{Windows: []Window{{Start: 18 * time.Hour, End: 22 * time.Hour}}},
+	}
+
+	puller := NewPuller(Config{CheckInterval: 10 * time.Millisecond}, []Source{source}, open, publish)
+	puller.now = func() time.Time { return time.Now().Add(offset) }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go puller.Run(ctx)
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the window to close the source")
+	}
+
+	// The window is closed, so the source should not be reopened.
+	time.Sleep(100 * time.Millisecond)
+	if got := atomic.LoadInt32(&opens); got != 1 {
+		t.Fatal(cmp.Diff(got, int32(1)))
+	}
+}
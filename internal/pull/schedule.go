@@ -0,0 +1,132 @@
+package pull
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidDay  = errors.New("invalid day in schedule window")
+	ErrInvalidTime = errors.New("invalid time in schedule window, expected HH:MM")
+)
+
+const day = 24 * time.Hour
+
+// Window is a recurring period of the day during which a source is pulled.
+// Windows ending before they start run past midnight into the following day.
+type Window struct {
+	// Days the window starts on. Empty matches every day.
+	Days []time.Weekday
+
+	// Start and End are offsets from midnight.
+	Start time.Duration
+	End   time.Duration
+}
+
+// Schedule is a set of windows evaluated in Location. An empty schedule is always active.
+type Schedule struct {
+	Windows  []Window
+	Location *time.Location
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// ParseWeekday converts a three letter day name, e.g. "mon", to a time.Weekday.
+func ParseWeekday(value string) (time.Weekday, error) {
+	weekday, ok := weekdays[strings.ToLower(value)]
+	if !ok {
+		return time.Sunday, fmt.Errorf("%w: %s", ErrInvalidDay, value)
+	}
+
+	return weekday, nil
+}
+
+// ParseTimeOfDay converts a "HH:MM" string to an offset from midnight.
+func ParseTimeOfDay(value string) (time.Duration, error) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", ErrInvalidTime, value)
+	}
+
+	return time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute, nil
+}
+
+// length returns how long the window runs for.
+func (w Window) length() time.Duration {
+	if w.End > w.Start {
+		return w.End - w.Start
+	}
+
+	return w.End + day - w.Start
+}
+
+// startsOn returns true if the window starts on the given day.
+func (w Window) startsOn(weekday time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+
+	for _, d := range w.Days {
+		if d == weekday {
+			return true
+		}
+	}
+
+	return false
+}
+
+// activeAt returns true and the end of the window if t falls within an
+// occurrence of the window starting today or yesterday.
+func (w Window) activeAt(t time.Time) (bool, time.Time) {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+
+	for _, startDay := range []time.Time{midnight.AddDate(0, 0, -1), midnight} {
+		if !w.startsOn(startDay.Weekday()) {
+			continue
+		}
+
+		start := startDay.Add(w.Start)
+		end := start.Add(w.length())
+
+		if !t.Before(start) && t.Before(end) {
+			return true, end
+		}
+	}
+
+	return false, time.Time{}
+}
+
+// Active returns true if t falls within any window, along with the time the
+// latest overlapping window ends. The end time is zero for an empty schedule.
+func (s Schedule) Active(t time.Time) (bool, time.Time) {
+	if len(s.Windows) == 0 {
+		return true, time.Time{}
+	}
+
+	if s.Location != nil {
+		t = t.In(s.Location)
+	}
+
+	active, latestEnd := false, time.Time{}
+
+	for _, window := range s.Windows {
+		if ok, end := window.activeAt(t); ok {
+			active = true
+			if end.After(latestEnd) {
+				latestEnd = end
+			}
+		}
+	}
+
+	return active, latestEnd
+}
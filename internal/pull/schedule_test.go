@@ -0,0 +1,143 @@
+package pull
+
+import (
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"testing"
+	"time"
+)
+
+func TestSchedule_Active(t *testing.T) {
+	evening := Window{
+		Days:  []time.Weekday{time.Monday, time.Wednesday},
+		Start: 18 * time.Hour,
+		End:   22 * time.Hour,
+	}
+	overnight := Window{
+		Days:  []time.Weekday{time.Friday},
+		Start: 23 * time.Hour,
+		End:   2 * time.Hour,
+	}
+
+	// 2026-10-19 is a Monday.
+	at := func(day int, hour int, minute int) time.Time {
+		return time.Date(2026, 10, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		testName       string
+		schedule       Schedule
+		time           time.Time
+		expectedActive bool
+		expectedEnd    time.Time
+	}{
+		{
+			testName:       "expect empty schedule to always be active",
+			schedule:       Schedule{},
+			time:           at(19, 3, 0),
+			expectedActive: true,
+		},
+		{
+			testName:       "expect active inside window",
+			schedule:       Schedule{Windows: []Window{evening}},
+			time:           at(19, 18, 30),
+			expectedActive: true,
+			expectedEnd:    at(19, 22, 0),
+		},
+		{
+			testName:       "expect inactive at window end",
+			schedule:       Schedule{Windows: []Window{evening}},
+			time:           at(19, 22, 0),
+			expectedActive: false,
+		},
+		{
+			testName:       "expect inactive on a day without the window",
+			schedule:       Schedule{Windows: []Window{evening}},
+			time:           at(20, 18, 30),
+			expectedActive: false,
+		},
+		{
+			testName:       "expect active before midnight in overnight window",
+			schedule:       Schedule{Windows: []Window{overnight}},
+			time:           at(23, 23, 30),
+			expectedActive: true,
+			expectedEnd:    at(24, 2, 0),
+		},
+		{
+			testName:       "expect active after midnight in overnight window started the previous day",
+			schedule:       Schedule{Windows: []Window{overnight}},
+			time:           at(24, 1, 0),
+			expectedActive: true,
+			expectedEnd:    at(24, 2, 0),
+		},
+		{
+			testName:       "expect inactive after midnight when the window did not start the previous day",
+			schedule:       Schedule{Windows: []Window{overnight}},
+			time:           at(23, 1, 0),
+			expectedActive: false,
+		},
+		{
+			testName:       "expect latest end of overlapping windows",
+			schedule:       Schedule{Windows: []Window{evening, {Start: 20 * time.Hour, End: 23 * time.Hour}}},
+			time:           at(19, 21, 0),
+			expectedActive: true,
+			expectedEnd:    at(19, 23, 0),
+		},
+		{
+			testName: "expect window evaluated in schedule location",
+			schedule: Schedule{
+				Windows:  []Window{{Start: 18 * time.Hour, End: 22 * time.Hour}},
+				Location: time.FixedZone("UTC+2", 2*60*60),
+			},
+			time:           at(19, 17, 0),
+			expectedActive: true,
+			expectedEnd:    at(19, 20, 0),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			active, end := test.schedule.Active(test.time)
+
+			if active != test.expectedActive {
+				t.Fatal(cmp.Diff(active, test.expectedActive))
+			}
+
+			if !end.Equal(test.expectedEnd) {
+				t.Fatal(cmp.Diff(end, test.expectedEnd))
+			}
+		})
+	}
+}
+
+func TestParseTimeOfDay(t *testing.T) {
+	offset, err := ParseTimeOfDay("18:45")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if offset != 18*time.Hour+45*time.Minute {
+		t.Fatal(cmp.Diff(offset, 18*time.Hour+45*time.Minute))
+	}
+
+	_, err = ParseTimeOfDay("25:00")
+	if !cmp.Equal(err, ErrInvalidTime, cmpopts.EquateErrors()) {
+		t.Fatal(cmp.Diff(err, ErrInvalidTime, cmpopts.EquateErrors()))
+	}
+}
+
+func TestParseWeekday(t *testing.T) {
+	weekday, err := ParseWeekday("Wed")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if weekday != time.Wednesday {
+		t.Fatal(cmp.Diff(weekday, time.Wednesday))
+	}
+
+	_, err = ParseWeekday("someday")
+	if !cmp.Equal(err, ErrInvalidDay, cmpopts.EquateErrors()) {
+		t.Fatal(cmp.Diff(err, ErrInvalidDay, cmpopts.EquateErrors()))
+	}
+}
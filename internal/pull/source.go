@@ -0,0 +1,159 @@
+package pull
+
+import (
+	"errors"
+	"fmt"
+	"github.com/M-Ro/go-vodstream/internal/domain/broadcast"
+	"github.com/spf13/viper"
+	"time"
+)
+
+var (
+	ErrMissingUsername = errors.New("pull source is missing a username")
+	ErrMissingURL      = errors.New("pull source is missing a url")
+)
+
+// Source is a remote stream pulled into the channel of a local user.
+type Source struct {
+	// Username owns the channel the source is republished to.
+	Username   string
+	URL        string
+	Visibility broadcast.Visibility
+	LowLatency bool
+	Retry      RetryPolicy
+	Schedule   Schedule
+}
+
+// RetryPolicy controls reconnection to a source after it fails or ends.
+type RetryPolicy struct {
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	// MaxAttempts is the number of consecutive failed connections before the
+	// source is abandoned until its next schedule window. Zero retries forever.
+	MaxAttempts int
+}
+
+type Config struct {
+	// CheckInterval is how often idle sources check whether their schedule is active.
+	CheckInterval time.Duration
+}
+
+type windowConfig struct {
+	Days  []string `mapstructure:"days"`
+	Start string   `mapstructure:"start"`
+	End   string   `mapstructure:"end"`
+}
+
+type sourceConfig struct {
+	Username   string `mapstructure:"username"`
+	URL        string `mapstructure:"url"`
+	Visibility string `mapstructure:"visibility"`
+	LowLatency bool   `mapstructure:"low_latency"`
+	Retry      struct {
+		InitialBackoff string `mapstructure:"initial_backoff"`
+		MaxBackoff     string `mapstructure:"max_backoff"`
+		MaxAttempts    int    `mapstructure:"max_attempts"`
+	} `mapstructure:"retry"`
+	Timezone string         `mapstructure:"timezone"`
+	Schedule []windowConfig `mapstructure:"schedule"`
+}
+
+// parseDuration parses value, falling back to the default when it is empty.
+func parseDuration(value string, fallback time.Duration) (time.Duration, error) {
+	if value == "" {
+		return fallback, nil
+	}
+
+	return time.ParseDuration(value)
+}
+
+// toSource validates the configured source and converts it to a Source.
+func (c sourceConfig) toSource() (Source, error) {
+	if c.Username == "" {
+		return Source{}, ErrMissingUsername
+	}
+
+	if c.URL == "" {
+		return Source{}, ErrMissingURL
+	}
+
+	source := Source{
+		Username:   c.Username,
+		URL:        c.URL,
+		Visibility: broadcast.ParseVisibility(c.Visibility),
+		LowLatency: c.LowLatency,
+	}
+
+	var err error
+
+	if source.Retry.InitialBackoff, err = parseDuration(c.Retry.InitialBackoff, viper.GetDuration("pull.initial_backoff")); err != nil {
+		return Source{}, err
+	}
+
+	if source.Retry.MaxBackoff, err = parseDuration(c.Retry.MaxBackoff, viper.GetDuration("pull.max_backoff")); err != nil {
+		return Source{}, err
+	}
+
+	source.Retry.MaxAttempts = c.Retry.MaxAttempts
+
+	if c.Timezone != "" {
+		if source.Schedule.Location, err = time.LoadLocation(c.Timezone); err != nil {
+			return Source{}, err
+		}
+	}
+
+	for _, w := range c.Schedule {
+		window := Window{}
+
+		for _, d := range w.Days {
+			weekday, err := ParseWeekday(d)
+			if err != nil {
+				return Source{}, err
+			}
+			window.Days = append(window.Days, weekday)
+		}
+
+		if window.Start, err = ParseTimeOfDay(w.Start); err != nil {
+			return Source{}, err
+		}
+
+		if window.End, err = ParseTimeOfDay(w.End); err != nil {
+			return Source{}, err
+		}
+
+		source.Schedule.Windows = append(source.Schedule.Windows, window)
+	}
+
+	return source, nil
+}
+
+// GetSources reads the pull sources configured under pull.sources.
+func GetSources() ([]Source, error) {
+	viper.SetDefault("pull.initial_backoff", "1s")
+	viper.SetDefault("pull.max_backoff", "1m")
+
+	var configs []sourceConfig
+	if err := viper.UnmarshalKey("pull.sources", &configs); err != nil {
+		return nil, err
+	}
+
+	sources := make([]Source, 0, len(configs))
+	for idx, c := range configs {
+		source, err := c.toSource()
+		if err != nil {
+			return nil, fmt.Errorf("pull source %d: %w", idx, err)
+		}
+		sources = append(sources, source)
+	}
+
+	return sources, nil
+}
+
+func GetConfig() Config {
+	viper.SetDefault("pull.check_interval", "30s")
+
+	return Config{
+		CheckInterval: viper.GetDuration("pull.check_interval"),
+	}
+}
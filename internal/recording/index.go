@@ -0,0 +1,168 @@
+package recording
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/M-Ro/go-vodstream/internal/blob"
+	"github.com/nareix/joy4/av"
+	"github.com/nareix/joy4/codec/aacparser"
+	"github.com/nareix/joy4/codec/h264parser"
+	"io"
+	"sort"
+	"time"
+)
+
+var (
+	ErrEmptyIndex       = errors.New("recording has no keyframes")
+	ErrUnsupportedCodec = errors.New("codec parameters cannot be indexed")
+	ErrIndexVersion     = errors.New("index was written by an incompatible version")
+)
+
+// IndexVersion is bumped whenever the index format changes incompatibly.
+const IndexVersion = 1
+
+// KeyFrame is a position playback may start from.
+type KeyFrame struct {
+	Time time.Duration `json:"time"`
+
+	// Offset is the byte offset of the FLV tag carrying the keyframe.
+	Offset int64 `json:"offset"`
+}
+
+// Stream holds the codec parameters of a recorded stream, so decoders can be
+// set up without reading the recording header.
+type Stream struct {
+	Codec string `json:"codec"`
+
+	Width      int `json:"width,omitempty"`
+	Height     int `json:"height,omitempty"`
+	SampleRate int `json:"sample_rate,omitempty"`
+	Channels   int `json:"channels,omitempty"`
+
+	// Config is the AVC decoder configuration record or the MPEG-4 audio
+	// specific config of the stream.
+	Config []byte `json:"config"`
+}
+
+// Index is the sidecar written alongside a recording, mapping keyframe times
+// to byte offsets so seeks, thumbnails and clips read only what they need.
+type Index struct {
+	Version  int           `json:"version"`
+	Duration time.Duration `json:"duration"`
+	Streams  []Stream      `json:"streams"`
+
+	// HeaderSize is the length of the FLV header and codec configuration tags,
+	// which must precede media read from any keyframe.
+	HeaderSize int64 `json:"header_size"`
+
+	// Size is the length of the whole recording.
+	Size int64 `json:"size"`
+
+	KeyFrames []KeyFrame `json:"keyframes"`
+}
+
+// NewStream returns the indexed codec parameters of a stream.
+func NewStream(codec av.CodecData) (Stream, error) {
+	switch codec := codec.(type) {
+	case h264parser.CodecData:
+		return Stream{
+			Codec:  codec.Type().String(),
+			Width:  codec.Width(),
+			Height: codec.Height(),
+			Config: codec.AVCDecoderConfRecordBytes(),
+		}, nil
+	case aacparser.CodecData:
+		return Stream{
+			Codec:      codec.Type().String(),
+			SampleRate: codec.SampleRate(),
+			Channels:   codec.ChannelLayout().Count(),
+			Config:     codec.MPEG4AudioConfigBytes(),
+		}, nil
+	default:
+		return Stream{}, ErrUnsupportedCodec
+	}
+}
+
+// CodecData returns the codec of the stream, parsed from its config.
+func (s Stream) CodecData() (av.CodecData, error) {
+	switch s.Codec {
+	case av.H264.String():
+		return h264parser.NewCodecDataFromAVCDecoderConfRecord(s.Config)
+	case av.AAC.String():
+		return aacparser.NewCodecDataFromMPEG4AudioConfigBytes(s.Config)
+	default:
+		return nil, ErrUnsupportedCodec
+	}
+}
+
+// search returns the index of the last keyframe at or before t, or the first
+// keyframe if t precedes them all.
+func (i Index) search(t time.Duration) int {
+	n := sort.Search(len(i.KeyFrames), func(idx int) bool {
+		return i.KeyFrames[idx].Time > t
+	})
+
+	if n == 0 {
+		return 0
+	}
+
+	return n - 1
+}
+
+// Seek returns the keyframe playback of time t must start from.
+func (i Index) Seek(t time.Duration) (KeyFrame, error) {
+	if len(i.KeyFrames) == 0 {
+		return KeyFrame{}, ErrEmptyIndex
+	}
+
+	return i.KeyFrames[i.search(t)], nil
+}
+
+// Range returns the keyframe a clip from start to end begins at, and the byte
+// offset it ends at: the first keyframe at or after end, or the end of the
+// recording.
+func (i Index) Range(start time.Duration, end time.Duration) (KeyFrame, int64, error) {
+	from, err := i.Seek(start)
+	if err != nil {
+		return KeyFrame{}, 0, err
+	}
+
+	for _, keyFrame := range i.KeyFrames[i.search(start)+1:] {
+		if keyFrame.Time >= end {
+			return from, keyFrame.Offset, nil
+		}
+	}
+
+	return from, i.Size, nil
+}
+
+// WriteIndex encodes the index to w.
+func WriteIndex(w io.Writer, index Index) error {
+	return json.NewEncoder(w).Encode(index)
+}
+
+// PutIndex writes the index sidecar of the recording called name to media storage.
+func PutIndex(ctx context.Context, media blob.Store, name string, index Index) error {
+	var buf bytes.Buffer
+	if err := WriteIndex(&buf, index); err != nil {
+		return err
+	}
+
+	return media.Put(ctx, IndexFile(name), &buf)
+}
+
+// ReadIndex decodes an index written by WriteIndex.
+func ReadIndex(r io.Reader) (Index, error) {
+	var index Index
+	if err := json.NewDecoder(r).Decode(&index); err != nil {
+		return Index{}, err
+	}
+
+	if index.Version != IndexVersion {
+		return Index{}, ErrIndexVersion
+	}
+
+	return index, nil
+}
@@ -0,0 +1,145 @@
+package recording
+
+import (
+	"bytes"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"testing"
+	"time"
+)
+
+var testIndex = Index{
+	Version:    IndexVersion,
+	Duration:   6 * time.Second,
+	HeaderSize: 100,
+	Size:       4000,
+	KeyFrames: []KeyFrame{
+		{Time: 0, Offset: 100},
+		{Time: 2 * time.Second, Offset: 1000},
+		{Time: 4 * time.Second, Offset: 2500},
+	},
+}
+
+func TestIndex_Seek(t *testing.T) {
+	tests := []struct {
+		testName    string
+		index       Index
+		time        time.Duration
+		expected    KeyFrame
+		expectedErr error
+	}{
+		{
+			testName: "expect the keyframe at the time",
+			index:    testIndex,
+			time:     2 * time.Second,
+			expected: KeyFrame{Time: 2 * time.Second, Offset: 1000},
+		},
+		{
+			testName: "expect the keyframe before the time",
+			index:    testIndex,
+			time:     3999 * time.Millisecond,
+			expected: KeyFrame{Time: 2 * time.Second, Offset: 1000},
+		},
+		{
+			testName: "expect the last keyframe beyond the end",
+			index:    testIndex,
+			time:     time.Minute,
+			expected: KeyFrame{Time: 4 * time.Second, Offset: 2500},
+		},
+		{
+			testName:    "expect an error without keyframes",
+			index:       Index{},
+			expectedErr: ErrEmptyIndex,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			keyFrame, err := test.index.Seek(test.time)
+
+			if !cmp.Equal(err, test.expectedErr, cmpopts.EquateErrors()) {
+				t.Fatal(cmp.Diff(err, test.expectedErr, cmpopts.EquateErrors()))
+			}
+
+			if !cmp.Equal(keyFrame, test.expected) {
+				t.Fatal(cmp.Diff(keyFrame, test.expected))
+			}
+		})
+	}
+}
+
+func TestIndex_Range(t *testing.T) {
+	tests := []struct {
+		testName      string
+		start, end    time.Duration
+		expected      KeyFrame
+		expectedUntil int64
+	}{
+		{
+			testName:      "expect the range to end at the keyframe after the end",
+			start:         500 * time.Millisecond,
+			end:           1500 * time.Millisecond,
+			expected:      KeyFrame{Time: 0, Offset: 100},
+			expectedUntil: 1000,
+		},
+		{
+			testName:      "expect the range to end at the keyframe at the end",
+			start:         2 * time.Second,
+			end:           4 * time.Second,
+			expected:      KeyFrame{Time: 2 * time.Second, Offset: 1000},
+			expectedUntil: 2500,
+		},
+		{
+			testName:      "expect the range to run to the end of the recording",
+			start:         3 * time.Second,
+			end:           5 * time.Second,
+			expected:      KeyFrame{Time: 2 * time.Second, Offset: 1000},
+			expectedUntil: 4000,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			keyFrame, until, err := testIndex.Range(test.start, test.end)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(keyFrame, test.expected) {
+				t.Fatal(cmp.Diff(keyFrame, test.expected))
+			}
+
+			if until != test.expectedUntil {
+				t.Fatal(cmp.Diff(until, test.expectedUntil))
+			}
+		})
+	}
+}
+
+func TestReadIndex(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := WriteIndex(buf, testIndex); err != nil {
+		t.Fatal(err)
+	}
+
+	index, err := ReadIndex(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(index, testIndex); diff != "" {
+		t.Fatal(diff)
+	}
+
+	old := testIndex
+	old.Version = 0
+
+	buf.Reset()
+	if err := WriteIndex(buf, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ReadIndex(buf); err != ErrIndexVersion {
+		t.Fatal(cmp.Diff(err, ErrIndexVersion, cmpopts.EquateErrors()))
+	}
+}
@@ -0,0 +1,332 @@
+// Package recording records published channels as FLV, each with a keyframe
+// index sidecar so playback can seek without reading from the start. Channels
+// are recorded to a local spool while live, and moved to media storage once
+// they end.
+package recording
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/M-Ro/go-vodstream/internal/blob"
+	"github.com/M-Ro/go-vodstream/internal/domain/broadcast"
+	"github.com/M-Ro/go-vodstream/internal/live"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNotRecording = errors.New("broadcast is not being recorded")
+)
+
+// VideoStorage persists the videos recordings are published as.
+type VideoStorage interface {
+	Insert(ctx context.Context, video *storage.Video) error
+}
+
+// BroadcastVodStorage links broadcasts to the videos recorded from them.
+type BroadcastVodStorage interface {
+	Insert(ctx context.Context, broadcastVod *storage.BroadcastVod) error
+}
+
+// Transactor runs units of work spanning several storages atomically.
+type Transactor interface {
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type Config struct {
+	// SpoolDirectory is where channels are recorded while live, and recordings
+	// are written before being moved to media storage.
+	SpoolDirectory string
+}
+
+// Recorder records channels as they go live.
+type Recorder struct {
+	config       Config
+	media        blob.Store
+	videos       VideoStorage
+	vods         BroadcastVodStorage
+	transactions Transactor
+
+	lock     sync.Mutex
+	sessions map[uuid.UUID]*Session
+}
+
+// Session records a single channel until it ends.
+type Session struct {
+	Channel *live.Channel
+
+	// name is the file the channel is recorded to, within the spool directory,
+	// and the key it is stored under once it ends.
+	name string
+
+	lock   sync.Mutex
+	writer *Writer
+
+	done  chan struct{}
+	video storage.Video
+	err   error
+}
+
+// IndexFile returns the name of the index sidecar of a recording.
+func IndexFile(name string) string {
+	return strings.TrimSuffix(name, filepath.Ext(name)) + ".index.json"
+}
+
+// Start begins recording the channel from its oldest buffered packet. The
+// recording is published as a video once the channel queue is closed.
+func (r *Recorder) Start(channel *live.Channel) *Session {
+	startedAt := time.Now()
+
+	session := &Session{
+		Channel: channel,
+		name:    fmt.Sprintf("%s-%s.flv", strings.ToLower(channel.Name), startedAt.UTC().Format("20060102T150405Z")),
+		done:    make(chan struct{}),
+	}
+
+	r.lock.Lock()
+	r.sessions[channel.BroadcastId] = session
+	r.lock.Unlock()
+
+	go func() {
+		defer close(session.done)
+
+		session.video, session.err = r.record(session, startedAt)
+
+		r.lock.Lock()
+		delete(r.sessions, channel.BroadcastId)
+		r.lock.Unlock()
+
+		if session.err != nil {
+			log.Errorf("Recording of channel %s failed: %v", channel.Name, session.err)
+			return
+		}
+
+		log.Infof("Recorded channel %s as video %s", channel.Name, session.video.Id)
+	}()
+
+	return session
+}
+
+// Live returns the session recording the broadcast, or ErrNotRecording once it
+// has ended.
+func (r *Recorder) Live(broadcastId uuid.UUID) (*Session, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	session, ok := r.sessions[broadcastId]
+	if !ok {
+		return nil, ErrNotRecording
+	}
+
+	return session, nil
+}
+
+// Snapshot flushes the recording so far, returning its file, relative to the
+// spool directory, and its index.
+func (s *Session) Snapshot() (string, Index, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.writer == nil {
+		return "", Index{}, ErrNotRecording
+	}
+
+	if err := s.writer.Flush(); err != nil {
+		return "", Index{}, err
+	}
+
+	return s.name, s.writer.Index(), nil
+}
+
+// Wait blocks until the recording has been published, returning its video.
+func (s *Session) Wait() (storage.Video, error) {
+	<-s.done
+
+	return s.video, s.err
+}
+
+// write copies the channel to the session's recording file until the channel
+// ends, returning its index.
+func (r *Recorder) write(session *Session) (Index, error) {
+	file, err := os.Create(filepath.Join(r.config.SpoolDirectory, session.name))
+	if err != nil {
+		return Index{}, err
+	}
+	defer file.Close()
+
+	cursor := session.Channel.Queue.Oldest()
+
+	streams, err := cursor.Streams()
+	if err != nil {
+		return Index{}, err
+	}
+
+	writer := NewWriter(file)
+	if err := writer.WriteHeader(streams); err != nil {
+		return Index{}, err
+	}
+
+	session.lock.Lock()
+	session.writer = writer
+	session.lock.Unlock()
+
+	for {
+		packet, err := cursor.ReadPacket()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return Index{}, err
+		}
+
+		session.lock.Lock()
+		err = writer.WritePacket(packet)
+		session.lock.Unlock()
+
+		if err != nil {
+			return Index{}, err
+		}
+	}
+
+	session.lock.Lock()
+	defer session.lock.Unlock()
+
+	if err := writer.WriteTrailer(); err != nil {
+		return Index{}, err
+	}
+
+	return writer.Index(), file.Close()
+}
+
+// store moves a recording from the spool to media storage, along with its
+// index. Recordings that fail to store are left in the spool.
+func (r *Recorder) store(ctx context.Context, name string, index Index) error {
+	spooled := filepath.Join(r.config.SpoolDirectory, name)
+
+	file, err := os.Open(spooled)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	err = r.media.Put(ctx, name, file)
+	if err == nil {
+		err = PutIndex(ctx, r.media, name, index)
+	}
+
+	if err != nil {
+		Remove(ctx, r.media, name)
+		return fmt.Errorf("recording left at %s: %w", spooled, err)
+	}
+
+	file.Close()
+	return os.Remove(spooled)
+}
+
+// record writes the session's channel and its index, and inserts it as a
+// video of the channel's broadcast, published only if the broadcast was public.
+// Recordings without a single keyframe are discarded.
+func (r *Recorder) record(session *Session, startedAt time.Time) (storage.Video, error) {
+	if err := os.MkdirAll(r.config.SpoolDirectory, 0755); err != nil {
+		return storage.Video{}, err
+	}
+
+	index, err := r.write(session)
+	if err == nil && len(index.KeyFrames) == 0 {
+		err = ErrEmptyIndex
+	}
+
+	if err != nil {
+		os.Remove(filepath.Join(r.config.SpoolDirectory, session.name))
+		return storage.Video{}, err
+	}
+
+	if err := r.store(context.Background(), session.name, index); err != nil {
+		return storage.Video{}, err
+	}
+
+	channel := session.Channel
+	video := storage.Video{
+		Title:         fmt.Sprintf("%s %s", channel.Name, startedAt.UTC().Format("2006-01-02 15:04")),
+		BroadcasterId: channel.BroadcasterId,
+		Length:        uint64(index.Duration.Milliseconds()),
+		IsPublished:   channel.Visibility == broadcast.VisibilityPublic,
+		PublishedAt:   startedAt,
+		FilePath:      session.name,
+		IndexPath:     IndexFile(session.name),
+	}
+
+	if err := r.publish(context.Background(), channel.BroadcastId, &video); err != nil {
+		return storage.Video{}, err
+	}
+
+	return video, nil
+}
+
+// publish inserts the video of a recording, linked to the broadcast it was
+// recorded from.
+func (r *Recorder) publish(ctx context.Context, broadcastId uuid.UUID, video *storage.Video) error {
+	return r.transactions.WithTx(ctx, func(ctx context.Context) error {
+		if err := r.videos.Insert(ctx, video); err != nil {
+			return err
+		}
+
+		return r.vods.Insert(ctx, &storage.BroadcastVod{
+			StreamId:    broadcastId,
+			VideoId:     video.Id,
+			PublishedAt: video.PublishedAt,
+		})
+	})
+}
+
+// Open opens the recording of a video from media storage, and reads its index.
+func Open(ctx context.Context, media blob.Store, video storage.Video) (*blob.File, Index, error) {
+	indexFile, err := media.Get(ctx, video.IndexPath)
+	if err != nil {
+		return nil, Index{}, err
+	}
+	defer indexFile.Close()
+
+	index, err := ReadIndex(indexFile)
+	if err != nil {
+		return nil, Index{}, err
+	}
+
+	file, err := blob.Open(ctx, media, video.FilePath)
+	if err != nil {
+		return nil, Index{}, err
+	}
+
+	return file, index, nil
+}
+
+func GetConfig() Config {
+	viper.SetDefault("recording.spool_directory", "spool")
+
+	return Config{
+		SpoolDirectory: viper.GetString("recording.spool_directory"),
+	}
+}
+
+// NewRecorder instantiates a Recorder storing recordings in media, and
+// publishing them to videos linked to their broadcasts in vods.
+func NewRecorder(
+	config Config, media blob.Store, videos VideoStorage, vods BroadcastVodStorage, transactions Transactor,
+) *Recorder {
+	return &Recorder{
+		config:       config,
+		media:        media,
+		videos:       videos,
+		vods:         vods,
+		transactions: transactions,
+		sessions:     make(map[uuid.UUID]*Session),
+	}
+}
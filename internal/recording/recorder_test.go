@@ -0,0 +1,185 @@
+package recording
+
+import (
+	"context"
+	"github.com/M-Ro/go-vodstream/internal/avtest"
+	"github.com/M-Ro/go-vodstream/internal/blob"
+	"github.com/M-Ro/go-vodstream/internal/domain/broadcast"
+	"github.com/M-Ro/go-vodstream/internal/live"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/google/uuid"
+	"github.com/spf13/afero"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type fakeVideos struct {
+	inserted []storage.Video
+}
+
+func (f *fakeVideos) Insert(_ context.Context, video *storage.Video) error {
+	video.Id = uuid.New()
+	f.inserted = append(f.inserted, *video)
+	return nil
+}
+
+type fakeVods struct {
+	inserted []storage.BroadcastVod
+}
+
+func (f *fakeVods) Insert(_ context.Context, vod *storage.BroadcastVod) error {
+	vod.Id = uuid.New()
+	f.inserted = append(f.inserted, *vod)
+	return nil
+}
+
+// recordChannel records a channel of the given visibility fed with count frames,
+// returning once the recording is published.
+func recordChannel(
+	t *testing.T, config Config, media blob.Store, videos VideoStorage, vods BroadcastVodStorage,
+	visibility broadcast.Visibility, count int,
+) (*live.Channel, storage.Video, error) {
+	channels := live.NewRegistry()
+
+	channel, err := channels.Open("testUser1", 1, visibility)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := channel.Queue.WriteHeader(avtest.Streams()); err != nil {
+		t.Fatal(err)
+	}
+
+	session := NewRecorder(config, media, videos, vods, storage.Immediate{}).Start(channel)
+
+	for _, packet := range avtest.Packets(count, 25) {
+		channel.Queue.WritePacket(packet)
+		time.Sleep(100 * time.Microsecond)
+	}
+	channels.Close(channel)
+
+	video, err := session.Wait()
+	return channel, video, err
+}
+
+// expectEmptySpool fails the test if anything is left in the spool directory.
+func expectEmptySpool(t *testing.T, config Config) {
+	entries, err := os.ReadDir(config.SpoolDirectory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 0 {
+		t.Fatalf("expected an empty spool, found %s", filepath.Join(config.SpoolDirectory, entries[0].Name()))
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	config := Config{SpoolDirectory: t.TempDir()}
+	media := blob.NewLocalStore(afero.NewMemMapFs())
+	videos := &fakeVideos{}
+	vods := &fakeVods{}
+
+	channel, video, err := recordChannel(t, config, media, videos, vods, broadcast.VisibilityPublic, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(videos.inserted, []storage.Video{video}); diff != "" {
+		t.Fatal(diff)
+	}
+
+	if !video.IsPublished {
+		t.Fatal("expected the recording of a public broadcast to be published")
+	}
+
+	// The video is linked to the broadcast it was recorded from.
+	expectedVods := []storage.BroadcastVod{
+		{StreamId: channel.BroadcastId, VideoId: video.Id, PublishedAt: video.PublishedAt},
+	}
+	if diff := cmp.Diff(vods.inserted, expectedVods, cmpopts.IgnoreFields(storage.BroadcastVod{}, "Id")); diff != "" {
+		t.Fatal(diff)
+	}
+
+	if video.Length != uint64((99 * avtest.FrameDuration).Milliseconds()) {
+		t.Fatal(cmp.Diff(video.Length, uint64((99 * avtest.FrameDuration).Milliseconds())))
+	}
+
+	if video.IndexPath != IndexFile(video.FilePath) {
+		t.Fatal(cmp.Diff(video.IndexPath, IndexFile(video.FilePath)))
+	}
+
+	file, index, err := Open(context.Background(), media, video)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	if index.Size != file.Object().Size {
+		t.Fatal(cmp.Diff(index.Size, file.Object().Size))
+	}
+
+	if len(index.KeyFrames) != 4 {
+		t.Fatal(cmp.Diff(len(index.KeyFrames), 4))
+	}
+
+	// The recording is moved out of the spool once stored.
+	expectEmptySpool(t, config)
+}
+
+func TestRecorder_Visibility(t *testing.T) {
+	for _, visibility := range []broadcast.Visibility{broadcast.VisibilityUnlisted, broadcast.VisibilityPrivate} {
+		t.Run(string(visibility), func(t *testing.T) {
+			config := Config{SpoolDirectory: t.TempDir()}
+			videos := &fakeVideos{}
+			vods := &fakeVods{}
+
+			channel, video, err := recordChannel(
+				t, config, blob.NewLocalStore(afero.NewMemMapFs()), videos, vods, visibility, 100,
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if video.IsPublished || len(videos.inserted) != 1 || videos.inserted[0].IsPublished {
+				t.Fatalf("expected the recording of a %s broadcast to be kept unpublished", visibility)
+			}
+
+			// The video is still linked to its broadcast, for its broadcaster to publish.
+			if len(vods.inserted) != 1 || vods.inserted[0].StreamId != channel.BroadcastId {
+				t.Fatalf("expected the recording linked to broadcast %s", channel.BroadcastId)
+			}
+		})
+	}
+}
+
+func TestRecorder_DiscardsEmpty(t *testing.T) {
+	config := Config{SpoolDirectory: t.TempDir()}
+	media := blob.NewLocalStore(afero.NewMemMapFs())
+	videos := &fakeVideos{}
+	vods := &fakeVods{}
+
+	_, _, err := recordChannel(t, config, media, videos, vods, broadcast.VisibilityPublic, 0)
+	if !cmp.Equal(err, ErrEmptyIndex, cmpopts.EquateErrors()) {
+		t.Fatal(cmp.Diff(err, ErrEmptyIndex, cmpopts.EquateErrors()))
+	}
+
+	if len(videos.inserted) != 0 || len(vods.inserted) != 0 {
+		t.Fatal("expected no video to be published")
+	}
+
+	expectEmptySpool(t, config)
+
+	objects, err := media.List(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(objects) != 0 {
+		t.Fatalf("expected nothing to be stored, found %s", objects[0].Key)
+	}
+}
@@ -0,0 +1,162 @@
+package recording
+
+import (
+	"bytes"
+	"context"
+	"github.com/M-Ro/go-vodstream/internal/blob"
+	"github.com/nareix/joy4/av"
+	"github.com/nareix/joy4/format/flv"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Section returns a demuxer over the recording from the keyframe up to the
+// byte offset until, reading only the header and that range of the file.
+func Section(file io.ReaderAt, index Index, from KeyFrame, until int64) *flv.Demuxer {
+	header := io.NewSectionReader(file, 0, index.HeaderSize)
+	media := io.NewSectionReader(file, from.Offset, until-from.Offset)
+
+	return flv.NewDemuxer(io.MultiReader(header, media))
+}
+
+// Copy writes every packet from src to the writer, shifted by offset. Packets
+// that would land before zero, interleaved after a keyframe being cut from, are
+// dropped. It returns the time the copied media ends at, one packet interval
+// after the last packet of the longest stream.
+func Copy(writer *Writer, src av.PacketReader, offset time.Duration) (time.Duration, error) {
+	last := map[int8]time.Duration{}
+	interval := map[int8]time.Duration{}
+
+	for {
+		packet, err := src.ReadPacket()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return 0, err
+		}
+
+		packet.Time += offset
+		if packet.Time < 0 {
+			continue
+		}
+
+		if previous, ok := last[packet.Idx]; ok && packet.Time > previous {
+			interval[packet.Idx] = packet.Time - previous
+		}
+		last[packet.Idx] = packet.Time
+
+		if err := writer.WritePacket(packet); err != nil {
+			return 0, err
+		}
+	}
+
+	var end time.Duration
+	for idx, t := range last {
+		if t+interval[idx] > end {
+			end = t + interval[idx]
+		}
+	}
+
+	return end, nil
+}
+
+// Create writes a new recording called name to media storage, with its index
+// alongside. fill writes the recording's packets once the header is written,
+// to a file in the spool directory that is moved to storage once complete.
+// Nothing is left behind if writing fails.
+func Create(
+	ctx context.Context, config Config, media blob.Store, name string, streams []av.CodecData,
+	fill func(writer *Writer) error,
+) (Index, error) {
+	if err := os.MkdirAll(config.SpoolDirectory, 0755); err != nil {
+		return Index{}, err
+	}
+
+	out, err := os.CreateTemp(config.SpoolDirectory, "create-*.flv")
+	if err != nil {
+		return Index{}, err
+	}
+	defer os.Remove(out.Name())
+	defer out.Close()
+
+	writer := NewWriter(out)
+
+	err = writer.WriteHeader(streams)
+	if err == nil {
+		err = fill(writer)
+	}
+
+	if err == nil {
+		err = writer.WriteTrailer()
+	}
+
+	if err == nil {
+		_, err = out.Seek(0, io.SeekStart)
+	}
+
+	if err == nil {
+		err = media.Put(ctx, name, out)
+	}
+
+	if err == nil {
+		err = PutIndex(ctx, media, name, writer.Index())
+	}
+
+	if err != nil {
+		Remove(ctx, media, name)
+		return Index{}, err
+	}
+
+	return writer.Index(), nil
+}
+
+// Prefix returns the prefix a recording shares with its index and any other
+// sidecars named after it, such as its thumbnails.
+func Prefix(name string) string {
+	return strings.TrimSuffix(name, filepath.Ext(name)) + "."
+}
+
+// Remove deletes a recording from media storage, along with its index and any
+// other sidecars named after it, such as its thumbnails.
+func Remove(ctx context.Context, media blob.Store, name string) {
+	keys := []string{name}
+
+	sidecars, err := media.List(ctx, Prefix(name))
+	if err != nil {
+		log.Warnf("Couldn't list sidecars of recording %s: %v", name, err)
+	}
+
+	for _, sidecar := range sidecars {
+		if sidecar.Key != name {
+			keys = append(keys, sidecar.Key)
+		}
+	}
+
+	for _, key := range keys {
+		if err := media.Delete(ctx, key); err != nil {
+			log.Warnf("Couldn't remove recording file %s: %v", key, err)
+		}
+	}
+}
+
+// Compatible reports whether recordings with the given streams can be joined
+// into one without re-encoding, carrying the same codecs with the same parameters.
+func Compatible(a []Stream, b []Stream) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i].Codec != b[i].Codec || a[i].Width != b[i].Width || a[i].Height != b[i].Height ||
+			a[i].SampleRate != b[i].SampleRate || a[i].Channels != b[i].Channels ||
+			!bytes.Equal(a[i].Config, b[i].Config) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,123 @@
+package recording
+
+import (
+	"bufio"
+	"github.com/nareix/joy4/av"
+	"github.com/nareix/joy4/format/flv"
+	"io"
+	"time"
+)
+
+// countingWriter buffers writes, counting the bytes written through it so
+// offsets are known without flushing.
+type countingWriter struct {
+	w *bufio.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingWriter) Flush() error {
+	return c.w.Flush()
+}
+
+// Writer muxes a recording as FLV, indexing its keyframes as they are written.
+type Writer struct {
+	w     *countingWriter
+	muxer *flv.Muxer
+	index Index
+
+	// video is the index of the stream keyframes are indexed on, or -1 if the
+	// recording has no video and every packet is a keyframe.
+	video int
+
+	first, last time.Duration
+	started     bool
+}
+
+// WriteHeader writes the FLV header and codec configuration tags.
+func (w *Writer) WriteHeader(streams []av.CodecData) error {
+	w.video = -1
+	w.index.Streams = make([]Stream, 0, len(streams))
+
+	for idx, codec := range streams {
+		stream, err := NewStream(codec)
+		if err != nil {
+			return err
+		}
+
+		if w.video == -1 && codec.Type().IsVideo() {
+			w.video = idx
+		}
+
+		w.index.Streams = append(w.index.Streams, stream)
+	}
+
+	if err := w.muxer.WriteHeader(streams); err != nil {
+		return err
+	}
+
+	w.index.HeaderSize = w.w.n
+
+	return nil
+}
+
+// WritePacket writes the packet, indexing it if it is a keyframe.
+func (w *Writer) WritePacket(packet av.Packet) error {
+	keyFrame := packet.IsKeyFrame && int(packet.Idx) == w.video
+	if w.video == -1 {
+		// Audio only recordings may start from any packet, they are indexed once a second.
+		n := len(w.index.KeyFrames)
+		keyFrame = n == 0 || packet.Time-w.index.KeyFrames[n-1].Time >= time.Second
+	}
+
+	if keyFrame {
+		w.index.KeyFrames = append(w.index.KeyFrames, KeyFrame{Time: packet.Time, Offset: w.w.n})
+	}
+
+	if !w.started || packet.Time < w.first {
+		w.first = packet.Time
+	}
+	if packet.Time > w.last {
+		w.last = packet.Time
+	}
+	w.started = true
+
+	return w.muxer.WritePacket(packet)
+}
+
+// Flush writes out buffered data, so everything indexed can be read back.
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
+
+// WriteTrailer flushes the recording.
+func (w *Writer) WriteTrailer() error {
+	return w.muxer.WriteTrailer()
+}
+
+// Index returns the index of everything written so far.
+func (w *Writer) Index() Index {
+	index := w.index
+	index.Version = IndexVersion
+	index.Size = w.w.n
+	index.Duration = w.last - w.first
+	index.KeyFrames = append([]KeyFrame(nil), w.index.KeyFrames...)
+
+	return index
+}
+
+// NewWriter instantiates a Writer muxing to w.
+func NewWriter(w io.Writer) *Writer {
+	counter := &countingWriter{w: bufio.NewWriter(w)}
+
+	return &Writer{
+		w:     counter,
+		muxer: flv.NewMuxerWriteFlusher(counter),
+		video: -1,
+	}
+}
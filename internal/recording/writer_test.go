@@ -0,0 +1,88 @@
+package recording
+
+import (
+	"bytes"
+	"github.com/M-Ro/go-vodstream/internal/avtest"
+	"github.com/google/go-cmp/cmp"
+	"github.com/nareix/joy4/format/flv"
+	"io"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	writer := NewWriter(buf)
+
+	if err := writer.WriteHeader(avtest.Streams()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, packet := range avtest.Packets(100, 25) {
+		if err := writer.WritePacket(packet); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := writer.WriteTrailer(); err != nil {
+		t.Fatal(err)
+	}
+
+	index := writer.Index()
+	data := buf.Bytes()
+
+	if index.Size != int64(len(data)) {
+		t.Fatal(cmp.Diff(index.Size, int64(len(data))))
+	}
+
+	if index.Duration != 99*avtest.FrameDuration {
+		t.Fatal(cmp.Diff(index.Duration, 99*avtest.FrameDuration))
+	}
+
+	if len(index.KeyFrames) != 4 {
+		t.Fatal(cmp.Diff(len(index.KeyFrames), 4))
+	}
+
+	for idx, stream := range index.Streams {
+		codec, err := stream.CodecData()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if codec.Type() != avtest.Streams()[idx].Type() {
+			t.Fatal(cmp.Diff(codec.Type(), avtest.Streams()[idx].Type()))
+		}
+	}
+
+	// The header followed by the media from any keyframe is a playable stream
+	// starting at that keyframe.
+	for _, keyFrame := range index.KeyFrames {
+		seek := append(append([]byte(nil), data[:index.HeaderSize]...), data[keyFrame.Offset:]...)
+		demuxer := flv.NewDemuxer(bytes.NewReader(seek))
+
+		streams, err := demuxer.Streams()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(streams) != len(avtest.Streams()) {
+			t.Fatal(cmp.Diff(len(streams), len(avtest.Streams())))
+		}
+
+		first, err := demuxer.ReadPacket()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if first.Idx != avtest.VideoIdx || !first.IsKeyFrame || first.Time != keyFrame.Time {
+			t.Fatalf("expected stream to start at the keyframe at %s, got %s", keyFrame.Time, first.Time)
+		}
+
+		for {
+			if _, err := demuxer.ReadPacket(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package relay
+
+import "time"
+
+// backoff produces exponentially increasing delays between reconnection attempts.
+type backoff struct {
+	initial time.Duration
+	max     time.Duration
+	current time.Duration
+}
+
+// Next returns the delay before the next attempt.
+func (b *backoff) Next() time.Duration {
+	if b.current == 0 {
+		b.current = b.initial
+	} else {
+		b.current *= 2
+	}
+
+	if b.current > b.max {
+		b.current = b.max
+	}
+
+	return b.current
+}
+
+// Reset restarts the delays from the initial value, after a successful connection.
+func (b *backoff) Reset() {
+	b.current = 0
+}
@@ -0,0 +1,265 @@
+package relay
+
+import (
+	"context"
+	"github.com/M-Ro/go-vodstream/internal/domain/relay"
+	"github.com/M-Ro/go-vodstream/internal/live"
+	"github.com/google/uuid"
+	"github.com/nareix/joy4/av"
+	"github.com/nareix/joy4/av/pubsub"
+	"github.com/nareix/joy4/format/rtmp"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dialTimeout bounds how long connecting to a single target may take.
+const dialTimeout = 10 * time.Second
+
+// Dialer opens an outbound connection to publish to the given URL.
+type Dialer func(url string) (av.MuxCloser, error)
+
+// StatusReporter persists the connection status of relay targets.
+type StatusReporter interface {
+	UpdateStatus(ctx context.Context, id uuid.UUID, status relay.Status, lastError string) error
+}
+
+type Config struct {
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// TargetStatus describes the state of forwarding to a single target.
+type TargetStatus struct {
+	TargetId  uuid.UUID
+	Name      string
+	Status    relay.Status
+	LastError string
+	Attempts  int
+}
+
+// Manager starts relay sessions for channels as they go live.
+type Manager struct {
+	config   Config
+	dial     Dialer
+	reporter StatusReporter
+}
+
+// Session forwards a single channel to each of its enabled targets.
+type Session struct {
+	manager *Manager
+	channel *live.Channel
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	lock     sync.RWMutex
+	statuses []*TargetStatus
+}
+
+// DialRTMP connects to an RTMP server for publishing.
+func DialRTMP(url string) (av.MuxCloser, error) {
+	return rtmp.DialTimeout(url, dialTimeout)
+}
+
+// PublishURL returns the URL packets are published to for the given target.
+func PublishURL(target relay.Target) string {
+	return strings.TrimSuffix(target.URL, "/") + "/" + target.StreamKey
+}
+
+// Start begins forwarding the channel to each enabled target. Forwarding ends
+// when the channel queue is closed or the session is stopped.
+func (m *Manager) Start(channel *live.Channel, targets []relay.Target) *Session {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	session := &Session{
+		manager:  m,
+		channel:  channel,
+		ctx:      ctx,
+		cancel:   cancel,
+		statuses: make([]*TargetStatus, 0, len(targets)),
+	}
+
+	for _, target := range targets {
+		if !target.Enabled {
+			continue
+		}
+
+		status := &TargetStatus{
+			TargetId: target.Id,
+			Name:     target.Name,
+			Status:   relay.StatusIdle,
+		}
+		session.statuses = append(session.statuses, status)
+
+		session.wg.Add(1)
+		go session.run(target, status)
+	}
+
+	return session
+}
+
+// Stop signals all forwarders to end. Forwarders waiting on the channel queue
+// exit once the queue is closed.
+func (s *Session) Stop() {
+	s.cancel()
+}
+
+// Wait blocks until all forwarders have ended.
+func (s *Session) Wait() {
+	s.wg.Wait()
+}
+
+// Statuses returns the current state of each target in the session.
+func (s *Session) Statuses() []TargetStatus {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	statuses := make([]TargetStatus, len(s.statuses))
+	for i, status := range s.statuses {
+		statuses[i] = *status
+	}
+
+	return statuses
+}
+
+// setStatus records a status change for a target, persisting it through the
+// manager's reporter.
+func (s *Session) setStatus(status *TargetStatus, newStatus relay.Status, err error) {
+	lastError := ""
+	if err != nil {
+		lastError = err.Error()
+	}
+
+	s.lock.Lock()
+	status.Status = newStatus
+	status.LastError = lastError
+	if newStatus == relay.StatusConnecting {
+		status.Attempts++
+	}
+	s.lock.Unlock()
+
+	log.Infof("Relay of channel %s to %s: %s %s", s.channel.Name, status.Name, newStatus, lastError)
+
+	if s.manager.reporter == nil {
+		return
+	}
+
+	// The session context may already be cancelled when reporting the final status.
+	if err := s.manager.reporter.UpdateStatus(context.Background(), status.TargetId, newStatus, lastError); err != nil {
+		log.Errorf("Couldn't report relay status for target %s: %v", status.TargetId, err)
+	}
+}
+
+// run forwards the channel to target, reconnecting with backoff on failure.
+func (s *Session) run(target relay.Target, status *TargetStatus) {
+	defer s.wg.Done()
+
+	delay := backoff{
+		initial: s.manager.config.InitialBackoff,
+		max:     s.manager.config.MaxBackoff,
+	}
+
+	for {
+		s.setStatus(status, relay.StatusConnecting, nil)
+
+		err := s.forward(target, func() {
+			delay.Reset()
+			s.setStatus(status, relay.StatusLive, nil)
+		})
+
+		// The broadcast has ended or the session was stopped.
+		if err == io.EOF || s.ctx.Err() != nil {
+			s.setStatus(status, relay.StatusIdle, nil)
+			return
+		}
+
+		s.setStatus(status, relay.StatusRetrying, err)
+
+		select {
+		case <-s.ctx.Done():
+			s.setStatus(status, relay.StatusIdle, nil)
+			return
+		case <-time.After(delay.Next()):
+		}
+	}
+}
+
+// forward publishes packets from the channel queue to target until either side
+// fails. onLive is called once the target has accepted the stream header.
+func (s *Session) forward(target relay.Target, onLive func()) error {
+	conn, err := s.manager.dial(PublishURL(target))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Unblock writes to an unresponsive target when the session is stopped.
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-s.ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	return copyStream(conn, s.channel.Queue, onLive)
+}
+
+// copyStream writes the stream from the latest keyframe of queue to conn.
+func copyStream(conn av.Muxer, queue *pubsub.Queue, onLive func()) error {
+	cursor := queue.Latest()
+
+	streams, err := cursor.Streams()
+	if err != nil {
+		return err
+	}
+
+	if err = conn.WriteHeader(streams); err != nil {
+		return err
+	}
+
+	onLive()
+
+	for {
+		packet, err := cursor.ReadPacket()
+		if err == io.EOF {
+			conn.WriteTrailer()
+			return err
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if err = conn.WritePacket(packet); err != nil {
+			return err
+		}
+	}
+}
+
+func GetConfig() Config {
+	viper.SetDefault("relay.initial_backoff", "1s")
+	viper.SetDefault("relay.max_backoff", "1m")
+
+	return Config{
+		InitialBackoff: viper.GetDuration("relay.initial_backoff"),
+		MaxBackoff:     viper.GetDuration("relay.max_backoff"),
+	}
+}
+
+// NewManager instantiates a Manager. The reporter may be nil if statuses should not be persisted.
+func NewManager(config Config, dial Dialer, reporter StatusReporter) *Manager {
+	return &Manager{
+		config:   config,
+		dial:     dial,
+		reporter: reporter,
+	}
+}
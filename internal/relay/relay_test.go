@@ -0,0 +1,274 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"github.com/M-Ro/go-vodstream/internal/avtest"
+	"github.com/M-Ro/go-vodstream/internal/domain/broadcast"
+	"github.com/M-Ro/go-vodstream/internal/domain/relay"
+	"github.com/M-Ro/go-vodstream/internal/live"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/nareix/joy4/av"
+	"github.com/nareix/joy4/format/rtmp"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testConfig = Config{
+	InitialBackoff: 10 * time.Millisecond,
+	MaxBackoff:     50 * time.Millisecond,
+}
+
+// mockStatusReporter records every status reported for each target.
+type mockStatusReporter struct {
+	lock     sync.Mutex
+	statuses map[uuid.UUID][]relay.Status
+}
+
+func (m *mockStatusReporter) UpdateStatus(_ context.Context, id uuid.UUID, status relay.Status, _ string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.statuses[id] = append(m.statuses[id], status)
+
+	return nil
+}
+
+func (m *mockStatusReporter) reported(id uuid.UUID) []relay.Status {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return append([]relay.Status{}, m.statuses[id]...)
+}
+
+// publishSession records a single publish received by the destination server.
+type publishSession struct {
+	path    string
+	packets chan av.Packet
+}
+
+// startDestination starts a local RTMP server recording each publish. Each
+// publisher is disconnected after maxPackets packets when maxPackets > 0.
+func startDestination(t *testing.T, maxPackets int) (string, chan publishSession) {
+	sessions := make(chan publishSession, 10)
+
+	server := &rtmp.Server{
+		HandlePublish: func(conn *rtmp.Conn) {
+			defer conn.Close()
+
+			session := publishSession{
+				path:    conn.URL.Path,
+				packets: make(chan av.Packet, 1000),
+			}
+			sessions <- session
+
+			if _, err := conn.Streams(); err != nil {
+				return
+			}
+
+			for i := 0; maxPackets <= 0 || i < maxPackets; i++ {
+				packet, err := conn.ReadPacket()
+				if err != nil {
+					return
+				}
+				session.packets <- packet
+			}
+		},
+	}
+
+	return avtest.StartRTMPServer(t, server), sessions
+}
+
+// feed writes packets to the channel until stop is closed.
+func feed(channel *live.Channel, stop chan struct{}) {
+	for _, packet := range avtest.Packets(100000, 25) {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		channel.Queue.WritePacket(packet)
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func waitForSession(t *testing.T, sessions chan publishSession) publishSession {
+	select {
+	case session := <-sessions:
+		return session
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for relay to publish")
+	}
+
+	return publishSession{}
+}
+
+func waitForPackets(t *testing.T, session publishSession, count int) {
+	for i := 0; i < count; i++ {
+		select {
+		case <-session.packets:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for packet %d", i)
+		}
+	}
+}
+
+func openChannel(t *testing.T) (*live.Registry, *live.Channel) {
+	registry := live.NewRegistry()
+
+	channel, err := registry.Open("broadcaster", 1, broadcast.VisibilityPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = channel.Queue.WriteHeader(avtest.Streams()); err != nil {
+		t.Fatal(err)
+	}
+
+	return registry, channel
+}
+
+func TestManager_Start_ForwardsToTarget(t *testing.T) {
+	addr, sessions := startDestination(t, 0)
+	registry, channel := openChannel(t)
+
+	reporter := &mockStatusReporter{statuses: make(map[uuid.UUID][]relay.Status)}
+	manager := NewManager(testConfig, DialRTMP, reporter)
+
+	target := relay.Target{
+		Id:        uuid.New(),
+		Name:      "destination",
+		URL:       "rtmp://" + addr + "/live/",
+		StreamKey: "testKey",
+		Enabled:   true,
+	}
+	disabled := relay.Target{
+		Id:        uuid.New(),
+		Name:      "disabled",
+		URL:       "rtmp://" + addr + "/live",
+		StreamKey: "disabledKey",
+		Enabled:   false,
+	}
+
+	session := manager.Start(channel, []relay.Target{target, disabled})
+
+	stop := make(chan struct{})
+	go feed(channel, stop)
+
+	published := waitForSession(t, sessions)
+	if !cmp.Equal(published.path, "/live/testKey") {
+		t.Fatal(cmp.Diff(published.path, "/live/testKey"))
+	}
+
+	waitForPackets(t, published, 50)
+
+	statuses := session.Statuses()
+	if len(statuses) != 1 || statuses[0].Status != relay.StatusLive {
+		t.Fatalf("expected single live target, got %+v", statuses)
+	}
+
+	// Ending the broadcast ends the relay.
+	close(stop)
+	registry.Close(channel)
+	session.Stop()
+	session.Wait()
+
+	expected := []relay.Status{relay.StatusConnecting, relay.StatusLive, relay.StatusIdle}
+	if !cmp.Equal(reporter.reported(target.Id), expected) {
+		t.Fatal(cmp.Diff(reporter.reported(target.Id), expected))
+	}
+
+	if len(reporter.reported(disabled.Id)) != 0 {
+		t.Fatal("expected disabled target to be skipped")
+	}
+}
+
+func TestManager_Start_Reconnects(t *testing.T) {
+	addr, sessions := startDestination(t, 20)
+	registry, channel := openChannel(t)
+
+	manager := NewManager(testConfig, DialRTMP, nil)
+
+	target := relay.Target{
+		Id:        uuid.New(),
+		Name:      "flaky",
+		URL:       "rtmp://" + addr + "/live",
+		StreamKey: "testKey",
+		Enabled:   true,
+	}
+
+	session := manager.Start(channel, []relay.Target{target})
+
+	stop := make(chan struct{})
+	go feed(channel, stop)
+
+	first := waitForSession(t, sessions)
+	waitForPackets(t, first, 20)
+
+	second := waitForSession(t, sessions)
+	waitForPackets(t, second, 10)
+
+	close(stop)
+	registry.Close(channel)
+	session.Stop()
+	session.Wait()
+
+	statuses := session.Statuses()
+	if statuses[0].Attempts < 2 {
+		t.Fatalf("expected at least 2 connection attempts, got %d", statuses[0].Attempts)
+	}
+}
+
+func TestManager_Start_RetriesFailedDial(t *testing.T) {
+	registry, channel := openChannel(t)
+
+	dialErr := errors.New("connection refused")
+	attempts := make(chan struct{}, 100)
+
+	manager := NewManager(testConfig, func(url string) (av.MuxCloser, error) {
+		attempts <- struct{}{}
+		return nil, dialErr
+	}, nil)
+
+	session := manager.Start(channel, []relay.Target{{Id: uuid.New(), URL: "rtmp://invalid", Enabled: true}})
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-attempts:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for retry")
+		}
+	}
+
+	statuses := session.Statuses()
+	if statuses[0].LastError != dialErr.Error() {
+		t.Fatal(cmp.Diff(statuses[0].LastError, dialErr.Error()))
+	}
+
+	session.Stop()
+	session.Wait()
+	registry.Close(channel)
+
+	if session.Statuses()[0].Status != relay.StatusIdle {
+		t.Fatal("expected target to be idle after stop")
+	}
+}
+
+func TestBackoff_Next(t *testing.T) {
+	b := backoff{initial: time.Second, max: 5 * time.Second}
+
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for _, delay := range expected {
+		if next := b.Next(); next != delay {
+			t.Fatal(cmp.Diff(next, delay))
+		}
+	}
+
+	b.Reset()
+	if next := b.Next(); next != time.Second {
+		t.Fatal(cmp.Diff(next, time.Second))
+	}
+}
@@ -0,0 +1,142 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"github.com/M-Ro/go-vodstream/internal/domain/relay"
+	"github.com/M-Ro/go-vodstream/internal/encryption"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrTargetNotFound = errors.New("no relay target found")
+)
+
+type StorageProvider interface {
+	GetByID(ctx context.Context, id uuid.UUID) *storage.RelayTarget
+	GetByUserID(ctx context.Context, userId uint64) ([]storage.RelayTarget, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+	Insert(ctx context.Context, relayTarget *storage.RelayTarget) error
+	Update(ctx context.Context, id uuid.UUID, relayTarget *storage.RelayTarget) error
+	UpdateStatus(ctx context.Context, id uuid.UUID, status string, lastError string) error
+}
+
+// Repository stores relay targets, encrypting stream keys at rest.
+type Repository struct {
+	StorageProvider StorageProvider
+	Cipher          encryption.Cipher
+}
+
+// toDomain converts a storage model to a domain model with a decrypted stream key.
+func (r Repository) toDomain(relayTarget storage.RelayTarget) (relay.Target, error) {
+	streamKey, err := r.Cipher.Decrypt(relayTarget.StreamKey)
+	if err != nil {
+		return relay.Target{}, err
+	}
+
+	target := storage.RelayTargetToDomain(relayTarget)
+	target.StreamKey = streamKey
+
+	return target, nil
+}
+
+// toStorage converts a domain model to a storage model with an encrypted stream key.
+func (r Repository) toStorage(target relay.Target) (storage.RelayTarget, error) {
+	streamKey, err := r.Cipher.Encrypt(target.StreamKey)
+	if err != nil {
+		return storage.RelayTarget{}, err
+	}
+
+	relayTarget := storage.RelayTargetToStorage(target)
+	relayTarget.StreamKey = streamKey
+
+	return relayTarget, nil
+}
+
+// GetByID returns the relay target with the given ID, or returns an error.
+func (r Repository) GetByID(ctx context.Context, id uuid.UUID) (relay.Target, error) {
+	getTarget := r.StorageProvider.GetByID(ctx, id)
+	if getTarget == nil {
+		return relay.Target{}, ErrTargetNotFound
+	}
+
+	return r.toDomain(*getTarget)
+}
+
+// GetByUserID returns all relay targets configured by the given user.
+func (r Repository) GetByUserID(ctx context.Context, userId uint64) ([]relay.Target, error) {
+	relayTargets, err := r.StorageProvider.GetByUserID(ctx, userId)
+	if err != nil {
+		return []relay.Target{}, err
+	}
+
+	targets := make([]relay.Target, len(relayTargets))
+	for i, relayTarget := range relayTargets {
+		targets[i], err = r.toDomain(relayTarget)
+		if err != nil {
+			return []relay.Target{}, err
+		}
+	}
+
+	return targets, nil
+}
+
+// Delete removes a relay target with the given ID. Returns an error on failure.
+func (r Repository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.StorageProvider.Delete(ctx, id)
+}
+
+// Insert takes a domain model and inserts it to the storage provider.
+// After successful insertion the target ID field will be filled, alternatively an error is returned.
+func (r Repository) Insert(ctx context.Context, target *relay.Target) error {
+	if target.Status == "" {
+		target.Status = relay.StatusIdle
+	}
+
+	relayTarget, err := r.toStorage(*target)
+	if err != nil {
+		return err
+	}
+
+	err = r.StorageProvider.Insert(ctx, &relayTarget)
+	if err != nil {
+		return err
+	}
+
+	target.Id = relayTarget.Id
+	target.CreatedAt = relayTarget.CreatedAt
+	target.UpdatedAt = relayTarget.UpdatedAt
+	target.StatusUpdatedAt = relayTarget.StatusUpdatedAt
+
+	return nil
+}
+
+// Update takes a relay target and updates the record within the StorageProvider.
+func (r Repository) Update(ctx context.Context, id uuid.UUID, target relay.Target) (relay.Target, error) {
+	relayTarget, err := r.toStorage(target)
+	if err != nil {
+		return relay.Target{}, err
+	}
+
+	err = r.StorageProvider.Update(ctx, id, &relayTarget)
+	if err != nil {
+		return relay.Target{}, err
+	}
+
+	target.UpdatedAt = relayTarget.UpdatedAt
+
+	return target, nil
+}
+
+// UpdateStatus records the connection status of a relay target.
+func (r Repository) UpdateStatus(ctx context.Context, id uuid.UUID, status relay.Status, lastError string) error {
+	return r.StorageProvider.UpdateStatus(ctx, id, string(status), lastError)
+}
+
+func NewRepository(s StorageProvider, cipher encryption.Cipher) Repository {
+	return Repository{
+		StorageProvider: s,
+		Cipher:          cipher,
+	}
+}
@@ -0,0 +1,139 @@
+package relay
+
+import (
+	"context"
+	"github.com/M-Ro/go-vodstream/internal/domain/relay"
+	"github.com/M-Ro/go-vodstream/internal/encryption"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/google/uuid"
+	"testing"
+)
+
+// mockRelayTargetStorage stores relay targets in memory, as the SQL storage would.
+type mockRelayTargetStorage map[uuid.UUID]storage.RelayTarget
+
+func (m mockRelayTargetStorage) GetByID(_ context.Context, id uuid.UUID) *storage.RelayTarget {
+	relayTarget, ok := m[id]
+	if !ok {
+		return nil
+	}
+
+	return &relayTarget
+}
+
+func (m mockRelayTargetStorage) GetByUserID(_ context.Context, userId uint64) ([]storage.RelayTarget, error) {
+	relayTargets := make([]storage.RelayTarget, 0)
+	for _, relayTarget := range m {
+		if relayTarget.UserId == userId {
+			relayTargets = append(relayTargets, relayTarget)
+		}
+	}
+
+	return relayTargets, nil
+}
+
+func (m mockRelayTargetStorage) Delete(_ context.Context, id uuid.UUID) error {
+	delete(m, id)
+	return nil
+}
+
+func (m mockRelayTargetStorage) Insert(_ context.Context, relayTarget *storage.RelayTarget) error {
+	relayTarget.Id = uuid.New()
+	m[relayTarget.Id] = *relayTarget
+
+	return nil
+}
+
+func (m mockRelayTargetStorage) Update(_ context.Context, id uuid.UUID, relayTarget *storage.RelayTarget) error {
+	m[id] = *relayTarget
+	return nil
+}
+
+func (m mockRelayTargetStorage) UpdateStatus(_ context.Context, id uuid.UUID, status string, lastError string) error {
+	relayTarget := m[id]
+	relayTarget.Status = status
+	relayTarget.LastError = lastError
+	m[id] = relayTarget
+
+	return nil
+}
+
+func TestRepository_EncryptsStreamKey(t *testing.T) {
+	ctx := context.Background()
+
+	cipher, err := encryption.NewCipher("testKey")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := mockRelayTargetStorage{}
+	r := NewRepository(s, cipher)
+
+	target := relay.Target{
+		UserId:    1,
+		Name:      "other platform",
+		URL:       "rtmp://live.example.com/app",
+		StreamKey: "secretKey",
+		Enabled:   true,
+	}
+
+	if err = r.Insert(ctx, &target); err != nil {
+		t.Fatal(err)
+	}
+
+	if target.Id == uuid.Nil {
+		t.Fatal("target ID should be set after insert")
+	}
+
+	if stored := s[target.Id].StreamKey; stored == "secretKey" || stored == "" {
+		t.Fatalf("expected stream key to be encrypted at rest, got %q", stored)
+	}
+
+	if s[target.Id].Status != string(relay.StatusIdle) {
+		t.Fatal(cmp.Diff(s[target.Id].Status, string(relay.StatusIdle)))
+	}
+
+	fetched, err := r.GetByID(ctx, target.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !cmp.Equal(fetched, target) {
+		t.Fatal(cmp.Diff(fetched, target))
+	}
+
+	targets, err := r.GetByUserID(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(targets) != 1 || targets[0].StreamKey != "secretKey" {
+		t.Fatalf("expected decrypted target for user, got %+v", targets)
+	}
+
+	// A repository configured with another key cannot read the stream key.
+	otherCipher, err := encryption.NewCipher("otherKey")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = NewRepository(s, otherCipher).GetByID(ctx, target.Id); err == nil {
+		t.Fatal("expected decryption with another key to fail")
+	}
+}
+
+func TestRepository_GetByID_NotFound(t *testing.T) {
+	cipher, err := encryption.NewCipher("testKey")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRepository(mockRelayTargetStorage{}, cipher)
+
+	_, err = r.GetByID(context.Background(), uuid.New())
+	if !cmp.Equal(err, ErrTargetNotFound, cmpopts.EquateErrors()) {
+		t.Fatal(cmp.Diff(err, ErrTargetNotFound, cmpopts.EquateErrors()))
+	}
+}
@@ -0,0 +1,356 @@
+// Package retention deletes videos beyond the configured retention rules,
+// along with the media they were recorded to, so recording every broadcast
+// does not fill storage. Pinned videos are never deleted.
+package retention
+
+import (
+	"context"
+	"errors"
+	"github.com/M-Ro/go-vodstream/internal/blob"
+	"github.com/M-Ro/go-vodstream/internal/domain/user"
+	"github.com/M-Ro/go-vodstream/internal/recording"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	ErrVideoNotFound = errors.New("video does not exist")
+)
+
+// Reason is the rule a video is deleted under.
+type Reason string
+
+const (
+	ReasonAge   Reason = "age"
+	ReasonCount Reason = "count"
+	ReasonQuota Reason = "quota"
+)
+
+type Config struct {
+	// Interval is how often the janitor sweeps.
+	Interval time.Duration
+
+	// MaxAge is how long videos are kept for. Zero keeps videos regardless of age.
+	MaxAge time.Duration
+
+	// KeepLast is how many of their most recent videos each broadcaster keeps,
+	// not counting pinned videos. Zero keeps every video.
+	KeepLast int
+
+	// Quota caps the bytes of video kept for broadcasters without a quota of
+	// their own. Pinned videos count towards quotas, but are never deleted to
+	// meet them. Zero is unlimited.
+	Quota int64
+
+	// DryRun reports what sweeps would delete without deleting anything.
+	DryRun bool
+}
+
+// VideoStorage lists the videos retention rules apply to, and deletes them.
+type VideoStorage interface {
+	All(ctx context.Context) ([]storage.Video, error)
+	GetByID(ctx context.Context, id uuid.UUID) *storage.Video
+	Update(ctx context.Context, id uuid.UUID, video *storage.Video) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+// BroadcastVodStorage deletes the links between broadcasts and deleted videos.
+type BroadcastVodStorage interface {
+	DeleteByVideoID(ctx context.Context, videoId uuid.UUID) error
+}
+
+// Transactor runs units of work spanning several storages atomically.
+type Transactor interface {
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// UserProvider looks up the storage quotas of broadcasters.
+type UserProvider interface {
+	GetByID(ctx context.Context, id uint64) (user.User, error)
+}
+
+// Deletion is a video deleted by a sweep, with the objects its media is made of.
+type Deletion struct {
+	Video   storage.Video
+	Reason  Reason
+	Objects []blob.Object
+	Size    int64
+}
+
+// Report describes a sweep. Reclaimed counts the bytes freed, or that would
+// have been freed in a dry run. Deletions that fail are left out, to be
+// retried by the next sweep.
+type Report struct {
+	DryRun    bool
+	Deletions []Deletion
+	Reclaimed int64
+}
+
+// candidate is a video considered by a sweep.
+type candidate struct {
+	video   storage.Video
+	objects []blob.Object
+	size    int64
+}
+
+// Janitor periodically deletes videos beyond the retention rules.
+type Janitor struct {
+	config       Config
+	media        blob.Store
+	videos       VideoStorage
+	vods         BroadcastVodStorage
+	users        UserProvider
+	transactions Transactor
+	now          func() time.Time
+}
+
+// objects returns the objects of a recording, from every object in the media
+// store sorted by key.
+func objects(all []blob.Object, name string) []blob.Object {
+	if name == "" {
+		return nil
+	}
+
+	prefix := recording.Prefix(name)
+	start := sort.Search(len(all), func(i int) bool {
+		return all[i].Key >= prefix
+	})
+
+	end := start
+	for end < len(all) && strings.HasPrefix(all[end].Key, prefix) {
+		end++
+	}
+
+	return all[start:end]
+}
+
+// quota returns the storage quota of a broadcaster.
+func (j *Janitor) quota(ctx context.Context, broadcasterId uint64) int64 {
+	if j.users != nil {
+		if broadcaster, err := j.users.GetByID(ctx, broadcasterId); err == nil && broadcaster.StorageQuota > 0 {
+			return broadcaster.StorageQuota
+		}
+	}
+
+	return j.config.Quota
+}
+
+// plan applies the retention rules to the videos of a broadcaster, newest first.
+func (j *Janitor) plan(ctx context.Context, broadcasterId uint64, candidates []candidate) []Deletion {
+	deletions := make([]Deletion, 0)
+	kept := make([]candidate, 0, len(candidates))
+	used := int64(0)
+
+	for _, c := range candidates {
+		var reason Reason
+
+		switch {
+		case c.video.Pinned:
+		case j.config.MaxAge > 0 && j.now().Sub(c.video.CreatedAt) > j.config.MaxAge:
+			reason = ReasonAge
+		case j.config.KeepLast > 0 && len(kept) >= j.config.KeepLast:
+			reason = ReasonCount
+		}
+
+		if reason != "" {
+			deletions = append(deletions, Deletion{Video: c.video, Reason: reason, Objects: c.objects, Size: c.size})
+			continue
+		}
+
+		if !c.video.Pinned {
+			kept = append(kept, c)
+		}
+		used += c.size
+	}
+
+	// The oldest videos are deleted first to bring the broadcaster within their quota.
+	quota := j.quota(ctx, broadcasterId)
+	for i := len(kept) - 1; i >= 0 && quota > 0 && used > quota; i-- {
+		c := kept[i]
+		deletions = append(deletions, Deletion{Video: c.video, Reason: ReasonQuota, Objects: c.objects, Size: c.size})
+		used -= c.size
+	}
+
+	return deletions
+}
+
+// Plan applies the retention rules to every video, returning what a sweep
+// would delete without deleting anything.
+func (j *Janitor) Plan(ctx context.Context) (Report, error) {
+	videos, err := j.videos.All(ctx)
+	if err != nil {
+		return Report{}, err
+	}
+
+	all, err := j.media.List(ctx, "")
+	if err != nil {
+		return Report{}, err
+	}
+
+	broadcasters := make([]uint64, 0)
+	candidates := make(map[uint64][]candidate)
+
+	for _, video := range videos {
+		c := candidate{video: video, objects: objects(all, video.FilePath)}
+		for _, object := range c.objects {
+			c.size += object.Size
+		}
+
+		if _, ok := candidates[video.BroadcasterId]; !ok {
+			broadcasters = append(broadcasters, video.BroadcasterId)
+		}
+		candidates[video.BroadcasterId] = append(candidates[video.BroadcasterId], c)
+	}
+
+	sort.Slice(broadcasters, func(a, b int) bool {
+		return broadcasters[a] < broadcasters[b]
+	})
+
+	report := Report{DryRun: true, Deletions: make([]Deletion, 0)}
+
+	for _, broadcasterId := range broadcasters {
+		newest := candidates[broadcasterId]
+		sort.SliceStable(newest, func(a, b int) bool {
+			return newest[a].video.CreatedAt.After(newest[b].video.CreatedAt)
+		})
+
+		for _, deletion := range j.plan(ctx, broadcasterId, newest) {
+			report.Deletions = append(report.Deletions, deletion)
+			report.Reclaimed += deletion.Size
+		}
+	}
+
+	return report, nil
+}
+
+// delete removes a video's media, then the video and the links of broadcasts
+// to it. Videos whose media cannot be removed are kept, so it is not orphaned.
+func (j *Janitor) delete(ctx context.Context, deletion Deletion) error {
+	for _, object := range deletion.Objects {
+		if err := j.media.Delete(ctx, object.Key); err != nil {
+			return err
+		}
+	}
+
+	return j.transactions.WithTx(ctx, func(ctx context.Context) error {
+		if j.vods != nil {
+			if err := j.vods.DeleteByVideoID(ctx, deletion.Video.Id); err != nil {
+				return err
+			}
+		}
+
+		return j.videos.Delete(ctx, deletion.Video.Id)
+	})
+}
+
+// Sweep deletes every video beyond the retention rules, or only reports what
+// would be deleted in dry run mode.
+func (j *Janitor) Sweep(ctx context.Context) (Report, error) {
+	planned, err := j.Plan(ctx)
+	if err != nil || j.config.DryRun {
+		return planned, err
+	}
+
+	report := Report{Deletions: make([]Deletion, 0, len(planned.Deletions))}
+
+	for _, deletion := range planned.Deletions {
+		if err := j.delete(ctx, deletion); err != nil {
+			log.Errorf("Couldn't delete video %s under the %s retention rule: %v", deletion.Video.Id, deletion.Reason, err)
+			continue
+		}
+
+		report.Deletions = append(report.Deletions, deletion)
+		report.Reclaimed += deletion.Size
+	}
+
+	return report, nil
+}
+
+// Run sweeps every interval until ctx is cancelled.
+func (j *Janitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := j.Sweep(ctx)
+		if err != nil {
+			log.Errorf("Retention sweep failed: %v", err)
+		}
+
+		for _, deletion := range report.Deletions {
+			if report.DryRun {
+				log.Infof("Would delete video %s of broadcaster %d under the %s retention rule, reclaiming %d bytes",
+					deletion.Video.Id, deletion.Video.BroadcasterId, deletion.Reason, deletion.Size)
+			} else {
+				log.Infof("Deleted video %s of broadcaster %d under the %s retention rule, reclaiming %d bytes",
+					deletion.Video.Id, deletion.Video.BroadcasterId, deletion.Reason, deletion.Size)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Video returns the video with the given id.
+func (j *Janitor) Video(ctx context.Context, id uuid.UUID) (storage.Video, error) {
+	video := j.videos.GetByID(ctx, id)
+	if video == nil {
+		return storage.Video{}, ErrVideoNotFound
+	}
+
+	return *video, nil
+}
+
+// Pin keeps a video forever, regardless of the retention rules, or unpins it
+// so they apply again.
+func (j *Janitor) Pin(ctx context.Context, video storage.Video, pinned bool) (storage.Video, error) {
+	video.Pinned = pinned
+	if err := j.videos.Update(ctx, video.Id, &video); err != nil {
+		return storage.Video{}, err
+	}
+
+	return video, nil
+}
+
+func GetConfig() Config {
+	viper.SetDefault("retention.interval", "1h")
+	viper.SetDefault("retention.max_age", "0s")
+	viper.SetDefault("retention.keep_last", 0)
+	viper.SetDefault("retention.quota", 0)
+	viper.SetDefault("retention.dry_run", false)
+
+	return Config{
+		Interval: viper.GetDuration("retention.interval"),
+		MaxAge:   viper.GetDuration("retention.max_age"),
+		KeepLast: viper.GetInt("retention.keep_last"),
+		Quota:    viper.GetInt64("retention.quota"),
+		DryRun:   viper.GetBool("retention.dry_run"),
+	}
+}
+
+// NewJanitor instantiates a Janitor deleting videos and their media. The
+// broadcast VOD storage and user provider may be nil if broadcasts are not
+// linked to videos, or every broadcaster has the configured quota.
+func NewJanitor(
+	config Config, media blob.Store, videos VideoStorage, vods BroadcastVodStorage, users UserProvider,
+	transactions Transactor,
+) *Janitor {
+	return &Janitor{
+		config:       config,
+		media:        media,
+		videos:       videos,
+		vods:         vods,
+		users:        users,
+		transactions: transactions,
+		now:          time.Now,
+	}
+}
@@ -0,0 +1,276 @@
+package retention
+
+import (
+	"bytes"
+	"context"
+	"github.com/M-Ro/go-vodstream/internal/blob"
+	"github.com/M-Ro/go-vodstream/internal/domain/user"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/spf13/afero"
+	"io"
+	"sort"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+// fakeVideos stores videos in memory.
+type fakeVideos map[uuid.UUID]storage.Video
+
+func (v fakeVideos) All(_ context.Context) ([]storage.Video, error) {
+	videos := make([]storage.Video, 0, len(v))
+	for _, video := range v {
+		videos = append(videos, video)
+	}
+
+	return videos, nil
+}
+
+func (v fakeVideos) GetByID(_ context.Context, id uuid.UUID) *storage.Video {
+	video, ok := v[id]
+	if !ok {
+		return nil
+	}
+	return &video
+}
+
+func (v fakeVideos) Update(_ context.Context, id uuid.UUID, video *storage.Video) error {
+	v[id] = *video
+	return nil
+}
+
+func (v fakeVideos) Delete(_ context.Context, id uuid.UUID) error {
+	delete(v, id)
+	return nil
+}
+
+// fakeVods links videos to the broadcasts they were recorded from.
+type fakeVods map[uuid.UUID]uuid.UUID
+
+func (v fakeVods) DeleteByVideoID(_ context.Context, videoId uuid.UUID) error {
+	delete(v, videoId)
+	return nil
+}
+
+type fakeUsers map[uint64]user.User
+
+func (u fakeUsers) GetByID(_ context.Context, id uint64) (user.User, error) {
+	found, ok := u[id]
+	if !ok {
+		return user.User{}, io.EOF
+	}
+	return found, nil
+}
+
+// testVideo describes a video of the test fixture, recorded days ago with a
+// recording and index of size bytes together.
+type testVideo struct {
+	name          string
+	broadcasterId uint64
+	days          int
+	size          int
+	pinned        bool
+}
+
+var testVideos = []testVideo{
+	{name: "a1", broadcasterId: 1, days: 1, size: 100},
+	{name: "a2", broadcasterId: 1, days: 2, size: 100, pinned: true},
+	{name: "a3", broadcasterId: 1, days: 3, size: 100},
+	{name: "a4", broadcasterId: 1, days: 10, size: 100},
+	{name: "b1", broadcasterId: 2, days: 1, size: 300},
+	{name: "b2", broadcasterId: 2, days: 4, size: 300},
+}
+
+// newTestJanitor stores the test videos, returning a janitor over them along
+// with the ids of the videos by name.
+func newTestJanitor(t *testing.T, config Config) (*Janitor, fakeVideos, fakeVods, map[string]uuid.UUID) {
+	ctx := context.Background()
+	media := blob.NewLocalStore(afero.NewMemMapFs())
+	videos, vods, ids := fakeVideos{}, fakeVods{}, make(map[string]uuid.UUID)
+
+	for _, v := range testVideos {
+		video := storage.Video{
+			Id:            uuid.New(),
+			Title:         v.name,
+			BroadcasterId: v.broadcasterId,
+			FilePath:      v.name + ".flv",
+			IndexPath:     v.name + ".index.json",
+			Pinned:        v.pinned,
+			CreatedAt:     testNow.Add(-time.Duration(v.days) * 24 * time.Hour),
+		}
+
+		// The recording takes all but ten bytes, its index the rest.
+		if err := media.Put(ctx, video.FilePath, bytes.NewReader(make([]byte, v.size-10))); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := media.Put(ctx, video.IndexPath, bytes.NewReader(make([]byte, 10))); err != nil {
+			t.Fatal(err)
+		}
+
+		videos[video.Id] = video
+		vods[video.Id] = uuid.New()
+		ids[v.name] = video.Id
+	}
+
+	// Objects sharing a prefix with a recording do not belong to it.
+	if err := media.Put(ctx, "a10.flv", bytes.NewReader(make([]byte, 1000))); err != nil {
+		t.Fatal(err)
+	}
+
+	users := fakeUsers{2: {Id: 2, StorageQuota: 500}}
+
+	janitor := NewJanitor(config, media, videos, vods, users, storage.Immediate{})
+	janitor.now = func() time.Time { return testNow }
+
+	return janitor, videos, vods, ids
+}
+
+// deleted describes a deletion by the video's title and reason.
+type deleted struct {
+	Title  string
+	Reason Reason
+}
+
+func describe(report Report) []deleted {
+	described := make([]deleted, 0, len(report.Deletions))
+	for _, deletion := range report.Deletions {
+		described = append(described, deleted{Title: deletion.Video.Title, Reason: deletion.Reason})
+	}
+
+	return described
+}
+
+func TestJanitor_Plan(t *testing.T) {
+	tests := []struct {
+		testName  string
+		config    Config
+		expected  []deleted
+		reclaimed int64
+	}{
+		{
+			testName: "expect nothing deleted without rules",
+			expected: []deleted{{Title: "b2", Reason: ReasonQuota}},
+			// The second broadcaster's own quota still applies.
+			reclaimed: 300,
+		},
+		{
+			testName:  "expect videos older than the maximum age deleted, unless pinned",
+			config:    Config{MaxAge: 36 * time.Hour},
+			expected:  []deleted{{Title: "a3", Reason: ReasonAge}, {Title: "a4", Reason: ReasonAge}, {Title: "b2", Reason: ReasonAge}},
+			reclaimed: 500,
+		},
+		{
+			testName:  "expect the most recent videos kept, not counting pinned videos",
+			config:    Config{KeepLast: 2},
+			expected:  []deleted{{Title: "a4", Reason: ReasonCount}, {Title: "b2", Reason: ReasonQuota}},
+			reclaimed: 400,
+		},
+		{
+			testName: "expect the oldest videos deleted to meet the default quota",
+			config:   Config{Quota: 250},
+			expected: []deleted{
+				{Title: "a4", Reason: ReasonQuota}, {Title: "a3", Reason: ReasonQuota}, {Title: "b2", Reason: ReasonQuota},
+			},
+			reclaimed: 500,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			janitor, _, _, _ := newTestJanitor(t, test.config)
+
+			report, err := janitor.Plan(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(describe(report), test.expected); diff != "" {
+				t.Fatal(diff)
+			}
+
+			if report.Reclaimed != test.reclaimed || !report.DryRun {
+				t.Fatalf("expected a dry run reclaiming %d bytes, got %+v", test.reclaimed, report)
+			}
+		})
+	}
+}
+
+func TestJanitor_Sweep(t *testing.T) {
+	for _, dryRun := range []bool{true, false} {
+		janitor, videos, vods, ids := newTestJanitor(t, Config{MaxAge: 5 * 24 * time.Hour, DryRun: dryRun})
+
+		report, err := janitor.Sweep(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := []deleted{{Title: "a4", Reason: ReasonAge}, {Title: "b2", Reason: ReasonQuota}}
+		if diff := cmp.Diff(describe(report), expected); diff != "" {
+			t.Fatal(diff)
+		}
+
+		if report.Reclaimed != 400 || report.DryRun != dryRun {
+			t.Fatalf("expected 400 bytes reclaimed with dry run %t, got %+v", dryRun, report)
+		}
+
+		objects, err := janitor.media.List(context.Background(), "a4.")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, videoKept := videos[ids["a4"]]
+		_, vodKept := vods[ids["a4"]]
+
+		if kept := len(objects) == 2 && videoKept && vodKept; kept != dryRun {
+			t.Fatalf("expected the video to be kept only in a dry run, dry run %t kept %d objects, video %t, vod %t",
+				dryRun, len(objects), videoKept, vodKept)
+		}
+
+		remaining := len(testVideos)
+		if !dryRun {
+			remaining -= len(expected)
+		}
+
+		if len(videos) != remaining {
+			t.Fatalf("expected no other video deleted, %d remain", len(videos))
+		}
+	}
+}
+
+func TestJanitor_Pin(t *testing.T) {
+	janitor, _, _, ids := newTestJanitor(t, Config{KeepLast: 1})
+	ctx := context.Background()
+
+	video, err := janitor.Video(ctx, ids["a1"])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := janitor.Pin(ctx, video, true); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := janitor.Plan(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	titles := make([]string, 0)
+	for _, d := range describe(report) {
+		titles = append(titles, d.Title)
+	}
+	sort.Strings(titles)
+
+	// Pinned videos neither count towards the videos kept, nor are deleted.
+	if diff := cmp.Diff(titles, []string{"a4", "b2"}); diff != "" {
+		t.Fatal(diff)
+	}
+
+	if _, err := janitor.Video(ctx, uuid.New()); err != ErrVideoNotFound {
+		t.Fatal(cmp.Diff(err, ErrVideoNotFound))
+	}
+}
@@ -0,0 +1,46 @@
+package search
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	maxQueryTerms = 8
+	maxTermLength = 64
+)
+
+var (
+	ErrEmptyQuery   = errors.New("search query must contain a letter or digit")
+	ErrQueryTooLong = errors.New("search query must have at most 8 words of at most 64 characters")
+)
+
+// ParseQuery turns what a user typed into a Postgres tsquery, matching documents
+// containing every word of the input as a prefix so that partially typed words
+// match while autocompleting. Words are split on anything but letters and digits,
+// which also keeps tsquery operators out of the result.
+func ParseQuery(input string) (string, error) {
+	terms := strings.FieldsFunc(strings.ToLower(input), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	if len(terms) == 0 {
+		return "", ErrEmptyQuery
+	}
+
+	if len(terms) > maxQueryTerms {
+		return "", ErrQueryTooLong
+	}
+
+	for i, term := range terms {
+		if utf8.RuneCountInString(term) > maxTermLength {
+			return "", ErrQueryTooLong
+		}
+
+		terms[i] = term + ":*"
+	}
+
+	return strings.Join(terms, " & "), nil
+}
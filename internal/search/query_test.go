@@ -0,0 +1,66 @@
+package search
+
+import (
+	"github.com/google/go-cmp/cmp"
+	"strings"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		testName string
+		input    string
+
+		expected    string
+		expectedErr error
+	}{
+		{
+			testName: "Expect a single word to match as a prefix.",
+			input:    "speed",
+			expected: "speed:*",
+		},
+		{
+			testName: "Expect every word to be required, lowercased.",
+			input:    "  Any% WORLD record ",
+			expected: "any:* & world:* & record:*",
+		},
+		{
+			testName: "Expect tsquery operators to be dropped.",
+			input:    "mario & !(luigi | peach):*",
+			expected: "mario:* & luigi:* & peach:*",
+		},
+		{
+			testName: "Expect letters outside ascii to be kept.",
+			input:    "Café über",
+			expected: "café:* & über:*",
+		},
+		{
+			testName:    "Expect an error for input without words.",
+			input:       " %&! ",
+			expectedErr: ErrEmptyQuery,
+		},
+		{
+			testName:    "Expect an error for too many words.",
+			input:       "a b c d e f g h i",
+			expectedErr: ErrQueryTooLong,
+		},
+		{
+			testName:    "Expect an error for a word that is too long.",
+			input:       strings.Repeat("a", maxTermLength+1),
+			expectedErr: ErrQueryTooLong,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			tsquery, err := ParseQuery(test.input)
+			if err != test.expectedErr {
+				t.Fatalf("expected error %v, got %v", test.expectedErr, err)
+			}
+
+			if !cmp.Equal(tsquery, test.expected) {
+				t.Fatal(cmp.Diff(tsquery, test.expected))
+			}
+		})
+	}
+}
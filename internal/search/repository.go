@@ -0,0 +1,46 @@
+package search
+
+import (
+	"context"
+	"github.com/M-Ro/go-vodstream/internal/domain/search"
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	"github.com/M-Ro/go-vodstream/storage"
+)
+
+type StorageProvider interface {
+	Search(ctx context.Context, tsquery string, types []string, options paginate.QueryOptions) ([]storage.SearchResult, error)
+}
+
+type Repository struct {
+	StorageProvider StorageProvider
+}
+
+// Search returns the published channels, broadcasts and videos of the given types matching
+// what a user typed, best matches first, limited and offset by the provided QueryOptions.
+// Returns ErrEmptyQuery or ErrQueryTooLong if the input cannot be searched for.
+func (r Repository) Search(
+	ctx context.Context, input string, types []search.Type, options paginate.QueryOptions,
+) ([]search.Result, error) {
+	tsquery, err := ParseQuery(input)
+	if err != nil {
+		return []search.Result{}, err
+	}
+
+	storageTypes := make([]string, len(types))
+	for i, t := range types {
+		storageTypes[i] = string(t)
+	}
+
+	results, err := r.StorageProvider.Search(ctx, tsquery, storageTypes, options)
+	if err != nil {
+		return []search.Result{}, err
+	}
+
+	return storage.SearchResultsToDomain(results), nil
+}
+
+func NewRepository(s StorageProvider) Repository {
+	return Repository{
+		StorageProvider: s,
+	}
+}
@@ -0,0 +1,106 @@
+package thumbnail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/nareix/joy4/av"
+	"github.com/nareix/joy4/codec/h264parser"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg"
+	_ "image/png"
+	"os/exec"
+	"strings"
+)
+
+var (
+	ErrNoVideo          = errors.New("media has no video stream")
+	ErrUnsupportedCodec = errors.New("codec cannot be decoded to an image")
+	ErrNoCommand        = errors.New("no frame extraction command configured")
+)
+
+// FrameExtractor decodes a single video keyframe to an image.
+type FrameExtractor interface {
+	Extract(ctx context.Context, codec av.VideoCodecData, frame av.Packet) (image.Image, error)
+}
+
+// NoopExtractor decodes nothing, returning a blank frame the size of the video.
+// It stands in for a decoder in tests and where no decoder is installed.
+type NoopExtractor struct{}
+
+func (NoopExtractor) Extract(_ context.Context, codec av.VideoCodecData, _ av.Packet) (image.Image, error) {
+	width, height := codec.Width(), codec.Height()
+	if width <= 0 || height <= 0 {
+		width, height = 16, 9
+	}
+
+	frame := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(frame, frame.Bounds(), image.NewUniform(color.Gray{Y: 0x40}), image.Point{}, draw.Src)
+
+	return frame, nil
+}
+
+// ProcessExtractor decodes keyframes with an external process, such as ffmpeg.
+// The keyframe is written to the process's stdin as an H.264 Annex B stream,
+// and the process is expected to write a single PNG or JPEG image to stdout.
+type ProcessExtractor struct {
+	Command []string
+}
+
+func (p ProcessExtractor) Extract(ctx context.Context, codec av.VideoCodecData, frame av.Packet) (image.Image, error) {
+	if len(p.Command) == 0 {
+		return nil, ErrNoCommand
+	}
+
+	h264, ok := codec.(h264parser.CodecData)
+	if !ok {
+		return nil, ErrUnsupportedCodec
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, p.Command[0], p.Command[1:]...)
+	cmd.Stdin = bytes.NewReader(AnnexB(h264, frame.Data))
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", p.Command[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	decoded, _, err := image.Decode(&stdout)
+	if err != nil {
+		return nil, err
+	}
+
+	return decoded, nil
+}
+
+// AnnexB converts a length prefixed H.264 keyframe to an Annex B stream led by
+// the stream's parameter sets, so it can be decoded on its own.
+func AnnexB(codec h264parser.CodecData, data []byte) []byte {
+	nalus, _ := h264parser.SplitNALUs(data)
+	nalus = append([][]byte{codec.SPS(), codec.PPS()}, nalus...)
+
+	stream := make([]byte, 0, len(data)+len(codec.SPS())+len(codec.PPS())+4*len(nalus))
+	for _, nalu := range nalus {
+		stream = append(stream, 0, 0, 0, 1)
+		stream = append(stream, nalu...)
+	}
+
+	return stream
+}
+
+// videoStream returns the index and codec of the first video stream.
+func videoStream(streams []av.CodecData) (int8, av.VideoCodecData, error) {
+	for idx, stream := range streams {
+		if codec, ok := stream.(av.VideoCodecData); ok && stream.Type().IsVideo() {
+			return int8(idx), codec, nil
+		}
+	}
+
+	return 0, nil, ErrNoVideo
+}
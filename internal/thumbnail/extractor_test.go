@@ -0,0 +1,94 @@
+package thumbnail
+
+import (
+	"bytes"
+	"context"
+	"github.com/M-Ro/go-vodstream/internal/avtest"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/nareix/joy4/av"
+	"github.com/nareix/joy4/codec/h264parser"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// keyFrame returns the codec of the test video stream and its first keyframe.
+func keyFrame() (av.VideoCodecData, av.Packet) {
+	return avtest.Streams()[avtest.VideoIdx].(av.VideoCodecData), avtest.Packets(1, 1)[0]
+}
+
+func TestAnnexB(t *testing.T) {
+	codec, packet := keyFrame()
+	h264 := codec.(h264parser.CodecData)
+
+	stream := AnnexB(h264, packet.Data)
+
+	nalus, typ := h264parser.SplitNALUs(stream)
+	if typ != h264parser.NALU_ANNEXB {
+		t.Fatal(cmp.Diff(typ, h264parser.NALU_ANNEXB))
+	}
+
+	if len(nalus) != 3 || !bytes.Equal(nalus[0], h264.SPS()) || !bytes.Equal(nalus[1], h264.PPS()) {
+		t.Fatal("expected the stream to be led by the parameter sets")
+	}
+
+	frame, _ := h264parser.SplitNALUs(packet.Data)
+	if !bytes.Equal(nalus[2], frame[0]) {
+		t.Fatal("expected the stream to carry the keyframe")
+	}
+}
+
+func TestNoopExtractor(t *testing.T) {
+	codec, packet := keyFrame()
+
+	frame, err := NoopExtractor{}.Extract(context.Background(), codec, packet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(frame.Bounds().Size(), image.Pt(1280, 720)); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestProcessExtractor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "frame.png")
+
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := png.Encode(file, image.NewGray(image.Rect(0, 0, 64, 36))); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	codec, packet := keyFrame()
+
+	tests := []struct {
+		testName string
+		command  []string
+		size     image.Point
+		err      error
+	}{
+		{testName: "decodes the command's output", command: []string{"cat", path}, size: image.Pt(64, 36)},
+		{testName: "no command", command: nil, err: ErrNoCommand},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			frame, err := ProcessExtractor{Command: test.command}.Extract(context.Background(), codec, packet)
+			if !cmp.Equal(err, test.err, cmpopts.EquateErrors()) {
+				t.Fatal(cmp.Diff(err, test.err, cmpopts.EquateErrors()))
+			}
+
+			if err == nil && frame.Bounds().Size() != test.size {
+				t.Fatal(cmp.Diff(frame.Bounds().Size(), test.size))
+			}
+		})
+	}
+}
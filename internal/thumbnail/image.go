@@ -0,0 +1,49 @@
+package thumbnail
+
+import (
+	"bytes"
+	"image"
+	"image/draw"
+	"image/jpeg"
+)
+
+// size returns the height of an image scaled to width, keeping the aspect
+// ratio of the source, which is assumed to be 16:9 when unknown.
+func size(width int, sourceWidth int, sourceHeight int) image.Point {
+	if sourceWidth <= 0 || sourceHeight <= 0 {
+		return image.Pt(width, width*9/16)
+	}
+
+	return image.Pt(width, width*sourceHeight/sourceWidth)
+}
+
+// scale resizes the image to the given size by nearest neighbour sampling.
+func scale(src image.Image, to image.Point) *image.RGBA {
+	bounds := src.Bounds()
+	dst := image.NewRGBA(image.Rectangle{Max: to})
+
+	if bounds.Dx() == to.X && bounds.Dy() == to.Y {
+		draw.Draw(dst, dst.Bounds(), src, bounds.Min, draw.Src)
+		return dst
+	}
+
+	for y := 0; y < to.Y; y++ {
+		sy := bounds.Min.Y + y*bounds.Dy()/to.Y
+		for x := 0; x < to.X; x++ {
+			sx := bounds.Min.X + x*bounds.Dx()/to.X
+			dst.Set(x, y, src.At(sx, sy))
+		}
+	}
+
+	return dst
+}
+
+// encode encodes the image as a JPEG of the given quality.
+func encode(img image.Image, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,113 @@
+package thumbnail
+
+import (
+	"context"
+	"errors"
+	"github.com/M-Ro/go-vodstream/internal/live"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoThumbnail = errors.New("channel has no thumbnail yet")
+)
+
+// LiveThumbnail is the latest thumbnail of a live channel.
+type LiveThumbnail struct {
+	Channel *live.Channel
+
+	// Image is the thumbnail, encoded as a JPEG.
+	Image     []byte
+	UpdatedAt time.Time
+}
+
+// Start renders thumbnails of the channel every LiveInterval of media, until
+// the channel ends.
+func (g *Generator) Start(channel *live.Channel) {
+	go func() {
+		if err := g.watch(channel); err != nil {
+			log.Warnf("Stopped rendering thumbnails of channel %s: %v", channel.Name, err)
+		}
+
+		g.lock.Lock()
+		if thumbnail, ok := g.live[strings.ToLower(channel.Name)]; ok && thumbnail.Channel == channel {
+			delete(g.live, strings.ToLower(channel.Name))
+		}
+		g.lock.Unlock()
+	}()
+}
+
+// watch follows the channel from its latest packet, rendering the first video
+// keyframe at least LiveInterval after the last one rendered.
+func (g *Generator) watch(channel *live.Channel) error {
+	cursor := channel.Queue.Latest()
+
+	streams, err := cursor.Streams()
+	if err != nil {
+		return err
+	}
+
+	video, codec, err := videoStream(streams)
+	if err != nil {
+		return err
+	}
+
+	rendered := false
+	var last time.Duration
+
+	for {
+		packet, err := cursor.ReadPacket()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if packet.Idx != video || !packet.IsKeyFrame || (rendered && packet.Time-last < g.config.LiveInterval) {
+			continue
+		}
+
+		// Failed frames wait out the interval too, rather than retrying every keyframe.
+		rendered, last = true, packet.Time
+
+		ctx, cancel := context.WithTimeout(context.Background(), g.config.Timeout)
+		frame, err := g.extractor.Extract(ctx, codec, packet)
+		cancel()
+
+		if err != nil {
+			log.Warnf("Couldn't render thumbnail of channel %s: %v", channel.Name, err)
+			continue
+		}
+
+		bounds := frame.Bounds()
+		encoded, err := encode(scale(frame, size(g.config.Width, bounds.Dx(), bounds.Dy())), g.config.Quality)
+		if err != nil {
+			log.Warnf("Couldn't encode thumbnail of channel %s: %v", channel.Name, err)
+			continue
+		}
+
+		g.lock.Lock()
+		g.live[strings.ToLower(channel.Name)] = &LiveThumbnail{
+			Channel:   channel,
+			Image:     encoded,
+			UpdatedAt: time.Now(),
+		}
+		g.lock.Unlock()
+	}
+}
+
+// Live returns the latest thumbnail of the named channel, or ErrNoThumbnail if
+// none has been rendered while it is live.
+func (g *Generator) Live(name string) (LiveThumbnail, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	thumbnail, ok := g.live[strings.ToLower(name)]
+	if !ok {
+		return LiveThumbnail{}, ErrNoThumbnail
+	}
+
+	return *thumbnail, nil
+}
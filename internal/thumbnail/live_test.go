@@ -0,0 +1,58 @@
+package thumbnail
+
+import (
+	"github.com/M-Ro/go-vodstream/internal/avtest"
+	"github.com/M-Ro/go-vodstream/internal/blob"
+	"github.com/M-Ro/go-vodstream/internal/domain/broadcast"
+	"github.com/M-Ro/go-vodstream/internal/live"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/spf13/afero"
+	"testing"
+	"time"
+)
+
+func TestGenerator_Live(t *testing.T) {
+	generator := NewGenerator(testConfig, blob.NewLocalStore(afero.NewMemMapFs()), NoopExtractor{})
+	channels := live.NewRegistry()
+
+	channel, err := channels.Open("testUser1", 1, broadcast.VisibilityPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := channel.Queue.WriteHeader(avtest.Streams()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := generator.Live("testuser1"); err != ErrNoThumbnail {
+		t.Fatal(cmp.Diff(err, ErrNoThumbnail, cmpopts.EquateErrors()))
+	}
+
+	generator.Start(channel)
+
+	// Wait for the generator to take its position before packets arrive.
+	time.Sleep(20 * time.Millisecond)
+	for _, packet := range avtest.Packets(50, 25) {
+		channel.Queue.WritePacket(packet)
+		time.Sleep(100 * time.Microsecond)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	thumbnail, err := generator.Live("TestUser1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if thumbnail.Channel != channel || len(thumbnail.Image) == 0 {
+		t.Fatalf("unexpected thumbnail: %+v", thumbnail)
+	}
+
+	channels.Close(channel)
+	time.Sleep(50 * time.Millisecond)
+
+	if _, err := generator.Live("testuser1"); err != ErrNoThumbnail {
+		t.Fatal(cmp.Diff(err, ErrNoThumbnail, cmpopts.EquateErrors()))
+	}
+}
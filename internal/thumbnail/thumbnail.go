@@ -0,0 +1,114 @@
+// Package thumbnail renders imagery for channels and videos: periodic
+// thumbnails of live channels, a poster for each video, and WebVTT sprite
+// sheets for scrub previews. Keyframes are decoded by a pluggable
+// FrameExtractor, as there is no H.264 decoder in pure Go to rely on.
+package thumbnail
+
+import (
+	"github.com/M-Ro/go-vodstream/internal/blob"
+	"github.com/spf13/viper"
+	"sync"
+	"time"
+)
+
+// Extractor names accepted by NewExtractor.
+const (
+	ExtractorProcess = "process"
+	ExtractorNone    = "none"
+)
+
+type Config struct {
+	// Width is the width of live thumbnails and posters, heights keep the
+	// aspect ratio of the video.
+	Width int
+
+	// Quality is the JPEG quality images are encoded with, from 1 to 100.
+	Quality int
+
+	// LiveInterval is how often the thumbnail of a live channel is refreshed.
+	LiveInterval time.Duration
+
+	// PosterOffset is how far into a video its poster is taken from, videos
+	// shorter than this take their poster from the last keyframe.
+	PosterOffset time.Duration
+
+	// SpriteInterval is the time between frames of a sprite sheet, tiled
+	// SpriteColumns by SpriteRows to a sheet, each SpriteWidth wide.
+	SpriteInterval time.Duration
+	SpriteWidth    int
+	SpriteColumns  int
+	SpriteRows     int
+
+	// Timeout bounds decoding a single frame.
+	Timeout time.Duration
+
+	// Extractor selects the FrameExtractor, Command is run by the process extractor.
+	Extractor string
+	Command   []string
+}
+
+// Generator renders thumbnails of live channels, and posters and sprite sheets
+// of videos, stored alongside their recordings.
+type Generator struct {
+	config    Config
+	media     blob.Store
+	extractor FrameExtractor
+
+	lock sync.Mutex
+	live map[string]*LiveThumbnail
+
+	// rendering serialises renders of video imagery, so concurrent requests
+	// for a video's imagery render it once.
+	rendering sync.Mutex
+}
+
+// NewExtractor returns the FrameExtractor selected by the config.
+func NewExtractor(config Config) FrameExtractor {
+	if config.Extractor == ExtractorNone {
+		return NoopExtractor{}
+	}
+
+	return ProcessExtractor{Command: config.Command}
+}
+
+func GetConfig() Config {
+	viper.SetDefault("thumbnail.width", 320)
+	viper.SetDefault("thumbnail.quality", 80)
+	viper.SetDefault("thumbnail.live_interval", "30s")
+	viper.SetDefault("thumbnail.poster_offset", "10s")
+	viper.SetDefault("thumbnail.sprite.interval", "10s")
+	viper.SetDefault("thumbnail.sprite.width", 160)
+	viper.SetDefault("thumbnail.sprite.columns", 10)
+	viper.SetDefault("thumbnail.sprite.rows", 10)
+	viper.SetDefault("thumbnail.timeout", "10s")
+	viper.SetDefault("thumbnail.extractor", ExtractorProcess)
+	viper.SetDefault("thumbnail.command", []string{
+		"ffmpeg", "-hide_banner", "-loglevel", "error", "-f", "h264", "-i", "pipe:0",
+		"-frames:v", "1", "-f", "image2pipe", "-c:v", "png", "pipe:1",
+	})
+
+	return Config{
+		Width:          viper.GetInt("thumbnail.width"),
+		Quality:        viper.GetInt("thumbnail.quality"),
+		LiveInterval:   viper.GetDuration("thumbnail.live_interval"),
+		PosterOffset:   viper.GetDuration("thumbnail.poster_offset"),
+		SpriteInterval: viper.GetDuration("thumbnail.sprite.interval"),
+		SpriteWidth:    viper.GetInt("thumbnail.sprite.width"),
+		SpriteColumns:  viper.GetInt("thumbnail.sprite.columns"),
+		SpriteRows:     viper.GetInt("thumbnail.sprite.rows"),
+		Timeout:        viper.GetDuration("thumbnail.timeout"),
+		Extractor:      viper.GetString("thumbnail.extractor"),
+		Command:        viper.GetStringSlice("thumbnail.command"),
+	}
+}
+
+// NewGenerator instantiates a Generator decoding frames with extractor, storing
+// video imagery in media.
+func NewGenerator(config Config, media blob.Store, extractor FrameExtractor) *Generator {
+	return &Generator{
+		config:    config,
+		media:     media,
+		extractor: extractor,
+		live:      make(map[string]*LiveThumbnail),
+	}
+}
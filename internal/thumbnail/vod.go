@@ -0,0 +1,318 @@
+package thumbnail
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/M-Ro/go-vodstream/internal/blob"
+	"github.com/M-Ro/go-vodstream/internal/recording"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/nareix/joy4/av"
+	"image"
+	"image/draw"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// base strips the extension from the name of a recording.
+func base(name string) string {
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// PosterFile returns the name of the poster of a recording.
+func PosterFile(name string) string {
+	return base(name) + ".poster.jpg"
+}
+
+// SpritesFile returns the name of the WebVTT track of a recording's sprite sheets.
+func SpritesFile(name string) string {
+	return base(name) + ".sprites.vtt"
+}
+
+// SpriteFile returns the name of one of a recording's sprite sheets.
+func SpriteFile(name string, sheet int) string {
+	return fmt.Sprintf("%s.sprite-%d.jpg", base(name), sheet)
+}
+
+// SpriteURI returns the URI sprite sheets are referenced by within the WebVTT
+// track, relative to where the track is served.
+func SpriteURI(sheet int) string {
+	return fmt.Sprintf("sprite-%d.jpg", sheet)
+}
+
+// frames reads keyframes from a recording.
+type frames struct {
+	file  *blob.File
+	index recording.Index
+
+	video int8
+	codec av.VideoCodecData
+}
+
+// open opens the recording of a video for reading keyframes.
+func (g *Generator) open(ctx context.Context, video storage.Video) (*frames, error) {
+	file, index, err := recording.Open(ctx, g.media, video)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(index.KeyFrames) == 0 {
+		file.Close()
+		return nil, recording.ErrEmptyIndex
+	}
+
+	streams := make([]av.CodecData, 0, len(index.Streams))
+	for _, stream := range index.Streams {
+		codec, err := stream.CodecData()
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+
+		streams = append(streams, codec)
+	}
+
+	idx, codec, err := videoStream(streams)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &frames{file: file, index: index, video: idx, codec: codec}, nil
+}
+
+// at returns the keyframe playback of offset t, from the first keyframe, starts from.
+func (f *frames) at(t time.Duration) recording.KeyFrame {
+	keyFrame, _ := f.index.Seek(f.index.KeyFrames[0].Time + t)
+	return keyFrame
+}
+
+// read returns the video packet of the keyframe.
+func (f *frames) read(keyFrame recording.KeyFrame) (av.Packet, error) {
+	demuxer := recording.Section(f.file, f.index, keyFrame, f.index.Size)
+
+	for {
+		packet, err := demuxer.ReadPacket()
+		if err == io.EOF {
+			return av.Packet{}, recording.ErrEmptyIndex
+		} else if err != nil {
+			return av.Packet{}, err
+		}
+
+		if packet.Idx == f.video && packet.IsKeyFrame {
+			return packet, nil
+		}
+	}
+}
+
+// extract decodes the keyframe to an image.
+func (g *Generator) extract(ctx context.Context, f *frames, keyFrame recording.KeyFrame) (image.Image, error) {
+	packet, err := f.read(keyFrame)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, g.config.Timeout)
+	defer cancel()
+
+	return g.extractor.Extract(ctx, f.codec, packet)
+}
+
+// exists returns true if the object is stored.
+func (g *Generator) exists(ctx context.Context, key string) (bool, error) {
+	_, err := g.media.Stat(ctx, key)
+	if err == blob.ErrNotFound {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// Poster returns the key of the video's poster, rendering it alongside the
+// recording on first use.
+func (g *Generator) Poster(ctx context.Context, video storage.Video) (string, error) {
+	key := PosterFile(video.FilePath)
+
+	g.rendering.Lock()
+	defer g.rendering.Unlock()
+
+	if exists, err := g.exists(ctx, key); exists || err != nil {
+		return key, err
+	}
+
+	f, err := g.open(ctx, video)
+	if err != nil {
+		return "", err
+	}
+	defer f.file.Close()
+
+	frame, err := g.extract(ctx, f, f.at(g.config.PosterOffset))
+	if err != nil {
+		return "", err
+	}
+
+	bounds := frame.Bounds()
+	encoded, err := encode(scale(frame, size(g.config.Width, bounds.Dx(), bounds.Dy())), g.config.Quality)
+	if err != nil {
+		return "", err
+	}
+
+	return key, g.media.Put(ctx, key, bytes.NewReader(encoded))
+}
+
+// Sprites returns the key of the WebVTT track of the video's sprite sheets,
+// rendering the track and sheets alongside the recording on first use. Each cue
+// covers SpriteInterval of the video, showing the keyframe at or before its start.
+func (g *Generator) Sprites(ctx context.Context, video storage.Video) (string, error) {
+	key := SpritesFile(video.FilePath)
+
+	g.rendering.Lock()
+	defer g.rendering.Unlock()
+
+	if exists, err := g.exists(ctx, key); exists || err != nil {
+		return key, err
+	}
+
+	f, err := g.open(ctx, video)
+	if err != nil {
+		return "", err
+	}
+	defer f.file.Close()
+
+	duration := f.index.Duration
+	count := int((duration + g.config.SpriteInterval - 1) / g.config.SpriteInterval)
+	if count == 0 {
+		count = 1
+	}
+
+	tile := size(g.config.SpriteWidth, f.codec.Width(), f.codec.Height())
+	perSheet := g.config.SpriteColumns * g.config.SpriteRows
+
+	var track bytes.Buffer
+	track.WriteString("WEBVTT\n")
+
+	var sheet *image.RGBA
+	var scaled *image.RGBA
+	previous := int64(-1)
+
+	for i := 0; i < count; i++ {
+		n := i / perSheet
+		if i%perSheet == 0 {
+			remaining := count - i
+			if remaining > perSheet {
+				remaining = perSheet
+			}
+
+			columns := g.config.SpriteColumns
+			if remaining < columns {
+				columns = remaining
+			}
+
+			rows := (remaining + g.config.SpriteColumns - 1) / g.config.SpriteColumns
+			sheet = image.NewRGBA(image.Rect(0, 0, columns*tile.X, rows*tile.Y))
+		}
+
+		start := time.Duration(i) * g.config.SpriteInterval
+		end := start + g.config.SpriteInterval
+		if end > duration {
+			end = duration
+		}
+
+		// Intervals shorter than a GOP show the same keyframe, decoded once.
+		keyFrame := f.at(start)
+		if keyFrame.Offset != previous {
+			frame, err := g.extract(ctx, f, keyFrame)
+			if err != nil {
+				g.removeSprites(ctx, video.FilePath, n)
+				return "", err
+			}
+
+			scaled, previous = scale(frame, tile), keyFrame.Offset
+		}
+
+		position := i % perSheet
+		at := image.Pt(position%g.config.SpriteColumns*tile.X, position/g.config.SpriteColumns*tile.Y)
+		draw.Draw(sheet, image.Rectangle{Min: at, Max: at.Add(tile)}, scaled, image.Point{}, draw.Src)
+
+		fmt.Fprintf(&track, "\n%s --> %s\n%s#xywh=%d,%d,%d,%d\n",
+			timestamp(start), timestamp(end), SpriteURI(n), at.X, at.Y, tile.X, tile.Y)
+
+		if position == perSheet-1 || i == count-1 {
+			encoded, err := encode(sheet, g.config.Quality)
+			if err == nil {
+				err = g.media.Put(ctx, SpriteFile(video.FilePath, n), bytes.NewReader(encoded))
+			}
+
+			if err != nil {
+				g.removeSprites(ctx, video.FilePath, n)
+				return "", err
+			}
+		}
+	}
+
+	// The track is written last, its presence marks the sheets as complete.
+	return key, g.media.Put(ctx, key, &track)
+}
+
+// Sheet returns the key of one of the video's sprite sheets, or
+// blob.ErrNotFound until its sheets have been rendered.
+func (g *Generator) Sheet(ctx context.Context, video storage.Video, sheet int) (string, error) {
+	for _, key := range []string{SpritesFile(video.FilePath), SpriteFile(video.FilePath, sheet)} {
+		if exists, err := g.exists(ctx, key); err != nil {
+			return "", err
+		} else if !exists {
+			return "", blob.ErrNotFound
+		}
+	}
+
+	return SpriteFile(video.FilePath, sheet), nil
+}
+
+// removeSprites deletes the sprite sheets of a recording up to sheet, after a
+// failed render.
+func (g *Generator) removeSprites(ctx context.Context, name string, sheet int) {
+	for n := 0; n <= sheet; n++ {
+		g.media.Delete(ctx, SpriteFile(name, n))
+	}
+}
+
+// Video renders the poster and sprite sheets of a video.
+func (g *Generator) Video(ctx context.Context, video storage.Video) error {
+	if _, err := g.Poster(ctx, video); err != nil {
+		return err
+	}
+
+	_, err := g.Sprites(ctx, video)
+	return err
+}
+
+// timestamp formats an offset as a WebVTT timestamp.
+func timestamp(t time.Duration) string {
+	ms := t.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+// WithQuery returns a WebVTT sprite track with query appended to the URI of
+// every sprite sheet, so signed URLs carry their signature to the sheets.
+func WithQuery(track []byte, query string) []byte {
+	if query == "" {
+		return track
+	}
+
+	var out bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(track))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#xywh="); i > 0 {
+			line = line[:i] + "?" + query + line[i:]
+		}
+
+		out.WriteString(line)
+		out.WriteByte('\n')
+	}
+
+	return out.Bytes()
+}
@@ -0,0 +1,187 @@
+package thumbnail
+
+import (
+	"bytes"
+	"context"
+	"github.com/M-Ro/go-vodstream/internal/avtest"
+	"github.com/M-Ro/go-vodstream/internal/blob"
+	"github.com/M-Ro/go-vodstream/internal/recording"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/afero"
+	"image"
+	"image/jpeg"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testConfig = Config{
+	Width:          64,
+	Quality:        80,
+	LiveInterval:   time.Second,
+	PosterOffset:   10 * time.Second,
+	SpriteInterval: 500 * time.Millisecond,
+	SpriteWidth:    16,
+	SpriteColumns:  3,
+	SpriteRows:     2,
+	Timeout:        time.Second,
+}
+
+// writeVideo records count frames, with a keyframe every second, as a video.
+func writeVideo(t *testing.T, media blob.Store, count int) storage.Video {
+	config := recording.Config{SpoolDirectory: t.TempDir()}
+
+	_, err := recording.Create(context.Background(), config, media, "test.flv", avtest.Streams(),
+		func(writer *recording.Writer) error {
+			for _, packet := range avtest.Packets(count, 25) {
+				if err := writer.WritePacket(packet); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return storage.Video{FilePath: "test.flv", IndexPath: recording.IndexFile("test.flv")}
+}
+
+// read reads a stored object.
+func read(t *testing.T, media blob.Store, key string) []byte {
+	reader, err := media.Get(context.Background(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+// decode reads the stored JPEG, returning its size.
+func decode(t *testing.T, media blob.Store, key string) image.Point {
+	img, err := jpeg.Decode(bytes.NewReader(read(t, media, key)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return img.Bounds().Size()
+}
+
+func TestGenerator_Poster(t *testing.T) {
+	media := blob.NewLocalStore(afero.NewMemMapFs())
+	video := writeVideo(t, media, 100)
+
+	generator := NewGenerator(testConfig, media, NoopExtractor{})
+
+	// The video is shorter than the poster offset, so the last keyframe is used.
+	key, err := generator.Poster(context.Background(), video)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if key != "test.poster.jpg" {
+		t.Fatal(cmp.Diff(key, "test.poster.jpg"))
+	}
+
+	if diff := cmp.Diff(decode(t, media, key), image.Pt(64, 36)); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestGenerator_Sprites(t *testing.T) {
+	media := blob.NewLocalStore(afero.NewMemMapFs())
+	ctx := context.Background()
+
+	// 3.96 seconds of video make 8 half second sprites, six to a sheet.
+	video := writeVideo(t, media, 100)
+
+	generator := NewGenerator(testConfig, media, NoopExtractor{})
+
+	key, err := generator.Sprites(ctx, video)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	track := read(t, media, key)
+
+	lines := strings.Split(string(track), "\n")
+	if lines[0] != "WEBVTT" {
+		t.Fatal(cmp.Diff(lines[0], "WEBVTT"))
+	}
+
+	expected := []string{
+		"00:00:00.000 --> 00:00:00.500", "sprite-0.jpg#xywh=0,0,16,9",
+		"00:00:02.500 --> 00:00:03.000", "sprite-0.jpg#xywh=32,9,16,9",
+		"00:00:03.500 --> 00:00:03.960", "sprite-1.jpg#xywh=16,0,16,9",
+	}
+
+	for i := 0; i < len(expected); i += 2 {
+		if !strings.Contains(string(track), expected[i]+"\n"+expected[i+1]+"\n") {
+			t.Fatalf("expected cue %q in the track:\n%s", expected[i], track)
+		}
+	}
+
+	if count := strings.Count(string(track), "-->"); count != 8 {
+		t.Fatal(cmp.Diff(count, 8))
+	}
+
+	sizes := []image.Point{image.Pt(48, 18), image.Pt(32, 9)}
+	for sheet, size := range sizes {
+		sheetKey, err := generator.Sheet(ctx, video, sheet)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if diff := cmp.Diff(decode(t, media, sheetKey), size); diff != "" {
+			t.Fatal(diff)
+		}
+	}
+
+	if _, err := generator.Sheet(ctx, video, 2); err != blob.ErrNotFound {
+		t.Fatalf("expected no third sheet: %v", err)
+	}
+}
+
+func TestWithQuery(t *testing.T) {
+	track := []byte("WEBVTT\n\n00:00:00.000 --> 00:00:10.000\nsprite-0.jpg#xywh=0,0,160,90\n")
+
+	expected := "WEBVTT\n\n00:00:00.000 --> 00:00:10.000\nsprite-0.jpg?exp=1&sig=abc#xywh=0,0,160,90\n"
+	if diff := cmp.Diff(string(WithQuery(track, "exp=1&sig=abc")), expected); diff != "" {
+		t.Fatal(diff)
+	}
+
+	if diff := cmp.Diff(string(WithQuery(track, "")), string(track)); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestRemove_Imagery(t *testing.T) {
+	media := blob.NewLocalStore(afero.NewMemMapFs())
+	video := writeVideo(t, media, 100)
+	ctx := context.Background()
+
+	generator := NewGenerator(testConfig, media, NoopExtractor{})
+	if err := generator.Video(ctx, video); err != nil {
+		t.Fatal(err)
+	}
+
+	recording.Remove(ctx, media, video.FilePath)
+
+	objects, err := media.List(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(objects) != 0 {
+		t.Fatalf("expected the recording's imagery to be removed with it, found %d objects", len(objects))
+	}
+}
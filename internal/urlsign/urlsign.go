@@ -0,0 +1,191 @@
+package urlsign
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrSignatureMissing = errors.New("url is not signed")
+	ErrSignatureInvalid = errors.New("url signature is not valid")
+	ErrExpired          = errors.New("signed url has expired")
+	ErrOutOfScope       = errors.New("signed url does not cover the requested path")
+	ErrAddressMismatch  = errors.New("signed url was issued to a different address")
+)
+
+// Query parameters appended to signed URLs.
+const (
+	ExpiresParam   = "exp"
+	ScopeParam     = "scope"
+	IPParam        = "ip"
+	SignatureParam = "sig"
+)
+
+// Options controls what a signature covers.
+type Options struct {
+	// Expires is the time after which the signature is rejected.
+	Expires time.Time
+
+	// IP binds the signature to a single client address when set.
+	IP string
+
+	// PathPrefix scopes the signature to every path beneath the prefix, allowing
+	// a single signature to cover a playlist and its segments. When empty the
+	// signature only covers the exact path signed.
+	PathPrefix string
+}
+
+type Signer struct {
+	secret []byte
+	now    func() time.Time
+
+	// canonical returns the form of a path compared against what was signed.
+	canonical func(path string) string
+}
+
+// payload builds the string covered by the signature.
+func payload(scope string, expires int64, ip string) []byte {
+	return []byte(strings.Join([]string{scope, strconv.FormatInt(expires, 10), ip}, "\n"))
+}
+
+func (s Signer) mac(scope string, expires int64, ip string) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write(payload(scope, expires, ip))
+
+	return mac.Sum(nil)
+}
+
+// Query returns the signature parameters for the given path. The returned values
+// can be appended to any URL within the signature scope.
+func (s Signer) Query(path string, options Options) url.Values {
+	scope := s.canonical(path)
+	if options.PathPrefix != "" {
+		scope = options.PathPrefix
+	}
+
+	expires := options.Expires.Unix()
+
+	values := url.Values{}
+	values.Set(ExpiresParam, strconv.FormatInt(expires, 10))
+	values.Set(SignatureParam, base64.RawURLEncoding.EncodeToString(s.mac(scope, expires, options.IP)))
+
+	if options.PathPrefix != "" {
+		values.Set(ScopeParam, options.PathPrefix)
+	}
+
+	if options.IP != "" {
+		values.Set(IPParam, options.IP)
+	}
+
+	return values
+}
+
+// Sign returns a copy of u carrying a signature for its path.
+func (s Signer) Sign(u *url.URL, options Options) *url.URL {
+	signed := *u
+
+	query := signed.Query()
+	for key, values := range s.Query(u.Path, options) {
+		query[key] = values
+	}
+	signed.RawQuery = query.Encode()
+
+	return &signed
+}
+
+// Verify checks the signature carried by u is valid for its path, has not
+// expired, and was issued to remoteIP if bound to an address. The path is
+// compared in its canonical form, so that it cannot climb out of the scope
+// signed with .. elements.
+func (s Signer) Verify(u *url.URL, remoteIP string) error {
+	query := u.Query()
+
+	signature := query.Get(SignatureParam)
+	if signature == "" {
+		return ErrSignatureMissing
+	}
+
+	expires, err := strconv.ParseInt(query.Get(ExpiresParam), 10, 64)
+	if err != nil {
+		return ErrSignatureInvalid
+	}
+
+	requested := s.canonical(u.Path)
+
+	scope := requested
+	if prefix := query.Get(ScopeParam); prefix != "" {
+		if !strings.HasPrefix(requested, prefix) {
+			return ErrOutOfScope
+		}
+
+		scope = prefix
+	}
+
+	ip := query.Get(IPParam)
+
+	decoded, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(decoded, s.mac(scope, expires, ip)) {
+		return ErrSignatureInvalid
+	}
+
+	if s.now().Unix() > expires {
+		return ErrExpired
+	}
+
+	if ip != "" && ip != remoteIP {
+		return ErrAddressMismatch
+	}
+
+	return nil
+}
+
+// RemoteIP returns the client address of the request without its port.
+func RemoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// Middleware rejects requests without a valid signature with 403 Forbidden.
+func (s Signer) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := s.Verify(r.URL, RemoteIP(r)); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// WithCanonicalPath returns a copy of the signer comparing paths in the form returned
+// by canonical, for servers matching parts of their paths regardless of case. Paths
+// are cleaned before they are passed to it.
+func (s Signer) WithCanonicalPath(canonical func(path string) string) Signer {
+	s.canonical = func(p string) string {
+		return canonical(path.Clean(p))
+	}
+
+	return s
+}
+
+// NewSigner instantiates a Signer using the given secret.
+func NewSigner(secret string) Signer {
+	return Signer{
+		secret:    []byte(secret),
+		now:       time.Now,
+		canonical: path.Clean,
+	}
+}
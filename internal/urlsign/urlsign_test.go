@@ -0,0 +1,221 @@
+package urlsign
+
+import (
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestSigner_Verify(t *testing.T) {
+	signer := NewSigner("testSecret")
+	otherSigner := NewSigner("otherSecret")
+
+	expires := time.Now().Add(time.Minute)
+
+	mustParse := func(raw string) *url.URL {
+		u, err := url.Parse(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return u
+	}
+
+	// tamper applies fn to the query of u, returning the modified URL.
+	tamper := func(u *url.URL, fn func(values url.Values)) *url.URL {
+		query := u.Query()
+		fn(query)
+		u.RawQuery = query.Encode()
+
+		return u
+	}
+
+	tests := []struct {
+		testName      string
+		url           *url.URL
+		remoteIP      string
+		expectedError error
+	}{
+		{
+			testName:      "expect success with exact path signature",
+			url:           signer.Sign(mustParse("/vod/abc/video.mp4"), Options{Expires: expires}),
+			remoteIP:      "10.0.0.1",
+			expectedError: nil,
+		},
+		{
+			testName:      "expect error without signature",
+			url:           mustParse("/vod/abc/video.mp4"),
+			expectedError: ErrSignatureMissing,
+		},
+		{
+			testName: "expect error with exact path signature on another path",
+			url: func() *url.URL {
+				u := signer.Sign(mustParse("/vod/abc/video.mp4"), Options{Expires: expires})
+				u.Path = "/vod/def/video.mp4"
+				return u
+			}(),
+			expectedError: ErrSignatureInvalid,
+		},
+		{
+			testName: "expect success with prefix signature beneath the prefix",
+			url: func() *url.URL {
+				u := signer.Sign(mustParse("/live/channel/index.m3u8"), Options{Expires: expires, PathPrefix: "/live/channel/"})
+				u.Path = "/live/channel/segment3.ts"
+				return u
+			}(),
+			expectedError: nil,
+		},
+		{
+			testName: "expect error with prefix signature outside the prefix",
+			url: func() *url.URL {
+				u := signer.Sign(mustParse("/live/channel/index.m3u8"), Options{Expires: expires, PathPrefix: "/live/channel/"})
+				u.Path = "/live/other/index.m3u8"
+				return u
+			}(),
+			expectedError: ErrOutOfScope,
+		},
+		{
+			testName: "expect error with prefix signature climbing out of the prefix",
+			url: func() *url.URL {
+				u := signer.Sign(mustParse("/live/channel/index.m3u8"), Options{Expires: expires, PathPrefix: "/live/channel/"})
+				u.Path = "/live/channel/../other/index.m3u8"
+				return u
+			}(),
+			expectedError: ErrOutOfScope,
+		},
+		{
+			testName: "expect success with exact path signature on an uncleaned path",
+			url: func() *url.URL {
+				u := signer.Sign(mustParse("/vod/abc/video.mp4"), Options{Expires: expires})
+				u.Path = "/vod/abc/./video.mp4"
+				return u
+			}(),
+			expectedError: nil,
+		},
+		{
+			testName: "expect error with widened prefix",
+			url: tamper(
+				signer.Sign(mustParse("/live/channel/index.m3u8"), Options{Expires: expires, PathPrefix: "/live/channel/"}),
+				func(values url.Values) { values.Set(ScopeParam, "/live/") },
+			),
+			expectedError: ErrSignatureInvalid,
+		},
+		{
+			testName: "expect error with extended expiry",
+			url: tamper(
+				signer.Sign(mustParse("/vod/abc/video.mp4"), Options{Expires: expires}),
+				func(values url.Values) { values.Set(ExpiresParam, "99999999999") },
+			),
+			expectedError: ErrSignatureInvalid,
+		},
+		{
+			testName:      "expect error when expired",
+			url:           signer.Sign(mustParse("/vod/abc/video.mp4"), Options{Expires: time.Now().Add(-time.Minute)}),
+			expectedError: ErrExpired,
+		},
+		{
+			testName:      "expect error when signed with another secret",
+			url:           otherSigner.Sign(mustParse("/vod/abc/video.mp4"), Options{Expires: expires}),
+			expectedError: ErrSignatureInvalid,
+		},
+		{
+			testName:      "expect success from bound address",
+			url:           signer.Sign(mustParse("/vod/abc/video.mp4"), Options{Expires: expires, IP: "10.0.0.1"}),
+			remoteIP:      "10.0.0.1",
+			expectedError: nil,
+		},
+		{
+			testName:      "expect error from another address",
+			url:           signer.Sign(mustParse("/vod/abc/video.mp4"), Options{Expires: expires, IP: "10.0.0.1"}),
+			remoteIP:      "10.0.0.2",
+			expectedError: ErrAddressMismatch,
+		},
+		{
+			testName: "expect error with removed address binding",
+			url: tamper(
+				signer.Sign(mustParse("/vod/abc/video.mp4"), Options{Expires: expires, IP: "10.0.0.1"}),
+				func(values url.Values) { values.Del(IPParam) },
+			),
+			remoteIP:      "10.0.0.2",
+			expectedError: ErrSignatureInvalid,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			err := signer.Verify(test.url, test.remoteIP)
+
+			if !cmp.Equal(err, test.expectedError, cmpopts.EquateErrors()) {
+				t.Fatal(cmp.Diff(err, test.expectedError, cmpopts.EquateErrors()))
+			}
+		})
+	}
+}
+
+func TestSigner_Sign_PreservesQuery(t *testing.T) {
+	signer := NewSigner("testSecret")
+
+	u, err := url.Parse("/vod/abc/video.mp4?start=10")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed := signer.Sign(u, Options{Expires: time.Now().Add(time.Minute)})
+
+	if signed.Query().Get("start") != "10" {
+		t.Fatal("expected existing query parameters to be kept")
+	}
+
+	if u.RawQuery != "start=10" {
+		t.Fatal("expected original url to be left unmodified")
+	}
+}
+
+func TestSigner_Middleware(t *testing.T) {
+	signer := NewSigner("testSecret")
+	handler := signer.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	u, err := url.Parse("/vod/abc/video.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		testName       string
+		target         string
+		expectedStatus int
+	}{
+		{
+			testName:       "expect 200 with valid signature",
+			target:         signer.Sign(u, Options{Expires: time.Now().Add(time.Minute)}).String(),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			testName:       "expect 403 without signature",
+			target:         u.String(),
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			testName:       "expect 403 with expired signature",
+			target:         signer.Sign(u, Options{Expires: time.Now().Add(-time.Minute)}).String(),
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, test.target, nil))
+
+			if !cmp.Equal(recorder.Code, test.expectedStatus) {
+				t.Fatal(cmp.Diff(recorder.Code, test.expectedStatus))
+			}
+		})
+	}
+}
@@ -14,7 +14,7 @@ var (
 
 type StorageProvider interface {
 	All(ctx context.Context) ([]storage.User, error)
-	List(ctx context.Context, options paginate.QueryOptions) ([]storage.User, error)
+	List(ctx context.Context, options paginate.QueryOptions) ([]storage.User, paginate.Page, error)
 	GetByID(ctx context.Context, id uint64) *storage.User
 	GetByUsername(ctx context.Context, username string) *storage.User
 	GetByEmail(ctx context.Context, email string) *storage.User
@@ -37,14 +37,15 @@ func (r Repository) All(ctx context.Context) ([]user.User, error) {
 	return storage.UsersToDomain(users), nil
 }
 
-// List returns a set of users specified by the provided QueryOptions.
-func (r Repository) List(ctx context.Context, options paginate.QueryOptions) ([]user.User, error) {
-	users, err := r.StorageProvider.List(ctx, options)
+// List returns a set of users specified by the provided QueryOptions,
+// with the cursors of the pages either side.
+func (r Repository) List(ctx context.Context, options paginate.QueryOptions) ([]user.User, paginate.Page, error) {
+	users, page, err := r.StorageProvider.List(ctx, options)
 	if err != nil {
-		return []user.User{}, err
+		return []user.User{}, paginate.Page{}, err
 	}
 
-	return storage.UsersToDomain(users), nil
+	return storage.UsersToDomain(users), page, nil
 }
 
 // GetByID returns the user with the given ID, or returns an error.
@@ -90,6 +90,7 @@ func TestRepository_List(t *testing.T) {
 		paginateOptions paginate.QueryOptions
 		expectedError   error
 		expectedValue   []user.User
+		expectedPage    paginate.Page
 	}{
 		{
 			testName: "expect empty array with empty storage",
@@ -114,6 +115,7 @@ func TestRepository_List(t *testing.T) {
 		{
 			testName: "expect correct output",
 			mockStorage: mockUserStorage{
+				ReturnListPage:  paginate.Page{Next: "next", Prev: "prev"},
 				ReturnListError: nil,
 				ReturnListUsers: []storage.User{
 					{
@@ -140,6 +142,7 @@ func TestRepository_List(t *testing.T) {
 					Id: 3,
 				},
 			},
+			expectedPage: paginate.Page{Next: "next", Prev: "prev"},
 		},
 	}
 
@@ -151,7 +154,7 @@ func TestRepository_List(t *testing.T) {
 				StorageProvider: test.mockStorage,
 			}
 
-			users, err := r.List(ctx, test.paginateOptions)
+			users, page, err := r.List(ctx, test.paginateOptions)
 
 			if !cmp.Equal(err, test.expectedError, cmpopts.EquateErrors()) {
 				t.Fatal(cmp.Diff(err, test.expectedError, cmpopts.EquateErrors()))
@@ -160,6 +163,10 @@ func TestRepository_List(t *testing.T) {
 			if !cmp.Equal(users, test.expectedValue, cmpopts.EquateApproxTime(time.Minute)) {
 				t.Fatal(cmp.Diff(users, test.expectedValue, cmpopts.EquateApproxTime(time.Minute)))
 			}
+
+			if !cmp.Equal(page, test.expectedPage) {
+				t.Fatal(cmp.Diff(page, test.expectedPage))
+			}
 		})
 	}
 }
@@ -512,6 +519,7 @@ type mockUserStorage struct {
 	ReturnAllUsers          []storage.User
 	ReturnAllError          error
 	ReturnListUsers         []storage.User
+	ReturnListPage          paginate.Page
 	ReturnListError         error
 	ReturnGetByIDUser       *storage.User
 	ReturnGetByUsernameUser *storage.User
@@ -527,8 +535,8 @@ func (m mockUserStorage) All(_ context.Context) ([]storage.User, error) {
 	return m.ReturnAllUsers, m.ReturnAllError
 }
 
-func (m mockUserStorage) List(_ context.Context, _ paginate.QueryOptions) ([]storage.User, error) {
-	return m.ReturnListUsers, m.ReturnListError
+func (m mockUserStorage) List(_ context.Context, _ paginate.QueryOptions) ([]storage.User, paginate.Page, error) {
+	return m.ReturnListUsers, m.ReturnListPage, m.ReturnListError
 }
 
 func (m mockUserStorage) GetByID(_ context.Context, _ uint64) *storage.User {
@@ -0,0 +1,130 @@
+package video
+
+import (
+	"context"
+	"errors"
+	"github.com/M-Ro/go-vodstream/internal/domain/video"
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrVideoNotFound = errors.New("no video found")
+)
+
+type StorageProvider interface {
+	All(ctx context.Context) ([]storage.Video, error)
+	List(ctx context.Context, options paginate.QueryOptions) ([]storage.Video, paginate.Page, error)
+	ListByBroadcaster(
+		ctx context.Context, broadcasterId uint64, options paginate.QueryOptions,
+	) ([]storage.Video, paginate.Page, error)
+	ListPublishedByBroadcaster(
+		ctx context.Context, broadcasterId uint64, options paginate.QueryOptions,
+	) ([]storage.Video, paginate.Page, error)
+	GetByID(ctx context.Context, id uuid.UUID) *storage.Video
+	Delete(ctx context.Context, id uuid.UUID) error
+	Insert(ctx context.Context, video *storage.Video) error
+	Update(ctx context.Context, id uuid.UUID, video *storage.Video) error
+}
+
+type Repository struct {
+	StorageProvider StorageProvider
+}
+
+// All returns all videos in the repository.
+func (r Repository) All(ctx context.Context) ([]video.Video, error) {
+	videos, err := r.StorageProvider.All(ctx)
+	if err != nil {
+		return []video.Video{}, err
+	}
+
+	return storage.VideosToDomain(videos), nil
+}
+
+// List returns a set of videos specified by the provided QueryOptions,
+// with the cursors of the pages either side.
+func (r Repository) List(ctx context.Context, options paginate.QueryOptions) ([]video.Video, paginate.Page, error) {
+	videos, page, err := r.StorageProvider.List(ctx, options)
+	if err != nil {
+		return []video.Video{}, paginate.Page{}, err
+	}
+
+	return storage.VideosToDomain(videos), page, nil
+}
+
+// ListByBroadcaster returns a set of the broadcaster's videos, published or not,
+// specified by the provided QueryOptions, with the cursors of the pages either side.
+func (r Repository) ListByBroadcaster(
+	ctx context.Context, broadcasterId uint64, options paginate.QueryOptions,
+) ([]video.Video, paginate.Page, error) {
+	videos, page, err := r.StorageProvider.ListByBroadcaster(ctx, broadcasterId, options)
+	if err != nil {
+		return []video.Video{}, paginate.Page{}, err
+	}
+
+	return storage.VideosToDomain(videos), page, nil
+}
+
+// ListPublishedByBroadcaster returns a set of the broadcaster's published videos,
+// specified by the provided QueryOptions, with the cursors of the pages either side.
+func (r Repository) ListPublishedByBroadcaster(
+	ctx context.Context, broadcasterId uint64, options paginate.QueryOptions,
+) ([]video.Video, paginate.Page, error) {
+	videos, page, err := r.StorageProvider.ListPublishedByBroadcaster(ctx, broadcasterId, options)
+	if err != nil {
+		return []video.Video{}, paginate.Page{}, err
+	}
+
+	return storage.VideosToDomain(videos), page, nil
+}
+
+// GetByID returns the video with the given ID, or returns an error.
+func (r Repository) GetByID(ctx context.Context, id uuid.UUID) (video.Video, error) {
+	getVideo := r.StorageProvider.GetByID(ctx, id)
+	if getVideo == nil {
+		return video.Video{}, ErrVideoNotFound
+	}
+
+	return storage.VideoToDomain(*getVideo), nil
+}
+
+// Delete removes a video with the given ID. Returns an error on failure.
+func (r Repository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.StorageProvider.Delete(ctx, id)
+}
+
+// Insert takes a domain model and inserts it to the storage provider.
+// After successful insertion the video ID and timestamps are filled.
+func (r Repository) Insert(ctx context.Context, newVideo *video.Video) error {
+	storageVideo := storage.VideoToStorage(*newVideo)
+
+	err := r.StorageProvider.Insert(ctx, &storageVideo)
+	if err != nil {
+		return err
+	}
+
+	newVideo.Id = storageVideo.Id
+	newVideo.CreatedAt = storageVideo.CreatedAt
+	newVideo.UpdatedAt = storageVideo.UpdatedAt
+
+	return nil
+}
+
+// Update takes a video and updates the record within the StorageProvider.
+func (r Repository) Update(ctx context.Context, id uuid.UUID, updateVideo video.Video) (video.Video, error) {
+	storageVideo := storage.VideoToStorage(updateVideo)
+
+	err := r.StorageProvider.Update(ctx, id, &storageVideo)
+	if err != nil {
+		return video.Video{}, err
+	}
+
+	return storage.VideoToDomain(storageVideo), nil
+}
+
+func NewRepository(s StorageProvider) Repository {
+	return Repository{
+		StorageProvider: s,
+	}
+}
@@ -0,0 +1,166 @@
+package video
+
+import (
+	"context"
+	"github.com/M-Ro/go-vodstream/internal/domain/video"
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"testing"
+	"time"
+)
+
+// mockVideoStorage stores videos in memory, as the SQL storage would.
+type mockVideoStorage map[uuid.UUID]storage.Video
+
+func (m mockVideoStorage) All(_ context.Context) ([]storage.Video, error) {
+	videos := make([]storage.Video, 0, len(m))
+	for _, v := range m {
+		videos = append(videos, v)
+	}
+
+	return videos, nil
+}
+
+func (m mockVideoStorage) List(ctx context.Context, _ paginate.QueryOptions) ([]storage.Video, paginate.Page, error) {
+	videos, err := m.All(ctx)
+	return videos, paginate.Page{}, err
+}
+
+func (m mockVideoStorage) ListByBroadcaster(
+	_ context.Context, broadcasterId uint64, _ paginate.QueryOptions,
+) ([]storage.Video, paginate.Page, error) {
+	videos := make([]storage.Video, 0)
+	for _, v := range m {
+		if v.BroadcasterId == broadcasterId {
+			videos = append(videos, v)
+		}
+	}
+
+	return videos, paginate.Page{}, nil
+}
+
+func (m mockVideoStorage) ListPublishedByBroadcaster(
+	_ context.Context, broadcasterId uint64, _ paginate.QueryOptions,
+) ([]storage.Video, paginate.Page, error) {
+	videos := make([]storage.Video, 0)
+	for _, v := range m {
+		if v.BroadcasterId == broadcasterId && v.IsPublished {
+			videos = append(videos, v)
+		}
+	}
+
+	return videos, paginate.Page{}, nil
+}
+
+func (m mockVideoStorage) GetByID(_ context.Context, id uuid.UUID) *storage.Video {
+	v, ok := m[id]
+	if !ok {
+		return nil
+	}
+
+	return &v
+}
+
+func (m mockVideoStorage) Delete(_ context.Context, id uuid.UUID) error {
+	delete(m, id)
+	return nil
+}
+
+func (m mockVideoStorage) Insert(_ context.Context, v *storage.Video) error {
+	v.Id = uuid.New()
+	v.CreatedAt = time.Now()
+	v.UpdatedAt = v.CreatedAt
+	m[v.Id] = *v
+
+	return nil
+}
+
+func (m mockVideoStorage) Update(_ context.Context, id uuid.UUID, v *storage.Video) error {
+	v.UpdatedAt = time.Now()
+	m[id] = *v
+
+	return nil
+}
+
+func TestRepository_Insert(t *testing.T) {
+	ctx := context.Background()
+	r := NewRepository(mockVideoStorage{})
+
+	publishedAt := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	inserted := video.Video{
+		Title:         "test",
+		BroadcasterId: 1,
+		Length:        1500 * time.Millisecond,
+		IsPublished:   true,
+		PublishedAt:   publishedAt,
+		FilePath:      "test.flv",
+	}
+
+	if err := r.Insert(ctx, &inserted); err != nil {
+		t.Fatal(err)
+	}
+
+	if inserted.Id == uuid.Nil || inserted.CreatedAt.IsZero() {
+		t.Fatalf("expected the id and creation time filled, got %+v", inserted)
+	}
+
+	got, err := r.GetByID(ctx, inserted.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !cmp.Equal(got, inserted) {
+		t.Fatal(cmp.Diff(got, inserted))
+	}
+
+	if _, err := r.GetByID(ctx, uuid.New()); err != ErrVideoNotFound {
+		t.Fatal(cmp.Diff(err, ErrVideoNotFound))
+	}
+}
+
+func TestRepository_ListPublishedByBroadcaster(t *testing.T) {
+	ctx := context.Background()
+	r := NewRepository(mockVideoStorage{})
+
+	for _, v := range []video.Video{
+		{Title: "published", BroadcasterId: 1, IsPublished: true},
+		{Title: "unpublished", BroadcasterId: 1},
+		{Title: "other broadcaster", BroadcasterId: 2, IsPublished: true},
+	} {
+		if err := r.Insert(ctx, &v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	videos, _, err := r.ListPublishedByBroadcaster(ctx, 1, paginate.NewPaginateOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(videos) != 1 || videos[0].Title != "published" {
+		t.Fatalf("expected only the published video, got %+v", videos)
+	}
+
+	unpublished := videos[0]
+	unpublished.Unpublish()
+
+	updated, err := r.Update(ctx, unpublished.Id, unpublished)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if updated.IsPublished || updated.PublishedAt != videos[0].PublishedAt {
+		t.Fatalf("expected the video unpublished, keeping when it was published, got %+v", updated)
+	}
+
+	all, _, err := r.ListByBroadcaster(ctx, 1, paginate.NewPaginateOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(all) != 2 {
+		t.Fatalf("expected both videos of the broadcaster, got %+v", all)
+	}
+}
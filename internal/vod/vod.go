@@ -0,0 +1,228 @@
+// Package vod packages recorded media into CMAF segments with a static DASH
+// manifest and fMP4 HLS playlists, stored as plain objects in the media store.
+package vod
+
+import (
+	"bytes"
+	"context"
+	"github.com/M-Ro/go-vodstream/internal/blob"
+	"github.com/M-Ro/go-vodstream/internal/dash"
+	"github.com/M-Ro/go-vodstream/internal/fmp4"
+	"github.com/M-Ro/go-vodstream/internal/hls"
+	"github.com/nareix/joy4/av"
+	"github.com/spf13/viper"
+	"io"
+	"path"
+	"time"
+)
+
+// Files written alongside the init segments and fragments of each track.
+const (
+	ManifestFile     = "manifest.mpd"
+	MultivariantFile = "master.m3u8"
+)
+
+type Config struct {
+	// SegmentDuration is the minimum length of a segment. Segments are cut on
+	// the first video keyframe after it, so may run longer.
+	SegmentDuration time.Duration
+}
+
+// packager cuts every track into fragments at the same boundaries, as the live
+// HLS packager does.
+type packager struct {
+	ctx         context.Context
+	config      Config
+	media       blob.Store
+	prefix      string
+	tracks      []fmp4.Track
+	fragmenters []*fmp4.Fragmenter
+	video       int
+
+	opened   bool
+	start    time.Duration
+	lastTime time.Duration
+
+	// frame is the last known spacing of packets, used as the length of the
+	// final one.
+	frame time.Duration
+
+	segments []dash.Segment
+
+	// bandwidth is the peak bitrate of each track over its segments.
+	bandwidth []int
+}
+
+// keyFrame returns true if a segment may start at the packet.
+func (p *packager) keyFrame(packet av.Packet) bool {
+	return p.video == -1 || (int(packet.Idx) == p.video && packet.IsKeyFrame)
+}
+
+// writeFile writes an object beneath the output prefix.
+func (p *packager) writeFile(name string, data []byte) error {
+	return p.media.Put(p.ctx, path.Join(p.prefix, name), bytes.NewReader(data))
+}
+
+// cut writes the fragments of the segment ending at end.
+func (p *packager) cut(end time.Duration) error {
+	segment := dash.Segment{Sequence: len(p.segments), Start: p.start, Duration: end - p.start}
+
+	for idx, fragmenter := range p.fragmenters {
+		// Fragment sequence numbers start from one.
+		fragment := fragmenter.Flush(uint32(segment.Sequence + 1))
+		if fragment == nil {
+			// Players expect every track to have every segment.
+			fragment = []byte{}
+		}
+
+		if err := p.writeFile(fmp4.SegmentURI(segment.Sequence, idx), fragment); err != nil {
+			return err
+		}
+
+		if segment.Duration > 0 {
+			if rate := int(float64(8*len(fragment)) / segment.Duration.Seconds()); rate > p.bandwidth[idx] {
+				p.bandwidth[idx] = rate
+			}
+		}
+	}
+
+	p.segments = append(p.segments, segment)
+	p.start = end
+
+	return nil
+}
+
+// writePacket adds the packet to its track, first cutting a segment if it is a
+// keyframe and the segment is long enough. Packets before the first keyframe
+// are dropped as they cannot be decoded.
+func (p *packager) writePacket(packet av.Packet) error {
+	if !p.opened {
+		if !p.keyFrame(packet) {
+			return nil
+		}
+
+		p.opened = true
+		p.start = packet.Time
+	} else if p.keyFrame(packet) && packet.Time-p.start >= p.config.SegmentDuration {
+		if err := p.cut(packet.Time); err != nil {
+			return err
+		}
+	}
+
+	if frame := packet.Time - p.lastTime; frame > 0 {
+		p.frame = frame
+	}
+	if packet.Time > p.lastTime {
+		p.lastTime = packet.Time
+	}
+
+	p.fragmenters[packet.Idx].WritePacket(packet)
+
+	return nil
+}
+
+// writeManifests writes the DASH manifest and HLS playlists over the segments.
+func (p *packager) writeManifests() error {
+	manifest := dash.Manifest{Segments: p.segments, MinBufferTime: p.config.SegmentDuration}
+	renditions := make([]hls.Rendition, 0, len(p.tracks))
+	playlist := hls.TrackPlaylist{VOD: true}
+
+	for _, segment := range p.segments {
+		playlist.Segments = append(playlist.Segments, hls.TrackSegment{
+			Sequence: segment.Sequence,
+			Duration: segment.Duration,
+		})
+	}
+
+	for idx, track := range p.tracks {
+		manifest.Representations = append(manifest.Representations, dash.Representation{
+			Index:     idx,
+			Track:     track,
+			Bandwidth: p.bandwidth[idx],
+		})
+		renditions = append(renditions, hls.Rendition{Index: idx, Track: track, Bandwidth: p.bandwidth[idx]})
+
+		if err := p.writeFile(fmp4.InitURI(idx), track.InitSegment()); err != nil {
+			return err
+		}
+
+		playlist.Track = idx
+		if err := p.writeFile(hls.TrackURI(idx), playlist.Marshal()); err != nil {
+			return err
+		}
+	}
+
+	if err := p.writeFile(MultivariantFile, hls.Multivariant(renditions, "")); err != nil {
+		return err
+	}
+
+	mpd, err := manifest.Marshal()
+	if err != nil {
+		return err
+	}
+
+	return p.writeFile(ManifestFile, mpd)
+}
+
+// Package reads the demuxer to the end, writing its streams beneath prefix in
+// media as CMAF tracks with a static DASH manifest and fMP4 HLS playlists
+// sharing the same segments. Streams must be H.264 or AAC, otherwise
+// fmp4.ErrUnsupportedCodec is returned.
+func Package(ctx context.Context, demuxer av.Demuxer, media blob.Store, prefix string, config Config) error {
+	streams, err := demuxer.Streams()
+	if err != nil {
+		return err
+	}
+
+	p := &packager{
+		ctx:       ctx,
+		config:    config,
+		media:     media,
+		prefix:    prefix,
+		video:     -1,
+		bandwidth: make([]int, len(streams)),
+	}
+
+	for idx, stream := range streams {
+		track, err := fmp4.NewTrack(stream)
+		if err != nil {
+			return err
+		}
+
+		if p.video == -1 && track.IsVideo() {
+			p.video = idx
+		}
+
+		p.tracks = append(p.tracks, track)
+		p.fragmenters = append(p.fragmenters, fmp4.NewFragmenter(track))
+	}
+
+	for {
+		packet, err := demuxer.ReadPacket()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		if err := p.writePacket(packet); err != nil {
+			return err
+		}
+	}
+
+	if p.opened {
+		if err := p.cut(p.lastTime + p.frame); err != nil {
+			return err
+		}
+	}
+
+	return p.writeManifests()
+}
+
+func GetConfig() Config {
+	viper.SetDefault("vod.segment_duration", "4s")
+
+	return Config{
+		SegmentDuration: viper.GetDuration("vod.segment_duration"),
+	}
+}
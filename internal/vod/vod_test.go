@@ -0,0 +1,106 @@
+package vod
+
+import (
+	"context"
+	"github.com/M-Ro/go-vodstream/internal/avtest"
+	"github.com/M-Ro/go-vodstream/internal/blob"
+	"github.com/M-Ro/go-vodstream/internal/fmp4"
+	"github.com/google/go-cmp/cmp"
+	"github.com/nareix/joy4/av"
+	"github.com/spf13/afero"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPackage(t *testing.T) {
+	media := blob.NewLocalStore(afero.NewMemMapFs())
+
+	// Ten seconds of packets with a keyframe every second, starting mid GOP.
+	packets := avtest.Packets(260, 25)[20:]
+	config := Config{SegmentDuration: 4 * time.Second}
+
+	if err := Package(context.Background(), avtest.NewDemuxer(avtest.Streams(), packets), media, "vod", config); err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(name string) string {
+		reader, err := media.Get(context.Background(), "vod/"+name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer reader.Close()
+
+		data, err := ioutil.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	// Packets before the keyframe at 1s are dropped, then segments are cut at
+	// the keyframes at 5s and 9s.
+	playlist := read("track0.m3u8")
+	want := strings.Join([]string{
+		"#EXTM3U",
+		"#EXT-X-VERSION:7",
+		"#EXT-X-TARGETDURATION:4",
+		"#EXT-X-MEDIA-SEQUENCE:0",
+		"#EXT-X-PLAYLIST-TYPE:VOD",
+		"#EXT-X-INDEPENDENT-SEGMENTS",
+		`#EXT-X-MAP:URI="init0.mp4"`,
+		"#EXTINF:4.000,",
+		"seg0.0.m4s",
+		"#EXTINF:4.000,",
+		"seg1.0.m4s",
+		"#EXTINF:1.400,",
+		"seg2.0.m4s",
+		"#EXT-X-ENDLIST",
+		"",
+	}, "\n")
+
+	if diff := cmp.Diff(playlist, want); diff != "" {
+		t.Fatal(diff)
+	}
+
+	manifest := read(ManifestFile)
+	for _, tag := range []string{`type="static"`, `mediaPresentationDuration="PT9.400S"`, `<S t="1000" d="4000">`} {
+		if !strings.Contains(manifest, tag) {
+			t.Fatalf("expected %s in manifest:\n%s", tag, manifest)
+		}
+	}
+
+	if multivariant := read(MultivariantFile); !strings.Contains(multivariant, "track1.m3u8") {
+		t.Fatalf("expected audio track in multivariant playlist:\n%s", multivariant)
+	}
+
+	for track := 0; track < 2; track++ {
+		if init := read(fmp4.InitURI(track)); !strings.HasPrefix(init[4:], "ftyp") {
+			t.Fatalf("expected track %d init segment to start with ftyp", track)
+		}
+
+		for sequence := 0; sequence < 3; sequence++ {
+			if fragment := read(fmp4.SegmentURI(sequence, track)); !strings.HasPrefix(fragment[4:], "moof") {
+				t.Fatalf("expected segment %d of track %d to start with moof", sequence, track)
+			}
+		}
+	}
+}
+
+func TestPackage_UnsupportedCodec(t *testing.T) {
+	streams := append(avtest.Streams(), unsupported{})
+
+	media := blob.NewLocalStore(afero.NewMemMapFs())
+
+	err := Package(context.Background(), avtest.NewDemuxer(streams, nil), media, "vod", Config{SegmentDuration: time.Second})
+	if err != fmp4.ErrUnsupportedCodec {
+		t.Fatal(cmp.Diff(err, fmp4.ErrUnsupportedCodec))
+	}
+}
+
+type unsupported struct{}
+
+func (unsupported) Type() av.CodecType {
+	return av.SPEEX
+}
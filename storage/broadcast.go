@@ -1,7 +1,9 @@
 package storage
 
 import (
+	"github.com/M-Ro/go-vodstream/internal/domain/broadcast"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"time"
 )
 
@@ -13,10 +15,76 @@ type Broadcast struct {
 
 	Title string `db:"title"`
 
+	// Metadata inherited from the broadcaster's channel as the broadcast started
+	Category string         `db:"category"`
+	Tags     pq.StringArray `db:"tags"`
+	Language string         `db:"language"`
+	Mature   bool           `db:"mature"`
+
 	IsActive    bool `db:"is_active"`
 	IsPublished bool `db:"is_published"`
 
+	ViewerCount int64 `db:"viewer_count"`
+
+	// Generated by the database for full-text search, never written
+	SearchVector string `db:"search_vector"`
+
 	PublishedAt time.Time `db:"published_at"`
 	CreatedAt   time.Time `db:"created_at"`
 	UpdatedAt   time.Time `db:"updated_at"`
 }
+
+// BroadcastsToDomain converts storage broadcast models to domain models.
+func BroadcastsToDomain(broadcasts []Broadcast) []broadcast.Broadcast {
+	convertedBroadcasts := make([]broadcast.Broadcast, len(broadcasts))
+
+	for i, b := range broadcasts {
+		convertedBroadcasts[i] = BroadcastToDomain(b)
+	}
+
+	return convertedBroadcasts
+}
+
+// BroadcastToDomain converts a storage broadcast model to a domain model.
+func BroadcastToDomain(b Broadcast) broadcast.Broadcast {
+	tags := make([]string, len(b.Tags))
+	copy(tags, b.Tags)
+
+	return broadcast.Broadcast{
+		Id:            b.Id,
+		BroadcasterId: b.BroadcasterId,
+		Title:         b.Title,
+		Category:      b.Category,
+		Tags:          tags,
+		Language:      b.Language,
+		Mature:        b.Mature,
+		IsActive:      b.IsActive,
+		IsPublished:   b.IsPublished,
+		ViewerCount:   b.ViewerCount,
+		PublishedAt:   b.PublishedAt,
+		CreatedAt:     b.CreatedAt,
+		UpdatedAt:     b.UpdatedAt,
+	}
+}
+
+// BroadcastToStorage converts a domain broadcast model to a storage model.
+func BroadcastToStorage(b broadcast.Broadcast) Broadcast {
+	tags := make(pq.StringArray, len(b.Tags))
+	copy(tags, b.Tags)
+
+	return Broadcast{
+		Id:            b.Id,
+		BroadcasterId: b.BroadcasterId,
+		Title:         b.Title,
+		Category:      b.Category,
+		Tags:          tags,
+		Language:      b.Language,
+		Mature:        b.Mature,
+		IsActive:      b.IsActive,
+		IsPublished:   b.IsPublished,
+		ViewerCount:   b.ViewerCount,
+		PublishedAt:   b.PublishedAt,
+		CreatedAt:     b.CreatedAt,
+		UpdatedAt:     b.UpdatedAt,
+	}
+}
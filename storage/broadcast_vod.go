@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"github.com/M-Ro/go-vodstream/internal/domain/broadcast"
 	"github.com/google/uuid"
 	"time"
 )
@@ -8,7 +9,7 @@ import (
 type BroadcastVod struct {
 	Id uuid.UUID `db:"id"`
 
-	StreamId uuid.UUID `db:"stream_id"`
+	StreamId uuid.UUID `db:"stream_id"` // the broadcast the video was recorded from
 	VideoId  uuid.UUID `db:"video_id"`
 
 	PublishedAt time.Time `db:"published_at"`
@@ -16,3 +17,38 @@ type BroadcastVod struct {
 	CreatedAt time.Time `db:"created_at"`
 	UpdatedAt time.Time `db:"updated_at"`
 }
+
+// BroadcastVodsToDomain converts storage broadcast VOD models to domain models.
+func BroadcastVodsToDomain(broadcastVods []BroadcastVod) []broadcast.Vod {
+	convertedVods := make([]broadcast.Vod, len(broadcastVods))
+
+	for i, v := range broadcastVods {
+		convertedVods[i] = BroadcastVodToDomain(v)
+	}
+
+	return convertedVods
+}
+
+// BroadcastVodToDomain converts a storage broadcast VOD model to a domain model.
+func BroadcastVodToDomain(v BroadcastVod) broadcast.Vod {
+	return broadcast.Vod{
+		Id:          v.Id,
+		BroadcastId: v.StreamId,
+		VideoId:     v.VideoId,
+		PublishedAt: v.PublishedAt,
+		CreatedAt:   v.CreatedAt,
+		UpdatedAt:   v.UpdatedAt,
+	}
+}
+
+// BroadcastVodToStorage converts a domain broadcast VOD model to a storage model.
+func BroadcastVodToStorage(v broadcast.Vod) BroadcastVod {
+	return BroadcastVod{
+		Id:          v.Id,
+		StreamId:    v.BroadcastId,
+		VideoId:     v.VideoId,
+		PublishedAt: v.PublishedAt,
+		CreatedAt:   v.CreatedAt,
+		UpdatedAt:   v.UpdatedAt,
+	}
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"github.com/M-Ro/go-vodstream/internal/domain/category"
+	"time"
+)
+
+type Category struct {
+	Slug        string `db:"slug"`
+	Name        string `db:"name"`
+	Description string `db:"description"`
+	ImageURL    string `db:"image_url"`
+
+	// Aggregated from live broadcasts when browsing, not stored
+	Viewers    int64 `db:"viewers"`
+	Broadcasts int64 `db:"broadcasts"`
+
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// CategoriesToDomain converts storage category models to domain models.
+func CategoriesToDomain(categories []Category) []category.Category {
+	convertedCategories := make([]category.Category, len(categories))
+
+	for i, c := range categories {
+		convertedCategories[i] = CategoryToDomain(c)
+	}
+
+	return convertedCategories
+}
+
+// CategoryToDomain converts a storage category model to a domain model.
+func CategoryToDomain(c Category) category.Category {
+	return category.Category{
+		Slug:        c.Slug,
+		Name:        c.Name,
+		Description: c.Description,
+		ImageURL:    c.ImageURL,
+		Viewers:     c.Viewers,
+		Broadcasts:  c.Broadcasts,
+		CreatedAt:   c.CreatedAt,
+		UpdatedAt:   c.UpdatedAt,
+	}
+}
+
+// CategoryToStorage converts a domain category model to a storage model.
+func CategoryToStorage(c category.Category) Category {
+	return Category{
+		Slug:        c.Slug,
+		Name:        c.Name,
+		Description: c.Description,
+		ImageURL:    c.ImageURL,
+		Viewers:     c.Viewers,
+		Broadcasts:  c.Broadcasts,
+		CreatedAt:   c.CreatedAt,
+		UpdatedAt:   c.UpdatedAt,
+	}
+}
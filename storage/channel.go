@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"github.com/M-Ro/go-vodstream/internal/domain/channel"
+	"github.com/lib/pq"
+	"time"
+)
+
+type Channel struct {
+	// The user this channel belongs to
+	BroadcasterId uint64 `db:"broadcaster_id"`
+
+	DisplayName string `db:"display_name"`
+	Description string `db:"description"`
+
+	AvatarURL string `db:"avatar_url"`
+	BannerURL string `db:"banner_url"`
+
+	DefaultTitle string `db:"default_title"`
+
+	Category string         `db:"category"`
+	Tags     pq.StringArray `db:"tags"`
+	Language string         `db:"language"`
+	Mature   bool           `db:"mature"`
+
+	// Generated by the database for full-text search, never written
+	SearchVector string `db:"search_vector"`
+
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// ChannelsToDomain converts storage channel models to domain models.
+func ChannelsToDomain(channels []Channel) []channel.Channel {
+	convertedChannels := make([]channel.Channel, len(channels))
+
+	for i, c := range channels {
+		convertedChannels[i] = ChannelToDomain(c)
+	}
+
+	return convertedChannels
+}
+
+// ChannelToDomain converts a storage channel model to a domain model.
+func ChannelToDomain(c Channel) channel.Channel {
+	tags := make([]string, len(c.Tags))
+	copy(tags, c.Tags)
+
+	return channel.Channel{
+		BroadcasterId: c.BroadcasterId,
+		DisplayName:   c.DisplayName,
+		Description:   c.Description,
+		AvatarURL:     c.AvatarURL,
+		BannerURL:     c.BannerURL,
+		DefaultTitle:  c.DefaultTitle,
+		Category:      c.Category,
+		Tags:          tags,
+		Language:      c.Language,
+		Mature:        c.Mature,
+		CreatedAt:     c.CreatedAt,
+		UpdatedAt:     c.UpdatedAt,
+	}
+}
+
+// ChannelToStorage converts a domain channel model to a storage model.
+func ChannelToStorage(c channel.Channel) Channel {
+	tags := make(pq.StringArray, len(c.Tags))
+	copy(tags, c.Tags)
+
+	return Channel{
+		BroadcasterId: c.BroadcasterId,
+		DisplayName:   c.DisplayName,
+		Description:   c.Description,
+		AvatarURL:     c.AvatarURL,
+		BannerURL:     c.BannerURL,
+		DefaultTitle:  c.DefaultTitle,
+		Category:      c.Category,
+		Tags:          tags,
+		Language:      c.Language,
+		Mature:        c.Mature,
+		CreatedAt:     c.CreatedAt,
+		UpdatedAt:     c.UpdatedAt,
+	}
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"github.com/M-Ro/go-vodstream/internal/domain/clip"
+	"github.com/google/uuid"
+	"time"
+)
+
+type Clip struct {
+	Id      uuid.UUID `db:"id"`
+	VideoId uuid.UUID `db:"video_id"`
+
+	CreatorId     uint64 `db:"creator_id"`
+	BroadcasterId uint64 `db:"broadcaster_id"`
+
+	SourceType string    `db:"source_type"`
+	SourceId   uuid.UUID `db:"source_id"`
+
+	StartOffset uint64 `db:"start_offset"` // milliseconds
+	EndOffset   uint64 `db:"end_offset"`   // milliseconds
+
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// ClipToDomain converts a storage clip model to a domain model.
+func ClipToDomain(c Clip) clip.Clip {
+	return clip.Clip{
+		Id:            c.Id,
+		VideoId:       c.VideoId,
+		CreatorId:     c.CreatorId,
+		BroadcasterId: c.BroadcasterId,
+		SourceType:    clip.SourceType(c.SourceType),
+		SourceId:      c.SourceId,
+		Start:         time.Duration(c.StartOffset) * time.Millisecond,
+		End:           time.Duration(c.EndOffset) * time.Millisecond,
+		CreatedAt:     c.CreatedAt,
+	}
+}
+
+// ClipsToDomain converts a slice of storage clip models to domain models.
+func ClipsToDomain(clips []Clip) []clip.Clip {
+	domainClips := make([]clip.Clip, len(clips))
+	for i, c := range clips {
+		domainClips[i] = ClipToDomain(c)
+	}
+
+	return domainClips
+}
+
+// ClipToStorage converts a domain clip model to a storage model.
+func ClipToStorage(c clip.Clip) Clip {
+	return Clip{
+		Id:            c.Id,
+		VideoId:       c.VideoId,
+		CreatorId:     c.CreatorId,
+		BroadcasterId: c.BroadcasterId,
+		SourceType:    string(c.SourceType),
+		SourceId:      c.SourceId,
+		StartOffset:   uint64(c.Start.Milliseconds()),
+		EndOffset:     uint64(c.End.Milliseconds()),
+		CreatedAt:     c.CreatedAt,
+	}
+}
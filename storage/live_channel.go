@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"github.com/M-Ro/go-vodstream/internal/domain/broadcast"
+	"github.com/M-Ro/go-vodstream/internal/domain/cluster"
+	"time"
+)
+
+type LiveChannel struct {
+	Name        string `db:"name"`
+	NodeAddress string `db:"node_address"`
+
+	BroadcasterId uint64 `db:"broadcaster_id"`
+	Visibility    string `db:"visibility"`
+	LowLatency    bool   `db:"low_latency"`
+
+	StartedAt   time.Time `db:"started_at"`
+	HeartbeatAt time.Time `db:"heartbeat_at"`
+}
+
+// LiveChannelToDomain converts a storage live channel model to a domain origin model.
+func LiveChannelToDomain(c LiveChannel) cluster.Origin {
+	return cluster.Origin{
+		Name:          c.Name,
+		NodeAddress:   c.NodeAddress,
+		BroadcasterId: c.BroadcasterId,
+		Visibility:    broadcast.ParseVisibility(c.Visibility),
+		LowLatency:    c.LowLatency,
+		StartedAt:     c.StartedAt,
+		HeartbeatAt:   c.HeartbeatAt,
+	}
+}
+
+// LiveChannelToStorage converts a domain origin model to a storage live channel model.
+func LiveChannelToStorage(o cluster.Origin) LiveChannel {
+	return LiveChannel{
+		Name:          o.Name,
+		NodeAddress:   o.NodeAddress,
+		BroadcasterId: o.BroadcasterId,
+		Visibility:    string(o.Visibility),
+		LowLatency:    o.LowLatency,
+		StartedAt:     o.StartedAt,
+		HeartbeatAt:   o.HeartbeatAt,
+	}
+}
@@ -0,0 +1,89 @@
+package broadcast
+
+import (
+	"context"
+	domainBroadcast "github.com/M-Ro/go-vodstream/internal/domain/broadcast"
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/M-Ro/go-vodstream/storage/memory/table"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// MemoryBroadcastStorage keeps broadcasts in memory, with the generic operations of table.Storage.
+type MemoryBroadcastStorage struct {
+	table.Storage[storage.Broadcast, uuid.UUID]
+}
+
+var (
+	ErrNoRowsAffected = table.ErrNoRowsAffected
+)
+
+var broadcastsTable = table.Table{
+	Key:      "id",
+	Schema:   domainBroadcast.Fields,
+	ReadOnly: []string{"search_vector"},
+}
+
+// ListLive returns a set of the active, published broadcasts, specified by the given pagination
+// options, with the cursors of the pages either side. Ties are broken by ID.
+func (s MemoryBroadcastStorage) ListLive(
+	ctx context.Context, options paginate.QueryOptions,
+) ([]storage.Broadcast, paginate.Page, error) {
+	return s.ListWhere(ctx, func(broadcast storage.Broadcast) bool {
+		return broadcast.IsActive && broadcast.IsPublished
+	}, options)
+}
+
+// ListLiveByCategory returns a set of the active, published broadcasts in the given category,
+// specified by the given pagination options, with the cursors of the pages either side. Ties
+// are broken by ID.
+func (s MemoryBroadcastStorage) ListLiveByCategory(
+	ctx context.Context, category string, options paginate.QueryOptions,
+) ([]storage.Broadcast, paginate.Page, error) {
+	return s.ListWhere(ctx, func(broadcast storage.Broadcast) bool {
+		return broadcast.IsActive && broadcast.IsPublished && broadcast.Category == category
+	}, options)
+}
+
+// Insert takes a storage model and keeps it. Returns an error on failure.
+// Broadcasts are inserted under the ID of the live channel they were published as,
+// or upon insertion the ID field of the model will be set if it was not. Nil tags
+// are kept as none, as by the SQL storage.
+func (s MemoryBroadcastStorage) Insert(ctx context.Context, broadcast *storage.Broadcast) error {
+	if broadcast.Id == uuid.Nil {
+		broadcast.Id = uuid.New()
+	}
+
+	if broadcast.Tags == nil {
+		broadcast.Tags = pq.StringArray{}
+	}
+
+	return s.Storage.Insert(ctx, broadcast)
+}
+
+// Update takes a storage model and replaces the broadcast at the given ID. Returns
+// ErrNoRowsAffected if there is none. Nil tags are kept as none, as by the SQL storage.
+func (s MemoryBroadcastStorage) Update(ctx context.Context, id uuid.UUID, broadcast *storage.Broadcast) error {
+	if broadcast.Tags == nil {
+		broadcast.Tags = pq.StringArray{}
+	}
+
+	return s.Storage.Update(ctx, id, broadcast)
+}
+
+// UpdateViewerCount sets the viewer count of the broadcast at the given ID, leaving the rest of
+// the broadcast untouched so that edits made while live are not overwritten.
+func (s MemoryBroadcastStorage) UpdateViewerCount(_ context.Context, id uuid.UUID, viewers int64) error {
+	return s.Modify(id, func(broadcast *storage.Broadcast) {
+		broadcast.ViewerCount = viewers
+	})
+}
+
+// NewBroadcastStorage instantiates a new, empty MemoryBroadcastStorage object.
+func NewBroadcastStorage() *MemoryBroadcastStorage {
+	newStorage := new(MemoryBroadcastStorage)
+	newStorage.Storage = table.New[storage.Broadcast, uuid.UUID](broadcastsTable, nil)
+
+	return newStorage
+}
@@ -0,0 +1,10 @@
+package broadcast
+
+import (
+	"github.com/M-Ro/go-vodstream/storage/storagetest"
+	"testing"
+)
+
+func TestMemoryBroadcastStorage(t *testing.T) {
+	storagetest.Broadcasts(t, NewBroadcastStorage())
+}
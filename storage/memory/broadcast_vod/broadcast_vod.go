@@ -0,0 +1,68 @@
+package broadcast_vod
+
+import (
+	"context"
+	"github.com/M-Ro/go-vodstream/internal/domain/broadcast"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/M-Ro/go-vodstream/storage/memory/table"
+	"github.com/google/uuid"
+	"sort"
+)
+
+// MemoryBroadcastVodStorage keeps the links between broadcasts and the videos recorded
+// from them in memory, with the generic operations of table.Storage.
+type MemoryBroadcastVodStorage struct {
+	table.Storage[storage.BroadcastVod, uuid.UUID]
+}
+
+var (
+	ErrNoRowsAffected = table.ErrNoRowsAffected
+)
+
+var broadcastVodsTable = table.Table{
+	Key:    "id",
+	Schema: broadcast.VodFields,
+}
+
+// GetByStreamID returns the broadcastVods of the broadcast with the given ID, in order of publication.
+func (s MemoryBroadcastVodStorage) GetByStreamID(_ context.Context, streamId uuid.UUID) ([]storage.BroadcastVod, error) {
+	vods := s.Select(func(vod storage.BroadcastVod) bool {
+		return vod.StreamId == streamId
+	})
+
+	sort.SliceStable(vods, func(i, j int) bool {
+		return vods[i].PublishedAt.Before(vods[j].PublishedAt)
+	})
+
+	return vods, nil
+}
+
+// GetByVideoID returns the broadcastVods of the video with the given ID, in order of publication.
+func (s MemoryBroadcastVodStorage) GetByVideoID(_ context.Context, videoId uuid.UUID) ([]storage.BroadcastVod, error) {
+	vods := s.Select(func(vod storage.BroadcastVod) bool {
+		return vod.VideoId == videoId
+	})
+
+	sort.SliceStable(vods, func(i, j int) bool {
+		return vods[i].PublishedAt.Before(vods[j].PublishedAt)
+	})
+
+	return vods, nil
+}
+
+// DeleteByVideoID removes every broadcastVod of the video with the given ID.
+func (s MemoryBroadcastVodStorage) DeleteByVideoID(_ context.Context, videoId uuid.UUID) error {
+	s.DeleteWhere(func(vod storage.BroadcastVod) bool {
+		return vod.VideoId == videoId
+	})
+
+	return nil
+}
+
+// NewBroadcastVodStorage instantiates a new, empty MemoryBroadcastVodStorage object.
+func NewBroadcastVodStorage() *MemoryBroadcastVodStorage {
+	newStorage := new(MemoryBroadcastVodStorage)
+	newStorage.Storage = table.New[storage.BroadcastVod, uuid.UUID](broadcastVodsTable, uuid.New)
+
+	return newStorage
+}
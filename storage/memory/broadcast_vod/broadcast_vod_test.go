@@ -0,0 +1,11 @@
+package broadcast_vod
+
+import (
+	"github.com/M-Ro/go-vodstream/storage/memory/video"
+	"github.com/M-Ro/go-vodstream/storage/storagetest"
+	"testing"
+)
+
+func TestMemoryBroadcastVodStorage(t *testing.T) {
+	storagetest.BroadcastVods(t, NewBroadcastVodStorage(), video.NewVideoStorage())
+}
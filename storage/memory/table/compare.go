@@ -0,0 +1,297 @@
+package table
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	"github.com/google/uuid"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	uuidType = reflect.TypeOf(uuid.UUID{})
+)
+
+// page completes a page of rows selected by ListWhere, pointed to by rows, returning the
+// cursors of the pages either side of it.
+func (s Storage[T, K]) page(rows *[]T, options paginate.QueryOptions) paginate.Page {
+	return paginate.Paginate(rows, options, func(i int) ([]string, string) {
+		row := reflect.ValueOf((*rows)[i])
+
+		values := make([]string, 0, len(options.ThenBy)+1)
+		for _, order := range options.Orders() {
+			values = append(values, format(mapper.FieldByName(row, order.Field)))
+		}
+
+		return values, format(mapper.FieldByName(row, s.Table.Key))
+	})
+}
+
+// cursorValues returns the values of the cursor for each of the orders, read as the types of
+// their fields, or ErrInvalidCursor.
+func (s Storage[T, K]) cursorValues(cursor paginate.Cursor, orders []paginate.Order) ([]reflect.Value, error) {
+	texts := append(append([]string{cursor.Value}, cursor.Then...), cursor.Id)
+	if len(texts) != len(orders) {
+		return nil, paginate.ErrInvalidCursor
+	}
+
+	fields := mapper.TypeMap(reflect.TypeOf(new(T)).Elem())
+
+	values := make([]reflect.Value, 0, len(orders))
+	for i, order := range orders {
+		field := fields.GetByPath(order.Field)
+		if field == nil {
+			return nil, paginate.ErrInvalidCursor
+		}
+
+		value, err := parse(texts[i], field.Field.Type)
+		if err != nil {
+			return nil, paginate.ErrInvalidCursor
+		}
+
+		values = append(values, value)
+	}
+
+	return values, nil
+}
+
+// format formats the value of a field for a cursor, as text parse reads back.
+func format(field reflect.Value) string {
+	if !field.IsValid() {
+		return ""
+	}
+
+	switch value := field.Interface().(type) {
+	case time.Time:
+		return value.Format(time.RFC3339Nano)
+	case fmt.Stringer:
+		return value.String()
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// parse reads text written by format as a value of the given type.
+func parse(text string, t reflect.Type) (reflect.Value, error) {
+	switch t {
+	case timeType:
+		value, err := time.Parse(time.RFC3339Nano, text)
+		return reflect.ValueOf(value), err
+	case uuidType:
+		value, err := uuid.Parse(text)
+		return reflect.ValueOf(value), err
+	}
+
+	value := reflect.New(t).Elem()
+
+	switch t.Kind() {
+	case reflect.String:
+		value.SetString(text)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(text)
+		if err != nil {
+			return value, err
+		}
+		value.SetBool(parsed)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(text, 10, t.Bits())
+		if err != nil {
+			return value, err
+		}
+		value.SetInt(parsed)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, err := strconv.ParseUint(text, 10, t.Bits())
+		if err != nil {
+			return value, err
+		}
+		value.SetUint(parsed)
+	default:
+		return value, fmt.Errorf("cannot read %s from a cursor", t)
+	}
+
+	return value, nil
+}
+
+// matches returns true if the field of a row matches the filter.
+func matches(field reflect.Value, filter paginate.Filter) bool {
+	switch filter.Operator {
+	case paginate.OperatorIn:
+		for _, value := range filter.Values {
+			if compare(field, reflect.ValueOf(value)) == 0 {
+				return true
+			}
+		}
+
+		return false
+	case paginate.OperatorRange:
+		from, to := filter.Values[0], filter.Values[1]
+
+		return (from == nil || compare(field, reflect.ValueOf(from)) >= 0) &&
+			(to == nil || compare(field, reflect.ValueOf(to)) <= 0)
+	case paginate.OperatorILike:
+		return ILike(filter.Values[0].(string)).MatchString(field.String())
+	default:
+		return compare(field, reflect.ValueOf(filter.Values[0])) == 0
+	}
+}
+
+// ILike returns an expression matching text as the SQL ILIKE pattern, where % matches any
+// run of characters, _ matches any single character and either is escaped by a backslash.
+func ILike(pattern string) *regexp.Regexp {
+	var expression strings.Builder
+	expression.WriteString(`(?is)^`)
+
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			expression.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '%':
+			expression.WriteString(`.*`)
+		case r == '_':
+			expression.WriteString(`.`)
+		default:
+			expression.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+
+	expression.WriteString(`$`)
+	return regexp.MustCompile(expression.String())
+}
+
+// compareRow compares the order keys of a row with the values of a cursor, in the direction
+// of each order. Returns a positive number if the row comes after the cursor.
+func compareRow(row reflect.Value, values []reflect.Value, orders []paginate.Order) int {
+	for i, order := range orders {
+		if c := directed(compare(mapper.FieldByName(row, order.Field), values[i]), order.Method); c != 0 {
+			return c
+		}
+	}
+
+	return 0
+}
+
+// compareRows compares the order keys of two rows, in the direction of each order.
+func compareRows(a reflect.Value, b reflect.Value, orders []paginate.Order) int {
+	for _, order := range orders {
+		c := compare(mapper.FieldByName(a, order.Field), mapper.FieldByName(b, order.Field))
+		if c = directed(c, order.Method); c != 0 {
+			return c
+		}
+	}
+
+	return 0
+}
+
+// directed returns the comparison c of two values in ascending order, in the given order.
+func directed(c int, method paginate.OrderMethod) int {
+	if method == paginate.OrderMethodDesc {
+		return -c
+	}
+
+	return c
+}
+
+// compare returns a negative number, zero or a positive number as a is less than, equal to
+// or greater than b. Values are compared as Postgres compares their columns, but for text,
+// which is compared byte by byte as under the C collation.
+func compare(a reflect.Value, b reflect.Value) int {
+	switch {
+	case a.Type() == timeType && b.Type() == timeType:
+		x, y := a.Interface().(time.Time), b.Interface().(time.Time)
+		if x.Before(y) {
+			return -1
+		} else if x.After(y) {
+			return 1
+		}
+
+		return 0
+	case a.Type() == uuidType && b.Type() == uuidType:
+		x, y := a.Interface().(uuid.UUID), b.Interface().(uuid.UUID)
+		return bytes.Compare(x[:], y[:])
+	}
+
+	switch {
+	case a.Kind() == reflect.String && b.Kind() == reflect.String:
+		return strings.Compare(a.String(), b.String())
+	case a.Kind() == reflect.Bool && b.Kind() == reflect.Bool:
+		return ordered(boolean(a.Bool()), boolean(b.Bool()))
+	case isInteger(a) && isInteger(b):
+		return compareIntegers(a, b)
+	default:
+		panic(fmt.Sprintf("cannot compare %s with %s", a.Type(), b.Type()))
+	}
+}
+
+// compareIntegers compares integers of any signedness or size.
+func compareIntegers(a reflect.Value, b reflect.Value) int {
+	aNegative := isSigned(a) && a.Int() < 0
+	bNegative := isSigned(b) && b.Int() < 0
+
+	switch {
+	case aNegative && bNegative:
+		return ordered(a.Int(), b.Int())
+	case aNegative:
+		return -1
+	case bNegative:
+		return 1
+	}
+
+	return ordered(unsigned(a), unsigned(b))
+}
+
+// isInteger returns true if the value is an integer of any signedness or size.
+func isInteger(v reflect.Value) bool {
+	return isSigned(v) || (v.Kind() >= reflect.Uint && v.Kind() <= reflect.Uint64)
+}
+
+// isSigned returns true if the value is a signed integer.
+func isSigned(v reflect.Value) bool {
+	return v.Kind() >= reflect.Int && v.Kind() <= reflect.Int64
+}
+
+// unsigned returns the value of a non-negative integer.
+func unsigned(v reflect.Value) uint64 {
+	if isSigned(v) {
+		return uint64(v.Int())
+	}
+
+	return v.Uint()
+}
+
+// boolean returns 1 for true and 0 for false, false ordering first.
+func boolean(b bool) int64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+// ordered compares two values of an ordered type.
+func ordered[V int64 | uint64](a V, b V) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+
+	return 0
+}
+
+// reverse returns the opposite order method.
+func reverse(method paginate.OrderMethod) paginate.OrderMethod {
+	if method == paginate.OrderMethodDesc {
+		return paginate.OrderMethodAsc
+	}
+
+	return paginate.OrderMethodDesc
+}
@@ -0,0 +1,314 @@
+// Package table keeps storage models in memory, with the semantics of the SQL
+// storages built on storage/sql/table, for tests and development without a
+// database.
+package table
+
+import (
+	"context"
+	"errors"
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrNoRowsAffected = storage.ErrNoRowsAffected
+	ErrDuplicateKey   = errors.New("a row already exists with id")
+)
+
+// mapper finds the fields of storage models by column name, as sqlx scans them.
+var mapper = reflectx.NewMapperFunc("db", sqlx.NameMapper)
+
+// Table describes the rows a storage keeps.
+type Table struct {
+	// The column identifying rows
+	Key string
+
+	// The fields listings of the rows may be ordered or filtered by
+	Schema paginate.Schema
+
+	// Columns generated by the database, never written
+	ReadOnly []string
+}
+
+// Storage keeps models of type T, keyed by values of type K, in memory. Rows are
+// copied in and out, so callers never share them. created_at and updated_at
+// columns are kept up to date, as by the SQL storages.
+type Storage[T any, K comparable] struct {
+	Table Table
+
+	// NewKey returns the key of each row inserted, or is nil if rows are
+	// inserted under the key they are given.
+	NewKey func() K
+
+	lock *sync.RWMutex
+	rows map[K]T
+}
+
+// New returns an empty storage of models of type T in the described table.
+func New[T any, K comparable](table Table, newKey func() K) Storage[T, K] {
+	return Storage[T, K]{
+		Table:  table,
+		NewKey: newKey,
+		lock:   new(sync.RWMutex),
+		rows:   make(map[K]T),
+	}
+}
+
+// Sequence returns a function generating keys counting up from 1, as a BIGSERIAL column.
+func Sequence() func() uint64 {
+	var last uint64
+
+	return func() uint64 {
+		return atomic.AddUint64(&last, 1)
+	}
+}
+
+// All returns all rows in the table, ordered by key.
+func (s Storage[T, K]) All(_ context.Context) ([]T, error) {
+	return s.Select(nil), nil
+}
+
+// List returns a set of rows from the table specified by the given pagination options,
+// with the cursors of the pages either side.
+func (s Storage[T, K]) List(ctx context.Context, options paginate.QueryOptions) ([]T, paginate.Page, error) {
+	return s.ListWhere(ctx, nil, options)
+}
+
+// ListWhere returns a set of the rows of the table matching the condition, specified by the
+// given pagination options, with the cursors of the pages either side. Ties are broken by key.
+// A nil condition matches every row.
+func (s Storage[T, K]) ListWhere(
+	_ context.Context, condition func(row T) bool, options paginate.QueryOptions,
+) ([]T, paginate.Page, error) {
+	if err := s.Table.Schema.Validate(options); err != nil {
+		return []T{}, paginate.Page{}, err
+	}
+
+	orders := options.Orders()
+	orders = append(orders, paginate.Order{Field: s.Table.Key, Method: options.Order.Method})
+
+	backward := options.Cursor != nil && options.Cursor.Backward
+	if backward {
+		for i := range orders {
+			orders[i].Method = reverse(orders[i].Method)
+		}
+	}
+
+	var after []reflect.Value
+	if options.Cursor != nil {
+		var err error
+		if after, err = s.cursorValues(*options.Cursor, orders); err != nil {
+			return []T{}, paginate.Page{}, err
+		}
+	}
+
+	rows := s.Select(func(row T) bool {
+		if condition != nil && !condition(row) {
+			return false
+		}
+
+		model := reflect.ValueOf(row)
+		for _, filter := range options.Filters {
+			if !matches(mapper.FieldByName(model, filter.Field), filter) {
+				return false
+			}
+		}
+
+		return after == nil || compareRow(model, after, orders) > 0
+	})
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		return compareRows(reflect.ValueOf(rows[i]), reflect.ValueOf(rows[j]), orders) < 0
+	})
+
+	if options.Cursor == nil {
+		rows = rows[min(int(options.Offset), len(rows)):]
+	}
+	rows = rows[:min(int(options.Limit)+1, len(rows))]
+
+	return rows, s.page(&rows, options), nil
+}
+
+// Select returns the rows of the table matching the condition, ordered by key. A nil
+// condition matches every row.
+func (s Storage[T, K]) Select(condition func(row T) bool) []T {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	selected := make([]T, 0)
+	for _, row := range s.rows {
+		if condition == nil || condition(row) {
+			selected = append(selected, clone(row))
+		}
+	}
+
+	sort.Slice(selected, func(i, j int) bool {
+		return compare(reflect.ValueOf(s.key(selected[i])), reflect.ValueOf(s.key(selected[j]))) < 0
+	})
+
+	return selected
+}
+
+// Get returns the first row of the table by key matching the condition, or nil if none do.
+func (s Storage[T, K]) Get(condition func(row T) bool) *T {
+	selected := s.Select(condition)
+	if len(selected) == 0 {
+		return nil
+	}
+
+	return &selected[0]
+}
+
+// GetByID returns the row with the given key, or nil if there is none.
+func (s Storage[T, K]) GetByID(_ context.Context, id K) *T {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	row, ok := s.rows[id]
+	if !ok {
+		return nil
+	}
+
+	row = clone(row)
+	return &row
+}
+
+// Delete removes the row with the given key from the table, if there is one.
+func (s Storage[T, K]) Delete(_ context.Context, id K) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.rows, id)
+	return nil
+}
+
+// DeleteWhere removes the rows of the table matching the condition.
+func (s Storage[T, K]) DeleteWhere(condition func(row T) bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for id, row := range s.rows {
+		if condition(row) {
+			delete(s.rows, id)
+		}
+	}
+}
+
+// Insert takes a storage model and inserts it into the table. Returns ErrDuplicateKey if a row
+// exists at its key. Upon insertion the key field of the model will be set if generated.
+func (s Storage[T, K]) Insert(_ context.Context, row *T) error {
+	model := reflect.ValueOf(row).Elem()
+
+	now := time.Now().Truncate(time.Microsecond)
+	s.setTime(model, "created_at", now)
+	s.setTime(model, "updated_at", now)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.NewKey != nil {
+		mapper.FieldByName(model, s.Table.Key).Set(reflect.ValueOf(s.NewKey()))
+	}
+
+	id := s.key(*row)
+	if _, ok := s.rows[id]; ok {
+		return ErrDuplicateKey
+	}
+
+	s.rows[id] = s.written(*row)
+	return nil
+}
+
+// Update takes a storage model and updates the row contents at the given key.
+// Returns ErrNoRowsAffected if a row was not found with the given key.
+func (s Storage[T, K]) Update(_ context.Context, id K, row *T) error {
+	model := reflect.ValueOf(row).Elem()
+	s.setTime(model, "updated_at", time.Now().Truncate(time.Microsecond))
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.rows[id]; !ok {
+		return ErrNoRowsAffected
+	}
+
+	// Every column but the key is written, as by the SQL storages.
+	stored := s.written(*row)
+	mapper.FieldByName(reflect.ValueOf(&stored).Elem(), s.Table.Key).Set(reflect.ValueOf(id))
+
+	s.rows[id] = stored
+	return nil
+}
+
+// Modify changes the row with the given key in place, returning ErrNoRowsAffected if there is none.
+func (s Storage[T, K]) Modify(id K, change func(row *T)) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	row, ok := s.rows[id]
+	if !ok {
+		return ErrNoRowsAffected
+	}
+
+	change(&row)
+	s.rows[id] = s.written(row)
+	return nil
+}
+
+// key returns the key of a row.
+func (s Storage[T, K]) key(row T) K {
+	return mapper.FieldByName(reflect.ValueOf(row), s.Table.Key).Interface().(K)
+}
+
+// written returns a copy of the row as stored, without the columns generated by the database.
+func (s Storage[T, K]) written(row T) T {
+	row = clone(row)
+
+	model := reflect.ValueOf(&row).Elem()
+	for _, column := range s.Table.ReadOnly {
+		field := mapper.FieldByName(model, column)
+		field.Set(reflect.Zero(field.Type()))
+	}
+
+	return row
+}
+
+// setTime sets the timestamp of the model in the given column, if it has it.
+func (s Storage[T, K]) setTime(model reflect.Value, column string, t time.Time) {
+	if field := mapper.FieldByName(model, column); field.IsValid() && field.Type() == reflect.TypeOf(t) {
+		field.Set(reflect.ValueOf(t))
+	}
+}
+
+// clone returns a copy of the row sharing no slices with it.
+func clone[T any](row T) T {
+	model := reflect.ValueOf(&row).Elem()
+	for i := 0; i < model.NumField(); i++ {
+		field := model.Field(i)
+		if field.Kind() != reflect.Slice || field.IsNil() || !field.CanSet() {
+			continue
+		}
+
+		copied := reflect.MakeSlice(field.Type(), field.Len(), field.Len())
+		reflect.Copy(copied, field)
+		field.Set(copied)
+	}
+
+	return row
+}
+
+// min returns the smaller of a and b.
+func min(a int, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,46 @@
+package user
+
+import (
+	"context"
+	domainUser "github.com/M-Ro/go-vodstream/internal/domain/user"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/M-Ro/go-vodstream/storage/memory/table"
+	"strings"
+)
+
+// MemoryUserStorage keeps users in memory, with the generic operations of table.Storage.
+type MemoryUserStorage struct {
+	table.Storage[storage.User, uint64]
+}
+
+var (
+	ErrNoRowsAffected = table.ErrNoRowsAffected
+)
+
+var usersTable = table.Table{
+	Key:      "id",
+	Schema:   domainUser.Fields,
+	ReadOnly: []string{"search_vector"},
+}
+
+// GetByUsername returns the user with the given username, case insensitively, or nil if there is none.
+func (s MemoryUserStorage) GetByUsername(_ context.Context, username string) *storage.User {
+	return s.Get(func(user storage.User) bool {
+		return strings.EqualFold(user.Username, username)
+	})
+}
+
+// GetByEmail returns the user with the given email, case insensitively, or nil if there is none.
+func (s MemoryUserStorage) GetByEmail(_ context.Context, email string) *storage.User {
+	return s.Get(func(user storage.User) bool {
+		return strings.EqualFold(user.Email, email)
+	})
+}
+
+// NewUserStorage instantiates a new, empty MemoryUserStorage object.
+func NewUserStorage() *MemoryUserStorage {
+	newStorage := new(MemoryUserStorage)
+	newStorage.Storage = table.New[storage.User, uint64](usersTable, table.Sequence())
+
+	return newStorage
+}
@@ -0,0 +1,10 @@
+package user
+
+import (
+	"github.com/M-Ro/go-vodstream/storage/storagetest"
+	"testing"
+)
+
+func TestMemoryUserStorage(t *testing.T) {
+	storagetest.Users(t, NewUserStorage())
+}
@@ -0,0 +1,53 @@
+package video
+
+import (
+	"context"
+	domainVideo "github.com/M-Ro/go-vodstream/internal/domain/video"
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/M-Ro/go-vodstream/storage/memory/table"
+	"github.com/google/uuid"
+)
+
+// MemoryVideoStorage keeps videos in memory, with the generic operations of table.Storage.
+type MemoryVideoStorage struct {
+	table.Storage[storage.Video, uuid.UUID]
+}
+
+var (
+	ErrNoRowsAffected = table.ErrNoRowsAffected
+)
+
+var videosTable = table.Table{
+	Key:      "id",
+	Schema:   domainVideo.Fields,
+	ReadOnly: []string{"search_vector"},
+}
+
+// ListByBroadcaster returns a set of the given broadcaster's videos, specified by the given pagination options,
+// with the cursors of the pages either side.
+func (s MemoryVideoStorage) ListByBroadcaster(
+	ctx context.Context, broadcasterId uint64, options paginate.QueryOptions,
+) ([]storage.Video, paginate.Page, error) {
+	return s.ListWhere(ctx, func(video storage.Video) bool {
+		return video.BroadcasterId == broadcasterId
+	}, options)
+}
+
+// ListPublishedByBroadcaster returns a set of the given broadcaster's published videos,
+// specified by the given pagination options, with the cursors of the pages either side.
+func (s MemoryVideoStorage) ListPublishedByBroadcaster(
+	ctx context.Context, broadcasterId uint64, options paginate.QueryOptions,
+) ([]storage.Video, paginate.Page, error) {
+	return s.ListWhere(ctx, func(video storage.Video) bool {
+		return video.BroadcasterId == broadcasterId && video.IsPublished
+	}, options)
+}
+
+// NewVideoStorage instantiates a new, empty MemoryVideoStorage object.
+func NewVideoStorage() *MemoryVideoStorage {
+	newStorage := new(MemoryVideoStorage)
+	newStorage.Storage = table.New[storage.Video, uuid.UUID](videosTable, uuid.New)
+
+	return newStorage
+}
@@ -0,0 +1,10 @@
+package video
+
+import (
+	"github.com/M-Ro/go-vodstream/storage/storagetest"
+	"testing"
+)
+
+func TestMemoryVideoStorage(t *testing.T) {
+	storagetest.Videos(t, NewVideoStorage())
+}
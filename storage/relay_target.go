@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"github.com/M-Ro/go-vodstream/internal/domain/relay"
+	"github.com/google/uuid"
+	"time"
+)
+
+type RelayTarget struct {
+	Id uuid.UUID `db:"id"`
+
+	// The user restreaming to this target
+	UserId uint64 `db:"user_id"`
+
+	Name      string `db:"name"`
+	Url       string `db:"url"`
+	StreamKey string `db:"stream_key"` // encrypted
+	Enabled   bool   `db:"enabled"`
+
+	Status          string    `db:"status"`
+	LastError       string    `db:"last_error"`
+	StatusUpdatedAt time.Time `db:"status_updated_at"`
+
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// RelayTargetToDomain converts a storage relay target model to a domain model.
+// The stream key is copied as stored, decryption is left to the caller.
+func RelayTargetToDomain(t RelayTarget) relay.Target {
+	return relay.Target{
+		Id:              t.Id,
+		UserId:          t.UserId,
+		Name:            t.Name,
+		URL:             t.Url,
+		StreamKey:       t.StreamKey,
+		Enabled:         t.Enabled,
+		Status:          relay.Status(t.Status),
+		LastError:       t.LastError,
+		StatusUpdatedAt: t.StatusUpdatedAt,
+		CreatedAt:       t.CreatedAt,
+		UpdatedAt:       t.UpdatedAt,
+	}
+}
+
+// RelayTargetToStorage converts a domain relay target model to a storage model.
+// The stream key is copied as given, encryption is left to the caller.
+func RelayTargetToStorage(t relay.Target) RelayTarget {
+	return RelayTarget{
+		Id:              t.Id,
+		UserId:          t.UserId,
+		Name:            t.Name,
+		Url:             t.URL,
+		StreamKey:       t.StreamKey,
+		Enabled:         t.Enabled,
+		Status:          string(t.Status),
+		LastError:       t.LastError,
+		StatusUpdatedAt: t.StatusUpdatedAt,
+		CreatedAt:       t.CreatedAt,
+		UpdatedAt:       t.UpdatedAt,
+	}
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"github.com/M-Ro/go-vodstream/internal/domain/search"
+	"github.com/lib/pq"
+	"time"
+)
+
+// SearchResult is a row matched by a full-text search, gathered from the
+// channels, broadcasts or videos tables. Results are never stored.
+type SearchResult struct {
+	Type string `db:"type"`
+	Id   string `db:"id"`
+
+	BroadcasterId uint64 `db:"broadcaster_id"`
+	Username      string `db:"username"`
+
+	Title       string `db:"title"`
+	Description string `db:"description"`
+
+	Category string         `db:"category"`
+	Tags     pq.StringArray `db:"tags"`
+
+	IsLive      bool      `db:"is_live"`
+	PublishedAt time.Time `db:"published_at"`
+
+	Rank float64 `db:"rank"`
+}
+
+// SearchResultsToDomain converts storage search results to domain models.
+func SearchResultsToDomain(results []SearchResult) []search.Result {
+	convertedResults := make([]search.Result, len(results))
+
+	for i, r := range results {
+		convertedResults[i] = SearchResultToDomain(r)
+	}
+
+	return convertedResults
+}
+
+// SearchResultToDomain converts a storage search result to a domain model.
+func SearchResultToDomain(r SearchResult) search.Result {
+	tags := make([]string, len(r.Tags))
+	copy(tags, r.Tags)
+
+	return search.Result{
+		Type:          search.Type(r.Type),
+		Id:            r.Id,
+		BroadcasterId: r.BroadcasterId,
+		Username:      r.Username,
+		Title:         r.Title,
+		Description:   r.Description,
+		Category:      r.Category,
+		Tags:          tags,
+		IsLive:        r.IsLive,
+		PublishedAt:   r.PublishedAt,
+		Rank:          r.Rank,
+	}
+}
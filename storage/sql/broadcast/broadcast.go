@@ -2,169 +2,87 @@ package broadcast
 
 import (
 	"context"
-	sql2 "database/sql"
-	"errors"
-	"fmt"
+	domainBroadcast "github.com/M-Ro/go-vodstream/internal/domain/broadcast"
 	"github.com/M-Ro/go-vodstream/internal/paginate"
 	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/M-Ro/go-vodstream/storage/sql/table"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
-	log "github.com/sirupsen/logrus"
-	"time"
+	"github.com/lib/pq"
 )
 
 const BroadcastsTableName = "broadcasts"
 
+// SqlBroadcastStorage stores broadcasts, with the generic operations of table.Storage.
 type SqlBroadcastStorage struct {
-	DB *sqlx.DB
+	table.Storage[storage.Broadcast, uuid.UUID]
 }
 
 var (
-	ErrNoRowsAffected = errors.New("no row found with id")
+	ErrNoRowsAffected = table.ErrNoRowsAffected
 )
 
-// insertTableName is a helper function to insert the dynamic BroadcastsTableName property
-// as bindvars cannot be used as identifiers.
-func insertTableName(query string) string {
-	return fmt.Sprintf(query, BroadcastsTableName)
+var broadcastsTable = table.Table{
+	Name:     BroadcastsTableName,
+	Key:      "id",
+	Schema:   domainBroadcast.Fields,
+	ReadOnly: []string{"search_vector"},
 }
 
-// All returns all rows in the broadcasts table.
-func (s SqlBroadcastStorage) All(ctx context.Context) ([]storage.Broadcast, error) {
-	broadcasts := make([]storage.Broadcast, 0)
-
-	rows, err := s.DB.QueryxContext(ctx, insertTableName("SELECT * FROM %s ORDER BY id ASC"))
-	if err != nil {
-		log.Error(err)
-		return broadcasts, err
-	}
-
-	for rows.Next() {
-		broadcast := storage.Broadcast{}
-		err = rows.StructScan(&broadcast)
-		if err != nil {
-			log.Error(err)
-			rows.Close()
-			return broadcasts, err
-		}
-
-		broadcasts = append(broadcasts, broadcast)
-	}
-
-	return broadcasts, nil
+// ListLive returns a set of the active, published rows from the broadcasts table, specified by the
+// given pagination options, with the cursors of the pages either side. Ties are broken by ID.
+func (s SqlBroadcastStorage) ListLive(
+	ctx context.Context, options paginate.QueryOptions,
+) ([]storage.Broadcast, paginate.Page, error) {
+	return s.ListWhere(ctx, "is_active AND is_published", options)
 }
 
-// List returns a set of rows from the broadcasts table specified by the given pagination options.
-func (s SqlBroadcastStorage) List(ctx context.Context, options paginate.QueryOptions) ([]storage.Broadcast, error) {
-	broadcasts := make([]storage.Broadcast, 0)
-
-	sql := fmt.Sprintf(
-		`SELECT * FROM %s ORDER BY %s %s LIMIT %d OFFSET %d`,
-		BroadcastsTableName, options.Order.Field, options.Order.Method, options.Limit, options.Offset,
-	)
-
-	rows, err := s.DB.QueryxContext(ctx, sql)
-	if err != nil {
-		log.Error(err)
-		return broadcasts, err
-	}
-
-	for rows.Next() {
-		broadcast := storage.Broadcast{}
-		err = rows.StructScan(&broadcast)
-		if err != nil {
-			log.Error(err)
-			return broadcasts, err
-		}
-
-		broadcasts = append(broadcasts, broadcast)
-	}
-
-	return broadcasts, nil
-}
-
-// GetByID returns the broadcast with the given ID, or nil on failure.
-func (s SqlBroadcastStorage) GetByID(ctx context.Context, id uuid.UUID) *storage.Broadcast {
-	row := s.DB.QueryRowxContext(ctx, insertTableName(`SELECT * from %s WHERE id = $1`), id)
-
-	var broadcast storage.Broadcast
-	err := row.StructScan(&broadcast)
-	if err != nil {
-		if err != sql2.ErrNoRows {
-			log.Error(err)
-		}
-		return nil
-	}
-
-	return &broadcast
-}
-
-// Delete removes a broadcast with the given ID from the table. Only returns on db error.
-func (s SqlBroadcastStorage) Delete(ctx context.Context, id uuid.UUID) error {
-	_, err := s.DB.ExecContext(ctx, insertTableName(`DELETE FROM %s WHERE id = $1`), id)
-	if err != nil {
-		log.Errorf("SqlBroadcastStorage::Delete: %s", err)
-	}
-
-	return err
+// ListLiveByCategory returns a set of the active, published rows from the broadcasts table in the
+// given category, specified by the given pagination options, with the cursors of the pages either
+// side. Ties are broken by ID.
+func (s SqlBroadcastStorage) ListLiveByCategory(
+	ctx context.Context, category string, options paginate.QueryOptions,
+) ([]storage.Broadcast, paginate.Page, error) {
+	return s.ListWhere(ctx, "is_active AND is_published AND category = $1", options, category)
 }
 
 // Insert takes a storage model and inserts into the db. Returns an error on failure.
-// Upon insertion the ID field of the model will be set.
+// Broadcasts are inserted under the ID of the live channel they were published as,
+// or upon insertion the ID field of the model will be set if it was not. Nil tags
+// are stored as none.
 func (s SqlBroadcastStorage) Insert(ctx context.Context, broadcast *storage.Broadcast) error {
-	broadcast.CreatedAt = time.Now().Truncate(time.Microsecond)
-	broadcast.UpdatedAt = broadcast.CreatedAt
-
-	row := s.DB.QueryRowContext(
-		ctx,
-		insertTableName(`INSERT INTO %s 
-			(broadcaster_id, title, is_active, is_published, published_at, created_at, updated_at)
-			VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING id`),
-		broadcast.BroadcasterId, broadcast.Title, broadcast.IsActive, broadcast.IsPublished, broadcast.PublishedAt,
-		broadcast.CreatedAt, broadcast.UpdatedAt,
-	)
+	if broadcast.Id == uuid.Nil {
+		broadcast.Id = uuid.New()
+	}
 
-	err := row.Scan(&broadcast.Id)
+	if broadcast.Tags == nil {
+		broadcast.Tags = pq.StringArray{}
+	}
 
-	return err
+	return s.Storage.Insert(ctx, broadcast)
 }
 
-// Update takes a storage model and updates row contents for the user at the given ID.
-// Returns error on failure, or if a user was not found with the given id.
+// Update takes a storage model and updates the row contents at the given ID. Returns
+// an error on failure, or if a row was not found with the given ID. Nil tags are
+// stored as none.
 func (s SqlBroadcastStorage) Update(ctx context.Context, id uuid.UUID, broadcast *storage.Broadcast) error {
-	broadcast.UpdatedAt = time.Now().Truncate(time.Microsecond)
-
-	result, err := s.DB.ExecContext(
-		ctx,
-		insertTableName(`UPDATE %s SET 
-			broadcaster_id=$1, title=$2, is_active=$3, is_published=$4, published_at=$5, created_at=$6,
-			updated_at=$7, WHERE id=$8`),
-		broadcast.BroadcasterId, broadcast.Title, broadcast.IsActive, broadcast.IsPublished, broadcast.PublishedAt,
-		broadcast.CreatedAt, broadcast.UpdatedAt, id)
-
-	if err != nil {
-		log.Error(err)
-		return err
+	if broadcast.Tags == nil {
+		broadcast.Tags = pq.StringArray{}
 	}
 
-	rows, err := result.RowsAffected()
-	if err != nil {
-		log.Error(err)
-		return err
-	}
-
-	if rows != 1 {
-		log.Warn(ErrNoRowsAffected)
-		return ErrNoRowsAffected
-	}
+	return s.Storage.Update(ctx, id, broadcast)
+}
 
-	return nil
+// UpdateViewerCount sets the viewer count of the broadcast at the given ID, leaving the rest of
+// the row untouched so that edits made while live are not overwritten.
+func (s SqlBroadcastStorage) UpdateViewerCount(ctx context.Context, id uuid.UUID, viewers int64) error {
+	return s.ExecOne(ctx, s.InsertTableName(`UPDATE %s SET viewer_count=$1 WHERE id=$2`), viewers, id)
 }
 
 // NewBroadcastStorage instantiates a new SqlBroadcastStorage object.
 func NewBroadcastStorage(db *sqlx.DB) *SqlBroadcastStorage {
 	newStorage := new(SqlBroadcastStorage)
-	newStorage.DB = db
+	newStorage.Storage = table.New[storage.Broadcast, uuid.UUID](db, broadcastsTable)
 
 	return newStorage
 }
@@ -0,0 +1,26 @@
+package broadcast
+
+import (
+	"github.com/M-Ro/go-vodstream/storage/sql/table/tabletest"
+	"github.com/M-Ro/go-vodstream/storage/storagetest"
+	"github.com/jmoiron/sqlx"
+	"os"
+	"testing"
+)
+
+var testDb *sqlx.DB
+
+func TestMain(m *testing.M) {
+	var purge func()
+	testDb, purge = tabletest.Postgres(BroadcastsTableName)
+
+	// Run tests
+	code := m.Run()
+	purge()
+
+	os.Exit(code)
+}
+
+func TestBroadcastStorage_Conformance(t *testing.T) {
+	storagetest.Broadcasts(t, NewBroadcastStorage(testDb))
+}
@@ -0,0 +1,27 @@
+package broadcast_vod
+
+import (
+	"github.com/M-Ro/go-vodstream/storage/sql/table/tabletest"
+	"github.com/M-Ro/go-vodstream/storage/sql/video"
+	"github.com/M-Ro/go-vodstream/storage/storagetest"
+	"github.com/jmoiron/sqlx"
+	"os"
+	"testing"
+)
+
+var testDb *sqlx.DB
+
+func TestMain(m *testing.M) {
+	var purge func()
+	testDb, purge = tabletest.Postgres(video.VideosTableName, BroadcastVodsTableName)
+
+	// Run tests
+	code := m.Run()
+	purge()
+
+	os.Exit(code)
+}
+
+func TestBroadcastVodStorage_Conformance(t *testing.T) {
+	storagetest.BroadcastVods(t, NewBroadcastVodStorage(testDb), video.NewVideoStorage(testDb))
+}
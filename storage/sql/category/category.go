@@ -0,0 +1,189 @@
+package category
+
+import (
+	"context"
+	sql2 "database/sql"
+	"errors"
+	"fmt"
+	domainCategory "github.com/M-Ro/go-vodstream/internal/domain/category"
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/M-Ro/go-vodstream/storage/sql/broadcast"
+	"github.com/M-Ro/go-vodstream/storage/sql/dialect"
+	"github.com/M-Ro/go-vodstream/storage/sql/keyset"
+	"github.com/M-Ro/go-vodstream/storage/sql/transaction"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+const CategoriesTableName = "categories"
+
+type SqlCategoryStorage struct {
+	DB *sqlx.DB
+}
+
+var (
+	ErrNoRowsAffected = errors.New("no row found with slug")
+)
+
+// insertTableName is a helper function to insert the dynamic CategoriesTableName property
+// as bindvars cannot be used as identifiers.
+func insertTableName(query string) string {
+	return fmt.Sprintf(query, CategoriesTableName)
+}
+
+// browseQuery selects categories with the viewers and number of their live,
+// published broadcasts, filtered by the given WHERE clause.
+func browseQuery(where string) string {
+	return fmt.Sprintf(
+		`SELECT c.*, COALESCE(SUM(b.viewer_count), 0) AS viewers, COUNT(b.id) AS broadcasts FROM %s c
+			LEFT JOIN %s b ON b.category = c.slug AND b.is_active AND b.is_published
+			%s GROUP BY c.slug`,
+		CategoriesTableName, broadcast.BroadcastsTableName, where,
+	)
+}
+
+// scanRows reads all categories from the given rows.
+func scanRows(rows *sqlx.Rows) ([]storage.Category, error) {
+	defer rows.Close()
+
+	categories := make([]storage.Category, 0)
+
+	for rows.Next() {
+		category := storage.Category{}
+		err := rows.StructScan(&category)
+		if err != nil {
+			log.Error(err)
+			return categories, err
+		}
+
+		categories = append(categories, category)
+	}
+
+	return categories, rows.Err()
+}
+
+// All returns all rows in the categories table.
+func (s SqlCategoryStorage) All(ctx context.Context) ([]storage.Category, error) {
+	rows, err := transaction.Conn(ctx, s.DB).QueryxContext(ctx, insertTableName("SELECT * FROM %s ORDER BY slug ASC"))
+	if err != nil {
+		log.Error(err)
+		return []storage.Category{}, err
+	}
+
+	return scanRows(rows)
+}
+
+// List returns a set of rows from the categories table specified by the given pagination
+// options, with the totals of their live broadcasts and the cursors of the pages either side.
+// Categories may be ordered by these totals as "viewers" or "broadcasts", ties broken by slug.
+func (s SqlCategoryStorage) List(
+	ctx context.Context, options paginate.QueryOptions,
+) ([]storage.Category, paginate.Page, error) {
+	sql, args, err := keyset.Query(dialect.Of(s.DB), browseQuery(""), domainCategory.Fields, options, "slug")
+	if err != nil {
+		return []storage.Category{}, paginate.Page{}, err
+	}
+
+	rows, err := transaction.Conn(ctx, s.DB).QueryxContext(ctx, sql, args...)
+	if err != nil {
+		log.Error(err)
+		return []storage.Category{}, paginate.Page{}, err
+	}
+
+	categories, err := scanRows(rows)
+	if err != nil {
+		return categories, paginate.Page{}, err
+	}
+
+	return categories, keyset.Page(dialect.Of(s.DB), &categories, options, "slug"), nil
+}
+
+// GetBySlug returns the category with the given slug and the totals of its live
+// broadcasts, or nil on failure.
+func (s SqlCategoryStorage) GetBySlug(ctx context.Context, slug string) *storage.Category {
+	row := transaction.Conn(ctx, s.DB).QueryRowxContext(ctx, browseQuery("WHERE c.slug = $1"), slug)
+
+	var category storage.Category
+	err := row.StructScan(&category)
+	if err != nil {
+		if err != sql2.ErrNoRows {
+			log.Error(err)
+		}
+		return nil
+	}
+
+	return &category
+}
+
+// Delete removes the category with the given slug from the table. Only returns on db error.
+// Channels and broadcasts keep referencing the slug until they are edited.
+func (s SqlCategoryStorage) Delete(ctx context.Context, slug string) error {
+	_, err := transaction.Conn(ctx, s.DB).ExecContext(ctx, insertTableName(`DELETE FROM %s WHERE slug = $1`), slug)
+	if err != nil {
+		log.Errorf("SqlCategoryStorage::Delete: %s", err)
+	}
+
+	return err
+}
+
+// Insert takes a storage model and inserts into the db. Returns an error on failure.
+// Categories are keyed by their slug, which must be set.
+func (s SqlCategoryStorage) Insert(ctx context.Context, category *storage.Category) error {
+	category.CreatedAt = time.Now().Truncate(time.Microsecond)
+	category.UpdatedAt = category.CreatedAt
+
+	_, err := transaction.Conn(ctx, s.DB).ExecContext(
+		ctx,
+		insertTableName(`INSERT INTO %s
+			(slug, name, description, image_url, created_at, updated_at)
+			VALUES ($1,$2,$3,$4,$5,$6)`),
+		category.Slug, category.Name, category.Description, category.ImageURL,
+		category.CreatedAt, category.UpdatedAt,
+	)
+
+	if err != nil {
+		log.Error(err)
+	}
+
+	return err
+}
+
+// Update takes a storage model and updates row contents for the category with the given slug.
+// Returns error on failure, or if a category was not found with the given slug.
+func (s SqlCategoryStorage) Update(ctx context.Context, slug string, category *storage.Category) error {
+	category.UpdatedAt = time.Now().Truncate(time.Microsecond)
+
+	result, err := transaction.Conn(ctx, s.DB).ExecContext(
+		ctx,
+		insertTableName(`UPDATE %s SET
+			name=$1, description=$2, image_url=$3, created_at=$4, updated_at=$5 WHERE slug=$6`),
+		category.Name, category.Description, category.ImageURL, category.CreatedAt, category.UpdatedAt, slug)
+
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	if rows != 1 {
+		log.Warn(ErrNoRowsAffected)
+		return ErrNoRowsAffected
+	}
+
+	return nil
+}
+
+// NewCategoryStorage instantiates a new SqlCategoryStorage object.
+func NewCategoryStorage(db *sqlx.DB) *SqlCategoryStorage {
+	newStorage := new(SqlCategoryStorage)
+	newStorage.DB = db
+
+	return newStorage
+}
@@ -0,0 +1,196 @@
+package channel
+
+import (
+	"context"
+	sql2 "database/sql"
+	"errors"
+	"fmt"
+	domainChannel "github.com/M-Ro/go-vodstream/internal/domain/channel"
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/M-Ro/go-vodstream/storage/sql/dialect"
+	"github.com/M-Ro/go-vodstream/storage/sql/keyset"
+	"github.com/M-Ro/go-vodstream/storage/sql/transaction"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+const ChannelsTableName = "channels"
+
+type SqlChannelStorage struct {
+	DB *sqlx.DB
+}
+
+var (
+	ErrNoRowsAffected = errors.New("no row found with broadcaster id")
+)
+
+// insertTableName is a helper function to insert the dynamic ChannelsTableName property
+// as bindvars cannot be used as identifiers.
+func insertTableName(query string) string {
+	return fmt.Sprintf(query, ChannelsTableName)
+}
+
+// scanRows reads all channels from the given rows.
+func scanRows(rows *sqlx.Rows) ([]storage.Channel, error) {
+	defer rows.Close()
+
+	channels := make([]storage.Channel, 0)
+
+	for rows.Next() {
+		channel := storage.Channel{}
+		err := rows.StructScan(&channel)
+		if err != nil {
+			log.Error(err)
+			return channels, err
+		}
+
+		channels = append(channels, channel)
+	}
+
+	return channels, rows.Err()
+}
+
+// All returns all rows in the channels table.
+func (s SqlChannelStorage) All(ctx context.Context) ([]storage.Channel, error) {
+	rows, err := transaction.Conn(ctx, s.DB).QueryxContext(
+		ctx, insertTableName("SELECT * FROM %s ORDER BY broadcaster_id ASC"),
+	)
+	if err != nil {
+		log.Error(err)
+		return []storage.Channel{}, err
+	}
+
+	return scanRows(rows)
+}
+
+// List returns a set of rows from the channels table specified by the given pagination options,
+// with the cursors of the pages either side.
+func (s SqlChannelStorage) List(
+	ctx context.Context, options paginate.QueryOptions,
+) ([]storage.Channel, paginate.Page, error) {
+	sql, args, err := keyset.Query(
+		dialect.Of(s.DB), insertTableName(`SELECT * FROM %s`), domainChannel.Fields, options, "broadcaster_id",
+	)
+	if err != nil {
+		return []storage.Channel{}, paginate.Page{}, err
+	}
+
+	rows, err := transaction.Conn(ctx, s.DB).QueryxContext(ctx, sql, args...)
+	if err != nil {
+		log.Error(err)
+		return []storage.Channel{}, paginate.Page{}, err
+	}
+
+	channels, err := scanRows(rows)
+	if err != nil {
+		return channels, paginate.Page{}, err
+	}
+
+	return channels, keyset.Page(dialect.Of(s.DB), &channels, options, "broadcaster_id"), nil
+}
+
+// GetByBroadcasterID returns the channel of the given broadcaster, or nil on failure.
+func (s SqlChannelStorage) GetByBroadcasterID(ctx context.Context, broadcasterId uint64) *storage.Channel {
+	row := transaction.Conn(ctx, s.DB).QueryRowxContext(
+		ctx, insertTableName(`SELECT * from %s WHERE broadcaster_id = $1`), broadcasterId,
+	)
+
+	var channel storage.Channel
+	err := row.StructScan(&channel)
+	if err != nil {
+		if err != sql2.ErrNoRows {
+			log.Error(err)
+		}
+		return nil
+	}
+
+	return &channel
+}
+
+// Delete removes the channel of the given broadcaster from the table. Only returns on db error.
+func (s SqlChannelStorage) Delete(ctx context.Context, broadcasterId uint64) error {
+	_, err := transaction.Conn(ctx, s.DB).ExecContext(
+		ctx, insertTableName(`DELETE FROM %s WHERE broadcaster_id = $1`), broadcasterId,
+	)
+	if err != nil {
+		log.Errorf("SqlChannelStorage::Delete: %s", err)
+	}
+
+	return err
+}
+
+// Insert takes a storage model and inserts into the db. Returns an error on failure.
+// Channels are keyed by their broadcaster, which must be set. Nil tags are stored as none.
+func (s SqlChannelStorage) Insert(ctx context.Context, channel *storage.Channel) error {
+	if channel.Tags == nil {
+		channel.Tags = pq.StringArray{}
+	}
+
+	channel.CreatedAt = time.Now().Truncate(time.Microsecond)
+	channel.UpdatedAt = channel.CreatedAt
+
+	_, err := transaction.Conn(ctx, s.DB).ExecContext(
+		ctx,
+		insertTableName(`INSERT INTO %s 
+			(broadcaster_id, display_name, description, avatar_url, banner_url, default_title, category, tags,
+			language, mature, created_at, updated_at)
+			VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)`),
+		channel.BroadcasterId, channel.DisplayName, channel.Description, channel.AvatarURL, channel.BannerURL,
+		channel.DefaultTitle, channel.Category, channel.Tags, channel.Language, channel.Mature,
+		channel.CreatedAt, channel.UpdatedAt,
+	)
+
+	if err != nil {
+		log.Error(err)
+	}
+
+	return err
+}
+
+// Update takes a storage model and updates row contents for the channel of the given broadcaster.
+// Returns error on failure, or if the broadcaster has no channel. Nil tags are stored as none.
+func (s SqlChannelStorage) Update(ctx context.Context, broadcasterId uint64, channel *storage.Channel) error {
+	if channel.Tags == nil {
+		channel.Tags = pq.StringArray{}
+	}
+
+	channel.UpdatedAt = time.Now().Truncate(time.Microsecond)
+
+	result, err := transaction.Conn(ctx, s.DB).ExecContext(
+		ctx,
+		insertTableName(`UPDATE %s SET 
+			display_name=$1, description=$2, avatar_url=$3, banner_url=$4, default_title=$5, category=$6,
+			tags=$7, language=$8, mature=$9, created_at=$10, updated_at=$11 WHERE broadcaster_id=$12`),
+		channel.DisplayName, channel.Description, channel.AvatarURL, channel.BannerURL, channel.DefaultTitle,
+		channel.Category, channel.Tags, channel.Language, channel.Mature, channel.CreatedAt, channel.UpdatedAt,
+		broadcasterId)
+
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	if rows != 1 {
+		log.Warn(ErrNoRowsAffected)
+		return ErrNoRowsAffected
+	}
+
+	return nil
+}
+
+// NewChannelStorage instantiates a new SqlChannelStorage object.
+func NewChannelStorage(db *sqlx.DB) *SqlChannelStorage {
+	newStorage := new(SqlChannelStorage)
+	newStorage.DB = db
+
+	return newStorage
+}
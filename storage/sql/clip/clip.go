@@ -0,0 +1,130 @@
+package clip
+
+import (
+	"context"
+	sql2 "database/sql"
+	"fmt"
+	domainClip "github.com/M-Ro/go-vodstream/internal/domain/clip"
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/M-Ro/go-vodstream/storage/sql/dialect"
+	"github.com/M-Ro/go-vodstream/storage/sql/keyset"
+	"github.com/M-Ro/go-vodstream/storage/sql/transaction"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+const ClipsTableName = "clips"
+
+type SqlClipStorage struct {
+	DB *sqlx.DB
+}
+
+// insertTableName is a helper function to insert the dynamic ClipsTableName property
+// as bindvars cannot be used as identifiers.
+func insertTableName(query string) string {
+	return fmt.Sprintf(query, ClipsTableName)
+}
+
+// scanRows reads all clips from the given rows.
+func scanRows(rows *sqlx.Rows) ([]storage.Clip, error) {
+	defer rows.Close()
+
+	clips := make([]storage.Clip, 0)
+
+	for rows.Next() {
+		clip := storage.Clip{}
+		err := rows.StructScan(&clip)
+		if err != nil {
+			log.Error(err)
+			return clips, err
+		}
+
+		clips = append(clips, clip)
+	}
+
+	return clips, rows.Err()
+}
+
+// ListByBroadcaster returns a set of clips cut from the given broadcaster's
+// media, specified by the given pagination options, with the cursors of the
+// pages either side.
+func (s SqlClipStorage) ListByBroadcaster(
+	ctx context.Context, broadcasterId uint64, options paginate.QueryOptions,
+) ([]storage.Clip, paginate.Page, error) {
+	sql, args, err := keyset.Query(
+		dialect.Of(s.DB), insertTableName(`SELECT * FROM %s WHERE broadcaster_id = $1`),
+		domainClip.Fields, options, "id", broadcasterId,
+	)
+	if err != nil {
+		return []storage.Clip{}, paginate.Page{}, err
+	}
+
+	rows, err := transaction.Conn(ctx, s.DB).QueryxContext(ctx, sql, args...)
+	if err != nil {
+		log.Error(err)
+		return []storage.Clip{}, paginate.Page{}, err
+	}
+
+	clips, err := scanRows(rows)
+	if err != nil {
+		return clips, paginate.Page{}, err
+	}
+
+	return clips, keyset.Page(dialect.Of(s.DB), &clips, options, "id"), nil
+}
+
+// GetByID returns the clip with the given ID, or nil on failure.
+func (s SqlClipStorage) GetByID(ctx context.Context, id uuid.UUID) *storage.Clip {
+	row := transaction.Conn(ctx, s.DB).QueryRowxContext(ctx, insertTableName(`SELECT * from %s WHERE id = $1`), id)
+
+	var clip storage.Clip
+	err := row.StructScan(&clip)
+	if err != nil {
+		if err != sql2.ErrNoRows {
+			log.Error(err)
+		}
+		return nil
+	}
+
+	return &clip
+}
+
+// Delete removes a clip with the given ID from the table. Only returns on db error.
+func (s SqlClipStorage) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := transaction.Conn(ctx, s.DB).ExecContext(ctx, insertTableName(`DELETE FROM %s WHERE id = $1`), id)
+	if err != nil {
+		log.Errorf("SqlClipStorage::Delete: %s", err)
+	}
+
+	return err
+}
+
+// Insert takes a storage model and inserts into the db. Returns an error on failure.
+// Upon insertion the ID field of the model will be set.
+func (s SqlClipStorage) Insert(ctx context.Context, clip *storage.Clip) error {
+	clip.CreatedAt = time.Now().Truncate(time.Microsecond)
+
+	row := transaction.Conn(ctx, s.DB).QueryRowContext(
+		ctx,
+		insertTableName(`INSERT INTO %s 
+			(video_id, creator_id, broadcaster_id, source_type, source_id, start_offset, end_offset, created_at)
+			VALUES ($1,$2,$3,$4,$5,$6,$7,$8) RETURNING id`),
+		clip.VideoId, clip.CreatorId, clip.BroadcasterId, clip.SourceType, clip.SourceId,
+		clip.StartOffset, clip.EndOffset, clip.CreatedAt,
+	)
+
+	err := row.Scan(&clip.Id)
+
+	return err
+}
+
+// NewClipStorage instantiates a new SqlClipStorage object.
+func NewClipStorage(db *sqlx.DB) *SqlClipStorage {
+	newStorage := new(SqlClipStorage)
+	newStorage.DB = db
+
+	return newStorage
+}
@@ -2,7 +2,10 @@ package sql
 
 import (
 	"fmt"
+	"github.com/M-Ro/go-vodstream/storage/sql/dialect"
+	"github.com/M-Ro/go-vodstream/storage/sql/sqlite"
 	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 )
@@ -10,7 +13,8 @@ import (
 var db *sqlx.DB
 
 func getConfig() {
-
+	viper.SetDefault("database.driver", string(dialect.Postgres))
+	viper.SetDefault("sqlite.path", "vodstream.db")
 }
 
 func getPostgresDSN() string {
@@ -24,19 +28,31 @@ func getPostgresDSN() string {
 		`host=%s port=%s user=%s password=%s dbname=%s sslmode=disable`,
 		host, port, user, pass, dbname,
 	)
-
-	return fmt.Sprintf(
-		`postgres://%s:%s@%s:%s/%s?sslmode=disable`,
-		user, pass, host, port, dbname,
-	)
 }
 
+// NewDbConn returns the connection to the database selected by the config, opening it
+// on first use. SQLite databases are migrated as they are opened.
 func NewDbConn() *sqlx.DB {
 	if db != nil {
 		return db
 	}
 
-	newDb, err := sqlx.Open("postgres", getPostgresDSN())
+	getConfig()
+
+	var (
+		newDb *sqlx.DB
+		err   error
+	)
+
+	switch driver := viper.GetString("database.driver"); dialect.Dialect(driver) {
+	case dialect.Postgres:
+		newDb, err = sqlx.Open("postgres", getPostgresDSN())
+	case dialect.SQLite:
+		newDb, err = sqlite.Open(viper.GetString("sqlite.path"))
+	default:
+		err = fmt.Errorf("unknown database driver %q, expected postgres or sqlite", driver)
+	}
+
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,46 @@
+// Package dialect writes the SQL that differs between the databases the storages
+// run on. Both take $n placeholders and RETURNING clauses as written.
+package dialect
+
+import (
+	"fmt"
+	"github.com/jmoiron/sqlx"
+)
+
+// Dialect is the SQL spoken by a database, named as its driver is registered.
+type Dialect string
+
+const (
+	Postgres Dialect = "postgres"
+	SQLite   Dialect = "sqlite"
+)
+
+// Of returns the dialect of the database, by the name of its driver.
+func Of(db *sqlx.DB) Dialect {
+	if db != nil && db.DriverName() == string(SQLite) {
+		return SQLite
+	}
+
+	return Postgres
+}
+
+// ILike returns the condition matching the column against the case insensitive LIKE
+// pattern bound to placeholder, where a backslash escapes % and _. SQLite folds the
+// case of ASCII letters only.
+func (d Dialect) ILike(column string, placeholder string) string {
+	if d == SQLite {
+		return fmt.Sprintf(`%s LIKE %s ESCAPE '\'`, column, placeholder)
+	}
+
+	return fmt.Sprintf("%s ILIKE %s", column, placeholder)
+}
+
+// TimestampLayout returns the layout of timestamps as text the database compares with
+// those it stores. SQLite stores timestamps as text, written by the driver with their zone.
+func (d Dialect) TimestampLayout() string {
+	if d == SQLite {
+		return "2006-01-02 15:04:05.999999999-07:00"
+	}
+
+	return "2006-01-02 15:04:05.999999999"
+}
@@ -0,0 +1,199 @@
+package keyset
+
+import (
+	"fmt"
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	"github.com/M-Ro/go-vodstream/storage/sql/dialect"
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// mapper finds the fields of storage models by column name, as sqlx scans them.
+var mapper = reflectx.NewMapperFunc("db", sqlx.NameMapper)
+
+// Query wraps a query selecting rows to select the page of them specified by the given options,
+// with one row more to tell whether there are more. Rows are filtered and ordered as given by the
+// options, ties broken by the unique tieBreaker field in the direction of the first order. From a
+// cursor, rows are selected by comparing their order keys with it, rather than by offset. args
+// are those bound within the query, to which those bound by the page are appended. The options
+// are checked against the schema of the rows first, as their fields are written into the query.
+// The query is written in the given dialect.
+func Query(
+	d dialect.Dialect,
+	query string,
+	schema paginate.Schema,
+	options paginate.QueryOptions,
+	tieBreaker string,
+	args ...interface{},
+) (string, []interface{}, error) {
+	if err := schema.Validate(options); err != nil {
+		return "", nil, err
+	}
+
+	orders := options.Orders()
+	orders = append(orders, paginate.Order{Field: tieBreaker, Method: options.Order.Method})
+
+	if options.Cursor != nil && options.Cursor.Backward {
+		for i := range orders {
+			orders[i].Method = reverse(orders[i].Method)
+		}
+	}
+
+	conditions := make([]string, 0, len(options.Filters)+1)
+	for _, filter := range options.Filters {
+		var condition string
+		condition, args = filterCondition(d, filter, args)
+		conditions = append(conditions, condition)
+	}
+
+	offset := options.Offset
+	if options.Cursor != nil {
+		values := append(append([]string{options.Cursor.Value}, options.Cursor.Then...), options.Cursor.Id)
+
+		var condition string
+		condition, args = cursorCondition(orders, values, args)
+		conditions = append(conditions, condition)
+		offset = 0
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	orderBy := make([]string, 0, len(orders))
+	for _, order := range orders {
+		orderBy = append(orderBy, fmt.Sprintf("%s %s", order.Field, order.Method))
+	}
+
+	sql := fmt.Sprintf(
+		`SELECT * FROM (%s) AS page%s ORDER BY %s LIMIT %d OFFSET %d`,
+		query, where, strings.Join(orderBy, ", "), options.Limit+1, offset,
+	)
+
+	return sql, args, nil
+}
+
+// filterCondition returns the condition selecting rows matching the filter, binding its values
+// after args.
+func filterCondition(d dialect.Dialect, filter paginate.Filter, args []interface{}) (string, []interface{}) {
+	switch filter.Operator {
+	case paginate.OperatorIn:
+		placeholders := make([]string, 0, len(filter.Values))
+		for _, value := range filter.Values {
+			args = append(args, value)
+			placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+		}
+
+		return fmt.Sprintf("%s IN (%s)", filter.Field, strings.Join(placeholders, ", ")), args
+	case paginate.OperatorRange:
+		bounds := make([]string, 0, 2)
+		for i, compare := range []string{">=", "<="} {
+			if filter.Values[i] == nil {
+				continue
+			}
+
+			args = append(args, filter.Values[i])
+			bounds = append(bounds, fmt.Sprintf("%s %s $%d", filter.Field, compare, len(args)))
+		}
+
+		return strings.Join(bounds, " AND "), args
+	case paginate.OperatorILike:
+		args = append(args, filter.Values[0])
+		return d.ILike(filter.Field, fmt.Sprintf("$%d", len(args))), args
+	default:
+		args = append(args, filter.Values[0])
+		return fmt.Sprintf("%s = $%d", filter.Field, len(args)), args
+	}
+}
+
+// cursorCondition returns the condition selecting rows after the cursor values of each order,
+// binding them after args. Rows ordered in a single direction compare their order keys as a
+// whole, which indexes serve, otherwise they are compared order by order.
+func cursorCondition(orders []paginate.Order, values []string, args []interface{}) (string, []interface{}) {
+	fields := make([]string, 0, len(orders))
+	placeholders := make([]string, 0, len(orders))
+	mixed := false
+
+	for i, order := range orders {
+		args = append(args, values[i])
+		fields = append(fields, order.Field)
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+		mixed = mixed || order.Method != orders[0].Method
+	}
+
+	if !mixed {
+		return fmt.Sprintf(
+			`(%s) %s (%s)`,
+			strings.Join(fields, ", "), compare(orders[0].Method), strings.Join(placeholders, ", "),
+		), args
+	}
+
+	alternatives := make([]string, 0, len(orders))
+	for i, order := range orders {
+		terms := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			terms = append(terms, fmt.Sprintf("%s = %s", fields[j], placeholders[j]))
+		}
+		terms = append(terms, fmt.Sprintf("%s %s %s", fields[i], compare(order.Method), placeholders[i]))
+
+		alternatives = append(alternatives, "("+strings.Join(terms, " AND ")+")")
+	}
+
+	return "(" + strings.Join(alternatives, " OR ") + ")", args
+}
+
+// compare returns the operator selecting rows after a value in the given order.
+func compare(method paginate.OrderMethod) string {
+	if method == paginate.OrderMethodDesc {
+		return "<"
+	}
+
+	return ">"
+}
+
+// Page completes a page of rows selected by Query in the given dialect, pointed to by rows,
+// returning the cursors of the pages either side of it.
+func Page(d dialect.Dialect, rows interface{}, options paginate.QueryOptions, tieBreaker string) paginate.Page {
+	slice := reflect.ValueOf(rows).Elem()
+
+	return paginate.Paginate(rows, options, func(i int) ([]string, string) {
+		row := slice.Index(i)
+
+		values := make([]string, 0, len(options.ThenBy)+1)
+		for _, order := range options.Orders() {
+			values = append(values, key(d, mapper.FieldByName(row, order.Field)))
+		}
+
+		return values, key(d, mapper.FieldByName(row, tieBreaker))
+	})
+}
+
+// reverse returns the opposite order method.
+func reverse(method paginate.OrderMethod) paginate.OrderMethod {
+	if method == paginate.OrderMethodDesc {
+		return paginate.OrderMethodAsc
+	}
+
+	return paginate.OrderMethodDesc
+}
+
+// key formats the value of a field for a cursor, as text the database of the dialect reads
+// back as the type of the field it is compared with.
+func key(d dialect.Dialect, field reflect.Value) string {
+	if !field.IsValid() {
+		return ""
+	}
+
+	switch value := field.Interface().(type) {
+	case time.Time:
+		return value.Format(d.TimestampLayout())
+	case fmt.Stringer:
+		return value.String()
+	default:
+		return fmt.Sprint(value)
+	}
+}
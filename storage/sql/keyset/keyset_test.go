@@ -0,0 +1,240 @@
+package keyset
+
+import (
+	"errors"
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	"github.com/M-Ro/go-vodstream/storage/sql/dialect"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"testing"
+	"time"
+)
+
+type keysetRow struct {
+	Id          uuid.UUID `db:"id"`
+	PublishedAt time.Time `db:"published_at"`
+}
+
+var keysetSchema = paginate.Schema{
+	"id":           {Kind: paginate.KindUUID, Sortable: true, Filterable: true},
+	"title":        {Kind: paginate.KindString, Sortable: true, Filterable: true},
+	"category":     {Kind: paginate.KindString, Filterable: true},
+	"viewer_count": {Kind: paginate.KindInt, Sortable: true, Filterable: true},
+	"published_at": {Kind: paginate.KindTime, Sortable: true, Filterable: true},
+}
+
+func TestQuery(t *testing.T) {
+	tests := []struct {
+		testName string
+		options  paginate.QueryOptions
+
+		expectedSql  string
+		expectedArgs []interface{}
+	}{
+		{
+			testName: "Expect offset paging to fetch a row more than the limit.",
+			options:  paginate.NewPaginateOptions(paginate.WithLimit(10), paginate.WithOffset(20)),
+			expectedSql: `SELECT * FROM (SELECT * FROM videos WHERE broadcaster_id = $1) AS page ` +
+				`ORDER BY id ASC, id ASC LIMIT 11 OFFSET 20`,
+			expectedArgs: []interface{}{uint64(1)},
+		},
+		{
+			testName: "Expect paging forward from a cursor to compare order keys, ignoring the offset.",
+			options: paginate.NewPaginateOptions(
+				paginate.WithOrderField("published_at"),
+				paginate.WithOrder(paginate.OrderMethodDesc),
+				paginate.WithOffset(20),
+				paginate.WithCursor(paginate.Cursor{Field: "published_at", Value: "2026-10-18 20:00:00", Id: "a"}),
+			),
+			expectedSql: `SELECT * FROM (SELECT * FROM videos WHERE broadcaster_id = $1) AS page ` +
+				`WHERE (published_at, id) < ($2, $3) ORDER BY published_at DESC, id DESC LIMIT 26 OFFSET 0`,
+			expectedArgs: []interface{}{uint64(1), "2026-10-18 20:00:00", "a"},
+		},
+		{
+			testName: "Expect paging backward from a cursor to reverse the order.",
+			options: paginate.NewPaginateOptions(
+				paginate.WithOrderField("published_at"),
+				paginate.WithOrder(paginate.OrderMethodDesc),
+				paginate.WithCursor(paginate.Cursor{
+					Field: "published_at", Value: "2026-10-18 20:00:00", Id: "a", Backward: true,
+				}),
+			),
+			expectedSql: `SELECT * FROM (SELECT * FROM videos WHERE broadcaster_id = $1) AS page ` +
+				`WHERE (published_at, id) > ($2, $3) ORDER BY published_at ASC, id ASC LIMIT 26 OFFSET 0`,
+			expectedArgs: []interface{}{uint64(1), "2026-10-18 20:00:00", "a"},
+		},
+		{
+			testName: "Expect filters bound as parameters.",
+			options: paginate.NewPaginateOptions(paginate.WithFilters(
+				paginate.Equals("title", "any%"),
+				paginate.In("category", "speedruns", "retro"),
+				paginate.Range("viewer_count", int64(10), nil),
+				paginate.ILike("title", "%any%"),
+			)),
+			expectedSql: `SELECT * FROM (SELECT * FROM videos WHERE broadcaster_id = $1) AS page ` +
+				`WHERE title = $2 AND category IN ($3, $4) AND viewer_count >= $5 AND title ILIKE $6 ` +
+				`ORDER BY id ASC, id ASC LIMIT 26 OFFSET 0`,
+			expectedArgs: []interface{}{uint64(1), "any%", "speedruns", "retro", int64(10), "%any%"},
+		},
+		{
+			testName: "Expect paging from a cursor over orders in either direction to compare order by order.",
+			options: paginate.NewPaginateOptions(
+				paginate.WithSort(
+					paginate.Order{Field: "viewer_count", Method: paginate.OrderMethodDesc},
+					paginate.Order{Field: "title", Method: paginate.OrderMethodAsc},
+				),
+				paginate.WithFilters(paginate.Range("viewer_count", nil, int64(100))),
+				paginate.WithCursor(paginate.Cursor{
+					Field: "viewer_count,title", Value: "20", Then: []string{"b"}, Id: "a",
+				}),
+			),
+			expectedSql: `SELECT * FROM (SELECT * FROM videos WHERE broadcaster_id = $1) AS page ` +
+				`WHERE viewer_count <= $2 AND ((viewer_count < $3) OR (viewer_count = $3 AND title > $4) ` +
+				`OR (viewer_count = $3 AND title = $4 AND id < $5)) ` +
+				`ORDER BY viewer_count DESC, title ASC, id DESC LIMIT 26 OFFSET 0`,
+			expectedArgs: []interface{}{uint64(1), int64(100), "20", "b", "a"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			sql, args, err := Query(
+				dialect.Postgres,
+				`SELECT * FROM videos WHERE broadcaster_id = $1`, keysetSchema, test.options, "id", uint64(1),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !cmp.Equal(sql, test.expectedSql) {
+				t.Fatal(cmp.Diff(sql, test.expectedSql))
+			}
+
+			if !cmp.Equal(args, test.expectedArgs) {
+				t.Fatal(cmp.Diff(args, test.expectedArgs))
+			}
+		})
+	}
+}
+
+func TestQuery_Invalid(t *testing.T) {
+	tests := []struct {
+		testName string
+		options  paginate.QueryOptions
+
+		expectedErr error
+	}{
+		{
+			testName:    "Expect error ordering by a field missing from the schema.",
+			options:     paginate.NewPaginateOptions(paginate.WithOrderField("id; DROP TABLE videos")),
+			expectedErr: paginate.ErrInvalidSort,
+		},
+		{
+			testName:    "Expect error ordering by a field that is not sortable.",
+			options:     paginate.NewPaginateOptions(paginate.WithOrderField("category")),
+			expectedErr: paginate.ErrInvalidSort,
+		},
+		{
+			testName: "Expect error ordering in an unknown direction.",
+			options: paginate.NewPaginateOptions(
+				paginate.WithSort(paginate.Order{Field: "title", Method: "ASC; DROP TABLE videos"}),
+			),
+			expectedErr: paginate.ErrInvalidSort,
+		},
+		{
+			testName:    "Expect error filtering by a field missing from the schema.",
+			options:     paginate.NewPaginateOptions(paginate.WithFilters(paginate.Equals("file_path", "a"))),
+			expectedErr: paginate.ErrInvalidFilter,
+		},
+		{
+			testName:    "Expect error filtering with an operator the field does not support.",
+			options:     paginate.NewPaginateOptions(paginate.WithFilters(paginate.ILike("viewer_count", "1%"))),
+			expectedErr: paginate.ErrInvalidFilter,
+		},
+		{
+			testName:    "Expect error filtering with a value of another kind.",
+			options:     paginate.NewPaginateOptions(paginate.WithFilters(paginate.Equals("viewer_count", "10"))),
+			expectedErr: paginate.ErrInvalidFilter,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			_, _, err := Query(dialect.Postgres, `SELECT * FROM videos`, keysetSchema, test.options, "id")
+			if !errors.Is(err, test.expectedErr) {
+				t.Fatalf("expected %v, got %v", test.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestQuery_SQLite(t *testing.T) {
+	options := paginate.NewPaginateOptions(paginate.WithFilters(paginate.ILike("title", "%any%")))
+
+	sql, args, err := Query(dialect.SQLite, `SELECT * FROM videos`, keysetSchema, options, "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedSql := `SELECT * FROM (SELECT * FROM videos) AS page WHERE title LIKE $1 ESCAPE '\' ` +
+		`ORDER BY id ASC, id ASC LIMIT 26 OFFSET 0`
+	if !cmp.Equal(sql, expectedSql) {
+		t.Fatal(cmp.Diff(sql, expectedSql))
+	}
+
+	if !cmp.Equal(args, []interface{}{"%any%"}) {
+		t.Fatal(cmp.Diff(args, []interface{}{"%any%"}))
+	}
+}
+
+func TestPage(t *testing.T) {
+	first := keysetRow{
+		Id:          uuid.MustParse("6f1c9a52-8d3e-4b7a-a1c2-5e4f3d2c1b01"),
+		PublishedAt: time.Date(2026, 10, 18, 20, 0, 0, 500, time.UTC),
+	}
+	second := keysetRow{
+		Id:          uuid.MustParse("6f1c9a52-8d3e-4b7a-a1c2-5e4f3d2c1b02"),
+		PublishedAt: time.Date(2026, 10, 18, 19, 0, 0, 0, time.UTC),
+	}
+
+	rows := []keysetRow{first, second}
+	options := paginate.NewPaginateOptions(paginate.WithLimit(1), paginate.WithOrderField("published_at"))
+
+	page := Page(dialect.Postgres, &rows, options, "id")
+
+	if !cmp.Equal(rows, []keysetRow{first}) {
+		t.Fatal(cmp.Diff(rows, []keysetRow{first}))
+	}
+
+	expected := paginate.Page{
+		Next: paginate.Cursor{
+			Field: "published_at", Value: "2026-10-18 20:00:00.0000005", Id: first.Id.String(),
+		}.Encode(),
+	}
+
+	if !cmp.Equal(page, expected) {
+		t.Fatal(cmp.Diff(page, expected))
+	}
+}
+
+func TestPage_SQLite(t *testing.T) {
+	row := keysetRow{
+		Id:          uuid.MustParse("6f1c9a52-8d3e-4b7a-a1c2-5e4f3d2c1b01"),
+		PublishedAt: time.Date(2026, 10, 18, 20, 0, 0, 500, time.FixedZone("", 2*60*60)),
+	}
+
+	rows := []keysetRow{row, row}
+	options := paginate.NewPaginateOptions(paginate.WithLimit(1), paginate.WithOrderField("published_at"))
+
+	page := Page(dialect.SQLite, &rows, options, "id")
+
+	expected := paginate.Page{
+		Next: paginate.Cursor{
+			Field: "published_at", Value: "2026-10-18 20:00:00.0000005+02:00", Id: row.Id.String(),
+		}.Encode(),
+	}
+
+	if !cmp.Equal(page, expected) {
+		t.Fatal(cmp.Diff(page, expected))
+	}
+}
@@ -0,0 +1,137 @@
+package live_channel
+
+import (
+	"context"
+	sql2 "database/sql"
+	"errors"
+	"fmt"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/M-Ro/go-vodstream/storage/sql/transaction"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+const LiveChannelsTableName = "live_channels"
+
+type SqlLiveChannelStorage struct {
+	DB *sqlx.DB
+}
+
+var (
+	ErrNoRowsAffected = errors.New("no row found with name")
+)
+
+// insertTableName is a helper function to insert the dynamic LiveChannelsTableName property
+// as bindvars cannot be used as identifiers.
+func insertTableName(query string) string {
+	return fmt.Sprintf(query, LiveChannelsTableName)
+}
+
+// GetByName returns the live channel with the given name whose heartbeat is no
+// older than staleBefore, or nil on failure.
+func (s SqlLiveChannelStorage) GetByName(ctx context.Context, name string, staleBefore time.Time) *storage.LiveChannel {
+	row := transaction.Conn(ctx, s.DB).QueryRowxContext(
+		ctx, insertTableName(`SELECT * FROM %s WHERE name = $1 AND heartbeat_at >= $2`), name, staleBefore,
+	)
+
+	var liveChannel storage.LiveChannel
+	err := row.StructScan(&liveChannel)
+	if err != nil {
+		if err != sql2.ErrNoRows {
+			log.Error(err)
+		}
+		return nil
+	}
+
+	return &liveChannel
+}
+
+// Claim inserts the live channel, taking over an existing row for the same name
+// only if its heartbeat is older than staleBefore. Returns ErrNoRowsAffected if
+// the channel is held by another live node.
+func (s SqlLiveChannelStorage) Claim(ctx context.Context, liveChannel *storage.LiveChannel, staleBefore time.Time) error {
+	liveChannel.HeartbeatAt = time.Now().Truncate(time.Microsecond)
+
+	result, err := transaction.Conn(ctx, s.DB).ExecContext(
+		ctx,
+		insertTableName(`INSERT INTO %s
+			(name, node_address, broadcaster_id, visibility, low_latency, started_at, heartbeat_at)
+			VALUES ($1,$2,$3,$4,$5,$6,$7)
+			ON CONFLICT (name) DO UPDATE SET
+			node_address=EXCLUDED.node_address, broadcaster_id=EXCLUDED.broadcaster_id,
+			visibility=EXCLUDED.visibility, low_latency=EXCLUDED.low_latency,
+			started_at=EXCLUDED.started_at, heartbeat_at=EXCLUDED.heartbeat_at
+			WHERE %[1]s.heartbeat_at < $8`),
+		liveChannel.Name, liveChannel.NodeAddress, liveChannel.BroadcasterId, liveChannel.Visibility,
+		liveChannel.LowLatency, liveChannel.StartedAt, liveChannel.HeartbeatAt, staleBefore,
+	)
+
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	return checkRowsAffected(result)
+}
+
+// Release removes the live channel with the given name if it is held by the given node.
+// Only returns on db error.
+func (s SqlLiveChannelStorage) Release(ctx context.Context, name string, nodeAddress string) error {
+	_, err := transaction.Conn(ctx, s.DB).ExecContext(
+		ctx, insertTableName(`DELETE FROM %s WHERE name = $1 AND node_address = $2`), name, nodeAddress,
+	)
+	if err != nil {
+		log.Errorf("SqlLiveChannelStorage::Release: %s", err)
+	}
+
+	return err
+}
+
+// ReleaseNode removes every live channel held by the given node. Only returns on db error.
+func (s SqlLiveChannelStorage) ReleaseNode(ctx context.Context, nodeAddress string) error {
+	_, err := transaction.Conn(ctx, s.DB).ExecContext(
+		ctx, insertTableName(`DELETE FROM %s WHERE node_address = $1`), nodeAddress,
+	)
+	if err != nil {
+		log.Errorf("SqlLiveChannelStorage::ReleaseNode: %s", err)
+	}
+
+	return err
+}
+
+// Heartbeat refreshes the heartbeat of every live channel held by the given node.
+func (s SqlLiveChannelStorage) Heartbeat(ctx context.Context, nodeAddress string) error {
+	_, err := transaction.Conn(ctx, s.DB).ExecContext(
+		ctx, insertTableName(`UPDATE %s SET heartbeat_at = $1 WHERE node_address = $2`),
+		time.Now().Truncate(time.Microsecond), nodeAddress,
+	)
+	if err != nil {
+		log.Errorf("SqlLiveChannelStorage::Heartbeat: %s", err)
+	}
+
+	return err
+}
+
+// checkRowsAffected returns ErrNoRowsAffected unless exactly one row was changed.
+func checkRowsAffected(result sql2.Result) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	if rows != 1 {
+		return ErrNoRowsAffected
+	}
+
+	return nil
+}
+
+// NewLiveChannelStorage instantiates a new SqlLiveChannelStorage object.
+func NewLiveChannelStorage(db *sqlx.DB) *SqlLiveChannelStorage {
+	newStorage := new(SqlLiveChannelStorage)
+	newStorage.DB = db
+
+	return newStorage
+}
@@ -5,17 +5,26 @@ import (
 	"embed"
 	"errors"
 	"fmt"
+	"github.com/M-Ro/go-vodstream/storage/sql/dialect"
 	"github.com/jmoiron/sqlx"
 	"github.com/spf13/afero"
+	"io/fs"
 	"log"
 	"os"
 	"regexp"
 )
 
 // MigrationFS contains all the SQL migration files.
-//go:embed migrations/*.sql
+//go:embed migrations/*.sql migrations/sqlite/*.sql
 var MigrationFS embed.FS
 
+// migrationDirs are the directories of MigrationFS holding the migrations written in
+// each dialect. SQLite has its own set, creating the same tables.
+var migrationDirs = map[dialect.Dialect]string{
+	dialect.Postgres: "migrations",
+	dialect.SQLite:   "migrations/sqlite",
+}
+
 var (
 	ErrHalfMigration = errors.New("migration is missing up/down component")
 )
@@ -37,21 +46,31 @@ const (
 )
 
 func (m Migration) Up(tx *sqlx.Tx) error {
-	// STUB
-	return nil
+	_, err := tx.Exec(m.UpQuery)
+	return err
 }
 
 func (m Migration) Down(tx *sqlx.Tx) error {
-	// STUB
-	return nil
+	_, err := tx.Exec(m.DownQuery)
+	return err
+}
+
+// Dir returns the directory of MigrationFS holding the migrations written in the given dialect.
+func Dir(d dialect.Dialect) string {
+	return migrationDirs[d]
 }
 
 func createMigrationTable(db *sqlx.DB) {
+	id := "SERIAL PRIMARY KEY"
+	if dialect.Of(db) == dialect.SQLite {
+		id = "INTEGER PRIMARY KEY AUTOINCREMENT"
+	}
+
 	sql := fmt.Sprintf(`CREATE TABLE %s (
-				id          SERIAL   PRIMARY KEY,
+				id          %s,
 				migration   TEXT     NOT NULL,
 				batch       INTEGER  NOT NULL
-		)`, migrationsTableName)
+		)`, migrationsTableName, id)
 
 	db.MustExec(sql)
 }
@@ -207,6 +226,10 @@ func migrationTableExists(db *sqlx.DB) bool {
 			tablename  = $1
 		)`
 
+	if dialect.Of(db) == dialect.SQLite {
+		sql = `SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = $1)`
+	}
+
 	row := db.QueryRow(sql, migrationsTableName)
 
 	var exists bool
@@ -221,3 +244,91 @@ func migrationTableExists(db *sqlx.DB) bool {
 func Run(db *sqlx.DB, tableName string) {
 	// STUB
 }
+
+// Up runs the up migrations of the given tables, or of every table if none are given, which
+// have not yet been run upon db, from the migrations written in its dialect. Tables are
+// migrated in the order given, or of name, each running its migrations in order, and the
+// migrations run are recorded in the migrations table as a single batch.
+func Up(db *sqlx.DB, tables ...string) error {
+	dir, err := fs.Sub(MigrationFS, Dir(dialect.Of(db)))
+	if err != nil {
+		return err
+	}
+
+	migrationFs := afero.FromIOFS{FS: dir}
+	files, err := afero.ReadDir(migrationFs, ".")
+	if err != nil {
+		return err
+	}
+
+	if len(tables) == 0 {
+		tables = tableNames(files)
+	}
+
+	migrations := make([]Migration, 0)
+	for _, tableName := range tables {
+		tableMigrations, err := getMigrationsForTable(migrationFs, files, tableName)
+		if err != nil {
+			return err
+		}
+
+		migrations = append(migrations, tableMigrations...)
+	}
+
+	if !migrationTableExists(db) {
+		createMigrationTable(db)
+	}
+
+	batch := lastBatchNumber(db) + 1
+	for _, migration := range migrations {
+		if !shouldRunMigration(db, migration.MigrationName) {
+			continue
+		}
+
+		if err := runUp(db, migration, batch); err != nil {
+			return fmt.Errorf("%s: %w", migration.MigrationName, err)
+		}
+	}
+
+	return nil
+}
+
+// runUp runs the up migration within a transaction, recording it in the given batch.
+func runUp(db *sqlx.DB, migration Migration, batch int) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	if err := migration.Up(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	sql := fmt.Sprintf(`INSERT INTO %s (migration, batch) VALUES ($1, $2)`, migrationsTableName)
+	if _, err := tx.Exec(sql, migration.MigrationName, batch); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// tableNames returns the names of the tables the migration files are of, in order of name.
+func tableNames(files []os.FileInfo) []string {
+	pattern := regexp.MustCompile(`^(.+?)_\d{12}_.+_(down|up)\.sql$`)
+
+	names := make([]string, 0)
+	for _, fileinfo := range files {
+		match := pattern.FindStringSubmatch(fileinfo.Name())
+		if fileinfo.IsDir() || match == nil {
+			continue
+		}
+
+		if len(names) == 0 || names[len(names)-1] != match[1] {
+			names = append(names, match[1])
+		}
+	}
+
+	return names
+}
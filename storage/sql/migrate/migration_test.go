@@ -288,6 +288,66 @@ func TestMigration_getMigrationsForTable(t *testing.T) {
 	}
 }
 
+func TestMigration_tableNames(t *testing.T) {
+	tests := []struct {
+		testName    string
+		dirContents []string
+
+		expectedReturn []string
+	}{
+		{
+			testName:    "expect nothing with empty directory",
+			dirContents: []string{},
+
+			expectedReturn: []string{},
+		},
+		{
+			testName: "expect each table once, in order of name",
+			dirContents: []string{
+				"video_edits_202205132200_create_table_down.sql",
+				"video_edits_202205132200_create_table_up.sql",
+				"videos_202205132200_create_table_down.sql",
+				"videos_202205132200_create_table_up.sql",
+				"videos_202205132300_add_title_down.sql",
+				"videos_202205132300_add_title_up.sql",
+			},
+
+			expectedReturn: []string{"video_edits", "videos"},
+		},
+		{
+			testName: "expect nothing with various invalid files",
+			dirContents: []string{
+				"videos_create_table_up.sql",
+				"videos_202205132200_create_table.sql",
+				"README.md",
+			},
+
+			expectedReturn: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			// Create mock filesystem
+			var fs = afero.NewMemMapFs()
+			for _, name := range test.dirContents {
+				afero.WriteFile(fs, name, []byte("sql here"), 0755)
+			}
+
+			files, err := afero.ReadDir(fs, ".")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			names := tableNames(files)
+
+			if !cmp.Equal(names, test.expectedReturn) {
+				t.Fatal(cmp.Diff(names, test.expectedReturn))
+			}
+		})
+	}
+}
+
 func TestMigration_createMigrationTable(t *testing.T) {
 	createMigrationTable(db)
 
@@ -0,0 +1 @@
+DROP TABLE broadcast_vods;
\ No newline at end of file
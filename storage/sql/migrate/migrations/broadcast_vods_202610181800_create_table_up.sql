@@ -0,0 +1,11 @@
+CREATE TABLE broadcast_vods (
+    id           UUID      PRIMARY KEY DEFAULT gen_random_uuid(),
+    stream_id    UUID      NOT NULL,
+    video_id     UUID      NOT NULL REFERENCES videos (id) ON DELETE CASCADE,
+    published_at TIMESTAMP,
+    created_at   TIMESTAMP,
+    updated_at   TIMESTAMP
+);
+
+CREATE INDEX broadcast_vods_stream_id_idx ON broadcast_vods (stream_id);
+CREATE INDEX broadcast_vods_video_id_idx ON broadcast_vods (video_id);
@@ -0,0 +1 @@
+DROP TABLE broadcasts;
\ No newline at end of file
@@ -0,0 +1,13 @@
+CREATE TABLE broadcasts (
+    id             UUID      PRIMARY KEY DEFAULT gen_random_uuid(),
+    broadcaster_id BIGINT    NOT NULL,
+    title          TEXT      NOT NULL DEFAULT '',
+    is_active      BOOL      NOT NULL DEFAULT false,
+    is_published   BOOL      NOT NULL DEFAULT true,
+    published_at   TIMESTAMP,
+    created_at     TIMESTAMP,
+    updated_at     TIMESTAMP
+);
+
+CREATE INDEX broadcasts_broadcaster_id_idx ON broadcasts (broadcaster_id);
+CREATE INDEX broadcasts_is_active_idx ON broadcasts (is_active) WHERE is_active;
@@ -0,0 +1,5 @@
+ALTER TABLE broadcasts
+    DROP COLUMN category,
+    DROP COLUMN tags,
+    DROP COLUMN language,
+    DROP COLUMN mature;
\ No newline at end of file
@@ -0,0 +1,7 @@
+ALTER TABLE broadcasts
+    ADD COLUMN category TEXT   NOT NULL DEFAULT '',
+    ADD COLUMN tags     TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN language TEXT   NOT NULL DEFAULT '',
+    ADD COLUMN mature   BOOL   NOT NULL DEFAULT false;
+
+CREATE INDEX broadcasts_category_idx ON broadcasts (category);
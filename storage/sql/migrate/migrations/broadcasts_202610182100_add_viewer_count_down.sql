@@ -0,0 +1 @@
+ALTER TABLE broadcasts DROP COLUMN viewer_count;
\ No newline at end of file
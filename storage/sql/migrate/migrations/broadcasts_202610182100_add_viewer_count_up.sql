@@ -0,0 +1,3 @@
+ALTER TABLE broadcasts ADD COLUMN viewer_count BIGINT NOT NULL DEFAULT 0;
+
+CREATE INDEX broadcasts_live_category_idx ON broadcasts (category, viewer_count) WHERE is_active AND is_published;
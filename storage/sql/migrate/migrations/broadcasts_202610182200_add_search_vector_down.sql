@@ -0,0 +1 @@
+ALTER TABLE broadcasts DROP COLUMN search_vector;
\ No newline at end of file
@@ -0,0 +1,7 @@
+ALTER TABLE broadcasts ADD COLUMN search_vector TSVECTOR
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', title), 'A') ||
+        setweight(array_to_tsvector(tags), 'B')
+    ) STORED;
+
+CREATE INDEX broadcasts_search_vector_idx ON broadcasts USING GIN (search_vector) WHERE is_published;
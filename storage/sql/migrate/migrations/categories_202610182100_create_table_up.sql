@@ -0,0 +1,8 @@
+CREATE TABLE categories (
+    slug        TEXT      PRIMARY KEY,
+    name        TEXT      NOT NULL,
+    description TEXT      NOT NULL DEFAULT '',
+    image_url   TEXT      NOT NULL DEFAULT '',
+    created_at  TIMESTAMP,
+    updated_at  TIMESTAMP
+);
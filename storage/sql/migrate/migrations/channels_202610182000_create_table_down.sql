@@ -0,0 +1 @@
+DROP TABLE channels;
\ No newline at end of file
@@ -0,0 +1,16 @@
+CREATE TABLE channels (
+    broadcaster_id BIGINT    PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    display_name   TEXT      NOT NULL DEFAULT '',
+    description    TEXT      NOT NULL DEFAULT '',
+    avatar_url     TEXT      NOT NULL DEFAULT '',
+    banner_url     TEXT      NOT NULL DEFAULT '',
+    default_title  TEXT      NOT NULL DEFAULT '',
+    category       TEXT      NOT NULL DEFAULT '',
+    tags           TEXT[]    NOT NULL DEFAULT '{}',
+    language       TEXT      NOT NULL DEFAULT '',
+    mature         BOOL      NOT NULL DEFAULT false,
+    created_at     TIMESTAMP,
+    updated_at     TIMESTAMP
+);
+
+CREATE INDEX channels_category_idx ON channels (category);
@@ -0,0 +1 @@
+ALTER TABLE channels DROP COLUMN search_vector;
\ No newline at end of file
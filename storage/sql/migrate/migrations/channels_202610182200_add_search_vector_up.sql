@@ -0,0 +1,8 @@
+ALTER TABLE channels ADD COLUMN search_vector TSVECTOR
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', display_name), 'A') ||
+        setweight(array_to_tsvector(tags), 'B') ||
+        setweight(to_tsvector('simple', description), 'C')
+    ) STORED;
+
+CREATE INDEX channels_search_vector_idx ON channels USING GIN (search_vector);
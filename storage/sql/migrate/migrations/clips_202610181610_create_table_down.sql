@@ -0,0 +1 @@
+DROP TABLE clips;
\ No newline at end of file
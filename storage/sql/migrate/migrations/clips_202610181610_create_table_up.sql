@@ -0,0 +1,13 @@
+CREATE TABLE clips (
+    id             UUID      PRIMARY KEY DEFAULT gen_random_uuid(),
+    video_id       UUID      NOT NULL REFERENCES videos (id) ON DELETE CASCADE,
+    creator_id     BIGINT    NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    broadcaster_id BIGINT    NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    source_type    TEXT      NOT NULL,
+    source_id      UUID      NOT NULL,
+    start_offset   BIGINT    NOT NULL,
+    end_offset     BIGINT    NOT NULL,
+    created_at     TIMESTAMP
+);
+
+CREATE INDEX clips_broadcaster_id_idx ON clips (broadcaster_id);
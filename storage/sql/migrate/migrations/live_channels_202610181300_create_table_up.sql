@@ -0,0 +1,10 @@
+CREATE TABLE live_channels (
+    name           TEXT      PRIMARY KEY,
+    node_address   TEXT      NOT NULL,
+    broadcaster_id BIGINT    NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    visibility     TEXT      NOT NULL DEFAULT 'public',
+    started_at     TIMESTAMP NOT NULL,
+    heartbeat_at   TIMESTAMP NOT NULL
+);
+
+CREATE INDEX live_channels_node_address_idx ON live_channels (node_address);
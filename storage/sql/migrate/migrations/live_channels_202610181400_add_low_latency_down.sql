@@ -0,0 +1 @@
+ALTER TABLE live_channels DROP COLUMN low_latency;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE live_channels ADD COLUMN low_latency BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1 @@
+DROP TABLE relay_targets;
\ No newline at end of file
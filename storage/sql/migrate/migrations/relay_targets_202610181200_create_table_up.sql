@@ -0,0 +1,15 @@
+CREATE TABLE relay_targets (
+    id                UUID        PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id           BIGINT      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name              TEXT        NOT NULL,
+    url               TEXT        NOT NULL,
+    stream_key        TEXT        NOT NULL,
+    enabled           BOOL        NOT NULL DEFAULT TRUE,
+    status            TEXT        NOT NULL DEFAULT 'idle',
+    last_error        TEXT        NOT NULL DEFAULT '',
+    status_updated_at TIMESTAMP,
+    created_at        TIMESTAMP,
+    updated_at        TIMESTAMP
+);
+
+CREATE INDEX relay_targets_user_id_idx ON relay_targets (user_id);
@@ -0,0 +1 @@
+DROP TABLE broadcast_vods;
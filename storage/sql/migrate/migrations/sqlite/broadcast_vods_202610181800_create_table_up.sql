@@ -0,0 +1,16 @@
+CREATE TABLE broadcast_vods (
+    id           TEXT      PRIMARY KEY DEFAULT (
+        -- a random (version 4) UUID, as gen_random_uuid()
+        lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' ||
+        substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + (random() & 3), 1) ||
+        substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))
+    ),
+    stream_id    TEXT      NOT NULL,
+    video_id     TEXT      NOT NULL REFERENCES videos (id) ON DELETE CASCADE,
+    published_at TIMESTAMP,
+    created_at   TIMESTAMP,
+    updated_at   TIMESTAMP
+);
+
+CREATE INDEX broadcast_vods_stream_id_idx ON broadcast_vods (stream_id);
+CREATE INDEX broadcast_vods_video_id_idx ON broadcast_vods (video_id);
@@ -0,0 +1,25 @@
+CREATE TABLE broadcasts (
+    id             TEXT      PRIMARY KEY DEFAULT (
+        -- a random (version 4) UUID, as gen_random_uuid()
+        lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' ||
+        substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + (random() & 3), 1) ||
+        substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))
+    ),
+    broadcaster_id INTEGER   NOT NULL,
+    title          TEXT      NOT NULL DEFAULT '',
+    category       TEXT      NOT NULL DEFAULT '',
+    tags           TEXT      NOT NULL DEFAULT '{}',
+    language       TEXT      NOT NULL DEFAULT '',
+    mature         BOOLEAN   NOT NULL DEFAULT false,
+    is_active      BOOLEAN   NOT NULL DEFAULT false,
+    is_published   BOOLEAN   NOT NULL DEFAULT true,
+    viewer_count   INTEGER   NOT NULL DEFAULT 0,
+    published_at   TIMESTAMP,
+    created_at     TIMESTAMP,
+    updated_at     TIMESTAMP
+);
+
+CREATE INDEX broadcasts_broadcaster_id_idx ON broadcasts (broadcaster_id);
+CREATE INDEX broadcasts_is_active_idx ON broadcasts (is_active) WHERE is_active;
+CREATE INDEX broadcasts_category_idx ON broadcasts (category);
+CREATE INDEX broadcasts_live_category_idx ON broadcasts (category, viewer_count) WHERE is_active AND is_published;
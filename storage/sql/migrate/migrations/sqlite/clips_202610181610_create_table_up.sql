@@ -0,0 +1,18 @@
+CREATE TABLE clips (
+    id             TEXT      PRIMARY KEY DEFAULT (
+        -- a random (version 4) UUID, as gen_random_uuid()
+        lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' ||
+        substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + (random() & 3), 1) ||
+        substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))
+    ),
+    video_id       TEXT      NOT NULL REFERENCES videos (id) ON DELETE CASCADE,
+    creator_id     INTEGER   NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    broadcaster_id INTEGER   NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    source_type    TEXT      NOT NULL,
+    source_id      TEXT      NOT NULL,
+    start_offset   INTEGER   NOT NULL,
+    end_offset     INTEGER   NOT NULL,
+    created_at     TIMESTAMP
+);
+
+CREATE INDEX clips_broadcaster_id_idx ON clips (broadcaster_id);
@@ -0,0 +1 @@
+DROP TABLE live_channels;
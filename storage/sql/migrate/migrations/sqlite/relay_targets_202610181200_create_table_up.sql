@@ -0,0 +1,20 @@
+CREATE TABLE relay_targets (
+    id                TEXT      PRIMARY KEY DEFAULT (
+        -- a random (version 4) UUID, as gen_random_uuid()
+        lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' ||
+        substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + (random() & 3), 1) ||
+        substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))
+    ),
+    user_id           INTEGER   NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name              TEXT      NOT NULL,
+    url               TEXT      NOT NULL,
+    stream_key        TEXT      NOT NULL,
+    enabled           BOOLEAN   NOT NULL DEFAULT true,
+    status            TEXT      NOT NULL DEFAULT 'idle',
+    last_error        TEXT      NOT NULL DEFAULT '',
+    status_updated_at TIMESTAMP,
+    created_at        TIMESTAMP,
+    updated_at        TIMESTAMP
+);
+
+CREATE INDEX relay_targets_user_id_idx ON relay_targets (user_id);
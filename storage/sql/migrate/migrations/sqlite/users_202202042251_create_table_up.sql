@@ -0,0 +1,13 @@
+CREATE TABLE users (
+    id            INTEGER   PRIMARY KEY AUTOINCREMENT,
+    username      TEXT      NOT NULL,
+    email         TEXT      NOT NULL,
+    password      TEXT,
+    publish_key   TEXT,
+    can_publish   BOOLEAN,
+    can_stream    BOOLEAN,
+    is_admin      BOOLEAN   NOT NULL DEFAULT false,
+    storage_quota INTEGER   NOT NULL DEFAULT 0,
+    created_at    TIMESTAMP,
+    updated_at    TIMESTAMP
+);
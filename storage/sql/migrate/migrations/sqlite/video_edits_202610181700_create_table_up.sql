@@ -0,0 +1,17 @@
+CREATE TABLE video_edits (
+    id             TEXT      PRIMARY KEY DEFAULT (
+        -- a random (version 4) UUID, as gen_random_uuid()
+        lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' ||
+        substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + (random() & 3), 1) ||
+        substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))
+    ),
+    broadcaster_id INTEGER   NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    operation      TEXT      NOT NULL,
+    status         TEXT      NOT NULL,
+    source_ids     TEXT      NOT NULL,
+    result_ids     TEXT      NOT NULL,
+    created_at     TIMESTAMP,
+    updated_at     TIMESTAMP
+);
+
+CREATE INDEX video_edits_broadcaster_id_idx ON video_edits (broadcaster_id);
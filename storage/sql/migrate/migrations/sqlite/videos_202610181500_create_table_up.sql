@@ -0,0 +1,20 @@
+CREATE TABLE videos (
+    id             TEXT      PRIMARY KEY DEFAULT (
+        -- a random (version 4) UUID, as gen_random_uuid()
+        lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' ||
+        substr(lower(hex(randomblob(2))), 2) || '-' || substr('89ab', 1 + (random() & 3), 1) ||
+        substr(lower(hex(randomblob(2))), 2) || '-' || lower(hex(randomblob(6)))
+    ),
+    title          TEXT      NOT NULL,
+    broadcaster_id INTEGER   NOT NULL DEFAULT 0,
+    length         INTEGER   NOT NULL DEFAULT 0,
+    is_published   BOOLEAN   NOT NULL DEFAULT true,
+    published_at   TIMESTAMP,
+    file_path      TEXT      NOT NULL DEFAULT '',
+    index_path     TEXT      NOT NULL DEFAULT '',
+    pinned         BOOLEAN   NOT NULL DEFAULT false,
+    created_at     TIMESTAMP,
+    updated_at     TIMESTAMP
+);
+
+CREATE INDEX videos_broadcaster_id_idx ON videos (broadcaster_id);
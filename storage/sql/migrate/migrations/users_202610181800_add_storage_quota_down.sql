@@ -0,0 +1 @@
+ALTER TABLE users DROP COLUMN storage_quota;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN storage_quota BIGINT NOT NULL DEFAULT 0;
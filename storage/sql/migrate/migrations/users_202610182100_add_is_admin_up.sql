@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN is_admin BOOL NOT NULL DEFAULT false;
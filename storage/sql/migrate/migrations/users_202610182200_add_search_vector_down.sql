@@ -0,0 +1 @@
+ALTER TABLE users DROP COLUMN search_vector;
\ No newline at end of file
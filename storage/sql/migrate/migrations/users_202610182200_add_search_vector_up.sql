@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN search_vector TSVECTOR
+    GENERATED ALWAYS AS (setweight(to_tsvector('simple', username), 'A')) STORED;
+
+CREATE INDEX users_search_vector_idx ON users USING GIN (search_vector);
@@ -0,0 +1,12 @@
+CREATE TABLE video_edits (
+    id             UUID      PRIMARY KEY DEFAULT gen_random_uuid(),
+    broadcaster_id BIGINT    NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    operation      TEXT      NOT NULL,
+    status         TEXT      NOT NULL,
+    source_ids     UUID[]    NOT NULL,
+    result_ids     UUID[]    NOT NULL,
+    created_at     TIMESTAMP,
+    updated_at     TIMESTAMP
+);
+
+CREATE INDEX video_edits_broadcaster_id_idx ON video_edits (broadcaster_id);
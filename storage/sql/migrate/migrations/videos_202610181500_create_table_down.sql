@@ -0,0 +1 @@
+DROP TABLE videos;
\ No newline at end of file
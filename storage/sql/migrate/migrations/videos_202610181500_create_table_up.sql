@@ -0,0 +1,10 @@
+CREATE TABLE videos (
+    id           UUID      PRIMARY KEY DEFAULT gen_random_uuid(),
+    title        TEXT      NOT NULL,
+    length       BIGINT    NOT NULL DEFAULT 0,
+    published_at TIMESTAMP,
+    file_path    TEXT      NOT NULL DEFAULT '',
+    index_path   TEXT      NOT NULL DEFAULT '',
+    created_at   TIMESTAMP,
+    updated_at   TIMESTAMP
+);
@@ -0,0 +1 @@
+ALTER TABLE videos DROP COLUMN broadcaster_id;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE videos ADD COLUMN broadcaster_id BIGINT NOT NULL DEFAULT 0;
+
+CREATE INDEX videos_broadcaster_id_idx ON videos (broadcaster_id);
@@ -0,0 +1 @@
+ALTER TABLE videos DROP COLUMN pinned;
\ No newline at end of file
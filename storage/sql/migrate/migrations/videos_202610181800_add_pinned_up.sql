@@ -0,0 +1 @@
+ALTER TABLE videos ADD COLUMN pinned BOOL NOT NULL DEFAULT false;
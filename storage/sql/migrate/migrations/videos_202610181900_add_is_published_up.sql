@@ -0,0 +1 @@
+ALTER TABLE videos ADD COLUMN is_published BOOL NOT NULL DEFAULT true;
@@ -0,0 +1 @@
+ALTER TABLE videos DROP COLUMN search_vector;
\ No newline at end of file
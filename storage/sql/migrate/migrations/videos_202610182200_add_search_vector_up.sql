@@ -0,0 +1,4 @@
+ALTER TABLE videos ADD COLUMN search_vector TSVECTOR
+    GENERATED ALWAYS AS (setweight(to_tsvector('simple', title), 'A')) STORED;
+
+CREATE INDEX videos_search_vector_idx ON videos USING GIN (search_vector) WHERE is_published;
@@ -0,0 +1,212 @@
+package relay_target
+
+import (
+	"context"
+	sql2 "database/sql"
+	"errors"
+	"fmt"
+	"github.com/M-Ro/go-vodstream/internal/domain/relay"
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/M-Ro/go-vodstream/storage/sql/dialect"
+	"github.com/M-Ro/go-vodstream/storage/sql/keyset"
+	"github.com/M-Ro/go-vodstream/storage/sql/transaction"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+const RelayTargetsTableName = "relay_targets"
+
+type SqlRelayTargetStorage struct {
+	DB *sqlx.DB
+}
+
+var (
+	ErrNoRowsAffected = errors.New("no row found with id")
+)
+
+// insertTableName is a helper function to insert the dynamic RelayTargetsTableName property
+// as bindvars cannot be used as identifiers.
+func insertTableName(query string) string {
+	return fmt.Sprintf(query, RelayTargetsTableName)
+}
+
+// scanRows reads all relay targets from the given rows.
+func scanRows(rows *sqlx.Rows) ([]storage.RelayTarget, error) {
+	defer rows.Close()
+
+	relayTargets := make([]storage.RelayTarget, 0)
+
+	for rows.Next() {
+		relayTarget := storage.RelayTarget{}
+		err := rows.StructScan(&relayTarget)
+		if err != nil {
+			log.Error(err)
+			return relayTargets, err
+		}
+
+		relayTargets = append(relayTargets, relayTarget)
+	}
+
+	return relayTargets, rows.Err()
+}
+
+// All returns all rows in the relay_targets table.
+func (s SqlRelayTargetStorage) All(ctx context.Context) ([]storage.RelayTarget, error) {
+	rows, err := transaction.Conn(ctx, s.DB).QueryxContext(ctx, insertTableName("SELECT * FROM %s ORDER BY id ASC"))
+	if err != nil {
+		log.Error(err)
+		return []storage.RelayTarget{}, err
+	}
+
+	return scanRows(rows)
+}
+
+// List returns a set of rows from the relay_targets table specified by the given pagination options,
+// with the cursors of the pages either side.
+func (s SqlRelayTargetStorage) List(
+	ctx context.Context, options paginate.QueryOptions,
+) ([]storage.RelayTarget, paginate.Page, error) {
+	sql, args, err := keyset.Query(dialect.Of(s.DB), insertTableName(`SELECT * FROM %s`), relay.TargetFields, options, "id")
+	if err != nil {
+		return []storage.RelayTarget{}, paginate.Page{}, err
+	}
+
+	rows, err := transaction.Conn(ctx, s.DB).QueryxContext(ctx, sql, args...)
+	if err != nil {
+		log.Error(err)
+		return []storage.RelayTarget{}, paginate.Page{}, err
+	}
+
+	relayTargets, err := scanRows(rows)
+	if err != nil {
+		return relayTargets, paginate.Page{}, err
+	}
+
+	return relayTargets, keyset.Page(dialect.Of(s.DB), &relayTargets, options, "id"), nil
+}
+
+// GetByUserID returns all relay targets belonging to the given user, oldest first.
+func (s SqlRelayTargetStorage) GetByUserID(ctx context.Context, userId uint64) ([]storage.RelayTarget, error) {
+	rows, err := transaction.Conn(ctx, s.DB).QueryxContext(
+		ctx, insertTableName(`SELECT * FROM %s WHERE user_id = $1 ORDER BY created_at ASC`), userId,
+	)
+	if err != nil {
+		log.Error(err)
+		return []storage.RelayTarget{}, err
+	}
+
+	return scanRows(rows)
+}
+
+// GetByID returns the relay target with the given ID, or nil on failure.
+func (s SqlRelayTargetStorage) GetByID(ctx context.Context, id uuid.UUID) *storage.RelayTarget {
+	row := transaction.Conn(ctx, s.DB).QueryRowxContext(ctx, insertTableName(`SELECT * from %s WHERE id = $1`), id)
+
+	var relayTarget storage.RelayTarget
+	err := row.StructScan(&relayTarget)
+	if err != nil {
+		if err != sql2.ErrNoRows {
+			log.Error(err)
+		}
+		return nil
+	}
+
+	return &relayTarget
+}
+
+// Delete removes a relay target with the given ID from the table. Only returns on db error.
+func (s SqlRelayTargetStorage) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := transaction.Conn(ctx, s.DB).ExecContext(ctx, insertTableName(`DELETE FROM %s WHERE id = $1`), id)
+	if err != nil {
+		log.Errorf("SqlRelayTargetStorage::Delete: %s", err)
+	}
+
+	return err
+}
+
+// Insert takes a storage model and inserts into the db. Returns an error on failure.
+// Upon insertion the ID field of the model will be set.
+func (s SqlRelayTargetStorage) Insert(ctx context.Context, relayTarget *storage.RelayTarget) error {
+	relayTarget.CreatedAt = time.Now().Truncate(time.Microsecond)
+	relayTarget.UpdatedAt = relayTarget.CreatedAt
+	relayTarget.StatusUpdatedAt = relayTarget.CreatedAt
+
+	row := transaction.Conn(ctx, s.DB).QueryRowContext(
+		ctx,
+		insertTableName(`INSERT INTO %s 
+			(user_id, name, url, stream_key, enabled, status, last_error, status_updated_at, created_at, updated_at)
+			VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10) RETURNING id`),
+		relayTarget.UserId, relayTarget.Name, relayTarget.Url, relayTarget.StreamKey, relayTarget.Enabled,
+		relayTarget.Status, relayTarget.LastError, relayTarget.StatusUpdatedAt,
+		relayTarget.CreatedAt, relayTarget.UpdatedAt,
+	)
+
+	err := row.Scan(&relayTarget.Id)
+
+	return err
+}
+
+// Update takes a storage model and updates row contents for the relay target at the given ID.
+// Returns error on failure, or if a relay target was not found with the given id.
+func (s SqlRelayTargetStorage) Update(ctx context.Context, id uuid.UUID, relayTarget *storage.RelayTarget) error {
+	relayTarget.UpdatedAt = time.Now().Truncate(time.Microsecond)
+
+	result, err := transaction.Conn(ctx, s.DB).ExecContext(
+		ctx,
+		insertTableName(`UPDATE %s SET 
+			user_id=$1, name=$2, url=$3, stream_key=$4, enabled=$5, status=$6, last_error=$7,
+			status_updated_at=$8, created_at=$9, updated_at=$10 WHERE id=$11`),
+		relayTarget.UserId, relayTarget.Name, relayTarget.Url, relayTarget.StreamKey, relayTarget.Enabled,
+		relayTarget.Status, relayTarget.LastError, relayTarget.StatusUpdatedAt,
+		relayTarget.CreatedAt, relayTarget.UpdatedAt, id)
+
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	return checkRowsAffected(result)
+}
+
+// UpdateStatus records the connection status of the relay target at the given ID,
+// leaving the configured fields untouched.
+func (s SqlRelayTargetStorage) UpdateStatus(ctx context.Context, id uuid.UUID, status string, lastError string) error {
+	result, err := transaction.Conn(ctx, s.DB).ExecContext(
+		ctx,
+		insertTableName(`UPDATE %s SET status=$1, last_error=$2, status_updated_at=$3 WHERE id=$4`),
+		status, lastError, time.Now().Truncate(time.Microsecond), id)
+
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	return checkRowsAffected(result)
+}
+
+// checkRowsAffected returns ErrNoRowsAffected unless exactly one row was changed.
+func checkRowsAffected(result sql2.Result) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	if rows != 1 {
+		log.Warn(ErrNoRowsAffected)
+		return ErrNoRowsAffected
+	}
+
+	return nil
+}
+
+// NewRelayTargetStorage instantiates a new SqlRelayTargetStorage object.
+func NewRelayTargetStorage(db *sqlx.DB) *SqlRelayTargetStorage {
+	newStorage := new(SqlRelayTargetStorage)
+	newStorage.DB = db
+
+	return newStorage
+}
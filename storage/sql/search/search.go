@@ -0,0 +1,234 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/M-Ro/go-vodstream/storage/sql/broadcast"
+	"github.com/M-Ro/go-vodstream/storage/sql/broadcast_vod"
+	"github.com/M-Ro/go-vodstream/storage/sql/channel"
+	"github.com/M-Ro/go-vodstream/storage/sql/dialect"
+	"github.com/M-Ro/go-vodstream/storage/sql/transaction"
+	"github.com/M-Ro/go-vodstream/storage/sql/user"
+	"github.com/M-Ro/go-vodstream/storage/sql/video"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"strings"
+)
+
+type SqlSearchStorage struct {
+	DB *sqlx.DB
+}
+
+var (
+	ErrUnknownType = errors.New("unknown search result type")
+)
+
+// typeQueries selects the documents of each type matching the tsquery bound to $1,
+// ranked against it. Each selects the same columns so that they may be combined.
+// Documents spanning several tables are concatenated before matching, so that
+// every term of a query may be found in any of them.
+var typeQueries = map[string]string{
+	"channel": fmt.Sprintf(
+		`SELECT 'channel' AS type, u.id::text AS id, u.id AS broadcaster_id, u.username,
+			COALESCE(NULLIF(c.display_name, ''), u.username) AS title, COALESCE(c.description, '') AS description,
+			COALESCE(c.category, '') AS category, COALESCE(c.tags, '{}') AS tags,
+			EXISTS (SELECT 1 FROM %[3]s b WHERE b.broadcaster_id = u.id AND b.is_active AND b.is_published) AS is_live,
+			u.created_at AS published_at,
+			ts_rank(u.search_vector || COALESCE(c.search_vector, ''), q) AS rank
+		FROM %[1]s u LEFT JOIN %[2]s c ON c.broadcaster_id = u.id, to_tsquery('simple', $1) q
+		WHERE u.can_publish AND (u.search_vector || COALESCE(c.search_vector, '')) @@ q`,
+		user.UsersTableName, channel.ChannelsTableName, broadcast.BroadcastsTableName,
+	),
+	"broadcast": fmt.Sprintf(
+		`SELECT 'broadcast' AS type, b.id::text AS id, b.broadcaster_id, COALESCE(u.username, '') AS username,
+			b.title, '' AS description, b.category, b.tags, b.is_active AS is_live, b.published_at,
+			ts_rank(b.search_vector, q) AS rank
+		FROM %[2]s b LEFT JOIN %[1]s u ON u.id = b.broadcaster_id, to_tsquery('simple', $1) q
+		WHERE b.is_published AND b.search_vector @@ q`,
+		user.UsersTableName, broadcast.BroadcastsTableName,
+	),
+	// Videos carry the category and tags of the broadcast they were recorded from.
+	"video": fmt.Sprintf(
+		`SELECT 'video' AS type, v.id::text AS id, v.broadcaster_id, COALESCE(u.username, '') AS username,
+			v.title, '' AS description, COALESCE(b.category, '') AS category, COALESCE(b.tags, '{}') AS tags,
+			false AS is_live, v.published_at,
+			ts_rank(v.search_vector || setweight(array_to_tsvector(COALESCE(b.tags, '{}')), 'B'), q) AS rank
+		FROM %[2]s v LEFT JOIN %[1]s u ON u.id = v.broadcaster_id
+			LEFT JOIN LATERAL (
+				SELECT b.category, b.tags FROM %[4]s bv JOIN %[3]s b ON b.id = bv.stream_id
+				WHERE bv.video_id = v.id ORDER BY bv.published_at ASC LIMIT 1
+			) b ON true,
+			to_tsquery('simple', $1) q
+		WHERE v.is_published AND (v.search_vector || setweight(array_to_tsvector(COALESCE(b.tags, '{}')), 'B')) @@ q`,
+		user.UsersTableName, video.VideosTableName, broadcast.BroadcastsTableName,
+		broadcast_vod.BroadcastVodsTableName,
+	),
+}
+
+// sqliteTypeQuery selects the documents of a type from SQLite, which has no full text search
+// of its own. The query is formatted with the rank of each document and the condition matching
+// it, both built from its heading and document by sqliteMatch.
+type sqliteTypeQuery struct {
+	query    string
+	heading  string
+	document string
+}
+
+// sqliteTypeQueries select the same documents as typeQueries, from the same tables migrated
+// upon SQLite. Words of the heading of a document rank above those of the rest of it, as the
+// weights of the Postgres search vectors.
+var sqliteTypeQueries = map[string]sqliteTypeQuery{
+	"channel": {
+		query: fmt.Sprintf(
+			`SELECT 'channel' AS type, CAST(u.id AS TEXT) AS id, u.id AS broadcaster_id, u.username,
+				COALESCE(NULLIF(c.display_name, ''), u.username) AS title, COALESCE(c.description, '') AS description,
+				COALESCE(c.category, '') AS category, COALESCE(c.tags, '{}') AS tags,
+				EXISTS (SELECT 1 FROM %[3]s b WHERE b.broadcaster_id = u.id AND b.is_active AND b.is_published) AS is_live,
+				u.created_at AS published_at, %%[1]s AS rank
+			FROM %[1]s u LEFT JOIN %[2]s c ON c.broadcaster_id = u.id
+			WHERE u.can_publish AND %%[2]s`,
+			user.UsersTableName, channel.ChannelsTableName, broadcast.BroadcastsTableName,
+		),
+		heading:  words(`u.username || ' ' || COALESCE(c.display_name, '')`),
+		document: words(`u.username || ' ' || COALESCE(c.display_name, '') || ' ' || COALESCE(c.tags, '') || ' ' || COALESCE(c.description, '')`),
+	},
+	"broadcast": {
+		query: fmt.Sprintf(
+			`SELECT 'broadcast' AS type, CAST(b.id AS TEXT) AS id, b.broadcaster_id, COALESCE(u.username, '') AS username,
+				b.title, '' AS description, b.category, b.tags, b.is_active AS is_live, b.published_at, %%[1]s AS rank
+			FROM %[2]s b LEFT JOIN %[1]s u ON u.id = b.broadcaster_id
+			WHERE b.is_published AND %%[2]s`,
+			user.UsersTableName, broadcast.BroadcastsTableName,
+		),
+		heading:  words(`b.title`),
+		document: words(`b.title || ' ' || b.tags`),
+	},
+	"video": {
+		query: fmt.Sprintf(
+			`SELECT 'video' AS type, CAST(v.id AS TEXT) AS id, v.broadcaster_id, COALESCE(u.username, '') AS username,
+				v.title, '' AS description, COALESCE(b.category, '') AS category, COALESCE(b.tags, '{}') AS tags,
+				false AS is_live, v.published_at, %%[1]s AS rank
+			FROM %[2]s v LEFT JOIN %[1]s u ON u.id = v.broadcaster_id
+				LEFT JOIN %[3]s b ON b.id = (
+					SELECT bv.stream_id FROM %[4]s bv WHERE bv.video_id = v.id ORDER BY bv.published_at ASC LIMIT 1
+				)
+			WHERE v.is_published AND %%[2]s`,
+			user.UsersTableName, video.VideosTableName, broadcast.BroadcastsTableName,
+			broadcast_vod.BroadcastVodsTableName,
+		),
+		heading:  words(`v.title`),
+		document: words(`v.title || ' ' || COALESCE(b.tags, '')`),
+	},
+}
+
+// wordSeparators are the punctuation replaced by spaces in documents searched upon SQLite,
+// including that of arrays stored as text, so that every word follows a space.
+var wordSeparators = []string{`{`, `}`, `"`, `,`, `.`, `:`, `;`, `!`, `?`, `(`, `)`, `[`, `]`, `/`, `-`}
+
+// words returns an SQL expression of the text with its words separated by spaces, and led by one.
+func words(text string) string {
+	for _, separator := range wordSeparators {
+		text = fmt.Sprintf(`replace(%s, '%s', ' ')`, text, separator)
+	}
+
+	return fmt.Sprintf(`(' ' || %s)`, text)
+}
+
+// sqliteMatch returns the query of the type matching documents containing every term bound to
+// $1 onwards as the prefix of a word, ranked by how many are found in its heading. Matching
+// ignores the case of ASCII letters only.
+func (q sqliteTypeQuery) sqliteMatch(terms int) string {
+	ranks := make([]string, 0, terms)
+	conditions := make([]string, 0, terms)
+	for i := 1; i <= terms; i++ {
+		ranks = append(ranks, fmt.Sprintf(`(CASE WHEN %s LIKE $%d THEN 1.0 ELSE 0.4 END)`, q.heading, i))
+		conditions = append(conditions, fmt.Sprintf(`%s LIKE $%d`, q.document, i))
+	}
+
+	return fmt.Sprintf(q.query, strings.Join(ranks, " + "), strings.Join(conditions, " AND "))
+}
+
+// sqliteTerms returns the LIKE patterns matching words prefixed by each term of the tsquery,
+// as built by search.ParseQuery from letters and digits only.
+func sqliteTerms(tsquery string) []interface{} {
+	terms := strings.Split(tsquery, " & ")
+
+	patterns := make([]interface{}, 0, len(terms))
+	for _, term := range terms {
+		patterns = append(patterns, "% "+strings.TrimSuffix(term, ":*")+"%")
+	}
+
+	return patterns
+}
+
+// Search returns the published channels, broadcasts and videos of the given types matching
+// the tsquery, best ranked first with ties broken by type and ID, limited and offset by the
+// given pagination options. The order field of the options is ignored.
+func (s SqlSearchStorage) Search(
+	ctx context.Context, tsquery string, types []string, options paginate.QueryOptions,
+) ([]storage.SearchResult, error) {
+	results := make([]storage.SearchResult, 0)
+
+	sqlite := dialect.Of(s.DB) == dialect.SQLite
+
+	args := []interface{}{tsquery}
+	if sqlite {
+		args = sqliteTerms(tsquery)
+	}
+
+	selects := make([]string, 0, len(types))
+	for _, t := range types {
+		query, ok := typeQueries[t]
+		if sqlite {
+			var sqliteQuery sqliteTypeQuery
+			sqliteQuery, ok = sqliteTypeQueries[t]
+			query = sqliteQuery.sqliteMatch(len(args))
+		}
+
+		if !ok {
+			return results, ErrUnknownType
+		}
+
+		selects = append(selects, query)
+	}
+
+	if len(selects) == 0 {
+		return results, nil
+	}
+
+	sql := fmt.Sprintf(
+		`%s ORDER BY rank DESC, type ASC, id ASC LIMIT %d OFFSET %d`,
+		strings.Join(selects, " UNION ALL "), options.Limit, options.Offset,
+	)
+
+	rows, err := transaction.Conn(ctx, s.DB).QueryxContext(ctx, sql, args...)
+	if err != nil {
+		log.Error(err)
+		return results, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		result := storage.SearchResult{}
+		err = rows.StructScan(&result)
+		if err != nil {
+			log.Error(err)
+			return results, err
+		}
+
+		results = append(results, result)
+	}
+
+	return results, rows.Err()
+}
+
+// NewSearchStorage instantiates a new SqlSearchStorage object.
+func NewSearchStorage(db *sqlx.DB) *SqlSearchStorage {
+	newStorage := new(SqlSearchStorage)
+	newStorage.DB = db
+
+	return newStorage
+}
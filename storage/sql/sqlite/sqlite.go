@@ -0,0 +1,36 @@
+// Package sqlite opens SQLite databases for the SQL storages, for single-node
+// deployments without a database server.
+package sqlite
+
+import (
+	"github.com/M-Ro/go-vodstream/storage/sql/migrate"
+	"github.com/jmoiron/sqlx"
+	_ "modernc.org/sqlite"
+	"net/url"
+)
+
+// options are the settings of every connection to a database. Foreign keys are enforced as
+// by Postgres, writers wait for each other rather than failing at once, transactions take
+// the write lock as they begin so that they never fail to upgrade to it, and times are
+// written as text ordering as they do.
+var options = url.Values{
+	"_pragma":      {"foreign_keys(1)", "busy_timeout(5000)"},
+	"_txlock":      {"immediate"},
+	"_time_format": {"sqlite"},
+}
+
+// Open opens the SQLite database at path, creating it if it does not exist, and brings
+// its tables up to date by running the migrations not yet run upon it.
+func Open(path string) (*sqlx.DB, error) {
+	db, err := sqlx.Open("sqlite", "file:"+path+"?"+options.Encode())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := migrate.Up(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
@@ -0,0 +1,125 @@
+package sqlite_test
+
+import (
+	"context"
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/M-Ro/go-vodstream/storage/sql/broadcast"
+	"github.com/M-Ro/go-vodstream/storage/sql/broadcast_vod"
+	"github.com/M-Ro/go-vodstream/storage/sql/search"
+	"github.com/M-Ro/go-vodstream/storage/sql/sqlite"
+	"github.com/M-Ro/go-vodstream/storage/sql/user"
+	"github.com/M-Ro/go-vodstream/storage/sql/video"
+	"github.com/M-Ro/go-vodstream/storage/storagetest"
+	"github.com/jmoiron/sqlx"
+	"path/filepath"
+	"testing"
+)
+
+// open opens a database in a temporary file, as the storages are shared by every
+// connection of the pool, which in-memory databases are not.
+func open(t *testing.T) *sqlx.DB {
+	db, err := sqlite.Open(filepath.Join(t.TempDir(), "vodstream.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func TestOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vodstream.db")
+
+	for i := 0; i < 2; i++ {
+		db, err := sqlite.Open(path)
+		if err != nil {
+			t.Fatalf("expected opening %d to succeed, got %v", i+1, err)
+		}
+
+		var batches int
+		if err := db.Get(&batches, `SELECT COUNT(DISTINCT batch) FROM migrations`); err != nil {
+			t.Fatal(err)
+		}
+		db.Close()
+
+		if batches != 1 {
+			t.Fatalf("expected migrations to run once, got %d batches", batches)
+		}
+	}
+}
+
+func TestUserStorage_Conformance(t *testing.T) {
+	storagetest.Users(t, user.NewUserStorage(open(t)))
+}
+
+func TestBroadcastStorage_Conformance(t *testing.T) {
+	storagetest.Broadcasts(t, broadcast.NewBroadcastStorage(open(t)))
+}
+
+func TestVideoStorage_Conformance(t *testing.T) {
+	storagetest.Videos(t, video.NewVideoStorage(open(t)))
+}
+
+func TestBroadcastVodStorage_Conformance(t *testing.T) {
+	db := open(t)
+	storagetest.BroadcastVods(t, broadcast_vod.NewBroadcastVodStorage(db), video.NewVideoStorage(db))
+}
+
+func TestSearch(t *testing.T) {
+	ctx := context.Background()
+	db := open(t)
+
+	broadcaster := storage.User{Username: "speedrunner", Email: "runner@example.com", CanPublish: true}
+	if err := user.NewUserStorage(db).Insert(ctx, &broadcaster); err != nil {
+		t.Fatal(err)
+	}
+
+	broadcasts := broadcast.NewBroadcastStorage(db)
+	for _, b := range []storage.Broadcast{
+		{BroadcasterId: broadcaster.Id, Title: "Any% speedrun", Tags: []string{"retro"}, IsPublished: true},
+		{BroadcasterId: broadcaster.Id, Title: "Retro classics", IsPublished: true},
+		{BroadcasterId: broadcaster.Id, Title: "Retro rehearsal", IsPublished: false},
+	} {
+		b := b
+		if err := broadcasts.Insert(ctx, &b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		testName string
+		tsquery  string
+		types    []string
+		expected []string
+	}{
+		{"Expect headings ranked above tags.", "retro:*", []string{"broadcast"}, []string{"Retro classics", "Any% speedrun"}},
+		{"Expect words matched by prefix.", "speed:*", []string{"broadcast"}, []string{"Any% speedrun"}},
+		{"Expect every term matched.", "retro:* & any:*", []string{"broadcast"}, []string{"Any% speedrun"}},
+		{"Expect channels matched by username.", "speedrunner:*", []string{"channel"}, []string{"speedrunner"}},
+		{"Expect words to match from their start only.", "run:*", []string{"broadcast", "channel"}, []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			results, err := search.NewSearchStorage(db).Search(ctx, test.tsquery, test.types, paginate.NewPaginateOptions())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			titles := make([]string, 0, len(results))
+			for _, result := range results {
+				titles = append(titles, result.Title)
+			}
+
+			if len(titles) != len(test.expected) {
+				t.Fatalf("expected %v, got %v", test.expected, titles)
+			}
+			for i := range titles {
+				if titles[i] != test.expected[i] {
+					t.Fatalf("expected %v, got %v", test.expected, titles)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,304 @@
+package table
+
+import (
+	"context"
+	sql2 "database/sql"
+	"fmt"
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/M-Ro/go-vodstream/storage/sql/dialect"
+	"github.com/M-Ro/go-vodstream/storage/sql/keyset"
+	"github.com/M-Ro/go-vodstream/storage/sql/transaction"
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
+	log "github.com/sirupsen/logrus"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoRowsAffected = storage.ErrNoRowsAffected
+)
+
+// mapper finds the fields of storage models by column name, as sqlx scans them.
+var mapper = reflectx.NewMapperFunc("db", sqlx.NameMapper)
+
+// Table describes the table a storage model is kept in.
+type Table struct {
+	Name string
+
+	// The column identifying rows, and whether its value is assigned by the
+	// database upon insertion rather than written from the model.
+	Key          string
+	GeneratedKey bool
+
+	// The fields listings of the rows may be ordered or filtered by
+	Schema paginate.Schema
+
+	// Columns generated by the database, never written
+	ReadOnly []string
+}
+
+// Storage stores models of type T, keyed by values of type K, in a table. Columns
+// are the db tags of the fields of T, all of which are written but the key and
+// those read only. created_at and updated_at columns are kept up to date.
+type Storage[T any, K comparable] struct {
+	DB    *sqlx.DB
+	Table Table
+
+	// The columns written on insertion and update, in order
+	columns []string
+}
+
+// New returns a storage of models of type T in the described table.
+func New[T any, K comparable](db *sqlx.DB, table Table) Storage[T, K] {
+	return Storage[T, K]{
+		DB:      db,
+		Table:   table,
+		columns: columns(reflect.TypeOf(new(T)).Elem(), table),
+	}
+}
+
+// columns returns the columns of the model type written by the storage of the table.
+func columns(t reflect.Type, table Table) []string {
+	written := make([]string, 0)
+
+	for _, field := range mapper.TypeMap(t).Index {
+		if len(field.Index) != 1 || field.Name == table.Key || contains(table.ReadOnly, field.Name) {
+			continue
+		}
+
+		written = append(written, field.Name)
+	}
+
+	return written
+}
+
+// contains returns true if column is within columns.
+func contains(columns []string, column string) bool {
+	for _, other := range columns {
+		if other == column {
+			return true
+		}
+	}
+
+	return false
+}
+
+// InsertTableName inserts the table name into a query in place of %s, as bindvars
+// cannot be used as identifiers.
+func (s Storage[T, K]) InsertTableName(query string) string {
+	return fmt.Sprintf(query, s.Table.Name)
+}
+
+// Dialect returns the dialect of the database the table is in.
+func (s Storage[T, K]) Dialect() dialect.Dialect {
+	return dialect.Of(s.DB)
+}
+
+// Conn returns the transaction carried by the context, or the database outside of one.
+func (s Storage[T, K]) Conn(ctx context.Context) transaction.Querier {
+	return transaction.Conn(ctx, s.DB)
+}
+
+// All returns all rows in the table, ordered by key.
+func (s Storage[T, K]) All(ctx context.Context) ([]T, error) {
+	return s.Select(ctx, s.InsertTableName(fmt.Sprintf(`SELECT * FROM %%s ORDER BY %s ASC`, s.Table.Key)))
+}
+
+// List returns a set of rows from the table specified by the given pagination options,
+// with the cursors of the pages either side.
+func (s Storage[T, K]) List(ctx context.Context, options paginate.QueryOptions) ([]T, paginate.Page, error) {
+	return s.ListWhere(ctx, "", options)
+}
+
+// ListWhere returns a set of the rows of the table matching the condition, specified by the
+// given pagination options, with the cursors of the pages either side. Ties are broken by key.
+// args are bound within the condition.
+func (s Storage[T, K]) ListWhere(
+	ctx context.Context, condition string, options paginate.QueryOptions, args ...interface{},
+) ([]T, paginate.Page, error) {
+	query := s.InsertTableName(`SELECT * FROM %s`)
+	if condition != "" {
+		query += " WHERE " + condition
+	}
+
+	sql, args, err := keyset.Query(s.Dialect(), query, s.Table.Schema, options, s.Table.Key, args...)
+	if err != nil {
+		return []T{}, paginate.Page{}, err
+	}
+
+	rows, err := s.Select(ctx, sql, args...)
+	if err != nil {
+		return rows, paginate.Page{}, err
+	}
+
+	return rows, keyset.Page(s.Dialect(), &rows, options, s.Table.Key), nil
+}
+
+// Select returns the rows of the table selected by the query.
+func (s Storage[T, K]) Select(ctx context.Context, query string, args ...interface{}) ([]T, error) {
+	selected := make([]T, 0)
+
+	rows, err := s.Conn(ctx).QueryxContext(ctx, query, args...)
+	if err != nil {
+		log.Error(err)
+		return selected, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row T
+		err = rows.StructScan(&row)
+		if err != nil {
+			log.Error(err)
+			return selected, err
+		}
+
+		selected = append(selected, row)
+	}
+
+	return selected, rows.Err()
+}
+
+// Get returns the first row of the table selected by the query, or nil on failure.
+func (s Storage[T, K]) Get(ctx context.Context, query string, args ...interface{}) *T {
+	var row T
+	err := s.Conn(ctx).QueryRowxContext(ctx, query, args...).StructScan(&row)
+	if err != nil {
+		if err != sql2.ErrNoRows {
+			log.Error(err)
+		}
+		return nil
+	}
+
+	return &row
+}
+
+// GetByID returns the row with the given key, or nil on failure.
+func (s Storage[T, K]) GetByID(ctx context.Context, id K) *T {
+	return s.Get(ctx, s.InsertTableName(fmt.Sprintf(`SELECT * FROM %%s WHERE %s = $1`, s.Table.Key)), id)
+}
+
+// Delete removes the row with the given key from the table. Only returns on db error.
+func (s Storage[T, K]) Delete(ctx context.Context, id K) error {
+	query := s.InsertTableName(fmt.Sprintf(`DELETE FROM %%s WHERE %s = $1`, s.Table.Key))
+
+	_, err := s.Conn(ctx).ExecContext(ctx, query, id)
+	if err != nil {
+		log.Errorf("%s Delete: %s", s.Table.Name, err)
+	}
+
+	return err
+}
+
+// Insert takes a storage model and inserts it into the table. Returns an error on failure.
+// Upon insertion the key field of the model will be set if generated by the database.
+func (s Storage[T, K]) Insert(ctx context.Context, row *T) error {
+	model := reflect.ValueOf(row).Elem()
+
+	now := time.Now().Truncate(time.Microsecond)
+	s.setTime(model, "created_at", now)
+	s.setTime(model, "updated_at", now)
+
+	query, columns := s.insertQuery()
+
+	if !s.Table.GeneratedKey {
+		_, err := s.Conn(ctx).ExecContext(ctx, query, values(model, columns)...)
+		return err
+	}
+
+	key := mapper.FieldByName(model, s.Table.Key)
+	return s.Conn(ctx).QueryRowContext(ctx, query, values(model, columns)...).Scan(key.Addr().Interface())
+}
+
+// Update takes a storage model and updates the row contents at the given key.
+// Returns error on failure, or if a row was not found with the given key.
+func (s Storage[T, K]) Update(ctx context.Context, id K, row *T) error {
+	model := reflect.ValueOf(row).Elem()
+	s.setTime(model, "updated_at", time.Now().Truncate(time.Microsecond))
+
+	return s.ExecOne(ctx, s.updateQuery(), append(values(model, s.columns), id)...)
+}
+
+// insertQuery returns the statement inserting a row, and the columns whose values it binds in
+// order. The key is returned by the statement when generated by the database.
+func (s Storage[T, K]) insertQuery() (string, []string) {
+	columns := s.columns
+	if !s.Table.GeneratedKey {
+		columns = append([]string{s.Table.Key}, columns...)
+	}
+
+	placeholders := make([]string, 0, len(columns))
+	for i := range columns {
+		placeholders = append(placeholders, fmt.Sprintf("$%d", i+1))
+	}
+
+	query := s.InsertTableName(fmt.Sprintf(
+		`INSERT INTO %%s (%s) VALUES (%s)`, strings.Join(columns, ", "), strings.Join(placeholders, ", "),
+	))
+
+	if s.Table.GeneratedKey {
+		query += " RETURNING " + s.Table.Key
+	}
+
+	return query, columns
+}
+
+// updateQuery returns the statement updating a row, binding the values of the written columns
+// in order, then the key.
+func (s Storage[T, K]) updateQuery() string {
+	assignments := make([]string, 0, len(s.columns))
+	for i, column := range s.columns {
+		assignments = append(assignments, fmt.Sprintf("%s=$%d", column, i+1))
+	}
+
+	return s.InsertTableName(fmt.Sprintf(
+		`UPDATE %%s SET %s WHERE %s=$%d`, strings.Join(assignments, ", "), s.Table.Key, len(s.columns)+1,
+	))
+}
+
+// ExecOne executes a statement expected to change a single row of the table.
+// Returns error on failure, or ErrNoRowsAffected if no row changed.
+func (s Storage[T, K]) ExecOne(ctx context.Context, query string, args ...interface{}) error {
+	result, err := s.Conn(ctx).ExecContext(ctx, query, args...)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	if rows != 1 {
+		log.Warn(ErrNoRowsAffected)
+		return ErrNoRowsAffected
+	}
+
+	return nil
+}
+
+// setTime sets the timestamp of the model in the given column, if the table has it.
+func (s Storage[T, K]) setTime(model reflect.Value, column string, t time.Time) {
+	if !contains(s.columns, column) {
+		return
+	}
+
+	if field := mapper.FieldByName(model, column); field.Type() == reflect.TypeOf(t) {
+		field.Set(reflect.ValueOf(t))
+	}
+}
+
+// values returns the values of the model in the given columns.
+func values(model reflect.Value, columns []string) []interface{} {
+	args := make([]interface{}, 0, len(columns))
+	for _, column := range columns {
+		args = append(args, mapper.FieldByName(model, column).Interface())
+	}
+
+	return args
+}
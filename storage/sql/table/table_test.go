@@ -0,0 +1,83 @@
+package table
+
+import (
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testRow struct {
+	Id    uuid.UUID `db:"id"`
+	Title string    `db:"title"`
+
+	SearchVector string `db:"search_vector"`
+
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+func TestStorage_Queries(t *testing.T) {
+	tests := []struct {
+		testName string
+		table    Table
+
+		expectedInsert  string
+		expectedColumns []string
+		expectedUpdate  string
+	}{
+		{
+			testName: "Expect the key written on insertion when not generated.",
+			table:    Table{Name: "rows", Key: "id", ReadOnly: []string{"search_vector"}},
+			expectedInsert: `INSERT INTO rows (id, title, created_at, updated_at) ` +
+				`VALUES ($1, $2, $3, $4)`,
+			expectedColumns: []string{"id", "title", "created_at", "updated_at"},
+			expectedUpdate:  `UPDATE rows SET title=$1, created_at=$2, updated_at=$3 WHERE id=$4`,
+		},
+		{
+			testName: "Expect the key returned on insertion when generated.",
+			table:    Table{Name: "rows", Key: "id", GeneratedKey: true, ReadOnly: []string{"search_vector"}},
+			expectedInsert: `INSERT INTO rows (title, created_at, updated_at) ` +
+				`VALUES ($1, $2, $3) RETURNING id`,
+			expectedColumns: []string{"title", "created_at", "updated_at"},
+			expectedUpdate:  `UPDATE rows SET title=$1, created_at=$2, updated_at=$3 WHERE id=$4`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			s := New[testRow, uuid.UUID](nil, test.table)
+
+			insert, columns := s.insertQuery()
+			if !cmp.Equal(insert, test.expectedInsert) {
+				t.Fatal(cmp.Diff(insert, test.expectedInsert))
+			}
+
+			if !cmp.Equal(columns, test.expectedColumns) {
+				t.Fatal(cmp.Diff(columns, test.expectedColumns))
+			}
+
+			if update := s.updateQuery(); !cmp.Equal(update, test.expectedUpdate) {
+				t.Fatal(cmp.Diff(update, test.expectedUpdate))
+			}
+		})
+	}
+}
+
+func TestStorage_Values(t *testing.T) {
+	s := New[testRow, uuid.UUID](nil, Table{Name: "rows", Key: "id"})
+
+	row := testRow{Id: uuid.New(), Title: "title", SearchVector: "'title':1"}
+	model := reflect.ValueOf(&row).Elem()
+
+	now := time.Now()
+	s.setTime(model, "created_at", now)
+
+	args := values(model, []string{"id", "title", "search_vector", "created_at", "updated_at"})
+
+	expected := []interface{}{row.Id, "title", "'title':1", now, time.Time{}}
+	if !cmp.Equal(args, expected) {
+		t.Fatal(cmp.Diff(args, expected))
+	}
+}
@@ -0,0 +1,126 @@
+// Package transaction runs units of work spanning several storages within a
+// single database transaction, carried to the storages through the context.
+package transaction
+
+import (
+	"context"
+	sql2 "database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	log "github.com/sirupsen/logrus"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+	"time"
+)
+
+// MaxAttempts is how many times a unit of work is run before its serialization
+// failures are given up on.
+const MaxAttempts = 5
+
+// retryBackoff is how long is waited before the second attempt, growing with each attempt after.
+const retryBackoff = 10 * time.Millisecond
+
+// Postgres error codes of transactions that may succeed if run again.
+const (
+	codeSerializationFailure = "40001"
+	codeDeadlockDetected     = "40P01"
+)
+
+// Querier runs queries against either a database or a transaction upon it.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql2.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql2.Rows, error)
+	QueryxContext(ctx context.Context, query string, args ...interface{}) (*sqlx.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql2.Row
+	QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row
+}
+
+// txKey is the context key of the transaction a unit of work runs within.
+type txKey struct{}
+
+// Conn returns the transaction carried by the context, or db outside of one.
+// Storages query through it so that they take part in any unit of work.
+func Conn(ctx context.Context, db *sqlx.DB) Querier {
+	if tx, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return tx
+	}
+
+	return db
+}
+
+// WithTx runs fn within a serializable transaction on db, carried by the context
+// passed to it. The transaction is committed if fn returns nil, and rolled back
+// otherwise. Transactions failing to serialize are retried up to MaxAttempts,
+// so fn may run more than once and should have no effects outside of the
+// database. When ctx already carries a transaction, fn joins it instead.
+func WithTx(ctx context.Context, db *sqlx.DB, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return fn(ctx)
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := run(ctx, db, fn)
+		if !Retryable(err) || attempt == MaxAttempts {
+			return err
+		}
+
+		log.Warnf("Retrying transaction after attempt %d: %s", attempt, err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * retryBackoff):
+		}
+	}
+}
+
+// run runs fn within a single transaction on db.
+func run(ctx context.Context, db *sqlx.DB, fn func(ctx context.Context) error) error {
+	tx, err := db.BeginTxx(ctx, &sql2.TxOptions{Isolation: sql2.LevelSerializable})
+	if err != nil {
+		return err
+	}
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			log.Errorf("Couldn't roll back transaction: %s", rollbackErr)
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Retryable returns true if the error is of a transaction that may succeed if run again.
+// SQLite transactions are serialized by locking the database, so only fail to take the lock.
+func Retryable(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == codeSerializationFailure || pqErr.Code == codeDeadlockDetected
+	}
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		// Extended result codes keep the primary code in their lowest byte.
+		code := sqliteErr.Code() & 0xff
+		return code == sqlite3.SQLITE_BUSY || code == sqlite3.SQLITE_LOCKED
+	}
+
+	return false
+}
+
+// Transactor runs units of work within transactions on a database.
+type Transactor struct {
+	DB *sqlx.DB
+}
+
+// WithTx runs fn within a transaction, as WithTx.
+func (t Transactor) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return WithTx(ctx, t.DB, fn)
+}
+
+// NewTransactor instantiates a new Transactor.
+func NewTransactor(db *sqlx.DB) *Transactor {
+	return &Transactor{DB: db}
+}
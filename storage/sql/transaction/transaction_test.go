@@ -0,0 +1,171 @@
+package transaction_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/M-Ro/go-vodstream/storage/sql/broadcast_vod"
+	"github.com/M-Ro/go-vodstream/storage/sql/table/tabletest"
+	"github.com/M-Ro/go-vodstream/storage/sql/transaction"
+	"github.com/M-Ro/go-vodstream/storage/sql/video"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"os"
+	"testing"
+)
+
+var testDb *sqlx.DB
+
+func TestMain(m *testing.M) {
+	var purge func()
+	testDb, purge = tabletest.Postgres(video.VideosTableName, broadcast_vod.BroadcastVodsTableName)
+
+	// Run tests
+	code := m.Run()
+	purge()
+
+	os.Exit(code)
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		testName string
+		err      error
+		expected bool
+	}{
+		{"Expect serialization failures to be retried.", &pq.Error{Code: "40001"}, true},
+		{"Expect deadlocks to be retried.", fmt.Errorf("insert: %w", &pq.Error{Code: "40P01"}), true},
+		{"Expect other database errors not to be retried.", &pq.Error{Code: "23505"}, false},
+		{"Expect errors outside the database not to be retried.", errors.New("any"), false},
+		{"Expect success not to be retried.", nil, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			if got := transaction.Retryable(test.err); got != test.expected {
+				t.Fatalf("expected %v, got %v", test.expected, got)
+			}
+		})
+	}
+}
+
+// publish inserts a video linked to a broadcast, as recordings are published.
+func publish(ctx context.Context, videos *video.SqlVideoStorage, vods *broadcast_vod.SqlBroadcastVodStorage) error {
+	newVideo := storage.Video{Title: "Recording"}
+	if err := videos.Insert(ctx, &newVideo); err != nil {
+		return err
+	}
+
+	return vods.Insert(ctx, &storage.BroadcastVod{VideoId: newVideo.Id})
+}
+
+// count returns the number of rows in the table.
+func count(t *testing.T, tableName string) int {
+	var rows int
+	if err := testDb.Get(&rows, fmt.Sprintf(`SELECT COUNT(*) FROM %s`, tableName)); err != nil {
+		t.Fatal(err)
+	}
+
+	return rows
+}
+
+func TestWithTx(t *testing.T) {
+	ctx := context.Background()
+	videos := video.NewVideoStorage(testDb)
+	vods := broadcast_vod.NewBroadcastVodStorage(testDb)
+
+	t.Run("Expect the work of each storage committed together.", func(t *testing.T) {
+		before := count(t, video.VideosTableName)
+
+		err := transaction.WithTx(ctx, testDb, func(ctx context.Context) error {
+			return publish(ctx, videos, vods)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := count(t, video.VideosTableName); got != before+1 {
+			t.Fatalf("expected %d videos, got %d", before+1, got)
+		}
+	})
+
+	t.Run("Expect the work of each storage rolled back on failure.", func(t *testing.T) {
+		before := count(t, video.VideosTableName)
+		failure := errors.New("failure")
+
+		err := transaction.WithTx(ctx, testDb, func(ctx context.Context) error {
+			if err := publish(ctx, videos, vods); err != nil {
+				return err
+			}
+
+			return failure
+		})
+		if err != failure {
+			t.Fatalf("expected %v, got %v", failure, err)
+		}
+
+		if got := count(t, video.VideosTableName); got != before {
+			t.Fatalf("expected %d videos, got %d", before, got)
+		}
+	})
+
+	t.Run("Expect nested units of work to join the outer transaction.", func(t *testing.T) {
+		before := count(t, video.VideosTableName)
+		failure := errors.New("failure")
+
+		err := transaction.WithTx(ctx, testDb, func(ctx context.Context) error {
+			err := transaction.WithTx(ctx, testDb, func(ctx context.Context) error {
+				return publish(ctx, videos, vods)
+			})
+			if err != nil {
+				return err
+			}
+
+			return failure
+		})
+		if err != failure {
+			t.Fatalf("expected %v, got %v", failure, err)
+		}
+
+		if got := count(t, video.VideosTableName); got != before {
+			t.Fatalf("expected %d videos, got %d", before, got)
+		}
+	})
+
+	t.Run("Expect serialization failures retried until they succeed.", func(t *testing.T) {
+		attempts := 0
+
+		err := transaction.WithTx(ctx, testDb, func(ctx context.Context) error {
+			attempts++
+			if attempts < transaction.MaxAttempts {
+				return &pq.Error{Code: "40001"}
+			}
+
+			return publish(ctx, videos, vods)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if attempts != transaction.MaxAttempts {
+			t.Fatalf("expected %d attempts, got %d", transaction.MaxAttempts, attempts)
+		}
+	})
+
+	t.Run("Expect serialization failures returned once attempts run out.", func(t *testing.T) {
+		attempts := 0
+
+		err := transaction.WithTx(ctx, testDb, func(ctx context.Context) error {
+			attempts++
+			return &pq.Error{Code: "40001"}
+		})
+		if !transaction.Retryable(err) {
+			t.Fatalf("expected a serialization failure, got %v", err)
+		}
+
+		if attempts != transaction.MaxAttempts {
+			t.Fatalf("expected %d attempts, got %d", transaction.MaxAttempts, attempts)
+		}
+	})
+}
@@ -6,13 +6,12 @@ import (
 	"fmt"
 	"github.com/M-Ro/go-vodstream/internal/paginate"
 	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/M-Ro/go-vodstream/storage/sql/table/tabletest"
+	"github.com/M-Ro/go-vodstream/storage/storagetest"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
-	"github.com/ory/dockertest/v3"
-	"github.com/ory/dockertest/v3/docker"
-	log "github.com/sirupsen/logrus"
 	"os"
 	"testing"
 	"time"
@@ -21,46 +20,8 @@ import (
 var testDb *sqlx.DB
 
 func TestMain(m *testing.M) {
-	pool, err := dockertest.NewPool("")
-	if err != nil {
-		log.Fatalf("Could not connect to docker: %s", err)
-	}
-
-	resource, err := pool.RunWithOptions(&dockertest.RunOptions{
-		Repository: "postgres",
-		Tag:        "13",
-		Env: []string{
-			"POSTGRES_PASSWORD=secret",
-			"POSTGRES_USER=user_name",
-			"POSTGRES_DB=dbname",
-			"listen_addresses = '*'",
-		},
-	}, func(config *docker.HostConfig) {
-		// set AutoRemove to true so that stopped container goes away by itself
-		config.AutoRemove = true
-		config.RestartPolicy = docker.RestartPolicy{Name: "no"}
-	})
-	if err != nil {
-		log.Fatalf("Could not start resource: %s", err)
-	}
-
-	hostAndPort := resource.GetHostPort("5432/tcp")
-	databaseUrl := fmt.Sprintf("postgres://user_name:secret@%s/dbname?sslmode=disable", hostAndPort)
-	log.Println("Connecting to database on url: ", databaseUrl)
-
-	resource.Expire(120) // Tell docker to hard kill the container in 120 seconds
-
-	// exponential backoff-retry, because the application in the container might not be ready to accept connections yet
-	pool.MaxWait = 120 * time.Second
-	if err = pool.Retry(func() error {
-		testDb, err = sqlx.Open("postgres", databaseUrl)
-		if err != nil {
-			return err
-		}
-		return testDb.Ping()
-	}); err != nil {
-		log.Fatalf("Could not connect to docker: %s", err)
-	}
+	var purge func()
+	testDb, purge = tabletest.Postgres()
 
 	testDb.MustExec(fmt.Sprintf(`CREATE TABLE %s (
 		id          BIGSERIAL   PRIMARY KEY,
@@ -70,6 +31,8 @@ func TestMain(m *testing.M) {
 		publish_key TEXT,
 		can_publish BOOL,
 		can_stream  BOOL,
+		is_admin    BOOL NOT NULL DEFAULT false,
+		storage_quota BIGINT NOT NULL DEFAULT 0,
 		created_at  TIMESTAMP,
 		updated_at  TIMESTAMP
 	);`, UsersTableName))
@@ -78,10 +41,7 @@ func TestMain(m *testing.M) {
 
 	// Run tests
 	code := m.Run()
-
-	if err := pool.Purge(resource); err != nil {
-		log.Fatalf("Could not purge resource: %s", err)
-	}
+	purge()
 
 	os.Exit(code)
 }
@@ -266,6 +226,34 @@ func TestUserStorage_List(t *testing.T) {
 				},
 			},
 		},
+		{
+			testName: "test after cursor",
+			paginateOptions: paginate.NewPaginateOptions(
+				paginate.WithLimit(1), paginate.WithCursor(paginate.Cursor{Field: "id", Value: "1", Id: "1"}),
+			),
+			expectedError: nil,
+			expectedReturn: []storage.User{
+				{
+					Id: 2,
+				},
+			},
+		},
+		{
+			testName: "test before cursor",
+			paginateOptions: paginate.NewPaginateOptions(
+				paginate.WithLimit(2),
+				paginate.WithCursor(paginate.Cursor{Field: "id", Value: "3", Id: "3", Backward: true}),
+			),
+			expectedError: nil,
+			expectedReturn: []storage.User{
+				{
+					Id: 1,
+				},
+				{
+					Id: 2,
+				},
+			},
+		},
 		{
 			testName:        "test offset larger than row count",
 			paginateOptions: paginate.NewPaginateOptions(paginate.WithOffset(250)),
@@ -280,7 +268,7 @@ func TestUserStorage_List(t *testing.T) {
 			seed(testDb)
 
 			// Perform check and compare output
-			users, err := userStorage.List(ctx, test.paginateOptions)
+			users, _, err := userStorage.List(ctx, test.paginateOptions)
 
 			if !cmp.Equal(err, test.expectedError, cmpopts.EquateErrors()) {
 				t.Fatal(cmp.Diff(err, test.expectedError, cmpopts.EquateErrors()))
@@ -476,7 +464,7 @@ func TestUserStorage_Delete(t *testing.T) {
 			}
 
 			// Ensure row is gone
-			row := testDb.QueryRowxContext(ctx, insertTableName(`SELECT * from %s WHERE id = $1`), test.Id)
+			row := testDb.QueryRowxContext(ctx, fmt.Sprintf(`SELECT * from %s WHERE id = $1`, UsersTableName), test.Id)
 			var user storage.User
 			err := row.StructScan(&user)
 
@@ -517,7 +505,9 @@ func TestUserStorage_Insert(t *testing.T) {
 			}
 
 			// Ensure row is added
-			row := testDb.QueryRowxContext(ctx, insertTableName(`SELECT * from %s WHERE username = $1`), test.user.Username)
+			row := testDb.QueryRowxContext(
+				ctx, fmt.Sprintf(`SELECT * from %s WHERE username = $1`, UsersTableName), test.user.Username,
+			)
 			var user storage.User
 			err = row.StructScan(&user)
 
@@ -569,7 +559,9 @@ func TestUserStorage_Update(t *testing.T) {
 
 			if err == nil {
 				// Ensure row is updated
-				row := testDb.QueryRowxContext(ctx, insertTableName(`SELECT * from %s WHERE id = $1`), test.user.Id)
+				row := testDb.QueryRowxContext(
+					ctx, fmt.Sprintf(`SELECT * from %s WHERE id = $1`, UsersTableName), test.user.Id,
+				)
 				var user storage.User
 				err = row.StructScan(&user)
 
@@ -584,3 +576,7 @@ func TestUserStorage_Update(t *testing.T) {
 		})
 	}
 }
+
+func TestUserStorage_Conformance(t *testing.T) {
+	storagetest.Users(t, NewUserStorage(testDb))
+}
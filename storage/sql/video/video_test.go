@@ -0,0 +1,26 @@
+package video
+
+import (
+	"github.com/M-Ro/go-vodstream/storage/sql/table/tabletest"
+	"github.com/M-Ro/go-vodstream/storage/storagetest"
+	"github.com/jmoiron/sqlx"
+	"os"
+	"testing"
+)
+
+var testDb *sqlx.DB
+
+func TestMain(m *testing.M) {
+	var purge func()
+	testDb, purge = tabletest.Postgres(VideosTableName)
+
+	// Run tests
+	code := m.Run()
+	purge()
+
+	os.Exit(code)
+}
+
+func TestVideoStorage_Conformance(t *testing.T) {
+	storagetest.Videos(t, NewVideoStorage(testDb))
+}
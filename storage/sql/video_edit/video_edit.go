@@ -0,0 +1,106 @@
+package video_edit
+
+import (
+	"context"
+	sql2 "database/sql"
+	"errors"
+	"fmt"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/M-Ro/go-vodstream/storage/sql/transaction"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+const VideoEditsTableName = "video_edits"
+
+var (
+	ErrNoRowsAffected = errors.New("no row found with id")
+)
+
+type SqlVideoEditStorage struct {
+	DB *sqlx.DB
+}
+
+// insertTableName is a helper function to insert the dynamic VideoEditsTableName property
+// as bindvars cannot be used as identifiers.
+func insertTableName(query string) string {
+	return fmt.Sprintf(query, VideoEditsTableName)
+}
+
+// GetByID returns the video edit with the given ID, or nil on failure.
+func (s SqlVideoEditStorage) GetByID(ctx context.Context, id uuid.UUID) *storage.VideoEdit {
+	row := transaction.Conn(ctx, s.DB).QueryRowxContext(ctx, insertTableName(`SELECT * from %s WHERE id = $1`), id)
+
+	var edit storage.VideoEdit
+	err := row.StructScan(&edit)
+	if err != nil {
+		if err != sql2.ErrNoRows {
+			log.Error(err)
+		}
+		return nil
+	}
+
+	return &edit
+}
+
+// Insert takes a storage model and inserts into the db. Returns an error on failure.
+// Upon insertion the ID field of the model will be set.
+func (s SqlVideoEditStorage) Insert(ctx context.Context, edit *storage.VideoEdit) error {
+	edit.CreatedAt = time.Now().Truncate(time.Microsecond)
+	edit.UpdatedAt = edit.CreatedAt
+
+	row := transaction.Conn(ctx, s.DB).QueryRowContext(
+		ctx,
+		insertTableName(`INSERT INTO %s 
+			(broadcaster_id, operation, status, source_ids, result_ids, created_at, updated_at)
+			VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING id`),
+		edit.BroadcasterId, edit.Operation, edit.Status, edit.SourceIds, edit.ResultIds,
+		edit.CreatedAt, edit.UpdatedAt,
+	)
+
+	err := row.Scan(&edit.Id)
+
+	return err
+}
+
+// Update takes a storage model and updates row contents for the video edit at the given ID.
+// Returns error on failure, or if a video edit was not found with the given id.
+func (s SqlVideoEditStorage) Update(ctx context.Context, id uuid.UUID, edit *storage.VideoEdit) error {
+	edit.UpdatedAt = time.Now().Truncate(time.Microsecond)
+
+	result, err := transaction.Conn(ctx, s.DB).ExecContext(
+		ctx,
+		insertTableName(`UPDATE %s SET 
+			broadcaster_id=$1, operation=$2, status=$3, source_ids=$4, result_ids=$5,
+			created_at=$6, updated_at=$7 WHERE id=$8`),
+		edit.BroadcasterId, edit.Operation, edit.Status, edit.SourceIds, edit.ResultIds,
+		edit.CreatedAt, edit.UpdatedAt, id)
+
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	if rows != 1 {
+		log.Warn(ErrNoRowsAffected)
+		return ErrNoRowsAffected
+	}
+
+	return nil
+}
+
+// NewVideoEditStorage instantiates a new SqlVideoEditStorage object.
+func NewVideoEditStorage(db *sqlx.DB) *SqlVideoEditStorage {
+	newStorage := new(SqlVideoEditStorage)
+	newStorage.DB = db
+
+	return newStorage
+}
@@ -0,0 +1,7 @@
+package storage
+
+import "errors"
+
+var (
+	ErrNoRowsAffected = errors.New("no row found with id")
+)
@@ -0,0 +1,546 @@
+// Package storagetest tests that the storages of every backend behave alike. Each
+// conformance test is run against the SQL and in-memory storages of an entity, against
+// rows it inserts alongside any already stored.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/M-Ro/go-vodstream/internal/broadcast"
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	"github.com/M-Ro/go-vodstream/internal/user"
+	"github.com/M-Ro/go-vodstream/internal/video"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Users tests a user storage.
+func Users(t *testing.T, users user.StorageProvider) {
+	ctx := context.Background()
+	prefix := unique()
+
+	t.Run("Suite", func(t *testing.T) {
+		Suite[storage.User, uint64]{
+			Storage:   users,
+			KeyColumn: "id",
+			ReadOnly:  []string{"search_vector"},
+			New: func(i int) storage.User {
+				return storage.User{
+					Username: fmt.Sprintf("%ssuite%d", prefix, i),
+					Email:    fmt.Sprintf("%ssuite%d@example.com", prefix, i),
+				}
+			},
+			Key: func(user storage.User) uint64 {
+				return user.Id
+			},
+			Edit: func(user *storage.User) {
+				user.CanPublish = true
+				user.StorageQuota = 1 << 30
+			},
+			Missing: 1 << 62,
+		}.Run(t)
+	})
+
+	mixed := storage.User{Username: prefix + "MixedCase", Email: prefix + "MixedCase@Example.com"}
+	if err := users.Insert(ctx, &mixed); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("GetByUsername", func(t *testing.T) {
+		for _, username := range []string{mixed.Username, strings.ToUpper(mixed.Username), strings.ToLower(mixed.Username)} {
+			if got := users.GetByUsername(ctx, username); got == nil || got.Id != mixed.Id {
+				t.Fatalf("expected user %d by username %q, got %+v", mixed.Id, username, got)
+			}
+		}
+
+		if got := users.GetByUsername(ctx, prefix+"missing"); got != nil {
+			t.Fatalf("expected no user, got %+v", *got)
+		}
+	})
+
+	// Wildcards of LIKE patterns must be matched literally.
+	underscored := storage.User{Username: prefix + "under_score", Email: prefix + "under_score@example.com"}
+	if err := users.Insert(ctx, &underscored); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("GetByUsername wildcards", func(t *testing.T) {
+		if got := users.GetByUsername(ctx, prefix+"UNDER_SCORE"); got == nil || got.Id != underscored.Id {
+			t.Fatalf("expected user %d, got %+v", underscored.Id, got)
+		}
+
+		for _, username := range []string{prefix + "underXscore", prefix + "under%", prefix + "%"} {
+			if got := users.GetByUsername(ctx, username); got != nil {
+				t.Fatalf("expected no user by username %q, got %+v", username, *got)
+			}
+		}
+	})
+
+	t.Run("GetByEmail wildcards", func(t *testing.T) {
+		for _, email := range []string{prefix + "underXscore@example.com", prefix + "under%@example.com"} {
+			if got := users.GetByEmail(ctx, email); got != nil {
+				t.Fatalf("expected no user by email %q, got %+v", email, *got)
+			}
+		}
+	})
+
+	t.Run("GetByEmail", func(t *testing.T) {
+		for _, email := range []string{mixed.Email, strings.ToUpper(mixed.Email), strings.ToLower(mixed.Email)} {
+			if got := users.GetByEmail(ctx, email); got == nil || got.Id != mixed.Id {
+				t.Fatalf("expected user %d by email %q, got %+v", mixed.Id, email, got)
+			}
+		}
+
+		if got := users.GetByEmail(ctx, prefix+"missing@example.com"); got != nil {
+			t.Fatalf("expected no user, got %+v", *got)
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		listed := make([]string, 5)
+		for i := range listed {
+			listed[i] = fmt.Sprintf("%slist%d", prefix, i)
+			if err := users.Insert(ctx, &storage.User{Username: listed[i], Email: listed[i] + "@example.com"}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		options := paginate.NewPaginateOptions(
+			paginate.WithLimit(2),
+			paginate.WithSort(paginate.Order{Field: "username", Method: paginate.OrderMethodDesc}),
+			paginate.WithFilters(paginate.ILike("username", strings.ToUpper(prefix)+"LIST%")),
+		)
+
+		pages := pageThrough(t, options, users.List, func(user storage.User) string {
+			return user.Username
+		})
+
+		expected := [][]string{{listed[4], listed[3]}, {listed[2], listed[1]}, {listed[0]}}
+		if !cmp.Equal(pages, expected) {
+			t.Fatal(cmp.Diff(pages, expected))
+		}
+	})
+
+	t.Run("List unsortable field", func(t *testing.T) {
+		_, _, err := users.List(ctx, paginate.NewPaginateOptions(paginate.WithOrderField("password")))
+		if !errors.Is(err, paginate.ErrInvalidSort) {
+			t.Fatalf("expected %v, got %v", paginate.ErrInvalidSort, err)
+		}
+	})
+}
+
+// Broadcasts tests a broadcast storage.
+func Broadcasts(t *testing.T, broadcasts broadcast.StorageProvider) {
+	ctx := context.Background()
+	category := unique()
+	broadcasterId := uint64(time.Now().UnixNano())
+
+	t.Run("Suite", func(t *testing.T) {
+		Suite[storage.Broadcast, uuid.UUID]{
+			Storage:   broadcasts,
+			KeyColumn: "id",
+			ReadOnly:  []string{"search_vector"},
+			New: func(i int) storage.Broadcast {
+				return storage.Broadcast{
+					BroadcasterId: uint64(i + 1),
+					Title:         fmt.Sprintf("Broadcast %d", i),
+					Category:      "speedruns",
+					Tags:          pq.StringArray{"any%"},
+					Language:      "en",
+					IsActive:      true,
+					PublishedAt:   time.Now().Truncate(time.Microsecond),
+				}
+			},
+			Key: func(broadcast storage.Broadcast) uuid.UUID {
+				return broadcast.Id
+			},
+			Edit: func(broadcast *storage.Broadcast) {
+				broadcast.IsActive = false
+				broadcast.IsPublished = true
+				broadcast.Tags = append(broadcast.Tags, "retro")
+			},
+			Missing: uuid.New(),
+		}.Run(t)
+	})
+
+	t.Run("Insert at ID", func(t *testing.T) {
+		published := storage.Broadcast{Id: uuid.New(), BroadcasterId: broadcasterId, Title: "Published"}
+		id := published.Id
+
+		if err := broadcasts.Insert(ctx, &published); err != nil {
+			t.Fatal(err)
+		}
+
+		if published.Id != id || broadcasts.GetByID(ctx, id) == nil {
+			t.Fatalf("expected the broadcast inserted at %s", id)
+		}
+	})
+
+	t.Run("Insert without tags", func(t *testing.T) {
+		untagged := storage.Broadcast{BroadcasterId: broadcasterId, Title: "Untagged"}
+		if err := broadcasts.Insert(ctx, &untagged); err != nil {
+			t.Fatal(err)
+		}
+
+		stored := broadcasts.GetByID(ctx, untagged.Id)
+		if stored == nil || stored.Tags == nil || len(stored.Tags) != 0 {
+			t.Fatalf("expected broadcast %s stored without tags, got %+v", untagged.Id, stored)
+		}
+
+		untagged.Tags = nil
+		if err := broadcasts.Update(ctx, untagged.Id, &untagged); err != nil {
+			t.Fatal(err)
+		}
+
+		stored = broadcasts.GetByID(ctx, untagged.Id)
+		if stored == nil || stored.Tags == nil || len(stored.Tags) != 0 {
+			t.Fatalf("expected broadcast %s updated without tags, got %+v", untagged.Id, stored)
+		}
+	})
+
+	live := storage.Broadcast{BroadcasterId: broadcasterId, Category: category, IsActive: true, IsPublished: true}
+	for _, b := range []storage.Broadcast{
+		live,
+		{BroadcasterId: broadcasterId, Category: category, IsActive: true},
+		{BroadcasterId: broadcasterId, Category: category, IsPublished: true},
+	} {
+		b := b
+		if err := broadcasts.Insert(ctx, &b); err != nil {
+			t.Fatal(err)
+		}
+
+		if b.IsActive && b.IsPublished {
+			live = b
+		}
+	}
+
+	byBroadcaster := paginate.NewPaginateOptions(
+		paginate.WithFilters(paginate.Equals("broadcaster_id", int64(broadcasterId))),
+	)
+
+	t.Run("ListLive", func(t *testing.T) {
+		listed, _, err := broadcasts.ListLive(ctx, byBroadcaster)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(listed) != 1 || listed[0].Id != live.Id {
+			t.Fatalf("expected only broadcast %s listed, got %+v", live.Id, listed)
+		}
+	})
+
+	t.Run("ListLiveByCategory", func(t *testing.T) {
+		listed, _, err := broadcasts.ListLiveByCategory(ctx, category, byBroadcaster)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(listed) != 1 || listed[0].Id != live.Id {
+			t.Fatalf("expected only broadcast %s listed, got %+v", live.Id, listed)
+		}
+
+		listed, _, err = broadcasts.ListLiveByCategory(ctx, category+"other", byBroadcaster)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(listed) != 0 {
+			t.Fatalf("expected no broadcasts listed, got %+v", listed)
+		}
+	})
+
+	t.Run("UpdateViewerCount", func(t *testing.T) {
+		if err := broadcasts.UpdateViewerCount(ctx, live.Id, 42); err != nil {
+			t.Fatal(err)
+		}
+
+		stored := broadcasts.GetByID(ctx, live.Id)
+		if stored == nil || stored.ViewerCount != 42 || stored.Category != category {
+			t.Fatalf("expected 42 viewers of broadcast %s, got %+v", live.Id, stored)
+		}
+
+		if err := broadcasts.UpdateViewerCount(ctx, uuid.New(), 42); err != storage.ErrNoRowsAffected {
+			t.Fatalf("expected %v, got %v", storage.ErrNoRowsAffected, err)
+		}
+	})
+}
+
+// Videos tests a video storage.
+func Videos(t *testing.T, videos video.StorageProvider) {
+	ctx := context.Background()
+	broadcasterId := uint64(time.Now().UnixNano())
+
+	t.Run("Suite", func(t *testing.T) {
+		Suite[storage.Video, uuid.UUID]{
+			Storage:   videos,
+			KeyColumn: "id",
+			ReadOnly:  []string{"search_vector"},
+			New: func(i int) storage.Video {
+				return storage.Video{
+					Title:         fmt.Sprintf("Video %d", i),
+					BroadcasterId: uint64(i + 1),
+					Length:        uint64(i+1) * 60000,
+					IsPublished:   true,
+					PublishedAt:   time.Now().Truncate(time.Microsecond),
+					FilePath:      fmt.Sprintf("videos/%d.mp4", i),
+				}
+			},
+			Key: func(video storage.Video) uuid.UUID {
+				return video.Id
+			},
+			Edit: func(video *storage.Video) {
+				video.Title += " (edited)"
+				video.Pinned = true
+			},
+			Missing: uuid.New(),
+		}.Run(t)
+	})
+
+	inserted := make([]storage.Video, 4)
+	for i := range inserted {
+		inserted[i] = storage.Video{
+			Title:         fmt.Sprintf("Recording %d", i),
+			BroadcasterId: broadcasterId,
+			Length:        uint64(i+1) * 60000,
+			IsPublished:   i%2 == 0,
+		}
+
+		if err := videos.Insert(ctx, &inserted[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	byLength := paginate.NewPaginateOptions(
+		paginate.WithLimit(3), paginate.WithOrderField("length"), paginate.WithOrder(paginate.OrderMethodDesc),
+	)
+
+	t.Run("ListByBroadcaster", func(t *testing.T) {
+		pages := pageThrough(t, byLength, func(ctx context.Context, options paginate.QueryOptions) (
+			[]storage.Video, paginate.Page, error,
+		) {
+			return videos.ListByBroadcaster(ctx, broadcasterId, options)
+		}, videoId)
+
+		expected := [][]uuid.UUID{{inserted[3].Id, inserted[2].Id, inserted[1].Id}, {inserted[0].Id}}
+		if !cmp.Equal(pages, expected) {
+			t.Fatal(cmp.Diff(pages, expected))
+		}
+	})
+
+	t.Run("ListPublishedByBroadcaster", func(t *testing.T) {
+		pages := pageThrough(t, byLength, func(ctx context.Context, options paginate.QueryOptions) (
+			[]storage.Video, paginate.Page, error,
+		) {
+			return videos.ListPublishedByBroadcaster(ctx, broadcasterId, options)
+		}, videoId)
+
+		expected := [][]uuid.UUID{{inserted[2].Id, inserted[0].Id}}
+		if !cmp.Equal(pages, expected) {
+			t.Fatal(cmp.Diff(pages, expected))
+		}
+	})
+}
+
+// BroadcastVods tests a broadcast VOD storage, linking videos it inserts into videos.
+func BroadcastVods(t *testing.T, vods broadcast.VodStorageProvider, videos video.StorageProvider) {
+	ctx := context.Background()
+
+	recordings := make([]storage.Video, 6)
+	for i := range recordings {
+		recordings[i].Title = fmt.Sprintf("Recording %d", i)
+		if err := videos.Insert(ctx, &recordings[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	streamId := uuid.New()
+
+	t.Run("Suite", func(t *testing.T) {
+		Suite[storage.BroadcastVod, uuid.UUID]{
+			Storage:   vods,
+			KeyColumn: "id",
+			New: func(i int) storage.BroadcastVod {
+				return storage.BroadcastVod{
+					StreamId:    streamId,
+					VideoId:     recordings[i].Id,
+					PublishedAt: time.Now().Truncate(time.Microsecond),
+				}
+			},
+			Key: func(vod storage.BroadcastVod) uuid.UUID {
+				return vod.Id
+			},
+			Edit: func(vod *storage.BroadcastVod) {
+				vod.PublishedAt = vod.PublishedAt.Add(time.Hour)
+			},
+			Missing: uuid.New(),
+		}.Run(t)
+	})
+
+	// Recordings are linked out of order of publication, the last two to the same video.
+	streamId = uuid.New()
+	start := time.Now().Truncate(time.Microsecond)
+	linked := []storage.BroadcastVod{
+		{StreamId: streamId, VideoId: recordings[3].Id, PublishedAt: start.Add(2 * time.Minute)},
+		{StreamId: streamId, VideoId: recordings[4].Id, PublishedAt: start},
+		{StreamId: streamId, VideoId: recordings[5].Id, PublishedAt: start.Add(time.Minute)},
+		{StreamId: streamId, VideoId: recordings[5].Id, PublishedAt: start.Add(3 * time.Minute)},
+	}
+	for i := range linked {
+		if err := vods.Insert(ctx, &linked[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("GetByStreamID", func(t *testing.T) {
+		stored, err := vods.GetByStreamID(ctx, streamId)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := []uuid.UUID{linked[1].Id, linked[2].Id, linked[0].Id, linked[3].Id}
+		if got := vodIds(stored); !cmp.Equal(got, expected) {
+			t.Fatal(cmp.Diff(got, expected))
+		}
+
+		stored, err = vods.GetByStreamID(ctx, uuid.New())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(stored) != 0 {
+			t.Fatalf("expected no broadcastVods, got %+v", stored)
+		}
+	})
+
+	t.Run("GetByVideoID", func(t *testing.T) {
+		stored, err := vods.GetByVideoID(ctx, recordings[5].Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := []uuid.UUID{linked[2].Id, linked[3].Id}
+		if got := vodIds(stored); !cmp.Equal(got, expected) {
+			t.Fatal(cmp.Diff(got, expected))
+		}
+
+		stored, err = vods.GetByVideoID(ctx, uuid.New())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(stored) != 0 {
+			t.Fatalf("expected no broadcastVods, got %+v", stored)
+		}
+	})
+
+	t.Run("DeleteByVideoID", func(t *testing.T) {
+		if err := vods.DeleteByVideoID(ctx, recordings[5].Id); err != nil {
+			t.Fatal(err)
+		}
+
+		stored, err := vods.GetByStreamID(ctx, streamId)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := []uuid.UUID{linked[1].Id, linked[0].Id}
+		if got := vodIds(stored); !cmp.Equal(got, expected) {
+			t.Fatal(cmp.Diff(got, expected))
+		}
+	})
+}
+
+// pageThrough lists every page of rows from the first, specified by the given options, returning
+// the identities of the rows of each. Once past the last page, the pages are listed again
+// backward from it, and must match.
+func pageThrough[T any, I any](
+	t *testing.T,
+	options paginate.QueryOptions,
+	list func(ctx context.Context, options paginate.QueryOptions) ([]T, paginate.Page, error),
+	identify func(row T) I,
+) [][]I {
+	t.Helper()
+
+	pages := make([][]I, 0)
+	cursors := make([]paginate.Page, 0)
+
+	for {
+		rows, page, err := list(context.Background(), options)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		identities := make([]I, 0, len(rows))
+		for _, row := range rows {
+			identities = append(identities, identify(row))
+		}
+		pages, cursors = append(pages, identities), append(cursors, page)
+
+		if page.Next == "" {
+			break
+		}
+		options.Cursor = decode(t, page.Next)
+	}
+
+	if cursors[0].Prev != "" {
+		t.Fatal("expected no page before the first")
+	}
+
+	for i := len(pages) - 1; i > 0; i-- {
+		options.Cursor = decode(t, cursors[i].Prev)
+
+		rows, _, err := list(context.Background(), options)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		identities := make([]I, 0, len(rows))
+		for _, row := range rows {
+			identities = append(identities, identify(row))
+		}
+
+		if !cmp.Equal(identities, pages[i-1]) {
+			t.Fatalf("expected the page before page %d to match, %s", i, cmp.Diff(identities, pages[i-1]))
+		}
+	}
+
+	return pages
+}
+
+// decode returns the encoded cursor.
+func decode(t *testing.T, encoded string) *paginate.Cursor {
+	t.Helper()
+
+	cursor, err := paginate.DecodeCursor(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &cursor
+}
+
+// unique returns a lowercase name no other test run has used, to find the rows a test inserts.
+func unique() string {
+	return "conformance" + strings.ReplaceAll(uuid.NewString(), "-", "")[:12]
+}
+
+// videoId returns the ID of a video.
+func videoId(video storage.Video) uuid.UUID {
+	return video.Id
+}
+
+// vodIds returns the IDs of the broadcastVods.
+func vodIds(vods []storage.BroadcastVod) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(vods))
+	for _, vod := range vods {
+		ids = append(ids, vod.Id)
+	}
+
+	return ids
+}
@@ -0,0 +1,188 @@
+package storagetest
+
+import (
+	"context"
+	"github.com/M-Ro/go-vodstream/internal/paginate"
+	"github.com/M-Ro/go-vodstream/storage"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/reflectx"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// mapper finds the fields of storage models by column name, as sqlx scans them.
+var mapper = reflectx.NewMapperFunc("db", sqlx.NameMapper)
+
+// Storage is the generic operations of the table storages of every backend, as may be
+// overridden by the storages built on them.
+type Storage[T any, K comparable] interface {
+	All(ctx context.Context) ([]T, error)
+	List(ctx context.Context, options paginate.QueryOptions) ([]T, paginate.Page, error)
+	GetByID(ctx context.Context, id K) *T
+	Delete(ctx context.Context, id K) error
+	Insert(ctx context.Context, row *T) error
+	Update(ctx context.Context, id K, row *T) error
+}
+
+// Suite tests the generic operations of a table storage, against rows it inserts
+// alongside any already stored.
+type Suite[T any, K comparable] struct {
+	Storage Storage[T, K]
+
+	// The column identifying rows
+	KeyColumn string
+
+	// Columns generated by the database, never written
+	ReadOnly []string
+
+	// New returns a row to insert, distinct for each i
+	New func(i int) T
+
+	// Key returns the key of a row
+	Key func(row T) K
+
+	// Edit changes a row to be updated
+	Edit func(row *T)
+
+	// The key of no row
+	Missing K
+}
+
+// Run runs the suite as subtests of t.
+func (s Suite[T, K]) Run(t *testing.T) {
+	ctx := context.Background()
+
+	rows := make([]T, 0, 3)
+	for i := 0; i < cap(rows); i++ {
+		rows = append(rows, s.New(i))
+	}
+
+	t.Run("Insert", func(t *testing.T) {
+		for i := range rows {
+			if err := s.Storage.Insert(ctx, &rows[i]); err != nil {
+				t.Fatal(err)
+			}
+
+			var zero K
+			if s.Key(rows[i]) == zero {
+				t.Fatal("expected the key of the inserted row to be set")
+			}
+		}
+	})
+
+	t.Run("GetByID", func(t *testing.T) {
+		for _, row := range rows {
+			s.expectStored(t, row)
+		}
+
+		if got := s.Storage.GetByID(ctx, s.Missing); got != nil {
+			t.Fatalf("expected no row, got %+v", *got)
+		}
+	})
+
+	t.Run("All", func(t *testing.T) {
+		all, err := s.Storage.All(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		s.expectEach(t, all, rows)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		listed := make([]T, 0)
+
+		options := paginate.NewPaginateOptions(paginate.WithLimit(2), paginate.WithOrderField(s.KeyColumn))
+		for {
+			page, cursors, err := s.Storage.List(ctx, options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			listed = append(listed, page...)
+
+			if cursors.Next == "" {
+				break
+			}
+
+			cursor, err := paginate.DecodeCursor(cursors.Next)
+			if err != nil {
+				t.Fatal(err)
+			}
+			options.Cursor = &cursor
+		}
+
+		s.expectEach(t, listed, rows)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		for i := range rows {
+			s.Edit(&rows[i])
+			if err := s.Storage.Update(ctx, s.Key(rows[i]), &rows[i]); err != nil {
+				t.Fatal(err)
+			}
+
+			s.expectStored(t, rows[i])
+		}
+
+		missing := s.New(len(rows))
+		if err := s.Storage.Update(ctx, s.Missing, &missing); err != storage.ErrNoRowsAffected {
+			t.Fatalf("expected %v, got %v", storage.ErrNoRowsAffected, err)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		for _, row := range rows {
+			if err := s.Storage.Delete(ctx, s.Key(row)); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := s.Storage.GetByID(ctx, s.Key(row)); got != nil {
+				t.Fatalf("expected the row deleted, got %+v", *got)
+			}
+		}
+	})
+}
+
+// expectStored fails the test unless the stored row at the key of row matches it.
+func (s Suite[T, K]) expectStored(t *testing.T, row T) {
+	t.Helper()
+
+	stored := s.Storage.GetByID(context.Background(), s.Key(row))
+	if stored == nil {
+		t.Fatalf("expected a row at %v", s.Key(row))
+	}
+
+	if got := s.written(*stored); !cmp.Equal(got, s.written(row), cmpopts.EquateApproxTime(time.Second)) {
+		t.Fatal(cmp.Diff(got, s.written(row), cmpopts.EquateApproxTime(time.Second)))
+	}
+}
+
+// expectEach fails the test unless every row is found exactly once within listed.
+func (s Suite[T, K]) expectEach(t *testing.T, listed []T, rows []T) {
+	t.Helper()
+
+	found := make(map[K]int)
+	for _, row := range listed {
+		found[s.Key(row)]++
+	}
+
+	for _, row := range rows {
+		if found[s.Key(row)] != 1 {
+			t.Fatalf("expected %v listed once, listed %d times", s.Key(row), found[s.Key(row)])
+		}
+	}
+}
+
+// written returns the row without the columns generated by the database.
+func (s Suite[T, K]) written(row T) T {
+	model := reflect.ValueOf(&row).Elem()
+	for _, column := range s.ReadOnly {
+		field := mapper.FieldByName(model, column)
+		field.Set(reflect.Zero(field.Type()))
+	}
+
+	return row
+}
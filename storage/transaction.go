@@ -0,0 +1,12 @@
+package storage
+
+import "context"
+
+// Immediate runs units of work directly against storages that cannot take part
+// in transactions. Work is not rolled back when a unit of work fails part way.
+type Immediate struct{}
+
+// WithTx runs fn with the given context.
+func (Immediate) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
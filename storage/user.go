@@ -15,6 +15,12 @@ type User struct {
 	// Permissions
 	CanPublish bool `db:"can_publish"`
 	CanStream  bool `db:"can_stream"`
+	IsAdmin    bool `db:"is_admin"`
+
+	StorageQuota int64 `db:"storage_quota"` // bytes, zero for the configured default
+
+	// Generated by the database for full-text search, never written
+	SearchVector string `db:"search_vector"`
 
 	CreatedAt time.Time `db:"created_at"`
 	UpdatedAt time.Time `db:"updated_at"`
@@ -24,8 +30,8 @@ type User struct {
 func UsersToDomain(users []User) []user.User {
 	convertedUsers := make([]user.User, len(users))
 
-	for i, user := range users {
-		convertedUsers[i] = UserToDomain(user)
+	for i, u := range users {
+		convertedUsers[i] = UserToDomain(u)
 	}
 
 	return convertedUsers
@@ -35,39 +41,47 @@ func UsersToDomain(users []User) []user.User {
 func UsersToStorage(users []user.User) []User {
 	convertedUsers := make([]User, len(users))
 
-	for i, user := range users {
-		convertedUsers[i] = UserToStorage(user)
+	for i, u := range users {
+		convertedUsers[i] = UserToStorage(u)
 	}
 
 	return convertedUsers
 }
 
 // UserToDomain converts a storage user model to a domain model.
-func UserToDomain(user User) user.User {
+func UserToDomain(u User) user.User {
 	return user.User{
-		Id:         user.Id,
-		Username:   user.Username,
-		Email:      user.Email,
-		Password:   user.Password,
-		PublishKey: user.PublishKey,
-		CanPublish: user.CanPublish,
-		CanStream:  user.CanStream,
-		CreatedAt:  user.CreatedAt,
-		UpdatedAt:  user.UpdatedAt,
+		Id:         u.Id,
+		Username:   u.Username,
+		Email:      u.Email,
+		Password:   u.Password,
+		PublishKey: u.PublishKey,
+		CanPublish: u.CanPublish,
+		CanStream:  u.CanStream,
+		IsAdmin:    u.IsAdmin,
+
+		StorageQuota: u.StorageQuota,
+
+		CreatedAt: u.CreatedAt,
+		UpdatedAt: u.UpdatedAt,
 	}
 }
 
 // UserToStorage converts a domain user model to a storage model.
-func UserToStorage(user user.User) User {
+func UserToStorage(u user.User) User {
 	return User{
-		Id:         user.Id,
-		Username:   user.Username,
-		Email:      user.Email,
-		Password:   user.Password,
-		PublishKey: user.PublishKey,
-		CanPublish: user.CanPublish,
-		CanStream:  user.CanStream,
-		CreatedAt:  user.CreatedAt,
-		UpdatedAt:  user.UpdatedAt,
+		Id:         u.Id,
+		Username:   u.Username,
+		Email:      u.Email,
+		Password:   u.Password,
+		PublishKey: u.PublishKey,
+		CanPublish: u.CanPublish,
+		CanStream:  u.CanStream,
+		IsAdmin:    u.IsAdmin,
+
+		StorageQuota: u.StorageQuota,
+
+		CreatedAt: u.CreatedAt,
+		UpdatedAt: u.UpdatedAt,
 	}
 }
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"github.com/M-Ro/go-vodstream/internal/domain/video"
 	"github.com/google/uuid"
 	"time"
 )
@@ -9,9 +10,67 @@ type Video struct {
 	Id    uuid.UUID `db:"id"`
 	Title string    `db:"title"`
 
+	// The user whose broadcast this video was recorded from
+	BroadcasterId uint64 `db:"broadcaster_id"`
+
 	Length      uint64    `db:"length"` // milliseconds
+	IsPublished bool      `db:"is_published"`
 	PublishedAt time.Time `db:"published_at"`
 
+	FilePath  string `db:"file_path"`  // recording, keyed in the media store
+	IndexPath string `db:"index_path"` // keyframe index sidecar of the recording
+
+	// Pinned videos are kept forever, regardless of retention rules
+	Pinned bool `db:"pinned"`
+
+	// Generated by the database for full-text search, never written
+	SearchVector string `db:"search_vector"`
+
 	CreatedAt time.Time `db:"created_at"`
 	UpdatedAt time.Time `db:"updated_at"`
 }
+
+// VideosToDomain converts storage video models to domain models.
+func VideosToDomain(videos []Video) []video.Video {
+	convertedVideos := make([]video.Video, len(videos))
+
+	for i, v := range videos {
+		convertedVideos[i] = VideoToDomain(v)
+	}
+
+	return convertedVideos
+}
+
+// VideoToDomain converts a storage video model to a domain model.
+func VideoToDomain(v Video) video.Video {
+	return video.Video{
+		Id:            v.Id,
+		Title:         v.Title,
+		BroadcasterId: v.BroadcasterId,
+		Length:        time.Duration(v.Length) * time.Millisecond,
+		IsPublished:   v.IsPublished,
+		PublishedAt:   v.PublishedAt,
+		FilePath:      v.FilePath,
+		IndexPath:     v.IndexPath,
+		Pinned:        v.Pinned,
+		CreatedAt:     v.CreatedAt,
+		UpdatedAt:     v.UpdatedAt,
+	}
+}
+
+// VideoToStorage converts a domain video model to a storage model.
+func VideoToStorage(v video.Video) Video {
+	return Video{
+		Id:            v.Id,
+		Title:         v.Title,
+		BroadcasterId: v.BroadcasterId,
+		Length:        uint64(v.Length.Milliseconds()),
+		IsPublished:   v.IsPublished,
+		PublishedAt:   v.PublishedAt,
+		FilePath:      v.FilePath,
+		IndexPath:     v.IndexPath,
+		Pinned:        v.Pinned,
+		CreatedAt:     v.CreatedAt,
+		UpdatedAt:     v.UpdatedAt,
+	}
+}
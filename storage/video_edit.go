@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"github.com/M-Ro/go-vodstream/internal/domain/edit"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"time"
+)
+
+type VideoEdit struct {
+	Id            uuid.UUID `db:"id"`
+	BroadcasterId uint64    `db:"broadcaster_id"`
+
+	Operation string `db:"operation"`
+	Status    string `db:"status"`
+
+	SourceIds pq.StringArray `db:"source_ids"`
+	ResultIds pq.StringArray `db:"result_ids"`
+
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// idsToDomain parses stored video ids, skipping any that are malformed.
+func idsToDomain(ids []string) []uuid.UUID {
+	parsed := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if u, err := uuid.Parse(id); err == nil {
+			parsed = append(parsed, u)
+		}
+	}
+
+	return parsed
+}
+
+// idsToStorage formats video ids for storage.
+func idsToStorage(ids []uuid.UUID) pq.StringArray {
+	formatted := make(pq.StringArray, len(ids))
+	for i, id := range ids {
+		formatted[i] = id.String()
+	}
+
+	return formatted
+}
+
+// VideoEditToDomain converts a storage video edit model to a domain model.
+func VideoEditToDomain(e VideoEdit) edit.Edit {
+	return edit.Edit{
+		Id:            e.Id,
+		BroadcasterId: e.BroadcasterId,
+		Operation:     edit.Operation(e.Operation),
+		Status:        edit.Status(e.Status),
+		Sources:       idsToDomain(e.SourceIds),
+		Results:       idsToDomain(e.ResultIds),
+		CreatedAt:     e.CreatedAt,
+		UpdatedAt:     e.UpdatedAt,
+	}
+}
+
+// VideoEditToStorage converts a domain video edit model to a storage model.
+func VideoEditToStorage(e edit.Edit) VideoEdit {
+	return VideoEdit{
+		Id:            e.Id,
+		BroadcasterId: e.BroadcasterId,
+		Operation:     string(e.Operation),
+		Status:        string(e.Status),
+		SourceIds:     idsToStorage(e.Sources),
+		ResultIds:     idsToStorage(e.Results),
+		CreatedAt:     e.CreatedAt,
+		UpdatedAt:     e.UpdatedAt,
+	}
+}